@@ -1,22 +1,13 @@
 package main
 
 import (
-	"context"
-	"fmt"
 	"log"
-	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
 
-	"github.com/gin-gonic/gin"
-	"github.com/redis/go-redis/v9"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 
-	"github.com/hafiztri123/document-api/internal/api"
-	"github.com/hafiztri123/document-api/internal/database"
+	"github.com/hafiztri123/document-api/internal/app"
 )
 
 func main() {
@@ -31,109 +22,9 @@ func main() {
 	}
 	defer logger.Sync()
 
-	zap.ReplaceGlobals(logger)
-
-	// Set Gin mode based on environment
-	if os.Getenv("ENVIRONMENT") == "production" {
-		gin.SetMode(gin.ReleaseMode)
-	}
-
-	// Initialize database
-	db, err := database.NewConnection()
-	if err != nil {
-		logger.Fatal("Failed to connect to database", zap.Error(err))
-	}
-
-	// Initialize Redis
-	redisClient := redis.NewClient(&redis.Options{
-		Addr:     fmt.Sprintf("%s:%s", os.Getenv("REDISHOST"), os.Getenv("REDISPORT")),
-		Password: os.Getenv("REDISPASSWORD"),
-		DB:       0,
-	})
-
-	// Test Redis connection
-	_, err = redisClient.Ping(context.Background()).Result()
-	if err != nil {
-		logger.Fatal("Failed to connect to Redis", zap.Error(err))
-	}
-
-	// Initialize router with middleware
-	router := gin.New()
-	router.Use(gin.Recovery())
-	
-	// Add request logging middleware
-	router.Use(func(c *gin.Context) {
-		// Start timer
-		start := time.Now()
-		path := c.Request.URL.Path
-		query := c.Request.URL.RawQuery
-
-		// Process request
-		c.Next()
-
-		// Log request details
-		if path != "/health" { // Skip logging health checks
-			logger.Info("API Request",
-				zap.String("method", c.Request.Method),
-				zap.String("path", path),
-				zap.String("query", query),
-				zap.Int("status", c.Writer.Status()),
-				zap.Duration("latency", time.Since(start)),
-				zap.String("ip", c.ClientIP()),
-				zap.String("user-agent", c.Request.UserAgent()),
-			)
-		}
-	})
-
-	// Setup CORS
-	router.Use(func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
-
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
-		}
-
-		c.Next()
-	})
-
-	// Setup routes
-	api.SetupRoutes(router, db, redisClient, logger)
-
-	// Start the server
-	srv := &http.Server{
-		Addr:    fmt.Sprintf(":%d", viper.GetInt("server.port")),
-		Handler: router,
-	}
-
-	// Run the server in a goroutine
-	go func() {
-		logger.Info("Starting server",
-			zap.String("address", srv.Addr),
-			zap.String("environment", viper.GetString("environment")))
-
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Fatal("Error starting server", zap.Error(err))
-		}
-	}()
-
-	// Wait for interrupt signal to gracefully shut down the server
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-
-	logger.Info("Shutting down server...")
-
-	// Create a deadline for server shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	if err := srv.Shutdown(ctx); err != nil {
-		logger.Fatal("Server forced to shutdown", zap.Error(err))
-	}
+	// Run blocks until SIGINT or SIGTERM, then stops every module in
+	// reverse dependency order
+	app.New(logger).Run()
 
 	logger.Info("Server exited properly")
 }
@@ -151,6 +42,47 @@ func initConfig() error {
 	viper.SetDefault("database.max_idle_connections", 10)
 	viper.SetDefault("database.max_open_connections", 100)
 	viper.SetDefault("database.connection_max_lifetime", "1h")
+	viper.SetDefault("redis.min_idle_connections", 10)
+	viper.SetDefault("documents.review_scan_interval", "1h")
+	viper.SetDefault("documents.max_content_bytes", 5<<20)
+	viper.SetDefault("documents.lock_ttl", "15m")
+	viper.SetDefault("documents.expiry_scan_interval", "15m")
+	viper.SetDefault("documents.expiry_notice", "72h")
+	viper.SetDefault("documents.export_cache_ttl", "24h")
+	viper.SetDefault("documents.export_cache_max_bytes", 10<<20)
+	viper.SetDefault("documents.history_debounce", "0s")
+	viper.SetDefault("documents.draft_ttl", "168h")
+	viper.SetDefault("documents.history_snapshot_interval", 20)
+	viper.SetDefault("documents.content_hooks", []string{"sanitize_html"})
+	viper.SetDefault("lint.on_save", false)
+	viper.SetDefault("redaction.on_publish", false)
+	viper.SetDefault("ws.cursor_ttl", "720h")
+	viper.SetDefault("kb.cache_ttl", "5m")
+	viper.SetDefault("directory.blocked_words", []string{})
+	viper.SetDefault("public.cache_ttl", "1m")
+	viper.SetDefault("cdn.ttl", "24h")
+	viper.SetDefault("analytics.hash_ips", false)
+	viper.SetDefault("analytics.digest_scan_interval", "1h")
+	viper.SetDefault("analytics.warehouse.interval", "24h")
+	viper.SetDefault("analytics.warehouse.batch_size", 10000)
+	viper.SetDefault("analytics.warehouse.gzip", true)
+	viper.SetDefault("replication.publish.interval", "5s")
+	viper.SetDefault("replication.publish.batch_size", 500)
+	viper.SetDefault("siem.format", "cef")
+	viper.SetDefault("siem.interval", "5s")
+	viper.SetDefault("siem.batch_size", 100)
+	viper.SetDefault("siem.max_backoff", "1h")
+	viper.SetDefault("siem.retention", "720h")
+	viper.SetDefault("warmup.enabled", true)
+	viper.SetDefault("warmup.timeout", "10s")
+	viper.SetDefault("warmup.window", "24h")
+	viper.SetDefault("warmup.documents", 500)
+	viper.SetDefault("mail.port", 587)
+	viper.SetDefault("rate_limit.requests", 100)
+	viper.SetDefault("rate_limit.duration", "1m")
+	viper.SetDefault("rate_limit.public.requests", 30)
+	viper.SetDefault("rate_limit.public.duration", "1m")
+	viper.SetDefault("rate_limit.warn_at", 0.8)
 
 	// Read the config file
 	if err := viper.ReadInConfig(); err != nil {
@@ -166,7 +98,8 @@ func initConfig() error {
 	return nil
 }
 
-// initLogger initializes the global logger
+// initLogger initializes the root logger; request-scoped loggers derive
+// from it, see the logging package
 func initLogger() (*zap.Logger, error) {
 	var logger *zap.Logger
 	var err error
@@ -30,6 +30,7 @@ const (
 	REDIS_PORT     = "redis.port"
 	REDIS_PASSWORD = "redis.password"
 	REDIS_DB       = "redis.db"
+	REDIS_MIN_IDLE_CONNECTIONS = "redis.min_idle_connections"
 
 	// JWT Configuration Keys
 	JWT_SECRET                 = "jwt.secret"
@@ -40,7 +41,95 @@ const (
 	LOG_LEVEL  = "logging.level"
 	LOG_FORMAT = "logging.format"
 
+	// Users allowed on /api/v1/admin, see middleware.AdminMiddleware
+	ADMIN_USER_IDS = "admin.user_ids"
+
+	// Deprecated routes, see middleware.Deprecation
+	DEPRECATIONS = "deprecations"
+
+	// Fault injection, see chaos.Config
+	CHAOS = "chaos"
+
+	// Document Configuration Keys
+	DOCUMENTS_REVIEW_SCAN_INTERVAL = "documents.review_scan_interval"
+	DOCUMENTS_CONTENT_HOOKS        = "documents.content_hooks"
+	DOCUMENTS_MAX_CONTENT_BYTES    = "documents.max_content_bytes"
+	DOCUMENTS_LOCK_TTL             = "documents.lock_ttl"
+	DOCUMENTS_EXPIRY_SCAN_INTERVAL = "documents.expiry_scan_interval"
+	DOCUMENTS_EXPIRY_NOTICE        = "documents.expiry_notice"
+	DOCUMENTS_EXPORT_CACHE_TTL     = "documents.export_cache_ttl"
+	DOCUMENTS_EXPORT_CACHE_MAX     = "documents.export_cache_max_bytes"
+	DOCUMENTS_HISTORY_DEBOUNCE     = "documents.history_debounce"
+	DOCUMENTS_DRAFT_TTL            = "documents.draft_ttl"
+	DOCUMENTS_HISTORY_SNAPSHOTS    = "documents.history_snapshot_interval"
+
+	// Lint Configuration Keys, see lint.Rules
+	LINT_ON_SAVE = "lint.on_save"
+	LINT_RULES   = "lint.rules"
+
+	// Redaction Configuration Keys, see redact.Rules
+	REDACTION_ON_PUBLISH = "redaction.on_publish"
+	REDACTION_RULES      = "redaction.rules"
+
+	// HTML sanitization allowlist, see sanitize.Allowlist
+	SANITIZATION = "sanitization"
+
+	// WebSocket Configuration Keys
+	WS_CURSOR_TTL = "ws.cursor_ttl"
+
+	// Knowledge Base Configuration Keys
+	KB_CACHE_TTL = "kb.cache_ttl"
+
+	// Public directory Configuration Keys, see the directory package
+	DIRECTORY_BLOCKED_WORDS = "directory.blocked_words"
+
+	// Anonymous read API Configuration Keys
+	PUBLIC_CACHE_TTL = "public.cache_ttl"
+
+	// CDN Configuration Keys, see cdn.Config
+	CDN     = "cdn"
+	CDN_TTL = "cdn.ttl"
+
+	// Push Notification Configuration Keys, see provider.Config
+	PUSH = "push"
+
+	// Analytics Configuration Keys
+	ANALYTICS_HASH_IPS             = "analytics.hash_ips"
+	ANALYTICS_DIGEST_SCAN_INTERVAL = "analytics.digest_scan_interval"
+	// ANALYTICS_WAREHOUSE holds the sink settings, see warehouse.Config
+	ANALYTICS_WAREHOUSE            = "analytics.warehouse"
+	ANALYTICS_WAREHOUSE_INTERVAL   = "analytics.warehouse.interval"
+	ANALYTICS_WAREHOUSE_BATCH_SIZE = "analytics.warehouse.batch_size"
+	ANALYTICS_WAREHOUSE_GZIP       = "analytics.warehouse.gzip"
+
+	// Cross-region replication, see the replication package
+	REPLICATION_PUBLISH            = "replication.publish"
+	REPLICATION_PUBLISH_URL        = "replication.publish.url"
+	REPLICATION_PUBLISH_INTERVAL   = "replication.publish.interval"
+	REPLICATION_PUBLISH_BATCH_SIZE = "replication.publish.batch_size"
+	REPLICATION_APPLY_SECRET       = "replication.apply.secret"
+
+	// Security event stream, see the siem package and model.Sink
+	SIEM             = "siem"
+	SIEM_TRANSPORT   = "siem.transport"
+	SIEM_INTERVAL    = "siem.interval"
+	SIEM_BATCH_SIZE  = "siem.batch_size"
+	SIEM_MAX_BACKOFF = "siem.max_backoff"
+	SIEM_RETENTION   = "siem.retention"
+
+	// Startup warm-up, see app.WarmUp
+	WARMUP_ENABLED   = "warmup.enabled"
+	WARMUP_TIMEOUT   = "warmup.timeout"
+	WARMUP_WINDOW    = "warmup.window"
+	WARMUP_DOCUMENTS = "warmup.documents"
+
+	// Mail Configuration Keys, see mail.Config
+	MAIL = "mail"
+
 	// Rate Limit Configuration Keys
-	RATE_LIMIT_REQUESTS = "rate_limit.requests"
-	RATE_LIMIT_DURATION = "rate_limit.duration"
+	RATE_LIMIT_REQUESTS        = "rate_limit.requests"
+	RATE_LIMIT_DURATION        = "rate_limit.duration"
+	RATE_LIMIT_PUBLIC_REQUESTS = "rate_limit.public.requests"
+	RATE_LIMIT_PUBLIC_DURATION = "rate_limit.public.duration"
+	RATE_LIMIT_WARN_AT         = "rate_limit.warn_at"
 )
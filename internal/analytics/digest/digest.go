@@ -0,0 +1,92 @@
+// Package digest renders the weekly analytics email from digest stats.
+package digest
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+
+	"github.com/hafiztri123/document-api/internal/analytics/model"
+)
+
+// Email is a rendered digest
+type Email struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// data is what the templates see. Document is empty for a workspace
+// digest.
+type data struct {
+	Recipient string
+	Document  string
+	Stats     *model.DigestStats
+}
+
+const textTemplate = `Hi {{.Recipient}},
+
+Here is what happened to {{if .Document}}"{{.Document}}"{{else}}your documents{{end}} between {{.Stats.Since.Format "Jan 2"}} and {{.Stats.Until.Format "Jan 2"}}.
+
+Views: {{.Stats.Views}} ({{.Stats.AnonymousViews}} anonymous)
+Signed-in viewers: {{.Stats.UniqueViewers}}
+Edits: {{.Stats.Edits}}
+{{if .Stats.TopDocuments}}
+Most viewed documents:
+{{range .Stats.TopDocuments}}  - {{.Name}}: {{.Count}}
+{{end}}{{end}}{{if .Stats.TopViewers}}
+Top viewers:
+{{range .Stats.TopViewers}}  - {{.Name}}: {{.Count}}
+{{end}}{{end}}{{if .Stats.TopEditors}}
+Top editors:
+{{range .Stats.TopEditors}}  - {{.Name}}: {{.Count}}
+{{end}}{{end}}
+You receive this weekly because you subscribed to analytics digests.
+`
+
+const htmlTemplate = `<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif; line-height: 1.5;">
+<p>Hi {{.Recipient}},</p>
+<p>Here is what happened to {{if .Document}}<strong>{{.Document}}</strong>{{else}}your documents{{end}} between {{.Stats.Since.Format "Jan 2"}} and {{.Stats.Until.Format "Jan 2"}}.</p>
+<table cellpadding="4">
+<tr><td>Views</td><td><strong>{{.Stats.Views}}</strong> ({{.Stats.AnonymousViews}} anonymous)</td></tr>
+<tr><td>Signed-in viewers</td><td><strong>{{.Stats.UniqueViewers}}</strong></td></tr>
+<tr><td>Edits</td><td><strong>{{.Stats.Edits}}</strong></td></tr>
+</table>
+{{if .Stats.TopDocuments}}<h3>Most viewed documents</h3>
+<ol>{{range .Stats.TopDocuments}}<li>{{.Name}}: {{.Count}}</li>{{end}}</ol>
+{{end}}{{if .Stats.TopViewers}}<h3>Top viewers</h3>
+<ol>{{range .Stats.TopViewers}}<li>{{.Name}}: {{.Count}}</li>{{end}}</ol>
+{{end}}{{if .Stats.TopEditors}}<h3>Top editors</h3>
+<ol>{{range .Stats.TopEditors}}<li>{{.Name}}: {{.Count}}</li>{{end}}</ol>
+{{end}}<p style="color: #888;">You receive this weekly because you subscribed to analytics digests.</p>
+</body>
+</html>
+`
+
+var (
+	textEmail = texttemplate.Must(texttemplate.New("text").Parse(textTemplate))
+	htmlEmail = htmltemplate.Must(htmltemplate.New("html").Parse(htmlTemplate))
+)
+
+// Render builds the digest for recipient. document is the title of the
+// document the digest covers, or empty for a workspace digest.
+func Render(recipient, document string, stats *model.DigestStats) (*Email, error) {
+	d := data{Recipient: recipient, Document: document, Stats: stats}
+
+	var text, html bytes.Buffer
+	if err := textEmail.Execute(&text, d); err != nil {
+		return nil, err
+	}
+	if err := htmlEmail.Execute(&html, d); err != nil {
+		return nil, err
+	}
+
+	subject := "Your weekly document analytics"
+	if document != "" {
+		subject = "Weekly analytics for " + document
+	}
+
+	return &Email{Subject: subject, Text: text.String(), HTML: html.String()}, nil
+}
@@ -10,10 +10,14 @@ import (
 type DocumentView struct {
 	ID         uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
 	DocumentID uuid.UUID `gorm:"type:uuid;not null" json:"document_id"`
-	UserID     uuid.UUID `gorm:"type:uuid" json:"user_id"` 
-	IPAddress  string    `gorm:"type:varchar(45)" json:"ip_address"`
+	// UserID is nil for anonymous views of public documents
+	UserID     *uuid.UUID `gorm:"type:uuid" json:"user_id"`
+	// IPAddress is a salted hash when analytics.hash_ips is enabled
+	IPAddress  string    `gorm:"type:varchar(64)" json:"ip_address"`
 	UserAgent  string    `gorm:"type:varchar(255)" json:"user_agent"`
 	ViewedAt   time.Time `gorm:"not null" json:"viewed_at"`
+	// DeletedAt is set while the document is in the trash
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 func (dv *DocumentView) BeforeCreate(tx *gorm.DB) error {
@@ -29,6 +33,8 @@ type DocumentEdit struct {
 	UserID     uuid.UUID `gorm:"type:uuid;not null" json:"user_id"`
 	Version    int       `gorm:"not null" json:"version"`
 	EditedAt   time.Time `gorm:"not null" json:"edited_at"`
+	// DeletedAt is set while the document is in the trash
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 func (de *DocumentEdit) BeforeCreate(tx *gorm.DB) error {
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DigestPeriod is how often a digest is emailed
+const DigestPeriod = 7 * 24 * time.Hour
+
+// Digest is an opt-in weekly analytics email. It covers one document, or
+// every document the user owns when DocumentID is nil.
+type Digest struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID     uuid.UUID  `gorm:"type:uuid;not null" json:"user_id"`
+	DocumentID *uuid.UUID `gorm:"type:uuid" json:"document_id"`
+	// LastSentAt is when the last digest went out; the next one covers the
+	// activity since then
+	LastSentAt *time.Time `json:"last_sent_at"`
+	CreatedAt  time.Time  `gorm:"not null" json:"created_at"`
+}
+
+func (Digest) TableName() string {
+	return "analytics_digests"
+}
+
+func (d *Digest) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return nil
+}
+
+// DueDigest is a digest claimed for sending. Since is the start of the
+// period it reports on.
+type DueDigest struct {
+	Digest
+	Since time.Time
+}
+
+// DigestStats are the aggregates a digest reports
+type DigestStats struct {
+	Since          time.Time
+	Until          time.Time
+	Views          int64
+	AnonymousViews int64
+	UniqueViewers  int64
+	Edits          int64
+	TopViewers     []DigestCount
+	TopEditors     []DigestCount
+	// TopDocuments is only filled for workspace digests
+	TopDocuments []DigestCount
+}
+
+// DigestCount is one ranked row of a digest
+type DigestCount struct {
+	ID    uuid.UUID
+	Name  string
+	Count int64
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Streams of events exported to the data warehouse. Each is exported to
+// its own directory with its own checkpoint.
+const (
+	StreamDocumentViews = "document_views"
+	StreamDocumentEdits = "document_edits"
+)
+
+// WarehouseStreams lists every exported stream
+var WarehouseStreams = []string{StreamDocumentViews, StreamDocumentEdits}
+
+// WarehouseCheckpoint is how far a stream has been exported: every event
+// up to (LastAt, LastID) in that order has been. Both are nil before the
+// first export.
+type WarehouseCheckpoint struct {
+	Stream      string `gorm:"type:varchar(64);primary_key"`
+	LastAt      *time.Time
+	LastID      *uuid.UUID `gorm:"type:uuid"`
+	LockedUntil *time.Time
+	UpdatedAt   time.Time `gorm:"not null"`
+}
+
+// WarehouseEvent is one exported row. Version is only set on edits, and
+// IPAddress and UserAgent only on views; UserID is nil for anonymous views.
+type WarehouseEvent struct {
+	ID         uuid.UUID  `json:"id"`
+	Event      string     `json:"event"`
+	DocumentID uuid.UUID  `json:"document_id"`
+	UserID     *uuid.UUID `json:"user_id"`
+	OccurredAt time.Time  `json:"occurred_at"`
+	Version    *int       `json:"version,omitempty"`
+	IPAddress  string     `json:"ip_address,omitempty"`
+	UserAgent  string     `json:"user_agent,omitempty"`
+}
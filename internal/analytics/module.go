@@ -0,0 +1,97 @@
+package analytics
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"github.com/hafiztri123/document-api/config"
+	"github.com/hafiztri123/document-api/internal/analytics/repository"
+	"github.com/hafiztri123/document-api/internal/analytics/service"
+	"github.com/hafiztri123/document-api/internal/analytics/warehouse"
+	"github.com/hafiztri123/document-api/internal/logging"
+)
+
+// Module provides the analytics repository and service, and schedules the
+// data warehouse export
+var Module = fx.Module("analytics",
+	fx.Provide(
+		repository.NewAnalyticsRepository,
+		service.NewAnalyticsService,
+		newWarehouseSink,
+	),
+	fx.Invoke(startWarehouseExport),
+)
+
+// newWarehouseSink creates the sink configured under analytics.warehouse,
+// or nil when there is none
+func newWarehouseSink(logger *zap.Logger) warehouse.Sink {
+	var warehouseConfig warehouse.Config
+	if err := viper.UnmarshalKey(config.ANALYTICS_WAREHOUSE, &warehouseConfig); err != nil {
+		logger.Error("Invalid warehouse config, not exporting analytics", zap.Error(err))
+		return nil
+	}
+
+	sink, err := warehouse.NewSink(warehouseConfig, &http.Client{})
+	if err != nil {
+		logger.Error("Invalid warehouse config, not exporting analytics", zap.Error(err))
+		return nil
+	}
+	return sink
+}
+
+// startWarehouseExport exports new analytics events every
+// analytics.warehouse.interval, nightly by default, and once at startup.
+// Every instance runs it; each stream is leased to one instance at a time.
+func startWarehouseExport(lc fx.Lifecycle, svc service.Service, sink warehouse.Sink, logger *zap.Logger) {
+	if sink == nil {
+		return
+	}
+
+	interval, err := time.ParseDuration(viper.GetString(config.ANALYTICS_WAREHOUSE_INTERVAL))
+	if err != nil || interval <= 0 {
+		logger.Warn("Invalid analytics.warehouse.interval, using default 24h", zap.Error(err))
+		interval = 24 * time.Hour
+	}
+
+	logger = logger.With(zap.String("task", "warehouse_export"))
+	ctx, cancel := context.WithCancel(logging.NewContext(context.Background(), logger))
+	done := make(chan struct{})
+
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go func() {
+				defer close(done)
+
+				ticker := time.NewTicker(interval)
+				defer ticker.Stop()
+
+				for {
+					if exported, err := svc.ExportToWarehouse(ctx); err == nil && exported > 0 {
+						logger.Info("Exported analytics events to the warehouse", zap.Int("count", exported))
+					}
+
+					select {
+					case <-ctx.Done():
+						return
+					case <-ticker.C:
+					}
+				}
+			}()
+			return nil
+		},
+		OnStop: func(stopCtx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-stopCtx.Done():
+				return stopCtx.Err()
+			}
+		},
+	})
+}
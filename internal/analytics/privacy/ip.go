@@ -0,0 +1,23 @@
+package privacy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// SaltEnv names the environment variable holding the IP hashing salt
+const SaltEnv = "IP_HASH_SALT"
+
+// HashedIPLength is the length of a hashed address. Raw addresses are at
+// most 45 characters, so the two forms never collide.
+const HashedIPLength = sha256.Size * 2
+
+// HashIP returns a keyed hash of an IP address. The same address and salt
+// always produce the same hash, so unique-visitor counts keep working, but
+// the address cannot be recovered without the salt.
+func HashIP(salt, ip string) string {
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(ip))
+	return hex.EncodeToString(mac.Sum(nil))
+}
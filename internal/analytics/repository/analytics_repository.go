@@ -2,11 +2,16 @@ package repository
 
 import (
 	"context"
+	"os"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/hafiztri123/document-api/config"
 	"github.com/hafiztri123/document-api/internal/analytics/model"
+	"github.com/hafiztri123/document-api/internal/analytics/privacy"
 	documentModel "github.com/hafiztri123/document-api/internal/document/model"
+	"github.com/hafiztri123/document-api/internal/logging"
+	"github.com/spf13/viper"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
@@ -24,35 +29,77 @@ type Repository interface {
 	GetUserDocumentsAnalytics(ctx context.Context, userID uuid.UUID) (*model.UserDocumentsResponse, error)
 	GetUserActivityAnalytics(ctx context.Context, userID uuid.UUID, period string) (*model.UserActivityResponse, error)
 	GetUserMostActiveDocuments(ctx context.Context, userID uuid.UUID, limit int) ([]model.UserAnalyticsDocumentResponse, error)
+
+	// Weekly email digests
+	GetDigest(ctx context.Context, userID uuid.UUID, documentID *uuid.UUID) (*model.Digest, error)
+	CreateDigest(ctx context.Context, digest *model.Digest) error
+	DeleteDigest(ctx context.Context, userID uuid.UUID, documentID *uuid.UUID) error
+	ClaimDueDigests(ctx context.Context, now time.Time, limit int) ([]*model.DueDigest, error)
+	GetDigestStats(ctx context.Context, digest *model.Digest, since, until time.Time) (*model.DigestStats, error)
+
+	// Data warehouse export, see the warehouse package
+	ClaimWarehouseStream(ctx context.Context, stream string, now time.Time, lease time.Duration) (*model.WarehouseCheckpoint, error)
+	AdvanceWarehouseCheckpoint(ctx context.Context, stream string, lastAt time.Time, lastID uuid.UUID, lockedUntil time.Time) error
+	ReleaseWarehouseStream(ctx context.Context, stream string) error
+	GetWarehouseEvents(ctx context.Context, checkpoint *model.WarehouseCheckpoint, until time.Time, limit int) ([]*model.WarehouseEvent, error)
 }
 
 type analyticsRepository struct {
 	db *gorm.DB
 	logger *zap.Logger
+	// anonymizeIP transforms addresses before they are stored
+	anonymizeIP func(ip string) string
 }
 
 func NewAnalyticsRepository (db *gorm.DB, logger *zap.Logger) Repository {
 	return &analyticsRepository{
 		db: db,
 		logger: logger,
+		anonymizeIP: newIPAnonymizer(logger),
+	}
+}
+
+// newIPAnonymizer honours analytics.hash_ips. Without a salt a hash of an
+// IPv4 address is trivially reversible, so in that case no address is
+// stored at all.
+func newIPAnonymizer(logger *zap.Logger) func(string) string {
+	if !viper.GetBool(config.ANALYTICS_HASH_IPS) {
+		return func(ip string) string { return ip }
+	}
+
+	salt := os.Getenv(privacy.SaltEnv)
+	if salt == "" {
+		logger.Error("IP hashing is enabled but no salt is set; IP addresses will not be recorded",
+			zap.String("env", privacy.SaltEnv))
+		return func(string) string { return "" }
+	}
+
+	return func(ip string) string {
+		if ip == "" {
+			return ""
+		}
+		return privacy.HashIP(salt, ip)
 	}
 }
 
 
 
 	// Document view tracking
+// RecordDocumentView stores a view; uuid.Nil as userID records an anonymous one
 func (r *analyticsRepository) RecordDocumentView(ctx context.Context, documentID, userID uuid.UUID, ipAddress, userAgent string) error {
 	view := model.DocumentView {
 		DocumentID: documentID,
-		UserID: userID,
-		IPAddress: ipAddress,
+		IPAddress: r.anonymizeIP(ipAddress),
 		UserAgent: userAgent,
 		ViewedAt: time.Now(),
 	}
+	if userID != uuid.Nil {
+		view.UserID = &userID
+	}
 
 	err := r.db.WithContext(ctx).Create(&view).Error
 	if err != nil {
-		r.logger.Error("Failed to record document view", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to record document view", zap.Error(err))
 		return err
 	}
 
@@ -94,7 +141,7 @@ switch period {
 		Count(&response.Total).Error
 
 	if err != nil {
-		r.logger.Error("Failed to get total document views", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to get total document views", zap.Error(err))
 		return nil, err
 	}
 
@@ -104,7 +151,7 @@ switch period {
 		Where("document_id = ? AND viewed_at >= ? AND user_id IS NOT NULL", documentID, startTime).
 		Distinct("user_id").
 		Count(&response.UniqueUsers).Error; err != nil {
-			r.logger.Error("Failed to get unique users for document views", zap.Error(err))
+			logging.FromContext(ctx, r.logger).Error("Failed to get unique users for document views", zap.Error(err))
 			return nil, err
 		}
 
@@ -118,11 +165,11 @@ switch period {
 	if err := r.db.WithContext(ctx).Raw(`
 		SELECT TO_CHAR(viewed_at, ?) as date, COUNT(*) as count
 		FROM document_views
-		WHERE document_id = ? AND viewed_at = >= ?
+		WHERE document_id = ? AND viewed_at >= ? AND deleted_at IS NULL
 		GROUP BY date
 		ORDER BY date
 	`, groupFormat, documentID, startTime).Scan(&timelineResults).Error; err != nil {
-		r.logger.Error("Failed to get document views timeline", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to get document views timeline", zap.Error(err))
 		return nil, err
 	}
 
@@ -147,7 +194,7 @@ func (r *analyticsRepository)	RecordDocumentEdit(ctx context.Context, documentID
 	}
 
 	if err := r.db.WithContext(ctx).Create(&edit).Error; err != nil {
-		r.logger.Error("Failed to record document edit", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to record document edit", zap.Error(err))
 		return err
 	}
 
@@ -191,7 +238,7 @@ func (r *analyticsRepository)	GetDocumentEdits(ctx context.Context, documentID u
 	Model(&model.DocumentEdit{}).
 	Where("document_id = ? AND edited_at >= ?", documentID, startTime).
 	Count(&response.Total).Error; err != nil {
-		r.logger.Error("Failed to get total document edits", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to get total document edits", zap.Error(err))
 		return nil, err
 	} 
 
@@ -206,11 +253,11 @@ func (r *analyticsRepository)	GetDocumentEdits(ctx context.Context, documentID u
 		SELECT de.user_id, u.name as user_name, COUNT(*) as count
 		FROM document_edits de
 		JOIN users u ON de.user_id = u.id
-		WHERE de.document_id = ? AND de.edited_at >= ?
+		WHERE de.document_id = ? AND de.edited_at >= ? AND de.deleted_at IS NULL
 		GROUP BY de.user_id, u.name
 		ORDER BY count DESC
 	`, documentID, startTime).Scan(&userEditResults).Error; err != nil {
-		r.logger.Error("Failed to get document edits by user", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to get document edits by user", zap.Error(err))
 		return nil, err
 	}
 
@@ -235,11 +282,11 @@ func (r *analyticsRepository)	GetDocumentEdits(ctx context.Context, documentID u
 	if err := r.db.WithContext(ctx).Raw(`
 		SELECT TO_CHAR(edited_at, ?) as date, COUNT(*) as count
 		FROM document_edits
-		WHERE document_id = ? AND edited_at >= ?
+		WHERE document_id = ? AND edited_at >= ? AND deleted_at IS NULL
 		GROUP BY date
 		ORDER BY date
 	`, groupFormat, documentID, startTime).Scan(&timelineResults).Error; err != nil {
-		r.logger.Error("Failed to get document edits timeline", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to get document edits timeline", zap.Error(err))
 		return nil, err
 	}
 
@@ -265,7 +312,7 @@ func (r *analyticsRepository)	GetUserDocumentsAnalytics(ctx context.Context, use
 		Model(&documentModel.Document{}).
 		Where("owner_id = ?", userID).
 		Count(&docsCreated).Error; err != nil {
-		r.logger.Error("Failed to count user created documents", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to count user created documents", zap.Error(err))
 		return nil, err
 	}
 
@@ -275,7 +322,7 @@ func (r *analyticsRepository)	GetUserDocumentsAnalytics(ctx context.Context, use
 		Where("user_id = ?", userID).
 		Distinct("document_id").
 		Count(&docsCollaborated).Error; err != nil {
-		r.logger.Error("Failed to count user collaborated documents", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to count user collaborated documents", zap.Error(err))
 		return nil, err
 	}
 
@@ -323,7 +370,7 @@ func (r *analyticsRepository) GetUserActivityAnalytics(ctx context.Context, user
 		Model(&model.DocumentView{}).
 		Where("user_id = ? AND viewed_at >= ?", userID, startTime).
 		Count(&response.Views).Error; err != nil {
-		r.logger.Error("Failed to get total user views", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to get total user views", zap.Error(err))
 		return nil, err
 	}
 	
@@ -332,7 +379,7 @@ func (r *analyticsRepository) GetUserActivityAnalytics(ctx context.Context, user
 		Model(&model.DocumentEdit{}).
 		Where("user_id = ? AND edited_at >= ?", userID, startTime).
 		Count(&response.Edits).Error; err != nil {
-		r.logger.Error("Failed to get total user edits", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to get total user edits", zap.Error(err))
 		return nil, err
 	}
 	
@@ -356,18 +403,18 @@ func (r *analyticsRepository) GetUserActivityAnalytics(ctx context.Context, user
 		LEFT JOIN (
 			SELECT TO_CHAR(viewed_at, ?) as date, COUNT(*) as view_count
 			FROM document_views
-			WHERE user_id = ? AND viewed_at >= ?
+			WHERE user_id = ? AND viewed_at >= ? AND deleted_at IS NULL
 			GROUP BY date
 		) views ON dates.date = views.date
 		LEFT JOIN (
 			SELECT TO_CHAR(edited_at, ?) as date, COUNT(*) as edit_count
 			FROM document_edits
-			WHERE user_id = ? AND edited_at >= ?
+			WHERE user_id = ? AND edited_at >= ? AND deleted_at IS NULL
 			GROUP BY date
 		) edits ON dates.date = edits.date
 		ORDER BY dates.date
 	`, groupFormat, startTime, groupFormat, userID, startTime, groupFormat, userID, startTime).Scan(&timelineResults).Error; err != nil {
-		r.logger.Error("Failed to get user activity timeline", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to get user activity timeline", zap.Error(err))
 		return nil, err
 	}
 	
@@ -406,25 +453,26 @@ func (r *analyticsRepository)	GetUserMostActiveDocuments(ctx context.Context, us
 		LEFT JOIN (
 			SELECT document_id, COUNT(*) as view_count
 			FROM document_views
-			WHERE viewed_at >= NOW() - INTERVAL '30 days'
+			WHERE viewed_at >= NOW() - INTERVAL '30 days' AND deleted_at IS NULL
 			GROUP BY document_id
 		) v ON d.id = v.document_id
 		LEFT JOIN (
 			SELECT document_id, COUNT(*) as edit_count
 			FROM document_edits
-			WHERE edited_at >= NOW() - INTERVAL '30 days'
+			WHERE edited_at >= NOW() - INTERVAL '30 days' AND deleted_at IS NULL
 			GROUP BY document_id
 		) e ON d.id = e.document_id
-		WHERE d.owner_id = ? 
+		WHERE d.deleted_at IS NULL
+		  AND (d.owner_id = ?
 		   OR d.id IN (
 			SELECT document_id 
 			FROM collaborators 
-			WHERE user_id = ?
-		   )
+			WHERE user_id = ? AND deleted_at IS NULL
+		   ))
 		ORDER BY (COALESCE(v.view_count, 0) + COALESCE(e.edit_count, 0) * 2) DESC
 		LIMIT ?
 	`, userID, userID, limit).Scan(&response).Error; err != nil {
-		r.logger.Error("Failed to get user's most active documents", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to get user's most active documents", zap.Error(err))
 		return nil, err
 	}
 	
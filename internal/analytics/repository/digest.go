@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hafiztri123/document-api/internal/analytics/model"
+	"github.com/hafiztri123/document-api/internal/logging"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// digestTopLimit is how many rows each ranking in a digest lists
+const digestTopLimit = 5
+
+func (r *analyticsRepository) GetDigest(ctx context.Context, userID uuid.UUID, documentID *uuid.UUID) (*model.Digest, error) {
+	var digest model.Digest
+	err := r.digestQuery(ctx, userID, documentID).First(&digest).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get analytics digest", zap.Error(err))
+		return nil, err
+	}
+	return &digest, nil
+}
+
+func (r *analyticsRepository) CreateDigest(ctx context.Context, digest *model.Digest) error {
+	if err := r.db.WithContext(ctx).Create(digest).Error; err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to create analytics digest", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r *analyticsRepository) DeleteDigest(ctx context.Context, userID uuid.UUID, documentID *uuid.UUID) error {
+	if err := r.digestQuery(ctx, userID, documentID).Delete(&model.Digest{}).Error; err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to delete analytics digest", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r *analyticsRepository) digestQuery(ctx context.Context, userID uuid.UUID, documentID *uuid.UUID) *gorm.DB {
+	query := r.db.WithContext(ctx).Where("user_id = ?", userID)
+	if documentID != nil {
+		return query.Where("document_id = ?", *documentID)
+	}
+	return query.Where("document_id IS NULL")
+}
+
+// ClaimDueDigests marks up to limit digests whose period has ended as sent
+// and returns them with the start of that period. A digest is claimed
+// once, even with several instances scanning, and is not retried if
+// sending fails.
+func (r *analyticsRepository) ClaimDueDigests(ctx context.Context, now time.Time, limit int) ([]*model.DueDigest, error) {
+	var digests []*model.DueDigest
+	err := r.db.WithContext(ctx).Raw(`
+		UPDATE analytics_digests SET last_sent_at = ?
+		FROM (
+			SELECT id, COALESCE(last_sent_at, created_at) AS since FROM analytics_digests
+			WHERE COALESCE(last_sent_at, created_at) <= ?
+			ORDER BY COALESCE(last_sent_at, created_at)
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		) due
+		WHERE analytics_digests.id = due.id
+		RETURNING analytics_digests.*, due.since`, now, now.Add(-model.DigestPeriod), limit).
+		Scan(&digests).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to claim due analytics digests", zap.Error(err))
+		return nil, err
+	}
+	return digests, nil
+}
+
+// GetDigestStats aggregates the views and edits a digest covers between
+// since and until
+func (r *analyticsRepository) GetDigestStats(ctx context.Context, digest *model.Digest, since, until time.Time) (*model.DigestStats, error) {
+	// Workspace digests cover every document the user owns
+	var scopeArg interface{} = digest.UserID
+	scope := "document_id IN (SELECT id FROM documents WHERE owner_id = ? AND deleted_at IS NULL)"
+	if digest.DocumentID != nil {
+		scope, scopeArg = "document_id = ?", *digest.DocumentID
+	}
+
+	stats := &model.DigestStats{Since: since, Until: until}
+	db := r.db.WithContext(ctx)
+
+	err := db.Raw(`
+		SELECT COUNT(*) AS views,
+			COUNT(*) FILTER (WHERE user_id IS NULL) AS anonymous_views,
+			COUNT(DISTINCT user_id) AS unique_viewers
+		FROM document_views
+		WHERE `+scope+` AND viewed_at >= ? AND viewed_at < ? AND deleted_at IS NULL`, scopeArg, since, until).
+		Row().Scan(&stats.Views, &stats.AnonymousViews, &stats.UniqueViewers)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to count digest views", zap.Error(err))
+		return nil, err
+	}
+
+	err = db.Raw(`
+		SELECT COUNT(*) FROM document_edits
+		WHERE `+scope+` AND edited_at >= ? AND edited_at < ? AND deleted_at IS NULL`, scopeArg, since, until).
+		Row().Scan(&stats.Edits)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to count digest edits", zap.Error(err))
+		return nil, err
+	}
+
+	err = db.Raw(`
+		SELECT u.id, u.name, COUNT(*) AS count
+		FROM document_views v JOIN users u ON u.id = v.user_id
+		WHERE v.`+scope+` AND v.viewed_at >= ? AND v.viewed_at < ? AND v.deleted_at IS NULL
+		GROUP BY u.id, u.name
+		ORDER BY count DESC, u.name
+		LIMIT ?`, scopeArg, since, until, digestTopLimit).
+		Scan(&stats.TopViewers).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to rank digest viewers", zap.Error(err))
+		return nil, err
+	}
+
+	err = db.Raw(`
+		SELECT u.id, u.name, COUNT(*) AS count
+		FROM document_edits e JOIN users u ON u.id = e.user_id
+		WHERE e.`+scope+` AND e.edited_at >= ? AND e.edited_at < ? AND e.deleted_at IS NULL
+		GROUP BY u.id, u.name
+		ORDER BY count DESC, u.name
+		LIMIT ?`, scopeArg, since, until, digestTopLimit).
+		Scan(&stats.TopEditors).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to rank digest editors", zap.Error(err))
+		return nil, err
+	}
+
+	if digest.DocumentID == nil {
+		err = db.Raw(`
+			SELECT d.id, d.title AS name, COUNT(*) AS count
+			FROM document_views v JOIN documents d ON d.id = v.document_id
+			WHERE v.`+scope+` AND v.viewed_at >= ? AND v.viewed_at < ? AND v.deleted_at IS NULL
+			GROUP BY d.id, d.title
+			ORDER BY count DESC, d.title
+			LIMIT ?`, scopeArg, since, until, digestTopLimit).
+			Scan(&stats.TopDocuments).Error
+		if err != nil {
+			logging.FromContext(ctx, r.logger).Error("Failed to rank digest documents", zap.Error(err))
+			return nil, err
+		}
+	}
+
+	return stats, nil
+}
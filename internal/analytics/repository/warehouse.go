@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hafiztri123/document-api/internal/analytics/model"
+	"github.com/hafiztri123/document-api/internal/logging"
+	"go.uber.org/zap"
+)
+
+// warehouseQueries select a stream's events as model.WarehouseEvent rows,
+// with the column its events are ordered by
+var warehouseQueries = map[string]struct {
+	table, at, columns string
+}{
+	model.StreamDocumentViews: {
+		table:   "document_views",
+		at:      "viewed_at",
+		columns: "id, 'document.viewed' AS event, document_id, user_id, viewed_at AS occurred_at, ip_address, user_agent",
+	},
+	model.StreamDocumentEdits: {
+		table:   "document_edits",
+		at:      "edited_at",
+		columns: "id, 'document.edited' AS event, document_id, user_id, edited_at AS occurred_at, version",
+	},
+}
+
+// ClaimWarehouseStream leases a stream to the caller until now+lease and
+// returns its checkpoint, or nil while another instance holds the lease
+func (r *analyticsRepository) ClaimWarehouseStream(ctx context.Context, stream string, now time.Time, lease time.Duration) (*model.WarehouseCheckpoint, error) {
+	var checkpoints []*model.WarehouseCheckpoint
+	err := r.db.WithContext(ctx).Raw(`
+		INSERT INTO warehouse_checkpoints (stream, locked_until, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT (stream) DO UPDATE SET locked_until = EXCLUDED.locked_until, updated_at = EXCLUDED.updated_at
+		WHERE warehouse_checkpoints.locked_until IS NULL OR warehouse_checkpoints.locked_until < ?
+		RETURNING *`, stream, now.Add(lease), now, now).
+		Scan(&checkpoints).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to claim warehouse stream", zap.Error(err))
+		return nil, err
+	}
+	if len(checkpoints) == 0 {
+		return nil, nil
+	}
+	return checkpoints[0], nil
+}
+
+// AdvanceWarehouseCheckpoint records that the stream is exported up to
+// (lastAt, lastID) and extends the lease
+func (r *analyticsRepository) AdvanceWarehouseCheckpoint(ctx context.Context, stream string, lastAt time.Time, lastID uuid.UUID, lockedUntil time.Time) error {
+	err := r.db.WithContext(ctx).Model(&model.WarehouseCheckpoint{}).
+		Where("stream = ?", stream).
+		Updates(map[string]interface{}{
+			"last_at":      lastAt,
+			"last_id":      lastID,
+			"locked_until": lockedUntil,
+			"updated_at":   time.Now(),
+		}).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to advance warehouse checkpoint", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r *analyticsRepository) ReleaseWarehouseStream(ctx context.Context, stream string) error {
+	err := r.db.WithContext(ctx).Model(&model.WarehouseCheckpoint{}).
+		Where("stream = ?", stream).
+		Update("locked_until", nil).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to release warehouse stream", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetWarehouseEvents returns up to limit events of the stream after the
+// checkpoint and before until, in checkpoint order
+func (r *analyticsRepository) GetWarehouseEvents(ctx context.Context, checkpoint *model.WarehouseCheckpoint, until time.Time, limit int) ([]*model.WarehouseEvent, error) {
+	source, ok := warehouseQueries[checkpoint.Stream]
+	if !ok {
+		return nil, fmt.Errorf("unknown warehouse stream %q", checkpoint.Stream)
+	}
+
+	query := r.db.WithContext(ctx).Table(source.table).
+		Select(source.columns).
+		Where(source.at+" < ?", until)
+	if checkpoint.LastAt != nil && checkpoint.LastID != nil {
+		query = query.Where("("+source.at+", id) > (?, ?)", *checkpoint.LastAt, *checkpoint.LastID)
+	}
+
+	var events []*model.WarehouseEvent
+	err := query.Order(source.at + ", id").Limit(limit).Scan(&events).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get warehouse events", zap.Error(err))
+		return nil, err
+	}
+	return events, nil
+}
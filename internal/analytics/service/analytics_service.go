@@ -4,8 +4,12 @@ import (
 	"context"
 
 	"github.com/google/uuid"
+	"github.com/hafiztri123/document-api/config"
 	"github.com/hafiztri123/document-api/internal/analytics/model"
 	"github.com/hafiztri123/document-api/internal/analytics/repository"
+	"github.com/hafiztri123/document-api/internal/analytics/warehouse"
+	"github.com/hafiztri123/document-api/internal/logging"
+	"github.com/spf13/viper"
 	"go.uber.org/zap"
 )
 
@@ -16,16 +20,32 @@ type Service interface {
     RecordDocumentEdit(ctx context.Context, documentID, userID uuid.UUID, version int) error
     GetDocumentEdits(ctx context.Context, documentID uuid.UUID, period string) (*model.DocumentEditsResponse, error)
     GetUserAnalytics(ctx context.Context, userID uuid.UUID, period string) (*model.UserAnalyticsResponse, error)
+    // ExportToWarehouse exports the events recorded since the last export
+    // to the data warehouse and returns how many. It does nothing while no
+    // sink is configured under analytics.warehouse.
+    ExportToWarehouse(ctx context.Context) (int, error)
 }
 
 type analyticsService struct {
 	repo repository.Repository
+	warehouse warehouse.Sink
+	warehouseBatchSize int
+	warehouseGzip bool
 	logger *zap.Logger
 }
 
-func NewAnalyticsService(repo repository.Repository, logger *zap.Logger) Service {
+func NewAnalyticsService(repo repository.Repository, sink warehouse.Sink, logger *zap.Logger) Service {
+	batchSize := viper.GetInt(config.ANALYTICS_WAREHOUSE_BATCH_SIZE)
+	if batchSize <= 0 {
+		logger.Warn("Invalid analytics.warehouse.batch_size, using default 10000")
+		batchSize = 10000
+	}
+
 	return &analyticsService{
 		repo: repo,
+		warehouse: sink,
+		warehouseBatchSize: batchSize,
+		warehouseGzip: viper.GetBool(config.ANALYTICS_WAREHOUSE_GZIP),
 		logger: logger,
 	}
 }
@@ -52,19 +72,19 @@ func (s *analyticsService)    GetDocumentEdits(ctx context.Context, documentID u
 func (s *analyticsService)    GetUserAnalytics(ctx context.Context, userID uuid.UUID, period string) (*model.UserAnalyticsResponse, error){
 	documents, err := s.repo.GetUserDocumentsAnalytics(ctx, userID)
 	if err != nil {
-		s.logger.Error("Failed to get user document analytics", zap.Error(err))
+		logging.FromContext(ctx, s.logger).Error("Failed to get user document analytics", zap.Error(err))
 		documents = &model.UserDocumentsResponse{}
 	}
 
 	activity, err := s.repo.GetUserActivityAnalytics(ctx, userID, period)
 	if err != nil {
-		s.logger.Error("Failed to get user acitivty analytics", zap.Error(err))
+		logging.FromContext(ctx, s.logger).Error("Failed to get user acitivty analytics", zap.Error(err))
 		activity = &model.UserActivityResponse{}
 	}
 
 	mostActive, err := s.repo.GetUserMostActiveDocuments(ctx, userID, 5)
 	if err != nil {
-		s.logger.Error("Failed to get user's most active documents", zap.Error(err))
+		logging.FromContext(ctx, s.logger).Error("Failed to get user's most active documents", zap.Error(err))
 		mostActive = []model.UserAnalyticsDocumentResponse{}
 	}
 
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hafiztri123/document-api/internal/analytics/model"
+	"github.com/hafiztri123/document-api/internal/analytics/warehouse"
+	"github.com/hafiztri123/document-api/internal/logging"
+	"go.uber.org/zap"
+)
+
+// warehouseLease is how long a stream stays claimed by an export without
+// progress; each exported file renews it
+const warehouseLease = time.Hour
+
+// warehouseSettle holds back the most recent events, so one committed late
+// with an earlier timestamp is not skipped by the checkpoint
+const warehouseSettle = time.Minute
+
+func (s *analyticsService) ExportToWarehouse(ctx context.Context) (int, error) {
+	if s.warehouse == nil {
+		return 0, nil
+	}
+
+	exported := 0
+	for _, stream := range model.WarehouseStreams {
+		count, err := s.exportStream(ctx, stream)
+		exported += count
+		if err != nil {
+			return exported, err
+		}
+	}
+	return exported, nil
+}
+
+// exportStream exports a stream from its checkpoint in files of
+// warehouseBatchSize events, advancing the checkpoint after each file. A
+// file is named after its first event, so one re-exported after a failed
+// checkpoint replaces the earlier upload.
+func (s *analyticsService) exportStream(ctx context.Context, stream string) (int, error) {
+	now := time.Now()
+	checkpoint, err := s.repo.ClaimWarehouseStream(ctx, stream, now, warehouseLease)
+	if err != nil {
+		return 0, err
+	}
+	if checkpoint == nil {
+		// Another instance is exporting it
+		return 0, nil
+	}
+	defer func() {
+		_ = s.repo.ReleaseWarehouseStream(context.WithoutCancel(ctx), stream)
+	}()
+
+	until := now.Add(-warehouseSettle)
+	exported := 0
+	for {
+		events, err := s.repo.GetWarehouseEvents(ctx, checkpoint, until, s.warehouseBatchSize)
+		if err != nil {
+			return exported, err
+		}
+		if len(events) == 0 {
+			return exported, nil
+		}
+
+		first, last := events[0], events[len(events)-1]
+		at := first.OccurredAt.UTC()
+		name := fmt.Sprintf("%s/dt=%s/%s-%s", stream, at.Format("2006-01-02"), at.Format("20060102T150405Z"), first.ID)
+
+		file, err := warehouse.Encode(name, events, s.warehouseGzip)
+		if err != nil {
+			logging.FromContext(ctx, s.logger).Error("Failed to encode warehouse export", zap.Error(err))
+			return exported, err
+		}
+		if err := s.warehouse.Put(ctx, file); err != nil {
+			logging.FromContext(ctx, s.logger).Error("Failed to upload warehouse export",
+				zap.String("file", file.Name), zap.Error(err))
+			return exported, err
+		}
+
+		err = s.repo.AdvanceWarehouseCheckpoint(ctx, stream, last.OccurredAt, last.ID, time.Now().Add(warehouseLease))
+		if err != nil {
+			return exported, err
+		}
+		checkpoint.LastAt, checkpoint.LastID = &last.OccurredAt, &last.ID
+		exported += len(events)
+
+		if len(events) < s.warehouseBatchSize {
+			return exported, nil
+		}
+	}
+}
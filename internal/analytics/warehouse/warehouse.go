@@ -0,0 +1,180 @@
+// Package warehouse exports analytics events to a company's own data
+// warehouse. Events are written as newline-delimited JSON files, optionally
+// gzipped, and stored in an S3 bucket or POSTed to a webhook.
+package warehouse
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/hafiztri123/document-api/internal/analytics/model"
+	"github.com/hafiztri123/document-api/internal/signature"
+)
+
+// Sinks that may be set in Config.Sink
+const (
+	SinkS3      = "s3"
+	SinkWebhook = "webhook"
+)
+
+// uploadTimeout bounds a single upload
+const uploadTimeout = 2 * time.Minute
+
+// Config is read from analytics.warehouse. Only the settings of the chosen
+// sink are used.
+type Config struct {
+	// Sink is empty when events are not exported
+	Sink    string  `mapstructure:"sink"`
+	S3      S3      `mapstructure:"s3"`
+	Webhook Webhook `mapstructure:"webhook"`
+}
+
+// S3 stores files in a bucket. Endpoint is only set for S3-compatible
+// stores, which are addressed path-style. The keys default to
+// AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY.
+type S3 struct {
+	Bucket          string `mapstructure:"bucket"`
+	Region          string `mapstructure:"region"`
+	Prefix          string `mapstructure:"prefix"`
+	Endpoint        string `mapstructure:"endpoint"`
+	AccessKeyID     string `mapstructure:"access_key_id"`
+	SecretAccessKey string `mapstructure:"secret_access_key"`
+}
+
+// Webhook receives each file as the body of a POST, named by the
+// X-Warehouse-Object header and signed like outgoing webhooks when Secret
+// is set
+type Webhook struct {
+	URL    string `mapstructure:"url"`
+	Secret string `mapstructure:"secret"`
+}
+
+// File is an encoded batch of events. Name is a path relative to the
+// sink's root, e.g. "document_views/dt=2024-01-31/....ndjson.gz".
+type File struct {
+	Name            string
+	Body            []byte
+	ContentEncoding string
+}
+
+// ContentType of exported files
+const ContentType = "application/x-ndjson"
+
+// Sink stores exported files. Storing a file under an existing name
+// replaces it.
+type Sink interface {
+	Put(ctx context.Context, file File) error
+}
+
+// NewSink creates the configured sink. It returns nil when no sink is set
+// and fails when the sink's settings are missing.
+func NewSink(config Config, client *http.Client) (Sink, error) {
+	switch config.Sink {
+	case "":
+		return nil, nil
+	case SinkS3:
+		if config.S3.AccessKeyID == "" {
+			config.S3.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		}
+		if config.S3.SecretAccessKey == "" {
+			config.S3.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		}
+		if config.S3.Bucket == "" || config.S3.Region == "" {
+			return nil, fmt.Errorf("analytics.warehouse.s3 needs bucket and region")
+		}
+		if config.S3.AccessKeyID == "" || config.S3.SecretAccessKey == "" {
+			return nil, fmt.Errorf("analytics.warehouse.s3 needs access_key_id and secret_access_key")
+		}
+		return &s3Sink{config: config.S3, client: client}, nil
+	case SinkWebhook:
+		if config.Webhook.URL == "" {
+			return nil, fmt.Errorf("analytics.warehouse.webhook needs url")
+		}
+		return &webhook{config: config.Webhook, client: client}, nil
+	default:
+		return nil, fmt.Errorf("unknown warehouse sink %q", config.Sink)
+	}
+}
+
+// Encode writes events as newline-delimited JSON, one event per line, and
+// gzips the result when compress is set
+func Encode(name string, events []*model.WarehouseEvent, compress bool) (File, error) {
+	var buf bytes.Buffer
+	var w io.Writer = &buf
+
+	var zw *gzip.Writer
+	if compress {
+		zw = gzip.NewWriter(&buf)
+		w = zw
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			return File{}, err
+		}
+	}
+
+	file := File{Name: name + ".ndjson"}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return File{}, err
+		}
+		file.Name += ".gz"
+		file.ContentEncoding = "gzip"
+	}
+	file.Body = buf.Bytes()
+	return file, nil
+}
+
+type webhook struct {
+	config Webhook
+	client *http.Client
+}
+
+func (s *webhook) Put(ctx context.Context, file File) error {
+	ctx, cancel := context.WithTimeout(ctx, uploadTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.URL, bytes.NewReader(file.Body))
+	if err != nil {
+		return err
+	}
+	setContentHeaders(req, file)
+	req.Header.Set("X-Warehouse-Object", file.Name)
+	if s.config.Secret != "" {
+		req.Header.Set("X-Webhook-Signature", signature.Header(file.Body, time.Now(), s.config.Secret))
+	}
+
+	return send(s.client, req)
+}
+
+func setContentHeaders(req *http.Request, file File) {
+	req.Header.Set("Content-Type", ContentType)
+	if file.ContentEncoding != "" {
+		req.Header.Set("Content-Encoding", file.ContentEncoding)
+	}
+}
+
+// send fails on any non-2xx response
+func send(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("upload failed with status %d: %s", resp.StatusCode, snippet)
+	}
+	return nil
+}
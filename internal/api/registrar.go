@@ -0,0 +1,37 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/fx"
+)
+
+// Groups are the router groups a module may mount routes on
+type Groups struct {
+	// Root is the engine itself, for routes outside /api/v1 such as WebSockets
+	Root *gin.Engine
+	// Public is /api/v1 without authentication
+	Public *gin.RouterGroup
+	// Protected is /api/v1 behind authentication and rate limiting
+	Protected *gin.RouterGroup
+	// Admin is /api/v1/admin, limited to the users in admin.user_ids
+	Admin *gin.RouterGroup
+	// Anonymous is /api/v1/public without authentication, rate limited per
+	// client IP apart from authenticated traffic
+	Anonymous *gin.RouterGroup
+}
+
+// RouteRegistrar is implemented by each module that exposes HTTP routes
+type RouteRegistrar interface {
+	RegisterRoutes(groups Groups)
+}
+
+// AsRouteRegistrar annotates a constructor so its result is collected by
+// SetupRoutes. Modules provide their registrar with it instead of being
+// listed centrally.
+func AsRouteRegistrar(constructor interface{}) interface{} {
+	return fx.Annotate(
+		constructor,
+		fx.As(new(RouteRegistrar)),
+		fx.ResultTags(`group:"routes"`),
+	)
+}
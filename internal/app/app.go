@@ -0,0 +1,75 @@
+package app
+
+import (
+	"net/http"
+	"time"
+
+	"go.uber.org/fx"
+	"go.uber.org/fx/fxevent"
+	"go.uber.org/zap"
+
+	"github.com/hafiztri123/document-api/internal/analytics"
+	"github.com/hafiztri123/document-api/internal/api"
+	"github.com/hafiztri123/document-api/internal/auth"
+	"github.com/hafiztri123/document-api/internal/backup"
+	"github.com/hafiztri123/document-api/internal/directory"
+	"github.com/hafiztri123/document-api/internal/document"
+	"github.com/hafiztri123/document-api/internal/folder"
+	"github.com/hafiztri123/document-api/internal/job"
+	"github.com/hafiztri123/document-api/internal/kb"
+	"github.com/hafiztri123/document-api/internal/push"
+	"github.com/hafiztri123/document-api/internal/replication"
+	"github.com/hafiztri123/document-api/internal/siem"
+	"github.com/hafiztri123/document-api/internal/template"
+	"github.com/hafiztri123/document-api/internal/usage"
+	"github.com/hafiztri123/document-api/internal/webhook"
+	"github.com/hafiztri123/document-api/internal/ws"
+)
+
+// StopTimeout bounds how long shutdown hooks may run in total
+const StopTimeout = 10 * time.Second
+
+// Modules lists every domain module. A new subsystem registers itself by
+// exposing an fx.Module and adding it here.
+var Modules = fx.Options(
+	analytics.Module,
+	auth.Module,
+	backup.Module,
+	directory.Module,
+	document.Module,
+	folder.Module,
+	job.Module,
+	kb.Module,
+	push.Module,
+	replication.Module,
+	siem.Module,
+	template.Module,
+	usage.Module,
+	webhook.Module,
+	ws.Module,
+)
+
+// New builds the application container. The server is requested last, so
+// its stop hook runs first: in-flight requests drain before Redis and the
+// database are closed. Its start hook runs after the warm-up's.
+func New(logger *zap.Logger) *fx.App {
+	return fx.New(
+		fx.Supply(logger),
+		fx.WithLogger(func() fxevent.Logger {
+			return &fxevent.ZapLogger{Logger: logger.Named("fx")}
+		}),
+		fx.StopTimeout(StopTimeout),
+
+		fx.Provide(
+			NewDatabase,
+			NewRedisClient,
+			NewRouter,
+			NewServer,
+		),
+		Modules,
+
+		fx.Invoke(api.SetupRoutes),
+		fx.Invoke(WarmUp),
+		fx.Invoke(func(*http.Server) {}),
+	)
+}
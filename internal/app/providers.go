@@ -0,0 +1,143 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/hafiztri123/document-api/config"
+	"github.com/hafiztri123/document-api/internal/database"
+	"github.com/hafiztri123/document-api/internal/middleware"
+)
+
+// NewDatabase opens the database connection and closes it on shutdown
+func NewDatabase(lc fx.Lifecycle, logger *zap.Logger) (*gorm.DB, error) {
+	db, err := database.NewConnection(logger)
+	if err != nil {
+		return nil, fmt.Errorf("connect to database: %w", err)
+	}
+
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			sqlDB, err := db.DB()
+			if err != nil {
+				return err
+			}
+
+			logger.Info("Closing database connection")
+			return sqlDB.Close()
+		},
+	})
+
+	return db, nil
+}
+
+// NewRedisClient connects to Redis and closes the client on shutdown
+func NewRedisClient(lc fx.Lifecycle, logger *zap.Logger) (*redis.Client, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:         fmt.Sprintf("%s:%s", os.Getenv("REDISHOST"), os.Getenv("REDISPORT")),
+		Password:     os.Getenv("REDISPASSWORD"),
+		DB:           0,
+		MinIdleConns: viper.GetInt(config.REDIS_MIN_IDLE_CONNECTIONS),
+	})
+
+	if _, err := client.Ping(context.Background()).Result(); err != nil {
+		return nil, fmt.Errorf("connect to redis: %w", err)
+	}
+
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			logger.Info("Closing Redis connection")
+			return client.Close()
+		},
+	})
+
+	return client, nil
+}
+
+// NewRouter creates the Gin engine with the global middleware chain
+func NewRouter(logger *zap.Logger) *gin.Engine {
+	if viper.GetString(config.ENVIRONMENT) == config.ENV_PROD {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	router := gin.New()
+	router.Use(gin.Recovery())
+
+	// Request-scoped logger and access log
+	router.Use(middleware.RequestLoggerMiddleware(logger))
+
+	// Client details for security events
+	router.Use(middleware.SecuritySourceMiddleware())
+
+	// Setup CORS
+	router.Use(func(c *gin.Context) {
+		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
+		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Request-ID, X-Chaos, If-Match, If-None-Match")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "Location, ETag, Retry-After, X-Request-ID, X-Chaos-Fault, X-RateLimit-Limit, X-RateLimit-Used, X-RateLimit-Remaining, X-RateLimit-Reset, X-RateLimit-Warning, Deprecation, Sunset, Link")
+
+		if c.Request.Method == "OPTIONS" {
+			c.AbortWithStatus(204)
+			return
+		}
+
+		c.Next()
+	})
+
+	// Announce deprecated routes
+	router.Use(middleware.DeprecationMiddleware(logger))
+
+	// Fault injection for resilience testing, never in production
+	router.Use(middleware.ChaosMiddleware(logger))
+
+	return router
+}
+
+// NewServer binds the HTTP listener on start and drains in-flight
+// requests on stop
+func NewServer(lc fx.Lifecycle, router *gin.Engine, logger *zap.Logger) *http.Server {
+	srv := &http.Server{
+		Addr:    fmt.Sprintf(":%d", viper.GetInt(config.SERVER_PORT)),
+		Handler: router,
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			// Bind synchronously so a busy port fails startup
+			listener, err := net.Listen("tcp", srv.Addr)
+			if err != nil {
+				return err
+			}
+
+			logger.Info("Starting server",
+				zap.String("address", srv.Addr),
+				zap.String("environment", viper.GetString(config.ENVIRONMENT)))
+
+			go func() {
+				if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					logger.Fatal("Error starting server", zap.Error(err))
+				}
+			}()
+
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			logger.Info("Shutting down server...")
+			return srv.Shutdown(ctx)
+		},
+	})
+
+	return srv
+}
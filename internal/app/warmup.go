@@ -0,0 +1,143 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/hafiztri123/document-api/config"
+	documentService "github.com/hafiztri123/document-api/internal/document/service"
+	"github.com/hafiztri123/document-api/internal/logging"
+)
+
+// WarmUp readies a new instance for traffic before the server starts
+// listening: it opens the idle connections of the database and Redis
+// pools, then warms the access checks of recently active documents, see
+// documentService.Service.WarmUp. It is invoked before the server is
+// requested, so its start hook runs first. Failures are logged and never
+// stop startup.
+func WarmUp(lc fx.Lifecycle, db *gorm.DB, redisClient *redis.Client, documents documentService.Service, logger *zap.Logger) {
+	if !viper.GetBool(config.WARMUP_ENABLED) {
+		return
+	}
+
+	timeout, err := time.ParseDuration(viper.GetString(config.WARMUP_TIMEOUT))
+	if err != nil || timeout <= 0 {
+		logger.Warn("Invalid warmup.timeout, using default 10s", zap.Error(err))
+		timeout = 10 * time.Second
+	}
+
+	window, err := time.ParseDuration(viper.GetString(config.WARMUP_WINDOW))
+	if err != nil || window <= 0 {
+		logger.Warn("Invalid warmup.window, using default 24h", zap.Error(err))
+		window = 24 * time.Hour
+	}
+
+	logger = logger.With(zap.String("task", "warmup"))
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			ctx, cancel := context.WithTimeout(logging.NewContext(ctx, logger), timeout)
+			defer cancel()
+
+			start := time.Now()
+			dbConnections := viper.GetInt(config.DB_MAX_IDLE_CONNECTIONS)
+
+			var wg sync.WaitGroup
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				sqlDB, err := db.DB()
+				if err == nil {
+					err = warmDatabasePool(ctx, sqlDB, dbConnections)
+				}
+				if err != nil {
+					logger.Warn("Failed to warm up database pool", zap.Error(err))
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				if err := warmRedisPool(ctx, redisClient, viper.GetInt(config.REDIS_MIN_IDLE_CONNECTIONS)); err != nil {
+					logger.Warn("Failed to warm up Redis pool", zap.Error(err))
+				}
+			}()
+			wg.Wait()
+
+			checked, err := documents.WarmUp(ctx, start.Add(-window), viper.GetInt(config.WARMUP_DOCUMENTS), dbConnections)
+			if err != nil {
+				logger.Warn("Document warm-up did not finish", zap.Int("checked", checked), zap.Error(err))
+			}
+
+			logger.Info("Warm-up finished",
+				zap.Int("access_checks", checked),
+				zap.Duration("duration", time.Since(start)))
+			return nil
+		},
+	})
+}
+
+// warmDatabasePool opens n connections at once, so they are all new ones,
+// and returns them to the pool idle
+func warmDatabasePool(ctx context.Context, sqlDB *sql.DB, n int) error {
+	// Holding more than the pool allows would wait out the timeout
+	if open := sqlDB.Stats().MaxOpenConnections; open > 0 {
+		n = min(n, open)
+	}
+	conns := make([]*sql.Conn, max(n, 0))
+	errs := make([]error, len(conns))
+
+	var wg sync.WaitGroup
+	for i := range conns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := sqlDB.Conn(ctx)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			conns[i] = conn
+			errs[i] = conn.PingContext(ctx)
+		}()
+	}
+	wg.Wait()
+
+	for _, conn := range conns {
+		if conn != nil {
+			_ = conn.Close()
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// warmRedisPool holds n connections at once, dialling those the pool has
+// not opened yet, and returns them to the pool idle
+func warmRedisPool(ctx context.Context, client *redis.Client, n int) error {
+	n = min(n, client.Options().PoolSize)
+	conns := make([]*redis.Conn, max(n, 0))
+	errs := make([]error, len(conns))
+
+	var wg sync.WaitGroup
+	for i := range conns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conns[i] = client.Conn()
+			errs[i] = conns[i].Ping(ctx).Err()
+		}()
+	}
+	wg.Wait()
+
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
+	return errors.Join(errs...)
+}
@@ -1,13 +1,14 @@
 package controller
 
 import (
-	"context"
 	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/hafiztri123/document-api/internal/auth/service"
+	"github.com/hafiztri123/document-api/internal/errcode"
+	"github.com/hafiztri123/document-api/internal/logging"
 	"github.com/hafiztri123/document-api/internal/user/model"
 	"go.uber.org/zap"
 )
@@ -37,7 +38,7 @@ func (ctrl *authController) Register(ctx *gin.Context) {
 
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
-			"code":    "validation_error",
+			"code":    errcode.ValidationError,
 			"message": "Invalid request data",
 			"details": err.Error(),
 		}})
@@ -48,15 +49,15 @@ func (ctrl *authController) Register(ctx *gin.Context) {
 	if err != nil {
 		if errors.Is(err, service.ErrUserExists) {
 			ctx.JSON(http.StatusConflict, gin.H{"error": gin.H{
-				"code":    "conflict",
+				"code":    errcode.UserExists,
 				"message": "User already exists with this email",
 			}})
 			return
 		}
 
-		ctrl.logger.Error("Error registering user", zap.Error(err))
+		logging.FromContext(ctx.Request.Context(), ctrl.logger).Error("Error registering user", zap.Error(err))
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
-			"code":    "internal_error",
+			"code":    errcode.InternalError,
 			"message": "Failed to register user",
 		}})
 		return
@@ -70,7 +71,7 @@ func (ctrl *authController) Login(ctx *gin.Context) {
 
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
-			"code":    "validation_error",
+			"code":    errcode.ValidationError,
 			"message": "Invalid request data",
 			"details": err.Error(),
 		}})
@@ -81,15 +82,15 @@ func (ctrl *authController) Login(ctx *gin.Context) {
 	if err != nil {
 		if errors.Is(err, service.ErrInvalidCredentials) {
 			ctx.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
-				"code":    "unauthorized",
+				"code":    errcode.InvalidCredentials,
 				"message": "Invalid email or password",
 			}})
 			return
 		}
 
-		ctrl.logger.Error("Error logging in user", zap.Error(err))
+		logging.FromContext(ctx.Request.Context(), ctrl.logger).Error("Error logging in user", zap.Error(err))
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
-			"code":    "internal_error",
+			"code":    errcode.InternalError,
 			"message": "Failed to login",
 		}})
 		return
@@ -103,7 +104,7 @@ func (ctrl *authController) RefreshToken(ctx *gin.Context) {
 
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
-			"code":    "validation_error",
+			"code":    errcode.ValidationError,
 			"message": "Invalid request data",
 			"details": err.Error(),
 		}})
@@ -114,15 +115,15 @@ func (ctrl *authController) RefreshToken(ctx *gin.Context) {
 	if err != nil {
 		if errors.Is(err, service.ErrInvalidToken) {
 			ctx.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
-				"code":    "unauthorized",
+				"code":    errcode.InvalidToken,
 				"message": "Invalid or expired refresh token",
 			}})
 			return
 		}
 
-		ctrl.logger.Error("Error refreshing token", zap.Error(err))
+		logging.FromContext(ctx.Request.Context(), ctrl.logger).Error("Error refreshing token", zap.Error(err))
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
-			"code":    "internal_error",
+			"code":    errcode.InternalError,
 			"message": "Failed to refresh token",
 		}})
 		return
@@ -136,7 +137,7 @@ func (ctrl *authController) Logout(ctx *gin.Context) {
 
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
-			"code":    "validation_error",
+			"code":    errcode.ValidationError,
 			"message": "Invalid request data",
 			"details": err.Error(),
 		}})
@@ -144,9 +145,9 @@ func (ctrl *authController) Logout(ctx *gin.Context) {
 	}
 
 	if err := ctrl.service.Logout(ctx.Request.Context(), req.RefreshToken); err != nil {
-		ctrl.logger.Error("Error logging out user", zap.Error(err))
+		logging.FromContext(ctx.Request.Context(), ctrl.logger).Error("Error logging out user", zap.Error(err))
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
-			"code":    "internal_error",
+			"code":    errcode.InternalError,
 			"message": "Failed to logout",
 		}})
 		return
@@ -158,22 +159,22 @@ func (ctrl *authController) Logout(ctx *gin.Context) {
 func (ctrl *authController) GetProfile(ctx *gin.Context) {
 	userID, ok  := ctx.Get("userID")
 	if !ok {
-		ctrl.logger.Error("Error getting userID")
-		ctx.JSON(http.StatusNotFound, gin.H{
-			"code": "not_found",
+		logging.FromContext(ctx.Request.Context(), ctrl.logger).Error("Error getting userID")
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
 			"message": "Failed to get user ID",
-		})
+		}})
 		return		
 	}
 
 
-	user, err := ctrl.service.GetProfile(context.Background(), userID.(uuid.UUID))
+	user, err := ctrl.service.GetProfile(ctx.Request.Context(), userID.(uuid.UUID))
 	if err != nil {
-		ctrl.logger.Error("Error getting profile")
-		ctx.JSON(http.StatusNotFound, gin.H{
-			"code": "not_found",
+		logging.FromContext(ctx.Request.Context(), ctrl.logger).Error("Error getting profile")
+		ctx.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+			"code":    errcode.UserNotFound,
 			"message": "Failed to get profile",
-		})
+		}})
 		return
 	}
 
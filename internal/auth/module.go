@@ -0,0 +1,20 @@
+package auth
+
+import (
+	"go.uber.org/fx"
+
+	"github.com/hafiztri123/document-api/internal/api"
+	"github.com/hafiztri123/document-api/internal/auth/controller"
+	"github.com/hafiztri123/document-api/internal/auth/repository"
+	"github.com/hafiztri123/document-api/internal/auth/service"
+)
+
+// Module provides the auth repository, service, controller and routes
+var Module = fx.Module("auth",
+	fx.Provide(
+		repository.NewAuthRepository,
+		service.NewAuthService,
+		controller.NewAuthController,
+		api.AsRouteRegistrar(newRoutes),
+	),
+)
@@ -0,0 +1,26 @@
+package auth
+
+import (
+	"github.com/hafiztri123/document-api/internal/api"
+	"github.com/hafiztri123/document-api/internal/auth/controller"
+)
+
+type routes struct {
+	ctrl controller.Controller
+}
+
+func newRoutes(ctrl controller.Controller) *routes {
+	return &routes{ctrl: ctrl}
+}
+
+func (r *routes) RegisterRoutes(groups api.Groups) {
+	auth := groups.Public.Group("/auth")
+	{
+		auth.POST("/register", r.ctrl.Register)
+		auth.POST("/login", r.ctrl.Login)
+		auth.POST("/refresh", r.ctrl.RefreshToken)
+		auth.POST("/logout", r.ctrl.Logout)
+	}
+
+	groups.Protected.GET("/users/me", r.ctrl.GetProfile)
+}
@@ -11,6 +11,9 @@ import (
 	"github.com/google/uuid"
 	"github.com/hafiztri123/document-api/config"
 	"github.com/hafiztri123/document-api/internal/auth/repository"
+	"github.com/hafiztri123/document-api/internal/logging"
+	siemModel "github.com/hafiztri123/document-api/internal/siem/model"
+	siemService "github.com/hafiztri123/document-api/internal/siem/service"
 	"github.com/hafiztri123/document-api/internal/user/model"
 	"github.com/redis/go-redis/v9"
 	"github.com/spf13/viper"
@@ -41,13 +44,15 @@ type Claims struct {
 type authService struct {
 	repo repository.Repository
 	redis *redis.Client
+	security siemService.Service
 	logger *zap.Logger
 }
 
-func NewAuthService(repo repository.Repository, redis *redis.Client, logger *zap.Logger) Service {
+func NewAuthService(repo repository.Repository, redis *redis.Client, security siemService.Service, logger *zap.Logger) Service {
 	return &authService{
 		repo: repo,
 		redis: redis,
+		security: security,
 		logger: logger,
 	}
 }
@@ -55,7 +60,7 @@ func NewAuthService(repo repository.Repository, redis *redis.Client, logger *zap
 func (s *authService) Register(ctx context.Context, reg model.UserRegistration) (*model.UserResponse, error){
 	exisingUser, err := s.repo.FindUserByEmail(ctx, reg.Email)
 	if err != nil {
-		s.logger.Error("[ERROR] error finding user by email", zap.Error(err))
+		logging.FromContext(ctx, s.logger).Error("[ERROR] error finding user by email", zap.Error(err))
 		return nil, err
 	}
 
@@ -71,12 +76,12 @@ func (s *authService) Register(ctx context.Context, reg model.UserRegistration)
 	}
 
 	if err := user.SetPassword(reg.Password); err != nil {
-		s.logger.Error("[ERROR] error setting password", zap.Error(err))
+		logging.FromContext(ctx, s.logger).Error("[ERROR] error setting password", zap.Error(err))
 		return nil, err
 	}
 
 	if err := s.repo.CreateUser(ctx, user); err != nil {
-		s.logger.Error("Error creating user", zap.Error(err))
+		logging.FromContext(ctx, s.logger).Error("Error creating user", zap.Error(err))
 		return nil, err
 	}
 
@@ -91,21 +96,46 @@ func (s *authService) Register(ctx context.Context, reg model.UserRegistration)
 func (s *authService) Login(ctx context.Context, login model.UserLogin) (*model.TokenResponse, error){
 	user, err := s.repo.FindUserByEmail(ctx, login.Email)
 	if err != nil {
-		s.logger.Error("[ERROR] error finding user by email", zap.Error(err))
+		logging.FromContext(ctx, s.logger).Error("[ERROR] error finding user by email", zap.Error(err))
 		return nil, err
 	}
 
 	//Email is not registered to particular user
 	if user == nil {
+		s.recordLogin(ctx, nil, login.Email, "unknown_email")
 		return nil, ErrInvalidCredentials
 	}
 
 
 	if !user.CheckPassword(login.Password) {
+		s.recordLogin(ctx, &user.ID, login.Email, "wrong_password")
 		return nil, ErrInvalidCredentials
 	}
 
-	return s.generateTokens(ctx, user)
+	tokens, err := s.generateTokens(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordLogin(ctx, &user.ID, login.Email, "")
+	return tokens, nil
+}
+
+// recordLogin reports a login attempt to the SIEM; a failed one gives the
+// reason
+func (s *authService) recordLogin(ctx context.Context, userID *uuid.UUID, email, failure string) {
+	message := siemModel.Message{
+		Type:    siemModel.TypeLogin,
+		Outcome: siemModel.OutcomeSuccess,
+		UserID:  userID,
+		Data:    map[string]interface{}{"email": email},
+	}
+	if failure != "" {
+		message.Type = siemModel.TypeLoginFailed
+		message.Outcome = siemModel.OutcomeFailure
+		message.Data["reason"] = failure
+	}
+	s.security.Record(ctx, message)
 }
 
 func (s *authService) RefreshToken(ctx context.Context, refreshToken string) (*model.TokenResponse, error){
@@ -126,7 +156,7 @@ func (s *authService) RefreshToken(ctx context.Context, refreshToken string) (*m
 	key := fmt.Sprintf("refresh_token:%s", refreshToken)
 	exists, err := s.redis.Exists(ctx, key).Result()
 	if err != nil {
-		s.logger.Error("[ERROR] error checking token in redis", zap.Error(err))
+		logging.FromContext(ctx, s.logger).Error("[ERROR] error checking token in redis", zap.Error(err))
 		return nil, err
 	}
 	if exists == 0 {
@@ -135,7 +165,7 @@ func (s *authService) RefreshToken(ctx context.Context, refreshToken string) (*m
 
 	user, err := s.repo.FindUserByID(ctx, claims.UserID)
 	if err != nil {
-		s.logger.Error("[ERROR] error finding user by ID", zap.Error(err))
+		logging.FromContext(ctx, s.logger).Error("[ERROR] error finding user by ID", zap.Error(err))
 		return nil, err
 	}
 
@@ -145,7 +175,7 @@ func (s *authService) RefreshToken(ctx context.Context, refreshToken string) (*m
 
 	// avoid multiple active refresh token
 	if err := s.redis.Del(ctx, key).Err(); err != nil {
-		s.logger.Error("[ERROR] error deleting fresh token", zap.Error(err))
+		logging.FromContext(ctx, s.logger).Error("[ERROR] error deleting fresh token", zap.Error(err))
 		return nil, err
 	}
 
@@ -168,10 +198,16 @@ func (s *authService) Logout(ctx context.Context, refreshToken string) error {
 
 	key := fmt.Sprintf("refresh_token:%s", refreshToken)
 	if err := s.redis.Del(ctx, key).Err(); err != nil {
-		s.logger.Error("[ERROR] error deleting refresh token", zap.Error(err))
+		logging.FromContext(ctx, s.logger).Error("[ERROR] error deleting refresh token", zap.Error(err))
 		return err
 	}
 
+	s.security.Record(ctx, siemModel.Message{
+		Type:    siemModel.TypeLogout,
+		Outcome: siemModel.OutcomeSuccess,
+		UserID:  &claims.UserID,
+	})
+
 	return nil
 }
 
@@ -210,13 +246,13 @@ func (s *authService) generateTokens(ctx context.Context, user *model.User) (*mo
 
 	accessExpiry, err := time.ParseDuration(accessExpiryStr)
 	if err != nil {
-		s.logger.Warn("[WARN] invalid access_token_expiry, using default 15m", zap.Error(err))
+		logging.FromContext(ctx, s.logger).Warn("[WARN] invalid access_token_expiry, using default 15m", zap.Error(err))
 		accessExpiry = 15 * time.Minute
 	}
 
 	refreshExpiry, err := time.ParseDuration(refreshExpiryStr)
 	if err != nil {
-		s.logger.Warn("[WARN] invalid refresh_token_expiry, using default 7d", zap.Error(err))
+		logging.FromContext(ctx, s.logger).Warn("[WARN] invalid refresh_token_expiry, using default 7d", zap.Error(err))
 		refreshExpiry = 7 * 24 * time.Hour
 	}
 
@@ -233,7 +269,7 @@ func (s *authService) generateTokens(ctx context.Context, user *model.User) (*mo
 	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims)
 	accessTokenString, err := accessToken.SignedString([]byte(os.Getenv("JWT_SECRET")))
 	if err != nil {
-		s.logger.Error("[ERROR] error signing access token", zap.Error(err))
+		logging.FromContext(ctx, s.logger).Error("[ERROR] error signing access token", zap.Error(err))
 		return nil, err
 	}
 
@@ -250,14 +286,14 @@ func (s *authService) generateTokens(ctx context.Context, user *model.User) (*mo
 	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
 	refreshTokenString, err := refreshToken.SignedString([]byte(os.Getenv("JWT_SECRET")))
 	if err != nil {
-		s.logger.Error("[ERROR] error signing refresh token", zap.Error(err))
+		logging.FromContext(ctx, s.logger).Error("[ERROR] error signing refresh token", zap.Error(err))
 		return nil, err
 	}
 
 	//to keep track of active refresh token with redis
 	key := fmt.Sprintf("refresh_token:%s", refreshTokenString)
 	if err := s.redis.Set(ctx, key, user.ID.String(),refreshExpiry).Err(); err != nil {
-		s.logger.Error("[ERROR] error storing refresh token in redis", zap.Error(err))
+		logging.FromContext(ctx, s.logger).Error("[ERROR] error storing refresh token in redis", zap.Error(err))
 		return nil, err
 	}
 
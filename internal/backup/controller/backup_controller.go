@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/hafiztri123/document-api/internal/backup/model"
+	"github.com/hafiztri123/document-api/internal/backup/service"
+	"github.com/hafiztri123/document-api/internal/errcode"
+	"github.com/hafiztri123/document-api/internal/logging"
+)
+
+type Controller interface {
+	BackupWorkspace(c *gin.Context)
+	RestoreWorkspace(c *gin.Context)
+}
+
+type backupController struct {
+	service service.Service
+	logger  *zap.Logger
+}
+
+func NewBackupController(service service.Service, logger *zap.Logger) Controller {
+	return &backupController{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// BackupWorkspace downloads an archive of everything the user in the path
+// owns
+func (ctrl *backupController) BackupWorkspace(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid user ID",
+		}})
+		return
+	}
+
+	archive, err := ctrl.service.Backup(c.Request.Context(), userID)
+	if err != nil {
+		ctrl.handleError(c, err, "Failed to back up workspace")
+		return
+	}
+
+	body, err := json.Marshal(archive)
+	if err != nil {
+		ctrl.handleError(c, err, "Failed to back up workspace")
+		return
+	}
+
+	filename := fmt.Sprintf("workspace-%s-%s.json", userID, archive.CreatedAt.UTC().Format("20060102-150405"))
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	c.Data(http.StatusOK, "application/json", body)
+}
+
+// RestoreWorkspace restores an archive into the empty workspace of the user
+// in the path. With ?dry_run=true the archive is only validated.
+func (ctrl *backupController) RestoreWorkspace(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid user ID",
+		}})
+		return
+	}
+
+	var archive model.Archive
+	if err := c.ShouldBindJSON(&archive); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid request data",
+			"details": err.Error(),
+		}})
+		return
+	}
+
+	dryRun := c.Query("dry_run") == "true"
+	report, err := ctrl.service.Restore(c.Request.Context(), userID, &archive, dryRun)
+	if err != nil {
+		ctrl.handleError(c, err, "Failed to restore workspace")
+		return
+	}
+
+	status := http.StatusCreated
+	if dryRun {
+		status = http.StatusOK
+	}
+	c.JSON(status, report)
+}
+
+func (ctrl *backupController) handleError(c *gin.Context, err error, message string) {
+	var archiveErr *service.ArchiveError
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+			"code":    errcode.UserNotFound,
+			"message": "User not found",
+		}})
+	case errors.Is(err, service.ErrWorkspaceNotEmpty):
+		c.JSON(http.StatusConflict, gin.H{"error": gin.H{
+			"code":    errcode.WorkspaceNotEmpty,
+			"message": "The user already owns documents or folders",
+		}})
+	case errors.As(err, &archiveErr):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": gin.H{
+			"code":     errcode.InvalidArchive,
+			"message":  "The archive cannot be restored",
+			"problems": archiveErr.Problems,
+		}})
+	default:
+		logging.FromContext(c.Request.Context(), ctrl.logger).Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
+			"code":    errcode.InternalError,
+			"message": message,
+		}})
+	}
+}
@@ -0,0 +1,121 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	docModel "github.com/hafiztri123/document-api/internal/document/model"
+	folderModel "github.com/hafiztri123/document-api/internal/folder/model"
+)
+
+// ArchiveFormat is the version of the archive layout written by backups.
+// Restores reject archives of any other format.
+const ArchiveFormat = 1
+
+// Archive is a full backup of one user's workspace: the folders and
+// documents they own, with each document's history, collaborators and
+// encryption keys. Users lists everyone the archive refers to, so a restore
+// on another deployment can match them by email.
+type Archive struct {
+	Format    int               `json:"format"`
+	CreatedAt time.Time         `json:"created_at"`
+	OwnerID   uuid.UUID         `json:"owner_id"`
+	Users     []ArchiveUser     `json:"users"`
+	Folders   []ArchiveFolder   `json:"folders"`
+	Documents []ArchiveDocument `json:"documents"`
+}
+
+type ArchiveUser struct {
+	ID    uuid.UUID `json:"id"`
+	Email string    `json:"email"`
+	Name  string    `json:"name"`
+}
+
+type ArchiveFolder struct {
+	ID        uuid.UUID  `json:"id"`
+	ParentID  *uuid.UUID `json:"parent_id"`
+	Name      string     `json:"name"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+type ArchiveDocument struct {
+	ID           uuid.UUID             `json:"id"`
+	FolderID     *uuid.UUID            `json:"folder_id"`
+	Title        string                `json:"title"`
+	Language     *string               `json:"language"`
+	Content      string                `json:"content"`
+	Version      int                   `json:"version"`
+	IsPublic     bool                  `json:"is_public"`
+	State        docModel.State        `json:"state"`
+	Alias        *string               `json:"alias"`
+	ShortCode    string                `json:"short_code,omitempty"`
+	ReviewBy     *time.Time            `json:"review_by"`
+	ArchivedAt   *time.Time            `json:"archived_at"`
+	ExpiresAt    *time.Time            `json:"expires_at"`
+	ExpiryAction docModel.ExpiryAction `json:"expiry_action"`
+	Terms        *string               `json:"terms"`
+	Encrypted    bool                  `json:"encrypted"`
+	// AnalyticsPermission is left out of archives from before it existed
+	AnalyticsPermission docModel.Permission   `json:"analytics_permission,omitempty"`
+	CopiedFromID        *uuid.UUID            `json:"copied_from_id"`
+	CopiedFromVersion   *int                  `json:"copied_from_version"`
+	ForkedFromID        *uuid.UUID            `json:"forked_from_id,omitempty"`
+	ForkedFromVersion   *int                  `json:"forked_from_version,omitempty"`
+	CreatedAt           time.Time             `json:"created_at"`
+	UpdatedAt           time.Time             `json:"updated_at"`
+	History             []ArchiveVersion      `json:"history"`
+	Collaborators       []ArchiveCollaborator `json:"collaborators"`
+	Keys                []ArchiveKey          `json:"keys"`
+}
+
+type ArchiveVersion struct {
+	Version      int        `json:"version"`
+	Content      string     `json:"content"`
+	UpdatedByID  uuid.UUID  `json:"updated_by_id"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	MergedFromID *uuid.UUID `json:"merged_from_id"`
+	Summary      *string    `json:"summary"`
+	Label        *string    `json:"label,omitempty"`
+}
+
+type ArchiveCollaborator struct {
+	UserID     uuid.UUID           `json:"user_id"`
+	Permission docModel.Permission `json:"permission"`
+	CreatedAt  time.Time           `json:"created_at"`
+	UpdatedAt  time.Time           `json:"updated_at"`
+}
+
+type ArchiveKey struct {
+	UserID      uuid.UUID  `json:"user_id"`
+	Algorithm   string     `json:"algorithm"`
+	PublicKey   string     `json:"public_key"`
+	WrappedKey  *string    `json:"wrapped_key"`
+	WrappedByID *uuid.UUID `json:"wrapped_by_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Workspace is what a restore writes, with every ID already remapped
+type Workspace struct {
+	Folders       []*folderModel.Folder
+	Documents     []*docModel.Document
+	History       []*docModel.DocumentHistory
+	Collaborators []*docModel.Collaborator
+	Keys          []*docModel.DocumentKey
+	Links         []*docModel.DocumentLink
+}
+
+// RestoreReport describes a restore. IDs maps each archived folder and
+// document ID to the ID it was restored under. Warnings list what could not
+// be restored as archived, such as collaborators with no account here.
+type RestoreReport struct {
+	DryRun        bool                    `json:"dry_run"`
+	Folders       int                     `json:"folders"`
+	Documents     int                     `json:"documents"`
+	Versions      int                     `json:"versions"`
+	Collaborators int                     `json:"collaborators"`
+	Keys          int                     `json:"keys"`
+	IDs           map[uuid.UUID]uuid.UUID `json:"ids"`
+	Warnings      []string                `json:"warnings"`
+}
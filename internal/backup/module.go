@@ -0,0 +1,20 @@
+package backup
+
+import (
+	"go.uber.org/fx"
+
+	"github.com/hafiztri123/document-api/internal/api"
+	"github.com/hafiztri123/document-api/internal/backup/controller"
+	"github.com/hafiztri123/document-api/internal/backup/repository"
+	"github.com/hafiztri123/document-api/internal/backup/service"
+)
+
+// Module provides the backup repository, service, controller and routes
+var Module = fx.Module("backup",
+	fx.Provide(
+		repository.NewBackupRepository,
+		service.NewBackupService,
+		controller.NewBackupController,
+		api.AsRouteRegistrar(newRoutes),
+	),
+)
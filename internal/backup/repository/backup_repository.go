@@ -0,0 +1,277 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/hafiztri123/document-api/config"
+	"github.com/hafiztri123/document-api/internal/backup/model"
+	docModel "github.com/hafiztri123/document-api/internal/document/model"
+	folderModel "github.com/hafiztri123/document-api/internal/folder/model"
+	"github.com/hafiztri123/document-api/internal/logging"
+	userModel "github.com/hafiztri123/document-api/internal/user/model"
+)
+
+type Repository interface {
+	GetUserByID(ctx context.Context, id uuid.UUID) (*userModel.User, error)
+	GetUsersByID(ctx context.Context, ids []uuid.UUID) ([]*userModel.User, error)
+	GetUsersByEmail(ctx context.Context, emails []string) ([]*userModel.User, error)
+
+	// GetFolders returns every folder ownerID owns
+	GetFolders(ctx context.Context, ownerID uuid.UUID) ([]*folderModel.Folder, error)
+	// GetDocuments returns every document ownerID owns that is not deleted,
+	// with its history, every version in full, and collaborators
+	GetDocuments(ctx context.Context, ownerID uuid.UUID) ([]*docModel.Document, error)
+	GetDocumentKeys(ctx context.Context, documentIDs []uuid.UUID) ([]*docModel.DocumentKey, error)
+
+	// IsWorkspaceEmpty reports whether ownerID owns no folders and no
+	// documents that are not deleted
+	IsWorkspaceEmpty(ctx context.Context, ownerID uuid.UUID) (bool, error)
+	// GetExistingDocumentIDs returns which of ids are documents that are not
+	// deleted
+	GetExistingDocumentIDs(ctx context.Context, ids []uuid.UUID) ([]uuid.UUID, error)
+	// GetTakenAliases returns which of aliases other documents already use
+	GetTakenAliases(ctx context.Context, aliases []string) ([]string, error)
+	// GetTakenShortCodes returns which of codes other documents already
+	// use, including documents in the trash
+	GetTakenShortCodes(ctx context.Context, codes []string) ([]string, error)
+
+	// RestoreWorkspace writes a restored workspace in one transaction
+	RestoreWorkspace(ctx context.Context, workspace *model.Workspace) error
+}
+
+type backupRepository struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	// snapshotInterval is how often restored history keeps full content,
+	// see docModel.EncodeHistory
+	snapshotInterval int
+}
+
+func NewBackupRepository(db *gorm.DB, logger *zap.Logger) Repository {
+	snapshotInterval := viper.GetInt(config.DOCUMENTS_HISTORY_SNAPSHOTS)
+	if snapshotInterval < 1 {
+		snapshotInterval = docModel.DefaultHistorySnapshotInterval
+	}
+
+	return &backupRepository{
+		db:               db,
+		logger:           logger,
+		snapshotInterval: snapshotInterval,
+	}
+}
+
+func (r *backupRepository) GetUserByID(ctx context.Context, id uuid.UUID) (*userModel.User, error) {
+	var user userModel.User
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get user by ID", zap.Error(err))
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *backupRepository) GetUsersByID(ctx context.Context, ids []uuid.UUID) ([]*userModel.User, error) {
+	var users []*userModel.User
+	if len(ids) == 0 {
+		return users, nil
+	}
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&users).Error; err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get users by ID", zap.Error(err))
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *backupRepository) GetUsersByEmail(ctx context.Context, emails []string) ([]*userModel.User, error) {
+	var users []*userModel.User
+	if len(emails) == 0 {
+		return users, nil
+	}
+	if err := r.db.WithContext(ctx).Where("email IN ?", emails).Find(&users).Error; err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get users by email", zap.Error(err))
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *backupRepository) GetFolders(ctx context.Context, ownerID uuid.UUID) ([]*folderModel.Folder, error) {
+	var folders []*folderModel.Folder
+	err := r.db.WithContext(ctx).
+		Where("owner_id = ?", ownerID).
+		Order("created_at, id").
+		Find(&folders).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get folders for backup", zap.Error(err))
+		return nil, err
+	}
+	return folders, nil
+}
+
+func (r *backupRepository) GetDocuments(ctx context.Context, ownerID uuid.UUID) ([]*docModel.Document, error) {
+	var documents []*docModel.Document
+	err := r.db.WithContext(ctx).
+		Preload("History", func(db *gorm.DB) *gorm.DB { return db.Order("version") }).
+		Preload("Collaborators").
+		Where("owner_id = ?", ownerID).
+		Order("created_at, id").
+		Find(&documents).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get documents for backup", zap.Error(err))
+		return nil, err
+	}
+	for _, document := range documents {
+		history := make([]*docModel.DocumentHistory, len(document.History))
+		for i := range document.History {
+			history[i] = &document.History[i]
+		}
+		if err := docModel.RebuildHistory(history); err != nil {
+			logging.FromContext(ctx, r.logger).Error("Failed to rebuild history for backup", zap.Error(err))
+			return nil, err
+		}
+	}
+	return documents, nil
+}
+
+func (r *backupRepository) GetDocumentKeys(ctx context.Context, documentIDs []uuid.UUID) ([]*docModel.DocumentKey, error) {
+	var keys []*docModel.DocumentKey
+	if len(documentIDs) == 0 {
+		return keys, nil
+	}
+	if err := r.db.WithContext(ctx).Where("document_id IN ?", documentIDs).Find(&keys).Error; err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get document keys for backup", zap.Error(err))
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *backupRepository) IsWorkspaceEmpty(ctx context.Context, ownerID uuid.UUID) (bool, error) {
+	var documents, folders int64
+	if err := r.db.WithContext(ctx).Model(&docModel.Document{}).Where("owner_id = ?", ownerID).Count(&documents).Error; err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to count documents", zap.Error(err))
+		return false, err
+	}
+	if err := r.db.WithContext(ctx).Model(&folderModel.Folder{}).Where("owner_id = ?", ownerID).Count(&folders).Error; err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to count folders", zap.Error(err))
+		return false, err
+	}
+	return documents == 0 && folders == 0, nil
+}
+
+func (r *backupRepository) GetExistingDocumentIDs(ctx context.Context, ids []uuid.UUID) ([]uuid.UUID, error) {
+	var existing []uuid.UUID
+	if len(ids) == 0 {
+		return existing, nil
+	}
+	err := r.db.WithContext(ctx).Model(&docModel.Document{}).Where("id IN ?", ids).Pluck("id", &existing).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to check documents exist", zap.Error(err))
+		return nil, err
+	}
+	return existing, nil
+}
+
+func (r *backupRepository) GetTakenAliases(ctx context.Context, aliases []string) ([]string, error) {
+	var taken []string
+	if len(aliases) == 0 {
+		return taken, nil
+	}
+	err := r.db.WithContext(ctx).Model(&docModel.Document{}).Where("alias IN ?", aliases).Pluck("alias", &taken).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to check aliases", zap.Error(err))
+		return nil, err
+	}
+	return taken, nil
+}
+
+func (r *backupRepository) GetTakenShortCodes(ctx context.Context, codes []string) ([]string, error) {
+	var taken []string
+	if len(codes) == 0 {
+		return taken, nil
+	}
+	err := r.db.WithContext(ctx).Unscoped().Model(&docModel.Document{}).Where("short_code IN ?", codes).Pluck("short_code", &taken).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to check short codes", zap.Error(err))
+		return nil, err
+	}
+	return taken, nil
+}
+
+// RestoreWorkspace inserts parents before children. Hooks are skipped so
+// documents keep their archived version instead of starting over at 1.
+func (r *backupRepository) RestoreWorkspace(ctx context.Context, workspace *model.Workspace) error {
+	history := r.encodeHistory(workspace.History)
+
+	err := r.db.WithContext(ctx).Session(&gorm.Session{SkipHooks: true}).Transaction(func(tx *gorm.DB) error {
+		for _, folder := range workspace.Folders {
+			if err := tx.Create(folder).Error; err != nil {
+				return err
+			}
+		}
+		if len(workspace.Documents) > 0 {
+			if err := tx.Omit(clause.Associations).CreateInBatches(workspace.Documents, 100).Error; err != nil {
+				return err
+			}
+		}
+		if len(history) > 0 {
+			if err := tx.Omit(clause.Associations).CreateInBatches(history, 100).Error; err != nil {
+				return err
+			}
+		}
+		if len(workspace.Collaborators) > 0 {
+			if err := tx.Omit(clause.Associations).CreateInBatches(workspace.Collaborators, 100).Error; err != nil {
+				return err
+			}
+		}
+		if len(workspace.Keys) > 0 {
+			if err := tx.CreateInBatches(workspace.Keys, 100).Error; err != nil {
+				return err
+			}
+		}
+		if len(workspace.Links) > 0 {
+			if err := tx.CreateInBatches(workspace.Links, 100).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to restore workspace", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// encodeHistory returns restored history as it is stored, each document's
+// versions as snapshots and deltas
+func (r *backupRepository) encodeHistory(history []*docModel.DocumentHistory) []*docModel.DocumentHistory {
+	byDocument := make(map[uuid.UUID][]*docModel.DocumentHistory)
+	var documentIDs []uuid.UUID
+	for _, version := range history {
+		if _, ok := byDocument[version.DocumentID]; !ok {
+			documentIDs = append(documentIDs, version.DocumentID)
+		}
+		byDocument[version.DocumentID] = append(byDocument[version.DocumentID], version)
+	}
+
+	stored := make([]*docModel.DocumentHistory, 0, len(history))
+	for _, documentID := range documentIDs {
+		versions := byDocument[documentID]
+		slices.SortFunc(versions, func(a, b *docModel.DocumentHistory) int {
+			return cmp.Compare(a.Version, b.Version)
+		})
+		stored = append(stored, docModel.EncodeHistory(versions, r.snapshotInterval)...)
+	}
+	return stored
+}
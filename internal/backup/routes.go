@@ -0,0 +1,19 @@
+package backup
+
+import (
+	"github.com/hafiztri123/document-api/internal/api"
+	"github.com/hafiztri123/document-api/internal/backup/controller"
+)
+
+type routes struct {
+	ctrl controller.Controller
+}
+
+func newRoutes(ctrl controller.Controller) *routes {
+	return &routes{ctrl: ctrl}
+}
+
+func (r *routes) RegisterRoutes(groups api.Groups) {
+	groups.Admin.GET("/users/:user_id/backup", r.ctrl.BackupWorkspace)
+	groups.Admin.POST("/users/:user_id/restore", r.ctrl.RestoreWorkspace)
+}
@@ -0,0 +1,782 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/hafiztri123/document-api/internal/backup/model"
+	"github.com/hafiztri123/document-api/internal/backup/repository"
+	"github.com/hafiztri123/document-api/internal/document/links"
+	docModel "github.com/hafiztri123/document-api/internal/document/model"
+	"github.com/hafiztri123/document-api/internal/document/outline"
+	folderModel "github.com/hafiztri123/document-api/internal/folder/model"
+	"github.com/hafiztri123/document-api/internal/logging"
+)
+
+var (
+	ErrUserNotFound      = errors.New("user not found")
+	ErrWorkspaceNotEmpty = errors.New("workspace is not empty")
+)
+
+// ArchiveError lists every reason an archive cannot be restored
+type ArchiveError struct {
+	Problems []string
+}
+
+func (e *ArchiveError) Error() string {
+	return "invalid archive: " + strings.Join(e.Problems, "; ")
+}
+
+// uuidPattern finds IDs in content, so links between restored documents
+// can follow them to their new IDs
+var uuidPattern = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+
+type Service interface {
+	// Backup archives every folder and document userID owns
+	Backup(ctx context.Context, userID uuid.UUID) (*model.Archive, error)
+	// Restore recreates an archive in userID's workspace, which must be
+	// empty. Folders and documents get new IDs; other users are matched by
+	// email. With dryRun nothing is written and the report describes what a
+	// restore would do.
+	Restore(ctx context.Context, userID uuid.UUID, archive *model.Archive, dryRun bool) (*model.RestoreReport, error)
+}
+
+type backupService struct {
+	repo   repository.Repository
+	logger *zap.Logger
+}
+
+func NewBackupService(repo repository.Repository, logger *zap.Logger) Service {
+	return &backupService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+func (s *backupService) Backup(ctx context.Context, userID uuid.UUID) (*model.Archive, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	folders, err := s.repo.GetFolders(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	documents, err := s.repo.GetDocuments(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	documentIDs := make([]uuid.UUID, len(documents))
+	for i, document := range documents {
+		documentIDs[i] = document.ID
+	}
+	keys, err := s.repo.GetDocumentKeys(ctx, documentIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := &model.Archive{
+		Format:    model.ArchiveFormat,
+		CreatedAt: time.Now(),
+		OwnerID:   userID,
+		Users:     []model.ArchiveUser{},
+		Folders:   make([]model.ArchiveFolder, 0, len(folders)),
+		Documents: make([]model.ArchiveDocument, 0, len(documents)),
+	}
+
+	referenced := map[uuid.UUID]bool{userID: true}
+
+	for _, folder := range folders {
+		archive.Folders = append(archive.Folders, model.ArchiveFolder{
+			ID:        folder.ID,
+			ParentID:  folder.ParentID,
+			Name:      folder.Name,
+			CreatedAt: folder.CreatedAt,
+			UpdatedAt: folder.UpdatedAt,
+		})
+	}
+
+	keysByDocument := map[uuid.UUID][]model.ArchiveKey{}
+	for _, key := range keys {
+		keysByDocument[key.DocumentID] = append(keysByDocument[key.DocumentID], model.ArchiveKey{
+			UserID:      key.UserID,
+			Algorithm:   key.Algorithm,
+			PublicKey:   key.PublicKey,
+			WrappedKey:  key.WrappedKey,
+			WrappedByID: key.WrappedByID,
+			CreatedAt:   key.CreatedAt,
+			UpdatedAt:   key.UpdatedAt,
+		})
+		referenced[key.UserID] = true
+		if key.WrappedByID != nil {
+			referenced[*key.WrappedByID] = true
+		}
+	}
+
+	for _, document := range documents {
+		entry := model.ArchiveDocument{
+			ID:                  document.ID,
+			FolderID:            document.FolderID,
+			Title:               document.Title,
+			Language:            document.Language,
+			Content:             document.Content,
+			Version:             document.Version,
+			IsPublic:            document.IsPublic,
+			State:               document.State,
+			Alias:               document.Alias,
+			ShortCode:           document.ShortCode,
+			ReviewBy:            document.ReviewBy,
+			ArchivedAt:          document.ArchivedAt,
+			ExpiresAt:           document.ExpiresAt,
+			ExpiryAction:        document.ExpiryAction,
+			Terms:               document.Terms,
+			Encrypted:           document.Encrypted,
+			AnalyticsPermission: document.AnalyticsPermission,
+			CopiedFromID:        document.CopiedFromID,
+			CopiedFromVersion:   document.CopiedFromVersion,
+			ForkedFromID:        document.ForkedFromID,
+			ForkedFromVersion:   document.ForkedFromVersion,
+			CreatedAt:           document.CreatedAt,
+			UpdatedAt:           document.UpdatedAt,
+			History:             make([]model.ArchiveVersion, 0, len(document.History)),
+			Collaborators:       make([]model.ArchiveCollaborator, 0, len(document.Collaborators)),
+			Keys:                keysByDocument[document.ID],
+		}
+		if entry.Keys == nil {
+			entry.Keys = []model.ArchiveKey{}
+		}
+
+		for _, version := range document.History {
+			entry.History = append(entry.History, model.ArchiveVersion{
+				Version:      version.Version,
+				Content:      version.Content,
+				UpdatedByID:  version.UpdatedByID,
+				UpdatedAt:    version.UpdatedAt,
+				MergedFromID: version.MergedFromID,
+				Summary:      version.Summary,
+				Label:        version.Label,
+			})
+			referenced[version.UpdatedByID] = true
+		}
+
+		for _, collaborator := range document.Collaborators {
+			entry.Collaborators = append(entry.Collaborators, model.ArchiveCollaborator{
+				UserID:     collaborator.UserID,
+				Permission: collaborator.Permission.Normalize(),
+				CreatedAt:  collaborator.CreatedAt,
+				UpdatedAt:  collaborator.UpdatedAt,
+			})
+			referenced[collaborator.UserID] = true
+		}
+
+		archive.Documents = append(archive.Documents, entry)
+	}
+
+	userIDs := make([]uuid.UUID, 0, len(referenced))
+	for id := range referenced {
+		userIDs = append(userIDs, id)
+	}
+	users, err := s.repo.GetUsersByID(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		archive.Users = append(archive.Users, model.ArchiveUser{ID: user.ID, Email: user.Email, Name: user.Name})
+	}
+	slices.SortFunc(archive.Users, func(a, b model.ArchiveUser) int { return strings.Compare(a.Email, b.Email) })
+
+	logging.FromContext(ctx, s.logger).Info("Workspace backed up",
+		zap.String("user_id", userID.String()),
+		zap.Int("folders", len(archive.Folders)),
+		zap.Int("documents", len(archive.Documents)))
+
+	return archive, nil
+}
+
+func (s *backupService) Restore(ctx context.Context, userID uuid.UUID, archive *model.Archive, dryRun bool) (*model.RestoreReport, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	depths, problems := validate(archive)
+	if len(problems) > 0 {
+		return nil, &ArchiveError{Problems: problems}
+	}
+
+	empty, err := s.repo.IsWorkspaceEmpty(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !empty {
+		return nil, ErrWorkspaceNotEmpty
+	}
+
+	report := &model.RestoreReport{
+		DryRun:   dryRun,
+		IDs:      make(map[uuid.UUID]uuid.UUID, len(archive.Folders)+len(archive.Documents)),
+		Warnings: []string{},
+	}
+	warn := func(format string, args ...any) {
+		report.Warnings = append(report.Warnings, fmt.Sprintf(format, args...))
+	}
+
+	users, err := s.mapUsers(ctx, userID, archive)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, folder := range archive.Folders {
+		report.IDs[folder.ID] = uuid.New()
+	}
+	for _, document := range archive.Documents {
+		report.IDs[document.ID] = uuid.New()
+	}
+	restored := make(map[uuid.UUID]bool, len(archive.Documents))
+	for _, document := range archive.Documents {
+		restored[report.IDs[document.ID]] = true
+	}
+
+	// Rewrite content first, so the links it holds can be checked along
+	// with the other references to documents outside the archive
+	rewrite := func(content string) string {
+		return uuidPattern.ReplaceAllStringFunc(content, func(match string) string {
+			if id, err := uuid.Parse(match); err == nil {
+				if mapped, ok := report.IDs[id]; ok {
+					return mapped.String()
+				}
+			}
+			return match
+		})
+	}
+	contents := make([]string, len(archive.Documents))
+	refs := make([]links.References, len(archive.Documents))
+	var external []uuid.UUID
+	for i, document := range archive.Documents {
+		contents[i] = document.Content
+		if !document.Encrypted {
+			contents[i] = rewrite(document.Content)
+			refs[i] = links.Parse(contents[i])
+			for _, id := range refs[i].IDs {
+				if !restored[id] {
+					external = append(external, id)
+				}
+			}
+		}
+		if document.CopiedFromID != nil {
+			if _, ok := report.IDs[*document.CopiedFromID]; !ok {
+				external = append(external, *document.CopiedFromID)
+			}
+		}
+		if document.ForkedFromID != nil {
+			if _, ok := report.IDs[*document.ForkedFromID]; !ok {
+				external = append(external, *document.ForkedFromID)
+			}
+		}
+		for _, version := range document.History {
+			if version.MergedFromID != nil {
+				if _, ok := report.IDs[*version.MergedFromID]; !ok {
+					external = append(external, *version.MergedFromID)
+				}
+			}
+		}
+	}
+
+	existingIDs, err := s.repo.GetExistingDocumentIDs(ctx, external)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[uuid.UUID]bool, len(existingIDs))
+	for _, id := range existingIDs {
+		existing[id] = true
+	}
+	// reference maps an archived document ID to where it points after the
+	// restore, or nil when the document exists neither in the archive nor
+	// here
+	reference := func(id *uuid.UUID) *uuid.UUID {
+		if id == nil {
+			return nil
+		}
+		if mapped, ok := report.IDs[*id]; ok {
+			return &mapped
+		}
+		if existing[*id] {
+			return id
+		}
+		return nil
+	}
+
+	var aliases []string
+	for _, document := range archive.Documents {
+		if document.Alias != nil {
+			aliases = append(aliases, *document.Alias)
+		}
+	}
+	takenAliases, err := s.repo.GetTakenAliases(ctx, aliases)
+	if err != nil {
+		return nil, err
+	}
+	taken := make(map[string]bool, len(takenAliases))
+	for _, alias := range takenAliases {
+		taken[alias] = true
+	}
+
+	shortCodes, err := s.shortCodes(ctx, archive, warn)
+	if err != nil {
+		return nil, err
+	}
+
+	workspace := &model.Workspace{}
+
+	folders := slices.Clone(archive.Folders)
+	slices.SortStableFunc(folders, func(a, b model.ArchiveFolder) int { return depths[a.ID] - depths[b.ID] })
+	for _, folder := range folders {
+		workspace.Folders = append(workspace.Folders, &folderModel.Folder{
+			ID:        report.IDs[folder.ID],
+			OwnerID:   userID,
+			ParentID:  reference(folder.ParentID),
+			Name:      folder.Name,
+			CreatedAt: folder.CreatedAt,
+			UpdatedAt: folder.UpdatedAt,
+		})
+	}
+
+	byAlias := map[string]uuid.UUID{}
+	for _, document := range archive.Documents {
+		if document.Alias == nil {
+			continue
+		}
+		if taken[*document.Alias] {
+			warn("alias %q of document %s is taken, restored without it", *document.Alias, document.ID)
+			continue
+		}
+		byAlias[*document.Alias] = report.IDs[document.ID]
+	}
+
+	for i, document := range archive.Documents {
+		id := report.IDs[document.ID]
+
+		restoredDocument := &docModel.Document{
+			ID:                  id,
+			Title:               document.Title,
+			ShortCode:           shortCodes[i],
+			Language:            document.Language,
+			Content:             contents[i],
+			Version:             document.Version,
+			IsPublic:            document.IsPublic,
+			OwnerID:             userID,
+			FolderID:            reference(document.FolderID),
+			ReviewBy:            document.ReviewBy,
+			State:               document.State,
+			ArchivedAt:          document.ArchivedAt,
+			ExpiresAt:           document.ExpiresAt,
+			ExpiryAction:        document.ExpiryAction,
+			Terms:               document.Terms,
+			Encrypted:           document.Encrypted,
+			AnalyticsPermission: document.AnalyticsPermission,
+			CopiedFromID:        reference(document.CopiedFromID),
+			ForkedFromID:        reference(document.ForkedFromID),
+			CreatedAt:           document.CreatedAt,
+			UpdatedAt:           document.UpdatedAt,
+		}
+		if restoredDocument.ExpiryAction == "" {
+			restoredDocument.ExpiryAction = docModel.ExpiryArchive
+		}
+		restoredDocument.AnalyticsPermission = restoredDocument.AnalyticsAccessOf()
+		if restoredDocument.Language != nil {
+			canonical, _ := docModel.CanonicalLanguage(*restoredDocument.Language)
+			restoredDocument.Language = &canonical
+		}
+		if document.Alias != nil && !taken[*document.Alias] {
+			restoredDocument.Alias = document.Alias
+		}
+		if restoredDocument.CopiedFromID != nil {
+			restoredDocument.CopiedFromVersion = document.CopiedFromVersion
+		} else if document.CopiedFromID != nil {
+			warn("document %s was copied from %s, which does not exist here", document.ID, *document.CopiedFromID)
+		}
+		if restoredDocument.ForkedFromID != nil {
+			restoredDocument.ForkedFromVersion = document.ForkedFromVersion
+		} else if document.ForkedFromID != nil {
+			warn("document %s was forked from %s, which does not exist here", document.ID, *document.ForkedFromID)
+		}
+		if document.Terms != nil && *document.Terms != "" {
+			hash := docModel.HashTerms(*document.Terms)
+			restoredDocument.TermsHash = &hash
+		}
+		if !document.Encrypted {
+			restoredDocument.Outline = outline.Rebase(nil, restoredDocument.Content)
+		}
+		workspace.Documents = append(workspace.Documents, restoredDocument)
+
+		for _, version := range document.History {
+			updatedBy, ok := users[version.UpdatedByID]
+			if !ok {
+				updatedBy = userID
+				warn("version %d of document %s was saved by %s, who has no account here; attributed to the owner", version.Version, document.ID, version.UpdatedByID)
+			}
+			content := version.Content
+			if !document.Encrypted {
+				content = rewrite(content)
+			}
+			workspace.History = append(workspace.History, &docModel.DocumentHistory{
+				ID:           uuid.New(),
+				DocumentID:   id,
+				Version:      version.Version,
+				Content:      content,
+				UpdatedByID:  updatedBy,
+				UpdatedAt:    version.UpdatedAt,
+				MergedFromID: reference(version.MergedFromID),
+				Summary:      version.Summary,
+				Label:        version.Label,
+			})
+		}
+
+		for _, collaborator := range document.Collaborators {
+			collaboratorID, ok := users[collaborator.UserID]
+			if !ok {
+				warn("collaborator %s of document %s has no account here, skipped", collaborator.UserID, document.ID)
+				continue
+			}
+			if collaboratorID == userID {
+				continue
+			}
+			workspace.Collaborators = append(workspace.Collaborators, &docModel.Collaborator{
+				ID:         uuid.New(),
+				DocumentID: id,
+				UserID:     collaboratorID,
+				Permission: collaborator.Permission.Normalize(),
+				CreatedAt:  collaborator.CreatedAt,
+				UpdatedAt:  collaborator.UpdatedAt,
+			})
+		}
+
+		for _, key := range document.Keys {
+			keyUserID, ok := users[key.UserID]
+			if !ok {
+				warn("key of %s for document %s has no account here, skipped", key.UserID, document.ID)
+				continue
+			}
+			restoredKey := &docModel.DocumentKey{
+				DocumentID: id,
+				UserID:     keyUserID,
+				Algorithm:  key.Algorithm,
+				PublicKey:  key.PublicKey,
+				CreatedAt:  key.CreatedAt,
+				UpdatedAt:  key.UpdatedAt,
+			}
+			// A wrapped key is only kept with the member who wrapped it, so
+			// clients can still check where it came from
+			if key.WrappedKey != nil && key.WrappedByID != nil {
+				if wrappedBy, ok := users[*key.WrappedByID]; ok {
+					restoredKey.WrappedKey = key.WrappedKey
+					restoredKey.WrappedByID = &wrappedBy
+				}
+			}
+			workspace.Keys = append(workspace.Keys, restoredKey)
+		}
+
+		if document.Encrypted {
+			continue
+		}
+		seen := map[uuid.UUID]bool{id: true}
+		targets := make([]uuid.UUID, 0, len(refs[i].IDs)+len(refs[i].Aliases))
+		for _, target := range refs[i].IDs {
+			if restored[target] || existing[target] {
+				targets = append(targets, target)
+			}
+		}
+		for _, alias := range refs[i].Aliases {
+			if target, ok := byAlias[alias]; ok {
+				targets = append(targets, target)
+			}
+		}
+		for _, target := range targets {
+			if seen[target] {
+				continue
+			}
+			seen[target] = true
+			workspace.Links = append(workspace.Links, &docModel.DocumentLink{
+				SourceID:  id,
+				TargetID:  target,
+				CreatedAt: time.Now(),
+			})
+		}
+	}
+
+	report.Folders = len(workspace.Folders)
+	report.Documents = len(workspace.Documents)
+	report.Versions = len(workspace.History)
+	report.Collaborators = len(workspace.Collaborators)
+	report.Keys = len(workspace.Keys)
+
+	if dryRun {
+		return report, nil
+	}
+
+	if err := s.repo.RestoreWorkspace(ctx, workspace); err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx, s.logger).Info("Workspace restored",
+		zap.String("user_id", userID.String()),
+		zap.Int("folders", report.Folders),
+		zap.Int("documents", report.Documents),
+		zap.Int("warnings", len(report.Warnings)))
+
+	return report, nil
+}
+
+// mapUsers maps archived user IDs to users here, matching them by email.
+// The archive's owner always maps to the user restoring it.
+func (s *backupService) mapUsers(ctx context.Context, userID uuid.UUID, archive *model.Archive) (map[uuid.UUID]uuid.UUID, error) {
+	emails := make([]string, 0, len(archive.Users))
+	for _, user := range archive.Users {
+		emails = append(emails, user.Email)
+	}
+	found, err := s.repo.GetUsersByEmail(ctx, emails)
+	if err != nil {
+		return nil, err
+	}
+	byEmail := make(map[string]uuid.UUID, len(found))
+	for _, user := range found {
+		byEmail[user.Email] = user.ID
+	}
+
+	users := map[uuid.UUID]uuid.UUID{archive.OwnerID: userID}
+	for _, user := range archive.Users {
+		if id, ok := byEmail[user.Email]; ok && user.ID != archive.OwnerID {
+			users[user.ID] = id
+		}
+	}
+	return users, nil
+}
+
+// maxShortCodeAttempts bounds the random codes tried for one document
+const maxShortCodeAttempts = 10
+
+// shortCodes returns the short code each archived document is restored
+// with: its archived code while no document here uses it, a new one
+// otherwise. Archives written before short codes get new codes throughout.
+func (s *backupService) shortCodes(ctx context.Context, archive *model.Archive, warn func(format string, args ...any)) ([]string, error) {
+	archived := make([]string, len(archive.Documents))
+	var candidates []string
+	for i, document := range archive.Documents {
+		if code, ok := docModel.NormalizeShortCode(document.ShortCode); ok {
+			archived[i] = code
+			candidates = append(candidates, code)
+		}
+	}
+	takenCodes, err := s.repo.GetTakenShortCodes(ctx, candidates)
+	if err != nil {
+		return nil, err
+	}
+	taken := make(map[string]bool, len(takenCodes))
+	for _, code := range takenCodes {
+		taken[code] = true
+	}
+
+	codes := make([]string, len(archive.Documents))
+	for i, document := range archive.Documents {
+		if archived[i] != "" && !taken[archived[i]] {
+			codes[i] = archived[i]
+			taken[archived[i]] = true
+		}
+		if codes[i] == "" && document.ShortCode != "" {
+			warn("short code %q of document %s is taken, restored with a new one", document.ShortCode, document.ID)
+		}
+	}
+
+	for i := range codes {
+		for n := 0; codes[i] == ""; n++ {
+			if n == maxShortCodeAttempts {
+				return nil, fmt.Errorf("no free short code after %d attempts", maxShortCodeAttempts)
+			}
+			code, err := docModel.NewShortCode()
+			if err != nil {
+				return nil, err
+			}
+			if taken[code] {
+				continue
+			}
+			found, err := s.repo.GetTakenShortCodes(ctx, []string{code})
+			if err != nil {
+				return nil, err
+			}
+			if len(found) == 0 {
+				codes[i] = code
+			}
+			taken[code] = true
+		}
+	}
+	return codes, nil
+}
+
+// validate checks that an archive is complete and consistent, and returns
+// the depth of each folder, so parents can be restored before children
+func validate(archive *model.Archive) (map[uuid.UUID]int, []string) {
+	var problems []string
+	add := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if archive.Format != model.ArchiveFormat {
+		add("unsupported archive format %d, expected %d", archive.Format, model.ArchiveFormat)
+		return nil, problems
+	}
+	if archive.OwnerID == uuid.Nil {
+		add("owner_id is missing")
+	}
+
+	seenUsers := map[uuid.UUID]bool{}
+	for i, user := range archive.Users {
+		if user.ID == uuid.Nil || user.Email == "" {
+			add("users[%d] needs an id and an email", i)
+		}
+		if seenUsers[user.ID] {
+			add("user %s is listed twice", user.ID)
+		}
+		seenUsers[user.ID] = true
+	}
+
+	seen := map[uuid.UUID]bool{}
+	folders := make(map[uuid.UUID]*uuid.UUID, len(archive.Folders))
+	for i, folder := range archive.Folders {
+		if folder.ID == uuid.Nil {
+			add("folders[%d] has no id", i)
+			continue
+		}
+		if seen[folder.ID] {
+			add("id %s is used twice", folder.ID)
+		}
+		seen[folder.ID] = true
+		folders[folder.ID] = folder.ParentID
+		if folder.Name == "" || len([]rune(folder.Name)) > 255 {
+			add("folder %s needs a name of at most 255 characters", folder.ID)
+		}
+	}
+
+	depths := make(map[uuid.UUID]int, len(folders))
+	for _, folder := range archive.Folders {
+		id := folder.ID
+		depth := 0
+		for current := folder.ParentID; current != nil; current = folders[*current] {
+			if _, ok := folders[*current]; !ok {
+				add("folder %s is in folder %s, which is not in the archive", id, *current)
+				break
+			}
+			depth++
+			if depth > len(folders) {
+				add("folder %s is inside itself", id)
+				break
+			}
+		}
+		depths[id] = depth
+	}
+
+	for i, document := range archive.Documents {
+		if document.ID == uuid.Nil {
+			add("documents[%d] has no id", i)
+			continue
+		}
+		if seen[document.ID] {
+			add("id %s is used twice", document.ID)
+		}
+		seen[document.ID] = true
+
+		if document.Title == "" || len([]rune(document.Title)) > 255 {
+			add("document %s needs a title of at most 255 characters", document.ID)
+		}
+		if document.FolderID != nil {
+			if _, ok := folders[*document.FolderID]; !ok {
+				add("document %s is in folder %s, which is not in the archive", document.ID, *document.FolderID)
+			}
+		}
+		if document.Version < 1 {
+			add("document %s has version %d", document.ID, document.Version)
+		}
+		if !document.State.Valid() {
+			add("document %s has unknown state %q", document.ID, document.State)
+		}
+		switch document.ExpiryAction {
+		case "", docModel.ExpiryArchive, docModel.ExpiryDelete:
+		default:
+			add("document %s has unknown expiry action %q", document.ID, document.ExpiryAction)
+		}
+		switch document.AnalyticsPermission {
+		case "", docModel.PermissionViewer, docModel.PermissionEditor, docModel.PermissionAdmin:
+		default:
+			add("document %s has unknown analytics permission %q", document.ID, document.AnalyticsPermission)
+		}
+		if document.Language != nil {
+			if _, ok := docModel.CanonicalLanguage(*document.Language); !ok {
+				add("document %s has invalid language %q", document.ID, *document.Language)
+			}
+		}
+		if document.Encrypted && document.IsPublic {
+			add("document %s is encrypted and public", document.ID)
+		}
+
+		versions := map[int]bool{}
+		labels := map[string]bool{}
+		for _, version := range document.History {
+			if version.Version < 1 || version.Version > document.Version || versions[version.Version] {
+				add("document %s has a duplicate or out of range version %d", document.ID, version.Version)
+			}
+			versions[version.Version] = true
+
+			if version.Label != nil {
+				label := *version.Label
+				if strings.TrimSpace(label) != label || label == "" || len([]rune(label)) > 100 || strings.Contains(label, "/") || labels[label] {
+					add("document %s has a duplicate or invalid version label %q", document.ID, label)
+				}
+				labels[label] = true
+			}
+		}
+
+		collaborators := map[uuid.UUID]bool{}
+		for _, collaborator := range document.Collaborators {
+			if !collaborator.Permission.Allows(docModel.PermissionViewer) {
+				add("collaborator %s of document %s has unknown permission %q", collaborator.UserID, document.ID, collaborator.Permission)
+			}
+			if collaborators[collaborator.UserID] {
+				add("collaborator %s of document %s is listed twice", collaborator.UserID, document.ID)
+			}
+			collaborators[collaborator.UserID] = true
+		}
+
+		keys := map[uuid.UUID]bool{}
+		for _, key := range document.Keys {
+			if !document.Encrypted {
+				add("document %s has keys but is not encrypted", document.ID)
+				break
+			}
+			if key.Algorithm == "" || key.PublicKey == "" {
+				add("key of %s for document %s needs an algorithm and a public key", key.UserID, document.ID)
+			}
+			if keys[key.UserID] {
+				add("key of %s for document %s is listed twice", key.UserID, document.ID)
+			}
+			keys[key.UserID] = true
+		}
+	}
+
+	return depths, problems
+}
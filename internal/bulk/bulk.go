@@ -0,0 +1,89 @@
+package bulk
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hafiztri123/document-api/internal/errcode"
+)
+
+// MaxItems is the maximum number of items accepted by a single batch request
+const MaxItems = 100
+
+// ItemResult is the outcome of a single item within a batch request
+type ItemResult struct {
+	Index   int          `json:"index"`
+	ID      string       `json:"id,omitempty"`
+	Status  int          `json:"status"`
+	Code    errcode.Code `json:"code,omitempty"`
+	Message string       `json:"message,omitempty"`
+	Data    interface{}  `json:"data,omitempty"`
+}
+
+// Summary counts the outcomes of a batch request
+type Summary struct {
+	Total     int `json:"total"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+}
+
+// Response is the multi-status body returned by batch endpoints
+type Response struct {
+	Summary Summary      `json:"summary"`
+	Results []ItemResult `json:"results"`
+}
+
+// Builder collects per-item results of a batch operation in request order
+type Builder struct {
+	results []ItemResult
+}
+
+func NewBuilder(capacity int) *Builder {
+	return &Builder{
+		results: make([]ItemResult, 0, capacity),
+	}
+}
+
+// Success records a successful item; data is optional
+func (b *Builder) Success(id string, status int, data interface{}) {
+	b.results = append(b.results, ItemResult{
+		Index:  len(b.results),
+		ID:     id,
+		Status: status,
+		Data:   data,
+	})
+}
+
+// Failure records a failed item with its error code
+func (b *Builder) Failure(id string, status int, code errcode.Code, message string) {
+	b.results = append(b.results, ItemResult{
+		Index:   len(b.results),
+		ID:      id,
+		Status:  status,
+		Code:    code,
+		Message: message,
+	})
+}
+
+// Build returns the response body with its summary
+func (b *Builder) Build() Response {
+	summary := Summary{Total: len(b.results)}
+	for _, result := range b.results {
+		if result.Status >= 200 && result.Status < 300 {
+			summary.Succeeded++
+		} else {
+			summary.Failed++
+		}
+	}
+
+	return Response{
+		Summary: summary,
+		Results: b.results,
+	}
+}
+
+// Write sends the response as 207 Multi-Status; clients must inspect
+// every item's status rather than the HTTP status of the batch
+func (b *Builder) Write(c *gin.Context) {
+	c.JSON(http.StatusMultiStatus, b.Build())
+}
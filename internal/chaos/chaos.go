@@ -0,0 +1,103 @@
+// Package chaos injects faults so clients can be tested against slow
+// responses, server errors and lost websocket messages. It is configured
+// under "chaos" and never runs in production.
+package chaos
+
+import (
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/hafiztri123/document-api/config"
+)
+
+// Header opts a request in to faults when Config.RequireHeader is set, so
+// one client team's tests do not disturb everyone else on a shared
+// environment
+const Header = "X-Chaos"
+
+// FaultHeader names the fault injected into a response, so clients can
+// tell injected failures from real ones
+const FaultHeader = "X-Chaos-Fault"
+
+// Config is read from the "chaos" key. Rates are probabilities between 0
+// and 1 applied independently to each request or message.
+type Config struct {
+	Enabled bool `mapstructure:"enabled"`
+	// RequireHeader limits HTTP faults to requests sending "X-Chaos: on"
+	RequireHeader bool `mapstructure:"require_header"`
+	// Paths are the request path prefixes faults apply to
+	Paths []string `mapstructure:"paths"`
+	// Latency is the longest delay added; each delay is picked uniformly
+	// between zero and Latency
+	Latency     time.Duration `mapstructure:"latency"`
+	LatencyRate float64       `mapstructure:"latency_rate"`
+	ErrorRate   float64       `mapstructure:"error_rate"`
+	// WSDropRate is the share of outgoing websocket messages discarded
+	WSDropRate float64 `mapstructure:"ws_drop_rate"`
+}
+
+// Load reads the configuration. It is disabled in production whatever the
+// config says, and when the config is invalid.
+func Load(logger *zap.Logger) Config {
+	var cfg Config
+	if err := viper.UnmarshalKey(config.CHAOS, &cfg); err != nil {
+		logger.Error("Invalid chaos config, disabling fault injection", zap.Error(err))
+		return Config{}
+	}
+
+	if !cfg.Enabled {
+		return Config{}
+	}
+
+	if viper.GetString(config.ENVIRONMENT) == config.ENV_PROD {
+		logger.Warn("Fault injection is never enabled in production, ignoring chaos.enabled")
+		return Config{}
+	}
+
+	return cfg
+}
+
+// Applies reports whether HTTP faults may be injected into a request
+func (c Config) Applies(path, header string) bool {
+	if !c.Enabled {
+		return false
+	}
+	if c.RequireHeader && !strings.EqualFold(header, "on") {
+		return false
+	}
+	if len(c.Paths) == 0 {
+		return true
+	}
+	for _, prefix := range c.Paths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Delay returns the latency to add to a request, zero for none
+func (c Config) Delay() time.Duration {
+	if c.Latency <= 0 || !roll(c.LatencyRate) {
+		return 0
+	}
+	return rand.N(c.Latency) + 1
+}
+
+// Fail reports whether a request should fail with a server error
+func (c Config) Fail() bool {
+	return roll(c.ErrorRate)
+}
+
+// DropMessage reports whether an outgoing websocket message should be lost
+func (c Config) DropMessage() bool {
+	return c.Enabled && roll(c.WSDropRate)
+}
+
+func roll(rate float64) bool {
+	return rate > 0 && rand.Float64() < rate
+}
@@ -15,8 +15,8 @@ import (
 	"gorm.io/gorm/logger"
 )
 
-func NewConnection() (*gorm.DB, error) {
-	db, err := gorm.Open(postgres.Open(createDataSource()), createGormConfig())
+func NewConnection(zapLogger *zap.Logger) (*gorm.DB, error) {
+	db, err := gorm.Open(postgres.Open(createDataSource(zapLogger)), createGormConfig())
 	if err != nil {
 		return nil, err
 	}
@@ -26,19 +26,19 @@ func NewConnection() (*gorm.DB, error) {
 		return nil, err
 	}
 
-	setConnectionPool(sqlDB)
+	setConnectionPool(sqlDB, zapLogger)
 
 
 	return db, nil
 }
 
-func setConnectionPool(sqlDB *sql.DB) {
+func setConnectionPool(sqlDB *sql.DB, zapLogger *zap.Logger) {
 	sqlDB.SetMaxIdleConns(viper.GetInt(config.DB_MAX_IDLE_CONNECTIONS))
 	sqlDB.SetMaxOpenConns(viper.GetInt(config.DB_MAX_OPEN_CONNECTIONS))
 
 	maxLifetime, err := time.ParseDuration(viper.GetString(config.DB_CONNECTION_MAX_LIFETIME))
 	if err != nil {
-		zap.L().Warn("Invalid connection_max_lifetime, using default 1h", zap.Error(err))
+		zapLogger.Warn("Invalid connection_max_lifetime, using default 1h", zap.Error(err))
 		maxLifetime = time.Hour
 	}
 	sqlDB.SetConnMaxLifetime(maxLifetime)
@@ -57,29 +57,39 @@ func createGormConfig() *gorm.Config {
 
 }
 
-func createDataSource() string {
+func createDataSource(zapLogger *zap.Logger) string {
     dsn := fmt.Sprintf(
         "host=%s port=%d user=%s password=%s dbname=%s sslmode=require",
         os.Getenv("PGHOST"),       // Railway provides PGHOST for PostgreSQL host
-        GetEnvAsInt("PGPORT", 5432), // Default port is 5432 if not set
+        GetEnvAsInt("PGPORT", 5432, zapLogger), // Default port is 5432 if not set
         os.Getenv("PGUSER"),       // Railway provides PGUSER for PostgreSQL username
         os.Getenv("PGPASSWORD"),   // Railway provides PGPASSWORD for PostgreSQL password
         os.Getenv("PGDATABASE"),   // Railway provides PGDATABASE for PostgreSQL database name
     )
 
+    // Writes made through the API feed the replication change stream while
+    // it is published, see capture_replication_change
+    if viper.GetString(config.REPLICATION_PUBLISH_URL) != "" {
+        dsn += " options='-c document_api.capture_changes=on'"
+    }
+
     return dsn
 }
 
 
 
-func GetEnvAsInt(key string, defaultValue int) int {
+func GetEnvAsInt(key string, defaultValue int, zapLogger *zap.Logger) int {
     value := os.Getenv(key)
     if value == "" {
         return defaultValue
     }
     parsedValue, err := strconv.Atoi(value)
     if err != nil {
-        zap.L().Warn(fmt.Sprintf("Invalid value for %s, using default", key), zap.Error(err))
+        zapLogger.Warn("Invalid environment value, using default",
+            zap.String("key", key),
+            zap.Int("default", defaultValue),
+            zap.Error(err),
+        )
         return defaultValue
     }
     return parsedValue
@@ -0,0 +1,88 @@
+// Package dbtest gives tests a scratch PostgreSQL database with the
+// migrations applied. Tests using it are skipped unless TEST_DATABASE_URL is
+// set to the postgres:// URL of a server the tests may create databases on.
+package dbtest
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Open creates a database for the test, migrates it and drops it once the
+// test is done
+func Open(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	serverURL := os.Getenv("TEST_DATABASE_URL")
+	if serverURL == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	server := connect(t, serverURL)
+	name := "document_api_test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if err := server.Exec("CREATE DATABASE " + name).Error; err != nil {
+		t.Fatalf("create database: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := server.Exec("DROP DATABASE IF EXISTS " + name + " WITH (FORCE)").Error; err != nil {
+			t.Errorf("drop database: %v", err)
+		}
+		closeDB(server)
+	})
+
+	databaseURL, err := url.Parse(serverURL)
+	if err != nil {
+		t.Fatalf("parse TEST_DATABASE_URL: %v", err)
+	}
+	databaseURL.Path = "/" + name
+
+	m, err := migrate.New("file://"+migrationsPath(), databaseURL.String())
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		t.Fatalf("migrate: %v", err)
+	}
+	m.Close()
+
+	db := connect(t, databaseURL.String())
+	t.Cleanup(func() { closeDB(db) })
+	return db
+}
+
+func connect(t *testing.T, dsn string) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	return db
+}
+
+func closeDB(db *gorm.DB) {
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.Close()
+	}
+}
+
+// migrationsPath is the migrations directory at the root of the module
+func migrationsPath() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "..", "migrations")
+}
@@ -0,0 +1,261 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/hafiztri123/document-api/internal/directory/model"
+	"github.com/hafiztri123/document-api/internal/directory/service"
+	"github.com/hafiztri123/document-api/internal/errcode"
+	"github.com/hafiztri123/document-api/internal/logging"
+)
+
+type Controller interface {
+	GetCategories(c *gin.Context)
+	CreateCategory(c *gin.Context)
+	UpdateCategory(c *gin.Context)
+	DeleteCategory(c *gin.Context)
+	GetListings(c *gin.Context)
+	CurateDocument(c *gin.Context)
+
+	GetTaxonomy(c *gin.Context)
+	Browse(c *gin.Context)
+}
+
+type directoryController struct {
+	service service.Service
+	logger  *zap.Logger
+}
+
+func NewDirectoryController(service service.Service, logger *zap.Logger) Controller {
+	return &directoryController{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func (ctrl *directoryController) GetCategories(c *gin.Context) {
+	categories, err := ctrl.service.GetCategories(c.Request.Context())
+	if err != nil {
+		ctrl.handleError(c, err, "Failed to retrieve directory categories")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": categories})
+}
+
+func (ctrl *directoryController) CreateCategory(c *gin.Context) {
+	var req model.CategoryRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	category, err := ctrl.service.CreateCategory(c.Request.Context(), req)
+	if err != nil {
+		ctrl.handleError(c, err, "Failed to create directory category")
+		return
+	}
+
+	c.JSON(http.StatusCreated, category)
+}
+
+func (ctrl *directoryController) UpdateCategory(c *gin.Context) {
+	categoryID, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	var req model.CategoryRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	category, err := ctrl.service.UpdateCategory(c.Request.Context(), categoryID, req)
+	if err != nil {
+		ctrl.handleError(c, err, "Failed to update directory category")
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+func (ctrl *directoryController) DeleteCategory(c *gin.Context) {
+	categoryID, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.service.DeleteCategory(c.Request.Context(), categoryID); err != nil {
+		ctrl.handleError(c, err, "Failed to delete directory category")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetListings lists curated documents, most recently curated first.
+// ?featured=true and ?hidden=true keep featured or hidden ones.
+func (ctrl *directoryController) GetListings(c *gin.Context) {
+	filter := model.ListingFilter{
+		Featured: c.Query("featured") == "true",
+		Hidden:   c.Query("hidden") == "true",
+	}
+	page, perPage := pagination(c)
+
+	listings, total, err := ctrl.service.GetListings(c.Request.Context(), filter, page, perPage)
+	if err != nil {
+		ctrl.handleError(c, err, "Failed to retrieve directory listings")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       listings,
+		"pagination": paginationOf(total, page, perPage),
+	})
+}
+
+// CurateDocument features, hides or categorizes a document
+func (ctrl *directoryController) CurateDocument(c *gin.Context) {
+	documentID, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+
+	var req model.CurationRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	listing, err := ctrl.service.Curate(c.Request.Context(), documentID, userID.(uuid.UUID), req)
+	if err != nil {
+		ctrl.handleError(c, err, "Failed to curate document")
+		return
+	}
+
+	c.JSON(http.StatusOK, listing)
+}
+
+func (ctrl *directoryController) GetTaxonomy(c *gin.Context) {
+	taxonomy, err := ctrl.service.GetTaxonomy(c.Request.Context())
+	if err != nil {
+		ctrl.handleError(c, err, "Failed to retrieve directory categories")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": taxonomy})
+}
+
+// Browse lists the directory, or the category in the path and its
+// subcategories. ?featured=true keeps featured documents.
+func (ctrl *directoryController) Browse(c *gin.Context) {
+	page, perPage := pagination(c)
+
+	entries, total, err := ctrl.service.Browse(c.Request.Context(), c.Param("slug"), c.Query("featured") == "true", page, perPage)
+	if err != nil {
+		ctrl.handleError(c, err, "Failed to retrieve directory")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       entries,
+		"pagination": paginationOf(total, page, perPage),
+	})
+}
+
+func parseID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid ID",
+		}})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func bindJSON(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid request data",
+			"details": err.Error(),
+		}})
+		return false
+	}
+	return true
+}
+
+// pagination reads ?page and ?per_page, with at most 100 per page
+func pagination(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 {
+		perPage = 20
+	}
+	if perPage > 100 {
+		perPage = 100
+	}
+	return page, perPage
+}
+
+func paginationOf(total int64, page, perPage int) gin.H {
+	return gin.H{
+		"total":       total,
+		"page":        page,
+		"per_page":    perPage,
+		"total_pages": (int(total) + perPage - 1) / perPage,
+	}
+}
+
+func (ctrl *directoryController) handleError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrCategoryNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+			"code":    errcode.CategoryNotFound,
+			"message": "Directory category not found",
+		}})
+	case service.ErrDocumentNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+			"code":    errcode.DocNotFound,
+			"message": "Document not found",
+		}})
+	case service.ErrSlugTaken:
+		c.JSON(http.StatusConflict, gin.H{"error": gin.H{
+			"code":    errcode.SlugTaken,
+			"message": "Another directory category already uses this slug",
+		}})
+	case service.ErrInvalidParent:
+		c.JSON(http.StatusConflict, gin.H{"error": gin.H{
+			"code":    errcode.InvalidCategoryParent,
+			"message": err.Error(),
+		}})
+	case service.ErrInvalidSlug, service.ErrFeaturedHidden:
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": err.Error(),
+		}})
+	default:
+		logging.FromContext(c.Request.Context(), ctrl.logger).Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
+			"code":    errcode.InternalError,
+			"message": message,
+		}})
+	}
+}
@@ -0,0 +1,107 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Category groups directory documents. Categories nest through ParentID,
+// and a category lists the documents of its subcategories too.
+type Category struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	ParentID    *uuid.UUID `gorm:"type:uuid" json:"parent_id"`
+	Slug        string     `gorm:"type:varchar(100);not null;uniqueIndex" json:"slug"`
+	Name        string     `gorm:"type:varchar(255);not null" json:"name"`
+	Description string     `gorm:"type:text;not null;default:''" json:"description"`
+	// Position orders siblings, then Name does
+	Position  int       `gorm:"not null;default:0" json:"position"`
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+	UpdatedAt time.Time `gorm:"not null" json:"updated_at"`
+}
+
+func (Category) TableName() string {
+	return "directory_categories"
+}
+
+func (c *Category) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
+
+// Listing is how admins curated a document for the directory. Public
+// documents without one are listed uncategorized.
+type Listing struct {
+	DocumentID   uuid.UUID  `gorm:"type:uuid;primary_key" json:"document_id"`
+	CategoryID   *uuid.UUID `gorm:"type:uuid" json:"category_id"`
+	FeaturedAt   *time.Time `json:"featured_at"`
+	HiddenAt     *time.Time `json:"hidden_at"`
+	HiddenReason *string    `gorm:"type:varchar(255)" json:"hidden_reason"`
+	CuratedByID  *uuid.UUID `gorm:"type:uuid" json:"curated_by_id"`
+	UpdatedAt    time.Time  `gorm:"not null" json:"updated_at"`
+	// Title and IsPublic are only loaded by GetListings
+	Title    string `gorm:"->" json:"title"`
+	IsPublic bool   `gorm:"->" json:"is_public"`
+}
+
+func (Listing) TableName() string {
+	return "directory_listings"
+}
+
+// CategoryRequest creates or replaces a category
+type CategoryRequest struct {
+	Slug        string     `json:"slug" binding:"required,max=100"`
+	Name        string     `json:"name" binding:"required,max=255"`
+	Description string     `json:"description"`
+	ParentID    *uuid.UUID `json:"parent_id"`
+	Position    int        `json:"position"`
+}
+
+// CurationRequest changes how a document appears in the directory; fields
+// left out are kept. Hiding a document unfeatures it.
+type CurationRequest struct {
+	// Category is a category slug; an empty one uncategorizes the document
+	Category     *string `json:"category"`
+	Featured     *bool   `json:"featured"`
+	Hidden       *bool   `json:"hidden"`
+	HiddenReason *string `json:"hidden_reason" binding:"omitempty,max=255"`
+}
+
+// ListingFilter narrows GET /admin/directory/documents
+type ListingFilter struct {
+	Featured bool
+	Hidden   bool
+}
+
+// EntryFilter narrows the public directory. CategoryIDs is a category and
+// its subcategories.
+type EntryFilter struct {
+	CategoryIDs []uuid.UUID
+	Featured    bool
+}
+
+// Entry is a document as the public directory lists it
+type Entry struct {
+	DocumentID   uuid.UUID  `json:"document_id"`
+	Title        string     `json:"title"`
+	Alias        *string    `json:"alias,omitempty"`
+	Language     *string    `json:"language"`
+	Category     *string    `json:"category"`
+	FeaturedAt   *time.Time `json:"featured_at"`
+	Version      int        `json:"-"`
+	ThumbnailURL string     `json:"thumbnail_url"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// CategoryNode is a category in the public taxonomy. DocumentCount
+// includes the documents of subcategories.
+type CategoryNode struct {
+	Slug          string          `json:"slug"`
+	Name          string          `json:"name"`
+	Description   string          `json:"description"`
+	DocumentCount int64           `json:"document_count"`
+	Children      []*CategoryNode `json:"children"`
+}
@@ -0,0 +1,24 @@
+// Package directory is the public directory: a gallery of public
+// documents, browsable by category. Admins curate it by defining the
+// category taxonomy and by featuring, hiding or categorizing documents;
+// titles with a word of directory.blocked_words are never listed.
+package directory
+
+import (
+	"go.uber.org/fx"
+
+	"github.com/hafiztri123/document-api/internal/api"
+	"github.com/hafiztri123/document-api/internal/directory/controller"
+	"github.com/hafiztri123/document-api/internal/directory/repository"
+	"github.com/hafiztri123/document-api/internal/directory/service"
+)
+
+// Module provides the directory repository, service, controller and routes
+var Module = fx.Module("directory",
+	fx.Provide(
+		repository.NewDirectoryRepository,
+		service.NewDirectoryService,
+		controller.NewDirectoryController,
+		api.AsRouteRegistrar(newRoutes),
+	),
+)
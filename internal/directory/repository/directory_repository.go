@@ -0,0 +1,257 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/hafiztri123/document-api/internal/directory/model"
+	"github.com/hafiztri123/document-api/internal/logging"
+)
+
+type Repository interface {
+	CreateCategory(ctx context.Context, category *model.Category) error
+	GetCategoryByID(ctx context.Context, id uuid.UUID) (*model.Category, error)
+	GetCategoryBySlug(ctx context.Context, slug string) (*model.Category, error)
+	// GetCategories returns every category, ordered by position, then name
+	GetCategories(ctx context.Context) ([]*model.Category, error)
+	UpdateCategory(ctx context.Context, category *model.Category) error
+	// DeleteCategory moves the category's children up to its parent; its
+	// documents become uncategorized
+	DeleteCategory(ctx context.Context, category *model.Category) error
+
+	GetListing(ctx context.Context, documentID uuid.UUID) (*model.Listing, error)
+	SaveListing(ctx context.Context, listing *model.Listing) error
+	// GetListings returns curated documents, most recently curated first
+	GetListings(ctx context.Context, filter model.ListingFilter, page, perPage int) ([]*model.Listing, int64, error)
+
+	// GetEntries returns the documents the directory lists, featured ones
+	// first, most recently featured first, then the most recently updated.
+	// Titles matching blocked, a PostgreSQL regular expression, are left
+	// out unless it is empty.
+	GetEntries(ctx context.Context, filter model.EntryFilter, blocked string, page, perPage int) ([]*model.Entry, int64, error)
+	// CountEntries counts the listed documents of each category, those of
+	// subcategories left out
+	CountEntries(ctx context.Context, blocked string) (map[uuid.UUID]int64, error)
+}
+
+type directoryRepository struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+func NewDirectoryRepository(db *gorm.DB, logger *zap.Logger) Repository {
+	return &directoryRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *directoryRepository) CreateCategory(ctx context.Context, category *model.Category) error {
+	if err := r.db.WithContext(ctx).Create(category).Error; err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to create directory category", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r *directoryRepository) GetCategoryByID(ctx context.Context, id uuid.UUID) (*model.Category, error) {
+	return r.findCategory(ctx, "id = ?", id)
+}
+
+func (r *directoryRepository) GetCategoryBySlug(ctx context.Context, slug string) (*model.Category, error) {
+	return r.findCategory(ctx, "slug = ?", slug)
+}
+
+func (r *directoryRepository) findCategory(ctx context.Context, query string, args ...interface{}) (*model.Category, error) {
+	var category model.Category
+	err := r.db.WithContext(ctx).Where(query, args...).First(&category).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get directory category", zap.Error(err))
+		return nil, err
+	}
+	return &category, nil
+}
+
+func (r *directoryRepository) GetCategories(ctx context.Context) ([]*model.Category, error) {
+	var categories []*model.Category
+	err := r.db.WithContext(ctx).Order("position ASC, name ASC").Find(&categories).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get directory categories", zap.Error(err))
+		return nil, err
+	}
+	return categories, nil
+}
+
+func (r *directoryRepository) UpdateCategory(ctx context.Context, category *model.Category) error {
+	if err := r.db.WithContext(ctx).Save(category).Error; err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to update directory category", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r *directoryRepository) DeleteCategory(ctx context.Context, category *model.Category) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Category{}).
+			Where("parent_id = ?", category.ID).
+			Update("parent_id", category.ParentID).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&model.Category{}, category.ID).Error
+	})
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to delete directory category", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r *directoryRepository) GetListing(ctx context.Context, documentID uuid.UUID) (*model.Listing, error) {
+	var listing model.Listing
+	err := r.db.WithContext(ctx).Where("document_id = ?", documentID).First(&listing).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get directory listing", zap.Error(err))
+		return nil, err
+	}
+	return &listing, nil
+}
+
+func (r *directoryRepository) SaveListing(ctx context.Context, listing *model.Listing) error {
+	if err := r.db.WithContext(ctx).Save(listing).Error; err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to save directory listing", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r *directoryRepository) GetListings(ctx context.Context, filter model.ListingFilter, page, perPage int) ([]*model.Listing, int64, error) {
+	var listings []*model.Listing
+	var total int64
+
+	db := r.db.WithContext(ctx).Model(&model.Listing{}).
+		Joins("JOIN documents ON documents.id = directory_listings.document_id AND documents.deleted_at IS NULL")
+	if filter.Featured {
+		db = db.Where("directory_listings.featured_at IS NOT NULL")
+	}
+	if filter.Hidden {
+		db = db.Where("directory_listings.hidden_at IS NOT NULL")
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to count directory listings", zap.Error(err))
+		return nil, 0, err
+	}
+
+	if page < 1 {
+		page = 1
+	}
+
+	if perPage < 1 {
+		perPage = 20
+	}
+
+	offset := (page - 1) * perPage
+
+	err := db.Select("directory_listings.*, documents.title, documents.is_public").
+		Order("directory_listings.updated_at DESC").
+		Limit(perPage).
+		Offset(offset).
+		Find(&listings).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get directory listings", zap.Error(err))
+		return nil, 0, err
+	}
+
+	return listings, total, nil
+}
+
+// listed scopes to the documents the directory lists: public ones
+// anonymous readers can read, neither archived nor hidden
+func (r *directoryRepository) listed(db *gorm.DB, blocked string) *gorm.DB {
+	db = db.Table("documents").
+		Joins("LEFT JOIN directory_listings ON directory_listings.document_id = documents.id").
+		Where("documents.deleted_at IS NULL").
+		Where("documents.is_public AND documents.terms_hash IS NULL AND documents.archived_at IS NULL").
+		Where("directory_listings.hidden_at IS NULL")
+	if blocked != "" {
+		db = db.Where("documents.title !~* ?", blocked)
+	}
+	return db
+}
+
+func (r *directoryRepository) GetEntries(ctx context.Context, filter model.EntryFilter, blocked string, page, perPage int) ([]*model.Entry, int64, error) {
+	var entries []*model.Entry
+	var total int64
+
+	db := r.listed(r.db.WithContext(ctx), blocked)
+	if len(filter.CategoryIDs) > 0 {
+		db = db.Where("directory_listings.category_id IN ?", filter.CategoryIDs)
+	}
+	if filter.Featured {
+		db = db.Where("directory_listings.featured_at IS NOT NULL")
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to count directory entries", zap.Error(err))
+		return nil, 0, err
+	}
+
+	if page < 1 {
+		page = 1
+	}
+
+	if perPage < 1 {
+		perPage = 20
+	}
+
+	offset := (page - 1) * perPage
+
+	err := db.Select(`documents.id AS document_id, documents.title, documents.alias, documents.language,
+			documents.version, documents.updated_at, directory_listings.featured_at,
+			directory_categories.slug AS category`).
+		Joins("LEFT JOIN directory_categories ON directory_categories.id = directory_listings.category_id").
+		Order("directory_listings.featured_at DESC NULLS LAST, documents.updated_at DESC, documents.id").
+		Limit(perPage).
+		Offset(offset).
+		Scan(&entries).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get directory entries", zap.Error(err))
+		return nil, 0, err
+	}
+
+	return entries, total, nil
+}
+
+func (r *directoryRepository) CountEntries(ctx context.Context, blocked string) (map[uuid.UUID]int64, error) {
+	var rows []struct {
+		CategoryID uuid.UUID
+		Count      int64
+	}
+
+	err := r.listed(r.db.WithContext(ctx), blocked).
+		Where("directory_listings.category_id IS NOT NULL").
+		Select("directory_listings.category_id, COUNT(*) AS count").
+		Group("directory_listings.category_id").
+		Scan(&rows).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to count directory entries by category", zap.Error(err))
+		return nil, err
+	}
+
+	counts := make(map[uuid.UUID]int64, len(rows))
+	for _, row := range rows {
+		counts[row.CategoryID] = row.Count
+	}
+	return counts, nil
+}
@@ -0,0 +1,33 @@
+package directory
+
+import (
+	"github.com/hafiztri123/document-api/internal/api"
+	"github.com/hafiztri123/document-api/internal/directory/controller"
+)
+
+type routes struct {
+	ctrl controller.Controller
+}
+
+func newRoutes(ctrl controller.Controller) *routes {
+	return &routes{ctrl: ctrl}
+}
+
+func (r *routes) RegisterRoutes(groups api.Groups) {
+	// Curation: the category taxonomy, and which documents are featured,
+	// hidden or in which category
+	curation := groups.Admin.Group("/directory")
+	{
+		curation.GET("/categories", r.ctrl.GetCategories)
+		curation.POST("/categories", r.ctrl.CreateCategory)
+		curation.PUT("/categories/:id", r.ctrl.UpdateCategory)
+		curation.DELETE("/categories/:id", r.ctrl.DeleteCategory)
+		curation.GET("/documents", r.ctrl.GetListings)
+		curation.PUT("/documents/:id", r.ctrl.CurateDocument)
+	}
+
+	// The gallery of public documents, browsable by category
+	groups.Anonymous.GET("/directory", r.ctrl.Browse)
+	groups.Anonymous.GET("/directory/categories", r.ctrl.GetTaxonomy)
+	groups.Anonymous.GET("/directory/categories/:slug", r.ctrl.Browse)
+}
@@ -0,0 +1,362 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/hafiztri123/document-api/config"
+	"github.com/hafiztri123/document-api/internal/directory/model"
+	"github.com/hafiztri123/document-api/internal/directory/repository"
+	docRepo "github.com/hafiztri123/document-api/internal/document/repository"
+	"github.com/hafiztri123/document-api/internal/document/thumbnail"
+)
+
+var (
+	ErrCategoryNotFound = errors.New("directory category not found")
+	ErrDocumentNotFound = errors.New("document not found")
+	ErrInvalidSlug      = errors.New("slugs may only contain lowercase letters, digits and single hyphens")
+	ErrSlugTaken        = errors.New("slug is already in use")
+	ErrInvalidParent    = errors.New("a category cannot be nested under itself or one of its subcategories")
+	ErrFeaturedHidden   = errors.New("hidden documents cannot be featured")
+)
+
+// slugPattern accepts lowercase words separated by single hyphens
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+type Service interface {
+	// Categories are managed by admins. A category may be nested under
+	// another one, but not under itself or its own subcategories.
+	CreateCategory(ctx context.Context, req model.CategoryRequest) (*model.Category, error)
+	GetCategories(ctx context.Context) ([]*model.Category, error)
+	UpdateCategory(ctx context.Context, id uuid.UUID, req model.CategoryRequest) (*model.Category, error)
+	// DeleteCategory moves the category's subcategories up to its parent
+	// and uncategorizes its documents
+	DeleteCategory(ctx context.Context, id uuid.UUID) error
+
+	// Curate changes how a document appears in the directory, see
+	// model.CurationRequest. Private documents can be curated ahead of
+	// being made public.
+	Curate(ctx context.Context, documentID uuid.UUID, adminID uuid.UUID, req model.CurationRequest) (*model.Listing, error)
+	GetListings(ctx context.Context, filter model.ListingFilter, page, perPage int) ([]*model.Listing, int64, error)
+
+	// GetTaxonomy returns the category tree, with how many documents the
+	// directory lists under each category
+	GetTaxonomy(ctx context.Context) ([]*model.CategoryNode, error)
+	// Browse lists the public documents anonymous readers can read that
+	// are neither archived, hidden nor titled with a word of
+	// directory.blocked_words. A category slug narrows it to the
+	// category and its subcategories.
+	Browse(ctx context.Context, category string, featured bool, page, perPage int) ([]*model.Entry, int64, error)
+}
+
+type directoryService struct {
+	repo    repository.Repository
+	docRepo docRepo.Repository
+	blocked string
+	logger  *zap.Logger
+}
+
+func NewDirectoryService(repo repository.Repository, docRepo docRepo.Repository, logger *zap.Logger) Service {
+	return &directoryService{
+		repo:    repo,
+		docRepo: docRepo,
+		blocked: blockedPattern(viper.GetStringSlice(config.DIRECTORY_BLOCKED_WORDS)),
+		logger:  logger,
+	}
+}
+
+// blockedPattern is a PostgreSQL regular expression matching any of words
+// as a whole word, or empty without words
+func blockedPattern(words []string) string {
+	var quoted []string
+	for _, word := range words {
+		if word = strings.TrimSpace(word); word != "" {
+			quoted = append(quoted, regexp.QuoteMeta(word))
+		}
+	}
+	if len(quoted) == 0 {
+		return ""
+	}
+	return `\m(` + strings.Join(quoted, "|") + `)\M`
+}
+
+func (s *directoryService) CreateCategory(ctx context.Context, req model.CategoryRequest) (*model.Category, error) {
+	category := &model.Category{
+		CreatedAt: time.Now(),
+	}
+	if err := s.applyCategory(ctx, category, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateCategory(ctx, category); err != nil {
+		return nil, err
+	}
+
+	return category, nil
+}
+
+func (s *directoryService) GetCategories(ctx context.Context) ([]*model.Category, error) {
+	return s.repo.GetCategories(ctx)
+}
+
+func (s *directoryService) UpdateCategory(ctx context.Context, id uuid.UUID, req model.CategoryRequest) (*model.Category, error) {
+	category, err := s.repo.GetCategoryByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if category == nil {
+		return nil, ErrCategoryNotFound
+	}
+
+	if err := s.applyCategory(ctx, category, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateCategory(ctx, category); err != nil {
+		return nil, err
+	}
+
+	return category, nil
+}
+
+// applyCategory validates req and copies it onto category
+func (s *directoryService) applyCategory(ctx context.Context, category *model.Category, req model.CategoryRequest) error {
+	if !slugPattern.MatchString(req.Slug) {
+		return ErrInvalidSlug
+	}
+
+	existing, err := s.repo.GetCategoryBySlug(ctx, req.Slug)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ID != category.ID {
+		return ErrSlugTaken
+	}
+
+	if req.ParentID != nil {
+		categories, err := s.repo.GetCategories(ctx)
+		if err != nil {
+			return err
+		}
+
+		found := false
+		for _, c := range categories {
+			if c.ID == *req.ParentID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return ErrCategoryNotFound
+		}
+
+		// New categories have no subcategories to nest under
+		if category.ID != uuid.Nil {
+			for _, id := range subtree(categories, category.ID) {
+				if id == *req.ParentID {
+					return ErrInvalidParent
+				}
+			}
+		}
+	}
+
+	category.Slug = req.Slug
+	category.Name = req.Name
+	category.Description = req.Description
+	category.ParentID = req.ParentID
+	category.Position = req.Position
+	category.UpdatedAt = time.Now()
+	return nil
+}
+
+func (s *directoryService) DeleteCategory(ctx context.Context, id uuid.UUID) error {
+	category, err := s.repo.GetCategoryByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if category == nil {
+		return ErrCategoryNotFound
+	}
+
+	return s.repo.DeleteCategory(ctx, category)
+}
+
+func (s *directoryService) Curate(ctx context.Context, documentID uuid.UUID, adminID uuid.UUID, req model.CurationRequest) (*model.Listing, error) {
+	document, err := s.docRepo.GetDocumentByID(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if document == nil {
+		return nil, ErrDocumentNotFound
+	}
+
+	listing, err := s.repo.GetListing(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if listing == nil {
+		listing = &model.Listing{DocumentID: documentID}
+	}
+
+	if req.Category != nil {
+		listing.CategoryID = nil
+		if *req.Category != "" {
+			category, err := s.repo.GetCategoryBySlug(ctx, *req.Category)
+			if err != nil {
+				return nil, err
+			}
+			if category == nil {
+				return nil, ErrCategoryNotFound
+			}
+			listing.CategoryID = &category.ID
+		}
+	}
+
+	now := time.Now()
+	if req.Hidden != nil {
+		if !*req.Hidden {
+			listing.HiddenAt = nil
+			listing.HiddenReason = nil
+		} else if listing.HiddenAt == nil {
+			listing.HiddenAt = &now
+			listing.FeaturedAt = nil
+		}
+	}
+	// The reason is only kept while the document is hidden
+	if req.HiddenReason != nil && listing.HiddenAt != nil {
+		listing.HiddenReason = nil
+		if *req.HiddenReason != "" {
+			listing.HiddenReason = req.HiddenReason
+		}
+	}
+
+	if req.Featured != nil {
+		if !*req.Featured {
+			listing.FeaturedAt = nil
+		} else if listing.HiddenAt != nil {
+			return nil, ErrFeaturedHidden
+		} else if listing.FeaturedAt == nil {
+			listing.FeaturedAt = &now
+		}
+	}
+
+	listing.CuratedByID = &adminID
+	listing.UpdatedAt = now
+
+	if err := s.repo.SaveListing(ctx, listing); err != nil {
+		return nil, err
+	}
+
+	listing.Title = document.Title
+	listing.IsPublic = document.IsPublic
+	return listing, nil
+}
+
+func (s *directoryService) GetListings(ctx context.Context, filter model.ListingFilter, page, perPage int) ([]*model.Listing, int64, error) {
+	return s.repo.GetListings(ctx, filter, page, perPage)
+}
+
+func (s *directoryService) GetTaxonomy(ctx context.Context) ([]*model.CategoryNode, error) {
+	categories, err := s.repo.GetCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := s.repo.CountEntries(ctx, s.blocked)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[uuid.UUID]*model.CategoryNode, len(categories))
+	for _, category := range categories {
+		nodes[category.ID] = &model.CategoryNode{
+			Slug:          category.Slug,
+			Name:          category.Name,
+			Description:   category.Description,
+			DocumentCount: counts[category.ID],
+			Children:      []*model.CategoryNode{},
+		}
+	}
+
+	// categories is in sibling order, so children are appended in it
+	roots := []*model.CategoryNode{}
+	for _, category := range categories {
+		node := nodes[category.ID]
+		if category.ParentID != nil {
+			if parent, ok := nodes[*category.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+
+	var total func(node *model.CategoryNode) int64
+	total = func(node *model.CategoryNode) int64 {
+		for _, child := range node.Children {
+			node.DocumentCount += total(child)
+		}
+		return node.DocumentCount
+	}
+	for _, root := range roots {
+		total(root)
+	}
+
+	return roots, nil
+}
+
+func (s *directoryService) Browse(ctx context.Context, category string, featured bool, page, perPage int) ([]*model.Entry, int64, error) {
+	filter := model.EntryFilter{Featured: featured}
+	if category != "" {
+		found, err := s.repo.GetCategoryBySlug(ctx, category)
+		if err != nil {
+			return nil, 0, err
+		}
+		if found == nil {
+			return nil, 0, ErrCategoryNotFound
+		}
+
+		categories, err := s.repo.GetCategories(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+		filter.CategoryIDs = subtree(categories, found.ID)
+	}
+
+	entries, total, err := s.repo.GetEntries(ctx, filter, s.blocked, page, perPage)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if entries == nil {
+		entries = []*model.Entry{}
+	}
+	for _, entry := range entries {
+		entry.ThumbnailURL = thumbnail.PublicURL(entry.DocumentID, entry.Version)
+	}
+
+	return entries, total, nil
+}
+
+// subtree returns id and the IDs of every category below it
+func subtree(categories []*model.Category, id uuid.UUID) []uuid.UUID {
+	children := make(map[uuid.UUID][]uuid.UUID)
+	for _, category := range categories {
+		if category.ParentID != nil {
+			children[*category.ParentID] = append(children[*category.ParentID], category.ID)
+		}
+	}
+
+	ids := []uuid.UUID{id}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, children[ids[i]]...)
+	}
+	return ids
+}
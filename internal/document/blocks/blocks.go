@@ -0,0 +1,206 @@
+// Package blocks converts markdown content into typed blocks, for consumers
+// such as static site generators that lay documents out themselves.
+package blocks
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+
+	"github.com/hafiztri123/document-api/internal/document/outline"
+)
+
+// Type identifies what a block holds. Types are only ever added, so
+// consumers should skip blocks of types they do not know.
+type Type string
+
+const (
+	Heading   Type = "heading"
+	Paragraph Type = "paragraph"
+	Image     Type = "image"
+	List      Type = "list"
+	ListItem  Type = "list_item"
+	Quote     Type = "quote"
+	Code      Type = "code"
+	Divider   Type = "divider"
+)
+
+// Block is one block of content. Headings and paragraphs carry their
+// plain Text and their HTML with inline markup; code carries its source in
+// Text. Lists, list items and quotes hold their content in Children. Raw
+// HTML is dropped, as it is from the document's rendered HTML.
+type Block struct {
+	Type Type `json:"type"`
+	// Level and Anchor are set on headings; the anchor matches the
+	// document's outline and HTML ids
+	Level  int    `json:"level,omitempty"`
+	Anchor string `json:"anchor,omitempty"`
+	// Ordered and Start are set on lists
+	Ordered bool `json:"ordered,omitempty"`
+	Start   int  `json:"start,omitempty"`
+	// Language is the info string of a fenced code block
+	Language string `json:"language,omitempty"`
+	// URL and Title are set on images, with the alt text in Text
+	URL      string  `json:"url,omitempty"`
+	Title    string  `json:"title,omitempty"`
+	Text     string  `json:"text,omitempty"`
+	HTML     string  `json:"html,omitempty"`
+	Children []Block `json:"children,omitempty"`
+}
+
+var markdown = goldmark.New()
+
+// Parse returns the blocks of content in document order. headings must be
+// its outline, so heading anchors are the same everywhere.
+func Parse(content string, headings []outline.Heading) ([]Block, error) {
+	source := []byte(content)
+	p := &parser{
+		source:  source,
+		anchors: outline.Anchors(content, headings),
+	}
+	return p.blocks(markdown.Parser().Parse(text.NewReader(source)))
+}
+
+type parser struct {
+	source  []byte
+	anchors []string
+	// headings counts the headings seen, indexing anchors
+	headings int
+}
+
+// blocks converts the block children of parent
+func (p *parser) blocks(parent ast.Node) ([]Block, error) {
+	result := []Block{}
+	for n := parent.FirstChild(); n != nil; n = n.NextSibling() {
+		block, err := p.block(n)
+		if err != nil {
+			return nil, err
+		}
+		if block != nil {
+			result = append(result, *block)
+		}
+	}
+	return result, nil
+}
+
+func (p *parser) block(n ast.Node) (*Block, error) {
+	switch n := n.(type) {
+	case *ast.Heading:
+		anchor := ""
+		if p.headings < len(p.anchors) {
+			anchor = p.anchors[p.headings]
+		}
+		p.headings++
+		// Rendered with its id, like the document's HTML
+		n.SetAttributeString("id", []byte(anchor))
+		return p.leaf(&Block{Type: Heading, Level: n.Level, Anchor: anchor}, n)
+
+	case *ast.Paragraph, *ast.TextBlock:
+		if image, ok := onlyImage(n); ok {
+			block := &Block{
+				Type:  Image,
+				Title: string(image.Title),
+				Text:  strings.TrimSpace(plainText(image, p.source)),
+			}
+			// Left out like the renderer leaves it out of src
+			if !html.IsDangerousURL(image.Destination) {
+				block.URL = string(image.Destination)
+			}
+			return block, nil
+		}
+		return p.leaf(&Block{Type: Paragraph}, n)
+
+	case *ast.List:
+		block := &Block{Type: List, Ordered: n.IsOrdered()}
+		if n.IsOrdered() {
+			block.Start = n.Start
+		}
+		return p.container(block, n)
+
+	case *ast.ListItem:
+		return p.container(&Block{Type: ListItem}, n)
+
+	case *ast.Blockquote:
+		return p.container(&Block{Type: Quote}, n)
+
+	case *ast.FencedCodeBlock:
+		return &Block{
+			Type:     Code,
+			Language: string(n.Language(p.source)),
+			Text:     lines(n, p.source),
+		}, nil
+
+	case *ast.CodeBlock:
+		return &Block{Type: Code, Text: lines(n, p.source)}, nil
+
+	case *ast.ThematicBreak:
+		return &Block{Type: Divider}, nil
+	}
+	return nil, nil
+}
+
+// leaf fills in the text and rendered HTML of a heading or paragraph
+func (p *parser) leaf(block *Block, n ast.Node) (*Block, error) {
+	var buf bytes.Buffer
+	if err := markdown.Renderer().Render(&buf, p.source, n); err != nil {
+		return nil, err
+	}
+	block.Text = strings.TrimSpace(plainText(n, p.source))
+	block.HTML = strings.TrimSpace(buf.String())
+	return block, nil
+}
+
+// container fills in the children of a list, list item or quote
+func (p *parser) container(block *Block, n ast.Node) (*Block, error) {
+	children, err := p.blocks(n)
+	if err != nil {
+		return nil, err
+	}
+	block.Children = children
+	return block, nil
+}
+
+// onlyImage returns the image a paragraph consists of, if that is all it
+// holds
+func onlyImage(n ast.Node) (*ast.Image, bool) {
+	child := n.FirstChild()
+	if child == nil || child.NextSibling() != nil {
+		return nil, false
+	}
+	image, ok := child.(*ast.Image)
+	return image, ok
+}
+
+// lines returns the raw lines of a code block
+func lines(n ast.Node, source []byte) string {
+	var b strings.Builder
+	segments := n.Lines()
+	for i := 0; i < segments.Len(); i++ {
+		segment := segments.At(i)
+		b.Write(segment.Value(source))
+	}
+	return b.String()
+}
+
+// plainText concatenates the text of a node, dropping inline markup
+func plainText(n ast.Node, source []byte) string {
+	var b strings.Builder
+	for child := n.FirstChild(); child != nil; child = child.NextSibling() {
+		switch child := child.(type) {
+		case *ast.Text:
+			b.Write(child.Segment.Value(source))
+			if child.SoftLineBreak() || child.HardLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			b.Write(child.Value)
+		default:
+			b.WriteString(plainText(child, source))
+		}
+	}
+	return b.String()
+}
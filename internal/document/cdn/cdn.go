@@ -0,0 +1,197 @@
+// Package cdn lets a CDN cache public document responses. Responses are
+// tagged with surrogate keys, and the keys of a changed document are purged
+// through the configured provider.
+package cdn
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hafiztri123/document-api/internal/signature"
+)
+
+// Providers that may be set in Config.Provider
+const (
+	ProviderFastly     = "fastly"
+	ProviderCloudflare = "cloudflare"
+	ProviderWebhook    = "webhook"
+)
+
+// AllDocumentsKey tags every public document response, so a deployment
+// can purge them all at once from the CDN's dashboard
+const AllDocumentsKey = "documents"
+
+// purgeTimeout bounds a single purge request
+const purgeTimeout = 10 * time.Second
+
+// Config is read from the cdn section. Only the settings of the chosen
+// provider are used.
+type Config struct {
+	// Provider is empty when no CDN sits in front of the API
+	Provider   string     `mapstructure:"provider"`
+	Fastly     Fastly     `mapstructure:"fastly"`
+	Cloudflare Cloudflare `mapstructure:"cloudflare"`
+	Webhook    Webhook    `mapstructure:"webhook"`
+}
+
+type Fastly struct {
+	ServiceID string `mapstructure:"service_id"`
+	APIToken  string `mapstructure:"api_token"`
+}
+
+type Cloudflare struct {
+	ZoneID   string `mapstructure:"zone_id"`
+	APIToken string `mapstructure:"api_token"`
+}
+
+// Webhook receives {"surrogate_keys": [...]}, signed like outgoing
+// webhooks when Secret is set
+type Webhook struct {
+	URL    string `mapstructure:"url"`
+	Secret string `mapstructure:"secret"`
+}
+
+// DocumentKey is the surrogate key of a document's public responses
+func DocumentKey(documentID uuid.UUID) string {
+	return "document-" + documentID.String()
+}
+
+// SnapshotKey is the surrogate key of a snapshot's public responses
+func SnapshotKey(snapshotID uuid.UUID) string {
+	return "snapshot-" + snapshotID.String()
+}
+
+// Policy is how a public response may be cached
+type Policy struct {
+	// MaxAge applies to browsers, and to the CDN when SharedMaxAge is zero
+	MaxAge       time.Duration
+	SharedMaxAge time.Duration
+	Keys         []string
+}
+
+// Apply sets the caching headers. Surrogate-Key is read by Fastly and
+// Cache-Tag by Cloudflare; both strip them before responding.
+func (p Policy) Apply(header http.Header) {
+	if p.MaxAge <= 0 {
+		header.Set("Cache-Control", "no-cache")
+		return
+	}
+
+	cacheControl := fmt.Sprintf("public, max-age=%d", int(p.MaxAge.Seconds()))
+	if p.SharedMaxAge > 0 {
+		cacheControl += fmt.Sprintf(", s-maxage=%d", int(p.SharedMaxAge.Seconds()))
+		header.Set("Surrogate-Control", fmt.Sprintf("max-age=%d", int(p.SharedMaxAge.Seconds())))
+	}
+	header.Set("Cache-Control", cacheControl)
+
+	if len(p.Keys) > 0 {
+		header.Set("Surrogate-Key", strings.Join(p.Keys, " "))
+		header.Set("Cache-Tag", strings.Join(p.Keys, ","))
+	}
+}
+
+// Purger invalidates every cached response tagged with one of the keys
+type Purger interface {
+	Purge(ctx context.Context, keys []string) error
+}
+
+// NewPurger creates the purger for the configured provider. It returns nil
+// when no provider is set and fails when the provider's settings are missing.
+func NewPurger(config Config, client *http.Client) (Purger, error) {
+	switch config.Provider {
+	case "":
+		return nil, nil
+	case ProviderFastly:
+		if config.Fastly.ServiceID == "" || config.Fastly.APIToken == "" {
+			return nil, fmt.Errorf("cdn.fastly needs service_id and api_token")
+		}
+		return &fastly{config: config.Fastly, client: client}, nil
+	case ProviderCloudflare:
+		if config.Cloudflare.ZoneID == "" || config.Cloudflare.APIToken == "" {
+			return nil, fmt.Errorf("cdn.cloudflare needs zone_id and api_token")
+		}
+		return &cloudflare{config: config.Cloudflare, client: client}, nil
+	case ProviderWebhook:
+		if config.Webhook.URL == "" {
+			return nil, fmt.Errorf("cdn.webhook needs url")
+		}
+		return &webhook{config: config.Webhook, client: client}, nil
+	default:
+		return nil, fmt.Errorf("unknown CDN provider %q", config.Provider)
+	}
+}
+
+type fastly struct {
+	config Fastly
+	client *http.Client
+}
+
+func (p *fastly) Purge(ctx context.Context, keys []string) error {
+	url := fmt.Sprintf("https://api.fastly.com/service/%s/purge", p.config.ServiceID)
+	return post(ctx, p.client, url, map[string][]string{"surrogate_keys": keys}, func(req *http.Request, _ []byte) {
+		req.Header.Set("Fastly-Key", p.config.APIToken)
+	})
+}
+
+type cloudflare struct {
+	config Cloudflare
+	client *http.Client
+}
+
+func (p *cloudflare) Purge(ctx context.Context, keys []string) error {
+	url := fmt.Sprintf("https://api.cloudflare.com/client/v4/zones/%s/purge_cache", p.config.ZoneID)
+	return post(ctx, p.client, url, map[string][]string{"tags": keys}, func(req *http.Request, _ []byte) {
+		req.Header.Set("Authorization", "Bearer "+p.config.APIToken)
+	})
+}
+
+type webhook struct {
+	config Webhook
+	client *http.Client
+}
+
+func (p *webhook) Purge(ctx context.Context, keys []string) error {
+	return post(ctx, p.client, p.config.URL, map[string][]string{"surrogate_keys": keys}, func(req *http.Request, payload []byte) {
+		if p.config.Secret != "" {
+			req.Header.Set("X-Webhook-Signature", signature.Header(payload, time.Now(), p.config.Secret))
+		}
+	})
+}
+
+// post sends body as JSON, letting authorize add credentials, and fails on
+// any non-2xx response
+func post(ctx context.Context, client *http.Client, url string, body interface{}, authorize func(req *http.Request, payload []byte)) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, purgeTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	authorize(req, payload)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("purge failed with status %d: %s", resp.StatusCode, snippet)
+	}
+	return nil
+}
@@ -1,44 +1,140 @@
 package controller
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 
+	"github.com/hafiztri123/document-api/internal/bulk"
+	"github.com/hafiztri123/document-api/internal/document/cdn"
+	"github.com/hafiztri123/document-api/internal/document/diff"
+	"github.com/hafiztri123/document-api/internal/document/export"
+	"github.com/hafiztri123/document-api/internal/document/find"
+	"github.com/hafiztri123/document-api/internal/document/hook"
+	"github.com/hafiztri123/document-api/internal/document/importer"
 	"github.com/hafiztri123/document-api/internal/document/model"
+	"github.com/hafiztri123/document-api/internal/errcode"
+	jobController "github.com/hafiztri123/document-api/internal/job/controller"
+	jobService "github.com/hafiztri123/document-api/internal/job/service"
 	"github.com/hafiztri123/document-api/internal/document/service"
+	"github.com/hafiztri123/document-api/internal/document/thumbnail"
+	"github.com/hafiztri123/document-api/internal/logging"
 )
 
 type Controller interface {
 	CreateDocument(c *gin.Context)
+	CopyPublicDocument(c *gin.Context)
+	ForkDocument(c *gin.Context)
+	GetForks(c *gin.Context)
+	ImportDocuments(c *gin.Context)
+	SyncDocument(c *gin.Context)
 	GetDocuments(c *gin.Context)
 	GetDocumentByID(c *gin.Context)
 	UpdateDocument(c *gin.Context)
+	MergeDocument(c *gin.Context)
 	DeleteDocument(c *gin.Context)
+	GetTrash(c *gin.Context)
+	RestoreDocument(c *gin.Context)
+	BulkDocuments(c *gin.Context)
+	SetReviewDate(c *gin.Context)
+	SetDocumentExpiry(c *gin.Context)
+	LintDocument(c *gin.Context)
+	SearchDocument(c *gin.Context)
+	TransitionDocument(c *gin.Context)
+	SetDocumentAlias(c *gin.Context)
+	RemoveDocumentAlias(c *gin.Context)
+	ResolveAlias(c *gin.Context)
+	ResolveShortCode(c *gin.Context)
+	ArchiveDocument(c *gin.Context)
+	UnarchiveDocument(c *gin.Context)
+	GetDocumentOutline(c *gin.Context)
+	RenderDocumentHTML(c *gin.Context)
+	ExportDocument(c *gin.Context)
+	ExportDocumentHistory(c *gin.Context)
+	GetDocumentThumbnail(c *gin.Context)
+	GetDocumentStats(c *gin.Context)
+	GetBacklinks(c *gin.Context)
+	GetDocumentStorage(c *gin.Context)
+	
+	LockDocument(c *gin.Context)
+	UnlockDocument(c *gin.Context)
+	GetDocumentLock(c *gin.Context)
+	SaveDraft(c *gin.Context)
+	GetDraft(c *gin.Context)
+	DiscardDraft(c *gin.Context)
+	PinDocument(c *gin.Context)
+	UnpinDocument(c *gin.Context)
+	SetDocumentTerms(c *gin.Context)
+	GetDocumentTerms(c *gin.Context)
+	AcceptDocumentTerms(c *gin.Context)
+	GetTermsAcceptances(c *gin.Context)
+	GetSectionSubscriptions(c *gin.Context)
+	SubscribeToSection(c *gin.Context)
+	UnsubscribeFromSection(c *gin.Context)
+	
+	GetPublicDocument(c *gin.Context)
+	GetPublicDocumentByAlias(c *gin.Context)
+	GetPublicSnapshot(c *gin.Context)
+	PublishSnapshot(c *gin.Context)
+	GetSnapshots(c *gin.Context)
+	DeleteSnapshot(c *gin.Context)
+	GetPublicDocumentHTML(c *gin.Context)
+	GetPublicDocumentThumbnail(c *gin.Context)
 	
 	GetDocumentHistory(c *gin.Context)
 	RestoreDocumentVersion(c *gin.Context)
+	PreviewDocumentVersionRestore(c *gin.Context)
+	LabelDocumentVersion(c *gin.Context)
+	RemoveDocumentVersionLabel(c *gin.Context)
+	AddVersionReaction(c *gin.Context)
+	RemoveVersionReaction(c *gin.Context)
+	GetLabeledVersions(c *gin.Context)
+	RestoreLabeledVersion(c *gin.Context)
+	CompareDocumentVersions(c *gin.Context)
+	CompactDocumentHistory(c *gin.Context)
 	
 	ShareDocument(c *gin.Context)
+	BulkShareDocument(c *gin.Context)
 	UpdateCollaboratorPermission(c *gin.Context)
 	RemoveCollaborator(c *gin.Context)
+	TransferOwnership(c *gin.Context)
+	GetDocumentKeys(c *gin.Context)
+	RegisterDocumentKey(c *gin.Context)
+	WrapDocumentKey(c *gin.Context)
 	
 	GetDocumentAnalytics(c *gin.Context)
+	CheckPermissions(c *gin.Context)
+	SetAnalyticsAccess(c *gin.Context)
 	GetUserAnalytics(c *gin.Context)
+	SubscribeDocumentDigest(c *gin.Context)
+	UnsubscribeDocumentDigest(c *gin.Context)
+	SubscribeWorkspaceDigest(c *gin.Context)
+	UnsubscribeWorkspaceDigest(c *gin.Context)
+	GetUserStorage(c *gin.Context)
 }
 
 type documentController struct {
-	service service.Service
-	logger  *zap.Logger
+	service    service.Service
+	jobService jobService.Service
+	logger     *zap.Logger
 }
 
-func NewDocumentController(service service.Service, logger *zap.Logger) Controller {
+func NewDocumentController(service service.Service, jobService jobService.Service, logger *zap.Logger) Controller {
 	return &documentController{
-		service: service,
-		logger:  logger,
+		service:    service,
+		jobService: jobService,
+		logger:     logger,
 	}
 }
 
@@ -47,7 +143,7 @@ func (ctrl *documentController) CreateDocument(c *gin.Context) {
 	
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
-			"code":    "validation_error",
+			"code":    errcode.ValidationError,
 			"message": "Invalid request data",
 			"details": err.Error(),
 		}})
@@ -57,7 +153,7 @@ func (ctrl *documentController) CreateDocument(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
-			"code":    "unauthorized",
+			"code":    errcode.Unauthorized,
 			"message": "User not authenticated",
 		}})
 		return
@@ -65,9 +161,13 @@ func (ctrl *documentController) CreateDocument(c *gin.Context) {
 	
 	document, err := ctrl.service.CreateDocument(c.Request.Context(), userID.(uuid.UUID), req)
 	if err != nil {
-		ctrl.logger.Error("Failed to create document", zap.Error(err))
+		if writeRejectedSave(c, err) {
+			return
+		}
+		
+		logging.FromContext(c.Request.Context(), ctrl.logger).Error("Failed to create document", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
-			"code":    "internal_error",
+			"code":    errcode.InternalError,
 			"message": "Failed to create document",
 		}})
 		return
@@ -76,11 +176,239 @@ func (ctrl *documentController) CreateDocument(c *gin.Context) {
 	c.JSON(http.StatusCreated, document)
 }
 
+// CopyPublicDocument copies a public document into the caller's workspace
+func (ctrl *documentController) CopyPublicDocument(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	var req model.DocumentCopyRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+				"code":    errcode.ValidationError,
+				"message": "Invalid request data",
+				"details": err.Error(),
+			}})
+			return
+		}
+	}
+	
+	document, err := ctrl.service.CopyPublicDocument(c.Request.Context(), documentID, userID.(uuid.UUID), req)
+	if err != nil {
+		if writeRejectedSave(c, err) {
+			return
+		}
+		
+		ctrl.handleReadError(c, err, "Failed to copy document")
+		return
+	}
+	
+	c.JSON(http.StatusCreated, document)
+}
+
+// ForkDocument branches a readable document into the caller's workspace
+func (ctrl *documentController) ForkDocument(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	var req model.DocumentForkRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+				"code":    errcode.ValidationError,
+				"message": "Invalid request data",
+				"details": err.Error(),
+			}})
+			return
+		}
+	}
+	
+	document, err := ctrl.service.ForkDocument(c.Request.Context(), documentID, userID.(uuid.UUID), req)
+	if err != nil {
+		if writeRejectedSave(c, err) {
+			return
+		}
+		
+		ctrl.handleReadError(c, err, "Failed to fork document")
+		return
+	}
+	
+	c.JSON(http.StatusCreated, document)
+}
+
+func (ctrl *documentController) GetForks(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	forks, err := ctrl.service.GetForks(c.Request.Context(), documentID, userID.(uuid.UUID))
+	if err != nil {
+		ctrl.handleReadError(c, err, "Failed to get forks")
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"data": forks})
+}
+
+// ImportDocuments creates one document per file uploaded in the "files"
+// form field and reports each file's outcome
+func (ctrl *documentController) ImportDocuments(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, bulk.MaxItems*importer.MaxFileSize)
+	form, err := c.MultipartForm()
+	if err != nil || len(form.File["files"]) == 0 || len(form.File["files"]) > bulk.MaxItems {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Upload between 1 and 100 files in the files field",
+		}})
+		return
+	}
+	
+	files := form.File["files"]
+	builder := bulk.NewBuilder(len(files))
+	for _, header := range files {
+		document, err := ctrl.importFile(c.Request.Context(), userID.(uuid.UUID), header.Filename, header.Size, header.Open)
+		if err != nil {
+			status, code, message := bulkFailure(err)
+			if status == http.StatusInternalServerError {
+				logging.FromContext(c.Request.Context(), ctrl.logger).Error("Failed to import document",
+					zap.Error(err),
+					zap.String("filename", header.Filename))
+			}
+			builder.Failure(header.Filename, status, code, message)
+			continue
+		}
+		
+		builder.Success(header.Filename, http.StatusCreated, document)
+	}
+	
+	builder.Write(c)
+}
+
+func (ctrl *documentController) importFile(ctx context.Context, userID uuid.UUID, filename string, size int64, open func() (multipart.File, error)) (*model.Document, error) {
+	if size > importer.MaxFileSize {
+		return nil, importer.ErrFileTooLarge
+	}
+	
+	file, err := open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	
+	data, err := io.ReadAll(io.LimitReader(file, importer.MaxFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	
+	return ctrl.service.ImportDocument(ctx, userID, filename, data)
+}
+
+// SyncDocument upserts the caller's document at the path after
+// /documents/by-path/, answering 201 when it was created
+func (ctrl *documentController) SyncDocument(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	var req model.DocumentSyncRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid request data",
+			"details": err.Error(),
+		}})
+		return
+	}
+	
+	document, created, err := ctrl.service.SyncDocument(c.Request.Context(), userID.(uuid.UUID), c.Param("path"), req)
+	if err != nil {
+		if err == service.ErrInvalidSourcePath {
+			c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+				"code":    errcode.ValidationError,
+				"message": err.Error(),
+			}})
+			return
+		}
+		
+		if writeRejectedSave(c, err) {
+			return
+		}
+		
+		ctrl.handleReadError(c, err, "Failed to sync document")
+		return
+	}
+	
+	if created {
+		c.JSON(http.StatusCreated, document)
+		return
+	}
+	
+	c.JSON(http.StatusOK, document)
+}
+
 func (ctrl *documentController) GetDocuments(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
-			"code":    "unauthorized",
+			"code":    errcode.Unauthorized,
 			"message": "User not authenticated",
 		}})
 		return
@@ -92,7 +420,76 @@ func (ctrl *documentController) GetDocuments(c *gin.Context) {
 	sortBy := c.DefaultQuery("sort_by", "updated_at")
 	sortDir := c.DefaultQuery("sort_dir", "desc")
 	
-	query := c.DefaultQuery("q", "")
+	filter := model.DocumentFilter{
+		Query: c.DefaultQuery("q", ""),
+	}
+
+	// locale tailors the order of titles, e.g. locale=sv sorts "Ö" after "Z"
+	if localeParam := c.Query("locale"); localeParam != "" {
+		locale, ok := model.CanonicalLanguage(localeParam)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+				"code":    errcode.ValidationError,
+				"message": "Invalid locale",
+			}})
+			return
+		}
+		filter.SortLocale = locale
+	}
+
+	// folder_id=root lists documents outside any folder
+	if folderParam := c.Query("folder_id"); folderParam != "" {
+		folderID := model.RootFolder
+		if folderParam != "root" {
+			parsed, err := uuid.Parse(folderParam)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+					"code":    errcode.ValidationError,
+					"message": "Invalid folder ID",
+				}})
+				return
+			}
+			folderID = parsed
+		}
+		filter.FolderID = &folderID
+	}
+
+	// Archived documents are hidden unless include_archived=true
+	if archivedParam := c.Query("include_archived"); archivedParam != "" {
+		includeArchived, err := strconv.ParseBool(archivedParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+				"code":    errcode.ValidationError,
+				"message": "Invalid include_archived filter",
+			}})
+			return
+		}
+		filter.IncludeArchived = includeArchived
+	}
+
+	if stateParam := c.Query("state"); stateParam != "" {
+		state := model.State(stateParam)
+		if !state.Valid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+				"code":    errcode.ValidationError,
+				"message": "Invalid state filter",
+			}})
+			return
+		}
+		filter.State = &state
+	}
+
+	if staleParam := c.Query("stale"); staleParam != "" {
+		stale, err := strconv.ParseBool(staleParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+				"code":    errcode.ValidationError,
+				"message": "Invalid stale filter",
+			}})
+			return
+		}
+		filter.Stale = &stale
+	}
 	
 	documents, total, err := ctrl.service.GetUserDocuments(
 		c.Request.Context(),
@@ -101,13 +498,13 @@ func (ctrl *documentController) GetDocuments(c *gin.Context) {
 		perPage,
 		sortBy,
 		sortDir,
-		query,
+		filter,
 	)
 	
 	if err != nil {
-		ctrl.logger.Error("Failed to get documents", zap.Error(err))
+		logging.FromContext(c.Request.Context(), ctrl.logger).Error("Failed to get documents", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
-			"code":    "internal_error",
+			"code":    errcode.InternalError,
 			"message": "Failed to retrieve documents",
 		}})
 		return
@@ -131,7 +528,7 @@ func (ctrl *documentController) GetDocumentByID(c *gin.Context) {
 	documentID, err := uuid.Parse(idStr)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
-			"code":    "validation_error",
+			"code":    errcode.ValidationError,
 			"message": "Invalid document ID",
 		}})
 		return
@@ -140,7 +537,7 @@ func (ctrl *documentController) GetDocumentByID(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
-			"code":    "unauthorized",
+			"code":    errcode.Unauthorized,
 			"message": "User not authenticated",
 		}})
 		return
@@ -161,7 +558,7 @@ func (ctrl *documentController) GetDocumentByID(c *gin.Context) {
 	if err != nil {
 		if err == service.ErrDocumentNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
-				"code":    "not_found",
+				"code":    errcode.DocNotFound,
 				"message": "Document not found",
 			}})
 			return
@@ -169,20 +566,36 @@ func (ctrl *documentController) GetDocumentByID(c *gin.Context) {
 		
 		if err == service.ErrUnauthorized {
 			c.JSON(http.StatusForbidden, gin.H{"error": gin.H{
-				"code":    "forbidden",
+				"code":    errcode.Forbidden,
 				"message": "You don't have permission to access this document",
 			}})
 			return
 		}
 		
-		ctrl.logger.Error("Failed to get document", zap.Error(err))
+		if err == service.ErrTermsNotAccepted {
+			writeTermsNotAccepted(c)
+			return
+		}
+		
+		logging.FromContext(c.Request.Context(), ctrl.logger).Error("Failed to get document", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
-			"code":    "internal_error",
+			"code":    errcode.InternalError,
 			"message": "Failed to retrieve document",
 		}})
 		return
 	}
 	
+	// ?include=draft adds the caller's draft; the ETag stays that of the
+	// saved document
+	if c.Query("include") == "draft" {
+		document.Draft, err = ctrl.service.GetDraft(c.Request.Context(), documentID, userID.(uuid.UUID))
+		if err != nil {
+			ctrl.handleReadError(c, err, "Failed to retrieve draft")
+			return
+		}
+	}
+	
+	c.Header("ETag", documentETag(document))
 	c.JSON(http.StatusOK, document)
 }
 
@@ -191,7 +604,7 @@ func (ctrl *documentController) UpdateDocument(c *gin.Context) {
 	documentID, err := uuid.Parse(idStr)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
-			"code":    "validation_error",
+			"code":    errcode.ValidationError,
 			"message": "Invalid document ID",
 		}})
 		return
@@ -200,7 +613,7 @@ func (ctrl *documentController) UpdateDocument(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
-			"code":    "unauthorized",
+			"code":    errcode.Unauthorized,
 			"message": "User not authenticated",
 		}})
 		return
@@ -209,13 +622,22 @@ func (ctrl *documentController) UpdateDocument(c *gin.Context) {
 	var req model.DocumentUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
-			"code":    "validation_error",
+			"code":    errcode.ValidationError,
 			"message": "Invalid request data",
 			"details": err.Error(),
 		}})
 		return
 	}
 	
+	version, ok := ifMatchVersion(c.GetHeader("If-Match"), documentID)
+	if !ok {
+		writeVersionConflict(c)
+		return
+	}
+	req.IfVersion = version
+	// Realtime clients send the client_id of their welcome message
+	req.OriginClientID = c.GetHeader("X-Client-ID")
+	
 	document, err := ctrl.service.UpdateDocument(
 		c.Request.Context(),
 		documentID,
@@ -226,7 +648,7 @@ func (ctrl *documentController) UpdateDocument(c *gin.Context) {
 	if err != nil {
 		if err == service.ErrDocumentNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
-				"code":    "not_found",
+				"code":    errcode.DocNotFound,
 				"message": "Document not found",
 			}})
 			return
@@ -234,29 +656,90 @@ func (ctrl *documentController) UpdateDocument(c *gin.Context) {
 		
 		if err == service.ErrUnauthorized {
 			c.JSON(http.StatusForbidden, gin.H{"error": gin.H{
-				"code":    "forbidden",
+				"code":    errcode.Forbidden,
 				"message": "You don't have permission to update this document",
 			}})
 			return
 		}
 		
-		ctrl.logger.Error("Failed to update document", zap.Error(err))
+		if err == service.ErrVersionConflict {
+			writeVersionConflict(c)
+			return
+		}
+		
+		var conflict *service.ConflictError
+		if errors.As(err, &conflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": gin.H{
+				"code":            errcode.EditConflict,
+				"message":         "Document has changed since the expected version",
+				"current_version": conflict.Document.Version,
+				"content":         conflict.Document.Content,
+			}})
+			return
+		}
+		
+		if writeRejectedSave(c, err) {
+			return
+		}
+		
+		logging.FromContext(c.Request.Context(), ctrl.logger).Error("Failed to update document", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
-			"code":    "internal_error",
+			"code":    errcode.InternalError,
 			"message": "Failed to update document",
 		}})
 		return
 	}
 	
+	c.Header("ETag", documentETag(document))
 	c.JSON(http.StatusOK, document)
 }
 
-func (ctrl *documentController) DeleteDocument(c *gin.Context) {
-	idStr := c.Param("id")
-	documentID, err := uuid.Parse(idStr)
+// documentETag identifies the version of a document. Every save bumps the
+// version, so clients send it back in If-Match to update only the version
+// they read.
+func documentETag(document *model.Document) string {
+	return fmt.Sprintf(`"%s-%d"`, document.ID, document.Version)
+}
+
+// ifMatchVersion returns the version an If-Match header asks to update.
+// It is nil without a header or for "*", and ok is false when no listed
+// tag is a version of documentID; weak tags never match.
+func ifMatchVersion(header string, documentID uuid.UUID) (*int, bool) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return nil, true
+	}
+
+	prefix := documentID.String() + "-"
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) || len(tag) < 2 {
+			continue
+		}
+		tag = strings.Trim(tag, `"`)
+		if !strings.HasPrefix(tag, prefix) {
+			continue
+		}
+		if version, err := strconv.Atoi(strings.TrimPrefix(tag, prefix)); err == nil {
+			return &version, true
+		}
+	}
+	return nil, false
+}
+
+// writeVersionConflict answers an update whose If-Match no longer matches
+func writeVersionConflict(c *gin.Context) {
+	c.JSON(http.StatusPreconditionFailed, gin.H{"error": gin.H{
+		"code":    errcode.VersionConflict,
+		"message": "Document has changed since the version in If-Match",
+	}})
+}
+
+func (ctrl *documentController) MergeDocument(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
-			"code":    "validation_error",
+			"code":    errcode.ValidationError,
 			"message": "Invalid document ID",
 		}})
 		return
@@ -265,52 +748,48 @@ func (ctrl *documentController) DeleteDocument(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
-			"code":    "unauthorized",
+			"code":    errcode.Unauthorized,
 			"message": "User not authenticated",
 		}})
 		return
 	}
 	
-	err = ctrl.service.DeleteDocument(
-		c.Request.Context(),
-		documentID,
-		userID.(uuid.UUID),
-	)
+	var req model.DocumentMergeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid request data",
+			"details": err.Error(),
+		}})
+		return
+	}
 	
+	result, err := ctrl.service.MergeDocument(c.Request.Context(), documentID, userID.(uuid.UUID), req)
 	if err != nil {
-		if err == service.ErrDocumentNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
-				"code":    "not_found",
-				"message": "Document not found",
+		if err == service.ErrMergeSameDocument {
+			c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+				"code":    errcode.ValidationError,
+				"message": "A document cannot be merged into itself",
 			}})
 			return
 		}
 		
-		if err == service.ErrUnauthorized {
-			c.JSON(http.StatusForbidden, gin.H{"error": gin.H{
-				"code":    "forbidden",
-				"message": "You don't have permission to delete this document",
-			}})
+		if writeRejectedSave(c, err) {
 			return
 		}
 		
-		ctrl.logger.Error("Failed to delete document", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
-			"code":    "internal_error",
-			"message": "Failed to delete document",
-		}})
+		ctrl.handleReadError(c, err, "Failed to merge document")
 		return
 	}
 	
-	c.Status(http.StatusNoContent)
+	c.JSON(http.StatusOK, result)
 }
 
-func (ctrl *documentController) GetDocumentHistory(c *gin.Context) {
-	idStr := c.Param("id")
-	documentID, err := uuid.Parse(idStr)
+func (ctrl *documentController) SetReviewDate(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
-			"code":    "validation_error",
+			"code":    errcode.ValidationError,
 			"message": "Invalid document ID",
 		}})
 		return
@@ -319,119 +798,110 @@ func (ctrl *documentController) GetDocumentHistory(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
-			"code":    "unauthorized",
+			"code":    errcode.Unauthorized,
 			"message": "User not authenticated",
 		}})
 		return
 	}
 	
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
-	
-	history, total, err := ctrl.service.GetDocumentHistory(
-		c.Request.Context(),
-		documentID,
-		userID.(uuid.UUID),
-		page,
-		perPage,
-	)
+	var req model.DocumentReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid request data",
+			"details": err.Error(),
+		}})
+		return
+	}
 	
+	document, err := ctrl.service.SetReviewDate(c.Request.Context(), documentID, userID.(uuid.UUID), req)
 	if err != nil {
+		if err == service.ErrDocumentNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+				"code":    errcode.DocNotFound,
+				"message": "Document not found",
+			}})
+			return
+		}
+		
 		if err == service.ErrUnauthorized {
 			c.JSON(http.StatusForbidden, gin.H{"error": gin.H{
-				"code":    "forbidden",
-				"message": "You don't have permission to access this document",
+				"code":    errcode.Forbidden,
+				"message": "You don't have permission to review this document",
 			}})
 			return
 		}
 		
-		ctrl.logger.Error("Failed to get document history", zap.Error(err))
+		logging.FromContext(c.Request.Context(), ctrl.logger).Error("Failed to set review date", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
-			"code":    "internal_error",
-			"message": "Failed to retrieve document history",
+			"code":    errcode.InternalError,
+			"message": "Failed to set review date",
 		}})
 		return
 	}
 	
-	totalPages := (int(total) + perPage - 1) / perPage
-	
-	c.JSON(http.StatusOK, gin.H{
-		"data": history,
-		"pagination": gin.H{
-			"total":       total,
-			"page":        page,
-			"per_page":    perPage,
-			"total_pages": totalPages,
-		},
-	})
+	c.JSON(http.StatusOK, document)
 }
 
-func (ctrl *documentController) RestoreDocumentVersion(c *gin.Context) {
-	idStr := c.Param("id")
-	documentID, err := uuid.Parse(idStr)
+func (ctrl *documentController) SetDocumentExpiry(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
-			"code":    "validation_error",
+			"code":    errcode.ValidationError,
 			"message": "Invalid document ID",
 		}})
 		return
 	}
 	
-	versionStr := c.Param("version")
-	version, err := strconv.Atoi(versionStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
-			"code":    "validation_error",
-			"message": "Invalid version number",
-		}})
-		return
-	}
-	
 	userID, exists := c.Get("userID")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
-			"code":    "unauthorized",
+			"code":    errcode.Unauthorized,
 			"message": "User not authenticated",
 		}})
 		return
 	}
 	
-	document, err := ctrl.service.RestoreDocumentVersion(
-		c.Request.Context(),
-		documentID,
-		userID.(uuid.UUID),
-		version,
-	)
+	var req model.DocumentExpiryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid request data",
+			"details": err.Error(),
+		}})
+		return
+	}
 	
+	document, err := ctrl.service.SetExpiry(c.Request.Context(), documentID, userID.(uuid.UUID), req)
 	if err != nil {
-		if err == service.ErrDocumentNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
-				"code":    "not_found",
-				"message": "Document not found",
+		if err == service.ErrExpiryInPast {
+			c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+				"code":    errcode.ValidationError,
+				"message": "Expiry must be in the future",
 			}})
 			return
 		}
 		
-		if err == service.ErrVersionNotFound {
+		if err == service.ErrDocumentNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
-				"code":    "not_found",
-				"message": "Document version not found",
+				"code":    errcode.DocNotFound,
+				"message": "Document not found",
 			}})
 			return
 		}
 		
 		if err == service.ErrUnauthorized {
 			c.JSON(http.StatusForbidden, gin.H{"error": gin.H{
-				"code":    "forbidden",
-				"message": "You don't have permission to restore this document",
+				"code":    errcode.Forbidden,
+				"message": "Only the owner can set a document's expiry",
 			}})
 			return
 		}
 		
-		ctrl.logger.Error("Failed to restore document version", zap.Error(err))
+		logging.FromContext(c.Request.Context(), ctrl.logger).Error("Failed to set document expiry", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
-			"code":    "internal_error",
-			"message": "Failed to restore document version",
+			"code":    errcode.InternalError,
+			"message": "Failed to set document expiry",
 		}})
 		return
 	}
@@ -439,12 +909,11 @@ func (ctrl *documentController) RestoreDocumentVersion(c *gin.Context) {
 	c.JSON(http.StatusOK, document)
 }
 
-func (ctrl *documentController) ShareDocument(c *gin.Context) {
-	idStr := c.Param("id")
-	documentID, err := uuid.Parse(idStr)
+func (ctrl *documentController) TransitionDocument(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
-			"code":    "validation_error",
+			"code":    errcode.ValidationError,
 			"message": "Invalid document ID",
 		}})
 		return
@@ -453,236 +922,212 @@ func (ctrl *documentController) ShareDocument(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
-			"code":    "unauthorized",
+			"code":    errcode.Unauthorized,
 			"message": "User not authenticated",
 		}})
 		return
 	}
 	
-	var req model.CollaboratorCreateRequest
+	var req model.DocumentTransitionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
-			"code":    "validation_error",
+			"code":    errcode.ValidationError,
 			"message": "Invalid request data",
 			"details": err.Error(),
 		}})
 		return
 	}
 	
-	collaborator, err := ctrl.service.ShareDocument(
-		c.Request.Context(),
-		documentID,
-		userID.(uuid.UUID),
-		req,
-	)
-	
+	document, err := ctrl.service.TransitionDocument(c.Request.Context(), documentID, userID.(uuid.UUID), req.State)
 	if err != nil {
-		if err == service.ErrDocumentNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
-				"code":    "not_found",
-				"message": "Document not found",
-			}})
-			return
-		}
-		
-		if err == service.ErrUserNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
-				"code":    "not_found",
-				"message": "User not found",
-			}})
-			return
-		}
-		
-		if err == service.ErrAlreadyCollaborator {
+		if err == service.ErrInvalidTransition {
 			c.JSON(http.StatusConflict, gin.H{"error": gin.H{
-				"code":    "conflict",
-				"message": "User is already a collaborator",
+				"code":    errcode.InvalidState,
+				"message": "Document cannot move from its current state to " + string(req.State),
 			}})
 			return
 		}
 		
-		if err == service.ErrUnauthorized {
-			c.JSON(http.StatusForbidden, gin.H{"error": gin.H{
-				"code":    "forbidden",
-				"message": "You don't have permission to share this document",
-			}})
+		if writeRejectedSave(c, err) {
 			return
 		}
 		
-		ctrl.logger.Error("Failed to share document", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
-			"code":    "internal_error",
-			"message": "Failed to share document",
-		}})
+		ctrl.handleReadError(c, err, "Failed to change document state")
 		return
 	}
 	
-	c.JSON(http.StatusOK, collaborator)
+	c.JSON(http.StatusOK, document)
 }
 
-func (ctrl *documentController) UpdateCollaboratorPermission(c *gin.Context) {
-	idStr := c.Param("id")
-	documentID, err := uuid.Parse(idStr)
+func (ctrl *documentController) SetDocumentAlias(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
-			"code":    "validation_error",
+			"code":    errcode.ValidationError,
 			"message": "Invalid document ID",
 		}})
 		return
 	}
 	
-	userIDStr := c.Param("user_id")
-	collaboratorUserID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
-			"code":    "validation_error",
-			"message": "Invalid user ID",
-		}})
-		return
-	}
-	
 	userID, exists := c.Get("userID")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
-			"code":    "unauthorized",
+			"code":    errcode.Unauthorized,
 			"message": "User not authenticated",
 		}})
 		return
 	}
 	
-	var req model.CollaboratorUpdateRequest
+	var req model.DocumentAliasRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
-			"code":    "validation_error",
+			"code":    errcode.ValidationError,
 			"message": "Invalid request data",
 			"details": err.Error(),
 		}})
 		return
 	}
 	
-	collaborator, err := ctrl.service.UpdateCollaboratorPermission(
-		c.Request.Context(),
-		documentID,
-		userID.(uuid.UUID),
-		collaboratorUserID,
-		req,
-	)
-	
+	document, err := ctrl.service.SetAlias(c.Request.Context(), documentID, userID.(uuid.UUID), req.Alias)
 	if err != nil {
-		if err == service.ErrDocumentNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
-				"code":    "not_found",
-				"message": "Document not found",
-			}})
-			return
-		}
-		
-		if err == service.ErrNotCollaborator {
-			c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
-				"code":    "not_found",
-				"message": "User is not a collaborator",
+		if err == service.ErrInvalidAlias || err == service.ErrReservedAlias {
+			c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+				"code":    errcode.ValidationError,
+				"message": err.Error(),
 			}})
 			return
 		}
 		
-		if err == service.ErrUnauthorized {
-			c.JSON(http.StatusForbidden, gin.H{"error": gin.H{
-				"code":    "forbidden",
-				"message": "You don't have permission to update collaborator permissions",
+		if err == service.ErrAliasTaken {
+			c.JSON(http.StatusConflict, gin.H{"error": gin.H{
+				"code":    errcode.AliasTaken,
+				"message": "Alias is already in use",
 			}})
 			return
 		}
 		
-		ctrl.logger.Error("Failed to update collaborator permission", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
-			"code":    "internal_error",
-			"message": "Failed to update collaborator permission",
-		}})
+		ctrl.handleReadError(c, err, "Failed to set document alias")
 		return
 	}
 	
-	c.JSON(http.StatusOK, collaborator)
+	c.JSON(http.StatusOK, document)
 }
 
-func (ctrl *documentController) RemoveCollaborator(c *gin.Context) {
-	idStr := c.Param("id")
-	documentID, err := uuid.Parse(idStr)
+func (ctrl *documentController) RemoveDocumentAlias(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
-			"code":    "validation_error",
+			"code":    errcode.ValidationError,
 			"message": "Invalid document ID",
 		}})
 		return
 	}
 	
-	userIDStr := c.Param("user_id")
-	collaboratorUserID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
-			"code":    "validation_error",
-			"message": "Invalid user ID",
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
 		}})
 		return
 	}
 	
+	document, err := ctrl.service.RemoveAlias(c.Request.Context(), documentID, userID.(uuid.UUID))
+	if err != nil {
+		ctrl.handleReadError(c, err, "Failed to remove document alias")
+		return
+	}
+	
+	c.JSON(http.StatusOK, document)
+}
+
+// ResolveAlias serves a document by its alias. Replaced aliases answer
+// with a permanent redirect to where the document now lives.
+func (ctrl *documentController) ResolveAlias(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
-			"code":    "unauthorized",
+			"code":    errcode.Unauthorized,
 			"message": "User not authenticated",
 		}})
 		return
 	}
 	
-	err = ctrl.service.RemoveCollaborator(
+	document, location, err := ctrl.service.ResolveAlias(
 		c.Request.Context(),
-		documentID,
+		c.Param("alias"),
 		userID.(uuid.UUID),
-		collaboratorUserID,
+		c.ClientIP(),
+		c.Request.UserAgent(),
 	)
-	
 	if err != nil {
-		if err == service.ErrDocumentNotFound {
+		if err == service.ErrAliasNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
-				"code":    "not_found",
-				"message": "Document not found",
+				"code":    errcode.AliasNotFound,
+				"message": "Alias not found",
 			}})
 			return
 		}
 		
-		if err == service.ErrCannotRemoveOwner {
-			c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
-				"code":    "validation_error",
-				"message": "Cannot remove document owner as collaborator",
-			}})
-			return
-		}
-		
-		if err == service.ErrUnauthorized {
-			c.JSON(http.StatusForbidden, gin.H{"error": gin.H{
-				"code":    "forbidden",
-				"message": "You don't have permission to remove collaborators",
+		ctrl.handleReadError(c, err, "Failed to resolve alias")
+		return
+	}
+	
+	if location != "" {
+		c.Redirect(http.StatusMovedPermanently, location)
+		return
+	}
+	
+	c.JSON(http.StatusOK, document)
+}
+
+// ResolveShortCode returns the document with the short code in the path
+func (ctrl *documentController) ResolveShortCode(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	document, err := ctrl.service.ResolveShortCode(
+		c.Request.Context(),
+		c.Param("code"),
+		userID.(uuid.UUID),
+		c.ClientIP(),
+		c.Request.UserAgent(),
+	)
+	if err != nil {
+		if err == service.ErrShortCodeNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+				"code":    errcode.ShortCodeNotFound,
+				"message": "Short code not found",
 			}})
 			return
 		}
 		
-		ctrl.logger.Error("Failed to remove collaborator", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
-			"code":    "internal_error",
-			"message": "Failed to remove collaborator",
-		}})
+		ctrl.handleReadError(c, err, "Failed to resolve short code")
 		return
 	}
 	
-	c.Status(http.StatusNoContent)
+	c.JSON(http.StatusOK, document)
 }
 
-func (ctrl *documentController) GetDocumentAnalytics(c *gin.Context) {
-	idStr := c.Param("id")
-	documentID, err := uuid.Parse(idStr)
+func (ctrl *documentController) ArchiveDocument(c *gin.Context) {
+	ctrl.setArchived(c, ctrl.service.ArchiveDocument, "Failed to archive document")
+}
+
+func (ctrl *documentController) UnarchiveDocument(c *gin.Context) {
+	ctrl.setArchived(c, ctrl.service.UnarchiveDocument, "Failed to unarchive document")
+}
+
+func (ctrl *documentController) setArchived(c *gin.Context, action func(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*model.Document, error), message string) {
+	documentID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
-			"code":    "validation_error",
+			"code":    errcode.ValidationError,
 			"message": "Invalid document ID",
 		}})
 		return
@@ -691,67 +1136,3064 @@ func (ctrl *documentController) GetDocumentAnalytics(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
-			"code":    "unauthorized",
+			"code":    errcode.Unauthorized,
 			"message": "User not authenticated",
 		}})
 		return
 	}
 	
-	period := c.DefaultQuery("period", "month")
-	
-	analytics, err := ctrl.service.GetDocumentAnalytics(
-		c.Request.Context(),
-		documentID,
-		userID.(uuid.UUID),
-		period,
-	)
-	
+	document, err := action(c.Request.Context(), documentID, userID.(uuid.UUID))
 	if err != nil {
-		if err == service.ErrUnauthorized {
-			c.JSON(http.StatusForbidden, gin.H{"error": gin.H{
-				"code":    "forbidden",
-				"message": "You don't have permission to access this document",
-			}})
-			return
-		}
-		
-		ctrl.logger.Error("Failed to get document analytics", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
-			"code":    "internal_error",
-			"message": "Failed to retrieve document analytics",
-		}})
+		ctrl.handleReadError(c, err, message)
 		return
 	}
 	
-	c.JSON(http.StatusOK, analytics)
+	c.JSON(http.StatusOK, document)
 }
 
-func (ctrl *documentController) GetUserAnalytics(c *gin.Context) {
+func (ctrl *documentController) LintDocument(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
 	userID, exists := c.Get("userID")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
-			"code":    "unauthorized",
+			"code":    errcode.Unauthorized,
 			"message": "User not authenticated",
 		}})
 		return
 	}
 	
-	period := c.DefaultQuery("period", "month")
-	
-	analytics, err := ctrl.service.GetUserAnalytics(
-		c.Request.Context(),
-		userID.(uuid.UUID),
-		period,
-	)
+	report, err := ctrl.service.LintDocument(c.Request.Context(), documentID, userID.(uuid.UUID))
+	if err != nil {
+		ctrl.handleReadError(c, err, "Failed to lint document")
+		return
+	}
 	
+	c.JSON(http.StatusOK, report)
+}
+
+func (ctrl *documentController) GetDocumentOutline(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		ctrl.logger.Error("Failed to get user analytics", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
-			"code":    "internal_error",
-			"message": "Failed to retrieve user analytics",
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
 		}})
 		return
 	}
 	
-	c.JSON(http.StatusOK, analytics)
-}
\ No newline at end of file
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	headings, err := ctrl.service.GetOutline(c.Request.Context(), documentID, userID.(uuid.UUID))
+	if err != nil {
+		ctrl.handleReadError(c, err, "Failed to get document outline")
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"data": headings})
+}
+
+// maxSearchQuery caps the length of a find-in-document query, in bytes
+const maxSearchQuery = 200
+
+// Matches returned by find-in-document unless ?limit= says otherwise, and
+// the most it may ask for
+const (
+	defaultSearchLimit = 100
+	maxSearchLimit     = 1000
+)
+
+// SearchDocument finds ?q= in the content. Matching ignores case unless
+// ?case_sensitive=true.
+func (ctrl *documentController) SearchDocument(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	query := find.Query{Text: c.Query("q"), Limit: defaultSearchLimit}
+	if query.Text == "" || len(query.Text) > maxSearchQuery {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": fmt.Sprintf("Query q is required and may be at most %d bytes", maxSearchQuery),
+		}})
+		return
+	}
+	
+	if limit := c.Query("limit"); limit != "" {
+		query.Limit, err = strconv.Atoi(limit)
+		if err != nil || query.Limit < 1 || query.Limit > maxSearchLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+				"code":    errcode.ValidationError,
+				"message": fmt.Sprintf("Limit must be between 1 and %d", maxSearchLimit),
+			}})
+			return
+		}
+	}
+	
+	query.CaseSensitive, _ = strconv.ParseBool(c.DefaultQuery("case_sensitive", "false"))
+	
+	result, err := ctrl.service.SearchDocument(c.Request.Context(), documentID, userID.(uuid.UUID), query)
+	if err != nil {
+		ctrl.handleReadError(c, err, "Failed to search document")
+		return
+	}
+	
+	c.JSON(http.StatusOK, result)
+}
+
+func (ctrl *documentController) GetBacklinks(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	backlinks, err := ctrl.service.GetBacklinks(c.Request.Context(), documentID, userID.(uuid.UUID))
+	if err != nil {
+		ctrl.handleReadError(c, err, "Failed to get backlinks")
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"data": backlinks})
+}
+
+// CheckPermissions returns the caller's permission on each requested
+// document, for dashboards rendering many documents at once
+func (ctrl *documentController) CheckPermissions(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	var req model.PermissionCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid request data",
+			"details": err.Error(),
+		}})
+		return
+	}
+	
+	permissions, err := ctrl.service.CheckPermissions(c.Request.Context(), userID.(uuid.UUID), req.DocumentIDs)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), ctrl.logger).Error("Failed to check permissions", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
+			"code":    errcode.InternalError,
+			"message": "Failed to check permissions",
+		}})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"data": permissions})
+}
+
+func (ctrl *documentController) LockDocument(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	// The body is optional; an empty one locks for documents.lock_ttl
+	var req model.DocumentLockRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid request data",
+			"details": err.Error(),
+		}})
+		return
+	}
+	
+	lock, err := ctrl.service.LockDocument(c.Request.Context(), documentID, userID.(uuid.UUID), req)
+	if err != nil {
+		if writeLocked(c, err) {
+			return
+		}
+		
+		ctrl.handleReadError(c, err, "Failed to lock document")
+		return
+	}
+	
+	c.JSON(http.StatusOK, lock)
+}
+
+func (ctrl *documentController) UnlockDocument(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	if err := ctrl.service.UnlockDocument(c.Request.Context(), documentID, userID.(uuid.UUID)); err != nil {
+		if writeLocked(c, err) {
+			return
+		}
+		
+		ctrl.handleReadError(c, err, "Failed to unlock document")
+		return
+	}
+	
+	c.Status(http.StatusNoContent)
+}
+
+// GetDocumentLock returns the current lock, or null when nobody holds one
+func (ctrl *documentController) GetDocumentLock(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	lock, err := ctrl.service.GetLock(c.Request.Context(), documentID, userID.(uuid.UUID))
+	if err != nil {
+		ctrl.handleReadError(c, err, "Failed to get document lock")
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"data": lock})
+}
+
+// SaveDraft replaces the caller's draft without touching the document
+func (ctrl *documentController) SaveDraft(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	var req model.DocumentDraftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid request data",
+			"details": err.Error(),
+		}})
+		return
+	}
+	
+	draft, err := ctrl.service.SaveDraft(c.Request.Context(), documentID, userID.(uuid.UUID), req)
+	if err != nil {
+		if err == service.ErrContentTooLarge {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": gin.H{
+				"code":    errcode.ContentTooLarge,
+				"message": "Draft content exceeds the maximum size",
+			}})
+			return
+		}
+		
+		ctrl.handleReadError(c, err, "Failed to save draft")
+		return
+	}
+	
+	c.JSON(http.StatusOK, draft)
+}
+
+// GetDraft returns the caller's draft, or null when they have none
+func (ctrl *documentController) GetDraft(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	draft, err := ctrl.service.GetDraft(c.Request.Context(), documentID, userID.(uuid.UUID))
+	if err != nil {
+		ctrl.handleReadError(c, err, "Failed to get draft")
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"data": draft})
+}
+
+func (ctrl *documentController) DiscardDraft(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	if err := ctrl.service.DiscardDraft(c.Request.Context(), documentID, userID.(uuid.UUID)); err != nil {
+		logging.FromContext(c.Request.Context(), ctrl.logger).Error("Failed to discard draft", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
+			"code":    errcode.InternalError,
+			"message": "Failed to discard draft",
+		}})
+		return
+	}
+	
+	c.Status(http.StatusNoContent)
+}
+
+func (ctrl *documentController) PinDocument(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	if err := ctrl.service.PinDocument(c.Request.Context(), documentID, userID.(uuid.UUID)); err != nil {
+		ctrl.handleReadError(c, err, "Failed to pin document")
+		return
+	}
+	
+	c.Status(http.StatusNoContent)
+}
+
+func (ctrl *documentController) UnpinDocument(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	if err := ctrl.service.UnpinDocument(c.Request.Context(), documentID, userID.(uuid.UUID)); err != nil {
+		ctrl.handleReadError(c, err, "Failed to unpin document")
+		return
+	}
+	
+	c.Status(http.StatusNoContent)
+}
+
+func (ctrl *documentController) SetDocumentTerms(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	var req model.DocumentTermsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid request data",
+			"details": err.Error(),
+		}})
+		return
+	}
+	
+	terms, err := ctrl.service.SetTerms(c.Request.Context(), documentID, userID.(uuid.UUID), req)
+	if err != nil {
+		ctrl.handleReadError(c, err, "Failed to set document terms")
+		return
+	}
+	
+	c.JSON(http.StatusOK, terms)
+}
+
+func (ctrl *documentController) GetDocumentTerms(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	terms, err := ctrl.service.GetTerms(c.Request.Context(), documentID, userID.(uuid.UUID))
+	if err != nil {
+		ctrl.handleReadError(c, err, "Failed to get document terms")
+		return
+	}
+	
+	c.JSON(http.StatusOK, terms)
+}
+
+// AcceptDocumentTerms records the caller's acceptance with their IP address
+func (ctrl *documentController) AcceptDocumentTerms(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	terms, err := ctrl.service.AcceptTerms(c.Request.Context(), documentID, userID.(uuid.UUID), c.ClientIP())
+	if err != nil {
+		ctrl.handleReadError(c, err, "Failed to accept document terms")
+		return
+	}
+	
+	c.JSON(http.StatusOK, terms)
+}
+
+func (ctrl *documentController) GetTermsAcceptances(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	acceptances, err := ctrl.service.GetTermsAcceptances(c.Request.Context(), documentID, userID.(uuid.UUID))
+	if err != nil {
+		ctrl.handleReadError(c, err, "Failed to get terms acceptances")
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"data": acceptances})
+}
+
+func (ctrl *documentController) GetSectionSubscriptions(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	subscriptions, err := ctrl.service.GetSectionSubscriptions(c.Request.Context(), documentID, userID.(uuid.UUID))
+	if err != nil {
+		ctrl.handleReadError(c, err, "Failed to get section subscriptions")
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"data": subscriptions})
+}
+
+func (ctrl *documentController) SubscribeToSection(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	var req model.SectionSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid request data",
+			"details": err.Error(),
+		}})
+		return
+	}
+	
+	subscription, err := ctrl.service.SubscribeToSection(c.Request.Context(), documentID, userID.(uuid.UUID), req.Anchor)
+	if err != nil {
+		if err == service.ErrSectionNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+				"code":    errcode.SectionNotFound,
+				"message": "Section not found",
+			}})
+			return
+		}
+		
+		ctrl.handleReadError(c, err, "Failed to subscribe to section")
+		return
+	}
+	
+	c.JSON(http.StatusCreated, subscription)
+}
+
+func (ctrl *documentController) UnsubscribeFromSection(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	if err := ctrl.service.UnsubscribeFromSection(c.Request.Context(), documentID, userID.(uuid.UUID), c.Param("anchor")); err != nil {
+		ctrl.handleReadError(c, err, "Failed to unsubscribe from section")
+		return
+	}
+	
+	c.Status(http.StatusNoContent)
+}
+
+func (ctrl *documentController) GetDocumentStorage(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	storage, err := ctrl.service.GetDocumentStorage(c.Request.Context(), documentID, userID.(uuid.UUID))
+	if err != nil {
+		ctrl.handleReadError(c, err, "Failed to get document storage")
+		return
+	}
+	
+	c.JSON(http.StatusOK, storage)
+}
+
+func (ctrl *documentController) GetDocumentStats(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	stats, err := ctrl.service.GetDocumentStats(c.Request.Context(), documentID, userID.(uuid.UUID))
+	if err != nil {
+		ctrl.handleReadError(c, err, "Failed to get document stats")
+		return
+	}
+	
+	c.JSON(http.StatusOK, stats)
+}
+
+// RenderDocumentHTML renders the content as HTML; headings carry the same
+// anchors as the outline, so /html#anchor deep links work
+func (ctrl *documentController) RenderDocumentHTML(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	html, err := ctrl.service.RenderHTML(c.Request.Context(), documentID, userID.(uuid.UUID))
+	if err != nil {
+		ctrl.handleReadError(c, err, "Failed to render document")
+		return
+	}
+	
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(html))
+}
+
+// GetPublicDocument serves a public document to anonymous readers. With
+// .json after the ID it serves the document as blocks for static site
+// generators instead; the route cannot tell the two apart.
+func (ctrl *documentController) GetPublicDocument(c *gin.Context) {
+	if id, ok := strings.CutSuffix(c.Param("id"), ".json"); ok {
+		ctrl.getPublishedDocument(c, id)
+		return
+	}
+	
+	document, ok := ctrl.publicDocument(c)
+	if !ok {
+		return
+	}
+	
+	c.JSON(http.StatusOK, document)
+}
+
+// getPublishedDocument serves GET /public/documents/:id.json
+func (ctrl *documentController) getPublishedDocument(c *gin.Context, id string) {
+	documentID, err := uuid.Parse(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	document, policy, err := ctrl.service.GetPublishedDocument(c.Request.Context(), documentID)
+	if err != nil {
+		ctrl.handleReadError(c, err, "Failed to get published document")
+		return
+	}
+	
+	if ctrl.notModified(c, publicETag(document.ID, document.Version, document.Alias), policy) {
+		return
+	}
+	
+	c.JSON(http.StatusOK, document)
+}
+
+// GetPublicDocumentByAlias serves a public document at its readable link,
+// redirecting replaced aliases
+func (ctrl *documentController) GetPublicDocumentByAlias(c *gin.Context) {
+	document, policy, location, err := ctrl.service.GetPublicDocumentByAlias(
+		c.Request.Context(),
+		c.Param("alias"),
+		c.ClientIP(),
+		c.Request.UserAgent(),
+	)
+	if err != nil {
+		if err == service.ErrAliasNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+				"code":    errcode.AliasNotFound,
+				"message": "Alias not found",
+			}})
+			return
+		}
+		
+		ctrl.handleReadError(c, err, "Failed to get public document")
+		return
+	}
+	
+	if location != "" {
+		c.Redirect(http.StatusMovedPermanently, location)
+		return
+	}
+	
+	if ctrl.notModified(c, publicETag(document.ID, document.Version, document.Alias), policy) {
+		return
+	}
+	
+	c.JSON(http.StatusOK, document)
+}
+
+// GetPublicSnapshot serves a published snapshot. Snapshots never change, so
+// the ID is the ETag.
+func (ctrl *documentController) GetPublicSnapshot(c *gin.Context) {
+	snapshotID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid snapshot ID",
+		}})
+		return
+	}
+	
+	snapshot, policy, err := ctrl.service.GetPublicSnapshot(c.Request.Context(), snapshotID, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		ctrl.handleSnapshotError(c, err, "Failed to get snapshot")
+		return
+	}
+	
+	etag := fmt.Sprintf(`"%s"`, snapshot.ID)
+	c.Header("ETag", etag)
+	policy.Apply(c.Writer.Header())
+	
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	
+	c.JSON(http.StatusOK, snapshot)
+}
+
+func (ctrl *documentController) PublishSnapshot(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	var req model.SnapshotPublishRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+				"code":    errcode.ValidationError,
+				"message": "Invalid request data",
+				"details": err.Error(),
+			}})
+			return
+		}
+	}
+	
+	snapshot, err := ctrl.service.PublishSnapshot(c.Request.Context(), documentID, userID.(uuid.UUID), req)
+	if err != nil {
+		ctrl.handleSnapshotError(c, err, "Failed to publish snapshot")
+		return
+	}
+	
+	c.JSON(http.StatusCreated, snapshot)
+}
+
+func (ctrl *documentController) GetSnapshots(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	snapshots, err := ctrl.service.GetSnapshots(c.Request.Context(), documentID, userID.(uuid.UUID))
+	if err != nil {
+		ctrl.handleReadError(c, err, "Failed to retrieve snapshots")
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"data": snapshots})
+}
+
+func (ctrl *documentController) DeleteSnapshot(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	snapshotID, err := uuid.Parse(c.Param("snapshot_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid snapshot ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	if err := ctrl.service.DeleteSnapshot(c.Request.Context(), documentID, snapshotID, userID.(uuid.UUID)); err != nil {
+		ctrl.handleSnapshotError(c, err, "Failed to delete snapshot")
+		return
+	}
+	
+	c.Status(http.StatusNoContent)
+}
+
+// handleSnapshotError maps snapshot and version errors, falling back to
+// handleReadError
+func (ctrl *documentController) handleSnapshotError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrSnapshotNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+			"code":    errcode.SnapshotNotFound,
+			"message": "Snapshot not found",
+		}})
+	case service.ErrVersionNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+			"code":    errcode.VersionNotFound,
+			"message": "Document version not found",
+		}})
+	default:
+		ctrl.handleReadError(c, err, message)
+	}
+}
+
+// GetPublicDocumentHTML serves a public document's rendered content
+func (ctrl *documentController) GetPublicDocumentHTML(c *gin.Context) {
+	document, ok := ctrl.publicDocument(c)
+	if !ok {
+		return
+	}
+	
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(document.HTML))
+}
+
+// publicDocument loads the public document in the path and sets the cache
+// headers. It answers the request itself on errors and when the client's
+// copy is still current, reporting whether the caller should respond.
+func (ctrl *documentController) publicDocument(c *gin.Context) (*model.PublicDocument, bool) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return nil, false
+	}
+	
+	document, policy, err := ctrl.service.GetPublicDocument(c.Request.Context(), documentID, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		ctrl.handleReadError(c, err, "Failed to get public document")
+		return nil, false
+	}
+	
+	if ctrl.notModified(c, publicETag(document.ID, document.Version, document.Alias), policy) {
+		return nil, false
+	}
+	
+	return document, true
+}
+
+// publicETag identifies a public document response. The version changes on
+// every save, so it identifies the content; the alias is part of the
+// response too.
+func publicETag(id uuid.UUID, version int, alias *string) string {
+	if alias != nil {
+		return fmt.Sprintf(`"%s-%d-%s"`, id, version, *alias)
+	}
+	return fmt.Sprintf(`"%s-%d"`, id, version)
+}
+
+// notModified sets the cache headers of a public document and answers 304
+// when the client's copy, identified by etag, is still current
+func (ctrl *documentController) notModified(c *gin.Context, etag string, policy cdn.Policy) bool {
+	c.Header("ETag", etag)
+	policy.Apply(c.Writer.Header())
+	
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+func (ctrl *documentController) ExportDocument(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	format := export.Format(c.DefaultQuery("format", string(export.FormatMarkdown)))
+	
+	file, err := ctrl.service.ExportDocument(c.Request.Context(), documentID, userID.(uuid.UUID), format)
+	if err != nil {
+		if err == export.ErrUnsupportedFormat {
+			c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+				"code":    errcode.ValidationError,
+				"message": "Unsupported export format",
+			}})
+			return
+		}
+		
+		ctrl.handleReadError(c, err, "Failed to export document")
+		return
+	}
+	
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": file.Filename}))
+	c.Data(http.StatusOK, file.ContentType, file.Body)
+}
+
+// ExportDocumentHistory downloads every version of the document as one
+// bundle, ?format=ndjson (the default) or zip
+func (ctrl *documentController) ExportDocumentHistory(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	format := export.Format(c.DefaultQuery("format", string(export.FormatNDJSON)))
+	
+	file, err := ctrl.service.ExportDocumentHistory(c.Request.Context(), documentID, userID.(uuid.UUID), format)
+	if err != nil {
+		if err == export.ErrUnsupportedFormat {
+			c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+				"code":    errcode.ValidationError,
+				"message": "Format must be ndjson or zip",
+			}})
+			return
+		}
+		
+		ctrl.handleReadError(c, err, "Failed to export document history")
+		return
+	}
+	
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": file.Filename}))
+	c.Data(http.StatusOK, file.ContentType, file.Body)
+}
+
+// GetDocumentThumbnail serves a PNG preview of the document's first page
+func (ctrl *documentController) GetDocumentThumbnail(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	image, err := ctrl.service.GetThumbnail(c.Request.Context(), documentID, userID.(uuid.UUID))
+	if err != nil {
+		ctrl.handleReadError(c, err, "Failed to get thumbnail")
+		return
+	}
+	
+	c.Data(http.StatusOK, thumbnail.ContentType, image)
+}
+
+// GetPublicDocumentThumbnail serves the preview of a public document, for
+// link unfurls, with the same cache policy as the document
+func (ctrl *documentController) GetPublicDocumentThumbnail(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	image, policy, err := ctrl.service.GetPublicThumbnail(c.Request.Context(), documentID)
+	if err != nil {
+		ctrl.handleReadError(c, err, "Failed to get public thumbnail")
+		return
+	}
+	
+	policy.Apply(c.Writer.Header())
+	c.Data(http.StatusOK, thumbnail.ContentType, image)
+}
+
+// writeRejectedSave answers a save or publish rejected by another user's
+// lock, for its content size or language, by the lint rules or by a
+// content hook, reporting whether err was one
+func writeRejectedSave(c *gin.Context, err error) bool {
+	if writeLocked(c, err) {
+		return true
+	}
+	
+	if err == service.ErrInvalidLanguage {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid language",
+			"details": err.Error(),
+		}})
+		return true
+	}
+	
+	if err == service.ErrContentTooLarge {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": gin.H{
+			"code":    errcode.ContentTooLarge,
+			"message": "Document content exceeds the maximum size",
+		}})
+		return true
+	}
+	
+	if err == service.ErrEncryptedDocument {
+		writeEncrypted(c)
+		return true
+	}
+	
+	var lintErr *service.LintError
+	if errors.As(err, &lintErr) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": gin.H{
+			"code":       errcode.LintFailed,
+			"message":    "Content violates lint rules",
+			"violations": lintErr.Report.Violations,
+		}})
+		return true
+	}
+	
+	var rejection *hook.Rejection
+	if errors.As(err, &rejection) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": gin.H{
+			"code":       errcode.ContentRejected,
+			"message":    "Content rejected by " + rejection.Hook,
+			"hook":       rejection.Hook,
+			"violations": rejection.Violations,
+		}})
+		return true
+	}
+	return false
+}
+
+// writeEncrypted answers a request that needs the plaintext of an encrypted
+// document, or would publish one
+func writeEncrypted(c *gin.Context) {
+	c.JSON(http.StatusConflict, gin.H{"error": gin.H{
+		"code":    errcode.Encrypted,
+		"message": "Document is end-to-end encrypted; the server cannot read its content",
+	}})
+}
+
+// writeLocked answers a request refused by another user's lock with who
+// holds it and until when, reporting whether err was one
+func writeLocked(c *gin.Context, err error) bool {
+	var locked *service.LockedError
+	if !errors.As(err, &locked) {
+		return false
+	}
+	
+	c.JSON(http.StatusLocked, gin.H{"error": gin.H{
+		"code":    errcode.DocumentLocked,
+		"message": "Document is locked by " + locked.Lock.UserName,
+		"lock":    locked.Lock,
+	}})
+	return true
+}
+
+func writeTermsNotAccepted(c *gin.Context) {
+	c.JSON(http.StatusForbidden, gin.H{"error": gin.H{
+		"code":    errcode.TermsNotAccepted,
+		"message": "Accept the document's terms before reading it",
+		"terms":   fmt.Sprintf("/api/v1/documents/%s/terms", c.Param("id")),
+	}})
+}
+
+// handleReadError answers errors from operations that need read access
+func (ctrl *documentController) handleReadError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrDocumentNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+			"code":    errcode.DocNotFound,
+			"message": "Document not found",
+		}})
+	case service.ErrUnauthorized:
+		c.JSON(http.StatusForbidden, gin.H{"error": gin.H{
+			"code":    errcode.Forbidden,
+			"message": "You don't have permission to access this document",
+		}})
+	case service.ErrTermsNotAccepted:
+		writeTermsNotAccepted(c)
+	case service.ErrEncryptedDocument:
+		writeEncrypted(c)
+	default:
+		logging.FromContext(c.Request.Context(), ctrl.logger).Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
+			"code":    errcode.InternalError,
+			"message": message,
+		}})
+	}
+}
+
+func (ctrl *documentController) DeleteDocument(c *gin.Context) {
+	idStr := c.Param("id")
+	documentID, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	err = ctrl.service.DeleteDocument(
+		c.Request.Context(),
+		documentID,
+		userID.(uuid.UUID),
+	)
+	
+	if err != nil {
+		if err == service.ErrDocumentNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+				"code":    errcode.DocNotFound,
+				"message": "Document not found",
+			}})
+			return
+		}
+		
+		if err == service.ErrUnauthorized {
+			c.JSON(http.StatusForbidden, gin.H{"error": gin.H{
+				"code":    errcode.Forbidden,
+				"message": "You don't have permission to delete this document",
+			}})
+			return
+		}
+		
+		logging.FromContext(c.Request.Context(), ctrl.logger).Error("Failed to delete document", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
+			"code":    errcode.InternalError,
+			"message": "Failed to delete document",
+		}})
+		return
+	}
+	
+	c.Status(http.StatusNoContent)
+}
+
+// GetTrash lists the documents the caller deleted
+func (ctrl *documentController) GetTrash(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	documents, err := ctrl.service.GetTrash(c.Request.Context(), userID.(uuid.UUID))
+	if err != nil {
+		logging.FromContext(c.Request.Context(), ctrl.logger).Error("Failed to get trash", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
+			"code":    errcode.InternalError,
+			"message": "Failed to retrieve trash",
+		}})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"documents": documents})
+}
+
+// RestoreDocument takes a document out of the caller's trash, with its
+// history, collaborators and analytics
+func (ctrl *documentController) RestoreDocument(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	document, err := ctrl.service.RestoreDocument(c.Request.Context(), documentID, userID.(uuid.UUID))
+	if err != nil {
+		if err == service.ErrDocumentNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+				"code":    errcode.DocNotFound,
+				"message": "Document not found in trash",
+			}})
+			return
+		}
+		
+		if err == service.ErrUnauthorized {
+			c.JSON(http.StatusForbidden, gin.H{"error": gin.H{
+				"code":    errcode.Forbidden,
+				"message": "You don't have permission to restore this document",
+			}})
+			return
+		}
+		
+		logging.FromContext(c.Request.Context(), ctrl.logger).Error("Failed to restore document", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
+			"code":    errcode.InternalError,
+			"message": "Failed to restore document",
+		}})
+		return
+	}
+	
+	c.JSON(http.StatusOK, document)
+}
+
+func (ctrl *documentController) BulkDocuments(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	var req model.BulkDocumentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid request data",
+			"details": err.Error(),
+		}})
+		return
+	}
+	
+	if c.Query("async") == "true" {
+		job, err := ctrl.jobService.Enqueue(c.Request.Context(), userID.(uuid.UUID), "bulk_documents",
+			func(ctx context.Context, progress jobService.ProgressFunc) (interface{}, map[string]string, error) {
+				return ctrl.applyBulkAction(ctx, userID.(uuid.UUID), req, progress).Build(), nil, nil
+			})
+		if err != nil {
+			logging.FromContext(c.Request.Context(), ctrl.logger).Error("Failed to enqueue bulk document job", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
+				"code":    errcode.InternalError,
+				"message": "Failed to start bulk operation",
+			}})
+			return
+		}
+		
+		jobController.Accepted(c, job)
+		return
+	}
+	
+	ctrl.applyBulkAction(c.Request.Context(), userID.(uuid.UUID), req, nil).Write(c)
+}
+
+// applyBulkAction runs a bulk action item by item, reporting progress when
+// running as a job
+func (ctrl *documentController) applyBulkAction(ctx context.Context, userID uuid.UUID, req model.BulkDocumentRequest, progress jobService.ProgressFunc) *bulk.Builder {
+	builder := bulk.NewBuilder(len(req.DocumentIDs))
+	for i, documentID := range req.DocumentIDs {
+		var err error
+		var status int
+		
+		switch req.Action {
+		case model.BulkActionDelete:
+			err = ctrl.service.DeleteDocument(ctx, documentID, userID)
+			status = http.StatusNoContent
+		case model.BulkActionMakePublic, model.BulkActionMakePrivate:
+			isPublic := req.Action == model.BulkActionMakePublic
+			_, err = ctrl.service.UpdateDocument(ctx, documentID, userID, model.DocumentUpdateRequest{
+				IsPublic: &isPublic,
+			})
+			status = http.StatusOK
+		}
+		
+		if err != nil {
+			status, code, message := bulkFailure(err)
+			if status == http.StatusInternalServerError {
+				logging.FromContext(ctx, ctrl.logger).Error("Failed to apply bulk document action",
+					zap.Error(err),
+					zap.String("action", string(req.Action)),
+					zap.String("documentID", documentID.String()))
+			}
+			builder.Failure(documentID.String(), status, code, message)
+		} else {
+			builder.Success(documentID.String(), status, nil)
+		}
+		
+		if progress != nil {
+			progress((i + 1) * 100 / len(req.DocumentIDs))
+		}
+	}
+	
+	return builder
+}
+
+func (ctrl *documentController) GetDocumentHistory(c *gin.Context) {
+	idStr := c.Param("id")
+	documentID, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+	
+	var filter model.HistoryFilter
+	
+	if updatedBy := c.Query("updated_by"); updatedBy != "" {
+		parsed, err := uuid.Parse(updatedBy)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+				"code":    errcode.ValidationError,
+				"message": "Invalid updated_by user ID",
+			}})
+			return
+		}
+		filter.UpdatedBy = &parsed
+	}
+	
+	var ok bool
+	if filter.From, ok = parseHistoryTime(c.Query("from")); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid from time, expected RFC 3339 or YYYY-MM-DD",
+		}})
+		return
+	}
+	if filter.To, ok = parseHistoryTime(c.Query("to")); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid to time, expected RFC 3339 or YYYY-MM-DD",
+		}})
+		return
+	}
+	if filter.From != nil && filter.To != nil && !filter.To.After(*filter.From) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "to must be after from",
+		}})
+		return
+	}
+	
+	// ?cursor= pages by version instead of by offset, which stays fast on
+	// long histories; an empty cursor starts from the newest version
+	if value, ok := c.GetQuery("cursor"); ok {
+		var cursor *int
+		if value != "" {
+			version, err := strconv.Atoi(value)
+			if err != nil || version < 1 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+					"code":    errcode.ValidationError,
+					"message": "Invalid cursor",
+				}})
+				return
+			}
+			cursor = &version
+		}
+		if perPage < 1 {
+			perPage = 20
+		}
+		
+		history, next, err := ctrl.service.GetDocumentHistoryPage(
+			c.Request.Context(),
+			documentID,
+			userID.(uuid.UUID),
+			filter,
+			cursor,
+			perPage,
+		)
+		
+		if err != nil {
+			ctrl.handleReadError(c, err, "Failed to retrieve document history")
+			return
+		}
+		
+		var nextCursor *string
+		if next != nil {
+			value := strconv.Itoa(*next)
+			nextCursor = &value
+		}
+		
+		c.JSON(http.StatusOK, gin.H{
+			"data": history,
+			"pagination": gin.H{
+				"per_page":    perPage,
+				"next_cursor": nextCursor,
+			},
+		})
+		return
+	}
+	
+	history, total, err := ctrl.service.GetDocumentHistory(
+		c.Request.Context(),
+		documentID,
+		userID.(uuid.UUID),
+		filter,
+		page,
+		perPage,
+	)
+	
+	if err != nil {
+		ctrl.handleReadError(c, err, "Failed to retrieve document history")
+		return
+	}
+	
+	totalPages := (int(total) + perPage - 1) / perPage
+	
+	// Lets a client switch to cursors from any page
+	var nextCursor *string
+	if page < totalPages && len(history) > 0 {
+		value := strconv.Itoa(history[len(history)-1].Version)
+		nextCursor = &value
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"data": history,
+		"pagination": gin.H{
+			"total":       total,
+			"page":        page,
+			"per_page":    perPage,
+			"total_pages": totalPages,
+			"next_cursor": nextCursor,
+		},
+	})
+}
+
+// parseHistoryTime reads an optional history filter bound: a timestamp, or
+// a date standing for its start in UTC. It is nil when value is empty.
+func parseHistoryTime(value string) (*time.Time, bool) {
+	if value == "" {
+		return nil, true
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return &parsed, true
+		}
+	}
+	return nil, false
+}
+
+func (ctrl *documentController) RestoreDocumentVersion(c *gin.Context) {
+	idStr := c.Param("id")
+	documentID, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	versionStr := c.Param("version")
+	version, err := strconv.Atoi(versionStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid version number",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	document, err := ctrl.service.RestoreDocumentVersion(
+		c.Request.Context(),
+		documentID,
+		userID.(uuid.UUID),
+		version,
+	)
+	
+	if err != nil {
+		if err == service.ErrDocumentNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+				"code":    errcode.DocNotFound,
+				"message": "Document not found",
+			}})
+			return
+		}
+		
+		if err == service.ErrVersionNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+				"code":    errcode.VersionNotFound,
+				"message": "Document version not found",
+			}})
+			return
+		}
+		
+		if err == service.ErrUnauthorized {
+			c.JSON(http.StatusForbidden, gin.H{"error": gin.H{
+				"code":    errcode.Forbidden,
+				"message": "You don't have permission to restore this document",
+			}})
+			return
+		}
+		
+		if writeRejectedSave(c, err) {
+			return
+		}
+		
+		logging.FromContext(c.Request.Context(), ctrl.logger).Error("Failed to restore document version", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
+			"code":    errcode.InternalError,
+			"message": "Failed to restore document version",
+		}})
+		return
+	}
+	
+	c.JSON(http.StatusOK, document)
+}
+
+// PreviewDocumentVersionRestore shows what restoring the version in the
+// path would save and how it differs from the current content
+func (ctrl *documentController) PreviewDocumentVersionRestore(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid version number",
+		}})
+		return
+	}
+	
+	engine, ok := diff.EngineByName(c.DefaultQuery("engine", diff.DefaultEngine))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Engine must be line, word or semantic",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	preview, err := ctrl.service.PreviewVersionRestore(c.Request.Context(), documentID, userID.(uuid.UUID), version, engine)
+	if err != nil {
+		if err == service.ErrVersionNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+				"code":    errcode.VersionNotFound,
+				"message": "Document version not found",
+			}})
+			return
+		}
+		
+		// A restore the content hooks or lint would reject
+		if writeRejectedSave(c, err) {
+			return
+		}
+		
+		ctrl.handleReadError(c, err, "Failed to preview document version restore")
+		return
+	}
+	
+	c.JSON(http.StatusOK, preview)
+}
+
+// LabelDocumentVersion names the version in the path
+func (ctrl *documentController) LabelDocumentVersion(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid version number",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	var req model.VersionLabelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid request data",
+			"details": err.Error(),
+		}})
+		return
+	}
+	
+	labeled, err := ctrl.service.LabelVersion(c.Request.Context(), documentID, userID.(uuid.UUID), version, req.Label)
+	if err != nil {
+		if err == service.ErrInvalidLabel {
+			c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+				"code":    errcode.ValidationError,
+				"message": err.Error(),
+			}})
+			return
+		}
+		
+		if err == service.ErrLabelTaken {
+			c.JSON(http.StatusConflict, gin.H{"error": gin.H{
+				"code":    errcode.LabelTaken,
+				"message": "Another version already has this label",
+			}})
+			return
+		}
+		
+		if err == service.ErrVersionNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+				"code":    errcode.VersionNotFound,
+				"message": "Document version not found",
+			}})
+			return
+		}
+		
+		ctrl.handleReadError(c, err, "Failed to label document version")
+		return
+	}
+	
+	c.JSON(http.StatusOK, labeled)
+}
+
+func (ctrl *documentController) RemoveDocumentVersionLabel(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid version number",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	if err := ctrl.service.RemoveVersionLabel(c.Request.Context(), documentID, userID.(uuid.UUID), version); err != nil {
+		if err == service.ErrVersionNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+				"code":    errcode.VersionNotFound,
+				"message": "Document version not found",
+			}})
+			return
+		}
+		
+		ctrl.handleReadError(c, err, "Failed to remove document version label")
+		return
+	}
+	
+	c.Status(http.StatusNoContent)
+}
+
+// AddVersionReaction leaves the caller's emoji on the version in the path
+func (ctrl *documentController) AddVersionReaction(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid version number",
+		}})
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+
+	var req model.ReactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid request data",
+			"details": err.Error(),
+		}})
+		return
+	}
+
+	counts, err := ctrl.service.AddReaction(c.Request.Context(), documentID, userID.(uuid.UUID), version, req.Emoji)
+	if err != nil {
+		if err == service.ErrInvalidEmoji {
+			c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+				"code":    errcode.ValidationError,
+				"message": err.Error(),
+			}})
+			return
+		}
+
+		if err == service.ErrVersionNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+				"code":    errcode.VersionNotFound,
+				"message": "Document version not found",
+			}})
+			return
+		}
+
+		ctrl.handleReadError(c, err, "Failed to add reaction")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reactions": counts})
+}
+
+// RemoveVersionReaction takes back the caller's emoji in the path
+func (ctrl *documentController) RemoveVersionReaction(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid version number",
+		}})
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+
+	counts, err := ctrl.service.RemoveReaction(c.Request.Context(), documentID, userID.(uuid.UUID), version, c.Param("emoji"))
+	if err != nil {
+		if err == service.ErrInvalidEmoji {
+			c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+				"code":    errcode.ValidationError,
+				"message": err.Error(),
+			}})
+			return
+		}
+
+		if err == service.ErrVersionNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+				"code":    errcode.VersionNotFound,
+				"message": "Document version not found",
+			}})
+			return
+		}
+
+		ctrl.handleReadError(c, err, "Failed to remove reaction")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reactions": counts})
+}
+
+func (ctrl *documentController) GetLabeledVersions(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	versions, err := ctrl.service.GetLabeledVersions(c.Request.Context(), documentID, userID.(uuid.UUID))
+	if err != nil {
+		ctrl.handleReadError(c, err, "Failed to retrieve labeled versions")
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"versions": versions})
+}
+
+// RestoreLabeledVersion restores the version with the label in the path
+func (ctrl *documentController) RestoreLabeledVersion(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	document, err := ctrl.service.RestoreLabeledVersion(c.Request.Context(), documentID, userID.(uuid.UUID), c.Param("label"))
+	if err != nil {
+		if err == service.ErrLabelNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+				"code":    errcode.LabelNotFound,
+				"message": "No version has this label",
+			}})
+			return
+		}
+		
+		if err == service.ErrVersionNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+				"code":    errcode.VersionNotFound,
+				"message": "Document version not found",
+			}})
+			return
+		}
+		
+		if writeRejectedSave(c, err) {
+			return
+		}
+		
+		ctrl.handleReadError(c, err, "Failed to restore document version")
+		return
+	}
+	
+	c.JSON(http.StatusOK, document)
+}
+
+// CompactDocumentHistory previews the compaction synchronously, which also
+// checks ownership, then runs it as a job unless ?dry_run=true
+func (ctrl *documentController) CompactDocumentHistory(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	// The body is optional; an empty one compacts with the defaults
+	var req model.HistoryCompactRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid request data",
+			"details": err.Error(),
+		}})
+		return
+	}
+	
+	preview, err := ctrl.service.CompactHistory(c.Request.Context(), documentID, userID.(uuid.UUID), req, true)
+	if err != nil {
+		if err == service.ErrUnauthorized {
+			c.JSON(http.StatusForbidden, gin.H{"error": gin.H{
+				"code":    errcode.Forbidden,
+				"message": "Only the document owner can compact its history",
+			}})
+			return
+		}
+		
+		ctrl.handleReadError(c, err, "Failed to compact document history")
+		return
+	}
+	
+	if c.Query("dry_run") == "true" {
+		c.JSON(http.StatusOK, preview)
+		return
+	}
+	
+	job, err := ctrl.jobService.Enqueue(c.Request.Context(), userID.(uuid.UUID), "compact_history",
+		func(ctx context.Context, progress jobService.ProgressFunc) (interface{}, map[string]string, error) {
+			compaction, err := ctrl.service.CompactHistory(ctx, documentID, userID.(uuid.UUID), req, false)
+			return compaction, nil, err
+		})
+	if err != nil {
+		logging.FromContext(c.Request.Context(), ctrl.logger).Error("Failed to enqueue history compaction job", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
+			"code":    errcode.InternalError,
+			"message": "Failed to start history compaction",
+		}})
+		return
+	}
+	
+	jobController.Accepted(c, job)
+}
+
+func (ctrl *documentController) CompareDocumentVersions(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	from, err := strconv.Atoi(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid from version number",
+		}})
+		return
+	}
+	
+	var to *int
+	if toStr := c.Query("to"); toStr != "" {
+		version, err := strconv.Atoi(toStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+				"code":    errcode.ValidationError,
+				"message": "Invalid to version number",
+			}})
+			return
+		}
+		to = &version
+	}
+	
+	// format=html renders the diff for direct display instead of as JSON
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "html" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Format must be json or html",
+		}})
+		return
+	}
+	
+	engine, ok := diff.EngineByName(c.DefaultQuery("engine", diff.DefaultEngine))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Engine must be line, word or semantic",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	comparison, err := ctrl.service.CompareVersions(c.Request.Context(), documentID, userID.(uuid.UUID), from, to, engine)
+	if err != nil {
+		if err == service.ErrVersionNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+				"code":    errcode.VersionNotFound,
+				"message": "Document version not found",
+			}})
+			return
+		}
+		
+		ctrl.handleReadError(c, err, "Failed to compare document versions")
+		return
+	}
+	
+	if format == "html" {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(comparison.HTML()))
+		return
+	}
+	
+	c.JSON(http.StatusOK, comparison)
+}
+
+func (ctrl *documentController) ShareDocument(c *gin.Context) {
+	idStr := c.Param("id")
+	documentID, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	var req model.CollaboratorCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid request data",
+			"details": err.Error(),
+		}})
+		return
+	}
+	
+	collaborator, err := ctrl.service.ShareDocument(
+		c.Request.Context(),
+		documentID,
+		userID.(uuid.UUID),
+		req,
+	)
+	
+	if err != nil {
+		if err == service.ErrDocumentNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+				"code":    errcode.DocNotFound,
+				"message": "Document not found",
+			}})
+			return
+		}
+		
+		if err == service.ErrUserNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+				"code":    errcode.UserNotFound,
+				"message": "User not found",
+			}})
+			return
+		}
+		
+		if err == service.ErrAlreadyCollaborator {
+			c.JSON(http.StatusConflict, gin.H{"error": gin.H{
+				"code":    errcode.AlreadyCollaborator,
+				"message": "User is already a collaborator",
+			}})
+			return
+		}
+		
+		if err == service.ErrUnauthorized {
+			c.JSON(http.StatusForbidden, gin.H{"error": gin.H{
+				"code":    errcode.Forbidden,
+				"message": "You don't have permission to share this document",
+			}})
+			return
+		}
+		
+		logging.FromContext(c.Request.Context(), ctrl.logger).Error("Failed to share document", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
+			"code":    errcode.InternalError,
+			"message": "Failed to share document",
+		}})
+		return
+	}
+	
+	c.JSON(http.StatusOK, collaborator)
+}
+
+func (ctrl *documentController) BulkShareDocument(c *gin.Context) {
+	idStr := c.Param("id")
+	documentID, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	var req model.BulkShareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid request data",
+			"details": err.Error(),
+		}})
+		return
+	}
+	
+	builder := bulk.NewBuilder(len(req.Collaborators))
+	for _, item := range req.Collaborators {
+		collaborator, err := ctrl.service.ShareDocument(
+			c.Request.Context(),
+			documentID,
+			userID.(uuid.UUID),
+			item,
+		)
+		
+		if err != nil {
+			status, code, message := bulkFailure(err)
+			if status == http.StatusInternalServerError {
+				logging.FromContext(c.Request.Context(), ctrl.logger).Error("Failed to share document",
+					zap.Error(err),
+					zap.String("userEmail", item.UserEmail))
+			}
+			builder.Failure(item.UserEmail, status, code, message)
+			continue
+		}
+		
+		builder.Success(item.UserEmail, http.StatusOK, collaborator)
+	}
+	
+	builder.Write(c)
+}
+
+func (ctrl *documentController) UpdateCollaboratorPermission(c *gin.Context) {
+	idStr := c.Param("id")
+	documentID, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userIDStr := c.Param("user_id")
+	collaboratorUserID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid user ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	var req model.CollaboratorUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid request data",
+			"details": err.Error(),
+		}})
+		return
+	}
+	
+	collaborator, err := ctrl.service.UpdateCollaboratorPermission(
+		c.Request.Context(),
+		documentID,
+		userID.(uuid.UUID),
+		collaboratorUserID,
+		req,
+	)
+	
+	if err != nil {
+		if err == service.ErrDocumentNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+				"code":    errcode.DocNotFound,
+				"message": "Document not found",
+			}})
+			return
+		}
+		
+		if err == service.ErrNotCollaborator {
+			c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+				"code":    errcode.NotCollaborator,
+				"message": "User is not a collaborator",
+			}})
+			return
+		}
+		
+		if err == service.ErrUnauthorized {
+			c.JSON(http.StatusForbidden, gin.H{"error": gin.H{
+				"code":    errcode.Forbidden,
+				"message": "You don't have permission to update collaborator permissions",
+			}})
+			return
+		}
+		
+		logging.FromContext(c.Request.Context(), ctrl.logger).Error("Failed to update collaborator permission", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
+			"code":    errcode.InternalError,
+			"message": "Failed to update collaborator permission",
+		}})
+		return
+	}
+	
+	c.JSON(http.StatusOK, collaborator)
+}
+
+func (ctrl *documentController) RemoveCollaborator(c *gin.Context) {
+	idStr := c.Param("id")
+	documentID, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userIDStr := c.Param("user_id")
+	collaboratorUserID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid user ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	err = ctrl.service.RemoveCollaborator(
+		c.Request.Context(),
+		documentID,
+		userID.(uuid.UUID),
+		collaboratorUserID,
+	)
+	
+	if err != nil {
+		if err == service.ErrDocumentNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+				"code":    errcode.DocNotFound,
+				"message": "Document not found",
+			}})
+			return
+		}
+		
+		if err == service.ErrCannotRemoveOwner {
+			c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+				"code":    errcode.CannotRemoveOwner,
+				"message": "Cannot remove document owner as collaborator",
+			}})
+			return
+		}
+		
+		if err == service.ErrUnauthorized {
+			c.JSON(http.StatusForbidden, gin.H{"error": gin.H{
+				"code":    errcode.Forbidden,
+				"message": "You don't have permission to remove collaborators",
+			}})
+			return
+		}
+		
+		logging.FromContext(c.Request.Context(), ctrl.logger).Error("Failed to remove collaborator", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
+			"code":    errcode.InternalError,
+			"message": "Failed to remove collaborator",
+		}})
+		return
+	}
+	
+	c.Status(http.StatusNoContent)
+}
+
+// TransferOwnership hands the document over to another user, keeping the
+// caller's access
+func (ctrl *documentController) TransferOwnership(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	var req model.OwnershipTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid request data",
+			"details": err.Error(),
+		}})
+		return
+	}
+	
+	document, err := ctrl.service.TransferOwnership(c.Request.Context(), documentID, userID.(uuid.UUID), req)
+	if err != nil {
+		if err == service.ErrDocumentNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+				"code":    errcode.DocNotFound,
+				"message": "Document not found",
+			}})
+			return
+		}
+		
+		if err == service.ErrUserNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+				"code":    errcode.UserNotFound,
+				"message": "User not found",
+			}})
+			return
+		}
+		
+		if err == service.ErrUnauthorized {
+			c.JSON(http.StatusForbidden, gin.H{"error": gin.H{
+				"code":    errcode.Forbidden,
+				"message": "Only an owner can transfer this document",
+			}})
+			return
+		}
+		
+		logging.FromContext(c.Request.Context(), ctrl.logger).Error("Failed to transfer document ownership", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
+			"code":    errcode.InternalError,
+			"message": "Failed to transfer document ownership",
+		}})
+		return
+	}
+	
+	c.JSON(http.StatusOK, document)
+}
+
+// GetDocumentKeys lists the key exchanges of an encrypted document
+func (ctrl *documentController) GetDocumentKeys(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	keys, err := ctrl.service.GetDocumentKeys(c.Request.Context(), documentID, userID.(uuid.UUID))
+	if err != nil {
+		ctrl.handleKeyError(c, err, "Failed to get document keys")
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"data": keys})
+}
+
+// RegisterDocumentKey records the caller's public key for an encrypted
+// document, for the owner to wrap the document key to
+func (ctrl *documentController) RegisterDocumentKey(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	var req model.DocumentPublicKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid request data",
+			"details": err.Error(),
+		}})
+		return
+	}
+	
+	key, err := ctrl.service.RegisterDocumentKey(c.Request.Context(), documentID, userID.(uuid.UUID), req)
+	if err != nil {
+		ctrl.handleKeyError(c, err, "Failed to register document key")
+		return
+	}
+	
+	c.JSON(http.StatusOK, key)
+}
+
+// WrapDocumentKey stores the document key wrapped for another member
+func (ctrl *documentController) WrapDocumentKey(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	memberID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid user ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	var req model.DocumentWrappedKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid request data",
+			"details": err.Error(),
+		}})
+		return
+	}
+	
+	key, err := ctrl.service.WrapDocumentKey(c.Request.Context(), documentID, userID.(uuid.UUID), memberID, req)
+	if err != nil {
+		ctrl.handleKeyError(c, err, "Failed to wrap document key")
+		return
+	}
+	
+	c.JSON(http.StatusOK, key)
+}
+
+func (ctrl *documentController) handleKeyError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrNotEncrypted:
+		c.JSON(http.StatusConflict, gin.H{"error": gin.H{
+			"code":    errcode.NotEncrypted,
+			"message": "Document is not encrypted",
+		}})
+	case service.ErrKeyNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+			"code":    errcode.KeyNotFound,
+			"message": "User has not registered a key for this document",
+		}})
+	case service.ErrNotCollaborator:
+		c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+			"code":    errcode.NotCollaborator,
+			"message": "User is not a collaborator on this document",
+		}})
+	default:
+		ctrl.handleReadError(c, err, message)
+	}
+}
+
+func (ctrl *documentController) GetDocumentAnalytics(c *gin.Context) {
+	idStr := c.Param("id")
+	documentID, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	period := c.DefaultQuery("period", "month")
+	
+	analytics, err := ctrl.service.GetDocumentAnalytics(
+		c.Request.Context(),
+		documentID,
+		userID.(uuid.UUID),
+		period,
+	)
+	
+	if err != nil {
+		if err == service.ErrDocumentNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+				"code":    errcode.DocNotFound,
+				"message": "Document not found",
+			}})
+			return
+		}
+		
+		if err == service.ErrUnauthorized {
+			c.JSON(http.StatusForbidden, gin.H{"error": gin.H{
+				"code":    errcode.Forbidden,
+				"message": "You don't have permission to see this document's analytics",
+			}})
+			return
+		}
+		
+		logging.FromContext(c.Request.Context(), ctrl.logger).Error("Failed to get document analytics", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
+			"code":    errcode.InternalError,
+			"message": "Failed to retrieve document analytics",
+		}})
+		return
+	}
+	
+	c.JSON(http.StatusOK, analytics)
+}
+
+// SetAnalyticsAccess sets the role collaborators need to see the document's
+// analytics
+func (ctrl *documentController) SetAnalyticsAccess(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	var req model.DocumentAnalyticsAccessRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid request data",
+			"details": err.Error(),
+		}})
+		return
+	}
+	
+	document, err := ctrl.service.SetAnalyticsAccess(c.Request.Context(), documentID, userID.(uuid.UUID), req)
+	if err != nil {
+		if err == service.ErrUnauthorized {
+			c.JSON(http.StatusForbidden, gin.H{"error": gin.H{
+				"code":    errcode.Forbidden,
+				"message": "Only the owner can change who sees a document's analytics",
+			}})
+			return
+		}
+		
+		ctrl.handleReadError(c, err, "Failed to set analytics access")
+		return
+	}
+	
+	c.JSON(http.StatusOK, document)
+}
+
+func (ctrl *documentController) GetUserAnalytics(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	period := c.DefaultQuery("period", "month")
+	
+	analytics, err := ctrl.service.GetUserAnalytics(
+		c.Request.Context(),
+		userID.(uuid.UUID),
+		period,
+	)
+	
+	if err != nil {
+		logging.FromContext(c.Request.Context(), ctrl.logger).Error("Failed to get user analytics", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
+			"code":    errcode.InternalError,
+			"message": "Failed to retrieve user analytics",
+		}})
+		return
+	}
+	
+	c.JSON(http.StatusOK, analytics)
+}
+
+// SubscribeDocumentDigest opts the owner into a weekly analytics email for
+// the document
+func (ctrl *documentController) SubscribeDocumentDigest(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	digest, err := ctrl.service.SubscribeDigest(c.Request.Context(), userID.(uuid.UUID), &documentID)
+	if err != nil {
+		ctrl.handleReadError(c, err, "Failed to subscribe to analytics digest")
+		return
+	}
+	
+	c.JSON(http.StatusOK, digest)
+}
+
+func (ctrl *documentController) UnsubscribeDocumentDigest(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid document ID",
+		}})
+		return
+	}
+	
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	if err := ctrl.service.UnsubscribeDigest(c.Request.Context(), userID.(uuid.UUID), &documentID); err != nil {
+		ctrl.handleReadError(c, err, "Failed to unsubscribe from analytics digest")
+		return
+	}
+	
+	c.Status(http.StatusNoContent)
+}
+
+// SubscribeWorkspaceDigest opts the user into a weekly analytics email
+// covering every document they own
+func (ctrl *documentController) SubscribeWorkspaceDigest(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	digest, err := ctrl.service.SubscribeDigest(c.Request.Context(), userID.(uuid.UUID), nil)
+	if err != nil {
+		ctrl.handleReadError(c, err, "Failed to subscribe to analytics digest")
+		return
+	}
+	
+	c.JSON(http.StatusOK, digest)
+}
+
+func (ctrl *documentController) UnsubscribeWorkspaceDigest(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	if err := ctrl.service.UnsubscribeDigest(c.Request.Context(), userID.(uuid.UUID), nil); err != nil {
+		ctrl.handleReadError(c, err, "Failed to unsubscribe from analytics digest")
+		return
+	}
+	
+	c.Status(http.StatusNoContent)
+}
+
+func (ctrl *documentController) GetUserStorage(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+	
+	usage, err := ctrl.service.GetUserStorage(c.Request.Context(), userID.(uuid.UUID))
+	if err != nil {
+		logging.FromContext(c.Request.Context(), ctrl.logger).Error("Failed to get user storage", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
+			"code":    errcode.InternalError,
+			"message": "Failed to retrieve storage usage",
+		}})
+		return
+	}
+	
+	c.JSON(http.StatusOK, usage)
+}
+
+// bulkFailure maps a service error to the status, code and message reported
+// for a single item of a batch request
+func bulkFailure(err error) (int, errcode.Code, string) {
+	var lintErr *service.LintError
+	if errors.As(err, &lintErr) {
+		return http.StatusUnprocessableEntity, errcode.LintFailed, "Content violates lint rules"
+	}
+	var rejection *hook.Rejection
+	if errors.As(err, &rejection) {
+		return http.StatusUnprocessableEntity, errcode.ContentRejected, "Content rejected by " + rejection.Hook
+	}
+	var locked *service.LockedError
+	if errors.As(err, &locked) {
+		return http.StatusLocked, errcode.DocumentLocked, "Document is locked by " + locked.Lock.UserName
+	}
+	
+	switch err {
+	case service.ErrDocumentNotFound:
+		return http.StatusNotFound, errcode.DocNotFound, "Document not found"
+	case service.ErrUnauthorized:
+		return http.StatusForbidden, errcode.Forbidden, "You don't have permission to modify this document"
+	case service.ErrUserNotFound:
+		return http.StatusNotFound, errcode.UserNotFound, "User not found"
+	case service.ErrAlreadyCollaborator:
+		return http.StatusConflict, errcode.AlreadyCollaborator, "User is already a collaborator"
+	case service.ErrContentTooLarge:
+		return http.StatusRequestEntityTooLarge, errcode.ContentTooLarge, "Document content exceeds the maximum size"
+	case service.ErrEncryptedDocument:
+		return http.StatusConflict, errcode.Encrypted, "Encrypted documents cannot be made public"
+	case importer.ErrUnsupportedType:
+		return http.StatusUnsupportedMediaType, errcode.ValidationError, err.Error()
+	case importer.ErrInvalidEncoding:
+		return http.StatusBadRequest, errcode.ValidationError, err.Error()
+	case importer.ErrFileTooLarge:
+		return http.StatusRequestEntityTooLarge, errcode.ValidationError, err.Error()
+	default:
+		return http.StatusInternalServerError, errcode.InternalError, "Failed to process item"
+	}
+}
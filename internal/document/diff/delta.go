@@ -0,0 +1,105 @@
+package diff
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrBadDelta is returned by Patch for a delta that was not made from the
+// content it is applied to
+var ErrBadDelta = errors.New("delta does not apply to this content")
+
+// Delta encodes the change from old to new, so a version can be stored as
+// a change to the one before it. Patch(old, Delta(old, new)) is new, byte
+// for byte.
+//
+// A delta is a sequence of operations on the lines of old: "=n" keeps n
+// lines, "-n" skips n lines and "+n" inserts the n bytes that follow it.
+// Each operation ends with a newline.
+func Delta(old, new string) string {
+	ops := compare(lines(old), lines(new))
+
+	var b strings.Builder
+	for i := 0; i < len(ops); {
+		j := i
+		for j < len(ops) && ops[j].Op == ops[i].Op {
+			j++
+		}
+
+		switch ops[i].Op {
+		case OpEqual:
+			b.WriteString("=" + strconv.Itoa(j-i) + "\n")
+		case OpDelete:
+			b.WriteString("-" + strconv.Itoa(j-i) + "\n")
+		case OpInsert:
+			var inserted strings.Builder
+			for _, line := range ops[i:j] {
+				inserted.WriteString(line.Text)
+			}
+			b.WriteString("+" + strconv.Itoa(inserted.Len()) + "\n")
+			b.WriteString(inserted.String())
+		}
+		i = j
+	}
+	return b.String()
+}
+
+// Patch applies a delta made by Delta to old
+func Patch(old, delta string) (string, error) {
+	source := lines(old)
+	var b strings.Builder
+
+	at := 0
+	for delta != "" {
+		end := strings.IndexByte(delta, '\n')
+		if end < 1 {
+			return "", ErrBadDelta
+		}
+		op := delta[0]
+		n, err := strconv.Atoi(delta[1:end])
+		if err != nil || n < 0 {
+			return "", ErrBadDelta
+		}
+		delta = delta[end+1:]
+
+		switch op {
+		case '=':
+			if at+n > len(source) {
+				return "", ErrBadDelta
+			}
+			for _, line := range source[at : at+n] {
+				b.WriteString(line)
+			}
+			at += n
+		case '-':
+			if at+n > len(source) {
+				return "", ErrBadDelta
+			}
+			at += n
+		case '+':
+			if n > len(delta) {
+				return "", ErrBadDelta
+			}
+			b.WriteString(delta[:n])
+			delta = delta[n:]
+		default:
+			return "", ErrBadDelta
+		}
+	}
+
+	if at != len(source) {
+		return "", ErrBadDelta
+	}
+	return b.String(), nil
+}
+
+// lines splits content after each newline, so joining the lines gives the
+// content back exactly
+func lines(content string) []string {
+	split := strings.SplitAfter(content, "\n")
+	if split[len(split)-1] == "" {
+		split = split[:len(split)-1]
+	}
+	return split
+}
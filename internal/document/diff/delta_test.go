@@ -0,0 +1,130 @@
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestDeltaRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+	}{
+		{"both empty", "", ""},
+		{"from empty", "", "a\nb\n"},
+		{"to empty", "a\nb\n", ""},
+		{"unchanged", "a\nb\n", "a\nb\n"},
+		{"changed line", "a\nb\nc\n", "a\nB\nc\n"},
+		{"no trailing newline", "a\nb", "a\nc"},
+		{"trailing newline added", "a\nb", "a\nb\n"},
+		{"trailing newline removed", "a\nb\n", "a\nb"},
+		{"CRLF", "a\r\nb\r\nc\r\n", "a\r\nB\r\nc\r\n"},
+		{"CRLF to LF", "a\r\nb\r\n", "a\nb\n"},
+		{"lone CR", "a\rb\n", "a\rc\n"},
+		{"blank lines", "\n\n\n", "\n\n"},
+		{"multibyte", "café\nnaïve\n", "café\nnaïf\n"},
+		{"inserted text looks like an operation", "a\n", "a\n=1\n+3\n-2\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delta := Delta(tt.old, tt.new)
+			got, err := Patch(tt.old, delta)
+			if err != nil {
+				t.Fatalf("Patch(%q, %q): %v", tt.old, delta, err)
+			}
+			if got != tt.new {
+				t.Errorf("Patch(%q, %q) = %q, want %q", tt.old, delta, got, tt.new)
+			}
+		})
+	}
+}
+
+// randomContent is up to 30 lines drawn from a small set, so versions share
+// lines, with CRLF and a missing trailing newline now and then
+func randomContent(r *rand.Rand) string {
+	pieces := []string{"alpha", "beta", "gamma", "", " ", "=1", "+2", "-3", "δ"}
+	endings := []string{"\n", "\n", "\n", "\r\n"}
+
+	var b strings.Builder
+	for i := r.Intn(31); i > 0; i-- {
+		b.WriteString(pieces[r.Intn(len(pieces))])
+		b.WriteString(endings[r.Intn(len(endings))])
+	}
+	content := b.String()
+	if r.Intn(4) == 0 {
+		content = strings.TrimSuffix(content, "\n")
+	}
+	return content
+}
+
+func TestDeltaRoundTripRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 5000; i++ {
+		old, new := randomContent(r), randomContent(r)
+		delta := Delta(old, new)
+		got, err := Patch(old, delta)
+		if err != nil || got != new {
+			t.Fatalf("Patch(%q, Delta(%q, %q) = %q) = %q, %v", old, old, new, delta, got, err)
+		}
+	}
+}
+
+func TestDeltaMaxEdits(t *testing.T) {
+	// Rewriting every other line takes more than maxEdits edits, so the
+	// changed middle is removed and re-added whole
+	n := maxEdits + 200
+	var old, new strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&old, "line %d\n", i)
+		if i%2 == 1 {
+			fmt.Fprintf(&new, "rewritten %d\n", i)
+		} else {
+			fmt.Fprintf(&new, "line %d\n", i)
+		}
+	}
+
+	delta := Delta(old.String(), new.String())
+	if want := fmt.Sprintf("=1\n-%d\n+", n-1); !strings.HasPrefix(delta, want) {
+		t.Errorf("delta starts with %q, want %q", delta[:min(len(delta), 20)], want)
+	}
+
+	got, err := Patch(old.String(), delta)
+	if err != nil {
+		t.Fatalf("Patch: %v", err)
+	}
+	if got != new.String() {
+		t.Error("Patch did not rebuild the new content")
+	}
+}
+
+func TestPatchBadDelta(t *testing.T) {
+	tests := []struct {
+		name       string
+		old, delta string
+	}{
+		{"keeps past the end", "a\n", "=2\n"},
+		{"skips past the end", "a\n", "-2\n"},
+		{"leaves lines over", "a\nb\n", "=1\n"},
+		{"inserts past the end", "a\n", "=1\n+5\nab"},
+		{"unknown operation", "a\n", "*1\n"},
+		{"missing count", "a\n", "=\n"},
+		{"negative count", "a\n", "=-1\n"},
+		{"count not a number", "a\n", "=one\n"},
+		{"unterminated operation", "a\n", "=1"},
+		{"empty operation", "a\n", "\n"},
+		{"made from other content", "x\n", Delta("a\nb\n", "a\nc\n")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Patch(tt.old, tt.delta)
+			if !errors.Is(err, ErrBadDelta) {
+				t.Errorf("Patch(%q, %q) = %q, %v; want ErrBadDelta", tt.old, tt.delta, got, err)
+			}
+		})
+	}
+}
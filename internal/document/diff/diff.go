@@ -0,0 +1,339 @@
+// Package diff compares two versions of document content, line by line or
+// at another granularity, see Engine.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Op is the change a line, or other unit, represents
+type Op string
+
+const (
+	OpEqual  Op = "equal"
+	OpInsert Op = "insert"
+	OpDelete Op = "delete"
+)
+
+// ContextLines is the number of unchanged lines kept around each change
+const ContextLines = 3
+
+// maxEdits bounds the work spent on heavily rewritten content; beyond it
+// the changed region is reported as removed and re-added as a whole
+const maxEdits = 1000
+
+// Line is one line of a hunk
+type Line struct {
+	Op   Op     `json:"op"`
+	Text string `json:"text"`
+}
+
+// Hunk is a run of changes with its surrounding context. Starts are 1-based
+// numbers of the result's unit, lines unless it says otherwise, in the old
+// and new content.
+type Hunk struct {
+	OldStart int    `json:"old_start"`
+	OldLines int    `json:"old_lines"`
+	NewStart int    `json:"new_start"`
+	NewLines int    `json:"new_lines"`
+	Lines    []Line `json:"lines"`
+}
+
+// Result is the difference between two contents. Added and Removed count
+// units.
+type Result struct {
+	Unit    Unit   `json:"unit"`
+	Added   int    `json:"added"`
+	Removed int    `json:"removed"`
+	Hunks   []Hunk `json:"hunks"`
+}
+
+// Lines compares old and new line by line
+func Lines(old, new string) *Result {
+	return newResult(UnitLine, compare(split(old), split(new)), ContextLines)
+}
+
+// newResult counts the changes in ops and groups them into hunks with
+// context units around each
+func newResult(unit Unit, ops []Line, context int) *Result {
+	result := &Result{Unit: unit}
+	for _, line := range ops {
+		switch line.Op {
+		case OpInsert:
+			result.Added++
+		case OpDelete:
+			result.Removed++
+		}
+	}
+	result.Hunks = hunks(ops, context)
+	return result
+}
+
+// Unified renders the result in unified diff format, without file headers
+func (r *Result) Unified() string {
+	var b strings.Builder
+	for _, hunk := range r.Hunks {
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", hunk.OldStart, hunk.OldLines, hunk.NewStart, hunk.NewLines)
+		for _, line := range hunk.Lines {
+			switch line.Op {
+			case OpInsert:
+				b.WriteByte('+')
+			case OpDelete:
+				b.WriteByte('-')
+			default:
+				b.WriteByte(' ')
+			}
+			b.WriteString(line.Text)
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+// Changed returns the 1-based numbers of the lines removed from the old
+// content and of the lines added to the new content
+func (r *Result) Changed() (removed []int, added []int) {
+	for _, hunk := range r.Hunks {
+		oldLine, newLine := hunk.OldStart, hunk.NewStart
+		for _, line := range hunk.Lines {
+			switch line.Op {
+			case OpDelete:
+				removed = append(removed, oldLine)
+				oldLine++
+			case OpInsert:
+				added = append(added, newLine)
+				newLine++
+			default:
+				oldLine++
+				newLine++
+			}
+		}
+	}
+	return removed, added
+}
+
+func split(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// compare returns the edit script turning a into b. The common prefix and
+// suffix are matched directly, leaving only the changed middle to search.
+func compare(a, b []string) []Line {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]Line, 0, len(a)+len(b))
+	for _, text := range a[:prefix] {
+		ops = append(ops, Line{Op: OpEqual, Text: text})
+	}
+	ops = append(ops, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, text := range a[len(a)-suffix:] {
+		ops = append(ops, Line{Op: OpEqual, Text: text})
+	}
+	return ops
+}
+
+// compareBy is compare on the keys of a and b, reporting the units as
+// written; units with equal keys as they are in b
+func compareBy(a, b []string, key func(string) string) []Line {
+	keysOf := func(units []string) []string {
+		keys := make([]string, len(units))
+		for i, unit := range units {
+			keys[i] = key(unit)
+		}
+		return keys
+	}
+	ops := compare(keysOf(a), keysOf(b))
+
+	i, j := 0, 0
+	for k := range ops {
+		switch ops[k].Op {
+		case OpEqual:
+			ops[k].Text = b[j]
+			i++
+			j++
+		case OpDelete:
+			ops[k].Text = a[i]
+			i++
+		case OpInsert:
+			ops[k].Text = b[j]
+			j++
+		}
+	}
+	return ops
+}
+
+// myers finds a shortest edit script with Myers' algorithm. trace keeps,
+// for every edit count d, the furthest x reached on diagonals -d..d.
+func myers(a, b []string) []Line {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return replace(a, b)
+	}
+
+	offset := n + m
+	v := make([]int, 2*offset+2)
+	var trace [][]int
+
+	for d := 0; d <= n+m; d++ {
+		if d > maxEdits {
+			return replace(a, b)
+		}
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				return backtrack(trace, a, b)
+			}
+		}
+	}
+	return replace(a, b)
+}
+
+func backtrack(trace [][]int, a, b []string) []Line {
+	x, y := len(a), len(b)
+	var reversed []Line
+
+	for d := len(trace) - 1; d >= 0; d-- {
+		snapshot := trace[d]
+		at := func(k int) int { return snapshot[k+d] }
+
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+
+		prevX := 0
+		if d > 0 {
+			prevX = at(prevK)
+		}
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			reversed = append(reversed, Line{Op: OpEqual, Text: a[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				reversed = append(reversed, Line{Op: OpInsert, Text: b[y-1]})
+			} else {
+				reversed = append(reversed, Line{Op: OpDelete, Text: a[x-1]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	ops := make([]Line, len(reversed))
+	for i, line := range reversed {
+		ops[len(reversed)-1-i] = line
+	}
+	return ops
+}
+
+// replace reports every line of a as removed and every line of b as added
+func replace(a, b []string) []Line {
+	ops := make([]Line, 0, len(a)+len(b))
+	for _, text := range a {
+		ops = append(ops, Line{Op: OpDelete, Text: text})
+	}
+	for _, text := range b {
+		ops = append(ops, Line{Op: OpInsert, Text: text})
+	}
+	return ops
+}
+
+// hunks groups changes that are within 2*context lines of each other
+func hunks(ops []Line, context int) []Hunk {
+	var result []Hunk
+	oldLine, newLine := 1, 1
+
+	for i := 0; i < len(ops); {
+		if ops[i].Op == OpEqual {
+			i++
+			oldLine++
+			newLine++
+			continue
+		}
+
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		hunk := Hunk{OldStart: oldLine - (i - start), NewStart: newLine - (i - start)}
+
+		// Extend the hunk until a run of unchanged lines is long enough
+		// to separate it from the next change
+		end := i
+		for end < len(ops) {
+			if ops[end].Op != OpEqual {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].Op == OpEqual {
+				run++
+			}
+			if run == len(ops) || run-end > 2*context {
+				break
+			}
+			end = run
+		}
+		stop := end + context
+		if stop > len(ops) {
+			stop = len(ops)
+		}
+
+		for _, line := range ops[start:stop] {
+			hunk.Lines = append(hunk.Lines, line)
+			if line.Op != OpInsert {
+				hunk.OldLines++
+			}
+			if line.Op != OpDelete {
+				hunk.NewLines++
+			}
+		}
+		for _, line := range ops[i:stop] {
+			if line.Op != OpInsert {
+				oldLine++
+			}
+			if line.Op != OpDelete {
+				newLine++
+			}
+		}
+
+		result = append(result, hunk)
+		i = stop
+	}
+
+	if result == nil {
+		return []Hunk{}
+	}
+	return result
+}
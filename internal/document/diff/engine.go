@@ -0,0 +1,46 @@
+package diff
+
+// Unit is what a result's lines are
+type Unit string
+
+const (
+	UnitLine  Unit = "line"
+	UnitWord  Unit = "word"
+	UnitBlock Unit = "block"
+)
+
+// Engine compares two contents at one granularity. Different documents
+// read best at different ones: code and data by line, prose by word, and
+// markdown by block.
+type Engine interface {
+	// Name selects the engine, e.g. with ?engine=word
+	Name() string
+	Compare(old, new string) *Result
+}
+
+// DefaultEngine is used when none is asked for
+const DefaultEngine = "line"
+
+var engines = map[string]Engine{
+	LineEngine{}.Name():     LineEngine{},
+	WordEngine{}.Name():     WordEngine{},
+	SemanticEngine{}.Name(): SemanticEngine{},
+}
+
+// EngineByName returns the engine called name, reporting whether there is
+// one
+func EngineByName(name string) (Engine, bool) {
+	engine, ok := engines[name]
+	return engine, ok
+}
+
+// LineEngine compares line by line, see Lines
+type LineEngine struct{}
+
+func (LineEngine) Name() string {
+	return "line"
+}
+
+func (LineEngine) Compare(old, new string) *Result {
+	return Lines(old, new)
+}
@@ -0,0 +1,79 @@
+package diff
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// htmlStyle makes the rendered diff readable as it is; clients restyle it
+// through the diff-* classes
+const htmlStyle = `<style>
+.diff{font-family:monospace}
+.diff-line{white-space:pre-wrap}
+.diff-hunk-header{color:#6a737d;background:#f1f8ff}
+.diff-line ins,.diff-line del{text-decoration:none}
+.diff-insert{background:#e6ffed}
+.diff-delete{background:#ffeef0}
+</style>
+`
+
+// HTML renders the result as inline highlighted HTML for direct display:
+// each hunk lists its lines in order, removed lines in del and added lines
+// in ins. Line numbers are in the data-old and data-new attributes. Word
+// results run the words of a hunk together as text instead.
+func (r *Result) HTML() string {
+	var b strings.Builder
+	b.WriteString(htmlStyle)
+	b.WriteString(`<div class="diff">` + "\n")
+	for _, hunk := range r.Hunks {
+		b.WriteString(`<div class="diff-hunk">` + "\n")
+		fmt.Fprintf(&b, `<div class="diff-hunk-header">@@ -%d,%d +%d,%d @@</div>`+"\n", hunk.OldStart, hunk.OldLines, hunk.NewStart, hunk.NewLines)
+
+		if r.Unit == UnitWord {
+			writeWords(&b, hunk)
+			b.WriteString("</div>\n")
+			continue
+		}
+
+		oldLine, newLine := hunk.OldStart, hunk.NewStart
+		for _, line := range hunk.Lines {
+			text := html.EscapeString(line.Text)
+			if text == "" {
+				text = "<br>"
+			}
+			switch line.Op {
+			case OpInsert:
+				fmt.Fprintf(&b, `<div class="diff-line diff-insert" data-new="%d"><ins>%s</ins></div>`+"\n", newLine, text)
+				newLine++
+			case OpDelete:
+				fmt.Fprintf(&b, `<div class="diff-line diff-delete" data-old="%d"><del>%s</del></div>`+"\n", oldLine, text)
+				oldLine++
+			default:
+				fmt.Fprintf(&b, `<div class="diff-line diff-equal" data-old="%d" data-new="%d">%s</div>`+"\n", oldLine, newLine, text)
+				oldLine++
+				newLine++
+			}
+		}
+		b.WriteString("</div>\n")
+	}
+	b.WriteString("</div>\n")
+	return b.String()
+}
+
+// writeWords renders the words of a hunk as one run of text
+func writeWords(b *strings.Builder, hunk Hunk) {
+	b.WriteString(`<div class="diff-line diff-words">`)
+	for _, line := range hunk.Lines {
+		text := html.EscapeString(line.Text)
+		switch line.Op {
+		case OpInsert:
+			b.WriteString(`<ins class="diff-insert">` + text + `</ins>`)
+		case OpDelete:
+			b.WriteString(`<del class="diff-delete">` + text + `</del>`)
+		default:
+			b.WriteString(text)
+		}
+	}
+	b.WriteString("</div>\n")
+}
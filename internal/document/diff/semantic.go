@@ -0,0 +1,80 @@
+package diff
+
+import (
+	"regexp"
+	"strings"
+)
+
+// contextBlocks is the number of unchanged blocks kept around each change
+const contextBlocks = 1
+
+var (
+	heading  = regexp.MustCompile(`^ {0,3}#{1,6}(\s|$)`)
+	listItem = regexp.MustCompile(`^\s*([-*+]|\d{1,9}[.)])(\s|$)`)
+	fence    = regexp.MustCompile("^ {0,3}(```|~~~)")
+)
+
+// SemanticEngine compares markdown block by block: headings, paragraphs,
+// list items and fenced code. Blocks differing only in how their text is
+// wrapped or spaced are equal, except code, where whitespace matters.
+type SemanticEngine struct{}
+
+func (SemanticEngine) Name() string {
+	return "semantic"
+}
+
+func (SemanticEngine) Compare(old, new string) *Result {
+	return newResult(UnitBlock, compareBy(blocks(old), blocks(new), blockKey), contextBlocks)
+}
+
+// blocks splits markdown into its blocks. Blank lines end a block, headings
+// are blocks of their own, each list item starts one, and fenced code is one
+// block up to its closing fence.
+func blocks(content string) []string {
+	var result, current []string
+	flush := func() {
+		if len(current) > 0 {
+			result = append(result, strings.Join(current, "\n"))
+			current = nil
+		}
+	}
+
+	closing := ""
+	for _, line := range split(content) {
+		if closing != "" {
+			current = append(current, line)
+			if strings.HasPrefix(strings.TrimSpace(line), closing) {
+				flush()
+				closing = ""
+			}
+			continue
+		}
+
+		switch {
+		case strings.TrimSpace(line) == "":
+			flush()
+		case fence.MatchString(line):
+			flush()
+			closing = fence.FindStringSubmatch(line)[1]
+			current = append(current, line)
+		case heading.MatchString(line):
+			flush()
+			result = append(result, line)
+		case listItem.MatchString(line):
+			flush()
+			current = append(current, line)
+		default:
+			current = append(current, line)
+		}
+	}
+	flush()
+	return result
+}
+
+// blockKey is what a block is compared by: its words, except for code
+func blockKey(block string) string {
+	if fence.MatchString(block) {
+		return block
+	}
+	return strings.Join(strings.Fields(block), " ")
+}
@@ -0,0 +1,26 @@
+package diff
+
+import (
+	"regexp"
+	"strings"
+)
+
+// contextWords is the number of unchanged words kept around each change
+const contextWords = 8
+
+// word matches a word with the whitespace after it, or whitespace the
+// content starts with, so the words join back into the content
+var word = regexp.MustCompile(`\S+\s*|\s+`)
+
+// WordEngine compares word by word, for prose where a line is a whole
+// paragraph. A word's text includes the whitespace after it, which is not
+// compared, so rewrapping changes nothing.
+type WordEngine struct{}
+
+func (WordEngine) Name() string {
+	return "word"
+}
+
+func (WordEngine) Compare(old, new string) *Result {
+	return newResult(UnitWord, compareBy(word.FindAllString(old, -1), word.FindAllString(new, -1), strings.TrimSpace), contextWords)
+}
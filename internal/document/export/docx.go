@@ -0,0 +1,272 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+
+	"github.com/hafiztri123/document-api/internal/document/model"
+)
+
+// indentStep is the indentation of one list or quote level, in twips
+const indentStep = 360
+
+// run is a span of text sharing one character format
+type run struct {
+	text   string
+	bold   bool
+	italic bool
+	code   bool
+	br     bool
+}
+
+// docxWriter builds the body of word/document.xml from markdown blocks
+type docxWriter struct {
+	body   bytes.Buffer
+	source []byte
+}
+
+// renderDOCX packages document as a WordprocessingML file
+func renderDOCX(document *model.Document) ([]byte, error) {
+	w := &docxWriter{source: []byte(document.Content)}
+
+	w.paragraph("Title", 0, "", []run{{text: document.Title}})
+	w.paragraph("Subtitle", 0, "", []run{{text: fmt.Sprintf("Version %d · Last updated %s",
+		document.Version, document.UpdatedAt.UTC().Format("2 Jan 2006 15:04 MST"))}})
+
+	root := markdown.Parser().Parse(text.NewReader(w.source))
+	for block := root.FirstChild(); block != nil; block = block.NextSibling() {
+		w.block(block, 0, "")
+	}
+
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", docxContentTypes},
+		{"_rels/.rels", docxRels},
+		{"docProps/core.xml", docxCoreProperties(document.Title, document.UpdatedAt)},
+		{"word/styles.xml", docxStyles},
+		{"word/document.xml", xml.Header + `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+			w.body.String() + `</w:body></w:document>`},
+	}
+
+	var out bytes.Buffer
+	archive := zip.NewWriter(&out)
+	for _, part := range parts {
+		file, err := archive.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := file.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// block writes one markdown block; style overrides the paragraph style of
+// plain paragraphs, e.g. inside block quotes
+func (w *docxWriter) block(node ast.Node, depth int, style string) {
+	switch node := node.(type) {
+	case *ast.Heading:
+		w.paragraph(fmt.Sprintf("Heading%d", node.Level), 0, "", w.runs(node))
+	case *ast.List:
+		number := node.Start
+		for item := node.FirstChild(); item != nil; item = item.NextSibling() {
+			marker := "•"
+			if node.IsOrdered() {
+				marker = fmt.Sprintf("%d.", number)
+				number++
+			}
+			w.listItem(item, marker, depth)
+		}
+	case *ast.FencedCodeBlock, *ast.CodeBlock, *ast.HTMLBlock:
+		w.paragraph("Code", depth, "", w.lines(node))
+	case *ast.Blockquote:
+		for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+			w.block(child, depth+1, "Quote")
+		}
+	case *ast.ThematicBreak:
+		w.body.WriteString(`<w:p><w:pPr><w:pBdr><w:bottom w:val="single" w:sz="6" w:space="1" w:color="auto"/></w:pBdr></w:pPr></w:p>`)
+	default:
+		w.paragraph(style, depth, "", w.runs(node))
+	}
+}
+
+// listItem writes the item's first paragraph after marker, hanging in the
+// gutter, and its other blocks indented below it
+func (w *docxWriter) listItem(item ast.Node, marker string, depth int) {
+	for child := item.FirstChild(); child != nil; child = child.NextSibling() {
+		if _, nested := child.(*ast.List); nested {
+			w.block(child, depth+1, "")
+			continue
+		}
+		w.paragraph("ListParagraph", depth+1, marker, w.runs(child))
+		marker = ""
+	}
+}
+
+// paragraph writes a paragraph indented by depth levels. A marker is set in
+// a hanging indent in front of the text.
+func (w *docxWriter) paragraph(style string, depth int, marker string, runs []run) {
+	w.body.WriteString("<w:p><w:pPr>")
+	if style != "" {
+		fmt.Fprintf(&w.body, `<w:pStyle w:val="%s"/>`, style)
+	}
+	if depth > 0 {
+		hanging := 0
+		if marker != "" {
+			hanging = indentStep
+		}
+		fmt.Fprintf(&w.body, `<w:ind w:left="%d" w:hanging="%d"/>`, depth*indentStep, hanging)
+	}
+	w.body.WriteString("</w:pPr>")
+
+	if marker != "" {
+		w.writeRun(run{text: marker})
+		w.body.WriteString("<w:r><w:tab/></w:r>")
+	}
+	for _, r := range runs {
+		w.writeRun(r)
+	}
+	w.body.WriteString("</w:p>")
+}
+
+func (w *docxWriter) writeRun(r run) {
+	w.body.WriteString("<w:r>")
+	if r.bold || r.italic || r.code {
+		w.body.WriteString("<w:rPr>")
+		if r.code {
+			w.body.WriteString(`<w:rFonts w:ascii="Courier New" w:hAnsi="Courier New" w:cs="Courier New"/>`)
+		}
+		if r.bold {
+			w.body.WriteString("<w:b/>")
+		}
+		if r.italic {
+			w.body.WriteString("<w:i/>")
+		}
+		w.body.WriteString("</w:rPr>")
+	}
+	if r.br {
+		w.body.WriteString("<w:br/>")
+	} else {
+		w.body.WriteString(`<w:t xml:space="preserve">`)
+		xml.EscapeText(&w.body, []byte(r.text))
+		w.body.WriteString("</w:t>")
+	}
+	w.body.WriteString("</w:r>")
+}
+
+// runs flattens the inline content of a block into formatted runs
+func (w *docxWriter) runs(node ast.Node) []run {
+	var runs []run
+	w.collect(node, run{}, &runs)
+	return runs
+}
+
+func (w *docxWriter) collect(node ast.Node, format run, runs *[]run) {
+	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+		switch child := child.(type) {
+		case *ast.Text:
+			r := format
+			r.text = string(child.Segment.Value(w.source))
+			if child.SoftLineBreak() {
+				r.text += " "
+			}
+			*runs = append(*runs, r)
+			if child.HardLineBreak() {
+				*runs = append(*runs, run{br: true})
+			}
+		case *ast.String:
+			r := format
+			r.text = string(child.Value)
+			*runs = append(*runs, r)
+		case *ast.AutoLink:
+			r := format
+			r.text = string(child.URL(w.source))
+			*runs = append(*runs, r)
+		case *ast.Emphasis:
+			nested := format
+			if child.Level >= 2 {
+				nested.bold = true
+			} else {
+				nested.italic = true
+			}
+			w.collect(child, nested, runs)
+		case *ast.CodeSpan:
+			nested := format
+			nested.code = true
+			w.collect(child, nested, runs)
+		default:
+			w.collect(child, format, runs)
+		}
+	}
+}
+
+// lines returns the lines of a code or HTML block as runs split by breaks
+func (w *docxWriter) lines(node ast.Node) []run {
+	var runs []run
+	segments := node.Lines()
+	for i := 0; i < segments.Len(); i++ {
+		if i > 0 {
+			runs = append(runs, run{br: true})
+		}
+		segment := segments.At(i)
+		line := strings.TrimRight(string(segment.Value(w.source)), "\r\n")
+		runs = append(runs, run{text: line, code: true})
+	}
+	return runs
+}
+
+func docxCoreProperties(title string, modified time.Time) string {
+	var escaped bytes.Buffer
+	xml.EscapeText(&escaped, []byte(title))
+	return xml.Header + `<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" ` +
+		`xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/" ` +
+		`xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` +
+		`<dc:title>` + escaped.String() + `</dc:title>` +
+		`<dcterms:modified xsi:type="dcterms:W3CDTF">` + modified.UTC().Format(time.RFC3339) + `</dcterms:modified>` +
+		`</cp:coreProperties>`
+}
+
+const docxContentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>` +
+	`<Override PartName="/word/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml"/>` +
+	`<Override PartName="/docProps/core.xml" ContentType="application/vnd.openxmlformats-package.core-properties+xml"/>` +
+	`</Types>`
+
+const docxRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties" Target="docProps/core.xml"/>` +
+	`</Relationships>`
+
+// docxStyles defines the paragraph styles the writer refers to; Word falls
+// back to its own look for anything not set here
+const docxStyles = xml.Header + `<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">` +
+	`<w:docDefaults><w:rPrDefault><w:rPr><w:rFonts w:ascii="Calibri" w:hAnsi="Calibri" w:cs="Calibri"/><w:sz w:val="22"/></w:rPr></w:rPrDefault>` +
+	`<w:pPrDefault><w:pPr><w:spacing w:after="120"/></w:pPr></w:pPrDefault></w:docDefaults>` +
+	`<w:style w:type="paragraph" w:default="1" w:styleId="Normal"><w:name w:val="Normal"/></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Title"><w:name w:val="Title"/><w:basedOn w:val="Normal"/><w:rPr><w:b/><w:sz w:val="48"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Subtitle"><w:name w:val="Subtitle"/><w:basedOn w:val="Normal"/><w:pPr><w:spacing w:after="240"/></w:pPr><w:rPr><w:color w:val="6E6E6E"/><w:sz w:val="18"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Heading1"><w:name w:val="heading 1"/><w:basedOn w:val="Normal"/><w:pPr><w:keepNext/><w:spacing w:before="240"/><w:outlineLvl w:val="0"/></w:pPr><w:rPr><w:b/><w:sz w:val="40"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Heading2"><w:name w:val="heading 2"/><w:basedOn w:val="Normal"/><w:pPr><w:keepNext/><w:spacing w:before="200"/><w:outlineLvl w:val="1"/></w:pPr><w:rPr><w:b/><w:sz w:val="32"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Heading3"><w:name w:val="heading 3"/><w:basedOn w:val="Normal"/><w:pPr><w:keepNext/><w:spacing w:before="160"/><w:outlineLvl w:val="2"/></w:pPr><w:rPr><w:b/><w:sz w:val="28"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Heading4"><w:name w:val="heading 4"/><w:basedOn w:val="Normal"/><w:pPr><w:keepNext/><w:outlineLvl w:val="3"/></w:pPr><w:rPr><w:b/><w:sz w:val="24"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Heading5"><w:name w:val="heading 5"/><w:basedOn w:val="Normal"/><w:pPr><w:keepNext/><w:outlineLvl w:val="4"/></w:pPr><w:rPr><w:b/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Heading6"><w:name w:val="heading 6"/><w:basedOn w:val="Normal"/><w:pPr><w:keepNext/><w:outlineLvl w:val="5"/></w:pPr><w:rPr><w:b/><w:i/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="ListParagraph"><w:name w:val="List Paragraph"/><w:basedOn w:val="Normal"/><w:pPr><w:spacing w:after="60"/></w:pPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Quote"><w:name w:val="Quote"/><w:basedOn w:val="Normal"/><w:rPr><w:i/><w:color w:val="5A5A5A"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Code"><w:name w:val="Code"/><w:basedOn w:val="Normal"/><w:pPr><w:shd w:val="clear" w:color="auto" w:fill="F2F2F2"/></w:pPr><w:rPr><w:rFonts w:ascii="Courier New" w:hAnsi="Courier New" w:cs="Courier New"/><w:sz w:val="18"/></w:rPr></w:style>` +
+	`</w:styles>`
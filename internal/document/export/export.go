@@ -0,0 +1,130 @@
+// Package export renders documents as downloadable files.
+package export
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark"
+
+	"github.com/hafiztri123/document-api/internal/document/model"
+	"github.com/hafiztri123/document-api/internal/document/outline"
+)
+
+// Format is an export file format
+type Format string
+
+const (
+	FormatMarkdown Format = "markdown"
+	FormatHTML     Format = "html"
+	FormatPDF      Format = "pdf"
+	FormatDOCX     Format = "docx"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported export format")
+
+var markdown = goldmark.New()
+
+// File is a rendered export
+type File struct {
+	Filename    string
+	ContentType string
+	Body        []byte
+}
+
+// Expensive reports whether rendering format costs enough to be worth
+// caching
+func Expensive(format Format) bool {
+	return format == FormatPDF || format == FormatDOCX
+}
+
+// Render exports document in format. headings must be the document's
+// outline so exported anchors match the ones clients link to.
+func Render(document *model.Document, headings []outline.Heading, format Format) (*File, error) {
+	switch format {
+	case FormatMarkdown:
+		return &File{
+			Filename:    filename(document.Title, "md"),
+			ContentType: "text/markdown; charset=utf-8",
+			Body:        []byte(document.Content),
+		}, nil
+	case FormatHTML:
+		body, err := renderHTML(document, headings)
+		if err != nil {
+			return nil, err
+		}
+		return &File{
+			Filename:    filename(document.Title, "html"),
+			ContentType: "text/html; charset=utf-8",
+			Body:        body,
+		}, nil
+	case FormatPDF:
+		body, err := renderPDF(document)
+		if err != nil {
+			return nil, err
+		}
+		return &File{
+			Filename:    filename(document.Title, "pdf"),
+			ContentType: "application/pdf",
+			Body:        body,
+		}, nil
+	case FormatDOCX:
+		body, err := renderDOCX(document)
+		if err != nil {
+			return nil, err
+		}
+		return &File{
+			Filename:    filename(document.Title, "docx"),
+			ContentType: "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+			Body:        body,
+		}, nil
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+var htmlPage = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+</head>
+<body>
+{{.Body}}</body>
+</html>
+`))
+
+// renderHTML wraps the rendered content in a standalone page
+func renderHTML(document *model.Document, headings []outline.Heading) ([]byte, error) {
+	body, err := outline.RenderHTML(document.Content, headings)
+	if err != nil {
+		return nil, err
+	}
+
+	var page bytes.Buffer
+	err = htmlPage.Execute(&page, struct {
+		Title string
+		Body  template.HTML
+	}{
+		Title: document.Title,
+		Body:  template.HTML(body),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return page.Bytes(), nil
+}
+
+var nonFilenameChars = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+// filename derives a download name from the document title
+func filename(title, extension string) string {
+	name := strings.Trim(nonFilenameChars.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if name == "" {
+		name = "document"
+	}
+	return name + "." + extension
+}
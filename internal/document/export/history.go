@@ -0,0 +1,165 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/hafiztri123/document-api/internal/document/model"
+)
+
+// History bundle formats, see RenderHistory
+const (
+	// FormatNDJSON writes one version per line, content included
+	FormatNDJSON Format = "ndjson"
+	// FormatZip stores each version's content in its own file, described
+	// by manifest.json
+	FormatZip Format = "zip"
+)
+
+// HistoryVersion describes one version in a history bundle. SHA256 is the
+// hash of the version's content, so archived copies can be verified.
+type HistoryVersion struct {
+	DocumentID    uuid.UUID  `json:"document_id"`
+	Version       int        `json:"version"`
+	UpdatedByID   uuid.UUID  `json:"updated_by_id"`
+	UpdatedByName string     `json:"updated_by_name"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	Summary       *string    `json:"summary"`
+	Label         *string    `json:"label"`
+	MergedFromID  *uuid.UUID `json:"merged_from_id"`
+	Bytes         int        `json:"bytes"`
+	SHA256        string     `json:"sha256"`
+}
+
+// HistoryManifest is manifest.json in a zip bundle. Each version's File is
+// its path in the zip.
+type HistoryManifest struct {
+	DocumentID     uuid.UUID              `json:"document_id"`
+	Title          string                 `json:"title"`
+	OwnerID        uuid.UUID              `json:"owner_id"`
+	CurrentVersion int                    `json:"current_version"`
+	ExportedAt     time.Time              `json:"exported_at"`
+	Versions       []HistoryManifestEntry `json:"versions"`
+}
+
+type HistoryManifestEntry struct {
+	HistoryVersion
+	File string `json:"file"`
+}
+
+// historyLine is a line of an NDJSON bundle
+type historyLine struct {
+	HistoryVersion
+	Content string `json:"content"`
+}
+
+// RenderHistory bundles every version of document in format. history must
+// be the document's full history, oldest first.
+func RenderHistory(document *model.Document, history []*model.DocumentHistory, format Format, exportedAt time.Time) (*File, error) {
+	switch format {
+	case FormatNDJSON:
+		var body bytes.Buffer
+		encoder := json.NewEncoder(&body)
+		encoder.SetEscapeHTML(false)
+		for _, h := range history {
+			if err := encoder.Encode(historyLine{HistoryVersion: historyVersion(h), Content: h.Content}); err != nil {
+				return nil, err
+			}
+		}
+		return &File{
+			Filename:    filename(document.Title+" history", "ndjson"),
+			ContentType: "application/x-ndjson",
+			Body:        body.Bytes(),
+		}, nil
+	case FormatZip:
+		body, err := renderHistoryZip(document, history, exportedAt)
+		if err != nil {
+			return nil, err
+		}
+		return &File{
+			Filename:    filename(document.Title+" history", "zip"),
+			ContentType: "application/zip",
+			Body:        body,
+		}, nil
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+func renderHistoryZip(document *model.Document, history []*model.DocumentHistory, exportedAt time.Time) ([]byte, error) {
+	var body bytes.Buffer
+	archive := zip.NewWriter(&body)
+
+	manifest := HistoryManifest{
+		DocumentID:     document.ID,
+		Title:          document.Title,
+		OwnerID:        document.OwnerID,
+		CurrentVersion: document.Version,
+		ExportedAt:     exportedAt,
+		Versions:       make([]HistoryManifestEntry, 0, len(history)),
+	}
+
+	for _, h := range history {
+		entry := HistoryManifestEntry{
+			HistoryVersion: historyVersion(h),
+			File:           fmt.Sprintf("versions/%06d.md", h.Version),
+		}
+
+		w, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     entry.File,
+			Method:   zip.Deflate,
+			Modified: h.UpdatedAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write([]byte(h.Content)); err != nil {
+			return nil, err
+		}
+
+		manifest.Versions = append(manifest.Versions, entry)
+	}
+
+	w, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     "manifest.json",
+		Method:   zip.Deflate,
+		Modified: exportedAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(manifest); err != nil {
+		return nil, err
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return body.Bytes(), nil
+}
+
+func historyVersion(h *model.DocumentHistory) HistoryVersion {
+	sum := sha256.Sum256([]byte(h.Content))
+	return HistoryVersion{
+		DocumentID:    h.DocumentID,
+		Version:       h.Version,
+		UpdatedByID:   h.UpdatedByID,
+		UpdatedByName: h.UpdatedBy.Name,
+		UpdatedAt:     h.UpdatedAt,
+		Summary:       h.Summary,
+		Label:         h.Label,
+		MergedFromID:  h.MergedFromID,
+		Bytes:         len(h.Content),
+		SHA256:        hex.EncodeToString(sum[:]),
+	}
+}
@@ -0,0 +1,190 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+
+	"github.com/hafiztri123/document-api/internal/document/model"
+)
+
+// headingSizes are font sizes in points for heading levels 1 to 6
+var headingSizes = [...]float64{20, 16, 14, 12, 11, 11}
+
+const (
+	bodySize   = 11
+	codeSize   = 9
+	lineHeight = 1.4 // multiple of the font size
+	listIndent = 6   // millimetres per nesting level
+	ptToMM     = 25.4 / 72
+)
+
+// leading is the line height in millimetres for a font size in points
+func leading(size float64) float64 {
+	return size * lineHeight * ptToMM
+}
+
+// pdfWriter lays out markdown blocks on an A4 page. The core PDF fonts only
+// cover Windows-1252; other characters are replaced.
+type pdfWriter struct {
+	pdf       *gofpdf.Fpdf
+	translate func(string) string
+	source    []byte
+	margin    float64
+}
+
+// renderPDF prints the title and version metadata followed by the content
+func renderPDF(document *model.Document) ([]byte, error) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle(document.Title, true)
+	pdf.SetCreationDate(document.UpdatedAt)
+	pdf.SetAutoPageBreak(true, 15)
+	pdf.AddPage()
+
+	margin, _, _, _ := pdf.GetMargins()
+	w := &pdfWriter{
+		pdf:       pdf,
+		translate: pdf.UnicodeTranslatorFromDescriptor(""),
+		source:    []byte(document.Content),
+		margin:    margin,
+	}
+
+	w.text("Helvetica", "B", headingSizes[0], document.Title)
+	pdf.SetTextColor(110, 110, 110)
+	w.text("Helvetica", "", codeSize, fmt.Sprintf("Version %d · Last updated %s",
+		document.Version, document.UpdatedAt.UTC().Format("2 Jan 2006 15:04 MST")))
+	pdf.SetTextColor(0, 0, 0)
+	w.rule()
+
+	root := markdown.Parser().Parse(text.NewReader(w.source))
+	for block := root.FirstChild(); block != nil; block = block.NextSibling() {
+		w.block(block, 0)
+	}
+
+	var out bytes.Buffer
+	if err := pdf.Output(&out); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func (w *pdfWriter) block(node ast.Node, depth int) {
+	switch node := node.(type) {
+	case *ast.Heading:
+		w.pdf.Ln(2)
+		w.text("Helvetica", "B", headingSizes[node.Level-1], w.inline(node))
+	case *ast.List:
+		number := node.Start
+		for item := node.FirstChild(); item != nil; item = item.NextSibling() {
+			marker := "•"
+			if node.IsOrdered() {
+				marker = fmt.Sprintf("%d.", number)
+				number++
+			}
+			w.listItem(item, marker, depth)
+		}
+	case *ast.FencedCodeBlock, *ast.CodeBlock:
+		w.pdf.SetFillColor(242, 242, 242)
+		w.pdf.SetFont("Courier", "", codeSize)
+		w.pdf.MultiCell(0, leading(codeSize), w.translate(strings.TrimRight(w.lines(node), "\n")), "", "L", true)
+		w.pdf.Ln(2)
+	case *ast.Blockquote:
+		w.pdf.SetTextColor(90, 90, 90)
+		w.indent(depth+1, func() {
+			for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+				w.block(child, depth+1)
+			}
+		})
+		w.pdf.SetTextColor(0, 0, 0)
+	case *ast.ThematicBreak:
+		w.rule()
+	case *ast.HTMLBlock:
+		w.text("Courier", "", codeSize, strings.TrimRight(w.lines(node), "\n"))
+	default:
+		w.text("Helvetica", "", bodySize, w.inline(node))
+	}
+}
+
+// listItem prints marker in the gutter and the item's blocks indented
+func (w *pdfWriter) listItem(item ast.Node, marker string, depth int) {
+	w.pdf.SetFont("Helvetica", "", bodySize)
+	w.pdf.SetX(w.margin + float64(depth)*listIndent)
+	w.pdf.CellFormat(listIndent, leading(bodySize), w.translate(marker), "", 0, "L", false, 0, "")
+
+	w.indent(depth+1, func() {
+		for child := item.FirstChild(); child != nil; child = child.NextSibling() {
+			if _, nested := child.(*ast.List); nested {
+				w.block(child, depth+1)
+				continue
+			}
+			w.pdf.SetFont("Helvetica", "", bodySize)
+			w.pdf.MultiCell(0, leading(bodySize), w.translate(w.inline(child)), "", "L", false)
+		}
+	})
+}
+
+// indent runs fn with the left margin moved in by depth list levels
+func (w *pdfWriter) indent(depth int, fn func()) {
+	w.pdf.SetLeftMargin(w.margin + float64(depth)*listIndent)
+	fn()
+	w.pdf.SetLeftMargin(w.margin)
+	w.pdf.SetX(w.margin)
+}
+
+// text prints a paragraph; size is in points
+func (w *pdfWriter) text(family, style string, size float64, content string) {
+	if content == "" {
+		return
+	}
+	w.pdf.SetFont(family, style, size)
+	w.pdf.MultiCell(0, leading(size), w.translate(content), "", "L", false)
+	w.pdf.Ln(size * ptToMM / 2)
+}
+
+func (w *pdfWriter) rule() {
+	pageWidth, _ := w.pdf.GetPageSize()
+	y := w.pdf.GetY() + 1
+	w.pdf.Line(w.margin, y, pageWidth-w.margin, y)
+	w.pdf.Ln(4)
+}
+
+// inline concatenates the text of a node, dropping inline markup
+func (w *pdfWriter) inline(node ast.Node) string {
+	var b strings.Builder
+	_ = ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Text:
+			b.Write(n.Segment.Value(w.source))
+			if n.HardLineBreak() {
+				b.WriteByte('\n')
+			} else if n.SoftLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			b.Write(n.Value)
+		case *ast.AutoLink:
+			b.Write(n.URL(w.source))
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return b.String()
+}
+
+// lines returns the raw lines of a code or HTML block
+func (w *pdfWriter) lines(node ast.Node) string {
+	var b strings.Builder
+	segments := node.Lines()
+	for i := 0; i < segments.Len(); i++ {
+		segment := segments.At(i)
+		b.Write(segment.Value(w.source))
+	}
+	return b.String()
+}
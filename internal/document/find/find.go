@@ -0,0 +1,148 @@
+// Package find locates text within a single document, so clients can jump
+// to matches without downloading the whole content.
+package find
+
+import (
+	"unicode"
+
+	"github.com/hafiztri123/document-api/internal/document/outline"
+)
+
+// contextRunes is how much of the line is returned on either side of a match
+const contextRunes = 40
+
+// Query is a find-in-document request. Matching is literal and, unless
+// CaseSensitive is set, case-insensitive.
+type Query struct {
+	Text          string
+	CaseSensitive bool
+	// Limit caps the matches returned; all matches are still counted
+	Limit int
+}
+
+// Match is one occurrence of the query. Offset counts characters from the
+// start of the content; Line and Column are 1-based, Column in characters.
+// Before and After are the surrounding text on the same line, cut to 40
+// characters. Section is the anchor of the innermost heading the match
+// falls under, empty before the first heading.
+type Match struct {
+	Offset  int    `json:"offset"`
+	Length  int    `json:"length"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Section string `json:"section,omitempty"`
+	Before  string `json:"before"`
+	Text    string `json:"text"`
+	After   string `json:"after"`
+}
+
+// Result lists the matches of a query. Truncated is set when Total is
+// more than the matches returned.
+type Result struct {
+	Query     string  `json:"query"`
+	Total     int     `json:"total"`
+	Truncated bool    `json:"truncated"`
+	Matches   []Match `json:"matches"`
+}
+
+// Search finds the non-overlapping occurrences of query in content.
+// headings must be its outline.
+func Search(content string, headings []outline.Heading, query Query) *Result {
+	result := &Result{Query: query.Text, Matches: []Match{}}
+
+	text := []rune(content)
+	needle := []rune(query.Text)
+	if len(needle) == 0 {
+		return result
+	}
+
+	haystack := text
+	if !query.CaseSensitive {
+		haystack = fold(text)
+		needle = fold(needle)
+	}
+
+	sections := outline.Sections(content, headings)
+	line, lineStart := 1, 0
+	scanned := 0
+
+	for i := 0; i+len(needle) <= len(haystack); {
+		if !hasPrefix(haystack[i:], needle) {
+			i++
+			continue
+		}
+
+		for ; scanned < i; scanned++ {
+			if text[scanned] == '\n' {
+				line++
+				lineStart = scanned + 1
+			}
+		}
+
+		result.Total++
+		if query.Limit <= 0 || len(result.Matches) < query.Limit {
+			end := i + len(needle)
+			result.Matches = append(result.Matches, Match{
+				Offset:  i,
+				Length:  len(needle),
+				Line:    line,
+				Column:  i - lineStart + 1,
+				Section: sectionOf(sections, line),
+				Before:  string(text[max(lineStart, i-contextRunes):i]),
+				Text:    string(text[i:end]),
+				After:   string(text[end:lineEnd(text, end, end+contextRunes)]),
+			})
+		}
+		i += len(needle)
+	}
+
+	result.Truncated = result.Total > len(result.Matches)
+	return result
+}
+
+// fold lowercases every character. It maps runes one to one, so offsets
+// into the folded text are offsets into the original.
+func fold(text []rune) []rune {
+	folded := make([]rune, len(text))
+	for i, r := range text {
+		folded[i] = unicode.ToLower(r)
+	}
+	return folded
+}
+
+func hasPrefix(text, prefix []rune) bool {
+	for i, r := range prefix {
+		if text[i] != r {
+			return false
+		}
+	}
+	return true
+}
+
+// lineEnd returns where the line containing from ends, but no further than
+// limit
+func lineEnd(text []rune, from, limit int) int {
+	limit = min(limit, len(text))
+	for i := from; i < limit; i++ {
+		if text[i] == '\n' {
+			return i
+		}
+	}
+	return limit
+}
+
+// sectionOf returns the anchor of the innermost section containing line.
+// Sections are in document order, so the last one containing it is the
+// innermost.
+func sectionOf(sections []outline.Section, line int) string {
+	anchor := ""
+	for _, section := range sections {
+		if section.Start > line {
+			break
+		}
+		if line <= section.End {
+			anchor = section.Anchor
+		}
+	}
+	return anchor
+}
@@ -0,0 +1,88 @@
+package hook
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+// Names of the hooks shipped with the document module
+const (
+	NameStripTrackingPixels = "strip_tracking_pixels"
+	NameNormalizeMarkdown   = "normalize_markdown"
+)
+
+var (
+	imgTag      = regexp.MustCompile(`(?is)<img\b[^>]*>`)
+	pixelWidth  = regexp.MustCompile(`(?i)\bwidth\s*=\s*["']?[01](px)?\b`)
+	pixelHeight = regexp.MustCompile(`(?i)\bheight\s*=\s*["']?[01](px)?\b`)
+)
+
+// StripTrackingPixels removes HTML images sized 1x1 or smaller, which are
+// used to track who opens a document
+type StripTrackingPixels struct{}
+
+// NewStripTrackingPixels creates the strip_tracking_pixels hook
+func NewStripTrackingPixels() *StripTrackingPixels {
+	return &StripTrackingPixels{}
+}
+
+func (StripTrackingPixels) Name() string {
+	return NameStripTrackingPixels
+}
+
+func (StripTrackingPixels) Apply(ctx context.Context, save *Save) error {
+	save.Content = imgTag.ReplaceAllStringFunc(save.Content, func(tag string) string {
+		if pixelWidth.MatchString(tag) && pixelHeight.MatchString(tag) {
+			return ""
+		}
+		return tag
+	})
+	return nil
+}
+
+// NormalizeMarkdown uses LF line endings, blanks whitespace-only lines,
+// collapses runs of blank lines outside code fences and ends the content
+// with a single newline. Trailing spaces on other lines are kept, since
+// they mark hard line breaks.
+type NormalizeMarkdown struct{}
+
+// NewNormalizeMarkdown creates the normalize_markdown hook
+func NewNormalizeMarkdown() *NormalizeMarkdown {
+	return &NormalizeMarkdown{}
+}
+
+func (NormalizeMarkdown) Name() string {
+	return NameNormalizeMarkdown
+}
+
+func (NormalizeMarkdown) Apply(ctx context.Context, save *Save) error {
+	content := strings.ReplaceAll(save.Content, "\r\n", "\n")
+	content = strings.TrimRight(content, " \t\n")
+	if content == "" {
+		save.Content = ""
+		return nil
+	}
+
+	lines := strings.Split(content, "\n")
+	normalized := make([]string, 0, len(lines))
+	inFence, blank := false, false
+
+	for _, line := range lines {
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+		} else if !inFence && trimmed == "" {
+			if blank {
+				continue
+			}
+			blank = true
+			normalized = append(normalized, "")
+			continue
+		}
+		blank = false
+		normalized = append(normalized, line)
+	}
+
+	save.Content = strings.Join(normalized, "\n") + "\n"
+	return nil
+}
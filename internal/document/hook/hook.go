@@ -0,0 +1,98 @@
+// Package hook runs validators and transformers on document content before
+// it is saved. Deployments register hooks with AsHook and choose which run,
+// and in what order, under documents.content_hooks.
+package hook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// Save is the content about to be persisted. Hooks may rewrite Content;
+// the other fields are for context only.
+type Save struct {
+	// DocumentID is nil while the document is being created
+	DocumentID *uuid.UUID
+	UserID     uuid.UUID
+	Title      string
+	Content    string
+}
+
+// Hook inspects or rewrites a save. Returning a *Rejection blocks the save;
+// any other error fails it.
+type Hook interface {
+	// Name identifies the hook in documents.content_hooks and rejections
+	Name() string
+	Apply(ctx context.Context, save *Save) error
+}
+
+// Violation is one reason a hook rejected content. Line is 1-based and
+// omitted when the violation is not tied to a line.
+type Violation struct {
+	Message string `json:"message"`
+	Line    int    `json:"line,omitempty"`
+}
+
+// Rejection blocks a save. Hooks leave Hook empty; the chain fills it in.
+type Rejection struct {
+	Hook       string      `json:"hook"`
+	Violations []Violation `json:"violations"`
+}
+
+func (r *Rejection) Error() string {
+	return fmt.Sprintf("content rejected by %s", r.Hook)
+}
+
+// AsHook annotates a constructor so its hook can be enabled in the chain,
+// like api.AsRouteRegistrar does for routes
+func AsHook(constructor interface{}) interface{} {
+	return fx.Annotate(
+		constructor,
+		fx.As(new(Hook)),
+		fx.ResultTags(`group:"content_hooks"`),
+	)
+}
+
+// Chain runs the enabled hooks in order
+type Chain struct {
+	hooks []Hook
+}
+
+// NewChain enables the registered hooks named in enabled, in that order.
+// Unknown names are logged and skipped.
+func NewChain(registered []Hook, enabled []string, logger *zap.Logger) *Chain {
+	byName := make(map[string]Hook, len(registered))
+	for _, hook := range registered {
+		byName[hook.Name()] = hook
+	}
+
+	chain := &Chain{}
+	for _, name := range enabled {
+		hook, ok := byName[name]
+		if !ok {
+			logger.Warn("Unknown content hook, skipping it", zap.String("hook", name))
+			continue
+		}
+		chain.hooks = append(chain.hooks, hook)
+	}
+	return chain
+}
+
+// Run applies every hook to save, stopping at the first error
+func (c *Chain) Run(ctx context.Context, save *Save) error {
+	for _, hook := range c.hooks {
+		if err := hook.Apply(ctx, save); err != nil {
+			var rejection *Rejection
+			if errors.As(err, &rejection) && rejection.Hook == "" {
+				rejection.Hook = hook.Name()
+			}
+			return err
+		}
+	}
+	return nil
+}
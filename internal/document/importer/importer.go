@@ -0,0 +1,108 @@
+// Package importer turns uploaded Markdown, HTML and plain text files into
+// document titles and markdown content.
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/hafiztri123/document-api/internal/document/outline"
+)
+
+// MaxFileSize is the largest file accepted for import, in bytes
+const MaxFileSize = 1 << 20
+
+// maxTitleLength matches the documents.title column
+const maxTitleLength = 255
+
+var (
+	ErrUnsupportedType = errors.New("only .md, .markdown, .html, .htm and .txt files can be imported")
+	ErrInvalidEncoding = errors.New("file is not valid UTF-8")
+	ErrFileTooLarge    = errors.New("file is too large to import")
+)
+
+// Parse returns the title and markdown content of an uploaded file. The
+// title comes from the file itself where it has one (the first top-level
+// heading, the HTML title, the first line of text) and from its name
+// otherwise.
+func Parse(filename string, data []byte) (string, string, error) {
+	if len(data) > MaxFileSize {
+		return "", "", ErrFileTooLarge
+	}
+	if !utf8.Valid(data) {
+		return "", "", ErrInvalidEncoding
+	}
+
+	var title, content string
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".md", ".markdown":
+		content = string(data)
+		title = firstHeading(content)
+	case ".html", ".htm":
+		var err error
+		title, content, err = parseHTML(data)
+		if err != nil {
+			return "", "", err
+		}
+	case ".txt":
+		content = string(data)
+		title = firstLine(content)
+	default:
+		return "", "", ErrUnsupportedType
+	}
+
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	}
+	return truncate(title, maxTitleLength), content, nil
+}
+
+// parseHTML converts the body to markdown and takes the title from the
+// title element, falling back to the first h1
+func parseHTML(data []byte) (string, string, error) {
+	page, err := goquery.NewDocumentFromReader(bytes.NewReader(data))
+	if err != nil {
+		return "", "", err
+	}
+
+	title := strings.TrimSpace(page.Find("title").First().Text())
+	if title == "" {
+		title = strings.TrimSpace(page.Find("h1").First().Text())
+	}
+
+	page.Find("head, script, style").Remove()
+	content := md.NewConverter("", true, nil).Convert(page.Selection)
+
+	return title, content, nil
+}
+
+func firstHeading(content string) string {
+	for _, heading := range outline.Rebase(nil, content) {
+		if heading.Level == 1 {
+			return heading.Text
+		}
+	}
+	return ""
+}
+
+func firstLine(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max])
+}
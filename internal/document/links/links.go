@@ -0,0 +1,73 @@
+// Package links finds references to other documents in markdown content.
+package links
+
+import (
+	"net/url"
+	"regexp"
+
+	"github.com/google/uuid"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+var markdown = goldmark.New()
+
+// Link destinations that point at a document, by ID or by alias. The API
+// prefix and host are optional, so both absolute and relative links count.
+var (
+	idPath    = regexp.MustCompile(`^(?:/api/v1)?/documents/([0-9a-fA-F-]{36})(?:/|$)`)
+	aliasPath = regexp.MustCompile(`^(?:/api/v1)?/d/([a-z0-9]+(?:-[a-z0-9]+)*)/?$`)
+)
+
+// References are the documents a piece of content links to
+type References struct {
+	IDs     []uuid.UUID
+	Aliases []string
+}
+
+// Parse returns the distinct documents linked from content. Only link and
+// autolink destinations are considered, so IDs quoted in text or code are
+// ignored.
+func Parse(content string) References {
+	source := []byte(content)
+	root := markdown.Parser().Parse(text.NewReader(source))
+
+	var refs References
+	seenIDs := map[uuid.UUID]bool{}
+	seenAliases := map[string]bool{}
+
+	_ = ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		var destination string
+		switch n := n.(type) {
+		case *ast.Link:
+			destination = string(n.Destination)
+		case *ast.AutoLink:
+			destination = string(n.URL(source))
+		default:
+			return ast.WalkContinue, nil
+		}
+
+		parsed, err := url.Parse(destination)
+		if err != nil {
+			return ast.WalkContinue, nil
+		}
+
+		if match := idPath.FindStringSubmatch(parsed.Path); match != nil {
+			if id, err := uuid.Parse(match[1]); err == nil && !seenIDs[id] {
+				seenIDs[id] = true
+				refs.IDs = append(refs.IDs, id)
+			}
+		} else if match := aliasPath.FindStringSubmatch(parsed.Path); match != nil && !seenAliases[match[1]] {
+			seenAliases[match[1]] = true
+			refs.Aliases = append(refs.Aliases, match[1])
+		}
+		return ast.WalkContinue, nil
+	})
+
+	return refs
+}
@@ -0,0 +1,103 @@
+// Package lint checks document content against configurable rules.
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hafiztri123/document-api/internal/document/outline"
+)
+
+// Rules are the checks configured under lint.rules; zero values disable a
+// check
+type Rules struct {
+	// RequiredSections are heading texts every document must contain,
+	// compared case-insensitively
+	RequiredSections []string `mapstructure:"required_sections"`
+	// ForbiddenWords may not appear anywhere in the content
+	ForbiddenWords []string `mapstructure:"forbidden_words"`
+	// MaxHeadingDepth is the deepest heading level allowed
+	MaxHeadingDepth int `mapstructure:"max_heading_depth"`
+}
+
+// Rule names reported in violations
+const (
+	RuleRequiredSection = "required_section"
+	RuleForbiddenWord   = "forbidden_word"
+	RuleMaxHeadingDepth = "max_heading_depth"
+)
+
+// Violation is a single rule failure. Line is 1-based and omitted when the
+// violation is not tied to a line.
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+	Line    int    `json:"line,omitempty"`
+}
+
+// Report is the result of linting a document
+type Report struct {
+	Passed     bool        `json:"passed"`
+	Violations []Violation `json:"violations"`
+}
+
+// Check lints content. headings must be its outline.
+func (r Rules) Check(content string, headings []outline.Heading) *Report {
+	violations := []Violation{}
+
+	for _, section := range r.RequiredSections {
+		if !hasSection(headings, section) {
+			violations = append(violations, Violation{
+				Rule:    RuleRequiredSection,
+				Message: fmt.Sprintf("Missing required section %q", section),
+			})
+		}
+	}
+
+	if r.MaxHeadingDepth > 0 {
+		for _, heading := range headings {
+			if heading.Level > r.MaxHeadingDepth {
+				violations = append(violations, Violation{
+					Rule:    RuleMaxHeadingDepth,
+					Message: fmt.Sprintf("Heading %q is level %d, deeper than the allowed %d", heading.Text, heading.Level, r.MaxHeadingDepth),
+				})
+			}
+		}
+	}
+
+	if len(r.ForbiddenWords) > 0 {
+		lines := strings.Split(content, "\n")
+		for _, word := range r.ForbiddenWords {
+			pattern := wordPattern(word)
+			for i, line := range lines {
+				if pattern.MatchString(line) {
+					violations = append(violations, Violation{
+						Rule:    RuleForbiddenWord,
+						Message: fmt.Sprintf("Forbidden word %q", word),
+						Line:    i + 1,
+					})
+				}
+			}
+		}
+	}
+
+	return &Report{
+		Passed:     len(violations) == 0,
+		Violations: violations,
+	}
+}
+
+func hasSection(headings []outline.Heading, section string) bool {
+	for _, heading := range headings {
+		if strings.EqualFold(heading.Text, section) {
+			return true
+		}
+	}
+	return false
+}
+
+// wordPattern matches word as a whole word, ignoring case
+func wordPattern(word string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)(^|[^\p{L}\p{N}])` + regexp.QuoteMeta(word) + `($|[^\p{L}\p{N}])`)
+}
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AliasRedirect remembers an alias a document no longer uses, so links to
+// it keep resolving until another document claims it
+type AliasRedirect struct {
+	Alias      string    `gorm:"type:varchar(64);primary_key" json:"alias"`
+	DocumentID uuid.UUID `gorm:"type:uuid;not null" json:"document_id"`
+	CreatedAt  time.Time `gorm:"not null" json:"created_at"`
+}
+
+func (AliasRedirect) TableName() string {
+	return "document_alias_redirects"
+}
+
+// DocumentAliasRequest sets a document's alias, resolvable at /d/:alias and,
+// for public documents, at /public/d/:alias. Without an alias one is
+// derived from the title.
+type DocumentAliasRequest struct {
+	Alias string `json:"alias" binding:"omitempty,min=3,max=64"`
+}
@@ -11,10 +11,50 @@ import (
 type Permission string
 
 const (
+	// Legacy permissions, still accepted and stored by older rows
 	PermissionRead  Permission = "read"
 	PermissionWrite Permission = "write"
+
+	// Roles
+	PermissionViewer Permission = "viewer"
+	PermissionEditor Permission = "editor"
+	PermissionAdmin  Permission = "admin"
+	// PermissionOwner makes the collaborator a co-owner, with every right
+	// of the owner, so a document outlives any one of its owners
+	PermissionOwner Permission = "owner"
 )
 
+// LegacyPermissions maps pre-role permission values to their equivalent role
+var LegacyPermissions = map[Permission]Permission{
+	PermissionRead:  PermissionViewer,
+	PermissionWrite: PermissionEditor,
+}
+
+var permissionRanks = map[Permission]int{
+	PermissionViewer: 1,
+	PermissionEditor: 2,
+	PermissionAdmin:  3,
+	PermissionOwner:  4,
+}
+
+// Normalize returns the role for a permission, translating legacy values so
+// rows written before roles existed keep working until they are backfilled
+func (p Permission) Normalize() Permission {
+	if role, ok := LegacyPermissions[p]; ok {
+		return role
+	}
+	return p
+}
+
+// Allows reports whether a collaborator holding p satisfies required
+func (p Permission) Allows(required Permission) bool {
+	rank, ok := permissionRanks[p.Normalize()]
+	if !ok {
+		return false
+	}
+	return rank >= permissionRanks[required.Normalize()]
+}
+
 type Collaborator struct {
 	ID         uuid.UUID      `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
 	DocumentID uuid.UUID      `gorm:"type:uuid;not null" json:"document_id"`
@@ -23,6 +63,8 @@ type Collaborator struct {
 	Permission Permission     `gorm:"type:varchar(20);not null" json:"permission"`
 	CreatedAt  time.Time      `gorm:"not null" json:"created_at"`
 	UpdatedAt  time.Time      `gorm:"not null" json:"updated_at"`
+	// DeletedAt is set while the document is in the trash
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 func (c *Collaborator) BeforeCreate(tx *gorm.DB) error {
@@ -49,11 +91,22 @@ type CollaboratorResponse struct {
 
 type CollaboratorCreateRequest struct {
 	UserEmail  string     `json:"user_email" binding:"required,email"`
-	Permission Permission `json:"permission" binding:"required,oneof=read write"`
+	Permission Permission `json:"permission" binding:"required,oneof=read write viewer editor admin owner"`
 }
 
 type CollaboratorUpdateRequest struct {
-	Permission Permission `json:"permission" binding:"required,oneof=read write"`
+	Permission Permission `json:"permission" binding:"required,oneof=read write viewer editor admin owner"`
+}
+
+// OwnershipTransferRequest makes another user the document's owner; the
+// previous owner stays on as a co-owner
+type OwnershipTransferRequest struct {
+	UserEmail string `json:"user_email" binding:"required,email"`
+}
+
+// BulkShareRequest shares a document with several users in one request
+type BulkShareRequest struct {
+	Collaborators []CollaboratorCreateRequest `json:"collaborators" binding:"required,min=1,max=100,dive"`
 }
 
 
@@ -72,7 +125,7 @@ func (c *Collaborator) ToResponse() CollaboratorResponse {
 			Name:  c.User.Name,
 			Email: c.User.Email,
 		},
-		Permission: c.Permission,
+		Permission: c.Permission.Normalize(),
 		CreatedAt:  c.CreatedAt,
 		UpdatedAt:  c.UpdatedAt,
 	}
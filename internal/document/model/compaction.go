@@ -0,0 +1,39 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultCompactWindow is how close together edits by the same user must
+// be to count as one when no window is requested
+const DefaultCompactWindow = 30 * time.Minute
+
+// HistoryCompactRequest squashes runs of edits made by the same user within
+// WindowMinutes of each other into the last edit of the run. The first and
+// latest versions, labeled versions and those listed in Keep are never
+// removed.
+type HistoryCompactRequest struct {
+	WindowMinutes int   `json:"window_minutes" binding:"omitempty,min=1,max=10080"`
+	Keep          []int `json:"keep" binding:"max=100"`
+}
+
+// HistoryEntry describes a recorded version without its content
+type HistoryEntry struct {
+	Version     int       `json:"version"`
+	UpdatedByID uuid.UUID `json:"updated_by_id"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Bytes       int64     `json:"bytes"`
+	Label       *string   `json:"label,omitempty"`
+}
+
+// HistoryCompaction is the outcome, or with DryRun the preview, of
+// compacting a document's history
+type HistoryCompaction struct {
+	DocumentID      uuid.UUID `json:"document_id"`
+	DryRun          bool      `json:"dry_run"`
+	RemovedVersions []int     `json:"removed_versions"`
+	KeptVersions    int       `json:"kept_versions"`
+	FreedBytes      int64     `json:"freed_bytes"`
+}
@@ -4,7 +4,11 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/hafiztri123/document-api/internal/document/diff"
+	"github.com/hafiztri123/document-api/internal/document/outline"
+	"github.com/hafiztri123/document-api/internal/document/thumbnail"
 	userModel "github.com/hafiztri123/document-api/internal/user/model"
+	"golang.org/x/text/language"
 	"gorm.io/gorm"
 )
 
@@ -12,16 +16,98 @@ import (
 type Document struct {
 	ID           	uuid.UUID     	 	`gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
 	Title        	string        	 	`gorm:"type:varchar(255);not null" json:"title"`
+	// Language is the BCP 47 tag of the content, e.g. "de" or "pt-BR"
+	Language     	*string       	 	`gorm:"type:varchar(35)" json:"language"`
 	Content      	string        	 	`gorm:"type:text" json:"content"`
 	Version      	int           	 	`gorm:"not null;default:1" json:"version"`
 	IsPublic     	bool          	 	`gorm:"not null;default:false" json:"is_public"`
 	OwnerID      	uuid.UUID     	 	`gorm:"type:uuid;not null" json:"owner_id"`
+	FolderID     	*uuid.UUID    	 	`gorm:"type:uuid" json:"folder_id"`
+	// Alias is an optional vanity ID, resolvable at /d/:alias
+	Alias        	*string       	 	`gorm:"type:varchar(64)" json:"alias"`
+	// ShortCode is a short code for dictating or typing a reference to the
+	// document, resolvable at /documents/code/:code, see NewShortCode
+	ShortCode    	string        	 	`gorm:"type:varchar(6);not null" json:"short_code"`
+	// SourcePath is the owner-scoped key documents synced from a
+	// repository are upserted by; SourceRevision is the last synced commit
+	SourcePath   	*string       	 	`gorm:"type:varchar(512)" json:"source_path,omitempty"`
+	SourceRevision	*string       	 	`gorm:"type:varchar(64)" json:"source_revision,omitempty"`
+	// CopiedFromID is the public document this one was copied from, as of
+	// CopiedFromVersion; it is cleared if the source is purged
+	CopiedFromID 	*uuid.UUID    	 	`gorm:"type:uuid" json:"copied_from_id,omitempty"`
+	CopiedFromVersion	*int      	 	`json:"copied_from_version,omitempty"`
+	// ForkedFromID is the document this one branched from, as of
+	// ForkedFromVersion; it is cleared if the source is purged
+	ForkedFromID 	*uuid.UUID    	 	`gorm:"type:uuid" json:"forked_from_id,omitempty"`
+	ForkedFromVersion	*int      	 	`json:"forked_from_version,omitempty"`
+	// ReviewBy is when the content should next be reviewed; StaleAt is set
+	// by the review scan once that date passes
+	ReviewBy     	*time.Time    	 	`json:"review_by"`
+	StaleAt      	*time.Time    	 	`json:"stale_at"`
+	// State is the lifecycle stage, see State.TransitionPermission.
+	// ArchivedAt is set while the document is archived and hides it from
+	// default listings without deleting it
+	State        	State         	 	`gorm:"type:varchar(20);not null;default:draft" json:"state"`
+	ArchivedAt   	*time.Time    	 	`json:"archived_at"`
+	// ExpiresAt is when the expiry scan applies ExpiryAction; the owner is
+	// notified documents.expiry_notice beforehand, at ExpiryNotifiedAt
+	ExpiresAt    	*time.Time    	 	`json:"expires_at"`
+	ExpiryAction 	ExpiryAction  	 	`gorm:"type:varchar(20);not null;default:archive" json:"expiry_action"`
+	ExpiryNotifiedAt	*time.Time	 	`json:"-"`
+	// Terms must be accepted by other users before they can read the
+	// content; TermsHash identifies the accepted text, see HashTerms
+	Terms        	*string       	 	`gorm:"type:text" json:"terms,omitempty"`
+	TermsHash    	*string       	 	`gorm:"type:varchar(64)" json:"-"`
+	// Encrypted documents hold client-side ciphertext, exchanged with
+	// DocumentKey; features that read the content are unavailable for them
+	Encrypted    	bool          	 	`gorm:"not null;default:false" json:"encrypted"`
+	// AnalyticsPermission is the role collaborators need to see analytics;
+	// the owner always can, see AnalyticsAccessOf
+	AnalyticsPermission	Permission	 	`gorm:"type:varchar(20);not null;default:admin" json:"analytics_permission"`
+	// Outline is recomputed on every content change, see outline.Rebase
+	Outline      	[]outline.Heading	`gorm:"type:jsonb;serializer:json" json:"-"`
 	Owner        	userModel.User	 	`gorm:"foreignKey:OwnerID" json:"-"`
 	CreatedAt    	time.Time     	 	`gorm:"not null" json:"created_at"`
 	UpdatedAt    	time.Time     	 	`gorm:"not null" json:"updated_at"`
 	DeletedAt    	gorm.DeletedAt	 	`gorm:"index" json:"-"` // Soft delete
 	Collaborators 	[]Collaborator	 	`gorm:"foreignKey:DocumentID" json:"collaborators,omitempty"`
 	History     	[]DocumentHistory 	`gorm:"foreignKey:DocumentID" json:"-"`
+	// Pinned is only loaded by user listings, for the listing user
+	Pinned       	bool          	 	`gorm:"->" json:"-"`
+	// PinPosition is only loaded by user listings, for a document pinned to
+	// the folder or workspace listed
+	PinPosition  	*int          	 	`gorm:"->" json:"-"`
+	// Draft is only set when a reader asks for their own draft alongside
+	Draft        	*DocumentDraft	 	`gorm:"-" json:"draft,omitempty"`
+}
+
+// CanonicalLanguage returns a BCP 47 tag in canonical form, e.g. "pt-BR"
+// for "PT_br", reporting whether it is well-formed
+func CanonicalLanguage(tag string) (string, bool) {
+	if tag == "" || len(tag) > 35 {
+		return "", false
+	}
+	parsed, err := language.Parse(tag)
+	if err != nil {
+		return "", false
+	}
+	return parsed.String(), true
+}
+
+// AnalyticsAccessOf returns the role collaborators need to see the
+// document's analytics, admin unless the owner opened them up
+func (d *Document) AnalyticsAccessOf() Permission {
+	if d.AnalyticsPermission == "" {
+		return PermissionAdmin
+	}
+	return d.AnalyticsPermission
+}
+
+// RequiresTerms reports whether userID must accept the terms before
+// reading. Only the primary owner is exempt here; callers also exempt
+// co-owners, see IsDocumentOwner.
+func (d *Document) RequiresTerms(userID uuid.UUID) bool {
+	return d.TermsHash != nil && d.OwnerID != userID
 }
 
 func (d *Document) BeforeCreate(tx *gorm.DB) error {
@@ -42,9 +128,22 @@ type DocumentHistory struct {
 	DocumentID uuid.UUID      `gorm:"type:uuid;not null" json:"document_id"`
 	Version    int            `gorm:"not null" json:"version"`
 	Content    string         `gorm:"type:text" json:"content"`
+	// Delta is how a version between snapshots is stored: as a change to
+	// the version before it, with no content. The repository rebuilds
+	// Content on read, see RebuildHistory.
+	Delta      *string        `gorm:"type:text" json:"-"`
 	UpdatedByID uuid.UUID     `gorm:"type:uuid;not null" json:"updated_by_id"`
 	UpdatedBy  userModel.User `gorm:"foreignKey:UpdatedByID" json:"updated_by"`
 	UpdatedAt  time.Time      `gorm:"not null" json:"updated_at"`
+	// Summary is the change summary given with the edit; for versions
+	// created by a merge it and MergedFromID describe the merge
+	MergedFromID *uuid.UUID   `gorm:"type:uuid" json:"merged_from_id,omitempty"`
+	Summary    *string        `gorm:"type:varchar(512)" json:"summary,omitempty"`
+	// Label names the version for reference and restore; labeled versions
+	// are never compacted or collapsed into later ones
+	Label      *string        `gorm:"type:varchar(100)" json:"label,omitempty"`
+	// DeletedAt is set while the document is in the trash
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 type DocumentHistoryResponse struct {
@@ -54,32 +153,231 @@ type DocumentHistoryResponse struct {
 		ID   uuid.UUID `json:"id"`
 		Name string    `json:"name"`
 	} `json:"updated_by"`
-	UpdatedAt time.Time `json:"updated_at"`
+	UpdatedAt    time.Time        `json:"updated_at"`
+	MergedFromID *uuid.UUID       `json:"merged_from_id,omitempty"`
+	Summary      *string          `json:"summary,omitempty"`
+	Label        *string          `json:"label,omitempty"`
+	Reactions    []*ReactionCount `json:"reactions"`
 }
 
 
 type DocumentCreateRequest struct {
-	Title    string `json:"title" binding:"required"`
-	Content  string `json:"content"`
-	IsPublic bool   `json:"is_public"`
+	Title    string     `json:"title" binding:"required"`
+	Content  string     `json:"content"`
+	IsPublic bool       `json:"is_public"`
+	ReviewBy *time.Time `json:"review_by"`
+	// Language is a BCP 47 tag; it is stored in canonical form
+	Language string `json:"language"`
+	// Encrypted stores content as given, as ciphertext; it cannot be
+	// changed later and rules out IsPublic
+	Encrypted bool `json:"encrypted"`
+}
+
+// DocumentCopyRequest copies a public document; without a title the
+// source's title is kept
+type DocumentCopyRequest struct {
+	Title string `json:"title" binding:"max=255"`
+}
+
+// DocumentForkRequest forks a document; without a title the source's
+// title is kept
+type DocumentForkRequest struct {
+	Title string `json:"title" binding:"max=255"`
 }
 
 type DocumentUpdateRequest struct {
 	Title    *string `json:"title"`
 	Content  *string `json:"content"`
 	IsPublic *bool   `json:"is_public"`
+	// Language replaces the BCP 47 tag; an empty one removes it
+	Language *string `json:"language"`
+	// ChangeSummary describes what a content change does, like a commit
+	// message; it is kept on the version the change creates
+	ChangeSummary *string `json:"change_summary" binding:"omitempty,max=512"`
+	// ExpectedVersion is the version the client's change was made against;
+	// the update is refused with the current document when it has moved on
+	ExpectedVersion *int `json:"expected_version" binding:"omitempty,min=1"`
+	// IfVersion, taken from If-Match, makes the update fail unless the
+	// document is still at this version
+	IfVersion *int `json:"-"`
+	// OriginClientID, taken from X-Client-ID, is the realtime connection
+	// the edit was made from; the update broadcast skips only it
+	OriginClientID string `json:"-"`
 }
 
 
+// DocumentSyncRequest upserts a document by its source path
+type DocumentSyncRequest struct {
+	Title     string `json:"title" binding:"required,max=255"`
+	Content   string `json:"content"`
+	CommitSHA string `json:"commit_sha" binding:"omitempty,hexadecimal,min=7,max=64"`
+}
+
+// DocumentReviewRequest schedules the next review; a null review_by removes
+// the schedule. Either way the document is no longer stale.
+type DocumentReviewRequest struct {
+	ReviewBy *time.Time `json:"review_by"`
+}
+
+// DocumentStaleEvent is the webhook and notification payload for documents
+// whose review date has passed
+type DocumentStaleEvent struct {
+	DocumentID uuid.UUID `json:"document_id"`
+	Title      string    `json:"title"`
+	ReviewBy   time.Time `json:"review_by"`
+	StaleAt    time.Time `json:"stale_at"`
+}
+
+// DocumentSharedEvent is the notification payload sent to a user a
+// document was shared with
+type DocumentSharedEvent struct {
+	DocumentID uuid.UUID  `json:"document_id"`
+	Title      string     `json:"title"`
+	Permission Permission `json:"permission"`
+	SharedBy   uuid.UUID  `json:"shared_by"`
+	SharedAt   time.Time  `json:"shared_at"`
+}
+
+// ExpiryAction is what happens to a document once it expires
+type ExpiryAction string
+
+const (
+	ExpiryArchive ExpiryAction = "archive"
+	ExpiryDelete  ExpiryAction = "delete"
+)
+
+// DocumentAnalyticsAccessRequest sets the role collaborators need to see the
+// document's analytics
+type DocumentAnalyticsAccessRequest struct {
+	Permission Permission `json:"permission" binding:"required,oneof=viewer editor admin"`
+}
+
+// DocumentExpiryRequest sets when a document expires; a null expires_at
+// removes the expiry. Action defaults to archive.
+type DocumentExpiryRequest struct {
+	ExpiresAt *time.Time   `json:"expires_at"`
+	Action    ExpiryAction `json:"action" binding:"omitempty,oneof=archive delete"`
+}
+
+// DocumentExpiringEvent is the webhook and notification payload sent to the
+// owner ahead of a document's expiry
+type DocumentExpiringEvent struct {
+	DocumentID uuid.UUID    `json:"document_id"`
+	Title      string       `json:"title"`
+	ExpiresAt  time.Time    `json:"expires_at"`
+	Action     ExpiryAction `json:"action"`
+}
+
+// DocumentFilter narrows the document list; zero values match everything
+type DocumentFilter struct {
+	Query string
+	// FolderID restricts the list to one folder; see RootFolder
+	FolderID *uuid.UUID
+	// Stale restricts the list to stale (true) or current (false) documents
+	Stale *bool
+	// State restricts the list to one lifecycle state; filtering on
+	// StateArchived implies IncludeArchived
+	State *State
+	// IncludeArchived lists archived documents alongside the others
+	IncludeArchived bool
+	// SortLocale is the BCP 47 tag whose collation orders titles; without
+	// one titles follow the root Unicode collation
+	SortLocale string
+}
+
+// HistoryFilter narrows a document's history; zero values match everything
+type HistoryFilter struct {
+	// UpdatedBy restricts the history to versions saved by one user
+	UpdatedBy *uuid.UUID
+	// From and To bound when versions were saved; From is inclusive and To
+	// exclusive
+	From *time.Time
+	To   *time.Time
+}
+
+// RootFolder is the FolderID filter value matching documents outside any folder
+var RootFolder = uuid.Nil
+
+type BulkAction string
+
+const (
+	BulkActionDelete      BulkAction = "delete"
+	BulkActionMakePublic  BulkAction = "make_public"
+	BulkActionMakePrivate BulkAction = "make_private"
+)
+
+// BulkDocumentRequest applies one action to several documents
+type BulkDocumentRequest struct {
+	Action      BulkAction  `json:"action" binding:"required,oneof=delete make_public make_private"`
+	DocumentIDs []uuid.UUID `json:"document_ids" binding:"required,min=1,max=100"`
+}
+
+// DocumentEvent is the webhook payload data for document events
+type DocumentEvent struct {
+	DocumentID uuid.UUID `json:"document_id"`
+	Title      string    `json:"title"`
+	Version    int       `json:"version"`
+	IsPublic   bool      `json:"is_public"`
+	State      State     `json:"state"`
+	ActorID    uuid.UUID `json:"actor_id"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	// Diff is set on document.updated events that change the content
+	Diff *DocumentDiff `json:"diff,omitempty"`
+}
+
+// DocumentDiff summarises a content change for webhooks and notifications.
+// Unified is cut short for large changes; CompareURL returns the full diff.
+type DocumentDiff struct {
+	FromVersion int    `json:"from_version"`
+	ToVersion   int    `json:"to_version"`
+	Added       int    `json:"added"`
+	Removed     int    `json:"removed"`
+	Unified     string `json:"unified"`
+	Truncated   bool   `json:"truncated"`
+	CompareURL  string `json:"compare_url"`
+}
+
+// DocumentCompareResponse is the diff between two versions
+type DocumentCompareResponse struct {
+	DocumentID  uuid.UUID `json:"document_id"`
+	FromVersion int       `json:"from_version"`
+	ToVersion   int       `json:"to_version"`
+	*diff.Result
+}
+
+// VersionRestorePreview is what restoring a version would do without doing
+// it: the content the document would have and the diff to it from the
+// current content
+type VersionRestorePreview struct {
+	DocumentID     uuid.UUID    `json:"document_id"`
+	Version        int          `json:"version"`
+	CurrentVersion int          `json:"current_version"`
+	Content        string       `json:"content"`
+	Diff           *diff.Result `json:"diff"`
+}
 
 type DocumentListResponse struct {
 	ID                uuid.UUID `json:"id"`
 	Title             string    `json:"title"`
+	Language          *string   `json:"language"`
 	Snippet           string    `json:"snippet"`
 	Version           int       `json:"version"`
 	IsPublic          bool      `json:"is_public"`
+	Encrypted         bool      `json:"encrypted"`
 	OwnerID           uuid.UUID `json:"owner_id"`
+	FolderID          *uuid.UUID `json:"folder_id"`
+	Alias             *string    `json:"alias"`
+	ShortCode         string     `json:"short_code"`
+	ReviewBy          *time.Time `json:"review_by"`
+	IsStale           bool       `json:"is_stale"`
+	State             State      `json:"state"`
+	ArchivedAt        *time.Time `json:"archived_at"`
+	Pinned            bool       `json:"pinned"`
+	// PinPosition is the document's place among the pins of the folder or
+	// workspace listed; the user's own pins have none
+	PinPosition       *int       `json:"pin_position,omitempty"`
 	CollaboratorsCount int       `json:"collaborators_count"`
+	ThumbnailURL      string     `json:"thumbnail_url"`
 	CreatedAt         time.Time `json:"created_at"`
 	UpdatedAt         time.Time `json:"updated_at"`
 }
@@ -91,14 +389,32 @@ func (d *Document) ToListResponse() DocumentListResponse {
 		snippet = snippet[:150] + "..."
 	}
 	
+	// Ciphertext makes no snippet and no preview
+	thumbnailURL := thumbnail.URL(d.ID, d.Version)
+	if d.Encrypted {
+		snippet, thumbnailURL = "", ""
+	}
+	
 	return DocumentListResponse{
 		ID:                d.ID,
 		Title:             d.Title,
+		Language:          d.Language,
 		Snippet:           snippet,
 		Version:           d.Version,
 		IsPublic:          d.IsPublic,
+		Encrypted:         d.Encrypted,
 		OwnerID:           d.OwnerID,
+		FolderID:          d.FolderID,
+		Alias:             d.Alias,
+		ShortCode:         d.ShortCode,
+		ReviewBy:          d.ReviewBy,
+		IsStale:           d.StaleAt != nil,
+		State:             d.State,
+		ArchivedAt:        d.ArchivedAt,
+		Pinned:            d.Pinned || d.PinPosition != nil,
+		PinPosition:       d.PinPosition,
 		CollaboratorsCount: len(d.Collaborators),
+		ThumbnailURL:      thumbnailURL,
 		CreatedAt:         d.CreatedAt,
 		UpdatedAt:         d.UpdatedAt,
 	}
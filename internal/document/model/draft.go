@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DocumentDraft is one user's unsaved work on a document. Drafts are kept
+// apart from the document: saving one does not bump the version, run
+// content hooks or add history. BaseVersion is the version the draft was
+// last saved against, so clients can tell when it has fallen behind.
+type DocumentDraft struct {
+	DocumentID  uuid.UUID `json:"document_id"`
+	UserID      uuid.UUID `json:"user_id"`
+	Content     string    `json:"content"`
+	BaseVersion int       `json:"base_version"`
+	SavedAt     time.Time `json:"saved_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// DocumentDraftRequest replaces the caller's draft of a document
+type DocumentDraftRequest struct {
+	Content *string `json:"content" binding:"required"`
+}
@@ -0,0 +1,20 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Fork is a document branched from the requested one, as of
+// ForkedFromVersion
+type Fork struct {
+	DocumentID        uuid.UUID `json:"document_id"`
+	Title             string    `json:"title"`
+	OwnerID           uuid.UUID `json:"owner_id"`
+	ForkedFromVersion *int      `json:"forked_from_version"`
+	Version           int       `json:"version"`
+	State             State     `json:"state"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
@@ -0,0 +1,99 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"github.com/hafiztri123/document-api/internal/document/diff"
+)
+
+// ErrBrokenHistory is returned when a stored version cannot be rebuilt
+// from the versions before it
+var ErrBrokenHistory = errors.New("document history cannot be rebuilt")
+
+// DefaultHistorySnapshotInterval is how often a version keeps its full
+// content when no interval is configured
+const DefaultHistorySnapshotInterval = 20
+
+// IsSnapshot reports whether h is stored with its full content
+func (h *DocumentHistory) IsSnapshot() bool {
+	return h.Delta == nil
+}
+
+// StoredAfter returns a copy of h as it is stored after previous, which
+// holds the full content of the version before it, with deltas versions
+// stored as deltas since the last snapshot. The copy keeps the full content
+// every interval versions, when there is no previous version and when a
+// delta would not be smaller than the content; otherwise it holds a delta
+// and no content.
+func (h *DocumentHistory) StoredAfter(previous *DocumentHistory, deltas, interval int) *DocumentHistory {
+	stored := *h
+	stored.Delta = nil
+
+	if previous == nil || deltas+1 >= interval {
+		return &stored
+	}
+
+	delta := diff.Delta(previous.Content, h.Content)
+	if len(delta) >= len(h.Content) {
+		return &stored
+	}
+
+	stored.Content = ""
+	stored.Delta = &delta
+	return &stored
+}
+
+// RebuildHistory fills in the content of versions stored as deltas.
+// history must be in version order and start with a snapshot; rebuilt
+// versions keep their Delta.
+func RebuildHistory(history []*DocumentHistory) error {
+	var previous *DocumentHistory
+	for _, h := range history {
+		if h.Delta != nil {
+			if previous == nil {
+				return ErrBrokenHistory
+			}
+			content, err := diff.Patch(previous.Content, *h.Delta)
+			if err != nil {
+				return errors.Join(ErrBrokenHistory, err)
+			}
+			h.Content = content
+		}
+		previous = h
+	}
+	return nil
+}
+
+// EncodeHistory returns history as stored, see StoredAfter. history must be
+// in version order with every content in full.
+func EncodeHistory(history []*DocumentHistory, interval int) []*DocumentHistory {
+	stored := make([]*DocumentHistory, len(history))
+
+	var previous *DocumentHistory
+	deltas := 0
+	for i, h := range history {
+		stored[i] = h.StoredAfter(previous, deltas, interval)
+		if stored[i].IsSnapshot() {
+			deltas = 0
+		} else {
+			deltas++
+		}
+		previous = h
+	}
+	return stored
+}
+
+// ContentUpdate is a save of new content, written all or nothing with the
+// version it adds to the history and the record of the edit
+type ContentUpdate struct {
+	Document *Document
+	// IfVersion makes the save conditional on the stored version
+	IfVersion *int
+	// History is the new version. Its Version is set to the document's
+	// once saved.
+	History *DocumentHistory
+	// CollapseSince lets History replace the latest version instead, see
+	// CollapseDocumentHistory
+	CollapseSince *time.Time
+}
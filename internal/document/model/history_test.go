@@ -0,0 +1,167 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hafiztri123/document-api/internal/document/diff"
+)
+
+// versions returns n versions of a growing document, each adding a line
+func versions(n int) []*DocumentHistory {
+	history := make([]*DocumentHistory, n)
+	content := strings.Repeat("An unchanged line of the document.\n", 10)
+	for i := range history {
+		content += fmt.Sprintf("Line added in version %d.\n", i+1)
+		history[i] = &DocumentHistory{Version: i + 1, Content: content}
+	}
+	return history
+}
+
+func TestStoredAfter(t *testing.T) {
+	history := versions(2)
+	previous, current := history[0], history[1]
+
+	tests := []struct {
+		name         string
+		previous     *DocumentHistory
+		current      *DocumentHistory
+		deltas       int
+		interval     int
+		wantSnapshot bool
+	}{
+		{"first version", nil, current, 0, 20, true},
+		{"after a snapshot", previous, current, 0, 20, false},
+		{"last delta before the interval", previous, current, 18, 20, false},
+		{"interval reached", previous, current, 19, 20, true},
+		{"interval of one", previous, current, 0, 1, true},
+		{"delta not smaller", &DocumentHistory{Content: "a\n"}, &DocumentHistory{Content: "b\n"}, 0, 20, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored := tt.current.StoredAfter(tt.previous, tt.deltas, tt.interval)
+			if stored.IsSnapshot() != tt.wantSnapshot {
+				t.Fatalf("IsSnapshot() = %v, want %v", stored.IsSnapshot(), tt.wantSnapshot)
+			}
+			if tt.wantSnapshot {
+				if stored.Content != tt.current.Content {
+					t.Errorf("snapshot content = %q, want %q", stored.Content, tt.current.Content)
+				}
+				return
+			}
+
+			if stored.Content != "" {
+				t.Errorf("delta keeps content %q", stored.Content)
+			}
+			got, err := diff.Patch(tt.previous.Content, *stored.Delta)
+			if err != nil || got != tt.current.Content {
+				t.Errorf("Patch(previous, delta) = %q, %v; want %q", got, err, tt.current.Content)
+			}
+			if tt.current.Delta != nil || tt.current.Content == "" {
+				t.Error("StoredAfter changed the version it copied")
+			}
+		})
+	}
+}
+
+func TestEncodeHistorySnapshots(t *testing.T) {
+	for _, interval := range []int{1, 2, 3, DefaultHistorySnapshotInterval} {
+		t.Run(fmt.Sprint(interval), func(t *testing.T) {
+			history := versions(2*interval + 1)
+			stored := EncodeHistory(history, interval)
+
+			for i, h := range stored {
+				if want := i%interval == 0; h.IsSnapshot() != want {
+					t.Errorf("version %d IsSnapshot() = %v, want %v", h.Version, h.IsSnapshot(), want)
+				}
+			}
+
+			if err := RebuildHistory(stored); err != nil {
+				t.Fatalf("RebuildHistory: %v", err)
+			}
+			for i, h := range stored {
+				if h.Content != history[i].Content {
+					t.Errorf("version %d rebuilt as %q, want %q", h.Version, h.Content, history[i].Content)
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeHistoryRoundTrip(t *testing.T) {
+	contents := []string{
+		"first\nsecond\nthird\n",
+		"",
+		"first\nsecond\nthird\n",
+		"first\nsecond\nthird",
+		"first\r\nsecond\r\nthird\r\n",
+		"first\r\nchanged\r\nthird\r\n",
+		"first\r\nchanged\r\nthird\r\nfourth",
+		"\n\n\n",
+		"",
+		"",
+	}
+	history := make([]*DocumentHistory, len(contents))
+	for i, content := range contents {
+		history[i] = &DocumentHistory{Version: i + 1, Content: content}
+	}
+
+	stored := EncodeHistory(history, DefaultHistorySnapshotInterval)
+	deltas := 0
+	for _, h := range stored {
+		if !h.IsSnapshot() {
+			deltas++
+		}
+	}
+	if deltas == 0 {
+		t.Fatal("every version was stored as a snapshot")
+	}
+
+	if err := RebuildHistory(stored); err != nil {
+		t.Fatalf("RebuildHistory: %v", err)
+	}
+	for i, h := range stored {
+		if h.Content != contents[i] {
+			t.Errorf("version %d rebuilt as %q, want %q", h.Version, h.Content, contents[i])
+		}
+	}
+}
+
+func TestRebuildHistoryBroken(t *testing.T) {
+	delta := "=1\n"
+	badDelta := "=5\n"
+
+	tests := []struct {
+		name         string
+		history      []*DocumentHistory
+		wantBadDelta bool
+	}{
+		{
+			name:    "starts with a delta",
+			history: []*DocumentHistory{{Version: 2, Delta: &delta}},
+		},
+		{
+			name: "delta does not apply",
+			history: []*DocumentHistory{
+				{Version: 1, Content: "a\n"},
+				{Version: 2, Delta: &badDelta},
+			},
+			wantBadDelta: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := RebuildHistory(tt.history)
+			if !errors.Is(err, ErrBrokenHistory) {
+				t.Errorf("RebuildHistory() = %v, want ErrBrokenHistory", err)
+			}
+			if errors.Is(err, diff.ErrBadDelta) != tt.wantBadDelta {
+				t.Errorf("RebuildHistory() = %v, wrapping ErrBadDelta: want %v", err, tt.wantBadDelta)
+			}
+		})
+	}
+}
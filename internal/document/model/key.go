@@ -0,0 +1,41 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DocumentKey is one member's key exchange for an encrypted document. The
+// member registers PublicKey; the owner, who holds the document key, then
+// stores WrappedKey, the document key encrypted to PublicKey. The server
+// never sees the document key itself.
+type DocumentKey struct {
+	DocumentID uuid.UUID `gorm:"type:uuid;primary_key" json:"document_id"`
+	UserID     uuid.UUID `gorm:"type:uuid;primary_key" json:"user_id"`
+	// Algorithm names the client's key agreement and wrapping scheme; the
+	// server does not interpret it
+	Algorithm   string     `gorm:"type:varchar(50);not null" json:"algorithm"`
+	PublicKey   string     `gorm:"type:text;not null" json:"public_key"`
+	WrappedKey  *string    `gorm:"type:text" json:"wrapped_key"`
+	WrappedByID *uuid.UUID `gorm:"type:uuid" json:"wrapped_by_id"`
+	CreatedAt   time.Time  `gorm:"not null" json:"created_at"`
+	UpdatedAt   time.Time  `gorm:"not null" json:"updated_at"`
+}
+
+func (DocumentKey) TableName() string {
+	return "document_keys"
+}
+
+// DocumentPublicKeyRequest registers the caller's public key. Changing it
+// drops the wrapped key, which was encrypted to the old one.
+type DocumentPublicKeyRequest struct {
+	Algorithm string `json:"algorithm" binding:"required,max=50"`
+	PublicKey string `json:"public_key" binding:"required,max=8192"`
+}
+
+// DocumentWrappedKeyRequest gives a member the document key, wrapped to
+// the public key they registered
+type DocumentWrappedKeyRequest struct {
+	WrappedKey string `json:"wrapped_key" binding:"required,max=8192"`
+}
@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// VersionLabelRequest names a version, e.g. "v1.0 sent to client". Labels
+// are unique per document; labeling a version again replaces its label.
+type VersionLabelRequest struct {
+	Label string `json:"label" binding:"required,max=100"`
+}
+
+// LabeledVersion is a labeled version without its content
+type LabeledVersion struct {
+	Version     int       `json:"version"`
+	Label       string    `json:"label"`
+	UpdatedByID uuid.UUID `json:"updated_by_id"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
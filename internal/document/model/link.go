@@ -0,0 +1,27 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DocumentLink records that the source document's content links to the
+// target document. Links are recomputed whenever the source content changes.
+type DocumentLink struct {
+	SourceID  uuid.UUID `gorm:"type:uuid;primary_key" json:"source_id"`
+	TargetID  uuid.UUID `gorm:"type:uuid;primary_key" json:"target_id"`
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+}
+
+func (DocumentLink) TableName() string {
+	return "document_links"
+}
+
+// Backlink is a document that links to the requested one
+type Backlink struct {
+	DocumentID uuid.UUID `json:"document_id"`
+	Title      string    `json:"title"`
+	State      State     `json:"state"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
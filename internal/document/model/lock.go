@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DocumentLock reserves a document for one writer until it is released or
+// expires. Other writers' saves are refused while it is held.
+type DocumentLock struct {
+	DocumentID uuid.UUID `json:"document_id"`
+	UserID     uuid.UUID `json:"user_id"`
+	UserName   string    `json:"user_name"`
+	AcquiredAt time.Time `json:"acquired_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// DocumentLockRequest acquires or extends a lock; TTLSeconds defaults to
+// documents.lock_ttl
+type DocumentLockRequest struct {
+	TTLSeconds int `json:"ttl_seconds" binding:"omitempty,min=30,max=86400"`
+}
@@ -0,0 +1,26 @@
+package model
+
+import "github.com/google/uuid"
+
+// MergePosition is where merged content goes in the target document
+type MergePosition string
+
+const (
+	MergeAppend  MergePosition = "append"
+	MergePrepend MergePosition = "prepend"
+)
+
+// DocumentMergeRequest merges the source document's content into the
+// target. Position defaults to append; DeleteSource requires owning the
+// source.
+type DocumentMergeRequest struct {
+	SourceID     uuid.UUID     `json:"source_id" binding:"required"`
+	Position     MergePosition `json:"position" binding:"omitempty,oneof=append prepend"`
+	DeleteSource bool          `json:"delete_source"`
+}
+
+// DocumentMergeResult is the target document after the merge
+type DocumentMergeResult struct {
+	Document      *Document `json:"document"`
+	SourceDeleted bool      `json:"source_deleted"`
+}
@@ -0,0 +1,27 @@
+package model
+
+import "github.com/google/uuid"
+
+// PermissionNone is the effective permission of a user with no access
+const PermissionNone Permission = "none"
+
+// PermissionCheckRequest asks for the caller's permission on many documents
+// at once
+type PermissionCheckRequest struct {
+	DocumentIDs []uuid.UUID `json:"document_ids" binding:"required,min=1,max=500"`
+}
+
+// RecentAccess is a user who viewed or edited a document recently
+type RecentAccess struct {
+	DocumentID uuid.UUID
+	UserID     uuid.UUID
+	IsPublic   bool
+}
+
+// EffectivePermission is what a user can do with a document: owner, their
+// role as a collaborator, viewer of a public document, or none. Documents
+// that do not exist or are in the trash are none too.
+type EffectivePermission struct {
+	DocumentID uuid.UUID  `json:"document_id"`
+	Permission Permission `json:"permission"`
+}
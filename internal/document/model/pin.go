@@ -0,0 +1,18 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DocumentPin keeps a document at the top of one user's document list
+type DocumentPin struct {
+	UserID     uuid.UUID `gorm:"type:uuid;primary_key" json:"user_id"`
+	DocumentID uuid.UUID `gorm:"type:uuid;primary_key" json:"document_id"`
+	CreatedAt  time.Time `gorm:"not null" json:"created_at"`
+}
+
+func (DocumentPin) TableName() string {
+	return "document_pins"
+}
@@ -0,0 +1,59 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hafiztri123/document-api/internal/document/blocks"
+	"github.com/hafiztri123/document-api/internal/document/outline"
+)
+
+// PublicDocument is what anonymous readers receive for a public document.
+// HTML is the rendered content with heading anchors. Alias, when set, gives
+// the readable link /public/d/:alias. ThumbnailURL is the URL of a preview
+// image for link unfurls.
+type PublicDocument struct {
+	ID           uuid.UUID `json:"id"`
+	Alias        *string   `json:"alias,omitempty"`
+	Title        string    `json:"title"`
+	Content      string    `json:"content"`
+	HTML         string    `json:"html"`
+	Version      int       `json:"version"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// PublishedFormat versions the layout of PublishedDocument. Fields and
+// block types may be added within a format; anything else bumps it.
+const PublishedFormat = 1
+
+// PublishedDocument is a public document as structured data, for static
+// site generators to consume at build time. It is served with the same
+// cache policy and ETag as PublicDocument.
+type PublishedDocument struct {
+	Format       int               `json:"format"`
+	ID           uuid.UUID         `json:"id"`
+	Alias        *string           `json:"alias,omitempty"`
+	Title        string            `json:"title"`
+	Version      int               `json:"version"`
+	ThumbnailURL string            `json:"thumbnail_url"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+	Authors      []PublishedAuthor `json:"authors"`
+	Outline      []outline.Heading `json:"outline"`
+	Blocks       []blocks.Block    `json:"blocks"`
+}
+
+// Author roles of a published document
+const (
+	AuthorOwner       = "owner"
+	AuthorContributor = "contributor"
+)
+
+// PublishedAuthor is the owner or someone who saved a version. The owner
+// comes first, then the others in order of their first version.
+type PublishedAuthor struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+	Role string    `json:"role"`
+}
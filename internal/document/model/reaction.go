@@ -0,0 +1,56 @@
+package model
+
+import (
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// Reaction is an emoji a user left on a version of a document, e.g. a 👀
+// to acknowledge reading it. A user leaves each emoji once per version.
+type Reaction struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	DocumentID uuid.UUID `gorm:"type:uuid;not null" json:"document_id"`
+	Version    int       `gorm:"not null" json:"version"`
+	UserID     uuid.UUID `gorm:"type:uuid;not null" json:"user_id"`
+	Emoji      string    `gorm:"type:varchar(32);not null" json:"emoji"`
+	CreatedAt  time.Time `gorm:"not null" json:"created_at"`
+}
+
+func (Reaction) TableName() string {
+	return "version_reactions"
+}
+
+// ReactionRequest adds an emoji to a version
+type ReactionRequest struct {
+	Emoji string `json:"emoji" binding:"required,max=32"`
+}
+
+// ReactionCount is how many users left an emoji on a version; Reacted is
+// whether the user asking is one of them
+type ReactionCount struct {
+	Version int    `json:"-"`
+	Emoji   string `json:"emoji"`
+	Count   int    `json:"count"`
+	Reacted bool   `json:"reacted"`
+}
+
+// ValidEmoji reports whether value is made of emoji only: pictographs,
+// optionally joined into sequences and modified by skin tones or variation
+// selectors, as in "👍", "👍🏽" or "🧑‍💻"
+func ValidEmoji(value string) bool {
+	pictographs := 0
+	for _, r := range value {
+		switch {
+		case unicode.Is(unicode.So, r):
+			pictographs++
+		case r == 0x200D, // zero width joiner
+			r == 0xFE0F,                  // emoji presentation
+			r >= 0x1F3FB && r <= 0x1F3FF: // skin tones
+		default:
+			return false
+		}
+	}
+	return pictographs > 0
+}
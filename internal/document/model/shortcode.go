@@ -0,0 +1,54 @@
+package model
+
+import (
+	"crypto/rand"
+	"strings"
+)
+
+// ShortCodeLength is the length of a document's short code
+const ShortCodeLength = 6
+
+// shortCodeAlphabet is Crockford's base32: digits and capitals without I,
+// L, O and U, which are easily misheard or mistyped
+const shortCodeAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewShortCode returns a random short code. Codes are not checked for
+// collisions here; callers retry with a new code when one is taken.
+func NewShortCode() (string, error) {
+	random := make([]byte, ShortCodeLength)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	code := make([]byte, ShortCodeLength)
+	for i, b := range random {
+		code[i] = shortCodeAlphabet[int(b)%len(shortCodeAlphabet)]
+	}
+	return string(code), nil
+}
+
+// NormalizeShortCode turns a code as a person typed it into its stored
+// form, reporting whether it is a well-formed code. Case, spaces and
+// hyphens are ignored, and O, I and L read as 0, 1 and 1.
+func NormalizeShortCode(code string) (string, bool) {
+	var normalized strings.Builder
+	for _, r := range strings.ToUpper(code) {
+		switch r {
+		case ' ', '-':
+			continue
+		case 'O':
+			r = '0'
+		case 'I', 'L':
+			r = '1'
+		}
+		if !strings.ContainsRune(shortCodeAlphabet, r) {
+			return "", false
+		}
+		normalized.WriteRune(r)
+	}
+
+	if normalized.Len() != ShortCodeLength {
+		return "", false
+	}
+	return normalized.String(), true
+}
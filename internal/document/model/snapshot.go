@@ -0,0 +1,51 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DocumentSnapshot is an immutable published copy of one version, readable
+// without authentication at /public/snapshots/:id while collaborators keep
+// editing the live document. Title is the document's title when the
+// snapshot was published.
+type DocumentSnapshot struct {
+	ID            uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	DocumentID    uuid.UUID `gorm:"type:uuid;not null" json:"document_id"`
+	Version       int       `gorm:"not null" json:"version"`
+	Title         string    `gorm:"type:varchar(255);not null" json:"title"`
+	Content       string    `gorm:"type:text;not null" json:"-"`
+	HTML          string    `gorm:"column:html;type:text;not null" json:"-"`
+	PublishedByID uuid.UUID `gorm:"type:uuid;not null" json:"published_by_id"`
+	CreatedAt     time.Time `gorm:"not null" json:"published_at"`
+}
+
+func (DocumentSnapshot) TableName() string {
+	return "document_snapshots"
+}
+
+func (s *DocumentSnapshot) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+// SnapshotPublishRequest publishes a version; without one the current
+// version is published
+type SnapshotPublishRequest struct {
+	Version *int `json:"version" binding:"omitempty,min=1"`
+}
+
+// PublicSnapshot is what anonymous readers receive for a snapshot
+type PublicSnapshot struct {
+	ID          uuid.UUID `json:"id"`
+	DocumentID  uuid.UUID `json:"document_id"`
+	Version     int       `json:"version"`
+	Title       string    `json:"title"`
+	Content     string    `json:"content"`
+	HTML        string    `json:"html"`
+	PublishedAt time.Time `json:"published_at"`
+}
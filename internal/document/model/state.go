@@ -0,0 +1,78 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// State is a document's lifecycle stage
+type State string
+
+const (
+	StateDraft     State = "draft"
+	StateInReview  State = "in_review"
+	StatePublished State = "published"
+	StateArchived  State = "archived"
+)
+
+// stateTransitions lists the states each state may move to, with the
+// permission a collaborator needs to make the move
+var stateTransitions = map[State]map[State]Permission{
+	StateDraft: {
+		StateInReview: PermissionEditor,
+		StateArchived: PermissionAdmin,
+	},
+	StateInReview: {
+		StateDraft:     PermissionEditor,
+		StatePublished: PermissionAdmin,
+		StateArchived:  PermissionAdmin,
+	},
+	StatePublished: {
+		StateDraft:    PermissionAdmin,
+		StateArchived: PermissionAdmin,
+	},
+	StateArchived: {
+		StateDraft: PermissionAdmin,
+	},
+}
+
+// Valid reports whether s is a known lifecycle state
+func (s State) Valid() bool {
+	_, ok := stateTransitions[s]
+	return ok
+}
+
+// TransitionPermission returns the permission needed to move from s to
+// next, or false when the lifecycle does not allow the move
+func (s State) TransitionPermission(next State) (Permission, bool) {
+	permission, ok := stateTransitions[s][next]
+	return permission, ok
+}
+
+// EditPermission is the permission needed to change a document's content
+// or settings while it is in state s. Published and archived documents are
+// only editable by admins.
+func (s State) EditPermission() Permission {
+	switch s {
+	case StatePublished, StateArchived:
+		return PermissionAdmin
+	default:
+		return PermissionEditor
+	}
+}
+
+// DocumentTransitionRequest moves a document to another lifecycle state
+type DocumentTransitionRequest struct {
+	State State `json:"state" binding:"required,oneof=draft in_review published archived"`
+}
+
+// DocumentStateEvent is the webhook payload for lifecycle transitions
+type DocumentStateEvent struct {
+	DocumentID uuid.UUID `json:"document_id"`
+	Title      string    `json:"title"`
+	From       State     `json:"from"`
+	To         State     `json:"to"`
+	ActorID    uuid.UUID `json:"actor_id"`
+	ChangedAt  time.Time `json:"changed_at"`
+}
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DocumentStats describes the size of a document's current content and how
+// it grew over its recorded versions
+type DocumentStats struct {
+	DocumentID         uuid.UUID       `json:"document_id"`
+	Version            int             `json:"version"`
+	Words              int             `json:"words"`
+	Characters         int             `json:"characters"`
+	ReadingTimeMinutes int             `json:"reading_time_minutes"`
+	Growth             []VersionGrowth `json:"growth"`
+}
+
+// VersionGrowth is the size of one recorded version and its change from the
+// version recorded before it
+type VersionGrowth struct {
+	Version         int       `json:"version"`
+	Words           int       `json:"words"`
+	Characters      int       `json:"characters"`
+	WordsDelta      int       `json:"words_delta"`
+	CharactersDelta int       `json:"characters_delta"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
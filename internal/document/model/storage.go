@@ -0,0 +1,27 @@
+package model
+
+import (
+	"github.com/google/uuid"
+)
+
+// DocumentStorage is the space a document takes up, in bytes. History
+// counts every recorded version, including the current one.
+type DocumentStorage struct {
+	DocumentID      uuid.UUID `json:"document_id"`
+	Title           string    `json:"title"`
+	ContentBytes    int64     `json:"content_bytes"`
+	HistoryBytes    int64     `json:"history_bytes"`
+	HistoryVersions int64     `json:"history_versions"`
+	TotalBytes      int64     `json:"total_bytes"`
+}
+
+// StorageUsage is the space taken up by every document a user owns, with
+// the largest documents listed first as cleanup candidates
+type StorageUsage struct {
+	Documents       int64              `json:"documents"`
+	ContentBytes    int64              `json:"content_bytes"`
+	HistoryBytes    int64              `json:"history_bytes"`
+	HistoryVersions int64              `json:"history_versions"`
+	TotalBytes      int64              `json:"total_bytes"`
+	Largest         []*DocumentStorage `json:"largest" gorm:"-"`
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SectionUpdatedNotification is the realtime notification sent to
+// subscribers of a section an edit touched
+const SectionUpdatedNotification = "document.section_updated"
+
+// SectionSubscription notifies a user of edits to the section under the
+// heading with Anchor. Anchors survive edits, see outline.Rebase, so the
+// subscription follows a renamed heading.
+type SectionSubscription struct {
+	DocumentID uuid.UUID `gorm:"type:uuid;primary_key" json:"document_id"`
+	UserID     uuid.UUID `gorm:"type:uuid;primary_key" json:"user_id"`
+	Anchor     string    `gorm:"type:varchar(255);primary_key" json:"anchor"`
+	CreatedAt  time.Time `gorm:"not null" json:"created_at"`
+}
+
+func (SectionSubscription) TableName() string {
+	return "section_subscriptions"
+}
+
+// SectionSubscriptionRequest subscribes to the section under a heading
+type SectionSubscriptionRequest struct {
+	Anchor string `json:"anchor" binding:"required,max=255"`
+}
+
+// SectionUpdatedEvent tells a subscriber which of their sections an edit
+// touched
+type SectionUpdatedEvent struct {
+	DocumentID uuid.UUID `json:"document_id"`
+	Title      string    `json:"title"`
+	Version    int       `json:"version"`
+	Anchors    []string  `json:"anchors"`
+	ActorID    uuid.UUID `json:"actor_id"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/google/uuid"
+	userModel "github.com/hafiztri123/document-api/internal/user/model"
+)
+
+// DocumentTermsAcceptance records that a user accepted one version of a
+// document's terms, and from where
+type DocumentTermsAcceptance struct {
+	DocumentID uuid.UUID      `gorm:"type:uuid;primary_key" json:"document_id"`
+	UserID     uuid.UUID      `gorm:"type:uuid;primary_key" json:"user_id"`
+	TermsHash  string         `gorm:"type:varchar(64);primary_key" json:"terms_hash"`
+	IPAddress  string         `gorm:"type:varchar(45);not null" json:"ip_address"`
+	AcceptedAt time.Time      `gorm:"not null" json:"accepted_at"`
+	User       userModel.User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+func (DocumentTermsAcceptance) TableName() string {
+	return "document_terms_acceptances"
+}
+
+// DocumentTermsRequest sets the terms other users must accept before
+// reading the document; a null or empty terms removes the requirement
+type DocumentTermsRequest struct {
+	Terms *string `json:"terms" binding:"omitempty,max=20000"`
+}
+
+// DocumentTerms is a document's terms and whether the caller may read the
+// content: Accepted is also true for the owner and when Terms is null.
+type DocumentTerms struct {
+	DocumentID uuid.UUID  `json:"document_id"`
+	Terms      *string    `json:"terms"`
+	TermsHash  *string    `json:"terms_hash"`
+	Accepted   bool       `json:"accepted"`
+	AcceptedAt *time.Time `json:"accepted_at"`
+}
+
+// TermsAcceptanceResponse lists one acceptance to the document owner.
+// Current is false for acceptances of terms that have since changed.
+type TermsAcceptanceResponse struct {
+	User struct {
+		ID    uuid.UUID `json:"id"`
+		Name  string    `json:"name"`
+		Email string    `json:"email"`
+	} `json:"user"`
+	TermsHash  string    `json:"terms_hash"`
+	Current    bool      `json:"current"`
+	IPAddress  string    `json:"ip_address"`
+	AcceptedAt time.Time `json:"accepted_at"`
+}
+
+// HashTerms identifies a version of a document's terms
+func HashTerms(terms string) string {
+	sum := sha256.Sum256([]byte(terms))
+	return hex.EncodeToString(sum[:])
+}
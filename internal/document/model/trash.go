@@ -0,0 +1,18 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TrashedDocument is a deleted document in its owner's trash. Restoring it
+// brings back its history, collaborators, views and edits with it.
+type TrashedDocument struct {
+	ID        uuid.UUID  `json:"id"`
+	Title     string     `json:"title"`
+	FolderID  *uuid.UUID `json:"folder_id"`
+	Version   int        `json:"version"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt time.Time  `json:"deleted_at"`
+}
@@ -0,0 +1,118 @@
+package document
+
+import (
+	"net/http"
+
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"github.com/hafiztri123/document-api/config"
+	"github.com/hafiztri123/document-api/internal/api"
+	"github.com/hafiztri123/document-api/internal/document/cdn"
+	"github.com/hafiztri123/document-api/internal/document/controller"
+	"github.com/hafiztri123/document-api/internal/document/hook"
+	"github.com/hafiztri123/document-api/internal/document/redact"
+	"github.com/hafiztri123/document-api/internal/document/sanitize"
+	"github.com/hafiztri123/document-api/internal/document/repository"
+	"github.com/hafiztri123/document-api/internal/document/service"
+	"github.com/hafiztri123/document-api/internal/mail"
+)
+
+// Module provides the document repository, service, controller and routes,
+// and schedules the background scans
+var Module = fx.Module("document",
+	fx.Provide(
+		repository.NewDocumentRepository,
+		repository.NewLockRepository,
+		repository.NewDraftRepository,
+		service.NewDocumentService,
+		controller.NewDocumentController,
+		api.AsRouteRegistrar(newRoutes),
+
+		// Content hooks; other modules may register more with hook.AsHook
+		hook.AsHook(hook.NewStripTrackingPixels),
+		hook.AsHook(hook.NewNormalizeMarkdown),
+		hook.AsHook(redactionHook),
+		hook.AsHook(newSanitizer),
+		newRedactionFilter,
+		newCDNPurger,
+		newMailSender,
+		fx.Annotate(newContentHooks, fx.ParamTags(`group:"content_hooks"`)),
+	),
+	fx.Invoke(startReviewScan),
+	fx.Invoke(startExpiryScan),
+	fx.Invoke(startDigestScan),
+)
+
+// newContentHooks builds the chain enabled under documents.content_hooks
+func newContentHooks(registered []hook.Hook, logger *zap.Logger) *hook.Chain {
+	return hook.NewChain(registered, viper.GetStringSlice(config.DOCUMENTS_CONTENT_HOOKS), logger)
+}
+
+// newRedactionFilter compiles redaction.rules; the service also checks it
+// before publishing
+func newRedactionFilter(logger *zap.Logger) *redact.Filter {
+	var rules redact.Rules
+	if err := viper.UnmarshalKey(config.REDACTION_RULES, &rules); err != nil {
+		logger.Error("Invalid redaction rules config, ignoring it", zap.Error(err))
+	}
+
+	filter, err := redact.NewFilter(rules)
+	if err != nil {
+		logger.Error("Invalid redaction rules config, ignoring it", zap.Error(err))
+		filter, _ = redact.NewFilter(redact.Rules{})
+	}
+	return filter
+}
+
+// newSanitizer builds the sanitize_html content hook from the allowlist
+// under sanitization
+func newSanitizer(logger *zap.Logger) *sanitize.Sanitizer {
+	var allowlist sanitize.Allowlist
+	if err := viper.UnmarshalKey(config.SANITIZATION, &allowlist); err != nil {
+		logger.Error("Invalid sanitization config, using the default allowlist", zap.Error(err))
+		allowlist = sanitize.Allowlist{}
+	}
+	return sanitize.New(allowlist)
+}
+
+// newCDNPurger creates the purger for the CDN configured under cdn, or nil
+// when there is none
+func newCDNPurger(logger *zap.Logger) cdn.Purger {
+	var cdnConfig cdn.Config
+	if err := viper.UnmarshalKey(config.CDN, &cdnConfig); err != nil {
+		logger.Error("Invalid CDN config, ignoring it", zap.Error(err))
+		return nil
+	}
+
+	purger, err := cdn.NewPurger(cdnConfig, &http.Client{})
+	if err != nil {
+		logger.Error("Invalid CDN config, ignoring it", zap.Error(err))
+		return nil
+	}
+	return purger
+}
+
+// newMailSender creates the SMTP sender configured under mail, or nil when
+// mail is not configured
+func newMailSender(logger *zap.Logger) mail.Sender {
+	var mailConfig mail.Config
+	if err := viper.UnmarshalKey(config.MAIL, &mailConfig); err != nil {
+		logger.Error("Invalid mail config, ignoring it", zap.Error(err))
+		return nil
+	}
+
+	sender, err := mail.NewSender(mailConfig)
+	if err != nil {
+		logger.Error("Invalid mail config, ignoring it", zap.Error(err))
+		return nil
+	}
+	return sender
+}
+
+// redactionHook registers the shared redaction filter as the "redact"
+// content hook
+func redactionHook(filter *redact.Filter) *redact.Filter {
+	return filter
+}
@@ -0,0 +1,235 @@
+// Package outline extracts the heading structure of markdown content and
+// assigns each heading an anchor that deep links can rely on.
+package outline
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// Heading is an entry of a document's table of contents
+type Heading struct {
+	Level  int    `json:"level"`
+	Text   string `json:"text"`
+	Anchor string `json:"anchor"`
+}
+
+var markdown = goldmark.New()
+
+var nonSlugChars = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+// Rebase returns the outline of content, reusing the anchors of previous
+// wherever a heading survived the edit. A heading keeps its anchor when its
+// text is unchanged or, failing that, when it was renamed in place: it sits
+// between the same unchanged neighbours as an old heading of its level.
+// Only headings that are genuinely new get fresh anchors.
+func Rebase(previous []Heading, content string) []Heading {
+	_, headings, _ := parse([]byte(content))
+	if headings == nil {
+		headings = []Heading{}
+	}
+
+	used := make(map[string]bool, len(headings))
+	claimed := make([]bool, len(previous))
+	// origin[i] is the index in previous that heading i was matched to
+	origin := make([]int, len(headings))
+
+	// Unchanged headings, in order, so duplicates keep their suffixes
+	for i := range headings {
+		origin[i] = -1
+		for j, old := range previous {
+			if !claimed[j] && old.Level == headings[i].Level && old.Text == headings[i].Text {
+				headings[i].Anchor = old.Anchor
+				claimed[j] = true
+				used[old.Anchor] = true
+				origin[i] = j
+				break
+			}
+		}
+	}
+
+	// Renamed headings
+	for i := range headings {
+		if origin[i] >= 0 {
+			continue
+		}
+
+		lo, hi := -1, len(previous)
+		for k := i - 1; k >= 0; k-- {
+			if origin[k] >= 0 {
+				lo = origin[k]
+				break
+			}
+		}
+		for k := i + 1; k < len(headings); k++ {
+			if origin[k] >= 0 {
+				hi = origin[k]
+				break
+			}
+		}
+
+		for j := lo + 1; j < hi; j++ {
+			if !claimed[j] && !used[previous[j].Anchor] && previous[j].Level == headings[i].Level {
+				headings[i].Anchor = previous[j].Anchor
+				claimed[j] = true
+				used[previous[j].Anchor] = true
+				origin[i] = j
+				break
+			}
+		}
+	}
+
+	// New headings
+	for i := range headings {
+		if headings[i].Anchor == "" {
+			headings[i].Anchor = uniqueSlug(headings[i].Text, used)
+			used[headings[i].Anchor] = true
+		}
+	}
+
+	return headings
+}
+
+// RenderHTML renders content as HTML with id attributes taken from
+// headings, which should be the outline of the same content. Headings that
+// do not line up with the outline get a fresh anchor.
+func RenderHTML(content string, headings []Heading) (string, error) {
+	source := []byte(content)
+	document, parsed, nodes := parse(source)
+
+	for i, anchor := range anchors(parsed, headings) {
+		nodes[i].SetAttributeString("id", []byte(anchor))
+	}
+
+	var buf bytes.Buffer
+	if err := markdown.Renderer().Render(&buf, source, document); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Section is the lines a heading covers: from the heading itself up to the
+// next heading of the same or a higher level. Lines are 1-based and
+// inclusive, so a subsection also lies within its parent sections.
+type Section struct {
+	Anchor string `json:"anchor"`
+	Start  int    `json:"start"`
+	End    int    `json:"end"`
+}
+
+// Sections returns the section of every heading in content, with anchors
+// taken from headings like RenderHTML does
+func Sections(content string, headings []Heading) []Section {
+	source := []byte(content)
+	_, parsed, nodes := parse(source)
+	lastLine := strings.Count(strings.TrimSuffix(content, "\n"), "\n") + 1
+
+	sections := make([]Section, len(nodes))
+	for i, anchor := range anchors(parsed, headings) {
+		sections[i] = Section{Anchor: anchor, Start: 1, End: lastLine}
+		// Empty headings have no text to locate them by; they start where
+		// the previous heading does
+		if lines := nodes[i].Lines(); lines.Len() > 0 {
+			sections[i].Start = bytes.Count(source[:lines.At(0).Start], []byte("\n")) + 1
+		} else if i > 0 {
+			sections[i].Start = sections[i-1].Start
+		}
+	}
+
+	for i := range sections {
+		for j := i + 1; j < len(sections); j++ {
+			if parsed[j].Level <= parsed[i].Level {
+				sections[i].End = sections[j].Start - 1
+				break
+			}
+		}
+	}
+	return sections
+}
+
+// Anchors returns the anchor of every heading in content, in document
+// order, taken from headings like RenderHTML does
+func Anchors(content string, headings []Heading) []string {
+	_, parsed, _ := parse([]byte(content))
+	return anchors(parsed, headings)
+}
+
+// anchors lines up the parsed headings with a stored outline, reusing its
+// anchors where the text matches and giving the other headings fresh ones
+func anchors(parsed, headings []Heading) []string {
+	result := make([]string, len(parsed))
+	used := make(map[string]bool, len(headings))
+
+	for i := range parsed {
+		if i < len(headings) && headings[i].Text == parsed[i].Text && !used[headings[i].Anchor] {
+			result[i] = headings[i].Anchor
+		} else {
+			result[i] = uniqueSlug(parsed[i].Text, used)
+		}
+		used[result[i]] = true
+	}
+	return result
+}
+
+// parse returns the document tree with its headings in document order
+func parse(source []byte) (ast.Node, []Heading, []*ast.Heading) {
+	document := markdown.Parser().Parse(text.NewReader(source))
+
+	var headings []Heading
+	var nodes []*ast.Heading
+	_ = ast.Walk(document, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := node.(*ast.Heading)
+		if !ok || !entering {
+			return ast.WalkContinue, nil
+		}
+
+		headings = append(headings, Heading{
+			Level: heading.Level,
+			Text:  strings.TrimSpace(plainText(heading, source)),
+		})
+		nodes = append(nodes, heading)
+		return ast.WalkSkipChildren, nil
+	})
+
+	return document, headings, nodes
+}
+
+// plainText concatenates the text of a node, dropping inline markup
+func plainText(node ast.Node, source []byte) string {
+	var b strings.Builder
+	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+		switch n := child.(type) {
+		case *ast.Text:
+			b.Write(n.Segment.Value(source))
+			if n.SoftLineBreak() || n.HardLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			b.Write(n.Value)
+		default:
+			b.WriteString(plainText(child, source))
+		}
+	}
+	return b.String()
+}
+
+// uniqueSlug derives an anchor from heading text, suffixing -1, -2, ... when
+// it is already taken
+func uniqueSlug(heading string, used map[string]bool) string {
+	slug := strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(heading), "-"), "-")
+	if slug == "" {
+		slug = "section"
+	}
+
+	anchor := slug
+	for i := 1; used[anchor]; i++ {
+		anchor = fmt.Sprintf("%s-%d", slug, i)
+	}
+	return anchor
+}
@@ -0,0 +1,195 @@
+// Package redact finds personal data and banned terms in document content,
+// either rejecting it or replacing the matches.
+package redact
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hafiztri123/document-api/internal/document/hook"
+)
+
+// Name is the content hook name of the filter
+const Name = "redact"
+
+// Replacement stands in for redacted text
+const Replacement = "[redacted]"
+
+// Mode is what the filter does with matches when content is saved
+type Mode string
+
+const (
+	// ModeBlock rejects the save with the matches as violations
+	ModeBlock Mode = "block"
+	// ModeRedact saves the content with the matches replaced
+	ModeRedact Mode = "redact"
+)
+
+// Detectors that may be listed in Rules.Detect
+const (
+	DetectEmail      = "email"
+	DetectCreditCard = "credit_card"
+)
+
+// Rules are configured under redaction.rules
+type Rules struct {
+	// Mode defaults to ModeBlock
+	Mode Mode `mapstructure:"mode"`
+	// Detect lists the personal data detectors to run
+	Detect []string `mapstructure:"detect"`
+	// BannedTerms are matched as whole words, case-insensitively
+	BannedTerms []string `mapstructure:"banned_terms"`
+}
+
+type detector struct {
+	describe func(match string) string
+	pattern  *regexp.Regexp
+	// valid filters out false positives the pattern cannot rule out
+	valid func(match string) bool
+}
+
+var (
+	emailPattern      = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`)
+	creditCardPattern = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+)
+
+// Filter detects and redacts content according to its rules
+type Filter struct {
+	mode      Mode
+	detectors []detector
+}
+
+// NewFilter compiles rules. It fails on unknown detectors or modes.
+func NewFilter(rules Rules) (*Filter, error) {
+	filter := &Filter{mode: rules.Mode}
+	switch rules.Mode {
+	case "":
+		filter.mode = ModeBlock
+	case ModeBlock, ModeRedact:
+	default:
+		return nil, fmt.Errorf("unknown redaction mode %q", rules.Mode)
+	}
+
+	for _, name := range rules.Detect {
+		switch name {
+		case DetectEmail:
+			filter.detectors = append(filter.detectors, detector{
+				describe: func(string) string { return "Email address" },
+				pattern:  emailPattern,
+			})
+		case DetectCreditCard:
+			filter.detectors = append(filter.detectors, detector{
+				describe: func(string) string { return "Credit card number" },
+				pattern:  creditCardPattern,
+				valid:    luhn,
+			})
+		default:
+			return nil, fmt.Errorf("unknown redaction detector %q", name)
+		}
+	}
+
+	if len(rules.BannedTerms) > 0 {
+		terms := make([]string, 0, len(rules.BannedTerms))
+		for _, term := range rules.BannedTerms {
+			if term = strings.TrimSpace(term); term != "" {
+				terms = append(terms, wholeWord(term))
+			}
+		}
+		if len(terms) > 0 {
+			filter.detectors = append(filter.detectors, detector{
+				describe: func(match string) string { return fmt.Sprintf("Banned term %q", match) },
+				pattern:  regexp.MustCompile(`(?i)` + strings.Join(terms, "|")),
+			})
+		}
+	}
+
+	return filter, nil
+}
+
+// Scan reports every match in content. Personal data is described, not
+// repeated, so reports can be logged and returned safely.
+func (f *Filter) Scan(content string) []hook.Violation {
+	violations := []hook.Violation{}
+	for _, d := range f.detectors {
+		for _, loc := range d.pattern.FindAllStringIndex(content, -1) {
+			match := content[loc[0]:loc[1]]
+			if d.valid != nil && !d.valid(match) {
+				continue
+			}
+			violations = append(violations, hook.Violation{
+				Message: d.describe(match),
+				Line:    strings.Count(content[:loc[0]], "\n") + 1,
+			})
+		}
+	}
+	return violations
+}
+
+// Redact replaces every match in content with Replacement
+func (f *Filter) Redact(content string) string {
+	for _, d := range f.detectors {
+		content = d.pattern.ReplaceAllStringFunc(content, func(match string) string {
+			if d.valid != nil && !d.valid(match) {
+				return match
+			}
+			return Replacement
+		})
+	}
+	return content
+}
+
+func (f *Filter) Name() string {
+	return Name
+}
+
+// Apply blocks or redacts the save depending on the mode
+func (f *Filter) Apply(ctx context.Context, save *hook.Save) error {
+	if f.mode == ModeRedact {
+		save.Content = f.Redact(save.Content)
+		return nil
+	}
+
+	if violations := f.Scan(save.Content); len(violations) > 0 {
+		return &hook.Rejection{Violations: violations}
+	}
+	return nil
+}
+
+var wordChar = regexp.MustCompile(`^\w$`)
+
+// wholeWord matches term only where it is not part of a longer word. Word
+// boundaries are only required next to word characters, so terms such as
+// "c++" still match.
+func wholeWord(term string) string {
+	pattern := regexp.QuoteMeta(term)
+	if wordChar.MatchString(term[:1]) {
+		pattern = `\b` + pattern
+	}
+	if wordChar.MatchString(term[len(term)-1:]) {
+		pattern += `\b`
+	}
+	return pattern
+}
+
+// luhn reports whether the digits in number pass the Luhn checksum
+func luhn(number string) bool {
+	sum, double := 0, false
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		digit := int(c - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%10 == 0
+}
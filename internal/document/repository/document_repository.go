@@ -4,50 +4,210 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/hafiztri123/document-api/config"
+	analyticsModel "github.com/hafiztri123/document-api/internal/analytics/model"
 	"github.com/hafiztri123/document-api/internal/document/model"
+	"github.com/hafiztri123/document-api/internal/logging"
+	"github.com/spf13/viper"
 	"go.uber.org/zap"
+	"golang.org/x/text/language"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 
 type Repository interface {
 	CreateDocument(ctx context.Context, document *model.Document) error
 	GetDocumentByID(ctx context.Context, id uuid.UUID) (*model.Document, error)
-	GetDocumentsByUserID(ctx context.Context, userID uuid.UUID, page, perPage int, sortBy string, sortDir string, query string) ([]*model.Document, int64, error)
+	GetDocumentByAlias(ctx context.Context, alias string) (*model.Document, error)
+	GetDocumentByShortCode(ctx context.Context, code string) (*model.Document, error)
+	// ShortCodeExists reports whether any document uses code, including
+	// documents in the trash
+	ShortCodeExists(ctx context.Context, code string) (bool, error)
+	GetDocumentBySourcePath(ctx context.Context, ownerID uuid.UUID, path string) (*model.Document, error)
+	GetDocumentsByUserID(ctx context.Context, userID uuid.UUID, page, perPage int, sortBy string, sortDir string, filter model.DocumentFilter) ([]*model.Document, int64, error)
 	UpdateDocument(ctx context.Context, document *model.Document) error
+	// UpdateDocumentIfVersion saves document only while the stored version
+	// is still version, reporting whether it did
+	UpdateDocumentIfVersion(ctx context.Context, document *model.Document, version int) (bool, error)
+	// SaveContentUpdate saves the document, adds the history version and
+	// records the edit in one transaction. It reports false, having written
+	// nothing, when IfVersion is set and no longer the stored version.
+	SaveContentUpdate(ctx context.Context, update *model.ContentUpdate) (bool, error)
+	// DeleteDocument moves a document to the trash, with its history,
+	// collaborators, views and edits
 	DeleteDocument(ctx context.Context, id uuid.UUID) error
+	// GetDeletedDocument returns a document in the trash, nil when the
+	// document does not exist or is not deleted
+	GetDeletedDocument(ctx context.Context, id uuid.UUID) (*model.Document, error)
+	// GetDeletedDocuments lists the trash of ownerID, documents they co-own
+	// included, most recently deleted first
+	GetDeletedDocuments(ctx context.Context, ownerID uuid.UUID) ([]*model.TrashedDocument, error)
+	// RestoreDocument takes a document out of the trash, with the rows
+	// deleted along with it, and saves its alias and source, which the
+	// caller may have cleared
+	RestoreDocument(ctx context.Context, document *model.Document) error
+	SetDocumentFolder(ctx context.Context, id uuid.UUID, folderID *uuid.UUID) error
+	SetDocumentReview(ctx context.Context, id uuid.UUID, reviewBy *time.Time) error
+	SetDocumentState(ctx context.Context, id uuid.UUID, state model.State, archivedAt *time.Time) error
+	SetDocumentAlias(ctx context.Context, id uuid.UUID, alias *string, redirect *model.AliasRedirect) error
+	SetDocumentRevision(ctx context.Context, id uuid.UUID, revision *string) error
+	GetAliasRedirect(ctx context.Context, alias string) (*model.AliasRedirect, error)
+	MarkStaleDocuments(ctx context.Context, now time.Time) ([]*model.Document, error)
+	SetDocumentExpiry(ctx context.Context, id uuid.UUID, expiresAt *time.Time, action model.ExpiryAction) error
+	SetDocumentAnalyticsPermission(ctx context.Context, id uuid.UUID, permission model.Permission) error
+	MarkExpiringDocuments(ctx context.Context, now, noticeBefore time.Time) ([]*model.Document, error)
+	ClaimExpiredDocuments(ctx context.Context, now time.Time) ([]*model.Document, error)
+	
+	// ReplaceDocumentLinks sets the documents source links to; targets that
+	// do not exist are dropped
+	ReplaceDocumentLinks(ctx context.Context, sourceID uuid.UUID, targetIDs []uuid.UUID) error
+	// GetBacklinks returns the documents linking to targetID that userID can read
+	GetBacklinks(ctx context.Context, targetID, userID uuid.UUID) ([]*model.Document, error)
+	// GetForks returns the documents forked from sourceID, newest first;
+	// unless all, only those userID can read
+	GetForks(ctx context.Context, sourceID, userID uuid.UUID, all bool) ([]*model.Document, error)
+	
+	// PinDocument is idempotent, as is UnpinDocument
+	PinDocument(ctx context.Context, userID, documentID uuid.UUID) error
+	UnpinDocument(ctx context.Context, userID, documentID uuid.UUID) error
+	
+	SetDocumentTerms(ctx context.Context, id uuid.UUID, terms *string, termsHash *string) error
+	// AcceptTerms keeps the first acceptance of each version of the terms
+	AcceptTerms(ctx context.Context, acceptance *model.DocumentTermsAcceptance) error
+	GetTermsAcceptance(ctx context.Context, documentID, userID uuid.UUID, termsHash string) (*model.DocumentTermsAcceptance, error)
+	// GetTermsAcceptances lists every acceptance of the document's terms,
+	// newest first, with the users who accepted
+	GetTermsAcceptances(ctx context.Context, documentID uuid.UUID) ([]*model.DocumentTermsAcceptance, error)
+	
+	// AddSectionSubscription is idempotent, as is RemoveSectionSubscription
+	AddSectionSubscription(ctx context.Context, subscription *model.SectionSubscription) error
+	RemoveSectionSubscription(ctx context.Context, documentID, userID uuid.UUID, anchor string) error
+	// GetSectionSubscriptions lists a document's subscriptions; a nil
+	// userID lists everyone's
+	GetSectionSubscriptions(ctx context.Context, documentID uuid.UUID, userID *uuid.UUID) ([]*model.SectionSubscription, error)
+	
+	CreateSnapshot(ctx context.Context, snapshot *model.DocumentSnapshot) error
+	GetSnapshotByID(ctx context.Context, id uuid.UUID) (*model.DocumentSnapshot, error)
+	GetSnapshotByVersion(ctx context.Context, documentID uuid.UUID, version int) (*model.DocumentSnapshot, error)
+	// GetSnapshots lists a document's snapshots, newest version first,
+	// without their content
+	GetSnapshots(ctx context.Context, documentID uuid.UUID) ([]*model.DocumentSnapshot, error)
+	DeleteSnapshot(ctx context.Context, id uuid.UUID) error
+	
+	// GetDocumentAuthors returns the owner and everyone who saved a
+	// version, the owner first and the others by their first version,
+	// without roles
+	GetDocumentAuthors(ctx context.Context, documentID, ownerID uuid.UUID) ([]model.PublishedAuthor, error)
+	
+	// SaveDocumentKey creates or replaces a member's key exchange
+	SaveDocumentKey(ctx context.Context, key *model.DocumentKey) error
+	GetDocumentKey(ctx context.Context, documentID, userID uuid.UUID) (*model.DocumentKey, error)
+	GetDocumentKeys(ctx context.Context, documentID uuid.UUID) ([]*model.DocumentKey, error)
+	DeleteDocumentKey(ctx context.Context, documentID, userID uuid.UUID) error
+	
+	GetDocumentStorage(ctx context.Context, id uuid.UUID) (*model.DocumentStorage, error)
+	// GetUserStorage totals the storage of ownerID's documents and lists
+	// the largest
+	GetUserStorage(ctx context.Context, ownerID uuid.UUID, largest int) (*model.StorageUsage, error)
 	
 	CreateDocumentHistory(ctx context.Context, history *model.DocumentHistory) error
-	GetDocumentHistory(ctx context.Context, documentID uuid.UUID, page, perPage int) ([]*model.DocumentHistory, int64, error)
+	GetDocumentHistory(ctx context.Context, documentID uuid.UUID, filter model.HistoryFilter, page, perPage int) ([]*model.DocumentHistory, int64, error)
+	// GetDocumentHistoryBefore returns up to limit versions below before,
+	// newest first, or the newest versions when before is nil. It seeks
+	// along (document_id, version) rather than skipping rows, so deep pages
+	// cost as little as the first, and counts nothing.
+	GetDocumentHistoryBefore(ctx context.Context, documentID uuid.UUID, filter model.HistoryFilter, before *int, limit int) ([]*model.DocumentHistory, error)
 	GetDocumentHistoryByVersion(ctx context.Context, documentID uuid.UUID, version int) (*model.DocumentHistory, error)
+	// GetDocumentHistoryContents returns every recorded version, oldest
+	// first, without the editor
+	GetDocumentHistoryContents(ctx context.Context, documentID uuid.UUID) ([]*model.DocumentHistory, error)
+	// GetAllDocumentHistory returns every recorded version in full, oldest
+	// first, with the editor
+	GetAllDocumentHistory(ctx context.Context, documentID uuid.UUID) ([]*model.DocumentHistory, error)
+	// GetDocumentHistoryEntries lists every recorded version, oldest first,
+	// with content sizes instead of content
+	GetDocumentHistoryEntries(ctx context.Context, documentID uuid.UUID) ([]*model.HistoryEntry, error)
+	DeleteDocumentHistoryVersions(ctx context.Context, documentID uuid.UUID, versions []int) error
+	// SetDocumentHistoryLabel labels a version, or with a nil label removes
+	// its label, reporting whether the version exists
+	SetDocumentHistoryLabel(ctx context.Context, documentID uuid.UUID, version int, label *string) (bool, error)
+	GetDocumentHistoryByLabel(ctx context.Context, documentID uuid.UUID, label string) (*model.DocumentHistory, error)
+	// GetLabeledVersions lists the labeled versions, newest first
+	GetLabeledVersions(ctx context.Context, documentID uuid.UUID) ([]*model.LabeledVersion, error)
+	// AddReaction stores the reaction unless the user already left that
+	// emoji, reporting false when the version does not exist
+	AddReaction(ctx context.Context, reaction *model.Reaction) (bool, error)
+	// RemoveReaction reports whether there was a reaction to remove
+	RemoveReaction(ctx context.Context, documentID uuid.UUID, version int, userID uuid.UUID, emoji string) (bool, error)
+	// GetReactionCounts counts the reactions on each of versions by emoji,
+	// in the order each emoji was first used, marking those of userID
+	GetReactionCounts(ctx context.Context, documentID uuid.UUID, versions []int, userID uuid.UUID) ([]*model.ReactionCount, error)
+	// CollapseDocumentHistory moves the latest history entry of the document
+	// to history's version, content, time and any change summary, provided
+	// the same user wrote it at or after since and it is not a merge or
+	// labeled. It reports whether it did.
+	CollapseDocumentHistory(ctx context.Context, history *model.DocumentHistory, since time.Time) (bool, error)
+	// GetDocumentHistoryAsOf returns the latest history entry at or before
+	// version; metadata-only updates bump the version without recording history
+	GetDocumentHistoryAsOf(ctx context.Context, documentID uuid.UUID, version int) (*model.DocumentHistory, error)
 	
 	AddCollaborator(ctx context.Context, collaborator *model.Collaborator) error
 	UpdateCollaborator(ctx context.Context, collaborator *model.Collaborator) error
 	RemoveCollaborator(ctx context.Context, documentID, userID uuid.UUID) error
 	GetCollaborators(ctx context.Context, documentID uuid.UUID) ([]*model.Collaborator, error)
 	GetCollaborator(ctx context.Context, documentID, userID uuid.UUID) (*model.Collaborator, error)
+	// IsDocumentOwner reports whether userID owns the document or co-owns
+	// it as a collaborator with the owner role, whether or not it is in the
+	// trash
+	IsDocumentOwner(ctx context.Context, documentID, userID uuid.UUID) (bool, error)
+	// TransferDocumentOwnership makes to the owner of the document in place
+	// of from, who stays on as a co-owner. The document leaves from's folder
+	// and repository sync, which belong to from's workspace.
+	TransferDocumentOwnership(ctx context.Context, documentID, from, to uuid.UUID) error
 	
 	CanUserAccess(ctx context.Context, documentID, userID uuid.UUID, requiredPermission model.Permission) (bool, error)
+	// GetEffectivePermissions returns userID's permission on each of
+	// documentIDs that exists, in one query. Collaborator roles are
+	// returned as stored, legacy values included.
+	GetEffectivePermissions(ctx context.Context, documentIDs []uuid.UUID, userID uuid.UUID) ([]*model.EffectivePermission, error)
+	// GetRecentAccess returns up to limit users who viewed or edited a
+	// document not in the trash since since, most recently active first
+	GetRecentAccess(ctx context.Context, since time.Time, limit int) ([]*model.RecentAccess, error)
 }
 
 type documentRepository struct {
 	db 		*gorm.DB
 	logger 	*zap.Logger
+	// collations caches titleCollation by language
+	collations sync.Map
+	// snapshotInterval is how often a history version keeps its full
+	// content, see model.DocumentHistory.StoredAfter
+	snapshotInterval int
 }
 
 func NewDocumentRepository(db *gorm.DB, logger *zap.Logger) Repository {
+	snapshotInterval := viper.GetInt(config.DOCUMENTS_HISTORY_SNAPSHOTS)
+	if snapshotInterval < 1 {
+		snapshotInterval = model.DefaultHistorySnapshotInterval
+	}
+
 	return &documentRepository{
 		db: db,
 		logger: logger,
+		snapshotInterval: snapshotInterval,
 	}
 }
 
 func (r *documentRepository) CreateDocument(ctx context.Context, document *model.Document) error {
 	err := r.db.WithContext(ctx).Create(document).Error
 	if err != nil {
-		r.logger.Error("Failed to create document", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to create document", zap.Error(err))
 		return err
 	}
 	return nil
@@ -62,31 +222,110 @@ func (r *documentRepository)	GetDocumentByID(ctx context.Context, id uuid.UUID)
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
-		r.logger.Error("Failed to get document by ID", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to get document by ID", zap.Error(err))
+		return nil, err
+	}
+	return &document, nil
+}
+
+func (r *documentRepository)	GetDocumentByAlias(ctx context.Context, alias string) (*model.Document, error){
+	var document model.Document
+	err := r.db.WithContext(ctx).Preload("Collaborators.User").Where("alias = ?", alias).First(&document).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get document by alias", zap.Error(err))
+		return nil, err
+	}
+	return &document, nil
+}
+
+func (r *documentRepository)	GetDocumentByShortCode(ctx context.Context, code string) (*model.Document, error){
+	var document model.Document
+	err := r.db.WithContext(ctx).Preload("Collaborators.User").Where("short_code = ?", code).First(&document).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get document by short code", zap.Error(err))
 		return nil, err
 	}
 	return &document, nil
 }
 
-func (r *documentRepository)	GetDocumentsByUserID(ctx context.Context, userID uuid.UUID, page, perPage int, sortBy string, sortDir string, query string) ([]*model.Document, int64, error){
+func (r *documentRepository)	ShortCodeExists(ctx context.Context, code string) (bool, error){
+	var count int64
+	err := r.db.WithContext(ctx).Unscoped().Model(&model.Document{}).Where("short_code = ?", code).Count(&count).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to check short code", zap.Error(err))
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *documentRepository)	GetDocumentBySourcePath(ctx context.Context, ownerID uuid.UUID, path string) (*model.Document, error){
+	var document model.Document
+	err := r.db.WithContext(ctx).Where("owner_id = ? AND source_path = ?", ownerID, path).First(&document).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get document by source path", zap.Error(err))
+		return nil, err
+	}
+	return &document, nil
+}
+
+func (r *documentRepository)	GetDocumentsByUserID(ctx context.Context, userID uuid.UUID, page, perPage int, sortBy string, sortDir string, filter model.DocumentFilter) ([]*model.Document, int64, error){
 	var documents []*model.Document
 	var total int64
 
 	db := r.db.WithContext(ctx).Model(&model.Document{})
 
-	db = db.Where("owner_id = ?", userID).
-		Or(
-			"id IN (?)", 
-			r.db.Model(&model.Collaborator{}).
-			Select("document_id").
-			Where("user_id = ?", userID))
+	// Grouped so the filters below apply to owned and shared documents alike
+	db = db.Where(
+		r.db.Where("owner_id = ?", userID).
+			Or(
+				"id IN (?)", 
+				r.db.Model(&model.Collaborator{}).
+				Select("document_id").
+				Where("user_id = ?", userID)))
 	
-	if query != "" {
-		db = db.Where("title ILIKE ? OR content ILIKE ?", "%"+query+"%", "%"+query+"%") //search with case insensitive
+	if filter.Query != "" {
+		// Encrypted content is ciphertext, so only their titles are searched
+		db = db.Where("title ILIKE ? OR (NOT encrypted AND content ILIKE ?)", "%"+filter.Query+"%", "%"+filter.Query+"%") //search with case insensitive
+	}
+
+	if filter.FolderID != nil {
+		if *filter.FolderID == model.RootFolder {
+			db = db.Where("folder_id IS NULL")
+		} else {
+			db = db.Where("folder_id = ?", *filter.FolderID)
+		}
+	}
+
+	if !filter.IncludeArchived && (filter.State == nil || *filter.State != model.StateArchived) {
+		db = db.Where("archived_at IS NULL")
+	}
+
+	if filter.State != nil {
+		db = db.Where("state = ?", *filter.State)
+	}
+
+	if filter.Stale != nil {
+		if *filter.Stale {
+			db = db.Where("stale_at IS NOT NULL")
+		} else {
+			db = db.Where("stale_at IS NULL")
+		}
 	}
 
 	if err := db.Count(&total).Error;  err != nil{
-		r.logger.Error("Failed to count documents", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to count documents", zap.Error(err))
 		return nil, 0, err
 	}
 
@@ -100,6 +339,13 @@ func (r *documentRepository)	GetDocumentsByUserID(ctx context.Context, userID uu
 
 	order := fmt.Sprintf("%s %s", sortBy, sortDir)
 
+	// Titles sort by the rules of a language rather than by code point
+	if sortBy == "title" {
+		if collation := r.titleCollation(ctx, filter.SortLocale); collation != "" {
+			order = fmt.Sprintf(`title COLLATE "%s" %s`, collation, sortDir)
+		}
+	}
+
 	if page < 1 {
 		page  = 1
 	}
@@ -126,199 +372,1522 @@ func (r *documentRepository)	GetDocumentsByUserID(ctx context.Context, userID uu
 
 	offset := (page - 1) * perPage
 
-	if err := db.Order(order).
+	// Pinned documents come first whatever the requested sort: those pinned
+	// to the folder listed in their order, or to the user's workspace when
+	// no folder is, then the user's own pins
+	pinned := "EXISTS (SELECT 1 FROM document_pins WHERE document_pins.document_id = documents.id AND document_pins.user_id = ?) AS pinned"
+	position := "(SELECT position FROM folder_pins WHERE folder_pins.document_id = documents.id AND folder_pins.owner_id = ? AND folder_pins.folder_id IS NULL) AS pin_position"
+	scope := userID
+	if filter.FolderID != nil && *filter.FolderID != model.RootFolder {
+		position = "(SELECT position FROM folder_pins WHERE folder_pins.document_id = documents.id AND folder_pins.folder_id = ?) AS pin_position"
+		scope = *filter.FolderID
+	}
+
+	if err := db.Select("documents.*, "+pinned+", "+position, userID, scope).
+		Order("pin_position ASC NULLS LAST").
+		Order("pinned DESC").
+		Order(order).
 		Limit(perPage).
 		Offset(offset).
 		Preload("Collaborators").
 		Find(&documents).Error; err != nil {
-		r.logger.Error("Failed to get documents by User ID", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to get documents by User ID", zap.Error(err))
 		return nil, 0, err
 	}
 
 	return documents, total, nil
 
 }
+// titleCollation returns the ICU collation of the language of locale,
+// falling back to the root collation, or "" when the database was built
+// without ICU
+func (r *documentRepository) titleCollation(ctx context.Context, locale string) string {
+	base := "und"
+	if locale != "" {
+		tag, _ := language.Make(locale).Base()
+		base = tag.String()
+	}
+
+	if collation, ok := r.collations.Load(base); ok {
+		return collation.(string)
+	}
+
+	candidates := []string{base + "-x-icu", "und-x-icu"}
+	var available []string
+	err := r.db.WithContext(ctx).Raw("SELECT collname FROM pg_collation WHERE collname IN ?", candidates).
+		Scan(&available).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to look up title collation", zap.Error(err))
+		return ""
+	}
+
+	collation := ""
+	for _, candidate := range candidates {
+		if slices.Contains(available, candidate) {
+			collation = candidate
+			break
+		}
+	}
+	r.collations.Store(base, collation)
+	return collation
+}
+
 func (r *documentRepository)	UpdateDocument(ctx context.Context, document *model.Document) error{
 	err := r.db.WithContext(ctx).Save(document).Error
 	if err != nil {
-		r.logger.Error("Failed to update document", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to update document", zap.Error(err))
 		return err
 	}
 	return nil
 }
-func (r *documentRepository)	DeleteDocument(ctx context.Context, id uuid.UUID) error{
-	err := r.db.WithContext(ctx).Delete(&model.Document{}, id).Error 
+
+func (r *documentRepository)	UpdateDocumentIfVersion(ctx context.Context, document *model.Document, version int) (bool, error){
+	updated, err := r.updateIfVersion(r.db.WithContext(ctx), document, version)
 	if err != nil {
-		r.logger.Error("Failed to delete document", zap.Error(err))
-		return err
+		logging.FromContext(ctx, r.logger).Error("Failed to update document", zap.Error(err))
+		return false, err
 	}
-	return nil
-
+	return updated, nil
 }
-func (r *documentRepository)	CreateDocumentHistory(ctx context.Context, history *model.DocumentHistory) error{
-	if err := r.db.Create(history).Error; err != nil {
-		r.logger.Error("Failed to create document history", zap.Error(err))
-		return err
+
+func (r *documentRepository) updateIfVersion(db *gorm.DB, document *model.Document, version int) (bool, error) {
+	result := db.Model(document).
+		Where("version = ?", version).
+		Select("*").
+		Omit(clause.Associations).
+		Updates(document)
+	if result.Error != nil {
+		return false, result.Error
 	}
+	return result.RowsAffected > 0, nil
+}
 
-	return nil
+func (r *documentRepository)	SaveContentUpdate(ctx context.Context, update *model.ContentUpdate) (bool, error){
+	saved := false
 
-}
-func (r *documentRepository)	GetDocumentHistory(ctx context.Context, documentID uuid.UUID, page, perPage int) ([]*model.DocumentHistory, int64, error){
-	var historyDocuments []*model.DocumentHistory
-	var total int64
-	
-	err := r.db.WithContext(ctx).
-		Model(&model.DocumentHistory{}).
-		Where("document_id = ?", documentID).
-		Count(&total).Error
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if update.IfVersion == nil {
+			if err := tx.Save(update.Document).Error; err != nil {
+				return err
+			}
+		} else {
+			updated, err := r.updateIfVersion(tx, update.Document, *update.IfVersion)
+			if err != nil || !updated {
+				return err
+			}
+		}
 
-	if err != nil {
-		r.logger.Error("Failed to count document history", zap.Error(err))
-		return nil, 0, err
-	}
+		history := update.History
+		history.Version = update.Document.Version
 
-	if page < 1 {
-		page = 1
-	}
+		collapsed := false
+		if update.CollapseSince != nil {
+			var err error
+			if collapsed, err = r.collapseHistory(tx, history, *update.CollapseSince); err != nil {
+				return err
+			}
+		}
+		if !collapsed {
+			if err := r.createHistory(tx, history); err != nil {
+				return err
+			}
+		}
 
-	if perPage < 1 {
-		perPage = 20
+		edit := analyticsModel.DocumentEdit{
+			DocumentID: update.Document.ID,
+			UserID: history.UpdatedByID,
+			Version: update.Document.Version,
+			EditedAt: history.UpdatedAt,
+		}
+		if err := tx.Create(&edit).Error; err != nil {
+			return err
+		}
+
+		saved = true
+		return nil
+	})
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to save document update", zap.Error(err))
+		return false, err
 	}
 
-	offset := (page - 1) * perPage
+	return saved, nil
+}
 
-	err = r.db.WithContext(ctx).
-		Where("document_id = ?", documentID).
-		Order("version DESC").
-		Limit(perPage).
-		Offset(offset).
-		Preload("UpdatedBy").
-		Find(&historyDocuments).
-		Error
-	
-	if err != nil{
-		r.logger.Error("Failed to get document history", zap.Error(err))
-		return nil, 0, err
+// trashedTables hold rows that go to the trash with their document. They
+// are stamped with the document's deleted_at, so a restore brings back
+// exactly the rows deleted along with it.
+var trashedTables = []string{"document_histories", "collaborators", "document_views", "document_edits"}
+
+func (r *documentRepository)	DeleteDocument(ctx context.Context, id uuid.UUID) error{
+	now := time.Now()
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.Document{}).Where("id = ?", id).UpdateColumn("deleted_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		for _, table := range trashedTables {
+			if err := tx.Table(table).Where("document_id = ? AND deleted_at IS NULL", id).UpdateColumn("deleted_at", now).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to delete document", zap.Error(err))
+		return err
 	}
+	return nil
 
-	return historyDocuments, total, nil
 }
-func (r *documentRepository)	GetDocumentHistoryByVersion(ctx context.Context, documentID uuid.UUID, version int) (*model.DocumentHistory, error){
-	var history model.DocumentHistory
 
-	err := r.db.WithContext(ctx).Where("document_id = ? AND version = ?", documentID, version).Preload("UpdatedBy").First(&history).Error
-	
+func (r *documentRepository)	GetDeletedDocument(ctx context.Context, id uuid.UUID) (*model.Document, error){
+	var document model.Document
+	err := r.db.WithContext(ctx).Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&document).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
-		r.logger.Error("Failed to get document history by version", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to get deleted document", zap.Error(err))
 		return nil, err
 	}
+	return &document, nil
+}
 
-	return &history, nil
+func (r *documentRepository)	GetDeletedDocuments(ctx context.Context, ownerID uuid.UUID) ([]*model.TrashedDocument, error){
+	documents := []*model.TrashedDocument{}
+	err := r.db.WithContext(ctx).Unscoped().
+		Model(&model.Document{}).
+		Select("id, title, folder_id, version, updated_at, deleted_at").
+		Where("deleted_at IS NOT NULL").
+		// Collaborators go to the trash stamped with their document's
+		// deleted_at
+		Where("owner_id = ? OR id IN (?)", ownerID,
+			r.db.Unscoped().Model(&model.Collaborator{}).
+				Select("document_id").
+				Where("user_id = ? AND permission = ? AND deleted_at = documents.deleted_at", ownerID, model.PermissionOwner)).
+		Order("deleted_at DESC, id").
+		Scan(&documents).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get deleted documents", zap.Error(err))
+		return nil, err
+	}
+	return documents, nil
 }
-func (r *documentRepository)	AddCollaborator(ctx context.Context, collaborator *model.Collaborator) error{
-	err := r.db.WithContext(ctx).Create(collaborator).Error
+
+func (r *documentRepository)	RestoreDocument(ctx context.Context, document *model.Document) error{
+	deletedAt := document.DeletedAt.Time
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, table := range trashedTables {
+			if err := tx.Table(table).Where("document_id = ? AND deleted_at = ?", document.ID, deletedAt).UpdateColumn("deleted_at", nil).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Model(&model.Document{}).Where("id = ?", document.ID).UpdateColumns(map[string]interface{}{
+			"deleted_at":      nil,
+			"alias":           document.Alias,
+			"source_path":     document.SourcePath,
+			"source_revision": document.SourceRevision,
+		}).Error
+	})
 	if err != nil {
-		r.logger.Error("Failed to add collaborator", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to restore document", zap.Error(err))
 		return err
 	}
+	document.DeletedAt = gorm.DeletedAt{}
 	return nil
 }
-func (r *documentRepository)	UpdateCollaborator(ctx context.Context, collaborator *model.Collaborator) error{
-	err := r.db.WithContext(ctx).Save(collaborator).Error
+// SetDocumentFolder files a document without touching its version; a nil
+// folderID moves it out of any folder. The document is unpinned from the
+// folder it leaves; its workspace pin stays.
+func (r *documentRepository)	SetDocumentFolder(ctx context.Context, id uuid.UUID, folderID *uuid.UUID) error{
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Document{}).
+			Where("id = ?", id).
+			UpdateColumn("folder_id", folderID).Error; err != nil {
+			return err
+		}
+
+		return tx.Exec("DELETE FROM folder_pins WHERE document_id = ? AND folder_id IS NOT NULL AND folder_id IS DISTINCT FROM ?", id, folderID).Error
+	})
 	if err != nil {
-		r.logger.Error("Failed to update collaborator", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to set document folder", zap.Error(err))
 		return err
 	}
 	return nil
 }
-func (r *documentRepository)	RemoveCollaborator(ctx context.Context, documentID, userID uuid.UUID) error{
-	err := r.db.WithContext(ctx).Where("document_id = ? AND user_id = ?", documentID, userID).Delete(&model.Collaborator{}).Error
+// SetDocumentAlias changes a document's alias without touching its version.
+// Claiming an alias retires any redirect from it; redirect, when set,
+// records the alias being replaced.
+func (r *documentRepository)	SetDocumentAlias(ctx context.Context, id uuid.UUID, alias *string, redirect *model.AliasRedirect) error{
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if alias != nil {
+			if err := tx.Where("alias = ?", *alias).Delete(&model.AliasRedirect{}).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&model.Document{}).Where("id = ?", id).UpdateColumn("alias", alias).Error; err != nil {
+			return err
+		}
+
+		if redirect != nil {
+			return tx.Save(redirect).Error
+		}
+		return nil
+	})
 	if err != nil {
-		r.logger.Error("Failed to remove collaborator", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to set document alias", zap.Error(err))
 		return err
 	}
-
 	return nil
-
 }
-func (r *documentRepository)	GetCollaborators(ctx context.Context, documentID uuid.UUID) ([]*model.Collaborator, error){
-	var collaborators []*model.Collaborator
 
-	err := r.db.WithContext(ctx).Where("document_id = ?", documentID).Preload("User").Find(&collaborators).Error
+func (r *documentRepository)	GetAliasRedirect(ctx context.Context, alias string) (*model.AliasRedirect, error){
+	var redirect model.AliasRedirect
+	err := r.db.WithContext(ctx).Where("alias = ?", alias).First(&redirect).Error
 	if err != nil {
-		r.logger.Error("Failed to get collaborators", zap.Error(err))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get alias redirect", zap.Error(err))
 		return nil, err
 	}
+	return &redirect, nil
+}
 
-	return collaborators, nil
+// SetDocumentRevision records the last synced commit without touching the
+// document's version
+func (r *documentRepository)	SetDocumentRevision(ctx context.Context, id uuid.UUID, revision *string) error{
+	err := r.db.WithContext(ctx).Model(&model.Document{}).
+		Where("id = ?", id).
+		UpdateColumn("source_revision", revision).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to set document revision", zap.Error(err))
+		return err
+	}
+	return nil
+}
 
+// SetDocumentReview reschedules a document's review and clears its stale
+// flag, without touching its version
+func (r *documentRepository)	SetDocumentReview(ctx context.Context, id uuid.UUID, reviewBy *time.Time) error{
+	err := r.db.WithContext(ctx).Model(&model.Document{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{
+			"review_by": reviewBy,
+			"stale_at":  nil,
+		}).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to set document review date", zap.Error(err))
+		return err
+	}
+	return nil
 }
-func (r *documentRepository)	GetCollaborator(ctx context.Context, documentID, userID uuid.UUID) (*model.Collaborator, error){
-	var collaborator model.Collaborator
+// SetDocumentState moves a document to a lifecycle state without touching
+// its version; archivedAt must be set exactly when state is StateArchived
+func (r *documentRepository)	SetDocumentState(ctx context.Context, id uuid.UUID, state model.State, archivedAt *time.Time) error{
+	err := r.db.WithContext(ctx).Model(&model.Document{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{
+			"state":       state,
+			"archived_at": archivedAt,
+		}).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to set document state", zap.Error(err))
+		return err
+	}
+	return nil
+}
+// MarkStaleDocuments flags every document whose review date has passed and
+// returns the newly flagged ones. The update is a single statement, so when
+// several instances scan at once each document is returned only once.
+func (r *documentRepository)	MarkStaleDocuments(ctx context.Context, now time.Time) ([]*model.Document, error){
+	var documents []*model.Document
 
-	err := r.db.WithContext(ctx).Where("document_id = ? AND user_id = ?", documentID, userID).Preload("User").First(&collaborator).Error
+	err := r.db.WithContext(ctx).Model(&documents).
+		Clauses(clause.Returning{}).
+		Where("review_by <= ? AND stale_at IS NULL AND archived_at IS NULL", now).
+		UpdateColumn("stale_at", now).Error
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, nil
-		}
-		r.logger.Error("Failed to get collaborator", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to mark stale documents", zap.Error(err))
 		return nil, err
 	}
-
-	return &collaborator, nil
+	return documents, nil
 }
-
-func (r *documentRepository) CanUserAccess(ctx context.Context, documentID, userID uuid.UUID, requiredPermission model.Permission) (bool, error) {
-	var count int64
-	err := r.db.WithContext(ctx).Model(&model.Document{}).Where("id = ? AND owner_id = ?", documentID, userID).Count(&count).Error
+// SetDocumentExpiry reschedules a document's expiry and clears its notice,
+// without touching its version
+func (r *documentRepository)	SetDocumentExpiry(ctx context.Context, id uuid.UUID, expiresAt *time.Time, action model.ExpiryAction) error{
+	err := r.db.WithContext(ctx).Model(&model.Document{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{
+			"expires_at":         expiresAt,
+			"expiry_action":      action,
+			"expiry_notified_at": nil,
+		}).Error
 	if err != nil {
-		r.logger.Error("Failed to check document ownership", zap.Error(err))
-		return false,err
+		logging.FromContext(ctx, r.logger).Error("Failed to set document expiry", zap.Error(err))
+		return err
+	}
+	return nil
+}
+// SetDocumentAnalyticsPermission does not touch the document's version
+func (r *documentRepository)	SetDocumentAnalyticsPermission(ctx context.Context, id uuid.UUID, permission model.Permission) error{
+	err := r.db.WithContext(ctx).Model(&model.Document{}).
+		Where("id = ?", id).
+		UpdateColumn("analytics_permission", permission).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to set document analytics permission", zap.Error(err))
+		return err
 	}
+	return nil
+}
+// MarkExpiringDocuments records the notice for every document expiring
+// before noticeBefore and returns the ones noticed now, once each like
+// MarkStaleDocuments
+func (r *documentRepository)	MarkExpiringDocuments(ctx context.Context, now, noticeBefore time.Time) ([]*model.Document, error){
+	var documents []*model.Document
 
-	//if document is owned by user then return true
-	if count > 0 {
-		return true, nil
+	err := r.db.WithContext(ctx).Model(&documents).
+		Clauses(clause.Returning{}).
+		Where("expires_at > ? AND expires_at <= ? AND expiry_notified_at IS NULL", now, noticeBefore).
+		UpdateColumn("expiry_notified_at", now).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to mark expiring documents", zap.Error(err))
+		return nil, err
 	}
+	return documents, nil
+}
+// ClaimExpiredDocuments clears the expiry of every document past it and
+// returns them as they were, so each is handled by exactly one scan
+func (r *documentRepository)	ClaimExpiredDocuments(ctx context.Context, now time.Time) ([]*model.Document, error){
+	var documents []*model.Document
 
-	/*
-	if document is public and has permission for read only, then everyone can access it
-	*/
+	err := r.db.WithContext(ctx).Raw(`
+		UPDATE documents SET expires_at = NULL, expiry_notified_at = NULL
+		FROM (
+			SELECT id, expires_at FROM documents
+			WHERE expires_at <= ? AND deleted_at IS NULL
+			FOR UPDATE SKIP LOCKED
+		) expired
+		WHERE documents.id = expired.id
+		RETURNING documents.*, expired.expires_at`, now).
+		Scan(&documents).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to claim expired documents", zap.Error(err))
+		return nil, err
+	}
+	return documents, nil
+}
+func (r *documentRepository)	ReplaceDocumentLinks(ctx context.Context, sourceID uuid.UUID, targetIDs []uuid.UUID) error{
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("source_id = ?", sourceID).Delete(&model.DocumentLink{}).Error; err != nil {
+			return err
+		}
+		if len(targetIDs) == 0 {
+			return nil
+		}
 
-	if requiredPermission == model.PermissionRead {
-		var isPublic bool
-		err := r.db.WithContext(ctx).Model(&model.Document{}).Select("is_public").Where("id = ?", documentID).First(&isPublic).Error
-		if err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return false, nil
-			}
-			r.logger.Error("Failed to check if document is public", zap.Error(err))
-			return false, err
+		var existing []uuid.UUID
+		if err := tx.Model(&model.Document{}).Where("id IN ?", targetIDs).Pluck("id", &existing).Error; err != nil {
+			return err
+		}
+		if len(existing) == 0 {
+			return nil
 		}
 
-		if isPublic {
-			return true, nil
+		links := make([]*model.DocumentLink, 0, len(existing))
+		for _, targetID := range existing {
+			links = append(links, &model.DocumentLink{
+				SourceID:  sourceID,
+				TargetID:  targetID,
+				CreatedAt: time.Now(),
+			})
 		}
+		return tx.Create(&links).Error
+	})
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to replace document links", zap.Error(err))
+		return err
 	}
+	return nil
+}
+func (r *documentRepository)	GetBacklinks(ctx context.Context, targetID, userID uuid.UUID) ([]*model.Document, error){
+	var documents []*model.Document
+
+	err := r.db.WithContext(ctx).
+		Select("id", "title", "state", "updated_at").
+		Where("id IN (?)", r.db.Model(&model.DocumentLink{}).Select("source_id").Where("target_id = ?", targetID)).
+		Where(
+			r.db.Where("owner_id = ? OR is_public", userID).
+				Or("id IN (?)", r.db.Model(&model.Collaborator{}).Select("document_id").Where("user_id = ?", userID))).
+		Order("updated_at DESC").
+		Find(&documents).Error
 
-	//if user is collaborator, then even if its not public, they have the required permission
-	var collaborator model.Collaborator
-	err = r.db.WithContext(ctx).Where("document_id = ? AND user_id = ?", documentID, userID).First(&collaborator).Error
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return false, nil
-		}
-		r.logger.Error("Failed to check collaborator permissions", zap.Error(err))
-		return false, err
+		logging.FromContext(ctx, r.logger).Error("Failed to get backlinks", zap.Error(err))
+		return nil, err
 	}
 
-	if requiredPermission == model.PermissionRead {
-		return true, nil
+	return documents, nil
+}
+func (r *documentRepository)	GetForks(ctx context.Context, sourceID, userID uuid.UUID, all bool) ([]*model.Document, error){
+	var documents []*model.Document
+
+	db := r.db.WithContext(ctx).
+		Select("id", "title", "owner_id", "forked_from_version", "version", "state", "created_at", "updated_at").
+		Where("forked_from_id = ?", sourceID)
+	if !all {
+		db = db.Where(
+			r.db.Where("owner_id = ? OR is_public", userID).
+				Or("id IN (?)", r.db.Model(&model.Collaborator{}).Select("document_id").Where("user_id = ?", userID)))
+	}
+
+	if err := db.Order("created_at DESC").Find(&documents).Error; err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get forks", zap.Error(err))
+		return nil, err
+	}
+
+	return documents, nil
+}
+func (r *documentRepository)	PinDocument(ctx context.Context, userID, documentID uuid.UUID) error{
+	pin := &model.DocumentPin{
+		UserID:     userID,
+		DocumentID: documentID,
+		CreatedAt:  time.Now(),
+	}
+
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(pin).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to pin document", zap.Error(err))
+		return err
+	}
+	return nil
+}
+func (r *documentRepository)	UnpinDocument(ctx context.Context, userID, documentID uuid.UUID) error{
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND document_id = ?", userID, documentID).
+		Delete(&model.DocumentPin{}).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to unpin document", zap.Error(err))
+		return err
+	}
+	return nil
+}
+func (r *documentRepository)	SetDocumentTerms(ctx context.Context, id uuid.UUID, terms *string, termsHash *string) error{
+	err := r.db.WithContext(ctx).Model(&model.Document{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{
+			"terms":      terms,
+			"terms_hash": termsHash,
+		}).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to set document terms", zap.Error(err))
+		return err
+	}
+	return nil
+}
+func (r *documentRepository)	AcceptTerms(ctx context.Context, acceptance *model.DocumentTermsAcceptance) error{
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(acceptance).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to record terms acceptance", zap.Error(err))
+		return err
+	}
+	return nil
+}
+func (r *documentRepository)	GetTermsAcceptance(ctx context.Context, documentID, userID uuid.UUID, termsHash string) (*model.DocumentTermsAcceptance, error){
+	var acceptance model.DocumentTermsAcceptance
+	err := r.db.WithContext(ctx).
+		Where("document_id = ? AND user_id = ? AND terms_hash = ?", documentID, userID, termsHash).
+		First(&acceptance).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get terms acceptance", zap.Error(err))
+		return nil, err
+	}
+	return &acceptance, nil
+}
+func (r *documentRepository)	GetTermsAcceptances(ctx context.Context, documentID uuid.UUID) ([]*model.DocumentTermsAcceptance, error){
+	var acceptances []*model.DocumentTermsAcceptance
+	err := r.db.WithContext(ctx).
+		Where("document_id = ?", documentID).
+		Preload("User").
+		Order("accepted_at DESC").
+		Find(&acceptances).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get terms acceptances", zap.Error(err))
+		return nil, err
+	}
+	return acceptances, nil
+}
+func (r *documentRepository)	AddSectionSubscription(ctx context.Context, subscription *model.SectionSubscription) error{
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(subscription).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to add section subscription", zap.Error(err))
+		return err
+	}
+	return nil
+}
+func (r *documentRepository)	RemoveSectionSubscription(ctx context.Context, documentID, userID uuid.UUID, anchor string) error{
+	err := r.db.WithContext(ctx).
+		Where("document_id = ? AND user_id = ? AND anchor = ?", documentID, userID, anchor).
+		Delete(&model.SectionSubscription{}).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to remove section subscription", zap.Error(err))
+		return err
+	}
+	return nil
+}
+func (r *documentRepository)	GetSectionSubscriptions(ctx context.Context, documentID uuid.UUID, userID *uuid.UUID) ([]*model.SectionSubscription, error){
+	var subscriptions []*model.SectionSubscription
+
+	db := r.db.WithContext(ctx).Where("document_id = ?", documentID)
+	if userID != nil {
+		db = db.Where("user_id = ?", *userID)
+	}
+
+	if err := db.Order("created_at ASC").Find(&subscriptions).Error; err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get section subscriptions", zap.Error(err))
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+func (r *documentRepository)	CreateSnapshot(ctx context.Context, snapshot *model.DocumentSnapshot) error{
+	if err := r.db.WithContext(ctx).Create(snapshot).Error; err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to create snapshot", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r *documentRepository)	GetSnapshotByID(ctx context.Context, id uuid.UUID) (*model.DocumentSnapshot, error){
+	var snapshot model.DocumentSnapshot
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&snapshot).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get snapshot by ID", zap.Error(err))
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+func (r *documentRepository)	GetSnapshotByVersion(ctx context.Context, documentID uuid.UUID, version int) (*model.DocumentSnapshot, error){
+	var snapshot model.DocumentSnapshot
+	err := r.db.WithContext(ctx).Where("document_id = ? AND version = ?", documentID, version).First(&snapshot).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get snapshot by version", zap.Error(err))
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+func (r *documentRepository)	GetSnapshots(ctx context.Context, documentID uuid.UUID) ([]*model.DocumentSnapshot, error){
+	var snapshots []*model.DocumentSnapshot
+	err := r.db.WithContext(ctx).
+		Omit("content", "html").
+		Where("document_id = ?", documentID).
+		Order("version DESC").
+		Find(&snapshots).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get snapshots", zap.Error(err))
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+func (r *documentRepository)	DeleteSnapshot(ctx context.Context, id uuid.UUID) error{
+	if err := r.db.WithContext(ctx).Where("id = ?", id).Delete(&model.DocumentSnapshot{}).Error; err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to delete snapshot", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r *documentRepository)	GetDocumentAuthors(ctx context.Context, documentID, ownerID uuid.UUID) ([]model.PublishedAuthor, error){
+	var authors []model.PublishedAuthor
+	err := r.db.WithContext(ctx).
+		Table("users").
+		Select("users.id, users.name").
+		Joins("LEFT JOIN document_histories h ON h.updated_by_id = users.id AND h.document_id = ?", documentID).
+		Where("users.id = ? OR h.id IS NOT NULL", ownerID).
+		Group("users.id, users.name").
+		Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:                "users.id = ? DESC, MIN(h.updated_at)",
+			Vars:               []interface{}{ownerID},
+			WithoutParentheses: true,
+		}}).
+		Scan(&authors).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get document authors", zap.Error(err))
+		return nil, err
+	}
+	return authors, nil
+}
+
+func (r *documentRepository)	SaveDocumentKey(ctx context.Context, key *model.DocumentKey) error{
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "document_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"algorithm", "public_key", "wrapped_key", "wrapped_by_id", "updated_at"}),
+	}).Create(key).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to save document key", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r *documentRepository)	GetDocumentKey(ctx context.Context, documentID, userID uuid.UUID) (*model.DocumentKey, error){
+	var key model.DocumentKey
+	err := r.db.WithContext(ctx).Where("document_id = ? AND user_id = ?", documentID, userID).First(&key).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get document key", zap.Error(err))
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *documentRepository)	GetDocumentKeys(ctx context.Context, documentID uuid.UUID) ([]*model.DocumentKey, error){
+	var keys []*model.DocumentKey
+	if err := r.db.WithContext(ctx).Where("document_id = ?", documentID).Order("created_at ASC").Find(&keys).Error; err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get document keys", zap.Error(err))
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *documentRepository)	DeleteDocumentKey(ctx context.Context, documentID, userID uuid.UUID) error{
+	err := r.db.WithContext(ctx).
+		Where("document_id = ? AND user_id = ?", documentID, userID).
+		Delete(&model.DocumentKey{}).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to delete document key", zap.Error(err))
+		return err
+	}
+	return nil
+}
+// storageQuery selects the storage of each document, see model.DocumentStorage
+func (r *documentRepository) storageQuery(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).
+		Model(&model.Document{}).
+		Select(`documents.id AS document_id, documents.title,
+			COALESCE(octet_length(documents.content), 0) AS content_bytes,
+			h.bytes AS history_bytes,
+			h.versions AS history_versions,
+			COALESCE(octet_length(documents.content), 0) + h.bytes AS total_bytes`).
+		Joins(`CROSS JOIN LATERAL (
+			SELECT COALESCE(SUM(octet_length(content)), 0) + COALESCE(SUM(octet_length(delta)), 0) AS bytes, COUNT(*) AS versions
+			FROM document_histories
+			WHERE document_id = documents.id
+		) h`)
+}
+func (r *documentRepository)	GetDocumentStorage(ctx context.Context, id uuid.UUID) (*model.DocumentStorage, error){
+	var storage []*model.DocumentStorage
+
+	err := r.storageQuery(ctx).Where("documents.id = ?", id).Scan(&storage).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get document storage", zap.Error(err))
+		return nil, err
+	}
+
+	if len(storage) == 0 {
+		return nil, nil
+	}
+	return storage[0], nil
+}
+func (r *documentRepository)	GetUserStorage(ctx context.Context, ownerID uuid.UUID, largest int) (*model.StorageUsage, error){
+	var usage model.StorageUsage
+
+	perDocument := r.storageQuery(ctx).Where("documents.owner_id = ?", ownerID)
+
+	err := r.db.WithContext(ctx).
+		Table("(?) AS storage", perDocument).
+		Select(`COUNT(*) AS documents,
+			COALESCE(SUM(content_bytes), 0) AS content_bytes,
+			COALESCE(SUM(history_bytes), 0) AS history_bytes,
+			COALESCE(SUM(history_versions), 0) AS history_versions,
+			COALESCE(SUM(total_bytes), 0) AS total_bytes`).
+		Scan(&usage).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to total user storage", zap.Error(err))
+		return nil, err
+	}
+
+	usage.Largest = []*model.DocumentStorage{}
+	err = r.storageQuery(ctx).
+		Where("documents.owner_id = ?", ownerID).
+		Order("total_bytes DESC").
+		Limit(largest).
+		Scan(&usage.Largest).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get largest documents", zap.Error(err))
+		return nil, err
+	}
+
+	return &usage, nil
+}
+// historyChain returns the versions from the last snapshot at or before
+// from up to to, with their content rebuilt
+func (r *documentRepository) historyChain(db *gorm.DB, documentID uuid.UUID, from, to int) ([]*model.DocumentHistory, error) {
+	var chain []*model.DocumentHistory
+
+	snapshot := db.Model(&model.DocumentHistory{}).
+		Select("COALESCE(MAX(version), 0)").
+		Where("document_id = ? AND version <= ? AND delta IS NULL", documentID, from)
+
+	err := db.
+		Where("document_id = ? AND version >= (?) AND version <= ?", documentID, snapshot, to).
+		Order("version ASC").
+		Find(&chain).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if err := model.RebuildHistory(chain); err != nil {
+		return nil, err
+	}
+	return chain, nil
+}
+// rebuildHistory fills in the content of those versions that are stored as
+// deltas
+func (r *documentRepository) rebuildHistory(db *gorm.DB, documentID uuid.UUID, versions ...*model.DocumentHistory) error {
+	from, to := 0, 0
+	for _, version := range versions {
+		if version.IsSnapshot() {
+			continue
+		}
+		if from == 0 || version.Version < from {
+			from = version.Version
+		}
+		if version.Version > to {
+			to = version.Version
+		}
+	}
+	if to == 0 {
+		return nil
+	}
+
+	chain, err := r.historyChain(db, documentID, from, to)
+	if err != nil {
+		return err
+	}
+
+	contents := make(map[int]string, len(chain))
+	for _, version := range chain {
+		contents[version.Version] = version.Content
+	}
+	for _, version := range versions {
+		if version.IsSnapshot() {
+			continue
+		}
+		content, ok := contents[version.Version]
+		if !ok {
+			return model.ErrBrokenHistory
+		}
+		version.Content = content
+	}
+	return nil
+}
+// previousVersion returns the version recorded before version, with its
+// content rebuilt, and how many versions up to it are stored as deltas since
+// the last snapshot
+func (r *documentRepository) previousVersion(db *gorm.DB, documentID uuid.UUID, version int) (*model.DocumentHistory, int, error) {
+	chain, err := r.historyChain(db, documentID, version-1, version-1)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(chain) == 0 {
+		return nil, 0, nil
+	}
+	return chain[len(chain)-1], len(chain) - 1, nil
+}
+// lockDocumentHistory holds the document row until tx ends. History versions
+// are stored against each other, so those of one document are written one
+// at a time.
+func (r *documentRepository) lockDocumentHistory(tx *gorm.DB, documentID uuid.UUID) error {
+	return tx.Exec("SELECT 1 FROM documents WHERE id = ? FOR UPDATE", documentID).Error
+}
+// CreateDocumentHistory stores the version as a delta from the one before
+// it where that is smaller. A version recorded after a later one also
+// re-encodes that later version against it.
+func (r *documentRepository)	CreateDocumentHistory(ctx context.Context, history *model.DocumentHistory) error{
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return r.createHistory(tx, history)
+	})
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to create document history", zap.Error(err))
+		return err
+	}
+
+	return nil
+
+}
+// createHistory is CreateDocumentHistory within tx
+func (r *documentRepository) createHistory(tx *gorm.DB, history *model.DocumentHistory) error {
+	if err := r.lockDocumentHistory(tx, history.DocumentID); err != nil {
+		return err
+	}
+
+	var next model.DocumentHistory
+	hasNext := true
+	err := tx.Where("document_id = ? AND version > ?", history.DocumentID, history.Version).Order("version ASC").First(&next).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		hasNext = false
+	} else if err != nil {
+		return err
+	}
+	if hasNext {
+		if err := r.rebuildHistory(tx, history.DocumentID, &next); err != nil {
+			return err
+		}
+	}
+
+	previous, deltas, err := r.previousVersion(tx, history.DocumentID, history.Version)
+	if err != nil {
+		return err
+	}
+
+	stored := history.StoredAfter(previous, deltas, r.snapshotInterval)
+	if err := tx.Create(stored).Error; err != nil {
+		return err
+	}
+	history.ID = stored.ID
+
+	if !hasNext || next.IsSnapshot() {
+		return nil
+	}
+
+	if stored.IsSnapshot() {
+		deltas = 0
+	} else {
+		deltas++
+	}
+	storedNext := next.StoredAfter(history, deltas, r.snapshotInterval)
+	return tx.Model(&model.DocumentHistory{}).Where("id = ?", next.ID).UpdateColumns(map[string]interface{}{
+		"content": storedNext.Content,
+		"delta":   storedNext.Delta,
+	}).Error
+}
+func (r *documentRepository)	GetDocumentHistory(ctx context.Context, documentID uuid.UUID, filter model.HistoryFilter, page, perPage int) ([]*model.DocumentHistory, int64, error){
+	var historyDocuments []*model.DocumentHistory
+	var total int64
+	
+	filtered := historyFilter(documentID, filter)
+
+	err := r.db.WithContext(ctx).
+		Model(&model.DocumentHistory{}).
+		Scopes(filtered).
+		Count(&total).Error
+
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to count document history", zap.Error(err))
+		return nil, 0, err
+	}
+
+	if page < 1 {
+		page = 1
+	}
+
+	if perPage < 1 {
+		perPage = 20
+	}
+
+	offset := (page - 1) * perPage
+
+	err = r.db.WithContext(ctx).
+		Scopes(filtered).
+		Order("version DESC").
+		Limit(perPage).
+		Offset(offset).
+		Preload("UpdatedBy").
+		Find(&historyDocuments).
+		Error
+	
+	if err == nil {
+		err = r.rebuildHistory(r.db.WithContext(ctx), documentID, historyDocuments...)
+	}
+
+	if err != nil{
+		logging.FromContext(ctx, r.logger).Error("Failed to get document history", zap.Error(err))
+		return nil, 0, err
+	}
+
+	return historyDocuments, total, nil
+}
+func (r *documentRepository)	GetDocumentHistoryBefore(ctx context.Context, documentID uuid.UUID, filter model.HistoryFilter, before *int, limit int) ([]*model.DocumentHistory, error){
+	var historyDocuments []*model.DocumentHistory
+
+	db := r.db.WithContext(ctx).Scopes(historyFilter(documentID, filter))
+	if before != nil {
+		db = db.Where("version < ?", *before)
+	}
+
+	err := db.
+		Order("version DESC").
+		Limit(limit).
+		Preload("UpdatedBy").
+		Find(&historyDocuments).
+		Error
+
+	if err == nil {
+		err = r.rebuildHistory(r.db.WithContext(ctx), documentID, historyDocuments...)
+	}
+
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get document history", zap.Error(err))
+		return nil, err
+	}
+
+	return historyDocuments, nil
+}
+// historyFilter scopes a query to the document's versions matching filter
+func historyFilter(documentID uuid.UUID, filter model.HistoryFilter) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Where("document_id = ?", documentID)
+		if filter.UpdatedBy != nil {
+			db = db.Where("updated_by_id = ?", *filter.UpdatedBy)
+		}
+		if filter.From != nil {
+			db = db.Where("updated_at >= ?", *filter.From)
+		}
+		if filter.To != nil {
+			db = db.Where("updated_at < ?", *filter.To)
+		}
+		return db
+	}
+}
+func (r *documentRepository)	GetDocumentHistoryByVersion(ctx context.Context, documentID uuid.UUID, version int) (*model.DocumentHistory, error){
+	var history model.DocumentHistory
+
+	err := r.db.WithContext(ctx).Where("document_id = ? AND version = ?", documentID, version).Preload("UpdatedBy").First(&history).Error
+	if err == nil {
+		err = r.rebuildHistory(r.db.WithContext(ctx), documentID, &history)
+	}
+	
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get document history by version", zap.Error(err))
+		return nil, err
+	}
+
+	return &history, nil
+}
+func (r *documentRepository)	GetDocumentHistoryContents(ctx context.Context, documentID uuid.UUID) ([]*model.DocumentHistory, error){
+	var history []*model.DocumentHistory
+
+	err := r.db.WithContext(ctx).
+		Select("version", "content", "delta", "updated_at").
+		Where("document_id = ?", documentID).
+		Order("version ASC").
+		Find(&history).Error
+	if err == nil {
+		err = model.RebuildHistory(history)
+	}
+
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get document history contents", zap.Error(err))
+		return nil, err
+	}
+
+	return history, nil
+}
+func (r *documentRepository)	GetAllDocumentHistory(ctx context.Context, documentID uuid.UUID) ([]*model.DocumentHistory, error){
+	var history []*model.DocumentHistory
+
+	err := r.db.WithContext(ctx).
+		Where("document_id = ?", documentID).
+		Order("version ASC").
+		Preload("UpdatedBy").
+		Find(&history).Error
+	if err == nil {
+		err = model.RebuildHistory(history)
+	}
+
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get all document history", zap.Error(err))
+		return nil, err
+	}
+
+	return history, nil
+}
+func (r *documentRepository)	GetDocumentHistoryEntries(ctx context.Context, documentID uuid.UUID) ([]*model.HistoryEntry, error){
+	var entries []*model.HistoryEntry
+
+	err := r.db.WithContext(ctx).
+		Model(&model.DocumentHistory{}).
+		Select("version, updated_by_id, updated_at, COALESCE(octet_length(content), 0) + COALESCE(octet_length(delta), 0) AS bytes, label").
+		Where("document_id = ?", documentID).
+		Order("version ASC").
+		Scan(&entries).Error
+
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get document history entries", zap.Error(err))
+		return nil, err
+	}
+
+	return entries, nil
+}
+// DeleteDocumentHistoryVersions re-encodes the versions that are left, as
+// those stored as deltas may have been stored against a deleted one
+func (r *documentRepository)	DeleteDocumentHistoryVersions(ctx context.Context, documentID uuid.UUID, versions []int) error{
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := r.lockDocumentHistory(tx, documentID); err != nil {
+			return err
+		}
+
+		var history []*model.DocumentHistory
+		if err := tx.Where("document_id = ?", documentID).Order("version ASC").Find(&history).Error; err != nil {
+			return err
+		}
+		if err := model.RebuildHistory(history); err != nil {
+			return err
+		}
+
+		err := tx.Unscoped().
+			Where("document_id = ? AND version IN ?", documentID, versions).
+			Delete(&model.DocumentHistory{}).Error
+		if err != nil {
+			return err
+		}
+
+		kept := slices.DeleteFunc(history, func(h *model.DocumentHistory) bool {
+			return slices.Contains(versions, h.Version)
+		})
+		for i, stored := range model.EncodeHistory(kept, r.snapshotInterval) {
+			if stored.IsSnapshot() == kept[i].IsSnapshot() && (stored.IsSnapshot() || *stored.Delta == *kept[i].Delta) {
+				continue
+			}
+			err := tx.Model(&model.DocumentHistory{}).Where("id = ?", stored.ID).UpdateColumns(map[string]interface{}{
+				"content": stored.Content,
+				"delta":   stored.Delta,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to delete document history versions", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+func (r *documentRepository)	SetDocumentHistoryLabel(ctx context.Context, documentID uuid.UUID, version int, label *string) (bool, error){
+	result := r.db.WithContext(ctx).
+		Model(&model.DocumentHistory{}).
+		Where("document_id = ? AND version = ?", documentID, version).
+		UpdateColumn("label", label)
+
+	if result.Error != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to set document history label", zap.Error(result.Error))
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+func (r *documentRepository)	GetDocumentHistoryByLabel(ctx context.Context, documentID uuid.UUID, label string) (*model.DocumentHistory, error){
+	var history model.DocumentHistory
+
+	err := r.db.WithContext(ctx).Where("document_id = ? AND label = ?", documentID, label).First(&history).Error
+	if err == nil {
+		err = r.rebuildHistory(r.db.WithContext(ctx), documentID, &history)
+	}
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get document history by label", zap.Error(err))
+		return nil, err
+	}
+
+	return &history, nil
+}
+func (r *documentRepository)	GetLabeledVersions(ctx context.Context, documentID uuid.UUID) ([]*model.LabeledVersion, error){
+	var versions []*model.LabeledVersion
+
+	err := r.db.WithContext(ctx).
+		Model(&model.DocumentHistory{}).
+		Select("version, label, updated_by_id, updated_at").
+		Where("document_id = ? AND label IS NOT NULL", documentID).
+		Order("version DESC").
+		Scan(&versions).Error
+
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get labeled versions", zap.Error(err))
+		return nil, err
+	}
+
+	return versions, nil
+}
+func (r *documentRepository)	CollapseDocumentHistory(ctx context.Context, history *model.DocumentHistory, since time.Time) (bool, error){
+	collapsed := false
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		collapsed, err = r.collapseHistory(tx, history, since)
+		return err
+	})
+
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to collapse document history", zap.Error(err))
+		return false, err
+	}
+
+	return collapsed, nil
+}
+// collapseHistory is CollapseDocumentHistory within tx
+func (r *documentRepository) collapseHistory(tx *gorm.DB, history *model.DocumentHistory, since time.Time) (bool, error) {
+	if err := r.lockDocumentHistory(tx, history.DocumentID); err != nil {
+		return false, err
+	}
+
+	var latest model.DocumentHistory
+	err := tx.Select("id", "version", "updated_by_id", "updated_at", "merged_from_id", "label").
+		Where("document_id = ?", history.DocumentID).
+		Order("version DESC").
+		First(&latest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if latest.UpdatedByID != history.UpdatedByID || latest.UpdatedAt.Before(since) || latest.MergedFromID != nil || latest.Label != nil {
+		return false, nil
+	}
+
+	// The collapsed version takes the place of the latest one, so it is
+	// encoded against the version before that
+	previous, deltas, err := r.previousVersion(tx, history.DocumentID, latest.Version)
+	if err != nil {
+		return false, err
+	}
+	stored := history.StoredAfter(previous, deltas, r.snapshotInterval)
+
+	updates := map[string]interface{}{
+		"version":    stored.Version,
+		"content":    stored.Content,
+		"delta":      stored.Delta,
+		"updated_at": stored.UpdatedAt,
+	}
+	if stored.Summary != nil {
+		updates["summary"] = stored.Summary
+	}
+
+	result := tx.Model(&model.DocumentHistory{}).
+		Where("id = ?", latest.ID).
+		Updates(updates)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+func (r *documentRepository) AddReaction(ctx context.Context, reaction *model.Reaction) (bool, error) {
+	found := false
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err := tx.Model(&model.DocumentHistory{}).
+			Where("document_id = ? AND version = ?", reaction.DocumentID, reaction.Version).
+			Count(&count).Error
+		if err != nil || count == 0 {
+			return err
+		}
+		found = true
+
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(reaction).Error
+	})
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to add reaction", zap.Error(err))
+		return false, err
+	}
+
+	return found, nil
+}
+
+func (r *documentRepository) RemoveReaction(ctx context.Context, documentID uuid.UUID, version int, userID uuid.UUID, emoji string) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Where("document_id = ? AND version = ? AND user_id = ? AND emoji = ?", documentID, version, userID, emoji).
+		Delete(&model.Reaction{})
+	if result.Error != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to remove reaction", zap.Error(result.Error))
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+func (r *documentRepository) GetReactionCounts(ctx context.Context, documentID uuid.UUID, versions []int, userID uuid.UUID) ([]*model.ReactionCount, error) {
+	counts := []*model.ReactionCount{}
+	if len(versions) == 0 {
+		return counts, nil
+	}
+
+	err := r.db.WithContext(ctx).
+		Model(&model.Reaction{}).
+		Select("version, emoji, COUNT(*) AS count, BOOL_OR(user_id = ?) AS reacted", userID).
+		Where("document_id = ? AND version IN ?", documentID, versions).
+		Group("version, emoji").
+		Order("version, MIN(created_at), emoji").
+		Scan(&counts).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get reaction counts", zap.Error(err))
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+func (r *documentRepository)	GetDocumentHistoryAsOf(ctx context.Context, documentID uuid.UUID, version int) (*model.DocumentHistory, error){
+	var history model.DocumentHistory
+
+	err := r.db.WithContext(ctx).
+		Where("document_id = ? AND version <= ?", documentID, version).
+		Order("version DESC").
+		First(&history).Error
+	if err == nil {
+		err = r.rebuildHistory(r.db.WithContext(ctx), documentID, &history)
+	}
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get document history as of version", zap.Error(err))
+		return nil, err
+	}
+
+	return &history, nil
+}
+func (r *documentRepository)	AddCollaborator(ctx context.Context, collaborator *model.Collaborator) error{
+	err := r.db.WithContext(ctx).Create(collaborator).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to add collaborator", zap.Error(err))
+		return err
+	}
+	return nil
+}
+func (r *documentRepository)	UpdateCollaborator(ctx context.Context, collaborator *model.Collaborator) error{
+	err := r.db.WithContext(ctx).Save(collaborator).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to update collaborator", zap.Error(err))
+		return err
+	}
+	return nil
+}
+func (r *documentRepository)	RemoveCollaborator(ctx context.Context, documentID, userID uuid.UUID) error{
+	err := r.db.WithContext(ctx).Unscoped().Where("document_id = ? AND user_id = ?", documentID, userID).Delete(&model.Collaborator{}).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to remove collaborator", zap.Error(err))
+		return err
+	}
+
+	return nil
+
+}
+func (r *documentRepository)	IsDocumentOwner(ctx context.Context, documentID, userID uuid.UUID) (bool, error){
+	var owner bool
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT EXISTS (
+			SELECT 1 FROM documents
+			WHERE id = ? AND owner_id = ?
+		) OR EXISTS (
+			SELECT 1 FROM collaborators
+			JOIN documents ON documents.id = collaborators.document_id
+			WHERE collaborators.document_id = ? AND collaborators.user_id = ? AND collaborators.permission = ?
+				AND collaborators.deleted_at IS NOT DISTINCT FROM documents.deleted_at
+		)`, documentID, userID, documentID, userID, model.PermissionOwner).
+		Scan(&owner).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to check document ownership", zap.Error(err))
+		return false, err
+	}
+
+	return owner, nil
+}
+func (r *documentRepository)	TransferDocumentOwnership(ctx context.Context, documentID, from, to uuid.UUID) error{
+	now := time.Now()
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.Document{}).Where("id = ?", documentID).UpdateColumns(map[string]interface{}{
+			"owner_id":        to,
+			"folder_id":       nil,
+			"source_path":     nil,
+			"source_revision": nil,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Where("document_id = ? AND user_id = ?", documentID, to).Delete(&model.Collaborator{}).Error; err != nil {
+			return err
+		}
+
+		previous := &model.Collaborator{
+			DocumentID: documentID,
+			UserID: from,
+			Permission: model.PermissionOwner,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		return tx.Omit(clause.Associations).Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "document_id"}, {Name: "user_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"permission": model.PermissionOwner, "updated_at": now}),
+		}).Create(previous).Error
+	})
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to transfer document ownership", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+func (r *documentRepository)	GetCollaborators(ctx context.Context, documentID uuid.UUID) ([]*model.Collaborator, error){
+	var collaborators []*model.Collaborator
+
+	err := r.db.WithContext(ctx).Where("document_id = ?", documentID).Preload("User").Find(&collaborators).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get collaborators", zap.Error(err))
+		return nil, err
+	}
+
+	return collaborators, nil
+
+}
+func (r *documentRepository)	GetCollaborator(ctx context.Context, documentID, userID uuid.UUID) (*model.Collaborator, error){
+	var collaborator model.Collaborator
+
+	err := r.db.WithContext(ctx).Where("document_id = ? AND user_id = ?", documentID, userID).Preload("User").First(&collaborator).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get collaborator", zap.Error(err))
+		return nil, err
+	}
+
+	return &collaborator, nil
+}
+
+func (r *documentRepository) CanUserAccess(ctx context.Context, documentID, userID uuid.UUID, requiredPermission model.Permission) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.Document{}).Where("id = ? AND owner_id = ?", documentID, userID).Count(&count).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to check document ownership", zap.Error(err))
+		return false,err
+	}
+
+	//if document is owned by user then return true
+	if count > 0 {
+		return true, nil
+	}
+
+	/*
+	if document is public and has permission for read only, then everyone can access it
+	*/
+
+	if requiredPermission == model.PermissionRead {
+		var isPublic bool
+		err := r.db.WithContext(ctx).Model(&model.Document{}).Select("is_public").Where("id = ?", documentID).First(&isPublic).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return false, nil
+			}
+			logging.FromContext(ctx, r.logger).Error("Failed to check if document is public", zap.Error(err))
+			return false, err
+		}
+
+		if isPublic {
+			return true, nil
+		}
+	}
+
+	//if user is collaborator, then even if its not public, they have the required permission
+	var collaborator model.Collaborator
+	err = r.db.WithContext(ctx).Where("document_id = ? AND user_id = ?", documentID, userID).First(&collaborator).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to check collaborator permissions", zap.Error(err))
+		return false, err
+	}
+
+	return collaborator.Permission.Allows(requiredPermission), nil
+}
+
+func (r *documentRepository) GetEffectivePermissions(ctx context.Context, documentIDs []uuid.UUID, userID uuid.UUID) ([]*model.EffectivePermission, error) {
+	var permissions []*model.EffectivePermission
+	if len(documentIDs) == 0 {
+		return permissions, nil
+	}
+
+	err := r.db.WithContext(ctx).
+		Model(&model.Document{}).
+		Select(`documents.id AS document_id,
+			CASE
+				WHEN documents.owner_id = ? THEN ?
+				WHEN collaborators.permission IS NOT NULL THEN collaborators.permission
+				WHEN documents.is_public THEN ?
+				ELSE ?
+			END AS permission`, userID, model.PermissionOwner, model.PermissionViewer, model.PermissionNone).
+		Joins("LEFT JOIN collaborators ON collaborators.document_id = documents.id AND collaborators.user_id = ? AND collaborators.deleted_at IS NULL", userID).
+		Where("documents.id IN ?", documentIDs).
+		Scan(&permissions).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get effective permissions", zap.Error(err))
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+func (r *documentRepository) GetRecentAccess(ctx context.Context, since time.Time, limit int) ([]*model.RecentAccess, error) {
+	var accesses []*model.RecentAccess
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT a.document_id, a.user_id, d.is_public
+		FROM (
+			SELECT document_id, user_id, MAX(active_at) AS active_at
+			FROM (
+				SELECT document_id, user_id, viewed_at AS active_at
+				FROM document_views
+				WHERE viewed_at >= ? AND user_id IS NOT NULL AND deleted_at IS NULL
+				UNION ALL
+				SELECT document_id, user_id, edited_at
+				FROM document_edits
+				WHERE edited_at >= ? AND deleted_at IS NULL
+			) activity
+			GROUP BY document_id, user_id
+		) a
+		JOIN documents d ON d.id = a.document_id AND d.deleted_at IS NULL
+		ORDER BY a.active_at DESC
+		LIMIT ?`, since, since, limit).
+		Scan(&accesses).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get recent document access", zap.Error(err))
+		return nil, err
 	}
 
-	return collaborator.Permission == model.PermissionWrite, nil
+	return accesses, nil
 }
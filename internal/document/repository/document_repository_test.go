@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/hafiztri123/document-api/internal/database/dbtest"
+	"github.com/hafiztri123/document-api/internal/document/model"
+	userModel "github.com/hafiztri123/document-api/internal/user/model"
+)
+
+func newUser(t *testing.T, db *gorm.DB) *userModel.User {
+	t.Helper()
+
+	user := &userModel.User{
+		Email:    uuid.NewString() + "@example.com",
+		Name:     "Test User",
+		Password: "not-a-hash",
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+// newTrashableDocument creates a document with two rows in each of
+// trashedTables
+func newTrashableDocument(t *testing.T, db *gorm.DB) *model.Document {
+	t.Helper()
+
+	owner := newUser(t, db)
+	code, err := model.NewShortCode()
+	if err != nil {
+		t.Fatalf("short code: %v", err)
+	}
+	document := &model.Document{
+		Title:     "Handbook",
+		Content:   "hello\n",
+		OwnerID:   owner.ID,
+		ShortCode: code,
+	}
+	if err := db.Create(document).Error; err != nil {
+		t.Fatalf("create document: %v", err)
+	}
+
+	for version := 1; version <= 2; version++ {
+		collaborator := newUser(t, db)
+		rows := []struct {
+			query string
+			args  []interface{}
+		}{
+			{"INSERT INTO document_histories (document_id, version, content, updated_by_id, updated_at) VALUES (?, ?, ?, ?, NOW())",
+				[]interface{}{document.ID, version, document.Content, owner.ID}},
+			{"INSERT INTO collaborators (document_id, user_id, permission, created_at, updated_at) VALUES (?, ?, ?, NOW(), NOW())",
+				[]interface{}{document.ID, collaborator.ID, model.PermissionViewer}},
+			{"INSERT INTO document_views (document_id, user_id) VALUES (?, ?)",
+				[]interface{}{document.ID, collaborator.ID}},
+			{"INSERT INTO document_edits (document_id, user_id, version) VALUES (?, ?, ?)",
+				[]interface{}{document.ID, owner.ID, version}},
+		}
+		for _, row := range rows {
+			if err := db.Exec(row.query, row.args...).Error; err != nil {
+				t.Fatalf("insert: %v", err)
+			}
+		}
+	}
+
+	return document
+}
+
+// deletedAt returns the deleted_at of the document's rows in table, by ID
+func deletedAt(t *testing.T, db *gorm.DB, table string, documentID uuid.UUID) map[uuid.UUID]sql.NullTime {
+	t.Helper()
+
+	var rows []struct {
+		ID        uuid.UUID
+		DeletedAt sql.NullTime
+	}
+	if err := db.Table(table).Select("id, deleted_at").Where("document_id = ?", documentID).Scan(&rows).Error; err != nil {
+		t.Fatalf("read %s: %v", table, err)
+	}
+	if len(rows) == 0 {
+		t.Fatalf("%s has no rows for the document", table)
+	}
+
+	stamps := make(map[uuid.UUID]sql.NullTime, len(rows))
+	for _, row := range rows {
+		stamps[row.ID] = row.DeletedAt
+	}
+	return stamps
+}
+
+func documentDeletedAt(t *testing.T, db *gorm.DB, id uuid.UUID) sql.NullTime {
+	t.Helper()
+
+	var stamp sql.NullTime
+	if err := db.Table("documents").Select("deleted_at").Where("id = ?", id).Scan(&stamp).Error; err != nil {
+		t.Fatalf("read document: %v", err)
+	}
+	return stamp
+}
+
+func TestDeleteDocumentStampsTrashedRows(t *testing.T) {
+	db := dbtest.Open(t)
+	repo := NewDocumentRepository(db, zap.NewNop())
+	document := newTrashableDocument(t, db)
+
+	if err := repo.DeleteDocument(context.Background(), document.ID); err != nil {
+		t.Fatalf("DeleteDocument: %v", err)
+	}
+
+	stamp := documentDeletedAt(t, db, document.ID)
+	if !stamp.Valid {
+		t.Fatal("document is not deleted")
+	}
+	for _, table := range trashedTables {
+		for id, rowStamp := range deletedAt(t, db, table, document.ID) {
+			if !rowStamp.Valid || !rowStamp.Time.Equal(stamp.Time) {
+				t.Errorf("%s %s deleted_at = %v, want %v", table, id, rowStamp, stamp.Time)
+			}
+		}
+	}
+}
+
+func TestDeleteDocumentIsAtomic(t *testing.T) {
+	db := dbtest.Open(t)
+	repo := NewDocumentRepository(db, zap.NewNop())
+	document := newTrashableDocument(t, db)
+
+	// Fail on the last table, once the others have been stamped
+	err := db.Exec(`
+		CREATE FUNCTION fail_update() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'injected failure';
+		END
+		$$ LANGUAGE plpgsql;
+		CREATE TRIGGER fail_update BEFORE UPDATE ON ` + trashedTables[len(trashedTables)-1] + `
+			FOR EACH ROW EXECUTE FUNCTION fail_update();`).Error
+	if err != nil {
+		t.Fatalf("create trigger: %v", err)
+	}
+
+	if err := repo.DeleteDocument(context.Background(), document.ID); err == nil {
+		t.Fatal("DeleteDocument succeeded despite the failing table")
+	}
+
+	if documentDeletedAt(t, db, document.ID).Valid {
+		t.Error("document was deleted")
+	}
+	for _, table := range trashedTables {
+		for id, stamp := range deletedAt(t, db, table, document.ID) {
+			if stamp.Valid {
+				t.Errorf("%s %s kept deleted_at %v after the rollback", table, id, stamp.Time)
+			}
+		}
+	}
+}
+
+func TestRestoreDocumentKeepsEarlierDeletions(t *testing.T) {
+	db := dbtest.Open(t)
+	repo := NewDocumentRepository(db, zap.NewNop())
+	ctx := context.Background()
+	document := newTrashableDocument(t, db)
+
+	// One row of each table was deleted on its own before the document
+	earlier := time.Now().Add(-time.Hour).Truncate(time.Microsecond)
+	deletedEarlier := make(map[string]uuid.UUID, len(trashedTables))
+	for _, table := range trashedTables {
+		var id string
+		err := db.Raw(`UPDATE `+table+` SET deleted_at = ?
+			WHERE id = (SELECT id FROM `+table+` WHERE document_id = ? ORDER BY id LIMIT 1)
+			RETURNING id`, earlier, document.ID).Scan(&id).Error
+		if err != nil {
+			t.Fatalf("delete from %s: %v", table, err)
+		}
+		deletedEarlier[table] = uuid.MustParse(id)
+	}
+
+	if err := repo.DeleteDocument(ctx, document.ID); err != nil {
+		t.Fatalf("DeleteDocument: %v", err)
+	}
+
+	deleted, err := repo.GetDeletedDocument(ctx, document.ID)
+	if err != nil || deleted == nil {
+		t.Fatalf("GetDeletedDocument = %v, %v", deleted, err)
+	}
+	if err := repo.RestoreDocument(ctx, deleted); err != nil {
+		t.Fatalf("RestoreDocument: %v", err)
+	}
+
+	if stamp := documentDeletedAt(t, db, document.ID); stamp.Valid {
+		t.Errorf("document deleted_at = %v after restore", stamp.Time)
+	}
+	for _, table := range trashedTables {
+		for id, stamp := range deletedAt(t, db, table, document.ID) {
+			if id == deletedEarlier[table] {
+				if !stamp.Valid || !stamp.Time.Equal(earlier) {
+					t.Errorf("%s %s deleted_at = %v, want it kept at %v", table, id, stamp, earlier)
+				}
+			} else if stamp.Valid {
+				t.Errorf("%s %s deleted_at = %v, want it restored", table, id, stamp.Time)
+			}
+		}
+	}
+}
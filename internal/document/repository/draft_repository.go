@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hafiztri123/document-api/internal/document/model"
+	"github.com/hafiztri123/document-api/internal/logging"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// DraftRepository keeps per-user drafts in Redis, where they expire on their
+// own
+type DraftRepository interface {
+	SaveDraft(ctx context.Context, draft *model.DocumentDraft) error
+	GetDraft(ctx context.Context, documentID, userID uuid.UUID) (*model.DocumentDraft, error)
+	DeleteDraft(ctx context.Context, documentID, userID uuid.UUID) error
+}
+
+type draftRepository struct {
+	redis  *redis.Client
+	logger *zap.Logger
+}
+
+func NewDraftRepository(redis *redis.Client, logger *zap.Logger) DraftRepository {
+	return &draftRepository{
+		redis:  redis,
+		logger: logger,
+	}
+}
+
+func (r *draftRepository) SaveDraft(ctx context.Context, draft *model.DocumentDraft) error {
+	data, err := json.Marshal(draft)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to marshal document draft", zap.Error(err))
+		return err
+	}
+
+	err = r.redis.Set(ctx, draftKey(draft.DocumentID, draft.UserID), data, time.Until(draft.ExpiresAt)).Err()
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to save document draft", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *draftRepository) GetDraft(ctx context.Context, documentID, userID uuid.UUID) (*model.DocumentDraft, error) {
+	data, err := r.redis.Get(ctx, draftKey(documentID, userID)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get document draft", zap.Error(err))
+		return nil, err
+	}
+
+	var draft model.DocumentDraft
+	if err := json.Unmarshal(data, &draft); err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to unmarshal document draft", zap.Error(err))
+		return nil, err
+	}
+
+	return &draft, nil
+}
+
+func (r *draftRepository) DeleteDraft(ctx context.Context, documentID, userID uuid.UUID) error {
+	if err := r.redis.Del(ctx, draftKey(documentID, userID)).Err(); err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to delete document draft", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func draftKey(documentID, userID uuid.UUID) string {
+	return fmt.Sprintf("document_draft:%s:%s", documentID, userID)
+}
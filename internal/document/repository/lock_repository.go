@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hafiztri123/document-api/internal/document/model"
+	"github.com/hafiztri123/document-api/internal/logging"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// LockRepository keeps document locks in Redis, where they expire on their own
+type LockRepository interface {
+	// AcquireLock stores lock unless another user holds the document. It
+	// returns the lock in force afterwards: lock itself, or the other user's.
+	AcquireLock(ctx context.Context, lock *model.DocumentLock) (*model.DocumentLock, error)
+	GetLock(ctx context.Context, documentID uuid.UUID) (*model.DocumentLock, error)
+	// ReleaseLock removes the lock if holderID still holds it
+	ReleaseLock(ctx context.Context, documentID, holderID uuid.UUID) error
+}
+
+// acquireScript sets the lock unless a different user holds it, returning
+// the lock in force
+var acquireScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if current and cjson.decode(current).user_id ~= ARGV[2] then
+	return current
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[3])
+return ARGV[1]
+`)
+
+// releaseScript deletes the lock only if the expected user holds it
+var releaseScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if current and cjson.decode(current).user_id == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+type lockRepository struct {
+	redis  *redis.Client
+	logger *zap.Logger
+}
+
+func NewLockRepository(redis *redis.Client, logger *zap.Logger) LockRepository {
+	return &lockRepository{
+		redis:  redis,
+		logger: logger,
+	}
+}
+
+func (r *lockRepository) AcquireLock(ctx context.Context, lock *model.DocumentLock) (*model.DocumentLock, error) {
+	data, err := json.Marshal(lock)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to marshal document lock", zap.Error(err))
+		return nil, err
+	}
+
+	ttl := time.Until(lock.ExpiresAt).Milliseconds()
+	current, err := acquireScript.Run(ctx, r.redis, []string{lockKey(lock.DocumentID)},
+		data, lock.UserID.String(), ttl).Text()
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to acquire document lock", zap.Error(err))
+		return nil, err
+	}
+
+	var held model.DocumentLock
+	if err := json.Unmarshal([]byte(current), &held); err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to unmarshal document lock", zap.Error(err))
+		return nil, err
+	}
+
+	return &held, nil
+}
+
+func (r *lockRepository) GetLock(ctx context.Context, documentID uuid.UUID) (*model.DocumentLock, error) {
+	data, err := r.redis.Get(ctx, lockKey(documentID)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get document lock", zap.Error(err))
+		return nil, err
+	}
+
+	var lock model.DocumentLock
+	if err := json.Unmarshal(data, &lock); err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to unmarshal document lock", zap.Error(err))
+		return nil, err
+	}
+
+	return &lock, nil
+}
+
+func (r *lockRepository) ReleaseLock(ctx context.Context, documentID, holderID uuid.UUID) error {
+	err := releaseScript.Run(ctx, r.redis, []string{lockKey(documentID)}, holderID.String()).Err()
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to release document lock", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func lockKey(documentID uuid.UUID) string {
+	return fmt.Sprintf("document_lock:%s", documentID)
+}
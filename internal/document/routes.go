@@ -0,0 +1,166 @@
+package document
+
+import (
+	"github.com/hafiztri123/document-api/internal/api"
+	"github.com/hafiztri123/document-api/internal/document/controller"
+)
+
+type routes struct {
+	ctrl controller.Controller
+}
+
+func newRoutes(ctrl controller.Controller) *routes {
+	return &routes{ctrl: ctrl}
+}
+
+func (r *routes) RegisterRoutes(groups api.Groups) {
+	docs := groups.Protected.Group("/documents")
+	{
+		docs.POST("", r.ctrl.CreateDocument)
+		docs.GET("", r.ctrl.GetDocuments)
+		docs.POST("/bulk", r.ctrl.BulkDocuments)
+		docs.POST("/import", r.ctrl.ImportDocuments)
+		docs.GET("/:id", r.ctrl.GetDocumentByID)
+		docs.PUT("/:id", r.ctrl.UpdateDocument)
+		docs.DELETE("/:id", r.ctrl.DeleteDocument)
+
+		// Deleted documents stay in the owner's trash until restored
+		docs.GET("/trash", r.ctrl.GetTrash)
+		docs.POST("/:id/restore", r.ctrl.RestoreDocument)
+
+		// Documentation as code: CI upserts documents by a stable path key
+		docs.PUT("/by-path/*path", r.ctrl.SyncDocument)
+
+		// Lifecycle; archive and unarchive are shorthands for transitions
+		docs.POST("/:id/transition", r.ctrl.TransitionDocument)
+		docs.POST("/:id/archive", r.ctrl.ArchiveDocument)
+		docs.POST("/:id/unarchive", r.ctrl.UnarchiveDocument)
+
+		// Vanity aliases, resolved at /d/:alias
+		docs.PUT("/:id/alias", r.ctrl.SetDocumentAlias)
+		docs.DELETE("/:id/alias", r.ctrl.RemoveDocumentAlias)
+		// Short codes for references read aloud or typed by hand
+		docs.GET("/code/:code", r.ctrl.ResolveShortCode)
+
+		// Content rules, see lint.Rules
+		docs.POST("/:id/lint", r.ctrl.LintDocument)
+		// Find in document, with match positions and context
+		docs.GET("/:id/search", r.ctrl.SearchDocument)
+
+		// Table of contents, rendered content, exports, previews and statistics
+		docs.GET("/:id/outline", r.ctrl.GetDocumentOutline)
+		docs.GET("/:id/html", r.ctrl.RenderDocumentHTML)
+		docs.GET("/:id/export", r.ctrl.ExportDocument)
+		docs.GET("/:id/thumbnail", r.ctrl.GetDocumentThumbnail)
+		docs.GET("/:id/stats", r.ctrl.GetDocumentStats)
+		docs.GET("/:id/storage", r.ctrl.GetDocumentStorage)
+
+		// Checkout locks; other writers' saves fail with 423 while held
+		docs.GET("/:id/lock", r.ctrl.GetDocumentLock)
+		docs.POST("/:id/lock", r.ctrl.LockDocument)
+		docs.DELETE("/:id/lock", r.ctrl.UnlockDocument)
+		// Per-user drafts; GET /:id?include=draft returns the caller's
+		// draft with the document
+		docs.GET("/:id/draft", r.ctrl.GetDraft)
+		docs.PUT("/:id/draft", r.ctrl.SaveDraft)
+		docs.DELETE("/:id/draft", r.ctrl.DiscardDraft)
+		// Pins only affect the caller's own document list
+		docs.POST("/:id/pin", r.ctrl.PinDocument)
+		docs.DELETE("/:id/pin", r.ctrl.UnpinDocument)
+		// Terms other users must accept before reading the content
+		docs.GET("/:id/terms", r.ctrl.GetDocumentTerms)
+		docs.PUT("/:id/terms", r.ctrl.SetDocumentTerms)
+		docs.POST("/:id/terms/accept", r.ctrl.AcceptDocumentTerms)
+		docs.GET("/:id/terms/acceptances", r.ctrl.GetTermsAcceptances)
+		// Section subscriptions, keyed by heading anchor from the outline
+		docs.GET("/:id/subscriptions", r.ctrl.GetSectionSubscriptions)
+		docs.POST("/:id/subscriptions", r.ctrl.SubscribeToSection)
+		docs.DELETE("/:id/subscriptions/:anchor", r.ctrl.UnsubscribeFromSection)
+
+		// Published snapshots of one version, readable at /public/snapshots/:id
+		docs.GET("/:id/snapshots", r.ctrl.GetSnapshots)
+		docs.POST("/:id/snapshots", r.ctrl.PublishSnapshot)
+		docs.DELETE("/:id/snapshots/:snapshot_id", r.ctrl.DeleteSnapshot)
+
+		// Documents whose content links here, see the links package
+		docs.GET("/:id/backlinks", r.ctrl.GetBacklinks)
+		// Forks branch a document into the caller's workspace, e.g. to
+		// propose changes; owners of the source see every fork
+		docs.POST("/:id/fork", r.ctrl.ForkDocument)
+		docs.GET("/:id/forks", r.ctrl.GetForks)
+
+		// Content review; stale documents are listed with ?stale=true
+		docs.PUT("/:id/review", r.ctrl.SetReviewDate)
+		docs.POST("/:id/merge", r.ctrl.MergeDocument)
+		docs.PUT("/:id/expiry", r.ctrl.SetDocumentExpiry)
+
+		// Document history; ?updated_by=, ?from= and ?to= narrow it to one
+		// author and a time range, to being exclusive. ?cursor= pages by
+		// version, following next_cursor, instead of ?page=
+		docs.GET("/:id/history", r.ctrl.GetDocumentHistory)
+		// Every version with its metadata, ?format=ndjson|zip
+		docs.GET("/:id/history/export", r.ctrl.ExportDocumentHistory)
+		docs.POST("/:id/history/compact", r.ctrl.CompactDocumentHistory)
+		docs.POST("/:id/history/:version", r.ctrl.RestoreDocumentVersion)
+		// What restoring a version would save, without saving it;
+		// ?engine= as for compare
+		docs.GET("/:id/history/:version/preview", r.ctrl.PreviewDocumentVersionRestore)
+		// Named versions, restorable by label
+		docs.GET("/:id/history/labels", r.ctrl.GetLabeledVersions)
+		docs.POST("/:id/history/labels/:label/restore", r.ctrl.RestoreLabeledVersion)
+		docs.POST("/:id/history/:version/label", r.ctrl.LabelDocumentVersion)
+		docs.DELETE("/:id/history/:version/label", r.ctrl.RemoveDocumentVersionLabel)
+		// Emoji reactions on a version, for anyone who can read the document
+		docs.POST("/:id/history/:version/reactions", r.ctrl.AddVersionReaction)
+		docs.DELETE("/:id/history/:version/reactions/:emoji", r.ctrl.RemoveVersionReaction)
+		// ?engine=line|word|semantic picks the diff granularity, see
+		// diff.Engine; ?format=html renders the diff for direct display
+		docs.GET("/:id/compare", r.ctrl.CompareDocumentVersions)
+
+		// Collaboration
+		docs.POST("/:id/share", r.ctrl.ShareDocument)
+		docs.POST("/:id/share/bulk", r.ctrl.BulkShareDocument)
+		docs.PUT("/:id/share/:user_id", r.ctrl.UpdateCollaboratorPermission)
+		docs.DELETE("/:id/share/:user_id", r.ctrl.RemoveCollaborator)
+		// Owners, co-owners included, hand the document over; sharing with
+		// the owner role adds co-owners
+		docs.PUT("/:id/owner", r.ctrl.TransferOwnership)
+		// Key exchange for encrypted documents: members register a public
+		// key, the owner stores the document key wrapped to each
+		docs.GET("/:id/keys", r.ctrl.GetDocumentKeys)
+		docs.PUT("/:id/keys", r.ctrl.RegisterDocumentKey)
+		docs.PUT("/:id/keys/:user_id", r.ctrl.WrapDocumentKey)
+
+		// Analytics; collaborators need the document's analytics
+		// permission, admin unless the owner changes it
+		docs.GET("/:id/analytics", r.ctrl.GetDocumentAnalytics)
+		docs.PUT("/:id/analytics/access", r.ctrl.SetAnalyticsAccess)
+		docs.PUT("/:id/analytics/digest", r.ctrl.SubscribeDocumentDigest)
+		docs.DELETE("/:id/analytics/digest", r.ctrl.UnsubscribeDocumentDigest)
+	}
+
+	groups.Protected.GET("/d/:alias", r.ctrl.ResolveAlias)
+
+	// The caller's permission on many documents in one round trip
+	groups.Protected.POST("/permissions/check", r.ctrl.CheckPermissions)
+
+	// Anonymous reads of public documents, cached and limited per IP
+	// Also serves /documents/:id.json, the document as blocks for static
+	// site generators
+	groups.Anonymous.GET("/documents/:id", r.ctrl.GetPublicDocument)
+	groups.Anonymous.GET("/documents/:id/html", r.ctrl.GetPublicDocumentHTML)
+	groups.Anonymous.GET("/documents/:id/thumbnail", r.ctrl.GetPublicDocumentThumbnail)
+	groups.Anonymous.GET("/d/:alias", r.ctrl.GetPublicDocumentByAlias)
+	groups.Anonymous.GET("/snapshots/:id", r.ctrl.GetPublicSnapshot)
+	// Copying needs an account to copy into, so it sits beside the
+	// anonymous reads but requires authentication
+	groups.Protected.POST("/public/documents/:id/copy", r.ctrl.CopyPublicDocument)
+
+	// User analytics
+	groups.Protected.GET("/users/me/analytics", r.ctrl.GetUserAnalytics)
+	groups.Protected.PUT("/users/me/analytics/digest", r.ctrl.SubscribeWorkspaceDigest)
+	groups.Protected.DELETE("/users/me/analytics/digest", r.ctrl.UnsubscribeWorkspaceDigest)
+
+	// Storage across the user's own documents
+	groups.Protected.GET("/users/me/storage", r.ctrl.GetUserStorage)
+}
@@ -0,0 +1,168 @@
+// Package sanitize removes unsafe HTML from document content, so documents
+// cannot carry scripts or event handlers to the collaborators who open them.
+package sanitize
+
+import (
+	"context"
+	"sort"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+
+	"github.com/hafiztri123/document-api/internal/document/hook"
+)
+
+// Name is the content hook name of the sanitizer
+const Name = "sanitize_html"
+
+// maxPasses bounds how often content is sanitized. Removing a tag can join
+// the text around it into new markup, so content is sanitized until it no
+// longer changes.
+const maxPasses = 5
+
+// Allowlist is configured under sanitization. Empty fields use the
+// defaults below.
+type Allowlist struct {
+	// Elements are the tags kept; others are removed, keeping their text,
+	// except script and style which are removed with their content
+	Elements []string `mapstructure:"elements"`
+	// Attributes maps an attribute to the elements it is kept on
+	Attributes map[string][]string `mapstructure:"attributes"`
+	// URLSchemes are the schemes allowed in href and src; relative URLs
+	// are always allowed
+	URLSchemes []string `mapstructure:"url_schemes"`
+}
+
+var (
+	defaultElements = []string{
+		"a", "abbr", "b", "blockquote", "br", "code", "dd", "del", "details",
+		"div", "dl", "dt", "em", "h1", "h2", "h3", "h4", "h5", "h6", "hr", "i",
+		"img", "ins", "kbd", "li", "mark", "ol", "p", "pre", "q", "s", "samp",
+		"span", "strong", "sub", "summary", "sup", "table", "tbody", "td",
+		"tfoot", "th", "thead", "tr", "u", "ul",
+	}
+	defaultAttributes = map[string][]string{
+		"href":    {"a"},
+		"src":     {"img"},
+		"alt":     {"img"},
+		"width":   {"img"},
+		"height":  {"img"},
+		"title":   {"a", "abbr", "img"},
+		"colspan": {"td", "th"},
+		"rowspan": {"td", "th"},
+		"open":    {"details"},
+	}
+	defaultURLSchemes = []string{"http", "https", "mailto"}
+)
+
+var markdown = goldmark.New()
+
+// Sanitizer rewrites the raw HTML in markdown content to the allowlist.
+// Markdown itself, including HTML quoted in code, is left as it is.
+type Sanitizer struct {
+	policy *bluemonday.Policy
+}
+
+// New builds a sanitizer for allowlist
+func New(allowlist Allowlist) *Sanitizer {
+	if len(allowlist.Elements) == 0 {
+		allowlist.Elements = defaultElements
+	}
+	if len(allowlist.Attributes) == 0 {
+		allowlist.Attributes = defaultAttributes
+	}
+	if len(allowlist.URLSchemes) == 0 {
+		allowlist.URLSchemes = defaultURLSchemes
+	}
+
+	policy := bluemonday.NewPolicy()
+	policy.AllowElements(allowlist.Elements...)
+	for attribute, elements := range allowlist.Attributes {
+		policy.AllowAttrs(attribute).OnElements(elements...)
+	}
+	policy.AllowURLSchemes(allowlist.URLSchemes...)
+	policy.AllowRelativeURLs(true)
+	policy.RequireParseableURLs(true)
+
+	return &Sanitizer{policy: policy}
+}
+
+// Sanitize returns content with its raw HTML sanitized
+func (s *Sanitizer) Sanitize(content string) string {
+	for i := 0; i < maxPasses; i++ {
+		sanitized := s.pass(content)
+		if sanitized == content {
+			break
+		}
+		content = sanitized
+	}
+	return content
+}
+
+// span is a byte range of raw HTML in the content
+type span struct {
+	start, stop int
+}
+
+func (s *Sanitizer) pass(content string) string {
+	source := []byte(content)
+	root := markdown.Parser().Parse(text.NewReader(source))
+
+	var spans []span
+	_ = ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.HTMLBlock:
+			lines := n.Lines()
+			if lines.Len() == 0 {
+				return ast.WalkSkipChildren, nil
+			}
+			block := span{start: lines.At(0).Start, stop: lines.At(lines.Len() - 1).Stop}
+			if n.HasClosure() {
+				block.stop = n.ClosureLine.Stop
+			}
+			spans = append(spans, block)
+			return ast.WalkSkipChildren, nil
+		case *ast.RawHTML:
+			for i := 0; i < n.Segments.Len(); i++ {
+				segment := n.Segments.At(i)
+				spans = append(spans, span{start: segment.Start, stop: segment.Stop})
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+
+	if len(spans) == 0 {
+		return content
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	sanitized := make([]byte, 0, len(source))
+	last := 0
+	for _, sp := range spans {
+		if sp.start < last {
+			continue
+		}
+		sanitized = append(sanitized, source[last:sp.start]...)
+		sanitized = append(sanitized, s.policy.SanitizeBytes(source[sp.start:sp.stop])...)
+		last = sp.stop
+	}
+	sanitized = append(sanitized, source[last:]...)
+	return string(sanitized)
+}
+
+func (s *Sanitizer) Name() string {
+	return Name
+}
+
+// Apply sanitizes the content being saved
+func (s *Sanitizer) Apply(ctx context.Context, save *hook.Save) error {
+	save.Content = s.Sanitize(save.Content)
+	return nil
+}
@@ -0,0 +1,101 @@
+package document
+
+import (
+	"context"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"github.com/hafiztri123/document-api/config"
+	"github.com/hafiztri123/document-api/internal/document/service"
+	"github.com/hafiztri123/document-api/internal/logging"
+)
+
+// startReviewScan periodically flags documents whose review date has passed.
+// Every instance runs the scan; flagging is atomic, so owners are notified
+// once per document.
+func startReviewScan(lc fx.Lifecycle, svc service.Service, logger *zap.Logger) {
+	interval := scanInterval(config.DOCUMENTS_REVIEW_SCAN_INTERVAL, time.Hour, logger)
+
+	startScan(lc, "review_scan", interval, logger, func(ctx context.Context, logger *zap.Logger) {
+		if flagged, err := svc.FlagStaleDocuments(ctx); err == nil && flagged > 0 {
+			logger.Info("Flagged stale documents", zap.Int("count", flagged))
+		}
+	})
+}
+
+// startExpiryScan periodically notifies owners of expiring documents and
+// archives or deletes expired ones. Like the review scan it runs on every
+// instance; documents are claimed atomically, so each is handled once.
+func startExpiryScan(lc fx.Lifecycle, svc service.Service, logger *zap.Logger) {
+	interval := scanInterval(config.DOCUMENTS_EXPIRY_SCAN_INTERVAL, 15*time.Minute, logger)
+
+	startScan(lc, "expiry_scan", interval, logger, func(ctx context.Context, logger *zap.Logger) {
+		notified, expired, err := svc.ExpireDocuments(ctx)
+		if err == nil && (notified > 0 || expired > 0) {
+			logger.Info("Processed document expiry", zap.Int("notified", notified), zap.Int("expired", expired))
+		}
+	})
+}
+
+// startDigestScan periodically emails the analytics digests whose week has
+// ended. Digests are claimed atomically, so each goes out once.
+func startDigestScan(lc fx.Lifecycle, svc service.Service, logger *zap.Logger) {
+	interval := scanInterval(config.ANALYTICS_DIGEST_SCAN_INTERVAL, time.Hour, logger)
+
+	startScan(lc, "digest_scan", interval, logger, func(ctx context.Context, logger *zap.Logger) {
+		if sent, err := svc.SendDigests(ctx); err == nil && sent > 0 {
+			logger.Info("Sent analytics digests", zap.Int("count", sent))
+		}
+	})
+}
+
+func scanInterval(key string, fallback time.Duration, logger *zap.Logger) time.Duration {
+	interval, err := time.ParseDuration(viper.GetString(key))
+	if err != nil || interval <= 0 {
+		logger.Warn("Invalid "+key+", using default "+fallback.String(), zap.Error(err))
+		return fallback
+	}
+	return interval
+}
+
+// startScan runs scan immediately and then every interval until the app
+// stops
+func startScan(lc fx.Lifecycle, task string, interval time.Duration, logger *zap.Logger, scan func(ctx context.Context, logger *zap.Logger)) {
+	logger = logger.With(zap.String("task", task))
+	ctx, cancel := context.WithCancel(logging.NewContext(context.Background(), logger))
+	done := make(chan struct{})
+
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go func() {
+				defer close(done)
+
+				ticker := time.NewTicker(interval)
+				defer ticker.Stop()
+
+				for {
+					scan(ctx, logger)
+
+					select {
+					case <-ctx.Done():
+						return
+					case <-ticker.C:
+					}
+				}
+			}()
+			return nil
+		},
+		OnStop: func(stopCtx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-stopCtx.Done():
+				return stopCtx.Err()
+			}
+		},
+	})
+}
@@ -2,15 +2,48 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/hafiztri123/document-api/config"
+	"github.com/hafiztri123/document-api/internal/analytics/digest"
 	analyticsModel "github.com/hafiztri123/document-api/internal/analytics/model"
 	analyticsRepo "github.com/hafiztri123/document-api/internal/analytics/repository"
 	userRepo "github.com/hafiztri123/document-api/internal/auth/repository"
+	"github.com/hafiztri123/document-api/internal/document/blocks"
+	"github.com/hafiztri123/document-api/internal/document/cdn"
+	"github.com/hafiztri123/document-api/internal/document/diff"
+	"github.com/hafiztri123/document-api/internal/document/export"
+	"github.com/hafiztri123/document-api/internal/document/find"
+	"github.com/hafiztri123/document-api/internal/document/hook"
+	"github.com/hafiztri123/document-api/internal/document/importer"
+	"github.com/hafiztri123/document-api/internal/document/links"
+	"github.com/hafiztri123/document-api/internal/document/lint"
 	"github.com/hafiztri123/document-api/internal/document/model"
+	"github.com/hafiztri123/document-api/internal/document/outline"
+	"github.com/hafiztri123/document-api/internal/document/redact"
+	"github.com/hafiztri123/document-api/internal/document/thumbnail"
 	docRepo "github.com/hafiztri123/document-api/internal/document/repository"
+	"github.com/hafiztri123/document-api/internal/logging"
+	"github.com/hafiztri123/document-api/internal/mail"
+	siemModel "github.com/hafiztri123/document-api/internal/siem/model"
+	siemService "github.com/hafiztri123/document-api/internal/siem/service"
+	webhookModel "github.com/hafiztri123/document-api/internal/webhook/model"
+	webhookService "github.com/hafiztri123/document-api/internal/webhook/service"
+	wsModel "github.com/hafiztri123/document-api/internal/ws/model"
+	wsService "github.com/hafiztri123/document-api/internal/ws/service"
+	"github.com/spf13/viper"
 	"go.uber.org/zap"
 )
 
@@ -22,66 +55,481 @@ var (
 	ErrAlreadyCollaborator   = errors.New("user is already a collaborator")
 	ErrNotCollaborator       = errors.New("user is not a collaborator")
 	ErrCannotRemoveOwner     = errors.New("cannot remove document owner as collaborator")
+	ErrInvalidTransition     = errors.New("document cannot move to the requested state")
+	ErrAliasNotFound         = errors.New("alias not found")
+	ErrInvalidAlias          = errors.New("aliases may only contain lowercase letters, digits and single hyphens")
+	ErrReservedAlias         = errors.New("alias is reserved")
+	ErrAliasTaken            = errors.New("alias is already in use")
+	ErrShortCodeNotFound     = errors.New("short code not found")
+	ErrInvalidLabel          = errors.New("labels must not be blank or contain slashes")
+	ErrLabelTaken            = errors.New("another version already has this label")
+	ErrLabelNotFound         = errors.New("no version has this label")
+	ErrInvalidEmoji          = errors.New("reactions must be emoji")
+	ErrInvalidSourcePath     = errors.New("source path must be a relative path of at most 512 characters")
+	ErrContentTooLarge       = errors.New("document content exceeds the maximum size")
+	ErrExpiryInPast          = errors.New("expiry must be in the future")
+	ErrTermsNotAccepted      = errors.New("document terms have not been accepted")
+	ErrMergeSameDocument     = errors.New("a document cannot be merged into itself")
+	ErrSectionNotFound       = errors.New("document has no heading with this anchor")
+	ErrSnapshotNotFound      = errors.New("snapshot not found")
+	ErrVersionConflict       = errors.New("document has changed since the expected version")
+	ErrEncryptedDocument     = errors.New("the server cannot read the content of an encrypted document")
+	ErrNotEncrypted          = errors.New("document is not encrypted")
+	ErrKeyNotFound           = errors.New("user has not registered a key for the document")
+	ErrInvalidLanguage       = errors.New("language must be a BCP 47 tag such as \"en\" or \"pt-BR\"")
 )
 
+// aliasPattern accepts lowercase words separated by single hyphens
+var aliasPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// reservedAliases could be mistaken for routes or system pages
+var reservedAliases = map[string]bool{
+	"admin": true, "api": true, "d": true, "documents": true, "edit": true,
+	"folders": true, "help": true, "kb": true, "login": true, "logout": true,
+	"me": true, "new": true, "search": true, "settings": true, "templates": true,
+}
+
+// LintError rejects a save whose content breaks the lint rules while
+// lint.on_save is enabled
+type LintError struct {
+	Report *lint.Report
+}
+
+func (e *LintError) Error() string {
+	return "content violates lint rules"
+}
+
+// LockedError refuses a save or lock while another user holds the
+// document's lock
+type LockedError struct {
+	Lock *model.DocumentLock
+}
+
+func (e *LockedError) Error() string {
+	return "document is locked by another user"
+}
+
+// ConflictError refuses an update whose expected_version the document has
+// moved past. Document is the document as it is now, for the client to
+// rebase its change onto.
+type ConflictError struct {
+	Document *model.Document
+}
+
+func (e *ConflictError) Error() string {
+	return "document has changed since the expected version"
+}
 
 type Service interface {
 	// Document operations
 	CreateDocument(ctx context.Context, ownerID uuid.UUID, req model.DocumentCreateRequest) (*model.Document, error)
+	// SyncDocument creates or updates the owner's document at a source
+	// path, reporting whether it was created. Repeating a sync is a no-op.
+	SyncDocument(ctx context.Context, ownerID uuid.UUID, sourcePath string, req model.DocumentSyncRequest) (*model.Document, bool, error)
+	// ImportDocument creates a document from an uploaded file, see importer.Parse
+	ImportDocument(ctx context.Context, ownerID uuid.UUID, filename string, data []byte) (*model.Document, error)
+	// CopyPublicDocument creates a private copy of a public document owned
+	// by userID, pointing back at the source version. Private documents
+	// are ErrDocumentNotFound; terms must have been accepted first.
+	CopyPublicDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID, req model.DocumentCopyRequest) (*model.Document, error)
+	// ForkDocument branches a readable document into a new one owned by
+	// userID, with its own history, pointing back at the source version.
+	// Encrypted documents cannot be forked.
+	ForkDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID, req model.DocumentForkRequest) (*model.Document, error)
+	// GetForks lists the documents forked from id. The document's owners
+	// see every fork, other readers those they can read.
+	GetForks(ctx context.Context, id uuid.UUID, userID uuid.UUID) ([]*model.Fork, error)
 	GetDocumentByID(ctx context.Context, id uuid.UUID, userID uuid.UUID, recordView bool, ipAddress, userAgent string) (*model.Document, error)
-	GetUserDocuments(ctx context.Context, userID uuid.UUID, page, perPage int, sortBy, sortDir, query string) ([]*model.DocumentListResponse, int64, error)
+	GetUserDocuments(ctx context.Context, userID uuid.UUID, page, perPage int, sortBy, sortDir string, filter model.DocumentFilter) ([]*model.DocumentListResponse, int64, error)
+	// UpdateDocument fails with ErrVersionConflict when req.IfVersion is
+	// set and the document has moved past it, and with a ConflictError when
+	// req.ExpectedVersion is
 	UpdateDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID, req model.DocumentUpdateRequest) (*model.Document, error)
+	// DeleteDocument moves a document to its owner's trash, with its
+	// history, collaborators, views and edits
 	DeleteDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID) error
+	// GetTrash lists the documents userID deleted, most recent first
+	GetTrash(ctx context.Context, userID uuid.UUID) ([]*model.TrashedDocument, error)
+	// RestoreDocument takes a document out of the owner's trash along with
+	// everything deleted with it. An alias or source path another document
+	// claimed in the meantime is dropped.
+	RestoreDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*model.Document, error)
+	// MergeDocument adds a readable source document's content to the
+	// target as a new version, optionally deleting the source
+	MergeDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID, req model.DocumentMergeRequest) (*model.DocumentMergeResult, error)
+	
+	// Archiving hides a document from default listings; owners and admin
+	// collaborators may archive
+	// TransitionDocument moves a document through its lifecycle, see
+	// model.State.TransitionPermission; archiving and unarchiving are
+	// transitions to and from model.StateArchived
+	TransitionDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID, next model.State) (*model.Document, error)
+	ArchiveDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*model.Document, error)
+	UnarchiveDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*model.Document, error)
+	
+	// Alias operations; a replaced alias keeps redirecting until another
+	// document claims it
+	// An empty alias is derived from the title, with a numeric suffix when
+	// the plain form is taken.
+	SetAlias(ctx context.Context, id uuid.UUID, userID uuid.UUID, alias string) (*model.Document, error)
+	RemoveAlias(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*model.Document, error)
+	ResolveAlias(ctx context.Context, alias string, userID uuid.UUID, ipAddress, userAgent string) (*model.Document, string, error)
+	// ResolveShortCode returns the document with a short code as a person
+	// typed it, see model.NormalizeShortCode. Access is checked as for
+	// GetDocumentByID.
+	ResolveShortCode(ctx context.Context, code string, userID uuid.UUID, ipAddress, userAgent string) (*model.Document, error)
+	
+	// Lock operations; while a user holds a document's lock, saves by other
+	// users fail with a *LockedError. Locking again extends the lock.
+	LockDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID, req model.DocumentLockRequest) (*model.DocumentLock, error)
+	// UnlockDocument releases the caller's lock; the owner may release anyone's
+	UnlockDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID) error
+
+	// Draft operations keep a user's unsaved content apart from the
+	// document, see model.DocumentDraft. Saving a draft needs edit access;
+	// saving the document's content discards the author's draft.
+	SaveDraft(ctx context.Context, id uuid.UUID, userID uuid.UUID, req model.DocumentDraftRequest) (*model.DocumentDraft, error)
+	// GetDraft returns the user's draft, or nil when they have none
+	GetDraft(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*model.DocumentDraft, error)
+	DiscardDraft(ctx context.Context, id uuid.UUID, userID uuid.UUID) error
+
+	// Pin operations; pinned documents list first for the user who pinned them
+	PinDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID) error
+	UnpinDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID) error
+
+	// Terms operations; until they accept a document's terms, users other
+	// than the owner get ErrTermsNotAccepted instead of its content
+	SetTerms(ctx context.Context, id uuid.UUID, userID uuid.UUID, req model.DocumentTermsRequest) (*model.DocumentTerms, error)
+	GetTerms(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*model.DocumentTerms, error)
+	AcceptTerms(ctx context.Context, id uuid.UUID, userID uuid.UUID, ipAddress string) (*model.DocumentTerms, error)
+	GetTermsAcceptances(ctx context.Context, id uuid.UUID, userID uuid.UUID) ([]*model.TermsAcceptanceResponse, error)
+
+	// Section subscriptions notify readers of edits touching a section,
+	// see model.SectionUpdatedEvent
+	SubscribeToSection(ctx context.Context, id uuid.UUID, userID uuid.UUID, anchor string) (*model.SectionSubscription, error)
+	UnsubscribeFromSection(ctx context.Context, id uuid.UUID, userID uuid.UUID, anchor string) error
+	GetSectionSubscriptions(ctx context.Context, id uuid.UUID, userID uuid.UUID) ([]*model.SectionSubscription, error)
+
+	// Snapshots publish one version at its own public URL. Publishing and
+	// deleting need edit access, listing needs read access. Publishing a
+	// version twice returns the existing snapshot.
+	PublishSnapshot(ctx context.Context, id uuid.UUID, userID uuid.UUID, req model.SnapshotPublishRequest) (*model.DocumentSnapshot, error)
+	GetSnapshots(ctx context.Context, id uuid.UUID, userID uuid.UUID) ([]*model.DocumentSnapshot, error)
+	DeleteSnapshot(ctx context.Context, id uuid.UUID, snapshotID uuid.UUID, userID uuid.UUID) error
+	// GetPublicSnapshot serves a snapshot without authentication, whether
+	// or not the live document is public. Snapshots of deleted documents
+	// and of documents with terms are ErrSnapshotNotFound.
+	GetPublicSnapshot(ctx context.Context, snapshotID uuid.UUID, ipAddress, userAgent string) (*model.PublicSnapshot, cdn.Policy, error)
+
+	GetLock(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*model.DocumentLock, error)
+	
+	// GetBacklinks lists the documents the user can read that link to id
+	GetBacklinks(ctx context.Context, id uuid.UUID, userID uuid.UUID) ([]*model.Backlink, error)
+	// CheckPermissions returns the user's effective permission on each of
+	// documentIDs, once each in the order asked
+	CheckPermissions(ctx context.Context, userID uuid.UUID, documentIDs []uuid.UUID) ([]*model.EffectivePermission, error)
+	
+	// LintDocument checks the current content against the lint rules
+	LintDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*lint.Report, error)
+	// SearchDocument finds text in the current content, returning the
+	// position and surrounding context of each match
+	SearchDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID, query find.Query) (*find.Result, error)
+	
+	// Outline operations; anchors are stable across edits where possible
+	GetOutline(ctx context.Context, id uuid.UUID, userID uuid.UUID) ([]outline.Heading, error)
+	RenderHTML(ctx context.Context, id uuid.UUID, userID uuid.UUID) (string, error)
+	
+	// GetPublicDocument serves a public document without authentication.
+	// Private and missing documents are both ErrDocumentNotFound. The result
+	// is cached until the document changes; the policy says how long
+	// browsers and the CDN may cache it too. Every read is recorded as an
+	// anonymous view; reads answered by the CDN never reach the API.
+	GetPublicDocument(ctx context.Context, id uuid.UUID, ipAddress, userAgent string) (*model.PublicDocument, cdn.Policy, error)
+	// GetPublicDocumentByAlias serves a public document by its alias. For
+	// a replaced alias it returns the location the document now lives at.
+	// Private documents are ErrAliasNotFound, as if the alias was unused.
+	GetPublicDocumentByAlias(ctx context.Context, alias string, ipAddress, userAgent string) (*model.PublicDocument, cdn.Policy, string, error)
+	// GetPublishedDocument returns a public document as blocks, with its
+	// authors, for static site generators
+	GetPublishedDocument(ctx context.Context, id uuid.UUID) (*model.PublishedDocument, cdn.Policy, error)
+	
+	// GetDocumentStats counts the words and characters of the current
+	// content and of every recorded version; results are cached per version
+	GetDocumentStats(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*model.DocumentStats, error)
+	
+	// Storage operations report sizes in bytes for quota and cleanup
+	GetDocumentStorage(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*model.DocumentStorage, error)
+	GetUserStorage(ctx context.Context, userID uuid.UUID) (*model.StorageUsage, error)
+	
+	// ExportDocument renders a document as a downloadable file. Expensive
+	// formats are cached per version.
+	ExportDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID, format export.Format) (*export.File, error)
+	// ExportDocumentHistory bundles every version with its metadata, see
+	// export.RenderHistory
+	ExportDocumentHistory(ctx context.Context, id uuid.UUID, userID uuid.UUID, format export.Format) (*export.File, error)
+	// GetThumbnail returns a PNG preview of the first page of the current
+	// version. Previews are rendered in the background on save and cached
+	// per version; a missing one is rendered on demand.
+	GetThumbnail(ctx context.Context, id uuid.UUID, userID uuid.UUID) ([]byte, error)
+	// GetPublicThumbnail is GetThumbnail for anonymous readers of a public
+	// document, for link unfurls. Reads are not recorded as views.
+	GetPublicThumbnail(ctx context.Context, id uuid.UUID) ([]byte, cdn.Policy, error)
+	
+	// Review operations
+	SetReviewDate(ctx context.Context, id uuid.UUID, userID uuid.UUID, req model.DocumentReviewRequest) (*model.Document, error)
+	// FlagStaleDocuments marks documents past their review date as stale and
+	// notifies their owners; it returns how many were flagged
+	FlagStaleDocuments(ctx context.Context) (int, error)
+
+	// Expiry operations
+	SetExpiry(ctx context.Context, id uuid.UUID, userID uuid.UUID, req model.DocumentExpiryRequest) (*model.Document, error)
+	// ExpireDocuments notifies owners of documents about to expire, then
+	// archives or deletes the expired ones; it returns how many of each
+	ExpireDocuments(ctx context.Context) (notified int, expired int, err error)
 	
 	// Document history operations
-	GetDocumentHistory(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, page, perPage int) ([]*model.DocumentHistoryResponse, int64, error)
+	GetDocumentHistory(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, filter model.HistoryFilter, page, perPage int) ([]*model.DocumentHistoryResponse, int64, error)
+	// GetDocumentHistoryPage is GetDocumentHistory paged by version: it
+	// returns the versions below cursor, the newest when cursor is nil, and
+	// the cursor of the next page, nil after the last one
+	GetDocumentHistoryPage(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, filter model.HistoryFilter, cursor *int, perPage int) ([]*model.DocumentHistoryResponse, *int, error)
 	RestoreDocumentVersion(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, version int) (*model.Document, error)
+	// PreviewVersionRestore returns what RestoreDocumentVersion would save,
+	// diffed with engine against the current content, changing nothing
+	PreviewVersionRestore(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, version int, engine diff.Engine) (*model.VersionRestorePreview, error)
+	// Named versions; labels are unique per document and keep their
+	// version from being compacted
+	LabelVersion(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, version int, label string) (*model.LabeledVersion, error)
+	RemoveVersionLabel(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, version int) error
+	GetLabeledVersions(ctx context.Context, documentID uuid.UUID, userID uuid.UUID) ([]*model.LabeledVersion, error)
+	// AddReaction leaves an emoji on a version for anyone who can read the
+	// document, and RemoveReaction takes it back. Both return the version's
+	// reactions afterwards and broadcast the change to the document's
+	// subscribers.
+	AddReaction(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, version int, emoji string) ([]*model.ReactionCount, error)
+	RemoveReaction(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, version int, emoji string) ([]*model.ReactionCount, error)
+	RestoreLabeledVersion(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, label string) (*model.Document, error)
+	// CompactHistory squashes runs of quick successive edits by the same
+	// user, see model.HistoryCompactRequest; only the owner may compact.
+	// With dryRun nothing is deleted and the result is a preview.
+	CompactHistory(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, req model.HistoryCompactRequest, dryRun bool) (*model.HistoryCompaction, error)
+	// CompareVersions diffs the content of two versions with engine; a nil
+	// to compares against the current content
+	CompareVersions(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, from int, to *int, engine diff.Engine) (*model.DocumentCompareResponse, error)
 	
 	// Collaboration operations
 	ShareDocument(ctx context.Context, documentID uuid.UUID, ownerID uuid.UUID, req model.CollaboratorCreateRequest) (*model.CollaboratorResponse, error)
 	UpdateCollaboratorPermission(ctx context.Context, documentID uuid.UUID, ownerID uuid.UUID, userID uuid.UUID, req model.CollaboratorUpdateRequest) (*model.CollaboratorResponse, error)
 	RemoveCollaborator(ctx context.Context, documentID uuid.UUID, ownerID uuid.UUID, userID uuid.UUID) error
+	// TransferOwnership makes another user the owner. Any owner may hand
+	// it over, co-owners included; the previous owner stays a co-owner, so
+	// nobody loses access.
+	TransferOwnership(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, req model.OwnershipTransferRequest) (*model.Document, error)
+
+	// RegisterDocumentKey records the caller's public key for an encrypted
+	// document they can read
+	RegisterDocumentKey(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, req model.DocumentPublicKeyRequest) (*model.DocumentKey, error)
+	// WrapDocumentKey stores the document key for memberID, wrapped to the
+	// public key they registered. Only the owner, who created the key, may.
+	WrapDocumentKey(ctx context.Context, documentID uuid.UUID, ownerID uuid.UUID, memberID uuid.UUID, req model.DocumentWrappedKeyRequest) (*model.DocumentKey, error)
+	// GetDocumentKeys lists every member's key exchange, so the owner can
+	// see whose key still needs wrapping
+	GetDocumentKeys(ctx context.Context, documentID uuid.UUID, userID uuid.UUID) ([]*model.DocumentKey, error)
 	
 	// Analytics operations
+	// GetDocumentAnalytics is open to the owner and to collaborators holding
+	// the document's analytics permission
 	GetDocumentAnalytics(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, period string) (*analyticsModel.DocumentAnalyticsResponse, error)
+	// SetAnalyticsAccess sets which collaborators see the document's
+	// analytics. Only the owner may.
+	SetAnalyticsAccess(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, req model.DocumentAnalyticsAccessRequest) (*model.Document, error)
 	GetUserAnalytics(ctx context.Context, userID uuid.UUID, period string) (*analyticsModel.UserAnalyticsResponse, error)
+
+	// SubscribeDigest opts the user into a weekly analytics email for a
+	// document they own, or for all their documents when documentID is nil.
+	// Subscribing twice returns the existing digest.
+	SubscribeDigest(ctx context.Context, userID uuid.UUID, documentID *uuid.UUID) (*analyticsModel.Digest, error)
+	UnsubscribeDigest(ctx context.Context, userID uuid.UUID, documentID *uuid.UUID) error
+	// SendDigests emails every digest whose week has ended; it does
+	// nothing while mail is not configured
+	SendDigests(ctx context.Context) (int, error)
+
+	// WarmUp runs the access checks of up to limit users active on a
+	// document since since, concurrency at a time, so their statements are
+	// prepared on that many connections and the rows they read are cached
+	// by the database. Public documents among them are loaded into the
+	// public cache. It returns how many checks ran.
+	WarmUp(ctx context.Context, since time.Time, limit, concurrency int) (int, error)
 }
 
 type documentService struct {
-	docRepo       docRepo.Repository
-	userRepo      userRepo.Repository
-	analyticsRepo analyticsRepo.Repository
-	logger        *zap.Logger
+	docRepo         docRepo.Repository
+	locks           docRepo.LockRepository
+	drafts          docRepo.DraftRepository
+	userRepo        userRepo.Repository
+	analyticsRepo   analyticsRepo.Repository
+	webhooks        webhookService.Service
+	security        siemService.Service
+	realtime        wsService.Service
+	redis           *redis.Client
+	contentHooks    *hook.Chain
+	lintRules       lint.Rules
+	lintOnSave      bool
+	redaction       *redact.Filter
+	redactOnPublish bool
+	maxContentBytes int
+	lockTTL         time.Duration
+	draftTTL        time.Duration
+	expiryNotice    time.Duration
+	exportCacheTTL  time.Duration
+	exportCacheMax  int
+	historyDebounce time.Duration
+	publicCacheTTL  time.Duration
+	purger          cdn.Purger
+	cdnTTL          time.Duration
+	mailer          mail.Sender
+	logger          *zap.Logger
 }
 
 // NewDocumentService creates a new document service
 func NewDocumentService(
 	docRepo docRepo.Repository,
+	locks docRepo.LockRepository,
+	drafts docRepo.DraftRepository,
 	userRepo userRepo.Repository,
 	analyticsRepo analyticsRepo.Repository,
+	webhooks webhookService.Service,
+	security siemService.Service,
+	realtime wsService.Service,
+	redis *redis.Client,
+	contentHooks *hook.Chain,
+	redaction *redact.Filter,
+	purger cdn.Purger,
+	mailer mail.Sender,
 	logger *zap.Logger,
 ) Service {
+	var lintRules lint.Rules
+	if err := viper.UnmarshalKey(config.LINT_RULES, &lintRules); err != nil {
+		logger.Error("Invalid lint rules config, ignoring it", zap.Error(err))
+	}
+
+	publicCacheTTL, err := time.ParseDuration(viper.GetString(config.PUBLIC_CACHE_TTL))
+	if err != nil || publicCacheTTL < 0 {
+		logger.Warn("Invalid public.cache_ttl, using default 1m", zap.Error(err))
+		publicCacheTTL = time.Minute
+	}
+
+	exportCacheTTL, err := time.ParseDuration(viper.GetString(config.DOCUMENTS_EXPORT_CACHE_TTL))
+	if err != nil || exportCacheTTL < 0 {
+		logger.Warn("Invalid documents.export_cache_ttl, using default 24h", zap.Error(err))
+		exportCacheTTL = 24 * time.Hour
+	}
+
 	return &documentService{
-		docRepo:       docRepo,
-		userRepo:      userRepo,
-		analyticsRepo: analyticsRepo,
-		logger:        logger,
+		docRepo:         docRepo,
+		locks:           locks,
+		drafts:          drafts,
+		userRepo:        userRepo,
+		analyticsRepo:   analyticsRepo,
+		webhooks:        webhooks,
+		security:        security,
+		realtime:        realtime,
+		redis:           redis,
+		contentHooks:    contentHooks,
+		lintRules:       lintRules,
+		lintOnSave:      viper.GetBool(config.LINT_ON_SAVE),
+		redaction:       redaction,
+		redactOnPublish: viper.GetBool(config.REDACTION_ON_PUBLISH),
+		maxContentBytes: viper.GetInt(config.DOCUMENTS_MAX_CONTENT_BYTES),
+		lockTTL:         viper.GetDuration(config.DOCUMENTS_LOCK_TTL),
+		draftTTL:        viper.GetDuration(config.DOCUMENTS_DRAFT_TTL),
+		expiryNotice:    viper.GetDuration(config.DOCUMENTS_EXPIRY_NOTICE),
+		exportCacheTTL:  exportCacheTTL,
+		exportCacheMax:  viper.GetInt(config.DOCUMENTS_EXPORT_CACHE_MAX),
+		historyDebounce: viper.GetDuration(config.DOCUMENTS_HISTORY_DEBOUNCE),
+		publicCacheTTL:  publicCacheTTL,
+		purger:          purger,
+		cdnTTL:          viper.GetDuration(config.CDN_TTL),
+		mailer:          mailer,
+		logger:          logger,
 	}
 }
 
 
 func(s *documentService) 	CreateDocument(ctx context.Context, ownerID uuid.UUID, req model.DocumentCreateRequest) (*model.Document, error){
+	return s.createDocument(ctx, ownerID, req, documentOrigin{})
+}
+
+
+// documentOrigin is where a new document came from: a synced source path,
+// a public document it was copied from, or a document it was forked from
+type documentOrigin struct {
+	sourcePath        *string
+	sourceRevision    *string
+	copiedFromID      *uuid.UUID
+	copiedFromVersion *int
+	forkedFromID      *uuid.UUID
+	forkedFromVersion *int
+}
+
+// createDocument creates a document, recording its origin
+func (s *documentService) createDocument(ctx context.Context, ownerID uuid.UUID, req model.DocumentCreateRequest, origin documentOrigin) (*model.Document, error) {
+	if s.contentTooLarge(req.Content) {
+		return nil, ErrContentTooLarge
+	}
+
+	documentLanguage, err := languageOf(req.Language)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ciphertext is stored as given and can never be served publicly
+	var headings []outline.Heading
+	if req.Encrypted {
+		if req.IsPublic {
+			return nil, ErrEncryptedDocument
+		}
+	} else {
+		content, err := s.runContentHooks(ctx, nil, ownerID, req.Title, req.Content)
+		if err != nil {
+			return nil, err
+		}
+		req.Content = content
+
+		headings = outline.Rebase(nil, req.Content)
+		if err := s.lintOnSaveCheck(req.Content, headings); err != nil {
+			return nil, err
+		}
+	}
+
+	shortCode, err := s.newShortCode(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	document := &model.Document{
 		Title: req.Title,
+		ShortCode: shortCode,
+		Language: documentLanguage,
 		Content: req.Content,
 		IsPublic: req.IsPublic,
+		Encrypted: req.Encrypted,
+		ReviewBy: req.ReviewBy,
+		State: model.StateDraft,
+		Outline: headings,
+		SourcePath: origin.sourcePath,
+		SourceRevision: origin.sourceRevision,
+		CopiedFromID: origin.copiedFromID,
+		CopiedFromVersion: origin.copiedFromVersion,
+		ForkedFromID: origin.forkedFromID,
+		ForkedFromVersion: origin.forkedFromVersion,
 		OwnerID: ownerID,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
 	if err := s.docRepo.CreateDocument(ctx, document); err != nil {
-		s.logger.Error("Failed to create document", zap.Error(err))
+		logging.FromContext(ctx, s.logger).Error("Failed to create document", zap.Error(err))
 		return nil, err
 	}
 
@@ -94,35 +542,196 @@ func(s *documentService) 	CreateDocument(ctx context.Context, ownerID uuid.UUID,
 	}
 
 	if err := s.docRepo.CreateDocumentHistory(ctx, history); err != nil {
-		s.logger.Error("Failed to create document history", zap.Error(err))
+		logging.FromContext(ctx, s.logger).Error("Failed to create document history", zap.Error(err))
 		return document, nil
 	}
 
 	_ = s.analyticsRepo.RecordDocumentEdit(ctx, document.ID, ownerID, document.Version)
+	s.updateLinks(ctx, document)
+	s.generateThumbnail(ctx, document)
+
+	s.dispatchEvent(ctx, document, webhookModel.EventDocumentCreated, ownerID)
 
 	return document ,nil
 }
 
 
-func(s *documentService)	GetDocumentByID(ctx context.Context, id uuid.UUID, userID uuid.UUID, recordView bool, ipAddress, userAgent string) (*model.Document, error){
-	document, err := s.docRepo.GetDocumentByID(ctx, id)
+func(s *documentService)	SyncDocument(ctx context.Context, ownerID uuid.UUID, sourcePath string, req model.DocumentSyncRequest) (*model.Document, bool, error){
+	sourcePath = strings.Trim(sourcePath, "/")
+	if sourcePath == "" || len(sourcePath) > 512 || path.Clean(sourcePath) != sourcePath || strings.HasPrefix(sourcePath, "../") || sourcePath == ".." {
+		return nil, false, ErrInvalidSourcePath
+	}
+	if s.contentTooLarge(req.Content) {
+		return nil, false, ErrContentTooLarge
+	}
+
+	var revision *string
+	if req.CommitSHA != "" {
+		revision = &req.CommitSHA
+	}
+
+	document, err := s.docRepo.GetDocumentBySourcePath(ctx, ownerID, sourcePath)
 	if err != nil {
-		s.logger.Error("Failed to get document by ID", zap.Error(err))
-		return nil, err
+		logging.FromContext(ctx, s.logger).Error("Failed to get document by source path", zap.Error(err))
+		return nil, false, err
 	}
 
 	if document == nil {
+		document, err = s.createDocument(ctx, ownerID, model.DocumentCreateRequest{
+			Title:   req.Title,
+			Content: req.Content,
+		}, documentOrigin{sourcePath: &sourcePath, sourceRevision: revision})
+		return document, err == nil, err
+	}
+
+	// Compare hooked content so a resync of unchanged source is a no-op
+	content, err := s.runContentHooks(ctx, &document.ID, ownerID, req.Title, req.Content)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if document.Title != req.Title || document.Content != content {
+		document, err = s.UpdateDocument(ctx, document.ID, ownerID, model.DocumentUpdateRequest{
+			Title:   &req.Title,
+			Content: &req.Content,
+		})
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	if revision != nil && (document.SourceRevision == nil || *document.SourceRevision != *revision) {
+		if err := s.docRepo.SetDocumentRevision(ctx, document.ID, revision); err != nil {
+			logging.FromContext(ctx, s.logger).Error("Failed to set document revision", zap.Error(err))
+			return nil, false, err
+		}
+		document.SourceRevision = revision
+	}
+
+	return document, false, nil
+}
+
+
+func(s *documentService)	ImportDocument(ctx context.Context, ownerID uuid.UUID, filename string, data []byte) (*model.Document, error){
+	title, content, err := importer.Parse(filename, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.CreateDocument(ctx, ownerID, model.DocumentCreateRequest{
+		Title:   title,
+		Content: content,
+	})
+}
+
+
+func(s *documentService)	CopyPublicDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID, req model.DocumentCopyRequest) (*model.Document, error){
+	source, err := s.docRepo.GetDocumentByID(ctx, id)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document by ID", zap.Error(err))
+		return nil, err
+	}
+
+	if source == nil || !source.IsPublic {
 		return nil, ErrDocumentNotFound
 	}
 
-	canAccess, err := s.docRepo.CanUserAccess(ctx, id, userID, model.PermissionRead)
+	if err := s.checkTerms(ctx, source, userID); err != nil {
+		return nil, err
+	}
+
+	title := req.Title
+	if title == "" {
+		title = source.Title
+	}
+
+	var sourceLanguage string
+	if source.Language != nil {
+		sourceLanguage = *source.Language
+	}
+
+	return s.createDocument(ctx, userID, model.DocumentCreateRequest{
+		Title:    title,
+		Content:  source.Content,
+		Language: sourceLanguage,
+	}, documentOrigin{copiedFromID: &source.ID, copiedFromVersion: &source.Version})
+}
+
+
+func(s *documentService)	ForkDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID, req model.DocumentForkRequest) (*model.Document, error){
+	source, err := s.GetDocumentByID(ctx, id, userID, false, "", "")
 	if err != nil {
-		s.logger.Error("Failed to check user access", zap.Error(err))
 		return nil, err
 	}
 
-	if !canAccess {
-		return nil, ErrUnauthorized
+	if source.Encrypted {
+		return nil, ErrEncryptedDocument
+	}
+
+	title := req.Title
+	if title == "" {
+		title = source.Title
+	}
+
+	var sourceLanguage string
+	if source.Language != nil {
+		sourceLanguage = *source.Language
+	}
+
+	return s.createDocument(ctx, userID, model.DocumentCreateRequest{
+		Title:    title,
+		Content:  source.Content,
+		Language: sourceLanguage,
+	}, documentOrigin{forkedFromID: &source.ID, forkedFromVersion: &source.Version})
+}
+
+
+func(s *documentService)	GetForks(ctx context.Context, id uuid.UUID, userID uuid.UUID) ([]*model.Fork, error){
+	source, err := s.GetDocumentByID(ctx, id, userID, false, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	// Owners see every proposal made against their document
+	all := true
+	if err := s.checkOwner(ctx, source, userID); err == ErrUnauthorized {
+		all = false
+	} else if err != nil {
+		return nil, err
+	}
+
+	documents, err := s.docRepo.GetForks(ctx, id, userID, all)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get forks", zap.Error(err))
+		return nil, err
+	}
+
+	forks := make([]*model.Fork, 0, len(documents))
+	for _, document := range documents {
+		forks = append(forks, &model.Fork{
+			DocumentID:        document.ID,
+			Title:             document.Title,
+			OwnerID:           document.OwnerID,
+			ForkedFromVersion: document.ForkedFromVersion,
+			Version:           document.Version,
+			State:             document.State,
+			CreatedAt:         document.CreatedAt,
+			UpdatedAt:         document.UpdatedAt,
+		})
+	}
+
+	return forks, nil
+}
+
+
+func(s *documentService)	GetDocumentByID(ctx context.Context, id uuid.UUID, userID uuid.UUID, recordView bool, ipAddress, userAgent string) (*model.Document, error){
+	document, err := s.getReadableDocument(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkTerms(ctx, document, userID); err != nil {
+		return nil, err
 	}
 
 	if recordView {
@@ -133,11 +742,11 @@ func(s *documentService)	GetDocumentByID(ctx context.Context, id uuid.UUID, user
 }
 
 
-func(s *documentService)	GetUserDocuments(ctx context.Context, userID uuid.UUID, page, perPage int, sortBy, sortDir, query string) ([]*model.DocumentListResponse, int64, error){
+func(s *documentService)	GetUserDocuments(ctx context.Context, userID uuid.UUID, page, perPage int, sortBy, sortDir string, filter model.DocumentFilter) ([]*model.DocumentListResponse, int64, error){
 
-	documents, total, err := s.docRepo.GetDocumentsByUserID(ctx, userID, page, perPage, sortBy, sortDir, query)
+	documents, total, err := s.docRepo.GetDocumentsByUserID(ctx, userID, page, perPage, sortBy, sortDir, filter)
 	if err != nil {
-		s.logger.Error("Failed to get documents by user ID", zap.Error(err))
+		logging.FromContext(ctx, s.logger).Error("Failed to get documents by user ID", zap.Error(err))
 		return nil, 0, err
 	}
 
@@ -152,9 +761,15 @@ func(s *documentService)	GetUserDocuments(ctx context.Context, userID uuid.UUID,
 
 
 func(s *documentService)	UpdateDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID, req model.DocumentUpdateRequest) (*model.Document, error){
+	return s.updateDocument(ctx, id, userID, req, nil)
+}
+
+// updateDocument applies an update; mergedFrom is the source document when
+// the new content comes from a merge, and is recorded in the history
+func (s *documentService) updateDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID, req model.DocumentUpdateRequest, mergedFrom *model.Document) (*model.Document, error) {
 	document, err := s.docRepo.GetDocumentByID(ctx, id)
 	if err != nil {
-		s.logger.Error("Failed to get document by ID", zap.Error(err))
+		logging.FromContext(ctx, s.logger).Error("Failed to get document by ID", zap.Error(err))
 		return nil, err
 	}
 
@@ -162,26 +777,73 @@ func(s *documentService)	UpdateDocument(ctx context.Context, id uuid.UUID, userI
 		return nil, ErrDocumentNotFound
 	}
 
-	canWrite, err := s.docRepo.CanUserAccess(ctx, id, userID, model.PermissionWrite)
+	canWrite, err := s.docRepo.CanUserAccess(ctx, id, userID, document.State.EditPermission())
 	if err != nil {
-		s.logger.Error("Failed to check user access", zap.Error(err))
+		logging.FromContext(ctx, s.logger).Error("Failed to check user access", zap.Error(err))
 		return nil, err
 	}
 	if !canWrite {
 		return nil, ErrUnauthorized
 	}
 
+	if err := s.checkLock(ctx, id, userID); err != nil {
+		return nil, err
+	}
+
+	if req.IfVersion != nil && *req.IfVersion != document.Version {
+		return nil, ErrVersionConflict
+	}
+
+	if req.ExpectedVersion != nil && *req.ExpectedVersion != document.Version {
+		return nil, &ConflictError{Document: document}
+	}
+	ifVersion := req.IfVersion
+	if ifVersion == nil {
+		ifVersion = req.ExpectedVersion
+	}
+
 	if req.Title != nil {
 		document.Title = *req.Title
 	}
 
-	// var oldContent string
+	if req.Language != nil {
+		document.Language, err = languageOf(*req.Language)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if document.Encrypted && req.IsPublic != nil && *req.IsPublic {
+		return nil, ErrEncryptedDocument
+	}
+
+	if req.Content != nil {
+		if s.contentTooLarge(*req.Content) {
+			return nil, ErrContentTooLarge
+		}
+
+		if !document.Encrypted {
+			content, err := s.runContentHooks(ctx, &document.ID, userID, document.Title, *req.Content)
+			if err != nil {
+				return nil, err
+			}
+			req.Content = &content
+		}
+	}
+
+	oldContent, oldOutline, oldVersion, wasPublic := document.Content, s.outlineOf(document), document.Version, document.IsPublic
 	var contentUpdated bool
 
 	if req.Content != nil && *req.Content != document.Content {
-		// oldContent = document.Content
 		document.Content = *req.Content
 		contentUpdated = true
+
+		if !document.Encrypted {
+			document.Outline = outline.Rebase(s.outlineOf(document), document.Content)
+			if err := s.lintOnSaveCheck(document.Content, document.Outline); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	if req.IsPublic != nil {
@@ -190,110 +852,169 @@ func(s *documentService)	UpdateDocument(ctx context.Context, id uuid.UUID, userI
 
 	if contentUpdated {
 		document.UpdatedAt = time.Now()
-		if err := s.docRepo.UpdateDocument(ctx, document); err != nil {
-			s.logger.Error("Failed to update document", zap.Error(err))
-			return nil, err
-		}
-
 		history := &model.DocumentHistory{
 			DocumentID: document.ID,
-			Version: document.Version,
 			Content: document.Content,
 			UpdatedByID: userID,
 			UpdatedAt: document.UpdatedAt,
 		}
+		if mergedFrom != nil {
+			summary := fmt.Sprintf("Merged %q (%s)", mergedFrom.Title, mergedFrom.ID)
+			history.MergedFromID = &mergedFrom.ID
+			history.Summary = &summary
+		} else if req.ChangeSummary != nil {
+			if summary := strings.TrimSpace(*req.ChangeSummary); summary != "" {
+				history.Summary = &summary
+			}
+		}
 
-		if err := s.docRepo.CreateDocumentHistory(ctx, history); err != nil {
-			s.logger.Error("Failed to create document history", zap.Error(err))
+		if err := s.saveContent(ctx, document, ifVersion, history); err != nil {
+			return nil, s.expectedVersionConflict(ctx, id, req, err)
 		}
 
-		_ = s.analyticsRepo.RecordDocumentEdit(ctx, document.ID, userID, document.Version)
-	} else if req.Title != nil || req.IsPublic != nil {
+		s.updateLinks(ctx, document)
+	} else if req.Title != nil || req.IsPublic != nil || req.Language != nil {
 		document.UpdatedAt = time.Now()
-		if err := s.docRepo.UpdateDocument(ctx, document); err != nil {
-			s.logger.Error("Failed to update document metadata", zap.Error(err))
-			return nil, err
+		if err := s.saveDocument(ctx, document, ifVersion); err != nil {
+			return nil, s.expectedVersionConflict(ctx, id, req, err)
+		}
+	}
+
+	if contentUpdated || req.Title != nil || req.IsPublic != nil || req.Language != nil {
+		s.invalidatePublic(ctx, document, wasPublic)
+	}
+
+	if document.IsPublic != wasPublic {
+		s.recordSecurityEvent(ctx, siemModel.TypeVisibilityChanged, userID, document.ID, map[string]interface{}{
+			"is_public": document.IsPublic,
+		})
+	}
+
+	if contentUpdated || req.Title != nil {
+		s.generateThumbnail(ctx, document)
+	}
+
+	if contentUpdated {
+		s.dispatchContentEvent(ctx, document, userID, req.OriginClientID, oldContent, oldOutline, oldVersion)
+	} else if req.Title != nil || req.IsPublic != nil || req.Language != nil {
+		s.dispatchEvent(ctx, document, webhookModel.EventDocumentUpdated, userID)
+	}
+
+	// The draft was the work in progress towards this save
+	if req.Content != nil && mergedFrom == nil {
+		if err := s.drafts.DeleteDraft(ctx, id, userID); err != nil {
+			logging.FromContext(ctx, s.logger).Error("Failed to discard draft after save", zap.Error(err))
 		}
 	}
 
 	return document ,nil
 }
 
+// saveContent persists an update to the content along with history, the
+// version it adds, and the record of the edit, so the version is never
+// bumped without them. ifVersion works as for saveDocument. With a
+// debounce window, an edit following the same user's previous edit within
+// the window replaces that version instead, so the entry keeps only the
+// latest content of a burst of autosaves, and the latest change summary
+// given in it. Merges always get their own version.
+func (s *documentService) saveContent(ctx context.Context, document *model.Document, ifVersion *int, history *model.DocumentHistory) error {
+	update := &model.ContentUpdate{
+		Document: document,
+		IfVersion: ifVersion,
+		History: history,
+	}
+	if s.historyDebounce > 0 && history.MergedFromID == nil {
+		since := history.UpdatedAt.Add(-s.historyDebounce)
+		update.CollapseSince = &since
+	}
 
-func(s *documentService)	DeleteDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID) error{
-	document, err := s.docRepo.GetDocumentByID(ctx, id)
+	saved, err := s.docRepo.SaveContentUpdate(ctx, update)
 	if err != nil {
-		s.logger.Error("Failed to get document by ID", zap.Error(err))
+		logging.FromContext(ctx, s.logger).Error("Failed to update document", zap.Error(err))
 		return err
 	}
+	if !saved {
+		return ErrVersionConflict
+	}
+	return nil
+}
 
-	if document == nil {
-		return ErrDocumentNotFound
+
+// saveDocument persists an update. With ifVersion set, the update is
+// conditional on the stored version so a concurrent save in between is
+// reported as ErrVersionConflict instead of being overwritten.
+func (s *documentService) saveDocument(ctx context.Context, document *model.Document, ifVersion *int) error {
+	if ifVersion == nil {
+		if err := s.docRepo.UpdateDocument(ctx, document); err != nil {
+			logging.FromContext(ctx, s.logger).Error("Failed to update document", zap.Error(err))
+			return err
+		}
+		return nil
 	}
 
-	if document.OwnerID != userID {
-		return ErrUnauthorized
+	updated, err := s.docRepo.UpdateDocumentIfVersion(ctx, document, *ifVersion)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to update document", zap.Error(err))
+		return err
 	}
+	if !updated {
+		return ErrVersionConflict
+	}
+	return nil
+}
 
-	if err := s.docRepo.DeleteDocument(ctx, id); err != nil {
-		s.logger.Error("Failed to delete document", zap.Error(err))
+
+// expectedVersionConflict turns the ErrVersionConflict of a save that lost
+// to a concurrent one into a ConflictError with the document that save
+// left, when the update was made against req.ExpectedVersion
+func (s *documentService) expectedVersionConflict(ctx context.Context, id uuid.UUID, req model.DocumentUpdateRequest, err error) error {
+	if err != ErrVersionConflict || req.IfVersion != nil || req.ExpectedVersion == nil {
 		return err
 	}
 
-	return nil
+	current, getErr := s.docRepo.GetDocumentByID(ctx, id)
+	if getErr != nil || current == nil {
+		return err
+	}
+	return &ConflictError{Document: current}
 }
 
 
-func(s *documentService)	GetDocumentHistory(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, page, perPage int) ([]*model.DocumentHistoryResponse, int64, error){
-	canAccess, err := s.docRepo.CanUserAccess(ctx, documentID, userID, model.PermissionRead)
+func(s *documentService)	DeleteDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID) error{
+	document, err := s.docRepo.GetDocumentByID(ctx, id)
 	if err != nil {
-		s.logger.Error("Failed to check user access", zap.Error(err))
-		return nil, 0, err
+		logging.FromContext(ctx, s.logger).Error("Failed to get document by ID", zap.Error(err))
+		return err
 	}
-	if !canAccess {
-		return nil, 0, ErrUnauthorized
+
+	if document == nil {
+		return ErrDocumentNotFound
 	}
 
-	history, total, err := s.docRepo.GetDocumentHistory(ctx, documentID, page, perPage)
-	if err != nil {
-		s.logger.Error("Failed to get document history", zap.Error(err))
-		return nil, 0, err
+	if err := s.checkOwner(ctx, document, userID); err != nil {
+		return err
 	}
 
-	response := make([]*model.DocumentHistoryResponse, 0, len(history))
-	for _, h := range history {
-		resp := &model.DocumentHistoryResponse{
-			Version: h.Version,
-			Content: h.Content,
-			UpdatedBy: struct {
-				ID uuid.UUID `json:"id"`
-				Name string `json:"name"`
-			}{
-				ID: h.UpdatedByID,
-				Name: h.UpdatedBy.Name,
-			},
-			UpdatedAt: h.UpdatedAt,
-		}
-		response = append(response, resp)
+	if err := s.docRepo.DeleteDocument(ctx, id); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to delete document", zap.Error(err))
+		return err
 	}
 
-	return response, total, nil
+	s.invalidatePublic(ctx, document, document.IsPublic)
+	s.dispatchEvent(ctx, document, webhookModel.EventDocumentDeleted, userID)
+
+	return nil
 }
 
 
-func(s *documentService)	RestoreDocumentVersion(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, version int) (*model.Document, error){
-	canWrite, err := s.docRepo.CanUserAccess(ctx, documentID, userID, model.PermissionWrite)
-	if err != nil {
-		s.logger.Error("Failed to check user access", zap.Error(err))
-		return nil, err
-	}
-	if !canWrite {
-		return nil, ErrUnauthorized
-	}
+func(s *documentService)	GetTrash(ctx context.Context, userID uuid.UUID) ([]*model.TrashedDocument, error){
+	return s.docRepo.GetDeletedDocuments(ctx, userID)
+}
 
-	document, err := s.docRepo.GetDocumentByID(ctx, documentID)
+
+func(s *documentService)	RestoreDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*model.Document, error){
+	document, err := s.docRepo.GetDeletedDocument(ctx, id)
 	if err != nil {
-		s.logger.Error("Failed to get document by ID", zap.Error(err))
 		return nil, err
 	}
 
@@ -301,61 +1022,2531 @@ func(s *documentService)	RestoreDocumentVersion(ctx context.Context, documentID
 		return nil, ErrDocumentNotFound
 	}
 
-	history, err := s.docRepo.GetDocumentHistoryByVersion(ctx, documentID, version)
-	if err != nil {
-		s.logger.Error("Failed to get document history by version", zap.Error(err))
+	if err := s.checkOwner(ctx, document, userID); err != nil {
 		return nil, err
 	}
 
-	if history == nil {
-		return nil, ErrVersionNotFound
+	if document.Alias != nil {
+		holder, err := s.docRepo.GetDocumentByAlias(ctx, *document.Alias)
+		if err != nil {
+			return nil, err
+		}
+		if holder != nil {
+			document.Alias = nil
+		}
 	}
 
-	document.Content = history.Content
-	document.UpdatedAt = time.Now()
-
-	if err := s.docRepo.UpdateDocument(ctx, document); err != nil {
-		s.logger.Error("Failed to update document", zap.Error(err))
-		return nil, err
+	if document.SourcePath != nil {
+		holder, err := s.docRepo.GetDocumentBySourcePath(ctx, document.OwnerID, *document.SourcePath)
+		if err != nil {
+			return nil, err
+		}
+		if holder != nil {
+			document.SourcePath = nil
+			document.SourceRevision = nil
+		}
+	}
+
+	if err := s.docRepo.RestoreDocument(ctx, document); err != nil {
+		return nil, err
+	}
+
+	restored, err := s.docRepo.GetDocumentByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if restored == nil {
+		return nil, ErrDocumentNotFound
+	}
+
+	s.invalidatePublic(ctx, restored, restored.IsPublic)
+	s.dispatchEvent(ctx, restored, webhookModel.EventDocumentRestored, userID)
+
+	return restored, nil
+}
+
+
+func(s *documentService)	MergeDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID, req model.DocumentMergeRequest) (*model.DocumentMergeResult, error){
+	if req.SourceID == id {
+		return nil, ErrMergeSameDocument
+	}
+
+	source, err := s.GetDocumentByID(ctx, req.SourceID, userID, false, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	// Checked before merging, so a merge never half succeeds for lack of
+	// permission to delete
+	if req.DeleteSource {
+		if err := s.checkOwner(ctx, source, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	target, err := s.docRepo.GetDocumentByID(ctx, id)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document by ID", zap.Error(err))
+		return nil, err
+	}
+
+	if target == nil {
+		return nil, ErrDocumentNotFound
+	}
+
+	// Joined ciphertexts would not decrypt
+	if source.Encrypted || target.Encrypted {
+		return nil, ErrEncryptedDocument
+	}
+
+	content := mergeContent(target.Content, source.Content, req.Position)
+
+	document, err := s.updateDocument(ctx, id, userID, model.DocumentUpdateRequest{Content: &content}, source)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &model.DocumentMergeResult{Document: document}
+	if req.DeleteSource {
+		if err := s.DeleteDocument(ctx, source.ID, userID); err != nil {
+			return nil, err
+		}
+		result.SourceDeleted = true
+	}
+
+	return result, nil
+}
+
+// mergeContent joins two documents' content with a blank line between them
+func mergeContent(target, source string, position model.MergePosition) string {
+	target = strings.TrimRight(target, "\n")
+	source = strings.TrimRight(source, "\n")
+	if position == model.MergePrepend {
+		target, source = source, target
+	}
+
+	switch {
+	case target == "":
+		return source
+	case source == "":
+		return target
+	default:
+		return target + "\n\n" + source
+	}
+}
+
+
+func(s *documentService)	TransitionDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID, next model.State) (*model.Document, error){
+	document, err := s.docRepo.GetDocumentByID(ctx, id)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document by ID", zap.Error(err))
+		return nil, err
+	}
+
+	if document == nil {
+		return nil, ErrDocumentNotFound
+	}
+
+	// Repeating a transition is a no-op, e.g. archiving twice keeps the
+	// original archive date
+	current := document.State
+	permission, allowed := current.TransitionPermission(next)
+	if current == next {
+		permission, allowed = current.EditPermission(), true
+	} else if !allowed {
+		permission = model.PermissionRead
+	}
+
+	canTransition, err := s.docRepo.CanUserAccess(ctx, id, userID, permission)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to check user access", zap.Error(err))
+		return nil, err
+	}
+	if !canTransition {
+		return nil, ErrUnauthorized
+	}
+	if !allowed {
+		return nil, ErrInvalidTransition
+	}
+	if current == next {
+		return document, nil
+	}
+
+	if next == model.StatePublished && s.redactOnPublish {
+		if violations := s.redaction.Scan(document.Content); len(violations) > 0 {
+			return nil, &hook.Rejection{Hook: redact.Name, Violations: violations}
+		}
+	}
+
+	var archivedAt *time.Time
+	if next == model.StateArchived {
+		now := time.Now()
+		archivedAt = &now
+	}
+
+	if err := s.docRepo.SetDocumentState(ctx, id, next, archivedAt); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to set document state", zap.Error(err))
+		return nil, err
+	}
+
+	document.State = next
+	document.ArchivedAt = archivedAt
+
+	s.webhooks.Dispatch(ctx, document.OwnerID, webhookModel.EventDocumentState, model.DocumentStateEvent{
+		DocumentID: document.ID,
+		Title:      document.Title,
+		From:       current,
+		To:         next,
+		ActorID:    userID,
+		ChangedAt:  time.Now(),
+	})
+
+	return document, nil
+}
+
+
+func(s *documentService)	ArchiveDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*model.Document, error){
+	return s.TransitionDocument(ctx, id, userID, model.StateArchived)
+}
+
+
+// UnarchiveDocument returns an archived document to draft; other documents
+// are left as they are
+func(s *documentService)	UnarchiveDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*model.Document, error){
+	document, err := s.docRepo.GetDocumentByID(ctx, id)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document by ID", zap.Error(err))
+		return nil, err
+	}
+
+	if document == nil {
+		return nil, ErrDocumentNotFound
+	}
+
+	if document.State != model.StateArchived {
+		return s.TransitionDocument(ctx, id, userID, document.State)
+	}
+
+	return s.TransitionDocument(ctx, id, userID, model.StateDraft)
+}
+
+
+func(s *documentService)	SetAlias(ctx context.Context, id uuid.UUID, userID uuid.UUID, alias string) (*model.Document, error){
+	if alias != "" {
+		if !aliasPattern.MatchString(alias) {
+			return nil, ErrInvalidAlias
+		}
+
+		// UUID-shaped aliases would be ambiguous with document IDs
+		if _, err := uuid.Parse(alias); err == nil || reservedAliases[alias] {
+			return nil, ErrReservedAlias
+		}
+	}
+
+	document, err := s.getEditableDocument(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if alias == "" {
+		if alias, err = s.aliasFromTitle(ctx, document); err != nil {
+			return nil, err
+		}
+	}
+
+	if document.Alias != nil && *document.Alias == alias {
+		return document, nil
+	}
+
+	taken, err := s.docRepo.GetDocumentByAlias(ctx, alias)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document by alias", zap.Error(err))
+		return nil, err
+	}
+	if taken != nil {
+		return nil, ErrAliasTaken
+	}
+
+	if err := s.docRepo.SetDocumentAlias(ctx, id, &alias, s.aliasRedirect(document)); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to set document alias", zap.Error(err))
+		return nil, err
+	}
+
+	document.Alias = &alias
+	s.invalidatePublic(ctx, document, false)
+
+	return document, nil
+}
+
+
+func(s *documentService)	RemoveAlias(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*model.Document, error){
+	document, err := s.getEditableDocument(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if document.Alias == nil {
+		return document, nil
+	}
+
+	if err := s.docRepo.SetDocumentAlias(ctx, id, nil, s.aliasRedirect(document)); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to remove document alias", zap.Error(err))
+		return nil, err
+	}
+
+	document.Alias = nil
+	s.invalidatePublic(ctx, document, false)
+
+	return document, nil
+}
+
+
+// ResolveAlias returns the document using alias. For a replaced alias it
+// returns the location the document now lives at instead.
+func(s *documentService)	ResolveAlias(ctx context.Context, alias string, userID uuid.UUID, ipAddress, userAgent string) (*model.Document, string, error){
+	document, err := s.docRepo.GetDocumentByAlias(ctx, alias)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document by alias", zap.Error(err))
+		return nil, "", err
+	}
+
+	if document != nil {
+		document, err = s.GetDocumentByID(ctx, document.ID, userID, true, ipAddress, userAgent)
+		return document, "", err
+	}
+
+	redirect, err := s.docRepo.GetAliasRedirect(ctx, alias)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get alias redirect", zap.Error(err))
+		return nil, "", err
+	}
+
+	if redirect == nil {
+		return nil, "", ErrAliasNotFound
+	}
+
+	document, err = s.GetDocumentByID(ctx, redirect.DocumentID, userID, false, "", "")
+	if err != nil {
+		if err == ErrDocumentNotFound {
+			return nil, "", ErrAliasNotFound
+		}
+		return nil, "", err
+	}
+
+	if document.Alias != nil {
+		return nil, "/api/v1/d/" + *document.Alias, nil
+	}
+	return nil, "/api/v1/documents/" + document.ID.String(), nil
+}
+
+
+// ResolveShortCode returns the document using code, if the user may read it
+func(s *documentService)	ResolveShortCode(ctx context.Context, code string, userID uuid.UUID, ipAddress, userAgent string) (*model.Document, error){
+	code, ok := model.NormalizeShortCode(code)
+	if !ok {
+		return nil, ErrShortCodeNotFound
+	}
+
+	document, err := s.docRepo.GetDocumentByShortCode(ctx, code)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document by short code", zap.Error(err))
+		return nil, err
+	}
+
+	if document == nil {
+		return nil, ErrShortCodeNotFound
+	}
+
+	return s.GetDocumentByID(ctx, document.ID, userID, true, ipAddress, userAgent)
+}
+
+
+// maxShortCodeAttempts bounds the random codes tried for a new document.
+// With a billion codes a collision is rare, let alone several in a row.
+const maxShortCodeAttempts = 10
+
+// newShortCode returns a short code no document uses yet. The unique index
+// on short_code catches a code taken by a concurrent create.
+func (s *documentService) newShortCode(ctx context.Context) (string, error) {
+	for n := 0; n < maxShortCodeAttempts; n++ {
+		code, err := model.NewShortCode()
+		if err != nil {
+			logging.FromContext(ctx, s.logger).Error("Failed to generate short code", zap.Error(err))
+			return "", err
+		}
+
+		taken, err := s.docRepo.ShortCodeExists(ctx, code)
+		if err != nil {
+			logging.FromContext(ctx, s.logger).Error("Failed to check short code", zap.Error(err))
+			return "", err
+		}
+		if !taken {
+			return code, nil
+		}
+	}
+
+	return "", fmt.Errorf("no free short code after %d attempts", maxShortCodeAttempts)
+}
+
+
+// maxAliasAttempts bounds the suffixes tried when deriving an alias
+const maxAliasAttempts = 100
+
+// aliasFromTitle derives a free alias from the document's title, trying
+// "title", "title-2", "title-3" and so on. A document keeps its current
+// alias when that is already the derived one.
+func (s *documentService) aliasFromTitle(ctx context.Context, document *model.Document) (string, error) {
+	base := slugify(document.Title)
+
+	for n := 1; n <= maxAliasAttempts; n++ {
+		candidate := base
+		if n > 1 {
+			suffix := fmt.Sprintf("-%d", n)
+			candidate = strings.TrimRight(truncate(base, maxAliasLength-len(suffix)), "-") + suffix
+		}
+
+		taken, err := s.docRepo.GetDocumentByAlias(ctx, candidate)
+		if err != nil {
+			logging.FromContext(ctx, s.logger).Error("Failed to get document by alias", zap.Error(err))
+			return "", err
+		}
+		if taken == nil || taken.ID == document.ID {
+			return candidate, nil
+		}
+	}
+
+	return "", ErrAliasTaken
+}
+
+// maxAliasLength matches DocumentAliasRequest and the alias column
+const maxAliasLength = 64
+
+// nonAlias matches runs of characters aliases may not contain
+var nonAlias = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slugify turns a title into a valid alias. Titles too short or reserved to
+// be aliases on their own get a "-doc" suffix.
+func slugify(title string) string {
+	slug := strings.Trim(nonAlias.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	slug = strings.TrimRight(truncate(slug, maxAliasLength), "-")
+
+	if _, err := uuid.Parse(slug); err == nil || len(slug) < 3 || reservedAliases[slug] {
+		slug = strings.TrimRight(truncate(slug, maxAliasLength-len("-doc")), "-")
+		if slug == "" {
+			return "untitled-doc"
+		}
+		slug += "-doc"
+	}
+	return slug
+}
+
+// truncate shortens an ASCII string to at most n bytes
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// getEditableDocument loads a document the user may edit in its current
+// lifecycle state
+func (s *documentService) getEditableDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*model.Document, error) {
+	document, err := s.docRepo.GetDocumentByID(ctx, id)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document by ID", zap.Error(err))
+		return nil, err
+	}
+
+	if document == nil {
+		return nil, ErrDocumentNotFound
+	}
+
+	canWrite, err := s.docRepo.CanUserAccess(ctx, id, userID, document.State.EditPermission())
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to check user access", zap.Error(err))
+		return nil, err
+	}
+	if !canWrite {
+		return nil, ErrUnauthorized
+	}
+
+	return document, nil
+}
+
+
+// aliasRedirect records the alias a document is giving up, if any
+func (s *documentService) aliasRedirect(document *model.Document) *model.AliasRedirect {
+	if document.Alias == nil {
+		return nil
+	}
+	return &model.AliasRedirect{
+		Alias:      *document.Alias,
+		DocumentID: document.ID,
+		CreatedAt:  time.Now(),
+	}
+}
+
+
+func(s *documentService)	LockDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID, req model.DocumentLockRequest) (*model.DocumentLock, error){
+	if _, err := s.getEditableDocument(ctx, id, userID); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindUserByID(ctx, userID)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to find user by ID", zap.Error(err))
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	ttl := s.lockTTL
+	if req.TTLSeconds > 0 {
+		ttl = time.Duration(req.TTLSeconds) * time.Second
+	}
+
+	// Extending a lock keeps the time it was first acquired
+	now := time.Now()
+	acquiredAt := now
+	current, err := s.locks.GetLock(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if current != nil && current.UserID == userID {
+		acquiredAt = current.AcquiredAt
+	}
+
+	lock, err := s.locks.AcquireLock(ctx, &model.DocumentLock{
+		DocumentID: id,
+		UserID:     userID,
+		UserName:   user.Name,
+		AcquiredAt: acquiredAt,
+		ExpiresAt:  now.Add(ttl),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if lock.UserID != userID {
+		return nil, &LockedError{Lock: lock}
+	}
+
+	return lock, nil
+}
+
+
+func(s *documentService)	UnlockDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID) error{
+	document, err := s.getEditableDocument(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+
+	lock, err := s.locks.GetLock(ctx, id)
+	if err != nil {
+		return err
+	}
+	if lock == nil {
+		return nil
+	}
+
+	if lock.UserID != userID {
+		if err := s.checkOwner(ctx, document, userID); err == ErrUnauthorized {
+			return &LockedError{Lock: lock}
+		} else if err != nil {
+			return err
+		}
+	}
+
+	return s.locks.ReleaseLock(ctx, id, lock.UserID)
+}
+
+
+// SaveDraft stores the content as is: content hooks, lint and history only
+// apply when the draft is saved to the document
+func(s *documentService)	SaveDraft(ctx context.Context, id uuid.UUID, userID uuid.UUID, req model.DocumentDraftRequest) (*model.DocumentDraft, error){
+	document, err := s.getEditableDocument(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.contentTooLarge(*req.Content) {
+		return nil, ErrContentTooLarge
+	}
+
+	now := time.Now()
+	draft := &model.DocumentDraft{
+		DocumentID:  id,
+		UserID:      userID,
+		Content:     *req.Content,
+		BaseVersion: document.Version,
+		SavedAt:     now,
+		ExpiresAt:   now.Add(s.draftTTL),
+	}
+	if err := s.drafts.SaveDraft(ctx, draft); err != nil {
+		return nil, err
+	}
+
+	return draft, nil
+}
+
+
+func(s *documentService)	GetDraft(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*model.DocumentDraft, error){
+	if _, err := s.GetDocumentByID(ctx, id, userID, false, "", ""); err != nil {
+		return nil, err
+	}
+
+	return s.drafts.GetDraft(ctx, id, userID)
+}
+
+
+// DiscardDraft needs no access, so users can drop drafts of documents they
+// lost access to
+func(s *documentService)	DiscardDraft(ctx context.Context, id uuid.UUID, userID uuid.UUID) error{
+	return s.drafts.DeleteDraft(ctx, id, userID)
+}
+
+
+func(s *documentService)	GetLock(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*model.DocumentLock, error){
+	if _, err := s.GetDocumentByID(ctx, id, userID, false, "", ""); err != nil {
+		return nil, err
+	}
+
+	return s.locks.GetLock(ctx, id)
+}
+
+
+func(s *documentService)	PinDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID) error{
+	if _, err := s.GetDocumentByID(ctx, id, userID, false, "", ""); err != nil {
+		return err
+	}
+
+	return s.docRepo.PinDocument(ctx, userID, id)
+}
+
+
+// UnpinDocument needs no access, so users can unpin documents they lost
+// access to
+func(s *documentService)	UnpinDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID) error{
+	return s.docRepo.UnpinDocument(ctx, userID, id)
+}
+
+
+// SetTerms is reserved to the owner. Changing the text asks everyone to
+// accept again; earlier acceptances are kept.
+func(s *documentService)	SetTerms(ctx context.Context, id uuid.UUID, userID uuid.UUID, req model.DocumentTermsRequest) (*model.DocumentTerms, error){
+	document, err := s.docRepo.GetDocumentByID(ctx, id)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document by ID", zap.Error(err))
+		return nil, err
+	}
+
+	if document == nil {
+		return nil, ErrDocumentNotFound
+	}
+
+	if err := s.checkOwner(ctx, document, userID); err != nil {
+		return nil, err
+	}
+
+	var terms, termsHash *string
+	if req.Terms != nil && strings.TrimSpace(*req.Terms) != "" {
+		hash := model.HashTerms(*req.Terms)
+		terms, termsHash = req.Terms, &hash
+	}
+
+	if err := s.docRepo.SetDocumentTerms(ctx, id, terms, termsHash); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to set document terms", zap.Error(err))
+		return nil, err
+	}
+
+	document.Terms, document.TermsHash = terms, termsHash
+	s.invalidatePublic(ctx, document, document.IsPublic)
+
+	return s.termsOf(ctx, document, userID)
+}
+
+
+// GetTerms needs read access but not the terms themselves accepted, so
+// readers can be shown what to accept
+func(s *documentService)	GetTerms(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*model.DocumentTerms, error){
+	document, err := s.getReadableDocument(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.termsOf(ctx, document, userID)
+}
+
+
+func(s *documentService)	AcceptTerms(ctx context.Context, id uuid.UUID, userID uuid.UUID, ipAddress string) (*model.DocumentTerms, error){
+	document, err := s.getReadableDocument(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	required, err := s.requiresTerms(ctx, document, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if required {
+		err := s.docRepo.AcceptTerms(ctx, &model.DocumentTermsAcceptance{
+			DocumentID: id,
+			UserID:     userID,
+			TermsHash:  *document.TermsHash,
+			IPAddress:  ipAddress,
+			AcceptedAt: time.Now(),
+		})
+		if err != nil {
+			logging.FromContext(ctx, s.logger).Error("Failed to record terms acceptance", zap.Error(err))
+			return nil, err
+		}
+	}
+
+	return s.termsOf(ctx, document, userID)
+}
+
+
+func(s *documentService)	GetTermsAcceptances(ctx context.Context, id uuid.UUID, userID uuid.UUID) ([]*model.TermsAcceptanceResponse, error){
+	document, err := s.docRepo.GetDocumentByID(ctx, id)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document by ID", zap.Error(err))
+		return nil, err
+	}
+
+	if document == nil {
+		return nil, ErrDocumentNotFound
+	}
+
+	if err := s.checkOwner(ctx, document, userID); err != nil {
+		return nil, err
+	}
+
+	acceptances, err := s.docRepo.GetTermsAcceptances(ctx, id)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get terms acceptances", zap.Error(err))
+		return nil, err
+	}
+
+	response := make([]*model.TermsAcceptanceResponse, 0, len(acceptances))
+	for _, acceptance := range acceptances {
+		resp := &model.TermsAcceptanceResponse{
+			TermsHash:  acceptance.TermsHash,
+			Current:    document.TermsHash != nil && acceptance.TermsHash == *document.TermsHash,
+			IPAddress:  acceptance.IPAddress,
+			AcceptedAt: acceptance.AcceptedAt,
+		}
+		resp.User.ID = acceptance.User.ID
+		resp.User.Name = acceptance.User.Name
+		resp.User.Email = acceptance.User.Email
+		response = append(response, resp)
+	}
+
+	return response, nil
+}
+
+
+// getReadableDocument checks read access without checking the terms
+func (s *documentService) getReadableDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*model.Document, error) {
+	document, err := s.docRepo.GetDocumentByID(ctx, id)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document by ID", zap.Error(err))
+		return nil, err
+	}
+
+	if document == nil {
+		return nil, ErrDocumentNotFound
+	}
+
+	canAccess, err := s.docRepo.CanUserAccess(ctx, id, userID, model.PermissionRead)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to check user access", zap.Error(err))
+		return nil, err
+	}
+	if !canAccess {
+		return nil, ErrUnauthorized
+	}
+
+	return document, nil
+}
+
+// getPlaintextDocument is GetDocumentByID for features that read the
+// content, which the server cannot do for encrypted documents
+func (s *documentService) getPlaintextDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*model.Document, error) {
+	document, err := s.GetDocumentByID(ctx, id, userID, false, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	if document.Encrypted {
+		return nil, ErrEncryptedDocument
+	}
+
+	return document, nil
+}
+
+// getOwnedDocument loads a document only its owners may act on
+func (s *documentService) getOwnedDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*model.Document, error) {
+	document, err := s.docRepo.GetDocumentByID(ctx, id)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document by ID", zap.Error(err))
+		return nil, err
+	}
+
+	if document == nil {
+		return nil, ErrDocumentNotFound
+	}
+
+	if err := s.checkOwner(ctx, document, userID); err != nil {
+		return nil, err
+	}
+
+	return document, nil
+}
+
+// checkOwner returns ErrUnauthorized unless userID is the document's owner
+// or one of its co-owners
+func (s *documentService) checkOwner(ctx context.Context, document *model.Document, userID uuid.UUID) error {
+	if document.OwnerID == userID {
+		return nil
+	}
+
+	owner, err := s.docRepo.IsDocumentOwner(ctx, document.ID, userID)
+	if err != nil {
+		return err
+	}
+	if !owner {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+// requiresTerms reports whether userID must accept the document's terms;
+// owners, co-owners included, never do
+func (s *documentService) requiresTerms(ctx context.Context, document *model.Document, userID uuid.UUID) (bool, error) {
+	if !document.RequiresTerms(userID) {
+		return false, nil
+	}
+
+	owner, err := s.docRepo.IsDocumentOwner(ctx, document.ID, userID)
+	if err != nil {
+		return false, err
+	}
+	return !owner, nil
+}
+
+// termsOf describes the document's terms to userID
+func (s *documentService) termsOf(ctx context.Context, document *model.Document, userID uuid.UUID) (*model.DocumentTerms, error) {
+	required, err := s.requiresTerms(ctx, document, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	terms := &model.DocumentTerms{
+		DocumentID: document.ID,
+		Terms:      document.Terms,
+		TermsHash:  document.TermsHash,
+		Accepted:   !required,
+	}
+	if terms.Accepted {
+		return terms, nil
+	}
+
+	acceptance, err := s.docRepo.GetTermsAcceptance(ctx, document.ID, userID, *document.TermsHash)
+	if err != nil {
+		return nil, err
+	}
+	if acceptance != nil {
+		terms.Accepted = true
+		terms.AcceptedAt = &acceptance.AcceptedAt
+	}
+	return terms, nil
+}
+
+// checkTerms refuses to show the content to userID until they accept the
+// document's terms
+func (s *documentService) checkTerms(ctx context.Context, document *model.Document, userID uuid.UUID) error {
+	required, err := s.requiresTerms(ctx, document, userID)
+	if err != nil || !required {
+		return err
+	}
+
+	acceptance, err := s.docRepo.GetTermsAcceptance(ctx, document.ID, userID, *document.TermsHash)
+	if err != nil {
+		return err
+	}
+	if acceptance == nil {
+		return ErrTermsNotAccepted
+	}
+	return nil
+}
+
+
+// SubscribeToSection needs read access, and the heading must exist in the
+// current outline
+func(s *documentService)	SubscribeToSection(ctx context.Context, id uuid.UUID, userID uuid.UUID, anchor string) (*model.SectionSubscription, error){
+	document, err := s.getPlaintextDocument(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	found := false
+	for _, heading := range s.outlineOf(document) {
+		if heading.Anchor == anchor {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, ErrSectionNotFound
+	}
+
+	subscription := &model.SectionSubscription{
+		DocumentID: id,
+		UserID:     userID,
+		Anchor:     anchor,
+		CreatedAt:  time.Now(),
+	}
+	if err := s.docRepo.AddSectionSubscription(ctx, subscription); err != nil {
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+
+// UnsubscribeFromSection needs no access, like UnpinDocument
+func(s *documentService)	UnsubscribeFromSection(ctx context.Context, id uuid.UUID, userID uuid.UUID, anchor string) error{
+	return s.docRepo.RemoveSectionSubscription(ctx, id, userID, anchor)
+}
+
+
+// GetSectionSubscriptions lists the caller's subscriptions to the document
+func(s *documentService)	GetSectionSubscriptions(ctx context.Context, id uuid.UUID, userID uuid.UUID) ([]*model.SectionSubscription, error){
+	return s.docRepo.GetSectionSubscriptions(ctx, id, &userID)
+}
+
+
+func(s *documentService)	PublishSnapshot(ctx context.Context, id uuid.UUID, userID uuid.UUID, req model.SnapshotPublishRequest) (*model.DocumentSnapshot, error){
+	document, err := s.getEditableDocument(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Snapshots are public and rendered, neither of which ciphertext allows
+	if document.Encrypted {
+		return nil, ErrEncryptedDocument
+	}
+
+	version := document.Version
+	if req.Version != nil {
+		version = *req.Version
+	}
+	if version > document.Version {
+		return nil, ErrVersionNotFound
+	}
+
+	existing, err := s.docRepo.GetSnapshotByVersion(ctx, id, version)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return existing, nil
+	}
+
+	content := document.Content
+	if version < document.Version {
+		// Metadata-only updates bump the version without recording history
+		history, err := s.docRepo.GetDocumentHistoryAsOf(ctx, id, version)
+		if err != nil {
+			logging.FromContext(ctx, s.logger).Error("Failed to get document history as of version", zap.Error(err))
+			return nil, err
+		}
+		if history == nil {
+			return nil, ErrVersionNotFound
+		}
+		content = history.Content
+	}
+
+	// Anchors are kept where the version has the same headings as now
+	html, err := outline.RenderHTML(content, outline.Rebase(s.outlineOf(document), content))
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to render document", zap.Error(err))
+		return nil, err
+	}
+
+	snapshot := &model.DocumentSnapshot{
+		DocumentID:    id,
+		Version:       version,
+		Title:         document.Title,
+		Content:       content,
+		HTML:          html,
+		PublishedByID: userID,
+		CreatedAt:     time.Now(),
+	}
+
+	if err := s.docRepo.CreateSnapshot(ctx, snapshot); err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+
+func(s *documentService)	GetSnapshots(ctx context.Context, id uuid.UUID, userID uuid.UUID) ([]*model.DocumentSnapshot, error){
+	if _, err := s.GetDocumentByID(ctx, id, userID, false, "", ""); err != nil {
+		return nil, err
+	}
+
+	return s.docRepo.GetSnapshots(ctx, id)
+}
+
+
+func(s *documentService)	DeleteSnapshot(ctx context.Context, id uuid.UUID, snapshotID uuid.UUID, userID uuid.UUID) error{
+	if _, err := s.getEditableDocument(ctx, id, userID); err != nil {
+		return err
+	}
+
+	snapshot, err := s.docRepo.GetSnapshotByID(ctx, snapshotID)
+	if err != nil {
+		return err
+	}
+	if snapshot == nil || snapshot.DocumentID != id {
+		return ErrSnapshotNotFound
+	}
+
+	if err := s.docRepo.DeleteSnapshot(ctx, snapshotID); err != nil {
+		return err
+	}
+
+	if s.purger != nil {
+		ctx = logging.Detach(ctx)
+		go func() {
+			if err := s.purger.Purge(ctx, []string{cdn.SnapshotKey(snapshotID)}); err != nil {
+				logging.FromContext(ctx, s.logger).Warn("Failed to purge snapshot from CDN",
+					zap.String("snapshot_id", snapshotID.String()),
+					zap.Error(err))
+			}
+		}()
+	}
+
+	return nil
+}
+
+
+func(s *documentService)	GetPublicSnapshot(ctx context.Context, snapshotID uuid.UUID, ipAddress, userAgent string) (*model.PublicSnapshot, cdn.Policy, error){
+	// Snapshots never change, so only deleting one needs a purge
+	policy := cdn.Policy{MaxAge: s.publicCacheTTL}
+	if s.purger != nil {
+		policy.SharedMaxAge = s.cdnTTL
+		policy.Keys = []string{cdn.SnapshotKey(snapshotID), cdn.AllDocumentsKey}
+	}
+
+	snapshot, err := s.docRepo.GetSnapshotByID(ctx, snapshotID)
+	if err != nil {
+		return nil, policy, err
+	}
+	if snapshot == nil {
+		return nil, policy, ErrSnapshotNotFound
+	}
+
+	document, err := s.docRepo.GetDocumentByID(ctx, snapshot.DocumentID)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document by ID", zap.Error(err))
+		return nil, policy, err
+	}
+
+	// Anonymous readers cannot accept terms
+	if document == nil || document.TermsHash != nil {
+		return nil, policy, ErrSnapshotNotFound
+	}
+
+	_ = s.analyticsRepo.RecordDocumentView(ctx, document.ID, uuid.Nil, ipAddress, userAgent)
+
+	return &model.PublicSnapshot{
+		ID:          snapshot.ID,
+		DocumentID:  snapshot.DocumentID,
+		Version:     snapshot.Version,
+		Title:       snapshot.Title,
+		Content:     snapshot.Content,
+		HTML:        snapshot.HTML,
+		PublishedAt: snapshot.CreatedAt,
+	}, policy, nil
+}
+
+
+// checkLock refuses a save by userID while another user holds the lock
+func (s *documentService) checkLock(ctx context.Context, id uuid.UUID, userID uuid.UUID) error {
+	lock, err := s.locks.GetLock(ctx, id)
+	if err != nil {
+		return err
+	}
+	if lock != nil && lock.UserID != userID {
+		return &LockedError{Lock: lock}
+	}
+	return nil
+}
+
+
+func(s *documentService)	GetBacklinks(ctx context.Context, id uuid.UUID, userID uuid.UUID) ([]*model.Backlink, error){
+	if _, err := s.GetDocumentByID(ctx, id, userID, false, "", ""); err != nil {
+		return nil, err
+	}
+
+	documents, err := s.docRepo.GetBacklinks(ctx, id, userID)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get backlinks", zap.Error(err))
+		return nil, err
+	}
+
+	backlinks := make([]*model.Backlink, 0, len(documents))
+	for _, document := range documents {
+		backlinks = append(backlinks, &model.Backlink{
+			DocumentID: document.ID,
+			Title:      document.Title,
+			State:      document.State,
+			UpdatedAt:  document.UpdatedAt,
+		})
+	}
+
+	return backlinks, nil
+}
+
+
+func(s *documentService)	CheckPermissions(ctx context.Context, userID uuid.UUID, documentIDs []uuid.UUID) ([]*model.EffectivePermission, error){
+	found, err := s.docRepo.GetEffectivePermissions(ctx, documentIDs, userID)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get effective permissions", zap.Error(err))
+		return nil, err
+	}
+
+	byDocument := make(map[uuid.UUID]model.Permission, len(found))
+	for _, permission := range found {
+		byDocument[permission.DocumentID] = permission.Permission.Normalize()
+	}
+
+	permissions := make([]*model.EffectivePermission, 0, len(documentIDs))
+	seen := make(map[uuid.UUID]bool, len(documentIDs))
+	for _, id := range documentIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		permission, ok := byDocument[id]
+		if !ok {
+			permission = model.PermissionNone
+		}
+		permissions = append(permissions, &model.EffectivePermission{DocumentID: id, Permission: permission})
+	}
+
+	return permissions, nil
+}
+
+
+// updateLinks records the documents the content links to, resolving
+// aliases to their current documents. Failures are logged; links catch up
+// on the next save. Links in encrypted content cannot be seen.
+func (s *documentService) updateLinks(ctx context.Context, document *model.Document) {
+	if document.Encrypted {
+		return
+	}
+
+	refs := links.Parse(document.Content)
+
+	targets := make([]uuid.UUID, 0, len(refs.IDs)+len(refs.Aliases))
+	for _, id := range refs.IDs {
+		if id != document.ID {
+			targets = append(targets, id)
+		}
+	}
+	for _, alias := range refs.Aliases {
+		target, err := s.docRepo.GetDocumentByAlias(ctx, alias)
+		if err != nil {
+			logging.FromContext(ctx, s.logger).Warn("Failed to resolve linked alias", zap.String("alias", alias), zap.Error(err))
+			continue
+		}
+		if target != nil && target.ID != document.ID {
+			targets = append(targets, target.ID)
+		}
+	}
+
+	if err := s.docRepo.ReplaceDocumentLinks(ctx, document.ID, targets); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to update document links", zap.Error(err))
+	}
+}
+
+
+func(s *documentService)	LintDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*lint.Report, error){
+	document, err := s.getPlaintextDocument(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.lintRules.Check(document.Content, s.outlineOf(document)), nil
+}
+
+
+func(s *documentService)	SearchDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID, query find.Query) (*find.Result, error){
+	document, err := s.getPlaintextDocument(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return find.Search(document.Content, s.outlineOf(document), query), nil
+}
+
+
+func(s *documentService)	GetOutline(ctx context.Context, id uuid.UUID, userID uuid.UUID) ([]outline.Heading, error){
+	document, err := s.getPlaintextDocument(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.outlineOf(document), nil
+}
+
+
+func(s *documentService)	RenderHTML(ctx context.Context, id uuid.UUID, userID uuid.UUID) (string, error){
+	document, err := s.getPlaintextDocument(ctx, id, userID)
+	if err != nil {
+		return "", err
+	}
+
+	html, err := outline.RenderHTML(document.Content, s.outlineOf(document))
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to render document", zap.Error(err))
+		return "", err
+	}
+
+	return html, nil
+}
+
+
+func(s *documentService)	GetPublicDocument(ctx context.Context, id uuid.UUID, ipAddress, userAgent string) (*model.PublicDocument, cdn.Policy, error){
+	public, policy, err := s.loadPublicDocument(ctx, id)
+	if err != nil {
+		return nil, policy, err
+	}
+
+	_ = s.analyticsRepo.RecordDocumentView(ctx, id, uuid.Nil, ipAddress, userAgent)
+
+	return public, policy, nil
+}
+
+func(s *documentService)	GetPublicDocumentByAlias(ctx context.Context, alias string, ipAddress, userAgent string) (*model.PublicDocument, cdn.Policy, string, error){
+	document, err := s.docRepo.GetDocumentByAlias(ctx, alias)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document by alias", zap.Error(err))
+		return nil, cdn.Policy{}, "", err
+	}
+
+	if document != nil {
+		public, policy, err := s.GetPublicDocument(ctx, document.ID, ipAddress, userAgent)
+		if err == ErrDocumentNotFound {
+			err = ErrAliasNotFound
+		}
+		return public, policy, "", err
+	}
+
+	redirect, err := s.docRepo.GetAliasRedirect(ctx, alias)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get alias redirect", zap.Error(err))
+		return nil, cdn.Policy{}, "", err
+	}
+
+	if redirect == nil {
+		return nil, cdn.Policy{}, "", ErrAliasNotFound
+	}
+
+	document, err = s.docRepo.GetDocumentByID(ctx, redirect.DocumentID)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document by ID", zap.Error(err))
+		return nil, cdn.Policy{}, "", err
+	}
+
+	if document == nil || !document.IsPublic || document.TermsHash != nil {
+		return nil, cdn.Policy{}, "", ErrAliasNotFound
+	}
+
+	if document.Alias != nil {
+		return nil, cdn.Policy{}, "/api/v1/public/d/" + *document.Alias, nil
+	}
+	return nil, cdn.Policy{}, "/api/v1/public/documents/" + document.ID.String(), nil
+}
+
+// loadPublicDocument reads a public document through the cache
+func (s *documentService) loadPublicDocument(ctx context.Context, id uuid.UUID) (*model.PublicDocument, cdn.Policy, error) {
+	policy := cdn.Policy{MaxAge: s.publicCacheTTL}
+	if s.purger != nil {
+		policy.SharedMaxAge = s.cdnTTL
+		policy.Keys = []string{cdn.DocumentKey(id), cdn.AllDocumentsKey}
+	}
+
+	key := publicCacheKey(id)
+	if s.publicCacheTTL > 0 {
+		if data, err := s.redis.Get(ctx, key).Bytes(); err == nil {
+			var public model.PublicDocument
+			if err := json.Unmarshal(data, &public); err == nil {
+				return &public, policy, nil
+			}
+		} else if !errors.Is(err, redis.Nil) {
+			logging.FromContext(ctx, s.logger).Warn("Failed to read public document cache", zap.Error(err))
+		}
+	}
+
+	document, err := s.docRepo.GetDocumentByID(ctx, id)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document by ID", zap.Error(err))
+		return nil, policy, err
+	}
+
+	// Anonymous readers cannot accept terms
+	if document == nil || !document.IsPublic || document.TermsHash != nil {
+		return nil, policy, ErrDocumentNotFound
+	}
+
+	html, err := outline.RenderHTML(document.Content, s.outlineOf(document))
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to render document", zap.Error(err))
+		return nil, policy, err
+	}
+
+	public := &model.PublicDocument{
+		ID:           document.ID,
+		Alias:        document.Alias,
+		Title:        document.Title,
+		Content:      document.Content,
+		HTML:         html,
+		Version:      document.Version,
+		ThumbnailURL: thumbnail.PublicURL(document.ID, document.Version),
+		UpdatedAt:    document.UpdatedAt,
+	}
+
+	if s.publicCacheTTL > 0 {
+		if data, err := json.Marshal(public); err == nil {
+			if err := s.redis.Set(ctx, key, data, s.publicCacheTTL).Err(); err != nil {
+				logging.FromContext(ctx, s.logger).Warn("Failed to cache public document", zap.Error(err))
+			}
+		}
+	}
+
+	return public, policy, nil
+}
+
+
+func(s *documentService)	GetPublishedDocument(ctx context.Context, id uuid.UUID) (*model.PublishedDocument, cdn.Policy, error){
+	policy := cdn.Policy{MaxAge: s.publicCacheTTL}
+	if s.purger != nil {
+		policy.SharedMaxAge = s.cdnTTL
+		policy.Keys = []string{cdn.DocumentKey(id), cdn.AllDocumentsKey}
+	}
+
+	key := publishedCacheKey(id)
+	if s.publicCacheTTL > 0 {
+		if data, err := s.redis.Get(ctx, key).Bytes(); err == nil {
+			var published model.PublishedDocument
+			if err := json.Unmarshal(data, &published); err == nil {
+				return &published, policy, nil
+			}
+		} else if !errors.Is(err, redis.Nil) {
+			logging.FromContext(ctx, s.logger).Warn("Failed to read published document cache", zap.Error(err))
+		}
+	}
+
+	document, err := s.docRepo.GetDocumentByID(ctx, id)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document by ID", zap.Error(err))
+		return nil, policy, err
+	}
+
+	// Anonymous readers cannot accept terms
+	if document == nil || !document.IsPublic || document.TermsHash != nil {
+		return nil, policy, ErrDocumentNotFound
+	}
+
+	headings := s.outlineOf(document)
+	content, err := blocks.Parse(document.Content, headings)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to parse document blocks", zap.Error(err))
+		return nil, policy, err
+	}
+
+	authors, err := s.docRepo.GetDocumentAuthors(ctx, document.ID, document.OwnerID)
+	if err != nil {
+		return nil, policy, err
+	}
+	for i := range authors {
+		authors[i].Role = model.AuthorContributor
+		if authors[i].ID == document.OwnerID {
+			authors[i].Role = model.AuthorOwner
+		}
+	}
+
+	if headings == nil {
+		headings = []outline.Heading{}
+	}
+
+	published := &model.PublishedDocument{
+		Format:       model.PublishedFormat,
+		ID:           document.ID,
+		Alias:        document.Alias,
+		Title:        document.Title,
+		Version:      document.Version,
+		ThumbnailURL: thumbnail.PublicURL(document.ID, document.Version),
+		CreatedAt:    document.CreatedAt,
+		UpdatedAt:    document.UpdatedAt,
+		Authors:      authors,
+		Outline:      headings,
+		Blocks:       content,
+	}
+
+	if s.publicCacheTTL > 0 {
+		if data, err := json.Marshal(published); err == nil {
+			if err := s.redis.Set(ctx, key, data, s.publicCacheTTL).Err(); err != nil {
+				logging.FromContext(ctx, s.logger).Warn("Failed to cache published document", zap.Error(err))
+			}
+		}
+	}
+
+	return published, policy, nil
+}
+
+
+func(s *documentService)	GetDocumentStats(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*model.DocumentStats, error){
+	document, err := s.getPlaintextDocument(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	key := statsCacheKey(document.ID, document.Version)
+	if data, err := s.redis.Get(ctx, key).Bytes(); err == nil {
+		var stats model.DocumentStats
+		if err := json.Unmarshal(data, &stats); err == nil {
+			return &stats, nil
+		}
+	} else if !errors.Is(err, redis.Nil) {
+		logging.FromContext(ctx, s.logger).Warn("Failed to read document stats cache", zap.Error(err))
+	}
+
+	history, err := s.docRepo.GetDocumentHistoryContents(ctx, document.ID)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document history contents", zap.Error(err))
+		return nil, err
+	}
+
+	words := countWords(document.Content)
+	stats := &model.DocumentStats{
+		DocumentID:         document.ID,
+		Version:            document.Version,
+		Words:              words,
+		Characters:         utf8.RuneCountInString(document.Content),
+		ReadingTimeMinutes: (words + wordsPerMinute - 1) / wordsPerMinute,
+		Growth:             make([]model.VersionGrowth, 0, len(history)),
+	}
+
+	var previousWords, previousCharacters int
+	for _, h := range history {
+		growth := model.VersionGrowth{
+			Version:    h.Version,
+			Words:      countWords(h.Content),
+			Characters: utf8.RuneCountInString(h.Content),
+			UpdatedAt:  h.UpdatedAt,
+		}
+		growth.WordsDelta = growth.Words - previousWords
+		growth.CharactersDelta = growth.Characters - previousCharacters
+		previousWords, previousCharacters = growth.Words, growth.Characters
+
+		stats.Growth = append(stats.Growth, growth)
+	}
+
+	if data, err := json.Marshal(stats); err == nil {
+		if err := s.redis.Set(ctx, key, data, statsCacheTTL).Err(); err != nil {
+			logging.FromContext(ctx, s.logger).Warn("Failed to cache document stats", zap.Error(err))
+		}
+	}
+
+	return stats, nil
+}
+
+
+func(s *documentService)	GetDocumentStorage(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*model.DocumentStorage, error){
+	if _, err := s.GetDocumentByID(ctx, id, userID, false, "", ""); err != nil {
+		return nil, err
+	}
+
+	storage, err := s.docRepo.GetDocumentStorage(ctx, id)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document storage", zap.Error(err))
+		return nil, err
+	}
+	if storage == nil {
+		return nil, ErrDocumentNotFound
+	}
+
+	return storage, nil
+}
+
+
+func(s *documentService)	GetUserStorage(ctx context.Context, userID uuid.UUID) (*model.StorageUsage, error){
+	usage, err := s.docRepo.GetUserStorage(ctx, userID, largestDocuments)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get user storage", zap.Error(err))
+		return nil, err
+	}
+
+	return usage, nil
+}
+
+
+func(s *documentService)	ExportDocument(ctx context.Context, id uuid.UUID, userID uuid.UUID, format export.Format) (*export.File, error){
+	document, err := s.getPlaintextDocument(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	cached := s.exportCacheTTL > 0 && export.Expensive(format)
+	key := exportCacheKey(document.ID, document.Version, format)
+	if cached {
+		if file := s.cachedExport(ctx, key); file != nil {
+			s.recordSecurityEvent(ctx, siemModel.TypeDocumentExported, userID, document.ID, map[string]interface{}{
+				"format":  format,
+				"version": document.Version,
+			})
+			return file, nil
+		}
+	}
+
+	file, err := export.Render(document, s.outlineOf(document), format)
+	if err != nil {
+		if err != export.ErrUnsupportedFormat {
+			logging.FromContext(ctx, s.logger).Error("Failed to export document", zap.Error(err))
+		}
+		return nil, err
+	}
+
+	if cached && (s.exportCacheMax <= 0 || len(file.Body) <= s.exportCacheMax) {
+		_, err := s.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, key, "filename", file.Filename, "content_type", file.ContentType, "body", file.Body)
+			pipe.Expire(ctx, key, s.exportCacheTTL)
+			return nil
+		})
+		if err != nil {
+			logging.FromContext(ctx, s.logger).Warn("Failed to cache document export", zap.Error(err))
+		}
+	}
+
+	s.recordSecurityEvent(ctx, siemModel.TypeDocumentExported, userID, document.ID, map[string]interface{}{
+		"format":  format,
+		"version": document.Version,
+	})
+	return file, nil
+}
+
+// ExportDocumentHistory is not cached: bundles are rare and every save
+// changes them
+func(s *documentService)	ExportDocumentHistory(ctx context.Context, id uuid.UUID, userID uuid.UUID, format export.Format) (*export.File, error){
+	document, err := s.getPlaintextDocument(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := s.docRepo.GetAllDocumentHistory(ctx, document.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := export.RenderHistory(document, history, format, time.Now())
+	if err != nil {
+		if err != export.ErrUnsupportedFormat {
+			logging.FromContext(ctx, s.logger).Error("Failed to export document history", zap.Error(err))
+		}
+		return nil, err
+	}
+
+	s.recordSecurityEvent(ctx, siemModel.TypeHistoryExported, userID, document.ID, map[string]interface{}{
+		"format":   format,
+		"versions": len(history),
+	})
+	return file, nil
+}
+
+func(s *documentService)	GetThumbnail(ctx context.Context, id uuid.UUID, userID uuid.UUID) ([]byte, error){
+	document, err := s.getPlaintextDocument(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.thumbnailOf(ctx, document.ID, document.Version, document.Title, document.Content)
+}
+
+
+func(s *documentService)	GetPublicThumbnail(ctx context.Context, id uuid.UUID) ([]byte, cdn.Policy, error){
+	public, policy, err := s.loadPublicDocument(ctx, id)
+	if err != nil {
+		return nil, policy, err
+	}
+
+	image, err := s.thumbnailOf(ctx, public.ID, public.Version, public.Title, public.Content)
+	return image, policy, err
+}
+
+// generateThumbnail renders the preview of a saved version in the
+// background, so list views rarely wait for one. Encrypted documents have
+// no preview.
+func (s *documentService) generateThumbnail(ctx context.Context, document *model.Document) {
+	if document.Encrypted {
+		return
+	}
+
+	id, version, title, content := document.ID, document.Version, document.Title, document.Content
+
+	ctx = logging.Detach(ctx)
+	go func() {
+		_, _ = s.thumbnailOf(ctx, id, version, title, content)
+	}()
+}
+
+// thumbnailOf returns the cached preview of a version, rendering and caching
+// it on a miss
+func (s *documentService) thumbnailOf(ctx context.Context, id uuid.UUID, version int, title, content string) ([]byte, error) {
+	key := thumbnailCacheKey(id, version)
+	if image, err := s.redis.Get(ctx, key).Bytes(); err == nil {
+		return image, nil
+	} else if !errors.Is(err, redis.Nil) {
+		logging.FromContext(ctx, s.logger).Warn("Failed to read thumbnail cache", zap.Error(err))
+	}
+
+	image, err := thumbnail.Render(title, content)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to render thumbnail",
+			zap.String("document_id", id.String()),
+			zap.Error(err))
+		return nil, err
+	}
+
+	if err := s.redis.Set(ctx, key, image, thumbnailCacheTTL).Err(); err != nil {
+		logging.FromContext(ctx, s.logger).Warn("Failed to cache thumbnail", zap.Error(err))
+	}
+
+	return image, nil
+}
+
+
+// cachedExport returns the export stored under key, or nil on a miss. Every
+// save bumps the version, so entries never go stale; they only expire.
+func (s *documentService) cachedExport(ctx context.Context, key string) *export.File {
+	fields, err := s.redis.HGetAll(ctx, key).Result()
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Warn("Failed to read document export cache", zap.Error(err))
+		return nil
+	}
+
+	body, ok := fields["body"]
+	if !ok {
+		return nil
+	}
+
+	return &export.File{
+		Filename:    fields["filename"],
+		ContentType: fields["content_type"],
+		Body:        []byte(body),
+	}
+}
+
+
+func(s *documentService)	SetReviewDate(ctx context.Context, id uuid.UUID, userID uuid.UUID, req model.DocumentReviewRequest) (*model.Document, error){
+	document, err := s.docRepo.GetDocumentByID(ctx, id)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document by ID", zap.Error(err))
+		return nil, err
+	}
+
+	if document == nil {
+		return nil, ErrDocumentNotFound
+	}
+
+	canWrite, err := s.docRepo.CanUserAccess(ctx, id, userID, model.PermissionWrite)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to check user access", zap.Error(err))
+		return nil, err
+	}
+	if !canWrite {
+		return nil, ErrUnauthorized
+	}
+
+	if err := s.docRepo.SetDocumentReview(ctx, id, req.ReviewBy); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to set document review date", zap.Error(err))
+		return nil, err
+	}
+
+	document.ReviewBy = req.ReviewBy
+	document.StaleAt = nil
+
+	return document, nil
+}
+
+
+func(s *documentService)	FlagStaleDocuments(ctx context.Context) (int, error){
+	documents, err := s.docRepo.MarkStaleDocuments(ctx, time.Now())
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to flag stale documents", zap.Error(err))
+		return 0, err
+	}
+
+	for _, document := range documents {
+		event := model.DocumentStaleEvent{
+			DocumentID: document.ID,
+			Title:      document.Title,
+			ReviewBy:   *document.ReviewBy,
+			StaleAt:    *document.StaleAt,
+		}
+
+		s.webhooks.Dispatch(ctx, document.OwnerID, webhookModel.EventDocumentStale, event)
+		if err := s.realtime.NotifyUser(ctx, document.OwnerID, string(webhookModel.EventDocumentStale), event); err != nil {
+			logging.FromContext(ctx, s.logger).Warn("Failed to notify owner of stale document",
+				zap.String("document_id", document.ID.String()),
+				zap.Error(err))
+		}
+	}
+
+	return len(documents), nil
+}
+
+
+// SetExpiry is reserved to the owner, since an expired document may be
+// deleted
+func(s *documentService)	SetExpiry(ctx context.Context, id uuid.UUID, userID uuid.UUID, req model.DocumentExpiryRequest) (*model.Document, error){
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, ErrExpiryInPast
+	}
+	if req.Action == "" {
+		req.Action = model.ExpiryArchive
+	}
+
+	document, err := s.docRepo.GetDocumentByID(ctx, id)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document by ID", zap.Error(err))
+		return nil, err
+	}
+
+	if document == nil {
+		return nil, ErrDocumentNotFound
+	}
+
+	if err := s.checkOwner(ctx, document, userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.docRepo.SetDocumentExpiry(ctx, id, req.ExpiresAt, req.Action); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to set document expiry", zap.Error(err))
+		return nil, err
+	}
+
+	document.ExpiresAt = req.ExpiresAt
+	document.ExpiryAction = req.Action
+	document.ExpiryNotifiedAt = nil
+
+	return document, nil
+}
+
+
+func(s *documentService)	ExpireDocuments(ctx context.Context) (int, int, error){
+	now := time.Now()
+
+	expiring, err := s.docRepo.MarkExpiringDocuments(ctx, now, now.Add(s.expiryNotice))
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to mark expiring documents", zap.Error(err))
+		return 0, 0, err
+	}
+
+	for _, document := range expiring {
+		event := model.DocumentExpiringEvent{
+			DocumentID: document.ID,
+			Title:      document.Title,
+			ExpiresAt:  *document.ExpiresAt,
+			Action:     document.ExpiryAction,
+		}
+
+		s.webhooks.Dispatch(ctx, document.OwnerID, webhookModel.EventDocumentExpiring, event)
+		if err := s.realtime.NotifyUser(ctx, document.OwnerID, string(webhookModel.EventDocumentExpiring), event); err != nil {
+			logging.FromContext(ctx, s.logger).Warn("Failed to notify owner of expiring document",
+				zap.String("document_id", document.ID.String()),
+				zap.Error(err))
+		}
+	}
+
+	expired, err := s.docRepo.ClaimExpiredDocuments(ctx, now)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to claim expired documents", zap.Error(err))
+		return len(expiring), 0, err
+	}
+
+	count := 0
+	for _, document := range expired {
+		if err := s.expireDocument(ctx, document); err != nil {
+			logging.FromContext(ctx, s.logger).Error("Failed to expire document",
+				zap.String("document_id", document.ID.String()),
+				zap.Error(err))
+			continue
+		}
+		count++
+	}
+
+	return len(expiring), count, nil
+}
+
+// expireDocument applies a claimed document's expiry action. Events are sent
+// with a nil actor, since no user made the change.
+func (s *documentService) expireDocument(ctx context.Context, document *model.Document) error {
+	if document.ExpiryAction == model.ExpiryDelete {
+		if err := s.docRepo.DeleteDocument(ctx, document.ID); err != nil {
+			return err
+		}
+
+		s.invalidatePublic(ctx, document, document.IsPublic)
+		s.dispatchEvent(ctx, document, webhookModel.EventDocumentDeleted, uuid.Nil)
+		return nil
+	}
+
+	if document.State == model.StateArchived {
+		return nil
+	}
+
+	now := time.Now()
+	if err := s.docRepo.SetDocumentState(ctx, document.ID, model.StateArchived, &now); err != nil {
+		return err
+	}
+
+	s.webhooks.Dispatch(ctx, document.OwnerID, webhookModel.EventDocumentState, model.DocumentStateEvent{
+		DocumentID: document.ID,
+		Title:      document.Title,
+		From:       document.State,
+		To:         model.StateArchived,
+		ActorID:    uuid.Nil,
+		ChangedAt:  now,
+	})
+	return nil
+}
+
+
+func(s *documentService)	GetDocumentHistory(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, filter model.HistoryFilter, page, perPage int) ([]*model.DocumentHistoryResponse, int64, error){
+	if _, err := s.GetDocumentByID(ctx, documentID, userID, false, "", ""); err != nil {
+		return nil, 0, err
+	}
+
+	history, total, err := s.docRepo.GetDocumentHistory(ctx, documentID, filter, page, perPage)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document history", zap.Error(err))
+		return nil, 0, err
+	}
+
+	response, err := s.historyResponses(ctx, documentID, userID, history)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return response, total, nil
+}
+
+
+func(s *documentService)	GetDocumentHistoryPage(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, filter model.HistoryFilter, cursor *int, perPage int) ([]*model.DocumentHistoryResponse, *int, error){
+	if _, err := s.GetDocumentByID(ctx, documentID, userID, false, "", ""); err != nil {
+		return nil, nil, err
+	}
+
+	if perPage < 1 {
+		perPage = 20
+	}
+
+	// One more than asked for tells whether there is a next page
+	history, err := s.docRepo.GetDocumentHistoryBefore(ctx, documentID, filter, cursor, perPage+1)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document history", zap.Error(err))
+		return nil, nil, err
+	}
+
+	var next *int
+	if len(history) > perPage {
+		history = history[:perPage]
+		next = &history[perPage-1].Version
+	}
+
+	response, err := s.historyResponses(ctx, documentID, userID, history)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return response, next, nil
+}
+
+// historyResponses describes history to userID, with the reactions on each
+// version
+func (s *documentService) historyResponses(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, history []*model.DocumentHistory) ([]*model.DocumentHistoryResponse, error) {
+	versions := make([]int, 0, len(history))
+	for _, h := range history {
+		versions = append(versions, h.Version)
+	}
+
+	counts, err := s.docRepo.GetReactionCounts(ctx, documentID, versions, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	reactions := make(map[int][]*model.ReactionCount)
+	for _, count := range counts {
+		reactions[count.Version] = append(reactions[count.Version], count)
+	}
+
+	response := make([]*model.DocumentHistoryResponse, 0, len(history))
+	for _, h := range history {
+		resp := &model.DocumentHistoryResponse{
+			Version: h.Version,
+			Content: h.Content,
+			UpdatedBy: struct {
+				ID   uuid.UUID `json:"id"`
+				Name string    `json:"name"`
+			}{
+				ID:   h.UpdatedByID,
+				Name: h.UpdatedBy.Name,
+			},
+			UpdatedAt:    h.UpdatedAt,
+			MergedFromID: h.MergedFromID,
+			Summary:      h.Summary,
+			Label:        h.Label,
+			Reactions:    reactions[h.Version],
+		}
+		if resp.Reactions == nil {
+			resp.Reactions = []*model.ReactionCount{}
+		}
+		response = append(response, resp)
+	}
+	return response, nil
+}
+
+
+func(s *documentService)	RestoreDocumentVersion(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, version int) (*model.Document, error){
+	document, err := s.docRepo.GetDocumentByID(ctx, documentID)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document by ID", zap.Error(err))
+		return nil, err
+	}
+
+	if document == nil {
+		return nil, ErrDocumentNotFound
+	}
+
+	canWrite, err := s.docRepo.CanUserAccess(ctx, documentID, userID, document.State.EditPermission())
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to check user access", zap.Error(err))
+		return nil, err
+	}
+	if !canWrite {
+		return nil, ErrUnauthorized
+	}
+
+	if err := s.checkLock(ctx, documentID, userID); err != nil {
+		return nil, err
+	}
+
+	history, err := s.docRepo.GetDocumentHistoryByVersion(ctx, documentID, version)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document history by version", zap.Error(err))
+		return nil, err
+	}
+
+	if history == nil {
+		return nil, ErrVersionNotFound
+	}
+
+	content := history.Content
+	if !document.Encrypted {
+		content, err = s.runContentHooks(ctx, &document.ID, userID, document.Title, history.Content)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	oldContent, oldOutline, oldVersion := document.Content, s.outlineOf(document), document.Version
+	document.Content = content
+
+	if !document.Encrypted {
+		document.Outline = outline.Rebase(oldOutline, content)
+		if err := s.lintOnSaveCheck(document.Content, document.Outline); err != nil {
+			return nil, err
+		}
+	}
+
+	document.UpdatedAt = time.Now()
+
+	newHistory := &model.DocumentHistory{
+		DocumentID: document.ID,
+		Content: document.Content,
+		UpdatedByID: userID,
+		UpdatedAt: document.UpdatedAt,
+	}
+
+	_, err = s.docRepo.SaveContentUpdate(ctx, &model.ContentUpdate{
+		Document: document,
+		History: newHistory,
+	})
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to update document", zap.Error(err))
+		return nil, err
+	}
+
+	s.updateLinks(ctx, document)
+
+	s.invalidatePublic(ctx, document, document.IsPublic)
+	s.dispatchContentEvent(ctx, document, userID, "", oldContent, oldOutline, oldVersion)
+
+	return document, nil
+
+}
+
+
+func(s *documentService)	LabelVersion(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, version int, label string) (*model.LabeledVersion, error){
+	// Restoring by label takes the label as a path segment
+	label = strings.TrimSpace(label)
+	if label == "" || strings.Contains(label, "/") {
+		return nil, ErrInvalidLabel
+	}
+
+	if _, err := s.getEditableDocument(ctx, documentID, userID); err != nil {
+		return nil, err
+	}
+
+	labeled, err := s.docRepo.GetDocumentHistoryByLabel(ctx, documentID, label)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document history by label", zap.Error(err))
+		return nil, err
+	}
+	if labeled != nil && labeled.Version != version {
+		return nil, ErrLabelTaken
+	}
+
+	found, err := s.docRepo.SetDocumentHistoryLabel(ctx, documentID, version, &label)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to label document version", zap.Error(err))
+		return nil, err
+	}
+	if !found {
+		return nil, ErrVersionNotFound
+	}
+
+	history, err := s.docRepo.GetDocumentHistoryByVersion(ctx, documentID, version)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document history by version", zap.Error(err))
+		return nil, err
+	}
+	if history == nil {
+		return nil, ErrVersionNotFound
+	}
+
+	return &model.LabeledVersion{
+		Version:     history.Version,
+		Label:       label,
+		UpdatedByID: history.UpdatedByID,
+		UpdatedAt:   history.UpdatedAt,
+	}, nil
+}
+
+
+func(s *documentService)	RemoveVersionLabel(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, version int) error{
+	if _, err := s.getEditableDocument(ctx, documentID, userID); err != nil {
+		return err
+	}
+
+	found, err := s.docRepo.SetDocumentHistoryLabel(ctx, documentID, version, nil)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to remove document version label", zap.Error(err))
+		return err
+	}
+	if !found {
+		return ErrVersionNotFound
+	}
+
+	return nil
+}
+
+func (s *documentService) AddReaction(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, version int, emoji string) ([]*model.ReactionCount, error) {
+	if !model.ValidEmoji(emoji) {
+		return nil, ErrInvalidEmoji
+	}
+
+	if _, err := s.GetDocumentByID(ctx, documentID, userID, false, "", ""); err != nil {
+		return nil, err
+	}
+
+	found, err := s.docRepo.AddReaction(ctx, &model.Reaction{
+		DocumentID: documentID,
+		Version:    version,
+		UserID:     userID,
+		Emoji:      emoji,
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrVersionNotFound
+	}
+
+	return s.reactionChanged(ctx, documentID, userID, version, emoji, true)
+}
+
+func (s *documentService) RemoveReaction(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, version int, emoji string) ([]*model.ReactionCount, error) {
+	if _, err := s.GetDocumentByID(ctx, documentID, userID, false, "", ""); err != nil {
+		return nil, err
+	}
+
+	removed, err := s.docRepo.RemoveReaction(ctx, documentID, version, userID, emoji)
+	if err != nil {
+		return nil, err
+	}
+	if !removed {
+		return s.docRepo.GetReactionCounts(ctx, documentID, []int{version}, userID)
+	}
+
+	return s.reactionChanged(ctx, documentID, userID, version, emoji, false)
+}
+
+// reactionChanged broadcasts userID's added or removed emoji on a version
+// and returns the version's reactions
+func (s *documentService) reactionChanged(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, version int, emoji string, added bool) ([]*model.ReactionCount, error) {
+	counts, err := s.docRepo.GetReactionCounts(ctx, documentID, []int{version}, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	message := wsModel.ReactionMessage{
+		DocumentID: documentID,
+		Version:    version,
+		Emoji:      emoji,
+		Added:      added,
+	}
+	for _, count := range counts {
+		if count.Emoji == emoji {
+			message.Count = count.Count
+		}
+	}
+	message.User.ID = userID
+	if user, err := s.userRepo.FindUserByID(ctx, userID); err == nil && user != nil {
+		message.User.Name = user.Name
 	}
 
-	newHistory := &model.DocumentHistory{
+	if err := s.realtime.BroadcastReaction(ctx, message); err != nil {
+		logging.FromContext(ctx, s.logger).Warn("Failed to broadcast reaction",
+			zap.String("document_id", documentID.String()),
+			zap.Error(err))
+	}
+
+	return counts, nil
+}
+
+func(s *documentService)	GetLabeledVersions(ctx context.Context, documentID uuid.UUID, userID uuid.UUID) ([]*model.LabeledVersion, error){
+	if _, err := s.GetDocumentByID(ctx, documentID, userID, false, "", ""); err != nil {
+		return nil, err
+	}
+
+	versions, err := s.docRepo.GetLabeledVersions(ctx, documentID)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get labeled versions", zap.Error(err))
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+
+// RestoreLabeledVersion restores the version with label, see
+// RestoreDocumentVersion
+func(s *documentService)	RestoreLabeledVersion(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, label string) (*model.Document, error){
+	if _, err := s.getEditableDocument(ctx, documentID, userID); err != nil {
+		return nil, err
+	}
+
+	history, err := s.docRepo.GetDocumentHistoryByLabel(ctx, documentID, strings.TrimSpace(label))
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document history by label", zap.Error(err))
+		return nil, err
+	}
+	if history == nil {
+		return nil, ErrLabelNotFound
+	}
+
+	return s.RestoreDocumentVersion(ctx, documentID, userID, history.Version)
+}
+
+
+func(s *documentService)	CompactHistory(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, req model.HistoryCompactRequest, dryRun bool) (*model.HistoryCompaction, error){
+	document, err := s.docRepo.GetDocumentByID(ctx, documentID)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document by ID", zap.Error(err))
+		return nil, err
+	}
+
+	if document == nil {
+		return nil, ErrDocumentNotFound
+	}
+
+	if err := s.checkOwner(ctx, document, userID); err != nil {
+		return nil, err
+	}
+
+	entries, err := s.docRepo.GetDocumentHistoryEntries(ctx, documentID)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document history entries", zap.Error(err))
+		return nil, err
+	}
+
+	window := model.DefaultCompactWindow
+	if req.WindowMinutes > 0 {
+		window = time.Duration(req.WindowMinutes) * time.Minute
+	}
+
+	keep := make(map[int]bool, len(req.Keep))
+	for _, version := range req.Keep {
+		keep[version] = true
+	}
+
+	// A version is superseded when the same user saved again within the
+	// window; the run survives as its last version
+	compaction := &model.HistoryCompaction{
+		DocumentID:      documentID,
+		DryRun:          dryRun,
+		RemovedVersions: []int{},
+	}
+	for i, entry := range entries {
+		if i == 0 || i == len(entries)-1 || keep[entry.Version] || entry.Label != nil {
+			continue
+		}
+
+		next := entries[i+1]
+		if next.UpdatedByID == entry.UpdatedByID && next.UpdatedAt.Sub(entry.UpdatedAt) <= window {
+			compaction.RemovedVersions = append(compaction.RemovedVersions, entry.Version)
+			compaction.FreedBytes += entry.Bytes
+		}
+	}
+	compaction.KeptVersions = len(entries) - len(compaction.RemovedVersions)
+
+	if dryRun || len(compaction.RemovedVersions) == 0 {
+		return compaction, nil
+	}
+
+	if err := s.docRepo.DeleteDocumentHistoryVersions(ctx, documentID, compaction.RemovedVersions); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to compact document history", zap.Error(err))
+		return nil, err
+	}
+
+	// Growth per version changed without a new version to key the cache by
+	if err := s.redis.Del(ctx, statsCacheKey(document.ID, document.Version)).Err(); err != nil {
+		logging.FromContext(ctx, s.logger).Warn("Failed to evict document stats cache", zap.Error(err))
+	}
+
+	return compaction, nil
+}
+
+
+func(s *documentService)	CompareVersions(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, from int, to *int, engine diff.Engine) (*model.DocumentCompareResponse, error){
+	document, err := s.docRepo.GetDocumentByID(ctx, documentID)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document by ID", zap.Error(err))
+		return nil, err
+	}
+
+	if document == nil {
+		return nil, ErrDocumentNotFound
+	}
+
+	canAccess, err := s.docRepo.CanUserAccess(ctx, documentID, userID, model.PermissionRead)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to check user access", zap.Error(err))
+		return nil, err
+	}
+	if !canAccess {
+		return nil, ErrUnauthorized
+	}
+
+	if err := s.checkTerms(ctx, document, userID); err != nil {
+		return nil, err
+	}
+
+	if document.Encrypted {
+		return nil, ErrEncryptedDocument
+	}
+
+	oldContent, err := s.contentAsOf(ctx, document, from)
+	if err != nil {
+		return nil, err
+	}
+
+	toVersion, newContent := document.Version, document.Content
+	if to != nil {
+		toVersion = *to
+		if newContent, err = s.contentAsOf(ctx, document, toVersion); err != nil {
+			return nil, err
+		}
+	}
+
+	return &model.DocumentCompareResponse{
+		DocumentID:  document.ID,
+		FromVersion: from,
+		ToVersion:   toVersion,
+		Result:      engine.Compare(oldContent, newContent),
+	}, nil
+}
+
+
+// PreviewVersionRestore needs only read access: the preview shows nothing
+// the history does not. Content hooks and lint run as on a restore, so a
+// restore they would reject fails the preview the same way.
+func(s *documentService)	PreviewVersionRestore(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, version int, engine diff.Engine) (*model.VersionRestorePreview, error){
+	document, err := s.docRepo.GetDocumentByID(ctx, documentID)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document by ID", zap.Error(err))
+		return nil, err
+	}
+
+	if document == nil {
+		return nil, ErrDocumentNotFound
+	}
+
+	canAccess, err := s.docRepo.CanUserAccess(ctx, documentID, userID, model.PermissionRead)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to check user access", zap.Error(err))
+		return nil, err
+	}
+	if !canAccess {
+		return nil, ErrUnauthorized
+	}
+
+	if err := s.checkTerms(ctx, document, userID); err != nil {
+		return nil, err
+	}
+
+	if document.Encrypted {
+		return nil, ErrEncryptedDocument
+	}
+
+	history, err := s.docRepo.GetDocumentHistoryByVersion(ctx, documentID, version)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document history by version", zap.Error(err))
+		return nil, err
+	}
+
+	if history == nil {
+		return nil, ErrVersionNotFound
+	}
+
+	content, err := s.runContentHooks(ctx, &document.ID, userID, document.Title, history.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.lintOnSaveCheck(content, outline.Rebase(s.outlineOf(document), content)); err != nil {
+		return nil, err
+	}
+
+	return &model.VersionRestorePreview{
+		DocumentID:     document.ID,
+		Version:        version,
+		CurrentVersion: document.Version,
+		Content:        content,
+		Diff:           engine.Compare(document.Content, content),
+	}, nil
+}
+
+
+// contentAsOf returns the document content as it was at version
+func (s *documentService) contentAsOf(ctx context.Context, document *model.Document, version int) (string, error) {
+	if version < 1 || version > document.Version {
+		return "", ErrVersionNotFound
+	}
+	if version == document.Version {
+		return document.Content, nil
+	}
+
+	history, err := s.docRepo.GetDocumentHistoryAsOf(ctx, document.ID, version)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document history as of version", zap.Error(err))
+		return "", err
+	}
+	if history == nil {
+		return "", ErrVersionNotFound
+	}
+	return history.Content, nil
+}
+
+
+func(s *documentService)	ShareDocument(ctx context.Context, documentID uuid.UUID, ownerID uuid.UUID, req model.CollaboratorCreateRequest) (*model.CollaboratorResponse, error){
+	document, err := s.docRepo.GetDocumentByID(ctx, documentID)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document by ID", zap.Error(err))	
+		return nil, err
+	}
+
+	if document == nil {
+		return nil, ErrDocumentNotFound
+	}
+
+	if err := s.checkOwner(ctx, document, ownerID); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindUserByEmail(ctx, req.UserEmail)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to find user by email", zap.Error(err))
+		return nil, err
+	}
+
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	existing, err := s.docRepo.GetCollaborator(ctx, documentID, user.ID)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get collaborator", zap.Error(err))
+		return nil, err
+	}
+
+	if existing != nil {
+		return nil, ErrAlreadyCollaborator
+	}
+
+	collaborator := &model.Collaborator{
+		DocumentID: documentID,
+		UserID: user.ID,
+		User: *user,
+		Permission: req.Permission.Normalize(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	if err := s.docRepo.AddCollaborator(ctx, collaborator); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to add collaborator", zap.Error(err))
+		return nil, err
+	}
+
+	response := collaborator.ToResponse()
+	s.webhooks.Dispatch(ctx, document.OwnerID, webhookModel.EventDocumentShared, response)
+	s.recordSecurityEvent(ctx, siemModel.TypeCollaboratorAdded, ownerID, document.ID, map[string]interface{}{
+		"collaborator_id": user.ID,
+		"permission":      collaborator.Permission,
+	})
+
+	event := model.DocumentSharedEvent{
 		DocumentID: document.ID,
-		Version: document.Version,
-		Content: document.Content,
-		UpdatedByID: userID,
-		UpdatedAt: document.UpdatedAt,
+		Title:      document.Title,
+		Permission: collaborator.Permission,
+		SharedBy:   ownerID,
+		SharedAt:   collaborator.CreatedAt,
+	}
+	if err := s.realtime.NotifyUser(ctx, user.ID, string(webhookModel.EventDocumentShared), event); err != nil {
+		logging.FromContext(ctx, s.logger).Warn("Failed to notify collaborator of share",
+			zap.String("document_id", document.ID.String()),
+			zap.Error(err))
+	}
+
+	return &response, nil
+
+}
+
+
+func(s *documentService)	UpdateCollaboratorPermission(ctx context.Context, documentID uuid.UUID, ownerID uuid.UUID, userID uuid.UUID, req model.CollaboratorUpdateRequest) (*model.CollaboratorResponse, error){
+	document, err := s.docRepo.GetDocumentByID(ctx, documentID)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document by ID", zap.Error(err))
+		return nil, err
+	}
+	if document == nil {
+		return nil, ErrDocumentNotFound
+	}
+
+	if err := s.checkOwner(ctx, document, ownerID); err != nil {
+		return nil, err
+	}
+
+	collaborator, err := s.docRepo.GetCollaborator(ctx, documentID, userID)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get collaborator", zap.Error(err))
+		return nil, err
+	}
+	if collaborator == nil {
+		return nil, ErrNotCollaborator
+	}
+
+	previous := collaborator.Permission
+	collaborator.Permission = req.Permission.Normalize()
+	collaborator.UpdatedAt = time.Now()
+
+	if err := s.docRepo.UpdateCollaborator(ctx, collaborator); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to updated collaborator", zap.Error(err))
+		return nil, err
+	}
+
+	s.recordSecurityEvent(ctx, siemModel.TypeCollaboratorUpdated, ownerID, document.ID, map[string]interface{}{
+		"collaborator_id":     userID,
+		"permission":          collaborator.Permission,
+		"previous_permission": previous,
+	})
+
+	response := collaborator.ToResponse()
+	return &response, nil
+
+}
+
+
+func(s *documentService)	RemoveCollaborator(ctx context.Context, documentID uuid.UUID, ownerID uuid.UUID, userID uuid.UUID) error{
+	document, err := s.docRepo.GetDocumentByID(ctx, documentID)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document by ID", zap.Error(err))
+		return err
+	}
+	if document == nil {
+		return ErrDocumentNotFound
+	}
+
+	if err := s.checkOwner(ctx, document, ownerID); err != nil {
+		return err
+	}
+
+	// Co-owners can be removed, the owner has to hand over ownership first
+	if document.OwnerID == userID {
+		return ErrCannotRemoveOwner
 	}
 
-	if err := s.docRepo.CreateDocumentHistory(ctx, newHistory); err != nil {
-		s.logger.Error("Failed to create document history", zap.Error(err))
+	if err := s.docRepo.RemoveCollaborator(ctx, documentID, userID); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to remove collaborator", zap.Error(err))
+		return err
 	}
 
-	_ = s.analyticsRepo.RecordDocumentEdit(ctx, document.ID, userID, document.Version)
+	s.recordSecurityEvent(ctx, siemModel.TypeCollaboratorRemoved, ownerID, document.ID, map[string]interface{}{
+		"collaborator_id": userID,
+	})
+
+	// The former member may still have the document key, so owners of
+	// encrypted documents should rotate it; their wrapped copy goes now
+	if document.Encrypted {
+		if err := s.docRepo.DeleteDocumentKey(ctx, documentID, userID); err != nil {
+			return err
+		}
+	}
 
-	return document, nil
+	return nil
 
 }
 
 
-func(s *documentService)	ShareDocument(ctx context.Context, documentID uuid.UUID, ownerID uuid.UUID, req model.CollaboratorCreateRequest) (*model.CollaboratorResponse, error){
-	document, err := s.docRepo.GetDocumentByID(ctx, documentID)
+func(s *documentService)	TransferOwnership(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, req model.OwnershipTransferRequest) (*model.Document, error){
+	document, err := s.getOwnedDocument(ctx, documentID, userID)
 	if err != nil {
-		s.logger.Error("Failed to get document by ID", zap.Error(err))	
 		return nil, err
 	}
 
-	if document == nil {
-		return nil, ErrDocumentNotFound
-	}
-
-	if document.OwnerID != ownerID {
-		return nil, ErrUnauthorized
-	}
-
 	user, err := s.userRepo.FindUserByEmail(ctx, req.UserEmail)
 	if err != nil {
-		s.logger.Error("Failed to find user by email", zap.Error(err))
+		logging.FromContext(ctx, s.logger).Error("Failed to find user by email", zap.Error(err))
 		return nil, err
 	}
 
@@ -363,105 +3554,141 @@ func(s *documentService)	ShareDocument(ctx context.Context, documentID uuid.UUID
 		return nil, ErrUserNotFound
 	}
 
-	existing, err := s.docRepo.GetCollaborator(ctx, documentID, user.ID)
-	if err != nil {
-		s.logger.Error("Failed to get collaborator", zap.Error(err))
-		return nil, err
-	}
-
-	if existing != nil {
-		return nil, ErrAlreadyCollaborator
+	if user.ID == document.OwnerID {
+		return document, nil
 	}
 
-	collaborator := &model.Collaborator{
-		DocumentID: documentID,
-		UserID: user.ID,
-		User: *user,
-		Permission: req.Permission,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+	previous := document.OwnerID
+	if err := s.docRepo.TransferDocumentOwnership(ctx, documentID, previous, user.ID); err != nil {
+		return nil, err
 	}
 
-	if err := s.docRepo.AddCollaborator(ctx, collaborator); err != nil {
-		s.logger.Error("Failed to add collaborator", zap.Error(err))
+	transferred, err := s.docRepo.GetDocumentByID(ctx, documentID)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get document by ID", zap.Error(err))
 		return nil, err
 	}
+	if transferred == nil {
+		return nil, ErrDocumentNotFound
+	}
 
-	response := collaborator.ToResponse()
-	return &response, nil
+	s.invalidatePublic(ctx, transferred, transferred.IsPublic)
+	s.recordSecurityEvent(ctx, siemModel.TypeOwnershipTransferred, userID, documentID, map[string]interface{}{
+		"owner_id":          user.ID,
+		"previous_owner_id": previous,
+	})
 
+	return transferred, nil
 }
 
 
-func(s *documentService)	UpdateCollaboratorPermission(ctx context.Context, documentID uuid.UUID, ownerID uuid.UUID, userID uuid.UUID, req model.CollaboratorUpdateRequest) (*model.CollaboratorResponse, error){
-	document, err := s.docRepo.GetDocumentByID(ctx, documentID)
+func(s *documentService)	RegisterDocumentKey(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, req model.DocumentPublicKeyRequest) (*model.DocumentKey, error){
+	document, err := s.getReadableDocument(ctx, documentID, userID)
 	if err != nil {
-		s.logger.Error("Failed to get document by ID", zap.Error(err))
 		return nil, err
 	}
-	if document == nil {
-		return nil, ErrDocumentNotFound
-	}
 
-	if document.OwnerID != ownerID {
-		return nil, ErrUnauthorized
+	if !document.Encrypted {
+		return nil, ErrNotEncrypted
 	}
 
-	collaborator, err := s.docRepo.GetCollaborator(ctx, documentID, userID)
+	key, err := s.docRepo.GetDocumentKey(ctx, documentID, userID)
 	if err != nil {
-		s.logger.Error("Failed to get collaborator", zap.Error(err))
 		return nil, err
 	}
-	if collaborator == nil {
-		return nil, ErrNotCollaborator
-	}
 
-	collaborator.Permission = req.Permission
-	collaborator.UpdatedAt = time.Now()
+	now := time.Now()
+	if key == nil {
+		key = &model.DocumentKey{
+			DocumentID: documentID,
+			UserID:     userID,
+			CreatedAt:  now,
+		}
+	} else if key.PublicKey != req.PublicKey || key.Algorithm != req.Algorithm {
+		// The wrapped key was encrypted to the old public key
+		key.WrappedKey = nil
+		key.WrappedByID = nil
+	}
+	key.Algorithm = req.Algorithm
+	key.PublicKey = req.PublicKey
+	key.UpdatedAt = now
 
-	if err := s.docRepo.UpdateCollaborator(ctx, collaborator); err != nil {
-		s.logger.Error("Failed to updated collaborator", zap.Error(err))
+	if err := s.docRepo.SaveDocumentKey(ctx, key); err != nil {
 		return nil, err
 	}
 
-	response := collaborator.ToResponse()
-	return &response, nil
-
+	return key, nil
 }
 
 
-func(s *documentService)	RemoveCollaborator(ctx context.Context, documentID uuid.UUID, ownerID uuid.UUID, userID uuid.UUID) error{
-	document, err := s.docRepo.GetDocumentByID(ctx, documentID)
+func(s *documentService)	WrapDocumentKey(ctx context.Context, documentID uuid.UUID, ownerID uuid.UUID, memberID uuid.UUID, req model.DocumentWrappedKeyRequest) (*model.DocumentKey, error){
+	document, err := s.getOwnedDocument(ctx, documentID, ownerID)
 	if err != nil {
-		s.logger.Error("Failed to get document by ID", zap.Error(err))
-		return err
+		return nil, err
 	}
-	if document == nil {
-		return ErrDocumentNotFound
+
+	if !document.Encrypted {
+		return nil, ErrNotEncrypted
 	}
 
-	if document.OwnerID != ownerID {
-		return ErrUnauthorized
+	// Collaborators removed since registering must not get the key
+	canRead, err := s.docRepo.CanUserAccess(ctx, documentID, memberID, model.PermissionRead)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to check user access", zap.Error(err))
+		return nil, err
+	}
+	if !canRead {
+		return nil, ErrNotCollaborator
 	}
 
-	if document.OwnerID == userID {
-		return ErrCannotRemoveOwner
+	key, err := s.docRepo.GetDocumentKey(ctx, documentID, memberID)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, ErrKeyNotFound
 	}
 
-	if err := s.docRepo.RemoveCollaborator(ctx, documentID, userID); err != nil {
-		s.logger.Error("Failed to remove collaborator", zap.Error(err))
-		return err
+	key.WrappedKey = &req.WrappedKey
+	key.WrappedByID = &ownerID
+	key.UpdatedAt = time.Now()
+
+	if err := s.docRepo.SaveDocumentKey(ctx, key); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return key, nil
+}
 
+
+func(s *documentService)	GetDocumentKeys(ctx context.Context, documentID uuid.UUID, userID uuid.UUID) ([]*model.DocumentKey, error){
+	document, err := s.getReadableDocument(ctx, documentID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !document.Encrypted {
+		return nil, ErrNotEncrypted
+	}
+
+	return s.docRepo.GetDocumentKeys(ctx, documentID)
 }
 
 
 func(s *documentService)	GetDocumentAnalytics(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, period string) (*analyticsModel.DocumentAnalyticsResponse, error){
-	canAcess, err := s.docRepo.CanUserAccess(ctx, documentID, userID, model.PermissionRead)
+	document, err := s.docRepo.GetDocumentByID(ctx, documentID)
 	if err != nil {
-		s.logger.Error("Failed to check user access", zap.Error(err))
+		logging.FromContext(ctx, s.logger).Error("Failed to get document by ID", zap.Error(err))
+		return nil, err
+	}
+
+	if document == nil {
+		return nil, ErrDocumentNotFound
+	}
+
+	canAcess, err := s.docRepo.CanUserAccess(ctx, documentID, userID, document.AnalyticsAccessOf())
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to check user access", zap.Error(err))
 		return nil, err
 	}
 
@@ -471,13 +3698,13 @@ func(s *documentService)	GetDocumentAnalytics(ctx context.Context, documentID uu
 
 	views, err := s.analyticsRepo.GetDocumentViews(ctx, documentID, period)
 	if err != nil {
-		s.logger.Error("Failed to get document views", zap.Error(err))
+		logging.FromContext(ctx, s.logger).Error("Failed to get document views", zap.Error(err))
 		views = &analyticsModel.DocumentViewsResponse{}
 	}
 
 	edits, err := s.analyticsRepo.GetDocumentEdits(ctx, documentID, period)
 	if err != nil {
-		s.logger.Error("Failed to get document edits", zap.Error(err))
+		logging.FromContext(ctx, s.logger).Error("Failed to get document edits", zap.Error(err))
 		edits = &analyticsModel.DocumentEditsResponse{}
 	}
 
@@ -491,22 +3718,43 @@ func(s *documentService)	GetDocumentAnalytics(ctx context.Context, documentID uu
 }
 
 
+func(s *documentService)	SetAnalyticsAccess(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, req model.DocumentAnalyticsAccessRequest) (*model.Document, error){
+	document, err := s.getOwnedDocument(ctx, documentID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.docRepo.SetDocumentAnalyticsPermission(ctx, documentID, req.Permission); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to set document analytics permission", zap.Error(err))
+		return nil, err
+	}
+
+	s.recordSecurityEvent(ctx, siemModel.TypeAnalyticsAccess, userID, documentID, map[string]interface{}{
+		"permission":          req.Permission,
+		"previous_permission": document.AnalyticsPermission,
+	})
+	document.AnalyticsPermission = req.Permission
+
+	return document, nil
+}
+
+
 func(s *documentService)	GetUserAnalytics(ctx context.Context, userID uuid.UUID, period string) (*analyticsModel.UserAnalyticsResponse, error){
 	documents, err := s.analyticsRepo.GetUserDocumentsAnalytics(ctx, userID)
 	if err != nil {
-		s.logger.Error("Failed to get user document analytics", zap.Error(err))
+		logging.FromContext(ctx, s.logger).Error("Failed to get user document analytics", zap.Error(err))
 		documents = &analyticsModel.UserDocumentsResponse{}
 	}
 
 	activity, err := s.analyticsRepo.GetUserActivityAnalytics(ctx, userID, period)
 	if err != nil {
-		s.logger.Error("Failed to get user activity analytics", zap.Error(err))
+		logging.FromContext(ctx, s.logger).Error("Failed to get user activity analytics", zap.Error(err))
 		activity = &analyticsModel.UserActivityResponse{}
 	}
 
 	mostActive, err := s.analyticsRepo.GetUserMostActiveDocuments(ctx, userID, 5)
 	if err != nil {
-		s.logger.Error("Failed to get user's most active documents", zap.Error(err))
+		logging.FromContext(ctx, s.logger).Error("Failed to get user's most active documents", zap.Error(err))
 		mostActive = []analyticsModel.UserAnalyticsDocumentResponse{}
 	}
 
@@ -520,5 +3768,444 @@ func(s *documentService)	GetUserAnalytics(ctx context.Context, userID uuid.UUID,
 }
 
 
+func(s *documentService)	SubscribeDigest(ctx context.Context, userID uuid.UUID, documentID *uuid.UUID) (*analyticsModel.Digest, error){
+	if documentID != nil {
+		if _, err := s.getOwnedDocument(ctx, *documentID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	digest, err := s.analyticsRepo.GetDigest(ctx, userID, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if digest != nil {
+		return digest, nil
+	}
+
+	digest = &analyticsModel.Digest{
+		UserID:     userID,
+		DocumentID: documentID,
+		CreatedAt:  time.Now(),
+	}
+	if err := s.analyticsRepo.CreateDigest(ctx, digest); err != nil {
+		return nil, err
+	}
+
+	return digest, nil
+}
+
+
+func(s *documentService)	UnsubscribeDigest(ctx context.Context, userID uuid.UUID, documentID *uuid.UUID) error{
+	return s.analyticsRepo.DeleteDigest(ctx, userID, documentID)
+}
+
+
+func(s *documentService)	SendDigests(ctx context.Context) (int, error){
+	if s.mailer == nil {
+		return 0, nil
+	}
+
+	sent := 0
+	for {
+		now := time.Now()
+		digests, err := s.analyticsRepo.ClaimDueDigests(ctx, now, digestBatchSize)
+		if err != nil {
+			return sent, err
+		}
+
+		for _, due := range digests {
+			if err := s.sendDigest(ctx, due, now); err != nil {
+				logging.FromContext(ctx, s.logger).Warn("Failed to send analytics digest",
+					zap.String("digest_id", due.ID.String()),
+					zap.Error(err))
+				continue
+			}
+			sent++
+		}
+
+		if len(digests) < digestBatchSize {
+			return sent, nil
+		}
+	}
+}
+
+// sendDigest emails one claimed digest covering due.Since to until.
+// Digests of documents that were deleted or changed owner are dropped.
+func(s *documentService)	WarmUp(ctx context.Context, since time.Time, limit, concurrency int) (int, error){
+	accesses, err := s.docRepo.GetRecentAccess(ctx, since, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	tasks := make(chan func())
+	var wg sync.WaitGroup
+	for i := 0; i < max(concurrency, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range tasks {
+				task()
+			}
+		}()
+	}
+
+	var checked atomic.Int64
+	cached := make(map[uuid.UUID]bool)
+	for _, access := range accesses {
+		task := func() {
+			if _, err := s.docRepo.CanUserAccess(ctx, access.DocumentID, access.UserID, model.PermissionRead); err == nil {
+				checked.Add(1)
+			}
+		}
+
+		if access.IsPublic && s.publicCacheTTL > 0 && !cached[access.DocumentID] {
+			cached[access.DocumentID] = true
+			check := task
+			task = func() {
+				check()
+				_, _, _ = s.loadPublicDocument(ctx, access.DocumentID)
+			}
+		}
+
+		select {
+		case tasks <- task:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(tasks)
+	wg.Wait()
+
+	return int(checked.Load()), ctx.Err()
+}
+
+
+func (s *documentService) sendDigest(ctx context.Context, due *analyticsModel.DueDigest, until time.Time) error {
+	user, err := s.userRepo.FindUserByID(ctx, due.UserID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return nil
+	}
+
+	title := ""
+	if due.DocumentID != nil {
+		document, err := s.getOwnedDocument(ctx, *due.DocumentID, due.UserID)
+		if errors.Is(err, ErrDocumentNotFound) || errors.Is(err, ErrUnauthorized) {
+			return s.analyticsRepo.DeleteDigest(ctx, due.UserID, due.DocumentID)
+		}
+		if err != nil {
+			return err
+		}
+		title = document.Title
+	}
+
+	stats, err := s.analyticsRepo.GetDigestStats(ctx, &due.Digest, due.Since, until)
+	if err != nil {
+		return err
+	}
+
+	email, err := digest.Render(user.Name, title, stats)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: email.Subject,
+		Text:    email.Text,
+		HTML:    email.HTML,
+	})
+}
+
+
+// languageOf validates a requested language, returning nil for none
+func languageOf(tag string) (*string, error) {
+	if tag == "" {
+		return nil, nil
+	}
+	canonical, ok := model.CanonicalLanguage(tag)
+	if !ok {
+		return nil, ErrInvalidLanguage
+	}
+	return &canonical, nil
+}
+
+
+// contentTooLarge reports whether content exceeds documents.max_content_bytes
+func (s *documentService) contentTooLarge(content string) bool {
+	return s.maxContentBytes > 0 && len(content) > s.maxContentBytes
+}
+
+// runContentHooks passes content through the content hook chain, returning
+// the content to save
+func (s *documentService) runContentHooks(ctx context.Context, documentID *uuid.UUID, userID uuid.UUID, title, content string) (string, error) {
+	save := &hook.Save{
+		DocumentID: documentID,
+		UserID:     userID,
+		Title:      title,
+		Content:    content,
+	}
+
+	if err := s.contentHooks.Run(ctx, save); err != nil {
+		var rejection *hook.Rejection
+		if !errors.As(err, &rejection) {
+			logging.FromContext(ctx, s.logger).Error("Content hook failed", zap.Error(err))
+		}
+		return "", err
+	}
+	return save.Content, nil
+}
+
+// lintOnSaveCheck returns a LintError when saving content would break the
+// lint rules and lint.on_save is enabled
+func (s *documentService) lintOnSaveCheck(content string, headings []outline.Heading) error {
+	if !s.lintOnSave {
+		return nil
+	}
+
+	if report := s.lintRules.Check(content, headings); !report.Passed {
+		return &LintError{Report: report}
+	}
+	return nil
+}
+
+// largestDocuments is how many documents GetUserStorage lists
+const largestDocuments = 10
+
+// digestBatchSize is how many digests SendDigests claims at a time
+const digestBatchSize = 100
+
+func publicCacheKey(documentID uuid.UUID) string {
+	return fmt.Sprintf("public_document:%s", documentID)
+}
+
+func publishedCacheKey(documentID uuid.UUID) string {
+	return fmt.Sprintf("published_document:%s", documentID)
+}
+
+// wordsPerMinute is the reading speed used to estimate reading time
+const wordsPerMinute = 200
+
+// statsCacheTTL bounds how long stats stay cached. Entries are keyed by
+// version, so edits never serve stale stats.
+const statsCacheTTL = 24 * time.Hour
+
+// thumbnailCacheTTL bounds how long previews stay cached. Entries are keyed
+// by version and re-rendered on demand once they expire.
+const thumbnailCacheTTL = 7 * 24 * time.Hour
+
+func thumbnailCacheKey(documentID uuid.UUID, version int) string {
+	return fmt.Sprintf("document_thumbnail:%s:%d", documentID, version)
+}
+
+func exportCacheKey(documentID uuid.UUID, version int, format export.Format) string {
+	return fmt.Sprintf("document_export:%s:%d:%s", documentID, version, format)
+}
+
+func statsCacheKey(documentID uuid.UUID, version int) string {
+	return fmt.Sprintf("document_stats:%s:%d", documentID, version)
+}
+
+// countWords counts whitespace-separated words, ignoring markdown syntax
+// such as heading markers and list bullets that contain no letters or digits
+func countWords(content string) int {
+	words := 0
+	for _, field := range strings.Fields(content) {
+		if strings.IndexFunc(field, func(r rune) bool {
+			return unicode.IsLetter(r) || unicode.IsDigit(r)
+		}) >= 0 {
+			words++
+		}
+	}
+	return words
+}
+
+// outlineOf returns the stored outline, computing it for documents saved
+// before outlines were tracked. Encrypted documents have none.
+func (s *documentService) outlineOf(document *model.Document) []outline.Heading {
+	if document.Encrypted {
+		return nil
+	}
+	if document.Outline == nil {
+		return outline.Rebase(nil, document.Content)
+	}
+	return document.Outline
+}
+
+// invalidatePublic drops a changed document from the public cache and
+// purges it from the CDN in the background. Documents that neither are nor
+// were public were never served, so nothing is done for them.
+func (s *documentService) invalidatePublic(ctx context.Context, document *model.Document, wasPublic bool) {
+	if !document.IsPublic && !wasPublic {
+		return
+	}
+
+	if err := s.redis.Del(ctx, publicCacheKey(document.ID), publishedCacheKey(document.ID)).Err(); err != nil {
+		logging.FromContext(ctx, s.logger).Warn("Failed to evict public document cache", zap.Error(err))
+	}
+
+	if s.purger == nil {
+		return
+	}
+
+	ctx = logging.Detach(ctx)
+	go func() {
+		if err := s.purger.Purge(ctx, []string{cdn.DocumentKey(document.ID)}); err != nil {
+			logging.FromContext(ctx, s.logger).Warn("Failed to purge document from CDN",
+				zap.String("document_id", document.ID.String()),
+				zap.Error(err))
+		}
+	}()
+}
+
+// maxEventDiffSize caps the unified diff carried in event payloads, in bytes
+const maxEventDiffSize = 4096
+
+// dispatchContentEvent sends document.updated with a diff against the
+// previous content to the owner's webhooks, and to the owner directly when
+// someone else made the change. Subscribers of the sections the change
+// touched are notified too. Encrypted changes carry no diff. The new
+// content goes to the document's realtime subscribers, except
+// originClientID, the connection the edit came from, if any.
+func (s *documentService) dispatchContentEvent(ctx context.Context, document *model.Document, actorID uuid.UUID, originClientID string, oldContent string, oldOutline []outline.Heading, oldVersion int) {
+	s.broadcastContent(ctx, document, actorID, originClientID)
+
+	event := model.DocumentEvent{
+		DocumentID: document.ID,
+		Title:      document.Title,
+		Version:    document.Version,
+		IsPublic:   document.IsPublic,
+		State:      document.State,
+		ActorID:    actorID,
+		UpdatedAt:  document.UpdatedAt,
+	}
+
+	if !document.Encrypted {
+		result := diff.Lines(oldContent, document.Content)
+		s.notifySectionSubscribers(ctx, document, actorID, oldContent, oldOutline, result)
 
+		summary := &model.DocumentDiff{
+			FromVersion: oldVersion,
+			ToVersion:   document.Version,
+			Added:       result.Added,
+			Removed:     result.Removed,
+			Unified:     result.Unified(),
+			CompareURL:  fmt.Sprintf("/api/v1/documents/%s/compare?from=%d&to=%d", document.ID, oldVersion, document.Version),
+		}
+		if len(summary.Unified) > maxEventDiffSize {
+			cut := strings.LastIndexByte(summary.Unified[:maxEventDiffSize], '\n')
+			summary.Unified = summary.Unified[:cut+1]
+			summary.Truncated = true
+		}
+		event.Diff = summary
+	}
+
+	s.webhooks.Dispatch(ctx, document.OwnerID, webhookModel.EventDocumentUpdated, event)
+	if actorID == document.OwnerID {
+		return
+	}
+	if err := s.realtime.NotifyUser(ctx, document.OwnerID, string(webhookModel.EventDocumentUpdated), event); err != nil {
+		logging.FromContext(ctx, s.logger).Warn("Failed to notify owner of document change",
+			zap.String("document_id", document.ID.String()),
+			zap.Error(err))
+	}
+}
+
+// broadcastContent sends the saved content to the document's realtime
+// subscribers as a replace of /content. The editor's other devices get it
+// too; only originClientID is skipped.
+func (s *documentService) broadcastContent(ctx context.Context, document *model.Document, actorID uuid.UUID, originClientID string) {
+	var name string
+	if user, err := s.userRepo.FindUserByID(ctx, actorID); err == nil && user != nil {
+		name = user.Name
+	}
+
+	patches := []wsModel.JSONPatchOperation{{Op: "replace", Path: "/content", Value: document.Content}}
+	if err := s.realtime.BroadcastDocumentUpdate(ctx, document.ID, originClientID, actorID, name, document.Version, patches); err != nil {
+		logging.FromContext(ctx, s.logger).Warn("Failed to broadcast document update",
+			zap.String("document_id", document.ID.String()),
+			zap.Error(err))
+	}
+}
+
+// notifySectionSubscribers notifies every subscriber other than the actor
+// whose sections contain a removed line of the old content or an added line
+// of the new content. Subscribers who lost read access are skipped.
+func (s *documentService) notifySectionSubscribers(ctx context.Context, document *model.Document, actorID uuid.UUID, oldContent string, oldOutline []outline.Heading, result *diff.Result) {
+	subscriptions, err := s.docRepo.GetSectionSubscriptions(ctx, document.ID, nil)
+	if err != nil || len(subscriptions) == 0 {
+		return
+	}
+
+	removed, added := result.Changed()
+	touched := map[string]bool{}
+	touch := func(sections []outline.Section, lines []int) {
+		for _, section := range sections {
+			for _, line := range lines {
+				if line >= section.Start && line <= section.End {
+					touched[section.Anchor] = true
+					break
+				}
+			}
+		}
+	}
+	touch(outline.Sections(oldContent, oldOutline), removed)
+	touch(outline.Sections(document.Content, s.outlineOf(document)), added)
+
+	anchors := map[uuid.UUID][]string{}
+	for _, subscription := range subscriptions {
+		if subscription.UserID != actorID && touched[subscription.Anchor] {
+			anchors[subscription.UserID] = append(anchors[subscription.UserID], subscription.Anchor)
+		}
+	}
+
+	for userID, userAnchors := range anchors {
+		if canRead, err := s.docRepo.CanUserAccess(ctx, document.ID, userID, model.PermissionRead); err != nil || !canRead {
+			continue
+		}
+
+		event := model.SectionUpdatedEvent{
+			DocumentID: document.ID,
+			Title:      document.Title,
+			Version:    document.Version,
+			Anchors:    userAnchors,
+			ActorID:    actorID,
+			UpdatedAt:  document.UpdatedAt,
+		}
+		if err := s.realtime.NotifyUser(ctx, userID, model.SectionUpdatedNotification, event); err != nil {
+			logging.FromContext(ctx, s.logger).Warn("Failed to notify section subscriber",
+				zap.String("document_id", document.ID.String()),
+				zap.Error(err))
+		}
+	}
+}
 
+// recordSecurityEvent reports an action of userID on a document to the SIEM
+func (s *documentService) recordSecurityEvent(ctx context.Context, eventType siemModel.Type, userID, documentID uuid.UUID, data map[string]interface{}) {
+	s.security.Record(ctx, siemModel.Message{
+		Type:       eventType,
+		Outcome:    siemModel.OutcomeSuccess,
+		UserID:     &userID,
+		TargetType: siemModel.TargetDocument,
+		TargetID:   &documentID,
+		Data:       data,
+	})
+}
+
+// dispatchEvent notifies the document owner's webhooks about a change
+func (s *documentService) dispatchEvent(ctx context.Context, document *model.Document, event webhookModel.Event, actorID uuid.UUID) {
+	s.webhooks.Dispatch(ctx, document.OwnerID, event, model.DocumentEvent{
+		DocumentID: document.ID,
+		Title:      document.Title,
+		Version:    document.Version,
+		IsPublic:   document.IsPublic,
+		State:      document.State,
+		ActorID:    actorID,
+		UpdatedAt:  document.UpdatedAt,
+	})
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	userRepo "github.com/hafiztri123/document-api/internal/auth/repository"
+	"github.com/hafiztri123/document-api/internal/database/dbtest"
+	"github.com/hafiztri123/document-api/internal/document/model"
+	docRepo "github.com/hafiztri123/document-api/internal/document/repository"
+	userModel "github.com/hafiztri123/document-api/internal/user/model"
+	webhookModel "github.com/hafiztri123/document-api/internal/webhook/model"
+	webhookService "github.com/hafiztri123/document-api/internal/webhook/service"
+	wsModel "github.com/hafiztri123/document-api/internal/ws/model"
+	wsService "github.com/hafiztri123/document-api/internal/ws/service"
+)
+
+// noWebhooks drops every event
+type noWebhooks struct {
+	webhookService.Service
+}
+
+func (noWebhooks) Dispatch(ctx context.Context, ownerID uuid.UUID, event webhookModel.Event, data interface{}) {
+}
+
+// broadcast is one BroadcastDocumentUpdate call
+type broadcast struct {
+	documentID     uuid.UUID
+	originClientID string
+	userID         uuid.UUID
+	userName       string
+	version        int
+	patches        []wsModel.JSONPatchOperation
+}
+
+// recordingRealtime keeps the document updates it is asked to broadcast
+type recordingRealtime struct {
+	wsService.Service
+	broadcasts []broadcast
+}
+
+func (r *recordingRealtime) BroadcastDocumentUpdate(ctx context.Context, documentID uuid.UUID, originClientID string, userID uuid.UUID, userName string, version int, patches []wsModel.JSONPatchOperation) error {
+	r.broadcasts = append(r.broadcasts, broadcast{documentID, originClientID, userID, userName, version, patches})
+	return nil
+}
+
+func (r *recordingRealtime) NotifyUser(ctx context.Context, userID uuid.UUID, event string, data interface{}) error {
+	return nil
+}
+
+// namedUsers finds every user, under the same name
+type namedUsers struct {
+	userRepo.Repository
+	name string
+}
+
+func (u namedUsers) FindUserByID(ctx context.Context, id uuid.UUID) (*userModel.User, error) {
+	return &userModel.User{ID: id, Name: u.name}, nil
+}
+
+// noSubscriptions is a document repository without section subscriptions
+type noSubscriptions struct {
+	docRepo.Repository
+}
+
+func (noSubscriptions) GetSectionSubscriptions(ctx context.Context, documentID uuid.UUID, userID *uuid.UUID) ([]*model.SectionSubscription, error) {
+	return nil, nil
+}
+
+func TestDispatchContentEventBroadcasts(t *testing.T) {
+	tests := []struct {
+		name           string
+		originClientID string
+		sameActor      bool
+	}{
+		{name: "from a realtime client", originClientID: "client-1", sameActor: true},
+		{name: "without a client", originClientID: "", sameActor: true},
+		{name: "by a collaborator", originClientID: "client-2", sameActor: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			realtime := &recordingRealtime{}
+			s := &documentService{
+				docRepo:  noSubscriptions{},
+				userRepo: namedUsers{name: "Editor"},
+				webhooks: noWebhooks{},
+				realtime: realtime,
+				logger:   zap.NewNop(),
+			}
+
+			document := &model.Document{ID: uuid.New(), OwnerID: uuid.New(), Content: "hello\nworld\n", Version: 3}
+			actorID := document.OwnerID
+			if !tt.sameActor {
+				actorID = uuid.New()
+			}
+
+			s.dispatchContentEvent(context.Background(), document, actorID, tt.originClientID, "hello\n", nil, 2)
+
+			if len(realtime.broadcasts) != 1 {
+				t.Fatalf("%d broadcasts, want 1", len(realtime.broadcasts))
+			}
+			got := realtime.broadcasts[0]
+			if got.documentID != document.ID || got.originClientID != tt.originClientID || got.userID != actorID ||
+				got.userName != "Editor" || got.version != document.Version {
+				t.Errorf("broadcast = %+v, want document %s, origin %q, user %s named Editor, version %d",
+					got, document.ID, tt.originClientID, actorID, document.Version)
+			}
+			want := wsModel.JSONPatchOperation{Op: "replace", Path: "/content", Value: document.Content}
+			if len(got.patches) != 1 || got.patches[0] != want {
+				t.Errorf("patches = %+v, want [%+v]", got.patches, want)
+			}
+		})
+	}
+}
+
+func newSyncedDocument(t *testing.T, db *gorm.DB, ownerID uuid.UUID, alias, sourcePath string) *model.Document {
+	t.Helper()
+
+	code, err := model.NewShortCode()
+	if err != nil {
+		t.Fatalf("short code: %v", err)
+	}
+	revision := "0123abcd"
+	document := &model.Document{
+		Title:          "Handbook",
+		Content:        "hello\n",
+		OwnerID:        ownerID,
+		ShortCode:      code,
+		Alias:          &alias,
+		SourcePath:     &sourcePath,
+		SourceRevision: &revision,
+	}
+	if err := db.Create(document).Error; err != nil {
+		t.Fatalf("create document: %v", err)
+	}
+	return document
+}
+
+func TestRestoreDocumentDropsTakenAlias(t *testing.T) {
+	tests := []struct {
+		name  string
+		taken bool
+	}{
+		{name: "free", taken: false},
+		{name: "taken", taken: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.Open(t)
+			ctx := context.Background()
+			logger := zap.NewNop()
+			repo := docRepo.NewDocumentRepository(db, logger)
+			s := &documentService{docRepo: repo, webhooks: noWebhooks{}, logger: logger}
+
+			owner := &userModel.User{Email: uuid.NewString() + "@example.com", Name: "Owner", Password: "not-a-hash"}
+			if err := db.Create(owner).Error; err != nil {
+				t.Fatalf("create user: %v", err)
+			}
+
+			document := newSyncedDocument(t, db, owner.ID, "handbook", "docs/handbook.md")
+			if err := repo.DeleteDocument(ctx, document.ID); err != nil {
+				t.Fatalf("DeleteDocument: %v", err)
+			}
+
+			var holder *model.Document
+			if tt.taken {
+				holder = newSyncedDocument(t, db, owner.ID, "handbook", "docs/handbook.md")
+			}
+
+			restored, err := s.RestoreDocument(ctx, document.ID, owner.ID)
+			if err != nil {
+				t.Fatalf("RestoreDocument: %v", err)
+			}
+
+			if tt.taken {
+				if restored.Alias != nil || restored.SourcePath != nil || restored.SourceRevision != nil {
+					t.Errorf("restored alias, source path, revision = %v, %v, %v; want them dropped",
+						restored.Alias, restored.SourcePath, restored.SourceRevision)
+				}
+
+				current, err := repo.GetDocumentByAlias(ctx, "handbook")
+				if err != nil || current == nil || current.ID != holder.ID {
+					t.Errorf("alias resolves to %v, %v; want the document that took it", current, err)
+				}
+			} else {
+				if restored.Alias == nil || *restored.Alias != "handbook" {
+					t.Errorf("restored alias = %v, want handbook", restored.Alias)
+				}
+				if restored.SourcePath == nil || *restored.SourcePath != "docs/handbook.md" {
+					t.Errorf("restored source path = %v, want docs/handbook.md", restored.SourcePath)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+// Package thumbnail draws a small preview of the first page of a document.
+// Text is drawn as bars rather than glyphs, so the preview shows the shape
+// of the page (headings, paragraphs, lists, code and images) without font
+// rendering.
+package thumbnail
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// Size of the preview in pixels, in the proportions of a portrait page
+const (
+	Width  = 240
+	Height = 320
+)
+
+// ContentType of the rendered preview
+const ContentType = "image/png"
+
+const (
+	margin = 16
+	// charWidth approximates the width of one character of body text
+	charWidth  = 3
+	lineHeight = 4
+	lineGap    = 3
+	blockGap   = 7
+	indent     = 12
+)
+
+var (
+	paper   = color.Gray{Y: 0xff}
+	border  = color.Gray{Y: 0xdd}
+	ink     = color.Gray{Y: 0x33}
+	body    = color.Gray{Y: 0xaa}
+	subtle  = color.Gray{Y: 0xf0}
+	picture = color.RGBA{R: 0xc8, G: 0xd6, B: 0xe5, A: 0xff}
+)
+
+var markdown = goldmark.New()
+
+// URL is where an authenticated reader fetches the preview of version of a
+// document. The version only busts client caches; the latest is served.
+func URL(documentID uuid.UUID, version int) string {
+	return fmt.Sprintf("/api/v1/documents/%s/thumbnail?v=%d", documentID, version)
+}
+
+// PublicURL is URL for anonymous readers of a public document
+func PublicURL(documentID uuid.UUID, version int) string {
+	return fmt.Sprintf("/api/v1/public/documents/%s/thumbnail?v=%d", documentID, version)
+}
+
+// Render draws the title and as much of content as fits on the first page
+// and encodes it as a PNG
+func Render(title, content string) ([]byte, error) {
+	c := &canvas{
+		img: image.NewRGBA(image.Rect(0, 0, Width, Height)),
+		y:   margin,
+	}
+	c.fill(0, 0, Width, Height, paper)
+	c.fill(0, 0, Width, 1, border)
+	c.fill(0, Height-1, Width, Height, border)
+	c.fill(0, 0, 1, Height, border)
+	c.fill(Width-1, 0, Width, Height, border)
+
+	c.text(margin, title, 8, ink)
+	c.y += blockGap
+
+	source := []byte(content)
+	c.blocks(markdown.Parser().Parse(text.NewReader(source)), source, margin)
+
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := encoder.Encode(&buf, c.img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type canvas struct {
+	img *image.RGBA
+	// y is the top of the next line
+	y int
+}
+
+func (c *canvas) full() bool {
+	return c.y >= Height-margin
+}
+
+// fill paints a rectangle
+func (c *canvas) fill(x0, y0, x1, y1 int, col color.Color) {
+	draw.Draw(c.img, image.Rect(x0, y0, x1, y1), &image.Uniform{C: col}, image.Point{}, draw.Src)
+}
+
+// text wraps s at word boundaries and draws each line as a bar of the given
+// height, with its length following the length of the line
+func (c *canvas) text(x int, s string, height int, col color.Color) {
+	scale := charWidth * height / lineHeight
+	perLine := (Width - margin - x) / scale
+	if perLine < 1 {
+		return
+	}
+
+	for _, line := range wrap(strings.Fields(s), perLine) {
+		if c.full() {
+			return
+		}
+		c.fill(x, c.y, x+line*scale, min(c.y+height, Height-margin), col)
+		c.y += height + lineGap
+	}
+}
+
+// blocks draws the block children of parent, stopping when the page is full
+func (c *canvas) blocks(parent ast.Node, source []byte, x int) {
+	for n := parent.FirstChild(); n != nil && !c.full(); n = n.NextSibling() {
+		switch n := n.(type) {
+		case *ast.Heading:
+			c.text(x, plain(n, source), max(9-n.Level, 5), ink)
+		case *ast.Paragraph, *ast.TextBlock:
+			if onlyImage(n) {
+				c.fill(x, c.y, Width-margin, min(c.y+60, Height-margin), picture)
+				c.y += 60
+			} else {
+				c.text(x, plain(n, source), lineHeight, body)
+			}
+		case *ast.List:
+			for item := n.FirstChild(); item != nil && !c.full(); item = item.NextSibling() {
+				c.fill(x+2, c.y, x+5, c.y+3, ink)
+				c.blocks(item, source, x+indent)
+			}
+		case *ast.Blockquote:
+			top := c.y
+			c.blocks(n, source, x+indent)
+			c.fill(x+2, top, x+4, min(c.y, Height-margin), border)
+		case *ast.FencedCodeBlock, *ast.CodeBlock:
+			c.code(n, source, x)
+		case *ast.ThematicBreak:
+			c.fill(x, c.y+1, Width-margin, c.y+2, border)
+			c.y += lineGap
+		case *ast.HTMLBlock:
+			continue
+		default:
+			c.blocks(n, source, x)
+		}
+		c.y += blockGap
+	}
+}
+
+// code draws a code block as a shaded box, keeping line indentation
+func (c *canvas) code(n ast.Node, source []byte, x int) {
+	lines := n.Lines()
+	top := c.y
+	c.y += lineGap
+
+	var rows []string
+	for i := 0; i < lines.Len(); i++ {
+		segment := lines.At(i)
+		rows = append(rows, strings.TrimRight(string(segment.Value(source)), "\n"))
+	}
+
+	height := (lineHeight+lineGap)*len(rows) + lineGap
+	c.fill(x, top, Width-margin, min(top+height, Height-margin), subtle)
+
+	perLine := (Width - margin - x - 2*lineGap) / charWidth
+	for _, row := range rows {
+		if c.full() {
+			return
+		}
+		trimmed := strings.TrimLeft(row, " \t")
+		start := len(row) - len(trimmed)
+		length := min(len(trimmed), perLine-start)
+		if length > 0 {
+			left := x + lineGap + start*charWidth
+			c.fill(left, c.y, left+length*charWidth, min(c.y+lineHeight, Height-margin), body)
+		}
+		c.y += lineHeight + lineGap
+	}
+}
+
+// wrap greedily fills lines of perLine characters with words and returns the
+// length of each line
+func wrap(words []string, perLine int) []int {
+	var lines []int
+	length := 0
+	for _, word := range words {
+		size := min(len(word), perLine)
+		switch {
+		case length == 0:
+			length = size
+		case length+1+size <= perLine:
+			length += 1 + size
+		default:
+			lines = append(lines, length)
+			length = size
+		}
+	}
+	if length > 0 {
+		lines = append(lines, length)
+	}
+	return lines
+}
+
+// plain returns the text of the inline children of n
+func plain(n ast.Node, source []byte) string {
+	var b strings.Builder
+	_ = ast.Walk(n, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Text:
+			b.Write(n.Segment.Value(source))
+			if n.SoftLineBreak() || n.HardLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			b.Write(n.Value)
+		}
+		return ast.WalkContinue, nil
+	})
+	return b.String()
+}
+
+// onlyImage reports whether a paragraph holds nothing but one image
+func onlyImage(n ast.Node) bool {
+	child := n.FirstChild()
+	if child == nil || child.NextSibling() != nil {
+		return false
+	}
+	_, ok := child.(*ast.Image)
+	return ok
+}
@@ -0,0 +1,163 @@
+package errcode
+
+import "net/http"
+
+// Code is a stable, machine-readable error identifier returned in the
+// "code" field of every error response. Clients should branch on these
+// values rather than on messages, so a released code must never be renamed.
+type Code string
+
+const (
+	// Generic
+	ValidationError Code = "VALIDATION_ERROR"
+	Unauthorized    Code = "UNAUTHORIZED"
+	Forbidden       Code = "FORBIDDEN"
+	InternalError   Code = "INTERNAL_ERROR"
+	RateLimited     Code = "RATE_LIMITED"
+
+	// Auth
+	InvalidCredentials Code = "INVALID_CREDENTIALS"
+	InvalidToken       Code = "INVALID_TOKEN"
+	UserExists         Code = "USER_EXISTS"
+	UserNotFound       Code = "USER_NOT_FOUND"
+
+	// Documents
+	DocNotFound       Code = "DOC_NOT_FOUND"
+	VersionNotFound   Code = "VERSION_NOT_FOUND"
+	LabelNotFound     Code = "LABEL_NOT_FOUND"
+	LabelTaken        Code = "LABEL_TAKEN"
+	LintFailed        Code = "LINT_FAILED"
+	ContentRejected   Code = "CONTENT_REJECTED"
+	ContentTooLarge   Code = "CONTENT_TOO_LARGE"
+	DocumentLocked    Code = "DOCUMENT_LOCKED"
+	InvalidState      Code = "INVALID_STATE_TRANSITION"
+	AliasNotFound     Code = "ALIAS_NOT_FOUND"
+	AliasTaken        Code = "ALIAS_TAKEN"
+	ShortCodeNotFound Code = "SHORT_CODE_NOT_FOUND"
+	TermsNotAccepted  Code = "TERMS_NOT_ACCEPTED"
+	SectionNotFound   Code = "SECTION_NOT_FOUND"
+	SnapshotNotFound  Code = "SNAPSHOT_NOT_FOUND"
+	VersionConflict   Code = "VERSION_CONFLICT"
+	EditConflict      Code = "EDIT_CONFLICT"
+	Encrypted         Code = "DOCUMENT_ENCRYPTED"
+	NotEncrypted      Code = "DOCUMENT_NOT_ENCRYPTED"
+	KeyNotFound       Code = "DOCUMENT_KEY_NOT_FOUND"
+
+	// Collaboration
+	AlreadyCollaborator Code = "ALREADY_COLLABORATOR"
+	NotCollaborator     Code = "NOT_COLLABORATOR"
+	CannotRemoveOwner   Code = "CANNOT_REMOVE_OWNER"
+
+	// Folders
+	FolderNotFound      Code = "FOLDER_NOT_FOUND"
+	InvalidFolderParent Code = "INVALID_FOLDER_PARENT"
+	DocumentNotInFolder Code = "DOCUMENT_NOT_IN_FOLDER"
+
+	// Knowledge base
+	SpaceNotFound Code = "SPACE_NOT_FOUND"
+	PageNotFound  Code = "PAGE_NOT_FOUND"
+	SlugTaken     Code = "SLUG_TAKEN"
+
+	// Templates
+	TemplateNotFound Code = "TEMPLATE_NOT_FOUND"
+
+	// Public directory
+	CategoryNotFound      Code = "CATEGORY_NOT_FOUND"
+	InvalidCategoryParent Code = "INVALID_CATEGORY_PARENT"
+
+	// Backups
+	InvalidArchive    Code = "INVALID_ARCHIVE"
+	WorkspaceNotEmpty Code = "WORKSPACE_NOT_EMPTY"
+
+	// Jobs
+	JobNotFound Code = "JOB_NOT_FOUND"
+
+	// Webhooks
+	WebhookNotFound  Code = "WEBHOOK_NOT_FOUND"
+	DeliveryNotFound Code = "DELIVERY_NOT_FOUND"
+
+	// Push notifications
+	DeviceNotFound Code = "DEVICE_NOT_FOUND"
+
+	// WebSocket
+	InvalidMessageType         Code = "INVALID_MESSAGE_TYPE"
+	UnsupportedProtocolVersion Code = "UNSUPPORTED_PROTOCOL_VERSION"
+)
+
+// Entry documents a single error code in the catalog
+type Entry struct {
+	Code        Code   `json:"code"`
+	Status      int    `json:"status"`
+	Description string `json:"description"`
+}
+
+var catalog = []Entry{
+	{ValidationError, http.StatusBadRequest, "The request is malformed or failed validation; see details"},
+	{Unauthorized, http.StatusUnauthorized, "Authentication is missing or invalid"},
+	{Forbidden, http.StatusForbidden, "The caller lacks permission for this resource"},
+	{InternalError, http.StatusInternalServerError, "An unexpected server error occurred"},
+	{RateLimited, http.StatusTooManyRequests, "Too many requests in the current window; see the X-RateLimit-* headers"},
+
+	{InvalidCredentials, http.StatusUnauthorized, "Email or password is incorrect"},
+	{InvalidToken, http.StatusUnauthorized, "The token is invalid, expired or revoked"},
+	{UserExists, http.StatusConflict, "A user with this email already exists"},
+	{UserNotFound, http.StatusNotFound, "The referenced user does not exist"},
+
+	{DocNotFound, http.StatusNotFound, "The document does not exist or has been deleted"},
+	{VersionNotFound, http.StatusNotFound, "The requested document version does not exist"},
+	{LabelNotFound, http.StatusNotFound, "No version of the document has the requested label"},
+	{LabelTaken, http.StatusConflict, "Another version of the document already has the label"},
+	{LintFailed, http.StatusUnprocessableEntity, "The content breaks the configured lint rules; see violations"},
+	{ContentRejected, http.StatusUnprocessableEntity, "A content hook rejected the save; see hook and violations"},
+	{ContentTooLarge, http.StatusRequestEntityTooLarge, "The content is larger than documents.max_content_bytes allows"},
+	{DocumentLocked, http.StatusLocked, "Another user holds the document's lock; see lock for who and until when"},
+	{InvalidState, http.StatusConflict, "The document's lifecycle state does not allow the requested transition"},
+	{AliasNotFound, http.StatusNotFound, "No document uses or used the requested alias"},
+	{AliasTaken, http.StatusConflict, "The alias is already used by another document"},
+	{ShortCodeNotFound, http.StatusNotFound, "No document uses the requested short code"},
+	{TermsNotAccepted, http.StatusForbidden, "The document's terms must be accepted first; see terms for where to read and accept them"},
+	{SectionNotFound, http.StatusNotFound, "The document has no heading with the requested anchor; see its outline"},
+	{SnapshotNotFound, http.StatusNotFound, "The snapshot does not exist or is no longer published"},
+	{VersionConflict, http.StatusPreconditionFailed, "The document changed since the version in If-Match; fetch it again and retry"},
+	{EditConflict, http.StatusConflict, "The document changed since expected_version; rebase onto current_version and content, then retry"},
+	{Encrypted, http.StatusConflict, "The document is end-to-end encrypted, so the server cannot read its content for this; decrypt and process it on the client"},
+	{NotEncrypted, http.StatusConflict, "Keys can only be exchanged for encrypted documents"},
+	{KeyNotFound, http.StatusNotFound, "The user has not registered a public key for the document"},
+
+	{AlreadyCollaborator, http.StatusConflict, "The user is already a collaborator on the document"},
+	{NotCollaborator, http.StatusNotFound, "The user is not a collaborator on the document"},
+	{CannotRemoveOwner, http.StatusBadRequest, "The document owner cannot be removed as a collaborator"},
+
+	{FolderNotFound, http.StatusNotFound, "The folder does not exist"},
+	{InvalidFolderParent, http.StatusConflict, "A folder cannot be moved into itself or one of its subfolders"},
+	{DocumentNotInFolder, http.StatusConflict, "Only documents filed in the folder can be pinned to it"},
+
+	{SpaceNotFound, http.StatusNotFound, "The knowledge base space does not exist"},
+	{PageNotFound, http.StatusNotFound, "Nothing is published at this address, or the document is not published"},
+	{SlugTaken, http.StatusConflict, "Another space, page or directory category already uses this slug"},
+
+	{TemplateNotFound, http.StatusNotFound, "The template does not exist"},
+
+	{CategoryNotFound, http.StatusNotFound, "The directory category does not exist"},
+	{InvalidCategoryParent, http.StatusConflict, "A directory category cannot be nested under itself or one of its subcategories"},
+
+	{InvalidArchive, http.StatusUnprocessableEntity, "The backup archive is incomplete or inconsistent; see problems"},
+	{WorkspaceNotEmpty, http.StatusConflict, "Backups can only be restored for a user who owns no documents or folders"},
+
+	{JobNotFound, http.StatusNotFound, "The job does not exist or its record has expired"},
+
+	{WebhookNotFound, http.StatusNotFound, "The webhook does not exist"},
+	{DeliveryNotFound, http.StatusNotFound, "The webhook delivery does not exist"},
+
+	{DeviceNotFound, http.StatusNotFound, "The push device is not registered to the user"},
+
+	{InvalidMessageType, 0, "WebSocket only: the message type is not supported"},
+	{UnsupportedProtocolVersion, 0, "WebSocket only: the client's protocol version is older than the server accepts"},
+}
+
+// Catalog returns every documented error code
+func Catalog() []Entry {
+	entries := make([]Entry, len(catalog))
+	copy(entries, catalog)
+	return entries
+}
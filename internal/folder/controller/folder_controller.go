@@ -0,0 +1,338 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	docModel "github.com/hafiztri123/document-api/internal/document/model"
+	docService "github.com/hafiztri123/document-api/internal/document/service"
+	"github.com/hafiztri123/document-api/internal/errcode"
+	"github.com/hafiztri123/document-api/internal/folder/model"
+	"github.com/hafiztri123/document-api/internal/folder/service"
+	"github.com/hafiztri123/document-api/internal/logging"
+)
+
+type Controller interface {
+	CreateFolder(c *gin.Context)
+	GetFolders(c *gin.Context)
+	RenameFolder(c *gin.Context)
+	MoveFolder(c *gin.Context)
+	DeleteFolder(c *gin.Context)
+	GetFolderDocuments(c *gin.Context)
+
+	MoveDocument(c *gin.Context)
+
+	GetPins(c *gin.Context)
+	ReorderPins(c *gin.Context)
+}
+
+type folderController struct {
+	service    service.Service
+	docService docService.Service
+	logger     *zap.Logger
+}
+
+func NewFolderController(service service.Service, docService docService.Service, logger *zap.Logger) Controller {
+	return &folderController{
+		service:    service,
+		docService: docService,
+		logger:     logger,
+	}
+}
+
+func (ctrl *folderController) CreateFolder(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+
+	var req model.FolderCreateRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	folder, err := ctrl.service.CreateFolder(c.Request.Context(), userID.(uuid.UUID), req)
+	if err != nil {
+		ctrl.handleError(c, err, "Failed to create folder")
+		return
+	}
+
+	c.JSON(http.StatusCreated, folder)
+}
+
+func (ctrl *folderController) GetFolders(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+
+	folders, err := ctrl.service.GetFolders(c.Request.Context(), userID.(uuid.UUID))
+	if err != nil {
+		ctrl.handleError(c, err, "Failed to retrieve folders")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": folders})
+}
+
+func (ctrl *folderController) RenameFolder(c *gin.Context) {
+	folderID, userID, ok := ctrl.parseRequest(c)
+	if !ok {
+		return
+	}
+
+	var req model.FolderRenameRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	folder, err := ctrl.service.RenameFolder(c.Request.Context(), folderID, userID, req)
+	if err != nil {
+		ctrl.handleError(c, err, "Failed to rename folder")
+		return
+	}
+
+	c.JSON(http.StatusOK, folder)
+}
+
+func (ctrl *folderController) MoveFolder(c *gin.Context) {
+	folderID, userID, ok := ctrl.parseRequest(c)
+	if !ok {
+		return
+	}
+
+	var req model.FolderMoveRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	folder, err := ctrl.service.MoveFolder(c.Request.Context(), folderID, userID, req)
+	if err != nil {
+		ctrl.handleError(c, err, "Failed to move folder")
+		return
+	}
+
+	c.JSON(http.StatusOK, folder)
+}
+
+func (ctrl *folderController) DeleteFolder(c *gin.Context) {
+	folderID, userID, ok := ctrl.parseRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.service.DeleteFolder(c.Request.Context(), folderID, userID); err != nil {
+		ctrl.handleError(c, err, "Failed to delete folder")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (ctrl *folderController) GetFolderDocuments(c *gin.Context) {
+	folderID, userID, ok := ctrl.parseRequest(c)
+	if !ok {
+		return
+	}
+
+	if _, err := ctrl.service.GetFolder(c.Request.Context(), folderID, userID); err != nil {
+		ctrl.handleError(c, err, "Failed to retrieve folder")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+	includeArchived, _ := strconv.ParseBool(c.DefaultQuery("include_archived", "false"))
+
+	documents, total, err := ctrl.docService.GetUserDocuments(
+		c.Request.Context(),
+		userID,
+		page,
+		perPage,
+		c.DefaultQuery("sort_by", "updated_at"),
+		c.DefaultQuery("sort_dir", "desc"),
+		docModel.DocumentFilter{
+			Query:           c.DefaultQuery("q", ""),
+			FolderID:        &folderID,
+			IncludeArchived: includeArchived,
+		},
+	)
+	if err != nil {
+		ctrl.handleError(c, err, "Failed to retrieve documents")
+		return
+	}
+
+	totalPages := (int(total) + perPage - 1) / perPage
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": documents,
+		"pagination": gin.H{
+			"total":       total,
+			"page":        page,
+			"per_page":    perPage,
+			"total_pages": totalPages,
+		},
+	})
+}
+
+func (ctrl *folderController) MoveDocument(c *gin.Context) {
+	documentID, userID, ok := ctrl.parseRequest(c)
+	if !ok {
+		return
+	}
+
+	var req model.DocumentMoveRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if err := ctrl.service.MoveDocument(c.Request.Context(), documentID, userID, req); err != nil {
+		ctrl.handleError(c, err, "Failed to move document")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetPins lists the documents pinned to the folder in the path, or to the
+// workspace for /folders/root/pins, in order
+func (ctrl *folderController) GetPins(c *gin.Context) {
+	folderID, userID, ok := ctrl.parsePinRequest(c)
+	if !ok {
+		return
+	}
+
+	pins, err := ctrl.service.GetPins(c.Request.Context(), folderID, userID)
+	if err != nil {
+		ctrl.handleError(c, err, "Failed to retrieve pins")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": pins})
+}
+
+// ReorderPins replaces the pins of the folder in the path, or of the
+// workspace for /folders/root/pins, with the documents in the body in order
+func (ctrl *folderController) ReorderPins(c *gin.Context) {
+	folderID, userID, ok := ctrl.parsePinRequest(c)
+	if !ok {
+		return
+	}
+
+	var req model.PinOrderRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	pins, err := ctrl.service.ReorderPins(c.Request.Context(), folderID, userID, req)
+	if err != nil {
+		ctrl.handleError(c, err, "Failed to reorder pins")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": pins})
+}
+
+// parsePinRequest is parseRequest for pin routes, where the folder ID
+// "root" stands for the workspace and parses to nil
+func (ctrl *folderController) parsePinRequest(c *gin.Context) (*uuid.UUID, uuid.UUID, bool) {
+	if c.Param("id") != "root" {
+		folderID, userID, ok := ctrl.parseRequest(c)
+		return &folderID, userID, ok
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return nil, uuid.Nil, false
+	}
+
+	return nil, userID.(uuid.UUID), true
+}
+
+// parseRequest extracts the :id path parameter and the authenticated
+// user, writing an error response when either is missing
+func (ctrl *folderController) parseRequest(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid ID",
+		}})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return id, userID.(uuid.UUID), true
+}
+
+func bindJSON(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid request data",
+			"details": err.Error(),
+		}})
+		return false
+	}
+	return true
+}
+
+func (ctrl *folderController) handleError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrFolderNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+			"code":    errcode.FolderNotFound,
+			"message": "Folder not found",
+		}})
+	case service.ErrDocumentNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+			"code":    errcode.DocNotFound,
+			"message": "Document not found",
+		}})
+	case service.ErrInvalidParent:
+		c.JSON(http.StatusConflict, gin.H{"error": gin.H{
+			"code":    errcode.InvalidFolderParent,
+			"message": "A folder cannot be moved into itself or one of its subfolders",
+		}})
+	case service.ErrNotInFolder:
+		c.JSON(http.StatusConflict, gin.H{"error": gin.H{
+			"code":    errcode.DocumentNotInFolder,
+			"message": "Only documents filed in the folder can be pinned to it",
+		}})
+	case service.ErrUnauthorized:
+		c.JSON(http.StatusForbidden, gin.H{"error": gin.H{
+			"code":    errcode.Forbidden,
+			"message": "You don't have permission to access this folder",
+		}})
+	default:
+		logging.FromContext(c.Request.Context(), ctrl.logger).Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
+			"code":    errcode.InternalError,
+			"message": message,
+		}})
+	}
+}
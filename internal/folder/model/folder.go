@@ -0,0 +1,45 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Folder groups documents of its owner; folders nest through ParentID
+type Folder struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	OwnerID   uuid.UUID  `gorm:"type:uuid;not null" json:"owner_id"`
+	ParentID  *uuid.UUID `gorm:"type:uuid" json:"parent_id"`
+	Name      string     `gorm:"type:varchar(255);not null" json:"name"`
+	CreatedAt time.Time  `gorm:"not null" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"not null" json:"updated_at"`
+}
+
+func (f *Folder) BeforeCreate(tx *gorm.DB) error {
+	if f.ID == uuid.Nil {
+		f.ID = uuid.New()
+	}
+	return nil
+}
+
+type FolderCreateRequest struct {
+	Name string `json:"name" binding:"required,max=255"`
+	// ParentID nests the folder; omit it to create a top-level folder
+	ParentID *uuid.UUID `json:"parent_id"`
+}
+
+type FolderRenameRequest struct {
+	Name string `json:"name" binding:"required,max=255"`
+}
+
+type FolderMoveRequest struct {
+	// ParentID is the new parent; null moves the folder to the top level
+	ParentID *uuid.UUID `json:"parent_id"`
+}
+
+type DocumentMoveRequest struct {
+	// FolderID is the destination; null moves the document out of any folder
+	FolderID *uuid.UUID `json:"folder_id"`
+}
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FolderPin keeps a document at the top of a folder listing, at Position
+// among the folder's pins. A nil FolderID pins the document to the top of
+// its owner's workspace listing instead.
+type FolderPin struct {
+	OwnerID    uuid.UUID  `gorm:"type:uuid;not null" json:"-"`
+	FolderID   *uuid.UUID `gorm:"type:uuid" json:"folder_id"`
+	DocumentID uuid.UUID  `gorm:"type:uuid;not null" json:"document_id"`
+	Position   int        `gorm:"not null" json:"position"`
+	CreatedAt  time.Time  `gorm:"not null" json:"created_at"`
+}
+
+func (FolderPin) TableName() string {
+	return "folder_pins"
+}
+
+type PinOrderRequest struct {
+	// DocumentIDs are the pinned documents, first to last; documents left
+	// out are unpinned, so an empty list clears the pins
+	DocumentIDs []uuid.UUID `json:"document_ids" binding:"max=100"`
+}
@@ -0,0 +1,20 @@
+package folder
+
+import (
+	"go.uber.org/fx"
+
+	"github.com/hafiztri123/document-api/internal/api"
+	"github.com/hafiztri123/document-api/internal/folder/controller"
+	"github.com/hafiztri123/document-api/internal/folder/repository"
+	"github.com/hafiztri123/document-api/internal/folder/service"
+)
+
+// Module provides the folder repository, service, controller and routes
+var Module = fx.Module("folder",
+	fx.Provide(
+		repository.NewFolderRepository,
+		service.NewFolderService,
+		controller.NewFolderController,
+		api.AsRouteRegistrar(newRoutes),
+	),
+)
@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hafiztri123/document-api/internal/folder/model"
+	"github.com/hafiztri123/document-api/internal/logging"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	CreateFolder(ctx context.Context, folder *model.Folder) error
+	GetFolderByID(ctx context.Context, id uuid.UUID) (*model.Folder, error)
+	GetFoldersByOwnerID(ctx context.Context, ownerID uuid.UUID) ([]*model.Folder, error)
+	UpdateFolder(ctx context.Context, folder *model.Folder) error
+	DeleteFolder(ctx context.Context, folder *model.Folder) error
+
+	// IsAncestorOrSelf reports whether ancestorID is folderID or one of its ancestors
+	IsAncestorOrSelf(ctx context.Context, ancestorID, folderID uuid.UUID) (bool, error)
+
+	// Pins of a folder, or of ownerID's workspace when folderID is nil,
+	// in position order
+	GetPins(ctx context.Context, ownerID uuid.UUID, folderID *uuid.UUID) ([]*model.FolderPin, error)
+	// ReplacePins swaps every pin of the folder or workspace for pins
+	ReplacePins(ctx context.Context, ownerID uuid.UUID, folderID *uuid.UUID, pins []*model.FolderPin) error
+	// GetFiledDocumentIDs returns which of ids are documents ownerID owns
+	// that are not deleted, only those filed in folderID unless it is nil
+	GetFiledDocumentIDs(ctx context.Context, ownerID uuid.UUID, folderID *uuid.UUID, ids []uuid.UUID) ([]uuid.UUID, error)
+}
+
+type folderRepository struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+func NewFolderRepository(db *gorm.DB, logger *zap.Logger) Repository {
+	return &folderRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *folderRepository) CreateFolder(ctx context.Context, folder *model.Folder) error {
+	if err := r.db.WithContext(ctx).Create(folder).Error; err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to create folder", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r *folderRepository) GetFolderByID(ctx context.Context, id uuid.UUID) (*model.Folder, error) {
+	var folder model.Folder
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&folder).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get folder by ID", zap.Error(err))
+		return nil, err
+	}
+	return &folder, nil
+}
+
+func (r *folderRepository) GetFoldersByOwnerID(ctx context.Context, ownerID uuid.UUID) ([]*model.Folder, error) {
+	var folders []*model.Folder
+	err := r.db.WithContext(ctx).Where("owner_id = ?", ownerID).Order("name ASC").Find(&folders).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get folders by owner ID", zap.Error(err))
+		return nil, err
+	}
+	return folders, nil
+}
+
+func (r *folderRepository) UpdateFolder(ctx context.Context, folder *model.Folder) error {
+	if err := r.db.WithContext(ctx).Save(folder).Error; err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to update folder", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// DeleteFolder removes a folder and hands its subfolders and documents to
+// its parent, so deleting a folder never deletes content
+func (r *folderRepository) DeleteFolder(ctx context.Context, folder *model.Folder) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Folder{}).
+			Where("parent_id = ?", folder.ID).
+			Updates(map[string]interface{}{"parent_id": folder.ParentID, "updated_at": time.Now()}).Error; err != nil {
+			return err
+		}
+
+		// Raw table update: document hooks would bump the version
+		if err := tx.Table("documents").
+			Where("folder_id = ?", folder.ID).
+			Update("folder_id", folder.ParentID).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&model.Folder{}, folder.ID).Error
+	})
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to delete folder", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r *folderRepository) IsAncestorOrSelf(ctx context.Context, ancestorID, folderID uuid.UUID) (bool, error) {
+	var found bool
+	err := r.db.WithContext(ctx).Raw(`
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id FROM folders WHERE id = ?
+			UNION
+			SELECT f.id, f.parent_id FROM folders f JOIN ancestors a ON f.id = a.parent_id
+		)
+		SELECT EXISTS (SELECT 1 FROM ancestors WHERE id = ?)`, folderID, ancestorID).
+		Scan(&found).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to walk folder ancestors", zap.Error(err))
+		return false, err
+	}
+	return found, nil
+}
+
+// pinScope narrows a query to the pins of a folder, or of ownerID's
+// workspace when folderID is nil
+func pinScope(ownerID uuid.UUID, folderID *uuid.UUID) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if folderID == nil {
+			return db.Where("owner_id = ? AND folder_id IS NULL", ownerID)
+		}
+		return db.Where("folder_id = ?", *folderID)
+	}
+}
+
+func (r *folderRepository) GetPins(ctx context.Context, ownerID uuid.UUID, folderID *uuid.UUID) ([]*model.FolderPin, error) {
+	var pins []*model.FolderPin
+	err := r.db.WithContext(ctx).
+		Scopes(pinScope(ownerID, folderID)).
+		Order("position ASC").
+		Find(&pins).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get folder pins", zap.Error(err))
+		return nil, err
+	}
+	return pins, nil
+}
+
+func (r *folderRepository) ReplacePins(ctx context.Context, ownerID uuid.UUID, folderID *uuid.UUID, pins []*model.FolderPin) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Scopes(pinScope(ownerID, folderID)).Delete(&model.FolderPin{}).Error; err != nil {
+			return err
+		}
+		if len(pins) == 0 {
+			return nil
+		}
+		return tx.Create(pins).Error
+	})
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to replace folder pins", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r *folderRepository) GetFiledDocumentIDs(ctx context.Context, ownerID uuid.UUID, folderID *uuid.UUID, ids []uuid.UUID) ([]uuid.UUID, error) {
+	var filed []uuid.UUID
+	if len(ids) == 0 {
+		return filed, nil
+	}
+
+	db := r.db.WithContext(ctx).Table("documents").
+		Where("id IN ? AND owner_id = ? AND deleted_at IS NULL", ids, ownerID)
+	if folderID != nil {
+		db = db.Where("folder_id = ?", *folderID)
+	}
+
+	if err := db.Pluck("id", &filed).Error; err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to check filed documents", zap.Error(err))
+		return nil, err
+	}
+	return filed, nil
+}
@@ -0,0 +1,34 @@
+package folder
+
+import (
+	"github.com/hafiztri123/document-api/internal/api"
+	"github.com/hafiztri123/document-api/internal/folder/controller"
+)
+
+type routes struct {
+	ctrl controller.Controller
+}
+
+func newRoutes(ctrl controller.Controller) *routes {
+	return &routes{ctrl: ctrl}
+}
+
+func (r *routes) RegisterRoutes(groups api.Groups) {
+	folders := groups.Protected.Group("/folders")
+	{
+		folders.POST("", r.ctrl.CreateFolder)
+		folders.GET("", r.ctrl.GetFolders)
+		folders.PUT("/:id", r.ctrl.RenameFolder)
+		folders.POST("/:id/move", r.ctrl.MoveFolder)
+		folders.DELETE("/:id", r.ctrl.DeleteFolder)
+		folders.GET("/:id/documents", r.ctrl.GetFolderDocuments)
+
+		// Pinning documents to the top of a folder listing; the ID "root"
+		// pins to the workspace listing
+		folders.GET("/:id/pins", r.ctrl.GetPins)
+		folders.PUT("/:id/pins", r.ctrl.ReorderPins)
+	}
+
+	// Filing a document
+	groups.Protected.PUT("/documents/:id/folder", r.ctrl.MoveDocument)
+}
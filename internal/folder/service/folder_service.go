@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	docRepo "github.com/hafiztri123/document-api/internal/document/repository"
+	"github.com/hafiztri123/document-api/internal/folder/model"
+	"github.com/hafiztri123/document-api/internal/folder/repository"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrFolderNotFound   = errors.New("folder not found")
+	ErrDocumentNotFound = errors.New("document not found")
+	ErrUnauthorized     = errors.New("unauthorized access to folder")
+	ErrInvalidParent    = errors.New("folder cannot be moved into itself or a descendant")
+	ErrNotInFolder      = errors.New("document is not filed in the folder")
+)
+
+type Service interface {
+	CreateFolder(ctx context.Context, ownerID uuid.UUID, req model.FolderCreateRequest) (*model.Folder, error)
+	GetFolder(ctx context.Context, id uuid.UUID, ownerID uuid.UUID) (*model.Folder, error)
+	GetFolders(ctx context.Context, ownerID uuid.UUID) ([]*model.Folder, error)
+	RenameFolder(ctx context.Context, id uuid.UUID, ownerID uuid.UUID, req model.FolderRenameRequest) (*model.Folder, error)
+	MoveFolder(ctx context.Context, id uuid.UUID, ownerID uuid.UUID, req model.FolderMoveRequest) (*model.Folder, error)
+	DeleteFolder(ctx context.Context, id uuid.UUID, ownerID uuid.UUID) error
+
+	// MoveDocument files one of the owner's documents into a folder
+	MoveDocument(ctx context.Context, documentID uuid.UUID, ownerID uuid.UUID, req model.DocumentMoveRequest) error
+
+	// Pins order documents at the top of a folder listing; a nil folderID
+	// means the owner's workspace listing
+	GetPins(ctx context.Context, folderID *uuid.UUID, ownerID uuid.UUID) ([]*model.FolderPin, error)
+	ReorderPins(ctx context.Context, folderID *uuid.UUID, ownerID uuid.UUID, req model.PinOrderRequest) ([]*model.FolderPin, error)
+}
+
+type folderService struct {
+	repo    repository.Repository
+	docRepo docRepo.Repository
+	logger  *zap.Logger
+}
+
+func NewFolderService(repo repository.Repository, docRepo docRepo.Repository, logger *zap.Logger) Service {
+	return &folderService{
+		repo:    repo,
+		docRepo: docRepo,
+		logger:  logger,
+	}
+}
+
+func (s *folderService) CreateFolder(ctx context.Context, ownerID uuid.UUID, req model.FolderCreateRequest) (*model.Folder, error) {
+	if req.ParentID != nil {
+		if _, err := s.GetFolder(ctx, *req.ParentID, ownerID); err != nil {
+			return nil, err
+		}
+	}
+
+	folder := &model.Folder{
+		OwnerID:   ownerID,
+		ParentID:  req.ParentID,
+		Name:      req.Name,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	if err := s.repo.CreateFolder(ctx, folder); err != nil {
+		return nil, err
+	}
+
+	return folder, nil
+}
+
+// GetFolder returns a folder owned by ownerID
+func (s *folderService) GetFolder(ctx context.Context, id uuid.UUID, ownerID uuid.UUID) (*model.Folder, error) {
+	folder, err := s.repo.GetFolderByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if folder == nil {
+		return nil, ErrFolderNotFound
+	}
+
+	if folder.OwnerID != ownerID {
+		return nil, ErrUnauthorized
+	}
+
+	return folder, nil
+}
+
+func (s *folderService) GetFolders(ctx context.Context, ownerID uuid.UUID) ([]*model.Folder, error) {
+	return s.repo.GetFoldersByOwnerID(ctx, ownerID)
+}
+
+func (s *folderService) RenameFolder(ctx context.Context, id uuid.UUID, ownerID uuid.UUID, req model.FolderRenameRequest) (*model.Folder, error) {
+	folder, err := s.GetFolder(ctx, id, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	folder.Name = req.Name
+	folder.UpdatedAt = time.Now()
+
+	if err := s.repo.UpdateFolder(ctx, folder); err != nil {
+		return nil, err
+	}
+
+	return folder, nil
+}
+
+func (s *folderService) MoveFolder(ctx context.Context, id uuid.UUID, ownerID uuid.UUID, req model.FolderMoveRequest) (*model.Folder, error) {
+	folder, err := s.GetFolder(ctx, id, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.ParentID != nil {
+		if _, err := s.GetFolder(ctx, *req.ParentID, ownerID); err != nil {
+			return nil, err
+		}
+
+		// The new parent must not be the folder itself or inside it
+		cycle, err := s.repo.IsAncestorOrSelf(ctx, folder.ID, *req.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		if cycle {
+			return nil, ErrInvalidParent
+		}
+	}
+
+	folder.ParentID = req.ParentID
+	folder.UpdatedAt = time.Now()
+
+	if err := s.repo.UpdateFolder(ctx, folder); err != nil {
+		return nil, err
+	}
+
+	return folder, nil
+}
+
+// DeleteFolder removes the folder; its contents move to its parent
+func (s *folderService) DeleteFolder(ctx context.Context, id uuid.UUID, ownerID uuid.UUID) error {
+	folder, err := s.GetFolder(ctx, id, ownerID)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.DeleteFolder(ctx, folder)
+}
+
+func (s *folderService) MoveDocument(ctx context.Context, documentID uuid.UUID, ownerID uuid.UUID, req model.DocumentMoveRequest) error {
+	document, err := s.docRepo.GetDocumentByID(ctx, documentID)
+	if err != nil {
+		return err
+	}
+
+	if document == nil {
+		return ErrDocumentNotFound
+	}
+
+	// Folders are personal, so only the owner files a document
+	if document.OwnerID != ownerID {
+		return ErrUnauthorized
+	}
+
+	if req.FolderID != nil {
+		if _, err := s.GetFolder(ctx, *req.FolderID, ownerID); err != nil {
+			return err
+		}
+	}
+
+	return s.docRepo.SetDocumentFolder(ctx, documentID, req.FolderID)
+}
+
+func (s *folderService) GetPins(ctx context.Context, folderID *uuid.UUID, ownerID uuid.UUID) ([]*model.FolderPin, error) {
+	if folderID != nil {
+		if _, err := s.GetFolder(ctx, *folderID, ownerID); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.repo.GetPins(ctx, ownerID, folderID)
+}
+
+// ReorderPins replaces the pins with req.DocumentIDs in order. Folders are
+// personal, so only their owner pins, and only documents filed in the
+// folder; workspace pins take any document the owner owns.
+func (s *folderService) ReorderPins(ctx context.Context, folderID *uuid.UUID, ownerID uuid.UUID, req model.PinOrderRequest) ([]*model.FolderPin, error) {
+	if folderID != nil {
+		if _, err := s.GetFolder(ctx, *folderID, ownerID); err != nil {
+			return nil, err
+		}
+	}
+
+	// A document listed twice keeps its first position
+	seen := make(map[uuid.UUID]bool, len(req.DocumentIDs))
+	ids := make([]uuid.UUID, 0, len(req.DocumentIDs))
+	for _, id := range req.DocumentIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	filed, err := s.repo.GetFiledDocumentIDs(ctx, ownerID, folderID, ids)
+	if err != nil {
+		return nil, err
+	}
+	if len(filed) != len(ids) {
+		if folderID == nil {
+			return nil, ErrDocumentNotFound
+		}
+		return nil, ErrNotInFolder
+	}
+
+	now := time.Now()
+	pins := make([]*model.FolderPin, len(ids))
+	for i, id := range ids {
+		pins[i] = &model.FolderPin{
+			OwnerID:    ownerID,
+			FolderID:   folderID,
+			DocumentID: id,
+			Position:   i + 1,
+			CreatedAt:  now,
+		}
+	}
+
+	if err := s.repo.ReplacePins(ctx, ownerID, folderID, pins); err != nil {
+		return nil, err
+	}
+
+	return pins, nil
+}
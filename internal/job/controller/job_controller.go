@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/hafiztri123/document-api/internal/errcode"
+	"github.com/hafiztri123/document-api/internal/job/model"
+	"github.com/hafiztri123/document-api/internal/job/service"
+	"github.com/hafiztri123/document-api/internal/logging"
+)
+
+type Controller interface {
+	GetJob(c *gin.Context)
+}
+
+type jobController struct {
+	service service.Service
+	logger  *zap.Logger
+}
+
+func NewJobController(service service.Service, logger *zap.Logger) Controller {
+	return &jobController{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func (ctrl *jobController) GetJob(c *gin.Context) {
+	idStr := c.Param("id")
+	jobID, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid job ID",
+		}})
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+
+	job, err := ctrl.service.GetJob(c.Request.Context(), jobID, userID.(uuid.UUID))
+	if err != nil {
+		if err == service.ErrJobNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+				"code":    errcode.JobNotFound,
+				"message": "Job not found",
+			}})
+			return
+		}
+
+		if err == service.ErrUnauthorized {
+			c.JSON(http.StatusForbidden, gin.H{"error": gin.H{
+				"code":    errcode.Forbidden,
+				"message": "You don't have permission to access this job",
+			}})
+			return
+		}
+
+		logging.FromContext(c.Request.Context(), ctrl.logger).Error("Failed to get job", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
+			"code":    errcode.InternalError,
+			"message": "Failed to retrieve job",
+		}})
+		return
+	}
+
+	c.JSON(http.StatusOK, WithSelfLink(job))
+}
+
+// Accepted writes the 202 response returned by endpoints that start a job
+func Accepted(c *gin.Context, job *model.Job) {
+	job = WithSelfLink(job)
+	c.Header("Location", job.Links["self"])
+	c.JSON(http.StatusAccepted, job)
+}
+
+// WithSelfLink returns a copy of the job whose links include its status URL
+func WithSelfLink(job *model.Job) *model.Job {
+	copied := *job
+	copied.Links = map[string]string{
+		"self": fmt.Sprintf("/api/v1/jobs/%s", job.ID),
+	}
+	for name, link := range job.Links {
+		copied.Links[name] = link
+	}
+	return &copied
+}
@@ -0,0 +1,37 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+// Job tracks a long-running operation started by a user
+type Job struct {
+	ID          uuid.UUID         `json:"id"`
+	Type        string            `json:"type"`
+	OwnerID     uuid.UUID         `json:"owner_id"`
+	Status      Status            `json:"status"`
+	Progress    int               `json:"progress"`
+	Result      json.RawMessage   `json:"result,omitempty"`
+	Links       map[string]string `json:"links,omitempty"`
+	Error       string            `json:"error,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+	CompletedAt *time.Time        `json:"completed_at,omitempty"`
+}
+
+// Done reports whether the job reached a terminal status
+func (j *Job) Done() bool {
+	return j.Status == StatusSucceeded || j.Status == StatusFailed
+}
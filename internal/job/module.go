@@ -0,0 +1,20 @@
+package job
+
+import (
+	"go.uber.org/fx"
+
+	"github.com/hafiztri123/document-api/internal/api"
+	"github.com/hafiztri123/document-api/internal/job/controller"
+	"github.com/hafiztri123/document-api/internal/job/repository"
+	"github.com/hafiztri123/document-api/internal/job/service"
+)
+
+// Module provides the job repository, service, controller and routes
+var Module = fx.Module("job",
+	fx.Provide(
+		repository.NewJobRepository,
+		service.NewJobService,
+		controller.NewJobController,
+		api.AsRouteRegistrar(newRoutes),
+	),
+)
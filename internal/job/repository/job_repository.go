@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hafiztri123/document-api/internal/job/model"
+	"github.com/hafiztri123/document-api/internal/logging"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// jobTTL is how long a job record is kept after its last update
+const jobTTL = 24 * time.Hour
+
+type Repository interface {
+	SaveJob(ctx context.Context, job *model.Job) error
+	GetJob(ctx context.Context, id uuid.UUID) (*model.Job, error)
+}
+
+type jobRepository struct {
+	redis  *redis.Client
+	logger *zap.Logger
+}
+
+func NewJobRepository(redis *redis.Client, logger *zap.Logger) Repository {
+	return &jobRepository{
+		redis:  redis,
+		logger: logger,
+	}
+}
+
+func (r *jobRepository) SaveJob(ctx context.Context, job *model.Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to marshal job", zap.Error(err))
+		return err
+	}
+
+	if err := r.redis.Set(ctx, jobKey(job.ID), data, jobTTL).Err(); err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to save job", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *jobRepository) GetJob(ctx context.Context, id uuid.UUID) (*model.Job, error) {
+	data, err := r.redis.Get(ctx, jobKey(id)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get job", zap.Error(err))
+		return nil, err
+	}
+
+	var job model.Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to unmarshal job", zap.Error(err))
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+func jobKey(id uuid.UUID) string {
+	return fmt.Sprintf("job:%s", id)
+}
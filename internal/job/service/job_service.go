@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hafiztri123/document-api/internal/job/model"
+	"github.com/hafiztri123/document-api/internal/job/repository"
+	"github.com/hafiztri123/document-api/internal/logging"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrJobNotFound  = errors.New("job not found")
+	ErrUnauthorized = errors.New("unauthorized access to job")
+)
+
+// jobTimeout bounds how long a single job may run
+const jobTimeout = 30 * time.Minute
+
+// ProgressFunc reports job progress as a percentage between 0 and 100
+type ProgressFunc func(percent int)
+
+// Func is the unit of work executed by a job. The returned result is stored
+// on the job as JSON; links are exposed to clients as result links.
+type Func func(ctx context.Context, progress ProgressFunc) (result interface{}, links map[string]string, err error)
+
+type Service interface {
+	Enqueue(ctx context.Context, ownerID uuid.UUID, jobType string, fn Func) (*model.Job, error)
+	GetJob(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*model.Job, error)
+}
+
+type jobService struct {
+	repo   repository.Repository
+	logger *zap.Logger
+}
+
+func NewJobService(repo repository.Repository, logger *zap.Logger) Service {
+	return &jobService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// Enqueue records a pending job and runs fn in the background, detached
+// from the request context
+func (s *jobService) Enqueue(ctx context.Context, ownerID uuid.UUID, jobType string, fn Func) (*model.Job, error) {
+	now := time.Now()
+	job := &model.Job{
+		ID:        uuid.New(),
+		Type:      jobType,
+		OwnerID:   ownerID,
+		Status:    model.StatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := s.repo.SaveJob(ctx, job); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to enqueue job", zap.Error(err))
+		return nil, err
+	}
+
+	snapshot := *job
+	go s.run(logging.Detach(ctx), &snapshot, fn)
+
+	return job, nil
+}
+
+// run executes fn with a context detached from the enqueuing request; it
+// keeps that request's logger, tagged with the job, so the job's logs can
+// be correlated with the request that started it
+func (s *jobService) run(ctx context.Context, job *model.Job, fn Func) {
+	ctx, cancel := context.WithTimeout(ctx, jobTimeout)
+	defer cancel()
+
+	logger := logging.FromContext(ctx, s.logger).With(
+		zap.String("jobID", job.ID.String()),
+		zap.String("jobType", job.Type))
+	ctx = logging.NewContext(ctx, logger)
+
+	job.Status = model.StatusRunning
+	s.save(ctx, job, logger)
+
+	var (
+		result interface{}
+		links  map[string]string
+		err    error
+	)
+
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("job panicked: %v", r)
+			}
+		}()
+
+		result, links, err = fn(ctx, func(percent int) {
+			if percent < 0 {
+				percent = 0
+			} else if percent > 100 {
+				percent = 100
+			}
+			job.Progress = percent
+			s.save(ctx, job, logger)
+		})
+	}()
+
+	completedAt := time.Now()
+	job.CompletedAt = &completedAt
+	job.Links = links
+
+	if err != nil {
+		logger.Error("Job failed", zap.Error(err))
+		job.Status = model.StatusFailed
+		job.Error = err.Error()
+		s.save(ctx, job, logger)
+		return
+	}
+
+	if result != nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			logger.Error("Failed to marshal job result", zap.Error(err))
+			job.Status = model.StatusFailed
+			job.Error = "failed to store job result"
+			s.save(ctx, job, logger)
+			return
+		}
+		job.Result = data
+	}
+
+	job.Status = model.StatusSucceeded
+	job.Progress = 100
+	s.save(ctx, job, logger)
+	logger.Info("Job completed")
+}
+
+func (s *jobService) save(ctx context.Context, job *model.Job, logger *zap.Logger) {
+	job.UpdatedAt = time.Now()
+	if err := s.repo.SaveJob(ctx, job); err != nil {
+		logger.Error("Failed to update job", zap.Error(err))
+	}
+}
+
+func (s *jobService) GetJob(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*model.Job, error) {
+	job, err := s.repo.GetJob(ctx, id)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get job", zap.Error(err))
+		return nil, err
+	}
+
+	if job == nil {
+		return nil, ErrJobNotFound
+	}
+
+	if job.OwnerID != userID {
+		return nil, ErrUnauthorized
+	}
+
+	return job, nil
+}
@@ -0,0 +1,228 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/hafiztri123/document-api/internal/errcode"
+	"github.com/hafiztri123/document-api/internal/kb/model"
+	"github.com/hafiztri123/document-api/internal/kb/service"
+	"github.com/hafiztri123/document-api/internal/logging"
+)
+
+type Controller interface {
+	CreateSpace(c *gin.Context)
+	GetSpaces(c *gin.Context)
+
+	PublishDocument(c *gin.Context)
+	UnpublishDocument(c *gin.Context)
+	GetPublication(c *gin.Context)
+
+	GetPublishedPage(c *gin.Context)
+}
+
+type kbController struct {
+	service service.Service
+	logger  *zap.Logger
+}
+
+func NewKBController(service service.Service, logger *zap.Logger) Controller {
+	return &kbController{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func (ctrl *kbController) CreateSpace(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req model.SpaceCreateRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	space, err := ctrl.service.CreateSpace(c.Request.Context(), userID, req)
+	if err != nil {
+		ctrl.handleError(c, err, "Failed to create space")
+		return
+	}
+
+	c.JSON(http.StatusCreated, space)
+}
+
+func (ctrl *kbController) GetSpaces(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	spaces, err := ctrl.service.GetSpaces(c.Request.Context(), userID)
+	if err != nil {
+		ctrl.handleError(c, err, "Failed to retrieve spaces")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": spaces})
+}
+
+func (ctrl *kbController) PublishDocument(c *gin.Context) {
+	documentID, userID, ok := ctrl.parseRequest(c)
+	if !ok {
+		return
+	}
+
+	var req model.PublishRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	page, err := ctrl.service.Publish(c.Request.Context(), documentID, userID, req)
+	if err != nil {
+		ctrl.handleError(c, err, "Failed to publish document")
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+func (ctrl *kbController) UnpublishDocument(c *gin.Context) {
+	documentID, userID, ok := ctrl.parseRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.service.Unpublish(c.Request.Context(), documentID, userID); err != nil {
+		ctrl.handleError(c, err, "Failed to unpublish document")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (ctrl *kbController) GetPublication(c *gin.Context) {
+	documentID, userID, ok := ctrl.parseRequest(c)
+	if !ok {
+		return
+	}
+
+	page, err := ctrl.service.GetPublication(c.Request.Context(), documentID, userID)
+	if err != nil {
+		ctrl.handleError(c, err, "Failed to retrieve publication")
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// GetPublishedPage serves the knowledge base without authentication.
+// Renamed or moved pages answer with a permanent redirect.
+func (ctrl *kbController) GetPublishedPage(c *gin.Context) {
+	page, location, err := ctrl.service.GetPublishedPage(c.Request.Context(), c.Param("workspace"), c.Param("slug"))
+	if err != nil {
+		ctrl.handleError(c, err, "Failed to retrieve page")
+		return
+	}
+
+	if location != "" {
+		c.Redirect(http.StatusMovedPermanently, location)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// parseRequest extracts the :id path parameter and the authenticated
+// user, writing an error response when either is missing
+func (ctrl *kbController) parseRequest(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid ID",
+		}})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	userID, ok := currentUser(c)
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return id, userID, true
+}
+
+func currentUser(c *gin.Context) (uuid.UUID, bool) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return uuid.Nil, false
+	}
+	return userID.(uuid.UUID), true
+}
+
+func bindJSON(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid request data",
+			"details": err.Error(),
+		}})
+		return false
+	}
+	return true
+}
+
+func (ctrl *kbController) handleError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrSpaceNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+			"code":    errcode.SpaceNotFound,
+			"message": "Space not found",
+		}})
+	case service.ErrPageNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+			"code":    errcode.PageNotFound,
+			"message": "Page not found",
+		}})
+	case service.ErrDocumentNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+			"code":    errcode.DocNotFound,
+			"message": "Document not found",
+		}})
+	case service.ErrInvalidSlug:
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": err.Error(),
+		}})
+	case service.ErrSlugTaken:
+		c.JSON(http.StatusConflict, gin.H{"error": gin.H{
+			"code":    errcode.SlugTaken,
+			"message": "Slug is already in use",
+		}})
+	case service.ErrUnauthorized:
+		c.JSON(http.StatusForbidden, gin.H{"error": gin.H{
+			"code":    errcode.Forbidden,
+			"message": "You don't have permission to publish this document",
+		}})
+	case service.ErrEncrypted:
+		c.JSON(http.StatusConflict, gin.H{"error": gin.H{
+			"code":    errcode.Encrypted,
+			"message": "Encrypted documents cannot be published",
+		}})
+	default:
+		logging.FromContext(c.Request.Context(), ctrl.logger).Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
+			"code":    errcode.InternalError,
+			"message": message,
+		}})
+	}
+}
@@ -0,0 +1,102 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Space is a knowledge base namespace; its slug is the :workspace segment
+// of published URLs
+type Space struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	OwnerID   uuid.UUID `gorm:"type:uuid;not null" json:"owner_id"`
+	Slug      string    `gorm:"type:varchar(100);not null;uniqueIndex" json:"slug"`
+	Name      string    `gorm:"type:varchar(255);not null" json:"name"`
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+	UpdatedAt time.Time `gorm:"not null" json:"updated_at"`
+}
+
+func (Space) TableName() string {
+	return "kb_spaces"
+}
+
+func (s *Space) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+// Page publishes a document under a slug within a space. A document is
+// published at most once.
+type Page struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	SpaceID     uuid.UUID `gorm:"type:uuid;not null" json:"space_id"`
+	DocumentID  uuid.UUID `gorm:"type:uuid;not null;uniqueIndex" json:"document_id"`
+	Slug        string    `gorm:"type:varchar(100);not null" json:"slug"`
+	PublishedAt time.Time `gorm:"not null" json:"published_at"`
+	UpdatedAt   time.Time `gorm:"not null" json:"updated_at"`
+}
+
+func (Page) TableName() string {
+	return "kb_pages"
+}
+
+func (p *Page) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+// Redirect keeps a page's previous address working after a rename or move
+type Redirect struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	SpaceID   uuid.UUID `gorm:"type:uuid;not null" json:"space_id"`
+	Slug      string    `gorm:"type:varchar(100);not null" json:"slug"`
+	PageID    uuid.UUID `gorm:"type:uuid;not null" json:"page_id"`
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+}
+
+func (Redirect) TableName() string {
+	return "kb_redirects"
+}
+
+func (r *Redirect) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+type SpaceCreateRequest struct {
+	Slug string `json:"slug" binding:"required,max=100"`
+	Name string `json:"name" binding:"required,max=255"`
+}
+
+// PublishRequest publishes a document, or renames or moves its page when it
+// is already published
+type PublishRequest struct {
+	SpaceID uuid.UUID `json:"space_id" binding:"required"`
+	Slug    string    `json:"slug" binding:"required,max=100"`
+}
+
+// PageResponse describes where a document is published
+type PageResponse struct {
+	Page
+	Space string `json:"space"`
+	Path  string `json:"path"`
+}
+
+// PublishedPage is what readers of the knowledge base receive
+type PublishedPage struct {
+	Space       string    `json:"space"`
+	Slug        string    `json:"slug"`
+	Title       string    `json:"title"`
+	Content     string    `json:"content"`
+	Version     int       `json:"version"`
+	PublishedAt time.Time `json:"published_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
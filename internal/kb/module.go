@@ -0,0 +1,20 @@
+package kb
+
+import (
+	"go.uber.org/fx"
+
+	"github.com/hafiztri123/document-api/internal/api"
+	"github.com/hafiztri123/document-api/internal/kb/controller"
+	"github.com/hafiztri123/document-api/internal/kb/repository"
+	"github.com/hafiztri123/document-api/internal/kb/service"
+)
+
+// Module provides the knowledge base repository, service, controller and routes
+var Module = fx.Module("kb",
+	fx.Provide(
+		repository.NewKBRepository,
+		service.NewKBService,
+		controller.NewKBController,
+		api.AsRouteRegistrar(newRoutes),
+	),
+)
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/hafiztri123/document-api/internal/kb/model"
+	"github.com/hafiztri123/document-api/internal/logging"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	CreateSpace(ctx context.Context, space *model.Space) error
+	GetSpaceByID(ctx context.Context, id uuid.UUID) (*model.Space, error)
+	GetSpaceBySlug(ctx context.Context, slug string) (*model.Space, error)
+	GetSpacesByOwnerID(ctx context.Context, ownerID uuid.UUID) ([]*model.Space, error)
+
+	GetPageByID(ctx context.Context, id uuid.UUID) (*model.Page, error)
+	GetPageByDocumentID(ctx context.Context, documentID uuid.UUID) (*model.Page, error)
+	GetPageBySlug(ctx context.Context, spaceID uuid.UUID, slug string) (*model.Page, error)
+	// SavePage creates or updates a page. It claims the page's slug from any
+	// redirect holding it and, when redirect is not nil, records it.
+	SavePage(ctx context.Context, page *model.Page, redirect *model.Redirect) error
+	DeletePage(ctx context.Context, id uuid.UUID) error
+
+	GetRedirect(ctx context.Context, spaceID uuid.UUID, slug string) (*model.Redirect, error)
+}
+
+type kbRepository struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+func NewKBRepository(db *gorm.DB, logger *zap.Logger) Repository {
+	return &kbRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *kbRepository) CreateSpace(ctx context.Context, space *model.Space) error {
+	if err := r.db.WithContext(ctx).Create(space).Error; err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to create KB space", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r *kbRepository) GetSpaceByID(ctx context.Context, id uuid.UUID) (*model.Space, error) {
+	return r.findSpace(ctx, "id = ?", id)
+}
+
+func (r *kbRepository) GetSpaceBySlug(ctx context.Context, slug string) (*model.Space, error) {
+	return r.findSpace(ctx, "slug = ?", slug)
+}
+
+func (r *kbRepository) findSpace(ctx context.Context, query string, args ...interface{}) (*model.Space, error) {
+	var space model.Space
+	err := r.db.WithContext(ctx).Where(query, args...).First(&space).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get KB space", zap.Error(err))
+		return nil, err
+	}
+	return &space, nil
+}
+
+func (r *kbRepository) GetSpacesByOwnerID(ctx context.Context, ownerID uuid.UUID) ([]*model.Space, error) {
+	var spaces []*model.Space
+	err := r.db.WithContext(ctx).Where("owner_id = ?", ownerID).Order("name ASC").Find(&spaces).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get KB spaces by owner ID", zap.Error(err))
+		return nil, err
+	}
+	return spaces, nil
+}
+
+func (r *kbRepository) GetPageByID(ctx context.Context, id uuid.UUID) (*model.Page, error) {
+	return r.findPage(ctx, "id = ?", id)
+}
+
+func (r *kbRepository) GetPageByDocumentID(ctx context.Context, documentID uuid.UUID) (*model.Page, error) {
+	return r.findPage(ctx, "document_id = ?", documentID)
+}
+
+func (r *kbRepository) GetPageBySlug(ctx context.Context, spaceID uuid.UUID, slug string) (*model.Page, error) {
+	return r.findPage(ctx, "space_id = ? AND slug = ?", spaceID, slug)
+}
+
+func (r *kbRepository) findPage(ctx context.Context, query string, args ...interface{}) (*model.Page, error) {
+	var page model.Page
+	err := r.db.WithContext(ctx).Where(query, args...).First(&page).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get KB page", zap.Error(err))
+		return nil, err
+	}
+	return &page, nil
+}
+
+func (r *kbRepository) SavePage(ctx context.Context, page *model.Page, redirect *model.Redirect) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("space_id = ? AND slug = ?", page.SpaceID, page.Slug).
+			Delete(&model.Redirect{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Save(page).Error; err != nil {
+			return err
+		}
+
+		if redirect != nil {
+			return tx.Create(redirect).Error
+		}
+		return nil
+	})
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to save KB page", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// DeletePage removes a page; its redirects are removed with it
+func (r *kbRepository) DeletePage(ctx context.Context, id uuid.UUID) error {
+	if err := r.db.WithContext(ctx).Delete(&model.Page{}, id).Error; err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to delete KB page", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r *kbRepository) GetRedirect(ctx context.Context, spaceID uuid.UUID, slug string) (*model.Redirect, error) {
+	var redirect model.Redirect
+	err := r.db.WithContext(ctx).Where("space_id = ? AND slug = ?", spaceID, slug).First(&redirect).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get KB redirect", zap.Error(err))
+		return nil, err
+	}
+	return &redirect, nil
+}
@@ -0,0 +1,31 @@
+package kb
+
+import (
+	"github.com/hafiztri123/document-api/internal/api"
+	"github.com/hafiztri123/document-api/internal/kb/controller"
+)
+
+type routes struct {
+	ctrl controller.Controller
+}
+
+func newRoutes(ctrl controller.Controller) *routes {
+	return &routes{ctrl: ctrl}
+}
+
+func (r *routes) RegisterRoutes(groups api.Groups) {
+	// Space management
+	spaces := groups.Protected.Group("/kb/spaces")
+	{
+		spaces.POST("", r.ctrl.CreateSpace)
+		spaces.GET("", r.ctrl.GetSpaces)
+	}
+
+	// Publishing a document
+	groups.Protected.GET("/documents/:id/publish", r.ctrl.GetPublication)
+	groups.Protected.PUT("/documents/:id/publish", r.ctrl.PublishDocument)
+	groups.Protected.DELETE("/documents/:id/publish", r.ctrl.UnpublishDocument)
+
+	// Reading the knowledge base
+	groups.Public.GET("/kb/:workspace/:slug", r.ctrl.GetPublishedPage)
+}
@@ -0,0 +1,395 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/hafiztri123/document-api/config"
+	docModel "github.com/hafiztri123/document-api/internal/document/model"
+	docRepo "github.com/hafiztri123/document-api/internal/document/repository"
+	"github.com/hafiztri123/document-api/internal/kb/model"
+	"github.com/hafiztri123/document-api/internal/kb/repository"
+	"github.com/hafiztri123/document-api/internal/logging"
+)
+
+var (
+	ErrSpaceNotFound    = errors.New("knowledge base space not found")
+	ErrPageNotFound     = errors.New("published page not found")
+	ErrDocumentNotFound = errors.New("document not found")
+	ErrUnauthorized     = errors.New("unauthorized access to knowledge base")
+	ErrInvalidSlug      = errors.New("slugs may only contain lowercase letters, digits and single hyphens")
+	ErrSlugTaken        = errors.New("slug is already in use")
+	ErrEncrypted        = errors.New("encrypted documents cannot be published")
+)
+
+// slugPattern accepts lowercase words separated by single hyphens
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+type Service interface {
+	CreateSpace(ctx context.Context, ownerID uuid.UUID, req model.SpaceCreateRequest) (*model.Space, error)
+	GetSpaces(ctx context.Context, ownerID uuid.UUID) ([]*model.Space, error)
+
+	// Publish publishes a document, or renames or moves its page; the old
+	// address keeps redirecting to the new one
+	Publish(ctx context.Context, documentID uuid.UUID, ownerID uuid.UUID, req model.PublishRequest) (*model.PageResponse, error)
+	Unpublish(ctx context.Context, documentID uuid.UUID, ownerID uuid.UUID) error
+	GetPublication(ctx context.Context, documentID uuid.UUID, ownerID uuid.UUID) (*model.PageResponse, error)
+
+	// GetPublishedPage resolves a public address. When the address has
+	// moved, the page is nil and the new path is returned instead.
+	GetPublishedPage(ctx context.Context, spaceSlug, slug string) (*model.PublishedPage, string, error)
+}
+
+type kbService struct {
+	repo     repository.Repository
+	docRepo  docRepo.Repository
+	redis    *redis.Client
+	cacheTTL time.Duration
+	logger   *zap.Logger
+}
+
+func NewKBService(repo repository.Repository, docRepo docRepo.Repository, redis *redis.Client, logger *zap.Logger) Service {
+	cacheTTL, err := time.ParseDuration(viper.GetString(config.KB_CACHE_TTL))
+	if err != nil || cacheTTL < 0 {
+		logger.Warn("Invalid kb.cache_ttl, using default 5m", zap.Error(err))
+		cacheTTL = 5 * time.Minute
+	}
+
+	return &kbService{
+		repo:     repo,
+		docRepo:  docRepo,
+		redis:    redis,
+		cacheTTL: cacheTTL,
+		logger:   logger,
+	}
+}
+
+func (s *kbService) CreateSpace(ctx context.Context, ownerID uuid.UUID, req model.SpaceCreateRequest) (*model.Space, error) {
+	if !slugPattern.MatchString(req.Slug) {
+		return nil, ErrInvalidSlug
+	}
+
+	existing, err := s.repo.GetSpaceBySlug(ctx, req.Slug)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrSlugTaken
+	}
+
+	space := &model.Space{
+		OwnerID:   ownerID,
+		Slug:      req.Slug,
+		Name:      req.Name,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	if err := s.repo.CreateSpace(ctx, space); err != nil {
+		return nil, err
+	}
+
+	return space, nil
+}
+
+func (s *kbService) GetSpaces(ctx context.Context, ownerID uuid.UUID) ([]*model.Space, error) {
+	return s.repo.GetSpacesByOwnerID(ctx, ownerID)
+}
+
+func (s *kbService) Publish(ctx context.Context, documentID uuid.UUID, ownerID uuid.UUID, req model.PublishRequest) (*model.PageResponse, error) {
+	document, err := s.ownedDocument(ctx, documentID, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Readers would only get ciphertext
+	if document.Encrypted {
+		return nil, ErrEncrypted
+	}
+
+	if !slugPattern.MatchString(req.Slug) {
+		return nil, ErrInvalidSlug
+	}
+
+	space, err := s.repo.GetSpaceByID(ctx, req.SpaceID)
+	if err != nil {
+		return nil, err
+	}
+	if space == nil {
+		return nil, ErrSpaceNotFound
+	}
+	if space.OwnerID != ownerID {
+		return nil, ErrUnauthorized
+	}
+
+	taken, err := s.repo.GetPageBySlug(ctx, space.ID, req.Slug)
+	if err != nil {
+		return nil, err
+	}
+	if taken != nil && taken.DocumentID != documentID {
+		return nil, ErrSlugTaken
+	}
+
+	page, err := s.repo.GetPageByDocumentID(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var redirect *model.Redirect
+	var oldSpaceSlug, oldSlug string
+
+	if page == nil {
+		page = &model.Page{
+			SpaceID:     space.ID,
+			DocumentID:  documentID,
+			Slug:        req.Slug,
+			PublishedAt: now,
+		}
+	} else if page.SpaceID != space.ID || page.Slug != req.Slug {
+		oldSpace, err := s.repo.GetSpaceByID(ctx, page.SpaceID)
+		if err != nil {
+			return nil, err
+		}
+
+		redirect = &model.Redirect{
+			SpaceID:   page.SpaceID,
+			Slug:      page.Slug,
+			PageID:    page.ID,
+			CreatedAt: now,
+		}
+		if oldSpace != nil {
+			oldSpaceSlug, oldSlug = oldSpace.Slug, page.Slug
+		}
+
+		page.SpaceID = space.ID
+		page.Slug = req.Slug
+	}
+	page.UpdatedAt = now
+
+	if err := s.repo.SavePage(ctx, page, redirect); err != nil {
+		return nil, err
+	}
+	s.invalidate(ctx, space.Slug, page.Slug)
+	if oldSlug != "" {
+		s.invalidate(ctx, oldSpaceSlug, oldSlug)
+	}
+
+	return newPageResponse(page, space), nil
+}
+
+func (s *kbService) Unpublish(ctx context.Context, documentID uuid.UUID, ownerID uuid.UUID) error {
+	if err := s.checkOwner(ctx, documentID, ownerID); err != nil {
+		return err
+	}
+
+	page, err := s.repo.GetPageByDocumentID(ctx, documentID)
+	if err != nil {
+		return err
+	}
+	if page == nil {
+		return ErrPageNotFound
+	}
+
+	if err := s.repo.DeletePage(ctx, page.ID); err != nil {
+		return err
+	}
+
+	if space, err := s.repo.GetSpaceByID(ctx, page.SpaceID); err == nil && space != nil {
+		s.invalidate(ctx, space.Slug, page.Slug)
+	}
+
+	return nil
+}
+
+func (s *kbService) GetPublication(ctx context.Context, documentID uuid.UUID, ownerID uuid.UUID) (*model.PageResponse, error) {
+	if err := s.checkOwner(ctx, documentID, ownerID); err != nil {
+		return nil, err
+	}
+
+	page, err := s.repo.GetPageByDocumentID(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if page == nil {
+		return nil, ErrPageNotFound
+	}
+
+	space, err := s.repo.GetSpaceByID(ctx, page.SpaceID)
+	if err != nil {
+		return nil, err
+	}
+	if space == nil {
+		return nil, ErrSpaceNotFound
+	}
+
+	return newPageResponse(page, space), nil
+}
+
+func (s *kbService) GetPublishedPage(ctx context.Context, spaceSlug, slug string) (*model.PublishedPage, string, error) {
+	if published := s.cached(ctx, spaceSlug, slug); published != nil {
+		return published, "", nil
+	}
+
+	space, err := s.repo.GetSpaceBySlug(ctx, spaceSlug)
+	if err != nil {
+		return nil, "", err
+	}
+	if space == nil {
+		return nil, "", ErrPageNotFound
+	}
+
+	page, err := s.repo.GetPageBySlug(ctx, space.ID, slug)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if page == nil {
+		return s.resolveRedirect(ctx, space.ID, slug)
+	}
+
+	document, err := s.docRepo.GetDocumentByID(ctx, page.DocumentID)
+	if err != nil {
+		return nil, "", err
+	}
+	if document == nil {
+		return nil, "", ErrPageNotFound
+	}
+
+	published := &model.PublishedPage{
+		Space:       space.Slug,
+		Slug:        page.Slug,
+		Title:       document.Title,
+		Content:     document.Content,
+		Version:     document.Version,
+		PublishedAt: page.PublishedAt,
+		UpdatedAt:   document.UpdatedAt,
+	}
+	s.cache(ctx, published)
+
+	return published, "", nil
+}
+
+// resolveRedirect finds where a retired address now points
+func (s *kbService) resolveRedirect(ctx context.Context, spaceID uuid.UUID, slug string) (*model.PublishedPage, string, error) {
+	redirect, err := s.repo.GetRedirect(ctx, spaceID, slug)
+	if err != nil {
+		return nil, "", err
+	}
+	if redirect == nil {
+		return nil, "", ErrPageNotFound
+	}
+
+	page, err := s.repo.GetPageByID(ctx, redirect.PageID)
+	if err != nil {
+		return nil, "", err
+	}
+	if page == nil {
+		return nil, "", ErrPageNotFound
+	}
+
+	space, err := s.repo.GetSpaceByID(ctx, page.SpaceID)
+	if err != nil {
+		return nil, "", err
+	}
+	if space == nil {
+		return nil, "", ErrPageNotFound
+	}
+
+	return nil, pagePath(space.Slug, page.Slug), nil
+}
+
+// checkOwner allows only the document's owners to manage its publication
+func (s *kbService) checkOwner(ctx context.Context, documentID uuid.UUID, ownerID uuid.UUID) error {
+	_, err := s.ownedDocument(ctx, documentID, ownerID)
+	return err
+}
+
+// ownedDocument is checkOwner, returning the document
+func (s *kbService) ownedDocument(ctx context.Context, documentID uuid.UUID, ownerID uuid.UUID) (*docModel.Document, error) {
+	document, err := s.docRepo.GetDocumentByID(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if document == nil {
+		return nil, ErrDocumentNotFound
+	}
+	if document.OwnerID != ownerID {
+		owner, err := s.docRepo.IsDocumentOwner(ctx, documentID, ownerID)
+		if err != nil {
+			return nil, err
+		}
+		if !owner {
+			return nil, ErrUnauthorized
+		}
+	}
+	return document, nil
+}
+
+// Published pages are cached for kb.cache_ttl; publishing changes evict
+// them immediately, document edits show up once the entry expires
+func (s *kbService) cached(ctx context.Context, spaceSlug, slug string) *model.PublishedPage {
+	if s.cacheTTL == 0 {
+		return nil
+	}
+
+	data, err := s.redis.Get(ctx, cacheKey(spaceSlug, slug)).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			logging.FromContext(ctx, s.logger).Warn("Failed to read KB page cache", zap.Error(err))
+		}
+		return nil
+	}
+
+	var published model.PublishedPage
+	if err := json.Unmarshal(data, &published); err != nil {
+		return nil
+	}
+	return &published
+}
+
+func (s *kbService) cache(ctx context.Context, published *model.PublishedPage) {
+	if s.cacheTTL == 0 {
+		return
+	}
+
+	data, err := json.Marshal(published)
+	if err != nil {
+		return
+	}
+
+	if err := s.redis.Set(ctx, cacheKey(published.Space, published.Slug), data, s.cacheTTL).Err(); err != nil {
+		logging.FromContext(ctx, s.logger).Warn("Failed to cache KB page", zap.Error(err))
+	}
+}
+
+func (s *kbService) invalidate(ctx context.Context, spaceSlug, slug string) {
+	if err := s.redis.Del(ctx, cacheKey(spaceSlug, slug)).Err(); err != nil {
+		logging.FromContext(ctx, s.logger).Warn("Failed to evict KB page cache", zap.Error(err))
+	}
+}
+
+func cacheKey(spaceSlug, slug string) string {
+	return fmt.Sprintf("kb_page:%s:%s", spaceSlug, slug)
+}
+
+func pagePath(spaceSlug, slug string) string {
+	return fmt.Sprintf("/api/v1/kb/%s/%s", spaceSlug, slug)
+}
+
+func newPageResponse(page *model.Page, space *model.Space) *model.PageResponse {
+	return &model.PageResponse{
+		Page:  *page,
+		Space: space.Slug,
+		Path:  pagePath(space.Slug, page.Slug),
+	}
+}
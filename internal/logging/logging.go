@@ -0,0 +1,47 @@
+package logging
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying logger
+func NewContext(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger carried by ctx, or fallback when there is
+// none. Components keep their injected logger as the fallback so code paths
+// without a request still log.
+func FromContext(ctx context.Context, fallback *zap.Logger) *zap.Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(contextKey{}).(*zap.Logger); ok {
+			return logger
+		}
+	}
+	return fallback
+}
+
+// With adds fields to the logger carried by ctx. It is a no-op when ctx
+// carries no logger.
+func With(ctx context.Context, fields ...zap.Field) context.Context {
+	logger := FromContext(ctx, nil)
+	if logger == nil {
+		return ctx
+	}
+	return NewContext(ctx, logger.With(fields...))
+}
+
+// Detach returns a background context that keeps only the logger of ctx.
+// Use it for goroutines that outlive the request, so their logs keep the
+// correlation fields without inheriting the request's cancellation.
+func Detach(ctx context.Context) context.Context {
+	detached := context.Background()
+	if logger := FromContext(ctx, nil); logger != nil {
+		detached = NewContext(detached, logger)
+	}
+	return detached
+}
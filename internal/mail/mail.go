@@ -0,0 +1,190 @@
+// Package mail sends email through an SMTP relay.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// sendTimeout bounds a single message, from dialing to QUIT
+const sendTimeout = 30 * time.Second
+
+// Config is read from the mail section. Mail is disabled while Host is
+// unset.
+type Config struct {
+	Host string `mapstructure:"host"`
+	Port int    `mapstructure:"port"`
+	// Username and Password are optional; they are only sent over TLS
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	// From is the sender address, e.g. "Documents <noreply@example.com>"
+	From string `mapstructure:"from"`
+}
+
+// Message is an email with plain text and HTML alternatives
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Sender delivers email
+type Sender interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// NewSender creates an SMTP sender, or returns nil when no host is
+// configured
+func NewSender(config Config) (Sender, error) {
+	if config.Host == "" {
+		return nil, nil
+	}
+	if config.From == "" {
+		return nil, fmt.Errorf("mail.from is required")
+	}
+	if config.Port == 0 {
+		config.Port = 587
+	}
+	return &smtpSender{config: config}, nil
+}
+
+type smtpSender struct {
+	config Config
+}
+
+func (s *smtpSender) Send(ctx context.Context, message Message) error {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	body, err := s.compose(message)
+	if err != nil {
+		return err
+	}
+
+	address := net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.config.Host}); err != nil {
+			return err
+		}
+	}
+
+	if s.config.Username != "" {
+		// PlainAuth refuses to send credentials without TLS, except to localhost
+		auth := smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	from, err := mailAddress(s.config.From)
+	if err != nil {
+		return err
+	}
+	to, err := mailAddress(message.To)
+	if err != nil {
+		return err
+	}
+
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(body); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// compose renders the message as multipart/alternative with both parts
+// quoted-printable encoded
+func (s *smtpSender) compose(message Message) ([]byte, error) {
+	boundary, err := newBoundary()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", s.config.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", message.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", boundary)
+
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain", message.Text},
+		{"text/html", message.HTML},
+	} {
+		fmt.Fprintf(&buf, "--%s\r\n", boundary)
+		fmt.Fprintf(&buf, "Content-Type: %s; charset=utf-8\r\n", part.contentType)
+		buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+		writer := quotedprintable.NewWriter(&buf)
+		if _, err := writer.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		buf.WriteString("\r\n")
+	}
+	fmt.Fprintf(&buf, "--%s--\r\n", boundary)
+
+	return buf.Bytes(), nil
+}
+
+// mailAddress extracts the bare address for the SMTP envelope
+func mailAddress(address string) (string, error) {
+	parsed, err := netmail.ParseAddress(address)
+	if err != nil {
+		return "", fmt.Errorf("invalid address %q: %w", address, err)
+	}
+	return parsed.Address, nil
+}
+
+func newBoundary() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/hafiztri123/document-api/config"
+	"github.com/hafiztri123/document-api/internal/errcode"
+	"github.com/hafiztri123/document-api/internal/logging"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// AdminMiddleware lets through only the users listed under admin.user_ids.
+// It must run after AuthMiddleware.
+func AdminMiddleware(logger *zap.Logger) gin.HandlerFunc {
+	admins := make(map[uuid.UUID]bool)
+	for _, value := range viper.GetStringSlice(config.ADMIN_USER_IDS) {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			logger.Error("Invalid admin user ID in config, ignoring it", zap.String("value", value))
+			continue
+		}
+		admins[id] = true
+	}
+
+	return func(ctx *gin.Context) {
+		userID, _ := ctx.Get("userID")
+		if id, ok := userID.(uuid.UUID); !ok || !admins[id] {
+			logging.FromContext(ctx.Request.Context(), logger).Warn("Admin route refused",
+				zap.String("route", ctx.FullPath()))
+			ctx.JSON(http.StatusForbidden, gin.H{
+				"error": gin.H{
+					"code":    errcode.Forbidden,
+					"message": "Admin access required",
+				},
+			})
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
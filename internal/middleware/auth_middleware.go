@@ -6,6 +6,9 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/hafiztri123/document-api/internal/auth/service"
+	"github.com/hafiztri123/document-api/internal/errcode"
+	"github.com/hafiztri123/document-api/internal/logging"
+	"go.uber.org/zap"
 )
 
 func AuthMiddleware(authService service.Service) gin.HandlerFunc {
@@ -14,7 +17,7 @@ func AuthMiddleware(authService service.Service) gin.HandlerFunc {
 		if authHeader == "" {
 			ctx.JSON(http.StatusUnauthorized, gin.H{
 				"error": gin.H{
-					"code": "unauthorized",
+					"code": errcode.Unauthorized,
 					"message": "Missing authorization header",
 				},
 			})
@@ -26,7 +29,7 @@ func AuthMiddleware(authService service.Service) gin.HandlerFunc {
 		if len(parts) != 2 || parts[0] != "Bearer" {
 			ctx.JSON(http.StatusUnauthorized, gin.H{
 				"error": gin.H{
-					"code": "unauthorized",
+					"code": errcode.Unauthorized,
 					"message": "Invalid authorization header format",
 				},
 			})
@@ -39,7 +42,7 @@ func AuthMiddleware(authService service.Service) gin.HandlerFunc {
 		if err != nil {
 			ctx.JSON(http.StatusUnauthorized, gin.H{
 				"error": gin.H{
-					"code": "unauthorized",
+					"code": errcode.InvalidToken,
 					"message": "Invalid or expired token",
 				},
 			})
@@ -49,6 +52,8 @@ func AuthMiddleware(authService service.Service) gin.HandlerFunc {
 
 		ctx.Set("userID", claims.UserID)
 		ctx.Set("userEmail", claims.Email)
+		ctx.Request = ctx.Request.WithContext(logging.With(ctx.Request.Context(),
+			zap.String("user_id", claims.UserID.String())))
 		ctx.Next()
 
 
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hafiztri123/document-api/internal/chaos"
+	"github.com/hafiztri123/document-api/internal/errcode"
+	"github.com/hafiztri123/document-api/internal/logging"
+	"go.uber.org/zap"
+)
+
+// ChaosMiddleware delays requests and fails them with 500s as configured
+// under "chaos". It does nothing unless fault injection is enabled, and it
+// is never enabled in production.
+func ChaosMiddleware(logger *zap.Logger) gin.HandlerFunc {
+	cfg := chaos.Load(logger)
+	if cfg.Enabled {
+		logger.Warn("HTTP fault injection enabled",
+			zap.Strings("paths", cfg.Paths),
+			zap.Bool("require_header", cfg.RequireHeader),
+			zap.Duration("latency", cfg.Latency),
+			zap.Float64("latency_rate", cfg.LatencyRate),
+			zap.Float64("error_rate", cfg.ErrorRate))
+	}
+
+	return func(ctx *gin.Context) {
+		if !cfg.Applies(ctx.Request.URL.Path, ctx.GetHeader(chaos.Header)) {
+			ctx.Next()
+			return
+		}
+
+		if delay := cfg.Delay(); delay > 0 {
+			ctx.Header(chaos.FaultHeader, "latency")
+			select {
+			case <-time.After(delay):
+			case <-ctx.Request.Context().Done():
+			}
+		}
+
+		if cfg.Fail() {
+			logging.FromContext(ctx.Request.Context(), logger).Debug("Injecting server error")
+			ctx.Header(chaos.FaultHeader, "error")
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
+				"code":    errcode.InternalError,
+				"message": "Injected failure",
+			}})
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hafiztri123/document-api/config"
+	"github.com/hafiztri123/document-api/internal/logging"
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// Deprecation describes a deprecated route. Sunset and Link are optional.
+type Deprecation struct {
+	Method string    `mapstructure:"method"`
+	Path   string    `mapstructure:"path"`
+	Since  time.Time `mapstructure:"since"`
+	Sunset time.Time `mapstructure:"sunset"`
+	Link   string    `mapstructure:"link"`
+}
+
+// Deprecated marks a single route as deprecated in code:
+//
+//	docs.GET("/legacy", middleware.Deprecated(middleware.Deprecation{...}, logger), handler)
+func Deprecated(deprecation Deprecation, logger *zap.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		setDeprecationHeaders(ctx, deprecation)
+		ctx.Next()
+		logDeprecatedUse(ctx, deprecation, logger)
+	}
+}
+
+// DeprecationMiddleware marks the routes listed under "deprecations" in the
+// configuration, so routes can be deprecated without a code change
+func DeprecationMiddleware(logger *zap.Logger) gin.HandlerFunc {
+	var deprecations []Deprecation
+	decodeHook := viper.DecodeHook(mapstructure.StringToTimeHookFunc(time.RFC3339))
+	if err := viper.UnmarshalKey(config.DEPRECATIONS, &deprecations, decodeHook); err != nil {
+		logger.Error("Invalid deprecations config, ignoring it", zap.Error(err))
+	}
+
+	routes := make(map[string]Deprecation, len(deprecations))
+	for _, deprecation := range deprecations {
+		routes[routeKey(deprecation.Method, deprecation.Path)] = deprecation
+	}
+
+	return func(ctx *gin.Context) {
+		deprecation, ok := routes[routeKey(ctx.Request.Method, ctx.FullPath())]
+		if !ok {
+			ctx.Next()
+			return
+		}
+
+		setDeprecationHeaders(ctx, deprecation)
+		ctx.Next()
+		logDeprecatedUse(ctx, deprecation, logger)
+	}
+}
+
+// setDeprecationHeaders runs before the handler, which writes the headers
+// out with the response
+func setDeprecationHeaders(ctx *gin.Context, deprecation Deprecation) {
+	if deprecation.Since.IsZero() {
+		ctx.Header("Deprecation", "true")
+	} else {
+		ctx.Header("Deprecation", fmt.Sprintf("@%d", deprecation.Since.Unix()))
+	}
+
+	if !deprecation.Sunset.IsZero() {
+		ctx.Header("Sunset", deprecation.Sunset.UTC().Format(http.TimeFormat))
+	}
+
+	if deprecation.Link != "" {
+		ctx.Header("Link", fmt.Sprintf("<%s>; rel=\"deprecation\"", deprecation.Link))
+	}
+}
+
+// logDeprecatedUse runs after the handler, once AuthMiddleware further down
+// the chain has set the user
+func logDeprecatedUse(ctx *gin.Context, deprecation Deprecation, logger *zap.Logger) {
+	userID, _ := ctx.Get("userID")
+	logging.FromContext(ctx.Request.Context(), logger).Warn("Deprecated route used",
+		zap.String("method", ctx.Request.Method),
+		zap.String("route", ctx.FullPath()),
+		zap.Any("userID", userID),
+		zap.String("user-agent", ctx.Request.UserAgent()),
+		zap.Time("sunset", deprecation.Sunset))
+}
+
+func routeKey(method, path string) string {
+	return strings.ToUpper(method) + " " + path
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/hafiztri123/document-api/config"
+)
+
+func TestDeprecationMiddlewareLogsUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	viper.Set(config.DEPRECATIONS, []map[string]interface{}{
+		{"method": "GET", "path": "/api/v1/legacy", "link": "https://example.com/migrate"},
+	})
+	t.Cleanup(func() { viper.Set(config.DEPRECATIONS, nil) })
+
+	core, logs := observer.New(zap.WarnLevel)
+	userID := uuid.New()
+
+	// Registered globally, ahead of the group's authentication, as in
+	// production
+	router := gin.New()
+	router.Use(DeprecationMiddleware(zap.New(core)))
+	api := router.Group("/api/v1", func(ctx *gin.Context) {
+		ctx.Set("userID", userID)
+		ctx.Next()
+	})
+	api.GET("/legacy", func(ctx *gin.Context) {
+		ctx.Status(http.StatusNoContent)
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/legacy", nil))
+
+	if got := rec.Header().Get("Deprecation"); got != "true" {
+		t.Errorf("Deprecation header = %q, want true", got)
+	}
+	if got := rec.Header().Get("Link"); got != `<https://example.com/migrate>; rel="deprecation"` {
+		t.Errorf("Link header = %q", got)
+	}
+
+	entries := logs.FilterMessage("Deprecated route used").All()
+	if len(entries) != 1 {
+		t.Fatalf("%d deprecation logs, want 1", len(entries))
+	}
+	if got := fmt.Sprint(entries[0].ContextMap()["userID"]); got != userID.String() {
+		t.Errorf("logged userID = %v, want %s", got, userID)
+	}
+}
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/hafiztri123/document-api/internal/errcode"
+	"github.com/hafiztri123/document-api/internal/logging"
+	"github.com/hafiztri123/document-api/internal/usage/model"
+	"github.com/hafiztri123/document-api/internal/usage/service"
+	"go.uber.org/zap"
+)
+
+// RateLimitMiddleware enforces the per-user request window and records API
+// usage. It must run after AuthMiddleware. Redis failures fail open.
+func RateLimitMiddleware(usageService service.Service, logger *zap.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userID, exists := ctx.Get("userID")
+		if !exists {
+			ctx.Next()
+			return
+		}
+
+		status, err := usageService.CheckRateLimit(ctx.Request.Context(), userID.(uuid.UUID))
+		if err != nil {
+			logging.FromContext(ctx.Request.Context(), logger).Warn("Rate limit check failed, allowing request", zap.Error(err))
+		} else if !applyRateLimit(ctx, status) {
+			recordUsage(ctx.Request.Context(), usageService, logger, userID.(uuid.UUID), http.StatusTooManyRequests)
+			return
+		}
+
+		ctx.Next()
+
+		recordUsage(ctx.Request.Context(), usageService, logger, userID.(uuid.UUID), ctx.Writer.Status())
+	}
+}
+
+// PublicRateLimitMiddleware enforces the per-IP window for anonymous
+// requests, kept apart from the per-user windows. Redis failures fail open.
+func PublicRateLimitMiddleware(usageService service.Service, logger *zap.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		status, err := usageService.CheckPublicRateLimit(ctx.Request.Context(), ctx.ClientIP())
+		if err != nil {
+			logging.FromContext(ctx.Request.Context(), logger).Warn("Public rate limit check failed, allowing request", zap.Error(err))
+		} else if !applyRateLimit(ctx, status) {
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// applyRateLimit sets the X-RateLimit-* headers, warns once the window is
+// nearly used up and rejects the request when it is exceeded, reporting
+// whether it may continue
+func applyRateLimit(ctx *gin.Context, status *model.RateLimitStatus) bool {
+	ctx.Header("X-RateLimit-Limit", strconv.FormatInt(status.Limit, 10))
+	ctx.Header("X-RateLimit-Used", strconv.FormatInt(status.Used, 10))
+	ctx.Header("X-RateLimit-Remaining", strconv.FormatInt(status.Remaining, 10))
+	ctx.Header("X-RateLimit-Reset", strconv.FormatInt(status.ResetAt.Unix(), 10))
+
+	if status.Exceeded() {
+		ctx.Header("Retry-After", strconv.Itoa(int(time.Until(status.ResetAt).Seconds())+1))
+		ctx.JSON(http.StatusTooManyRequests, gin.H{"error": gin.H{
+			"code":    errcode.RateLimited,
+			"message": "Rate limit exceeded",
+		}})
+		ctx.Abort()
+		return false
+	}
+
+	if status.Warning {
+		ctx.Header("X-RateLimit-Warning", fmt.Sprintf("%d of %d requests used, window resets at %s",
+			status.Used, status.Limit, status.ResetAt.UTC().Format(time.RFC3339)))
+	}
+	return true
+}
+
+func recordUsage(ctx context.Context, usageService service.Service, logger *zap.Logger, userID uuid.UUID, status int) {
+	// The request context may already be cancelled once the handler returns
+	ctx, cancel := context.WithTimeout(logging.Detach(ctx), time.Second)
+	defer cancel()
+
+	if err := usageService.RecordRequest(ctx, userID, status); err != nil {
+		logging.FromContext(ctx, logger).Warn("Failed to record API usage", zap.Error(err))
+	}
+}
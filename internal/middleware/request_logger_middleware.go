@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/hafiztri123/document-api/internal/logging"
+)
+
+// RequestIDHeader carries the request ID; a client-supplied value is kept
+// so calls can be traced across services
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds client-supplied IDs before they reach the logs
+const maxRequestIDLength = 128
+
+// RequestLoggerMiddleware attaches a request-scoped logger carrying the
+// request ID to the request context and writes the access log line when
+// the request completes
+func RequestLoggerMiddleware(logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Start timer
+		start := time.Now()
+		path := c.Request.URL.Path
+		query := c.Request.URL.RawQuery
+
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" || len(requestID) > maxRequestIDLength {
+			requestID = uuid.New().String()
+		}
+		c.Set("requestID", requestID)
+		c.Header(RequestIDHeader, requestID)
+
+		ctx := logging.NewContext(c.Request.Context(), logger.With(zap.String("request_id", requestID)))
+		c.Request = c.Request.WithContext(ctx)
+
+		// Process request
+		c.Next()
+
+		// Log request details; the logger now includes the user when authenticated
+		if path != "/health" { // Skip logging health checks
+			logging.FromContext(c.Request.Context(), logger).Info("API Request",
+				zap.String("method", c.Request.Method),
+				zap.String("path", path),
+				zap.String("query", query),
+				zap.Int("status", c.Writer.Status()),
+				zap.Duration("latency", time.Since(start)),
+				zap.String("ip", c.ClientIP()),
+				zap.String("user-agent", c.Request.UserAgent()),
+			)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	siemModel "github.com/hafiztri123/document-api/internal/siem/model"
+	siemService "github.com/hafiztri123/document-api/internal/siem/service"
+)
+
+// SecuritySourceMiddleware stamps the request context with the client's
+// address, user agent and request ID for the security events it records.
+// It must run after RequestLoggerMiddleware.
+func SecuritySourceMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := siemService.WithSource(c.Request.Context(), siemService.Source{
+			IPAddress: c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+			RequestID: c.GetString("requestID"),
+		})
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
+
+// AdminAuditMiddleware records every admin request as a security event
+// once it completes. It runs before AdminMiddleware so refused requests
+// are recorded too.
+func AdminAuditMiddleware(security siemService.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		message := siemModel.Message{
+			Type:    siemModel.TypeAdminAction,
+			Outcome: siemModel.OutcomeSuccess,
+			Data: map[string]interface{}{
+				"method": c.Request.Method,
+				"route":  c.FullPath(),
+				"path":   c.Request.URL.Path,
+				"status": c.Writer.Status(),
+			},
+		}
+		if c.Writer.Status() >= 400 {
+			message.Outcome = siemModel.OutcomeFailure
+		}
+		if userID, ok := c.Get("userID"); ok {
+			if id, ok := userID.(uuid.UUID); ok {
+				message.UserID = &id
+			}
+		}
+		if value := c.Param("user_id"); value != "" {
+			if id, err := uuid.Parse(value); err == nil {
+				message.TargetType = siemModel.TargetUser
+				message.TargetID = &id
+			}
+		}
+
+		security.Record(c.Request.Context(), message)
+	}
+}
@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/hafiztri123/document-api/internal/errcode"
+	"github.com/hafiztri123/document-api/internal/logging"
+	"github.com/hafiztri123/document-api/internal/push/model"
+	"github.com/hafiztri123/document-api/internal/push/service"
+)
+
+type Controller interface {
+	RegisterDevice(c *gin.Context)
+	GetDevices(c *gin.Context)
+	RemoveDevice(c *gin.Context)
+}
+
+type pushController struct {
+	service service.Service
+	logger  *zap.Logger
+}
+
+func NewPushController(service service.Service, logger *zap.Logger) Controller {
+	return &pushController{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func (ctrl *pushController) RegisterDevice(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+
+	var req model.DeviceRegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid request data",
+			"details": err.Error(),
+		}})
+		return
+	}
+
+	device, err := ctrl.service.RegisterDevice(c.Request.Context(), userID.(uuid.UUID), req)
+	if err != nil {
+		ctrl.handleError(c, err, "Failed to register push device")
+		return
+	}
+
+	c.JSON(http.StatusCreated, device)
+}
+
+func (ctrl *pushController) GetDevices(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+
+	devices, err := ctrl.service.GetDevices(c.Request.Context(), userID.(uuid.UUID))
+	if err != nil {
+		ctrl.handleError(c, err, "Failed to retrieve push devices")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": devices})
+}
+
+func (ctrl *pushController) RemoveDevice(c *gin.Context) {
+	deviceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid device ID",
+		}})
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+
+	if err := ctrl.service.RemoveDevice(c.Request.Context(), deviceID, userID.(uuid.UUID)); err != nil {
+		ctrl.handleError(c, err, "Failed to remove push device")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (ctrl *pushController) handleError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrDeviceNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+			"code":    errcode.DeviceNotFound,
+			"message": "Push device not found",
+		}})
+	case service.ErrUnauthorized:
+		c.JSON(http.StatusForbidden, gin.H{"error": gin.H{
+			"code":    errcode.Forbidden,
+			"message": "You don't have permission to remove this device",
+		}})
+	default:
+		logging.FromContext(c.Request.Context(), ctrl.logger).Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
+			"code":    errcode.InternalError,
+			"message": message,
+		}})
+	}
+}
@@ -0,0 +1,51 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Platform is the push service a device token belongs to
+type Platform string
+
+const (
+	PlatformFCM  Platform = "fcm"
+	PlatformAPNs Platform = "apns"
+)
+
+// Device is a mobile device registered for push notifications. A token
+// belongs to a single user; registering it again moves it to the caller.
+type Device struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null" json:"user_id"`
+	Platform  Platform  `gorm:"type:varchar(10);not null" json:"platform"`
+	Token     string    `gorm:"type:varchar(512);not null;uniqueIndex" json:"-"`
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+	UpdatedAt time.Time `gorm:"not null" json:"updated_at"`
+}
+
+func (Device) TableName() string {
+	return "push_devices"
+}
+
+func (d *Device) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return nil
+}
+
+type DeviceRegisterRequest struct {
+	Platform Platform `json:"platform" binding:"required,oneof=fcm apns"`
+	Token    string   `json:"token" binding:"required,max=512"`
+}
+
+// Message is a push notification as shown on the device. Data carries the
+// event for the app to act on; values are strings, as FCM requires.
+type Message struct {
+	Title string
+	Body  string
+	Data  map[string]string
+}
@@ -0,0 +1,47 @@
+package push
+
+import (
+	"net/http"
+
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"github.com/hafiztri123/document-api/config"
+	"github.com/hafiztri123/document-api/internal/api"
+	"github.com/hafiztri123/document-api/internal/push/controller"
+	"github.com/hafiztri123/document-api/internal/push/model"
+	"github.com/hafiztri123/document-api/internal/push/provider"
+	"github.com/hafiztri123/document-api/internal/push/repository"
+	"github.com/hafiztri123/document-api/internal/push/service"
+)
+
+// Module provides the push repository, senders, service, controller and
+// routes
+var Module = fx.Module("push",
+	fx.Provide(
+		repository.NewPushRepository,
+		newSenders,
+		service.NewPushService,
+		controller.NewPushController,
+		api.AsRouteRegistrar(newRoutes),
+	),
+)
+
+// newSenders creates a sender for each platform configured under push.
+// Devices can still register while push is not configured; nothing is sent
+// to them until it is.
+func newSenders(logger *zap.Logger) map[model.Platform]provider.Sender {
+	var pushConfig provider.Config
+	if err := viper.UnmarshalKey(config.PUSH, &pushConfig); err != nil {
+		logger.Error("Invalid push config, ignoring it", zap.Error(err))
+		return nil
+	}
+
+	senders, err := provider.NewSenders(pushConfig, &http.Client{})
+	if err != nil {
+		logger.Error("Invalid push config, ignoring it", zap.Error(err))
+		return nil
+	}
+	return senders
+}
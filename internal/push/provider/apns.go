@@ -0,0 +1,138 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/hafiztri123/document-api/internal/push/model"
+)
+
+// apnsTokenLifetime is how long a provider token is reused. Apple rejects
+// tokens older than an hour and refreshes more often than every 20 minutes.
+const apnsTokenLifetime = 50 * time.Minute
+
+// apns sends through the APNs HTTP/2 API with token-based authentication
+type apns struct {
+	config APNs
+	host   string
+	key    interface{}
+	client *http.Client
+
+	mutex    sync.Mutex
+	token    string
+	issuedAt time.Time
+}
+
+func newAPNs(config APNs, client *http.Client) (*apns, error) {
+	if config.KeyID == "" || config.TeamID == "" || config.Topic == "" {
+		return nil, fmt.Errorf("key_id, team_id and topic are required")
+	}
+
+	data, err := os.ReadFile(config.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := jwt.ParseECPrivateKeyFromPEM(data)
+	if err != nil {
+		return nil, err
+	}
+
+	host := "https://api.sandbox.push.apple.com"
+	if config.Production {
+		host = "https://api.push.apple.com"
+	}
+
+	return &apns{config: config, host: host, key: key, client: client}, nil
+}
+
+func (p *apns) Send(ctx context.Context, token string, message model.Message) error {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	providerToken, err := p.providerToken()
+	if err != nil {
+		return err
+	}
+
+	body := map[string]interface{}{
+		"aps": map[string]interface{}{
+			"alert": map[string]string{
+				"title": message.Title,
+				"body":  message.Body,
+			},
+		},
+	}
+	for key, value := range message.Data {
+		body[key] = value
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.host+"/3/device/"+token, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "bearer "+providerToken)
+	req.Header.Set("apns-topic", p.config.Topic)
+	req.Header.Set("apns-push-type", "alert")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusGone {
+		return ErrInvalidToken
+	}
+	if resp.StatusCode == http.StatusBadRequest {
+		var reason struct {
+			Reason string `json:"reason"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&reason) == nil && reason.Reason == "BadDeviceToken" {
+			return ErrInvalidToken
+		}
+		return fmt.Errorf("push failed with status %d: %s", resp.StatusCode, reason.Reason)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return readError(resp)
+	}
+	return nil
+}
+
+func (p *apns) providerToken() (string, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.token != "" && time.Since(p.issuedAt) < apnsTokenLifetime {
+		return p.token, nil
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss": p.config.TeamID,
+		"iat": now.Unix(),
+	})
+	token.Header["kid"] = p.config.KeyID
+
+	signed, err := token.SignedString(p.key)
+	if err != nil {
+		return "", err
+	}
+
+	p.token, p.issuedAt = signed, now
+	return p.token, nil
+}
@@ -0,0 +1,173 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/hafiztri123/document-api/internal/push/model"
+)
+
+const fcmScope = "https://www.googleapis.com/auth/firebase.messaging"
+
+// serviceAccount holds the fields of a service account key file used here
+type serviceAccount struct {
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// fcm sends through the FCM HTTP v1 API, exchanging a signed service
+// account assertion for an access token that is reused until shortly
+// before it expires
+type fcm struct {
+	account   serviceAccount
+	projectID string
+	key       interface{}
+	client    *http.Client
+
+	mutex     sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func newFCM(config FCM, client *http.Client) (*fcm, error) {
+	data, err := os.ReadFile(config.CredentialsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var account serviceAccount
+	if err := json.Unmarshal(data, &account); err != nil {
+		return nil, err
+	}
+	if account.ClientEmail == "" || account.PrivateKey == "" || account.TokenURI == "" {
+		return nil, fmt.Errorf("credentials need client_email, private_key and token_uri")
+	}
+
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(account.PrivateKey))
+	if err != nil {
+		return nil, err
+	}
+
+	projectID := config.ProjectID
+	if projectID == "" {
+		projectID = account.ProjectID
+	}
+	if projectID == "" {
+		return nil, fmt.Errorf("project_id is not set")
+	}
+
+	return &fcm{account: account, projectID: projectID, key: key, client: client}, nil
+}
+
+func (p *fcm) Send(ctx context.Context, token string, message model.Message) error {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	accessToken, err := p.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"message": map[string]interface{}{
+			"token": token,
+			"notification": map[string]string{
+				"title": message.Title,
+				"body":  message.Body,
+			},
+			"data": message.Data,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("https://fcm.googleapis.com/v1/projects/%s/messages:send", p.projectID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// FCM answers 404 UNREGISTERED for tokens of uninstalled apps
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrInvalidToken
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return readError(resp)
+	}
+	return nil
+}
+
+func (p *fcm) accessToken(ctx context.Context) (string, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.token != "" && time.Now().Before(p.expiresAt) {
+		return p.token, nil
+	}
+
+	now := time.Now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   p.account.ClientEmail,
+		"scope": fcmScope,
+		"aud":   p.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(p.key)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.account.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", readError(resp)
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+
+	// Refreshed a minute early so a token never expires mid-request
+	p.token = result.AccessToken
+	p.expiresAt = now.Add(time.Duration(result.ExpiresIn)*time.Second - time.Minute)
+	return p.token, nil
+}
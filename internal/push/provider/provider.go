@@ -0,0 +1,82 @@
+// Package provider delivers push notifications through Firebase Cloud
+// Messaging and the Apple Push Notification service.
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/hafiztri123/document-api/internal/push/model"
+)
+
+// ErrInvalidToken reports a device token the provider no longer accepts;
+// the device should be forgotten
+var ErrInvalidToken = errors.New("device token is no longer valid")
+
+// sendTimeout bounds a single delivery, including fetching credentials
+const sendTimeout = 10 * time.Second
+
+// Config is read from the push section. A platform is disabled while its
+// credentials are unset.
+type Config struct {
+	FCM  FCM  `mapstructure:"fcm"`
+	APNs APNs `mapstructure:"apns"`
+}
+
+// FCM authenticates with a Google service account key file
+type FCM struct {
+	CredentialsFile string `mapstructure:"credentials_file"`
+	// ProjectID defaults to the project of the service account
+	ProjectID string `mapstructure:"project_id"`
+}
+
+// APNs authenticates with a token signing key (.p8) from the Apple
+// developer account
+type APNs struct {
+	KeyFile string `mapstructure:"key_file"`
+	KeyID   string `mapstructure:"key_id"`
+	TeamID  string `mapstructure:"team_id"`
+	// Topic is the app's bundle ID
+	Topic string `mapstructure:"topic"`
+	// Production selects the production gateway over the sandbox
+	Production bool `mapstructure:"production"`
+}
+
+// Sender delivers a message to one device
+type Sender interface {
+	Send(ctx context.Context, token string, message model.Message) error
+}
+
+// NewSenders creates a sender for every configured platform. It fails when
+// a platform's credentials cannot be loaded.
+func NewSenders(config Config, client *http.Client) (map[model.Platform]Sender, error) {
+	senders := map[model.Platform]Sender{}
+
+	if config.FCM.CredentialsFile != "" {
+		sender, err := newFCM(config.FCM, client)
+		if err != nil {
+			return nil, fmt.Errorf("push.fcm: %w", err)
+		}
+		senders[model.PlatformFCM] = sender
+	}
+
+	if config.APNs.KeyFile != "" {
+		sender, err := newAPNs(config.APNs, client)
+		if err != nil {
+			return nil, fmt.Errorf("push.apns: %w", err)
+		}
+		senders[model.PlatformAPNs] = sender
+	}
+
+	return senders, nil
+}
+
+// readError returns a short description of a failed response
+func readError(resp *http.Response) error {
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("push failed with status %d: %s", resp.StatusCode, snippet)
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/hafiztri123/document-api/internal/logging"
+	"github.com/hafiztri123/document-api/internal/push/model"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository interface {
+	// SaveDevice registers a device, moving its token to device.UserID when
+	// another user registered it before
+	SaveDevice(ctx context.Context, device *model.Device) error
+	GetDeviceByID(ctx context.Context, id uuid.UUID) (*model.Device, error)
+	GetDevicesByUserID(ctx context.Context, userID uuid.UUID) ([]*model.Device, error)
+	DeleteDevice(ctx context.Context, id uuid.UUID) error
+	DeleteDeviceByToken(ctx context.Context, token string) error
+}
+
+type pushRepository struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+func NewPushRepository(db *gorm.DB, logger *zap.Logger) Repository {
+	return &pushRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *pushRepository) SaveDevice(ctx context.Context, device *model.Device) error {
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "token"}},
+		DoUpdates: clause.AssignmentColumns([]string{"user_id", "platform", "updated_at"}),
+	}, clause.Returning{}).Create(device).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to save push device", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r *pushRepository) GetDeviceByID(ctx context.Context, id uuid.UUID) (*model.Device, error) {
+	var device model.Device
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&device).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get push device by ID", zap.Error(err))
+		return nil, err
+	}
+	return &device, nil
+}
+
+func (r *pushRepository) GetDevicesByUserID(ctx context.Context, userID uuid.UUID) ([]*model.Device, error) {
+	var devices []*model.Device
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&devices).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get push devices by user ID", zap.Error(err))
+		return nil, err
+	}
+	return devices, nil
+}
+
+func (r *pushRepository) DeleteDevice(ctx context.Context, id uuid.UUID) error {
+	if err := r.db.WithContext(ctx).Where("id = ?", id).Delete(&model.Device{}).Error; err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to delete push device", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r *pushRepository) DeleteDeviceByToken(ctx context.Context, token string) error {
+	if err := r.db.WithContext(ctx).Where("token = ?", token).Delete(&model.Device{}).Error; err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to delete push device by token", zap.Error(err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package push
+
+import (
+	"github.com/hafiztri123/document-api/internal/api"
+	"github.com/hafiztri123/document-api/internal/push/controller"
+)
+
+type routes struct {
+	ctrl controller.Controller
+}
+
+func newRoutes(ctrl controller.Controller) *routes {
+	return &routes{ctrl: ctrl}
+}
+
+func (r *routes) RegisterRoutes(groups api.Groups) {
+	devices := groups.Protected.Group("/push/devices")
+	{
+		devices.POST("", r.ctrl.RegisterDevice)
+		devices.GET("", r.ctrl.GetDevices)
+		devices.DELETE("/:id", r.ctrl.RemoveDevice)
+	}
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hafiztri123/document-api/internal/logging"
+	"github.com/hafiztri123/document-api/internal/push/model"
+	"github.com/hafiztri123/document-api/internal/push/provider"
+	"github.com/hafiztri123/document-api/internal/push/repository"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrDeviceNotFound = errors.New("push device not found")
+	ErrUnauthorized   = errors.New("unauthorized access to push device")
+)
+
+// notifyTimeout bounds delivering one notification to all of a user's devices
+const notifyTimeout = time.Minute
+
+// alertTitles are the alert titles of the events pushed to devices. Events
+// not listed are delivered over WebSocket only.
+var alertTitles = map[string]string{
+	"document.shared":          "A document was shared with you",
+	"document.updated":         "Your document was edited",
+	"document.section_updated": "A section you follow was edited",
+	"document.stale":           "A document is due for review",
+	"document.expiring":        "A document expires soon",
+}
+
+type Service interface {
+	RegisterDevice(ctx context.Context, userID uuid.UUID, req model.DeviceRegisterRequest) (*model.Device, error)
+	GetDevices(ctx context.Context, userID uuid.UUID) ([]*model.Device, error)
+	RemoveDevice(ctx context.Context, id uuid.UUID, userID uuid.UUID) error
+
+	// Notify pushes an event in the background to every registered device
+	// of the user. Devices whose token was rejected are removed.
+	Notify(ctx context.Context, userID uuid.UUID, event string, data interface{})
+}
+
+type pushService struct {
+	repo    repository.Repository
+	senders map[model.Platform]provider.Sender
+	logger  *zap.Logger
+}
+
+func NewPushService(repo repository.Repository, senders map[model.Platform]provider.Sender, logger *zap.Logger) Service {
+	return &pushService{
+		repo:    repo,
+		senders: senders,
+		logger:  logger,
+	}
+}
+
+func (s *pushService) RegisterDevice(ctx context.Context, userID uuid.UUID, req model.DeviceRegisterRequest) (*model.Device, error) {
+	device := &model.Device{
+		UserID:    userID,
+		Platform:  req.Platform,
+		Token:     req.Token,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	if err := s.repo.SaveDevice(ctx, device); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to register push device", zap.Error(err))
+		return nil, err
+	}
+
+	return device, nil
+}
+
+func (s *pushService) GetDevices(ctx context.Context, userID uuid.UUID) ([]*model.Device, error) {
+	devices, err := s.repo.GetDevicesByUserID(ctx, userID)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get push devices", zap.Error(err))
+		return nil, err
+	}
+	return devices, nil
+}
+
+func (s *pushService) RemoveDevice(ctx context.Context, id uuid.UUID, userID uuid.UUID) error {
+	device, err := s.repo.GetDeviceByID(ctx, id)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get push device", zap.Error(err))
+		return err
+	}
+
+	if device == nil {
+		return ErrDeviceNotFound
+	}
+
+	if device.UserID != userID {
+		return ErrUnauthorized
+	}
+
+	return s.repo.DeleteDevice(ctx, id)
+}
+
+func (s *pushService) Notify(ctx context.Context, userID uuid.UUID, event string, data interface{}) {
+	title, ok := alertTitles[event]
+	if !ok || len(s.senders) == 0 {
+		return
+	}
+
+	ctx = logging.Detach(ctx)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+		defer cancel()
+
+		devices, err := s.repo.GetDevicesByUserID(ctx, userID)
+		if err != nil {
+			logging.FromContext(ctx, s.logger).Error("Failed to load push devices for notification", zap.Error(err))
+			return
+		}
+		if len(devices) == 0 {
+			return
+		}
+
+		message, err := newMessage(title, event, data)
+		if err != nil {
+			logging.FromContext(ctx, s.logger).Error("Failed to build push notification", zap.Error(err))
+			return
+		}
+
+		for _, device := range devices {
+			sender, ok := s.senders[device.Platform]
+			if !ok {
+				continue
+			}
+
+			err := sender.Send(ctx, device.Token, message)
+			if errors.Is(err, provider.ErrInvalidToken) {
+				logging.FromContext(ctx, s.logger).Info("Removing push device with rejected token",
+					zap.String("device_id", device.ID.String()))
+				if err := s.repo.DeleteDeviceByToken(ctx, device.Token); err != nil {
+					logging.FromContext(ctx, s.logger).Warn("Failed to remove push device", zap.Error(err))
+				}
+			} else if err != nil {
+				logging.FromContext(ctx, s.logger).Warn("Failed to send push notification",
+					zap.String("device_id", device.ID.String()),
+					zap.String("platform", string(device.Platform)),
+					zap.Error(err))
+			}
+		}
+	}()
+}
+
+// newMessage builds the alert for an event. The document title, when the
+// payload has one, becomes the body; the app receives the event name, the
+// document ID and the whole payload as JSON in the data.
+func newMessage(title string, event string, data interface{}) (model.Message, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return model.Message{}, err
+	}
+
+	var fields struct {
+		DocumentID string `json:"document_id"`
+		Title      string `json:"title"`
+	}
+	_ = json.Unmarshal(payload, &fields)
+
+	message := model.Message{
+		Title: title,
+		Body:  fields.Title,
+		Data: map[string]string{
+			"event":   event,
+			"payload": string(payload),
+		},
+	}
+	if fields.DocumentID != "" {
+		message.Data["document_id"] = fields.DocumentID
+	}
+	return message, nil
+}
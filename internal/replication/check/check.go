@@ -0,0 +1,142 @@
+// Package check compares documents between the databases of two regions,
+// by hashes of their content and history, to confirm replication keeps
+// them equal.
+package check
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// digestQuery hashes each document, deleted ones included, in ID order.
+// The history hash covers every stored version as it is stored, snapshot
+// or delta.
+const digestQuery = `
+	SELECT d.id, d.version, d.deleted_at IS NOT NULL, md5(COALESCE(d.content, '')),
+		COALESCE((
+			SELECT md5(string_agg(h.version || ':' || md5(COALESCE(h.content, '')) || ':' || md5(COALESCE(h.delta, '')), ',' ORDER BY h.version))
+			FROM document_histories h WHERE h.document_id = d.id
+		), '')
+	FROM documents d
+	ORDER BY d.id`
+
+// Digest identifies the state of one document
+type Digest struct {
+	ID          uuid.UUID
+	Version     int
+	Deleted     bool
+	ContentHash string
+	HistoryHash string
+}
+
+// Mismatch is a document that differs between the regions. Reason names
+// the first difference found.
+type Mismatch struct {
+	DocumentID uuid.UUID
+	Reason     string
+}
+
+// Report counts the documents compared, from the union of both regions
+type Report struct {
+	Documents  int
+	Mismatches []Mismatch
+}
+
+// Compare streams the digests of both databases side by side. Documents
+// written while it runs may show up as mismatches, so run it again before
+// acting on them.
+func Compare(ctx context.Context, primary, replica *sql.DB) (*Report, error) {
+	primaryRows, err := primary.QueryContext(ctx, digestQuery)
+	if err != nil {
+		return nil, fmt.Errorf("primary: %w", err)
+	}
+	defer primaryRows.Close()
+
+	replicaRows, err := replica.QueryContext(ctx, digestQuery)
+	if err != nil {
+		return nil, fmt.Errorf("replica: %w", err)
+	}
+	defer replicaRows.Close()
+
+	report := &Report{}
+	p, err := next(primaryRows)
+	if err != nil {
+		return nil, fmt.Errorf("primary: %w", err)
+	}
+	r, err := next(replicaRows)
+	if err != nil {
+		return nil, fmt.Errorf("replica: %w", err)
+	}
+
+	for p != nil || r != nil {
+		report.Documents++
+
+		// uuid columns sort bytewise, as bytes.Compare does
+		order := 0
+		switch {
+		case p == nil:
+			order = 1
+		case r == nil:
+			order = -1
+		default:
+			order = bytes.Compare(p.ID[:], r.ID[:])
+		}
+
+		switch {
+		case order < 0:
+			report.Mismatches = append(report.Mismatches, Mismatch{DocumentID: p.ID, Reason: "missing from replica"})
+		case order > 0:
+			report.Mismatches = append(report.Mismatches, Mismatch{DocumentID: r.ID, Reason: "missing from primary"})
+		default:
+			if reason := differ(p, r); reason != "" {
+				report.Mismatches = append(report.Mismatches, Mismatch{DocumentID: p.ID, Reason: reason})
+			}
+		}
+
+		if order <= 0 {
+			if p, err = next(primaryRows); err != nil {
+				return nil, fmt.Errorf("primary: %w", err)
+			}
+		}
+		if order >= 0 {
+			if r, err = next(replicaRows); err != nil {
+				return nil, fmt.Errorf("replica: %w", err)
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// differ describes the first difference between two digests of the same
+// document, or returns "" when they match
+func differ(primary, replica *Digest) string {
+	switch {
+	case primary.Version != replica.Version:
+		return fmt.Sprintf("version %d in primary, %d in replica", primary.Version, replica.Version)
+	case primary.Deleted != replica.Deleted:
+		return "deleted in only one region"
+	case primary.ContentHash != replica.ContentHash:
+		return "content differs"
+	case primary.HistoryHash != replica.HistoryHash:
+		return "history differs"
+	}
+	return ""
+}
+
+// next scans the next digest, or returns nil after the last
+func next(rows *sql.Rows) (*Digest, error) {
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	var digest Digest
+	if err := rows.Scan(&digest.ID, &digest.Version, &digest.Deleted, &digest.ContentHash, &digest.HistoryHash); err != nil {
+		return nil, err
+	}
+	return &digest, nil
+}
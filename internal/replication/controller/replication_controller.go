@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/hafiztri123/document-api/internal/errcode"
+	"github.com/hafiztri123/document-api/internal/logging"
+	"github.com/hafiztri123/document-api/internal/replication/model"
+	"github.com/hafiztri123/document-api/internal/replication/service"
+)
+
+type Controller interface {
+	ApplyChanges(c *gin.Context)
+}
+
+type replicationController struct {
+	service service.Service
+	logger  *zap.Logger
+}
+
+func NewReplicationController(service service.Service, logger *zap.Logger) Controller {
+	return &replicationController{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ApplyChanges receives a batch of changes published by the primary
+// region. The batch is authenticated by its X-Webhook-Signature instead of
+// a user.
+func (ctrl *replicationController) ApplyChanges(c *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, model.MaxBatchBytes))
+	if err != nil {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Replication batch is too large",
+		}})
+		return
+	}
+
+	result, err := ctrl.service.Apply(c.Request.Context(), body, c.GetHeader("X-Webhook-Signature"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrApplyDisabled):
+			c.JSON(http.StatusForbidden, gin.H{"error": gin.H{
+				"code":    errcode.Forbidden,
+				"message": "This region does not accept replication changes",
+			}})
+		case errors.Is(err, service.ErrInvalidSignature):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+				"code":    errcode.Unauthorized,
+				"message": "Invalid replication signature",
+			}})
+		case errors.Is(err, service.ErrInvalidChange):
+			c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+				"code":    errcode.ValidationError,
+				"message": "Invalid replication batch",
+				"details": err.Error(),
+			}})
+		default:
+			logging.FromContext(c.Request.Context(), ctrl.logger).Error("Failed to apply replication changes", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
+				"code":    errcode.InternalError,
+				"message": "Failed to apply replication changes",
+			}})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
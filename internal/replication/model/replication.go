@@ -0,0 +1,85 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Operations a change records. An upsert carries the whole row as written;
+// a delete only its ID.
+const (
+	OperationUpsert = "upsert"
+	OperationDelete = "delete"
+)
+
+// Checkpoints, one per side of replication
+const (
+	CheckpointPublish = "publish"
+	CheckpointApply   = "apply"
+)
+
+// MaxBatchBytes bounds a published batch. Batches hold fewer changes than
+// replication.publish.batch_size when their rows are large, but always at
+// least one.
+const MaxBatchBytes = 64 << 20
+
+// Tables lists the tables whose writes are captured, see the
+// capture_replication_change trigger. Changes to any other table are
+// refused when applied.
+var Tables = []string{"documents", "document_histories"}
+
+// Change is one captured row write. Changes are published and applied in
+// order of the transaction that captured them, XactID, then of ID.
+type Change struct {
+	ID        int64           `gorm:"primary_key" json:"id"`
+	XactID    int64           `json:"xact_id"`
+	Table     string          `gorm:"column:table_name" json:"table"`
+	Operation string          `json:"operation"`
+	RowID     uuid.UUID       `gorm:"type:uuid" json:"row_id"`
+	RowData   json.RawMessage `gorm:"type:jsonb;serializer:json" json:"row,omitempty"`
+	ChangedAt time.Time       `json:"changed_at"`
+}
+
+func (Change) TableName() string {
+	return "replication_changes"
+}
+
+// After reports whether the change comes after (xactID, id) in publishing
+// order
+func (c *Change) After(xactID, id int64) bool {
+	return c.XactID > xactID || c.XactID == xactID && c.ID > id
+}
+
+// Checkpoint is the position, LastXactID and LastID, of the last change
+// published or applied. LockedUntil leases publishing to one instance at a
+// time.
+type Checkpoint struct {
+	Name        string `gorm:"type:varchar(64);primary_key"`
+	LastXactID  int64
+	LastID      int64
+	LockedUntil *time.Time
+	UpdatedAt   time.Time `gorm:"not null"`
+}
+
+func (Checkpoint) TableName() string {
+	return "replication_checkpoints"
+}
+
+// Publish is read from replication.publish. Publishing is off while URL is
+// empty.
+type Publish struct {
+	URL    string `mapstructure:"url"`
+	Secret string `mapstructure:"secret"`
+}
+
+// ApplyResult reports a batch of changes applied in the secondary.
+// LastXactID and LastID are where it now stands, so changes it had already
+// applied are counted as skipped.
+type ApplyResult struct {
+	Applied    int   `json:"applied"`
+	Skipped    int   `json:"skipped"`
+	LastXactID int64 `json:"last_xact_id"`
+	LastID     int64 `json:"last_id"`
+}
@@ -0,0 +1,86 @@
+// Package replication streams document and history writes to a secondary
+// region for disaster recovery. The capture_replication_change trigger
+// records each write as a change; the primary publishes changes in order to
+// the secondary, which applies them. The check package compares the two
+// regions afterwards.
+package replication
+
+import (
+	"context"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"github.com/hafiztri123/document-api/config"
+	"github.com/hafiztri123/document-api/internal/api"
+	"github.com/hafiztri123/document-api/internal/logging"
+	"github.com/hafiztri123/document-api/internal/replication/controller"
+	"github.com/hafiztri123/document-api/internal/replication/repository"
+	"github.com/hafiztri123/document-api/internal/replication/service"
+)
+
+// Module provides the replication repository, service, controller and
+// routes, and schedules publishing
+var Module = fx.Module("replication",
+	fx.Provide(
+		repository.NewReplicationRepository,
+		service.NewReplicationService,
+		controller.NewReplicationController,
+		api.AsRouteRegistrar(newRoutes),
+	),
+	fx.Invoke(startPublisher),
+)
+
+// startPublisher publishes new changes every replication.publish.interval
+// while replication.publish.url is set. Every instance runs it; publishing
+// is leased to one instance at a time.
+func startPublisher(lc fx.Lifecycle, svc service.Service, logger *zap.Logger) {
+	if viper.GetString(config.REPLICATION_PUBLISH_URL) == "" {
+		return
+	}
+
+	interval, err := time.ParseDuration(viper.GetString(config.REPLICATION_PUBLISH_INTERVAL))
+	if err != nil || interval <= 0 {
+		logger.Warn("Invalid replication.publish.interval, using default 5s", zap.Error(err))
+		interval = 5 * time.Second
+	}
+
+	logger = logger.With(zap.String("task", "replication_publish"))
+	ctx, cancel := context.WithCancel(logging.NewContext(context.Background(), logger))
+	done := make(chan struct{})
+
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go func() {
+				defer close(done)
+
+				ticker := time.NewTicker(interval)
+				defer ticker.Stop()
+
+				for {
+					if published, err := svc.Publish(ctx); err == nil && published > 0 {
+						logger.Debug("Published replication changes", zap.Int("count", published))
+					}
+
+					select {
+					case <-ctx.Done():
+						return
+					case <-ticker.C:
+					}
+				}
+			}()
+			return nil
+		},
+		OnStop: func(stopCtx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-stopCtx.Done():
+				return stopCtx.Err()
+			}
+		},
+	})
+}
@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/hafiztri123/document-api/internal/logging"
+	"github.com/hafiztri123/document-api/internal/replication/model"
+)
+
+type Repository interface {
+	// ClaimPublishing leases publishing to the caller until now+lease and
+	// returns the publish checkpoint, or nil while another instance holds
+	// the lease
+	ClaimPublishing(ctx context.Context, now time.Time, lease time.Duration) (*model.Checkpoint, error)
+	// AdvancePublished records that changes up to (lastXactID, lastID) are
+	// published, deletes them and extends the lease
+	AdvancePublished(ctx context.Context, lastXactID, lastID int64, lockedUntil time.Time) error
+	ReleasePublishing(ctx context.Context) error
+	// GetChanges returns up to limit changes after (afterXactID, afterID)
+	// in publishing order. Only changes of transactions older than every
+	// running one are returned, so none can commit behind them later.
+	GetChanges(ctx context.Context, afterXactID, afterID int64, limit int) ([]*model.Change, error)
+
+	// ApplyChanges writes changes in one transaction, skipping those at or
+	// before the apply checkpoint, without capturing them again
+	ApplyChanges(ctx context.Context, changes []*model.Change) (*model.ApplyResult, error)
+}
+
+type replicationRepository struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+func NewReplicationRepository(db *gorm.DB, logger *zap.Logger) Repository {
+	return &replicationRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *replicationRepository) ClaimPublishing(ctx context.Context, now time.Time, lease time.Duration) (*model.Checkpoint, error) {
+	var checkpoints []*model.Checkpoint
+	err := r.db.WithContext(ctx).Raw(`
+		INSERT INTO replication_checkpoints (name, locked_until, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET locked_until = EXCLUDED.locked_until, updated_at = EXCLUDED.updated_at
+		WHERE replication_checkpoints.locked_until IS NULL OR replication_checkpoints.locked_until < ?
+		RETURNING *`, model.CheckpointPublish, now.Add(lease), now, now).
+		Scan(&checkpoints).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to claim replication publishing", zap.Error(err))
+		return nil, err
+	}
+	if len(checkpoints) == 0 {
+		return nil, nil
+	}
+	return checkpoints[0], nil
+}
+
+func (r *replicationRepository) AdvancePublished(ctx context.Context, lastXactID, lastID int64, lockedUntil time.Time) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.Checkpoint{}).
+			Where("name = ?", model.CheckpointPublish).
+			Updates(map[string]interface{}{
+				"last_xact_id": lastXactID,
+				"last_id":      lastID,
+				"locked_until": lockedUntil,
+				"updated_at":   time.Now(),
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Where("(xact_id, id) <= (?, ?)", lastXactID, lastID).Delete(&model.Change{}).Error
+	})
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to advance replication checkpoint", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r *replicationRepository) ReleasePublishing(ctx context.Context) error {
+	err := r.db.WithContext(ctx).Model(&model.Checkpoint{}).
+		Where("name = ?", model.CheckpointPublish).
+		Update("locked_until", nil).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to release replication publishing", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r *replicationRepository) GetChanges(ctx context.Context, afterXactID, afterID int64, limit int) ([]*model.Change, error) {
+	var changes []*model.Change
+	err := r.db.WithContext(ctx).
+		Where("(xact_id, id) > (?, ?)", afterXactID, afterID).
+		Where("xact_id < pg_snapshot_xmin(pg_current_snapshot())::text::bigint").
+		Order("xact_id, id").
+		Limit(limit).
+		Find(&changes).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get replication changes", zap.Error(err))
+		return nil, err
+	}
+	return changes, nil
+}
+
+func (r *replicationRepository) ApplyChanges(ctx context.Context, changes []*model.Change) (*model.ApplyResult, error) {
+	result := &model.ApplyResult{}
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// A secondary that publishes too must not echo what it applies
+		if err := tx.Exec("SET LOCAL document_api.capture_changes = 'off'").Error; err != nil {
+			return err
+		}
+
+		checkpoint := model.Checkpoint{Name: model.CheckpointApply}
+		err := tx.Raw(`
+			INSERT INTO replication_checkpoints (name, updated_at) VALUES (?, NOW())
+			ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+			RETURNING *`, model.CheckpointApply).
+			Scan(&checkpoint).Error
+		if err != nil {
+			return err
+		}
+		result.LastXactID, result.LastID = checkpoint.LastXactID, checkpoint.LastID
+
+		upserts := make(map[string]string)
+		for _, change := range changes {
+			if !change.After(result.LastXactID, result.LastID) {
+				result.Skipped++
+				continue
+			}
+
+			switch change.Operation {
+			case model.OperationUpsert:
+				upsert, ok := upserts[change.Table]
+				if !ok {
+					if upsert, err = upsertStatement(tx, change.Table); err != nil {
+						return err
+					}
+					upserts[change.Table] = upsert
+				}
+				if err := tx.Exec(upsert, string(change.RowData)).Error; err != nil {
+					return fmt.Errorf("apply change %d: %w", change.ID, err)
+				}
+			case model.OperationDelete:
+				if err := tx.Exec(fmt.Sprintf("DELETE FROM %q WHERE id = ?", change.Table), change.RowID).Error; err != nil {
+					return fmt.Errorf("apply change %d: %w", change.ID, err)
+				}
+			default:
+				return fmt.Errorf("apply change %d: unknown operation %q", change.ID, change.Operation)
+			}
+
+			result.Applied++
+			result.LastXactID, result.LastID = change.XactID, change.ID
+		}
+
+		return tx.Model(&model.Checkpoint{}).
+			Where("name = ?", model.CheckpointApply).
+			Updates(map[string]interface{}{
+				"last_xact_id": result.LastXactID,
+				"last_id":      result.LastID,
+				"updated_at":   time.Now(),
+			}).Error
+	})
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to apply replication changes", zap.Error(err))
+		return nil, err
+	}
+	return result, nil
+}
+
+// upsertStatement writes a row given as JSON into table, replacing every
+// column of an existing row with the same ID. Columns missing from the
+// JSON are written as NULL and then filled by the table's triggers, as
+// content_tsv is.
+func upsertStatement(tx *gorm.DB, table string) (string, error) {
+	var columns []string
+	err := tx.Raw(`
+		SELECT column_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = ?
+		ORDER BY ordinal_position`, table).
+		Scan(&columns).Error
+	if err != nil {
+		return "", err
+	}
+	if len(columns) == 0 {
+		return "", fmt.Errorf("table %q does not exist", table)
+	}
+
+	updates := make([]string, 0, len(columns))
+	for _, column := range columns {
+		if column != "id" {
+			updates = append(updates, fmt.Sprintf("%q = EXCLUDED.%q", column, column))
+		}
+	}
+
+	return fmt.Sprintf(
+		"INSERT INTO %[1]q SELECT * FROM jsonb_populate_record(NULL::%[1]q, ?::jsonb) ON CONFLICT (id) DO UPDATE SET %[2]s",
+		table, strings.Join(updates, ", "),
+	), nil
+}
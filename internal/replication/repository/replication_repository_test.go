@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/hafiztri123/document-api/internal/database/dbtest"
+	"github.com/hafiztri123/document-api/internal/replication/model"
+)
+
+// capture records a change in tx as the capture trigger would and returns
+// its ID
+func capture(t *testing.T, tx *gorm.DB) int64 {
+	t.Helper()
+
+	var id int64
+	err := tx.Raw(`INSERT INTO replication_changes (table_name, operation, row_id)
+		VALUES ('documents', 'delete', ?) RETURNING id`, uuid.New()).Scan(&id).Error
+	if err != nil {
+		t.Fatalf("capture: %v", err)
+	}
+	return id
+}
+
+func getChanges(t *testing.T, repo Repository, afterXactID, afterID int64) []*model.Change {
+	t.Helper()
+
+	changes, err := repo.GetChanges(context.Background(), afterXactID, afterID, 100)
+	if err != nil {
+		t.Fatalf("GetChanges: %v", err)
+	}
+	return changes
+}
+
+func TestGetChangesWaitsForLateCommits(t *testing.T) {
+	db := dbtest.Open(t)
+	repo := NewReplicationRepository(db, zap.NewNop())
+
+	// older starts first, so its transaction ID is lower, but captures its
+	// change after late, which commits last
+	older := db.Begin()
+	defer older.Rollback()
+	if err := older.Exec("SELECT pg_current_xact_id()").Error; err != nil {
+		t.Fatalf("start older: %v", err)
+	}
+	late := db.Begin()
+	defer late.Rollback()
+	lateID := capture(t, late)
+	olderID := capture(t, older)
+	if err := older.Commit().Error; err != nil {
+		t.Fatalf("commit older: %v", err)
+	}
+	committedID := capture(t, db)
+
+	if olderID < lateID {
+		t.Fatalf("older change %d was captured before late change %d", olderID, lateID)
+	}
+
+	changes := getChanges(t, repo, 0, 0)
+	if len(changes) != 1 || changes[0].ID != olderID {
+		t.Fatalf("changes while late is running = %v, want only %d", changes, olderID)
+	}
+	checkpoint := changes[0]
+
+	if err := late.Commit().Error; err != nil {
+		t.Fatalf("commit late: %v", err)
+	}
+
+	changes = getChanges(t, repo, checkpoint.XactID, checkpoint.ID)
+	if len(changes) != 2 || changes[0].ID != lateID || changes[1].ID != committedID {
+		t.Fatalf("changes after the checkpoint = %v, want %d then %d", changes, lateID, committedID)
+	}
+}
+
+func TestApplyChangesSkipsApplied(t *testing.T) {
+	db := dbtest.Open(t)
+	repo := NewReplicationRepository(db, zap.NewNop())
+	ctx := context.Background()
+
+	// Deletes of missing rows apply cleanly and are enough to move the
+	// checkpoint
+	change := func(xactID, id int64) *model.Change {
+		return &model.Change{ID: id, XactID: xactID, Table: "documents", Operation: model.OperationDelete, RowID: uuid.New()}
+	}
+
+	result, err := repo.ApplyChanges(ctx, []*model.Change{change(10, 5), change(11, 2)})
+	if err != nil {
+		t.Fatalf("ApplyChanges: %v", err)
+	}
+	if result.Applied != 2 || result.LastXactID != 11 || result.LastID != 2 {
+		t.Fatalf("first batch = %+v, want 2 applied up to (11, 2)", result)
+	}
+
+	// The batch is sent again with a change from a later transaction that
+	// has a lower ID
+	result, err = repo.ApplyChanges(ctx, []*model.Change{change(10, 5), change(11, 2), change(12, 1)})
+	if err != nil {
+		t.Fatalf("ApplyChanges: %v", err)
+	}
+	if result.Applied != 1 || result.Skipped != 2 || result.LastXactID != 12 || result.LastID != 1 {
+		t.Errorf("second batch = %+v, want 1 applied and 2 skipped up to (12, 1)", result)
+	}
+}
@@ -0,0 +1,20 @@
+package replication
+
+import (
+	"github.com/hafiztri123/document-api/internal/api"
+	"github.com/hafiztri123/document-api/internal/replication/controller"
+)
+
+type routes struct {
+	ctrl controller.Controller
+}
+
+func newRoutes(ctrl controller.Controller) *routes {
+	return &routes{ctrl: ctrl}
+}
+
+func (r *routes) RegisterRoutes(groups api.Groups) {
+	// Changes published by the primary region, signed with
+	// replication.apply.secret
+	groups.Public.POST("/replication/changes", r.ctrl.ApplyChanges)
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/hafiztri123/document-api/config"
+	"github.com/hafiztri123/document-api/internal/logging"
+	"github.com/hafiztri123/document-api/internal/replication/model"
+	"github.com/hafiztri123/document-api/internal/replication/repository"
+	"github.com/hafiztri123/document-api/internal/signature"
+)
+
+var (
+	ErrApplyDisabled    = errors.New("applying replication changes is not enabled")
+	ErrInvalidChange    = errors.New("invalid replication change")
+	ErrInvalidSignature = errors.New("invalid replication signature")
+)
+
+// ContentType of published batches: one change per line, as JSON
+const ContentType = "application/x-ndjson"
+
+// publishLease is how long publishing stays claimed by an instance without
+// progress; each published batch renews it
+const publishLease = 5 * time.Minute
+
+// publishTimeout bounds a single request to the secondary
+const publishTimeout = time.Minute
+
+type Service interface {
+	// Publish sends every change of transactions that have ended to the
+	// secondary in batches and returns how many. It does nothing while replication.publish.url is
+	// unset.
+	Publish(ctx context.Context) (int, error)
+
+	// Apply verifies a batch published by the primary and applies it. The
+	// batch is applied whole or not at all.
+	Apply(ctx context.Context, body []byte, signatureHeader string) (*model.ApplyResult, error)
+}
+
+type replicationService struct {
+	repo        repository.Repository
+	publish     model.Publish
+	batchSize   int
+	applySecret string
+	client      *http.Client
+	logger      *zap.Logger
+}
+
+func NewReplicationService(repo repository.Repository, logger *zap.Logger) Service {
+	var publish model.Publish
+	if err := viper.UnmarshalKey(config.REPLICATION_PUBLISH, &publish); err != nil {
+		logger.Error("Invalid replication.publish, not publishing changes", zap.Error(err))
+		publish = model.Publish{}
+	}
+
+	batchSize := viper.GetInt(config.REPLICATION_PUBLISH_BATCH_SIZE)
+	if batchSize < 1 {
+		logger.Warn("Invalid replication.publish.batch_size, using default 500")
+		batchSize = 500
+	}
+
+	return &replicationService{
+		repo:        repo,
+		publish:     publish,
+		batchSize:   batchSize,
+		applySecret: viper.GetString(config.REPLICATION_APPLY_SECRET),
+		client:      &http.Client{Timeout: publishTimeout},
+		logger:      logger,
+	}
+}
+
+// Publish advances the checkpoint only once the secondary accepts a batch,
+// so a failed batch is sent again from its first change. The secondary
+// skips changes it already applied.
+func (s *replicationService) Publish(ctx context.Context) (int, error) {
+	if s.publish.URL == "" {
+		return 0, nil
+	}
+
+	now := time.Now()
+	checkpoint, err := s.repo.ClaimPublishing(ctx, now, publishLease)
+	if err != nil {
+		return 0, err
+	}
+	if checkpoint == nil {
+		// Another instance is publishing
+		return 0, nil
+	}
+	defer func() {
+		_ = s.repo.ReleasePublishing(context.WithoutCancel(ctx))
+	}()
+
+	published := 0
+	for {
+		changes, err := s.repo.GetChanges(ctx, checkpoint.LastXactID, checkpoint.LastID, s.batchSize)
+		if err != nil {
+			return published, err
+		}
+		if len(changes) == 0 {
+			return published, nil
+		}
+
+		body, sent, err := encodeBatch(changes)
+		if err != nil {
+			return published, err
+		}
+		if err := s.send(ctx, body); err != nil {
+			logging.FromContext(ctx, s.logger).Error("Failed to publish replication changes",
+				zap.Int64("first_id", changes[0].ID), zap.Error(err))
+			return published, err
+		}
+
+		last := changes[sent-1]
+		if err := s.repo.AdvancePublished(ctx, last.XactID, last.ID, time.Now().Add(publishLease)); err != nil {
+			return published, err
+		}
+		checkpoint.LastXactID, checkpoint.LastID = last.XactID, last.ID
+		published += sent
+
+		if len(changes) < s.batchSize && sent == len(changes) {
+			return published, nil
+		}
+	}
+}
+
+// encodeBatch writes changes one per line, stopping before the batch
+// would outgrow model.MaxBatchBytes, and returns how many it wrote
+func encodeBatch(changes []*model.Change) ([]byte, int, error) {
+	var body, line bytes.Buffer
+	encoder := json.NewEncoder(&line)
+	encoder.SetEscapeHTML(false)
+	for i, change := range changes {
+		line.Reset()
+		if err := encoder.Encode(change); err != nil {
+			return nil, 0, err
+		}
+		if i > 0 && body.Len()+line.Len() > model.MaxBatchBytes {
+			return body.Bytes(), i, nil
+		}
+		body.Write(line.Bytes())
+	}
+	return body.Bytes(), len(changes), nil
+}
+
+// send POSTs a batch to the secondary, signed like outgoing webhooks
+func (s *replicationService) send(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.publish.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", ContentType)
+	if s.publish.Secret != "" {
+		req.Header.Set("X-Webhook-Signature", signature.Header(body, time.Now(), s.publish.Secret))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("secondary responded with status %d: %s", resp.StatusCode, snippet)
+	}
+	return nil
+}
+
+func (s *replicationService) Apply(ctx context.Context, body []byte, signatureHeader string) (*model.ApplyResult, error) {
+	if s.applySecret == "" {
+		return nil, ErrApplyDisabled
+	}
+
+	if err := signature.Verify(body, signatureHeader, signature.DefaultTolerance, time.Now(), s.applySecret); err != nil {
+		return nil, ErrInvalidSignature
+	}
+
+	changes, err := decodeChanges(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.ApplyChanges(ctx, changes)
+}
+
+// decodeChanges reads a published batch, refusing changes out of
+// publishing order or to tables that are not replicated
+func decodeChanges(body []byte) ([]*model.Change, error) {
+	var changes []*model.Change
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	for decoder.More() {
+		var change model.Change
+		if err := decoder.Decode(&change); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidChange, err)
+		}
+
+		if !slices.Contains(model.Tables, change.Table) {
+			return nil, fmt.Errorf("%w: table %q is not replicated", ErrInvalidChange, change.Table)
+		}
+		if change.Operation != model.OperationUpsert && change.Operation != model.OperationDelete {
+			return nil, fmt.Errorf("%w: unknown operation %q", ErrInvalidChange, change.Operation)
+		}
+		if change.Operation == model.OperationUpsert && len(change.RowData) == 0 {
+			return nil, fmt.Errorf("%w: change %d has no row", ErrInvalidChange, change.ID)
+		}
+		if previous := len(changes) - 1; previous >= 0 && !change.After(changes[previous].XactID, changes[previous].ID) {
+			return nil, fmt.Errorf("%w: change %d is out of order", ErrInvalidChange, change.ID)
+		}
+
+		changes = append(changes, &change)
+	}
+	return changes, nil
+}
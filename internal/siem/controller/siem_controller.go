@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/hafiztri123/document-api/internal/errcode"
+	"github.com/hafiztri123/document-api/internal/logging"
+	"github.com/hafiztri123/document-api/internal/siem/model"
+	"github.com/hafiztri123/document-api/internal/siem/service"
+)
+
+type Controller interface {
+	GetEvents(c *gin.Context)
+}
+
+type siemController struct {
+	service service.Service
+	logger  *zap.Logger
+}
+
+func NewSIEMController(service service.Service, logger *zap.Logger) Controller {
+	return &siemController{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// GetEvents lists recorded security events, newest first, with their
+// delivery state. ?pending=true keeps those not delivered yet.
+func (ctrl *siemController) GetEvents(c *gin.Context) {
+	var filter model.EventFilter
+	if value := c.Query("user_id"); value != "" {
+		userID, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+				"code":    errcode.ValidationError,
+				"message": "Invalid user ID",
+			}})
+			return
+		}
+		filter.UserID = &userID
+	}
+	filter.Type = model.Type(c.Query("type"))
+	filter.Pending = c.Query("pending") == "true"
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+	if perPage > 100 {
+		perPage = 100
+	}
+
+	events, total, err := ctrl.service.GetEvents(c.Request.Context(), filter, page, perPage)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), ctrl.logger).Error("Failed to get security events", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
+			"code":    errcode.InternalError,
+			"message": "Failed to retrieve security events",
+		}})
+		return
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 {
+		perPage = 20
+	}
+	totalPages := (int(total) + perPage - 1) / perPage
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": events,
+		"pagination": gin.H{
+			"total":       total,
+			"page":        page,
+			"per_page":    perPage,
+			"total_pages": totalPages,
+		},
+	})
+}
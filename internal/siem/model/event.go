@@ -0,0 +1,139 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Type names a security event. Types are grouped by the prefix before the
+// dot: auth, permission, export and admin.
+type Type string
+
+const (
+	TypeLogin       Type = "auth.login"
+	TypeLoginFailed Type = "auth.login_failed"
+	TypeLogout      Type = "auth.logout"
+
+	TypeCollaboratorAdded    Type = "permission.collaborator_added"
+	TypeCollaboratorUpdated  Type = "permission.collaborator_updated"
+	TypeCollaboratorRemoved  Type = "permission.collaborator_removed"
+	TypeVisibilityChanged    Type = "permission.visibility_changed"
+	TypeAnalyticsAccess      Type = "permission.analytics_access_changed"
+	TypeOwnershipTransferred Type = "permission.ownership_transferred"
+
+	TypeDocumentExported Type = "export.document"
+	TypeHistoryExported  Type = "export.history"
+
+	// TypeAdminAction is any request to /api/v1/admin, refused ones
+	// included
+	TypeAdminAction Type = "admin.action"
+)
+
+// Severity is the CEF severity of the type, from 0 to 10
+func (t Type) Severity() int {
+	switch t {
+	case TypeLoginFailed, TypeAdminAction:
+		return 6
+	case TypeCollaboratorAdded, TypeCollaboratorUpdated, TypeCollaboratorRemoved, TypeVisibilityChanged, TypeAnalyticsAccess, TypeOwnershipTransferred:
+		return 5
+	case TypeDocumentExported, TypeHistoryExported:
+		return 4
+	default:
+		return 3
+	}
+}
+
+type Outcome string
+
+const (
+	OutcomeSuccess Outcome = "success"
+	OutcomeFailure Outcome = "failure"
+)
+
+// Targets an event may name
+const (
+	TargetDocument = "document"
+	TargetUser     = "user"
+)
+
+// LeaseDeliver leases delivery to one instance at a time
+const LeaseDeliver = "deliver"
+
+// Message is an event as the SIEM receives it. UserID is who acted, or nil
+// when nobody could be identified, as for a login with an unknown email.
+// ID increases with each event and is unique, so receivers can order and
+// deduplicate on it.
+type Message struct {
+	ID         int64                  `gorm:"primary_key" json:"id"`
+	Type       Type                   `gorm:"type:varchar(64);not null" json:"type"`
+	Outcome    Outcome                `gorm:"type:varchar(16);not null" json:"outcome"`
+	UserID     *uuid.UUID             `gorm:"type:uuid" json:"user_id"`
+	TargetType string                 `gorm:"type:varchar(32)" json:"target_type,omitempty"`
+	TargetID   *uuid.UUID             `gorm:"type:uuid" json:"target_id,omitempty"`
+	IPAddress  string                 `gorm:"type:varchar(45)" json:"ip_address,omitempty"`
+	UserAgent  string                 `gorm:"type:varchar(255)" json:"user_agent,omitempty"`
+	RequestID  string                 `gorm:"type:varchar(128)" json:"request_id,omitempty"`
+	Data       map[string]interface{} `gorm:"type:jsonb;serializer:json" json:"data,omitempty"`
+	OccurredAt time.Time              `gorm:"not null" json:"occurred_at"`
+}
+
+// Event is a message with its delivery state. A user's events are
+// delivered in ID order: while one is failing, the ones after it wait.
+type Event struct {
+	Message       `gorm:"embedded"`
+	DeliveredAt   *time.Time `json:"delivered_at"`
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"not null" json:"next_attempt_at"`
+	LastError     string     `gorm:"type:text" json:"last_error,omitempty"`
+}
+
+func (Event) TableName() string {
+	return "security_events"
+}
+
+// Lease is held by the instance delivering events
+type Lease struct {
+	Name        string `gorm:"type:varchar(64);primary_key"`
+	LockedUntil *time.Time
+	UpdatedAt   time.Time `gorm:"not null"`
+}
+
+func (Lease) TableName() string {
+	return "security_event_leases"
+}
+
+// EventFilter narrows GET /admin/security-events
+type EventFilter struct {
+	UserID  *uuid.UUID
+	Type    Type
+	Pending bool
+}
+
+// Sink is read from siem. Events are not recorded while Transport is
+// empty.
+type Sink struct {
+	// Transport is TransportHTTPS or TransportSyslog
+	Transport string `mapstructure:"transport"`
+
+	// URL receives each event as JSON, signed with Secret like webhook
+	// deliveries, with Headers added, for example a collector token
+	URL     string            `mapstructure:"url"`
+	Secret  string            `mapstructure:"secret"`
+	Headers map[string]string `mapstructure:"headers"`
+
+	// Address is the host:port of a syslog server taking RFC 5424
+	// messages over TCP, with TLS unless Insecure. Format is FormatCEF or
+	// FormatJSON.
+	Address  string `mapstructure:"address"`
+	Insecure bool   `mapstructure:"insecure"`
+	Format   string `mapstructure:"format"`
+}
+
+const (
+	TransportHTTPS  = "https"
+	TransportSyslog = "syslog"
+
+	FormatCEF  = "cef"
+	FormatJSON = "json"
+)
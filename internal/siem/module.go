@@ -0,0 +1,86 @@
+// Package siem streams security events, such as logins, permission
+// changes, exports and admin actions, to a SIEM. It is separate from the
+// product webhooks: events are recorded as they happen and delivered over
+// HTTPS as JSON, or to syslog as CEF or JSON, in order per user and with
+// retries until they are accepted.
+package siem
+
+import (
+	"context"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"github.com/hafiztri123/document-api/config"
+	"github.com/hafiztri123/document-api/internal/api"
+	"github.com/hafiztri123/document-api/internal/logging"
+	"github.com/hafiztri123/document-api/internal/siem/controller"
+	"github.com/hafiztri123/document-api/internal/siem/repository"
+	"github.com/hafiztri123/document-api/internal/siem/service"
+)
+
+// Module provides the SIEM repository, service, controller and routes, and
+// schedules delivery
+var Module = fx.Module("siem",
+	fx.Provide(
+		repository.NewSIEMRepository,
+		service.NewSIEMService,
+		controller.NewSIEMController,
+		api.AsRouteRegistrar(newRoutes),
+	),
+	fx.Invoke(startDelivery),
+)
+
+// startDelivery delivers due events every siem.interval while
+// siem.transport is set. Every instance runs it; delivery is leased to one
+// instance at a time.
+func startDelivery(lc fx.Lifecycle, svc service.Service, logger *zap.Logger) {
+	if viper.GetString(config.SIEM_TRANSPORT) == "" {
+		return
+	}
+
+	interval, err := time.ParseDuration(viper.GetString(config.SIEM_INTERVAL))
+	if err != nil || interval <= 0 {
+		logger.Warn("Invalid siem.interval, using default 5s", zap.Error(err))
+		interval = 5 * time.Second
+	}
+
+	logger = logger.With(zap.String("task", "siem_delivery"))
+	ctx, cancel := context.WithCancel(logging.NewContext(context.Background(), logger))
+	done := make(chan struct{})
+
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go func() {
+				defer close(done)
+
+				ticker := time.NewTicker(interval)
+				defer ticker.Stop()
+
+				for {
+					if delivered, err := svc.Deliver(ctx); err == nil && delivered > 0 {
+						logger.Debug("Delivered security events", zap.Int("count", delivered))
+					}
+
+					select {
+					case <-ctx.Done():
+						return
+					case <-ticker.C:
+					}
+				}
+			}()
+			return nil
+		},
+		OnStop: func(stopCtx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return svc.Close()
+			case <-stopCtx.Done():
+				return stopCtx.Err()
+			}
+		},
+	})
+}
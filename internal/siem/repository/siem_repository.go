@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/hafiztri123/document-api/internal/logging"
+	"github.com/hafiztri123/document-api/internal/siem/model"
+)
+
+type Repository interface {
+	CreateEvent(ctx context.Context, event *model.Event) error
+	// GetDueEvents returns up to limit undelivered events in ID order,
+	// leaving out every event of a user whose earliest undelivered one is
+	// waiting for a retry after now
+	GetDueEvents(ctx context.Context, now time.Time, limit int) ([]*model.Event, error)
+	MarkDelivered(ctx context.Context, id int64, deliveredAt time.Time) error
+	MarkFailed(ctx context.Context, id int64, attempts int, nextAttemptAt time.Time, lastError string) error
+	// DeleteDelivered deletes events delivered before before
+	DeleteDelivered(ctx context.Context, before time.Time) (int64, error)
+	GetEvents(ctx context.Context, filter model.EventFilter, page, perPage int) ([]*model.Event, int64, error)
+
+	// ClaimDelivery leases delivery to the caller until now+lease,
+	// reporting false while another instance holds the lease
+	ClaimDelivery(ctx context.Context, now time.Time, lease time.Duration) (bool, error)
+	ReleaseDelivery(ctx context.Context) error
+}
+
+type siemRepository struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+func NewSIEMRepository(db *gorm.DB, logger *zap.Logger) Repository {
+	return &siemRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *siemRepository) CreateEvent(ctx context.Context, event *model.Event) error {
+	if err := r.db.WithContext(ctx).Create(event).Error; err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to record security event", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r *siemRepository) GetDueEvents(ctx context.Context, now time.Time, limit int) ([]*model.Event, error) {
+	var events []*model.Event
+	err := r.db.WithContext(ctx).
+		Where("delivered_at IS NULL").
+		Where(`NOT EXISTS (
+			SELECT 1 FROM security_events waiting
+			WHERE waiting.delivered_at IS NULL
+				AND waiting.user_id IS NOT DISTINCT FROM security_events.user_id
+				AND waiting.id <= security_events.id
+				AND waiting.next_attempt_at > ?
+		)`, now).
+		Order("id").
+		Limit(limit).
+		Find(&events).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get due security events", zap.Error(err))
+		return nil, err
+	}
+	return events, nil
+}
+
+func (r *siemRepository) MarkDelivered(ctx context.Context, id int64, deliveredAt time.Time) error {
+	err := r.db.WithContext(ctx).Model(&model.Event{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"delivered_at": deliveredAt,
+			"last_error":   nil,
+		}).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to mark security event delivered", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r *siemRepository) MarkFailed(ctx context.Context, id int64, attempts int, nextAttemptAt time.Time, lastError string) error {
+	err := r.db.WithContext(ctx).Model(&model.Event{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"attempts":        attempts,
+			"next_attempt_at": nextAttemptAt,
+			"last_error":      lastError,
+		}).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to record security event delivery failure", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r *siemRepository) DeleteDelivered(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("delivered_at < ?", before).
+		Delete(&model.Event{})
+	if result.Error != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to delete delivered security events", zap.Error(result.Error))
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+func (r *siemRepository) GetEvents(ctx context.Context, filter model.EventFilter, page, perPage int) ([]*model.Event, int64, error) {
+	var events []*model.Event
+	var total int64
+
+	db := r.db.WithContext(ctx).Model(&model.Event{})
+	if filter.UserID != nil {
+		db = db.Where("user_id = ?", *filter.UserID)
+	}
+	if filter.Type != "" {
+		db = db.Where("type = ?", filter.Type)
+	}
+	if filter.Pending {
+		db = db.Where("delivered_at IS NULL")
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to count security events", zap.Error(err))
+		return nil, 0, err
+	}
+
+	if page < 1 {
+		page = 1
+	}
+
+	if perPage < 1 {
+		perPage = 20
+	}
+
+	offset := (page - 1) * perPage
+
+	if err := db.Order("id DESC").Limit(perPage).Offset(offset).Find(&events).Error; err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get security events", zap.Error(err))
+		return nil, 0, err
+	}
+
+	return events, total, nil
+}
+
+func (r *siemRepository) ClaimDelivery(ctx context.Context, now time.Time, lease time.Duration) (bool, error) {
+	result := r.db.WithContext(ctx).Exec(`
+		INSERT INTO security_event_leases (name, locked_until, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET locked_until = EXCLUDED.locked_until, updated_at = EXCLUDED.updated_at
+		WHERE security_event_leases.locked_until IS NULL OR security_event_leases.locked_until < ?`,
+		model.LeaseDeliver, now.Add(lease), now, now)
+	if result.Error != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to claim security event delivery", zap.Error(result.Error))
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *siemRepository) ReleaseDelivery(ctx context.Context) error {
+	err := r.db.WithContext(ctx).Model(&model.Lease{}).
+		Where("name = ?", model.LeaseDeliver).
+		Update("locked_until", nil).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to release security event delivery", zap.Error(err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/hafiztri123/document-api/config"
+	"github.com/hafiztri123/document-api/internal/logging"
+	"github.com/hafiztri123/document-api/internal/siem/model"
+	"github.com/hafiztri123/document-api/internal/siem/repository"
+)
+
+// deliverLease is how long delivery stays claimed by an instance; a run
+// stops short of it
+const deliverLease = 5 * time.Minute
+
+// Retries back off exponentially from minBackoff up to siem.max_backoff
+const minBackoff = 5 * time.Second
+
+// maxConsecutiveFailures ends a run early, as the SIEM is most likely down
+const maxConsecutiveFailures = 3
+
+type Service interface {
+	// Record adds an event to the stream, stamped with the source of the
+	// request in ctx, see WithSource. It does nothing while siem.transport
+	// is unset. Failures are logged rather than returned, so they never
+	// fail the action being recorded.
+	Record(ctx context.Context, message model.Message)
+
+	// Deliver sends due events to the SIEM and returns how many. A user's
+	// events go out in order: after a failure, that user's later events
+	// wait for the retry.
+	Deliver(ctx context.Context) (int, error)
+	// Close drops the connection to the SIEM, if the transport keeps one
+	Close() error
+
+	GetEvents(ctx context.Context, filter model.EventFilter, page, perPage int) ([]*model.Event, int64, error)
+}
+
+type siemService struct {
+	repo       repository.Repository
+	sink       sink
+	batchSize  int
+	maxBackoff time.Duration
+	retention  time.Duration
+	logger     *zap.Logger
+}
+
+func NewSIEMService(repo repository.Repository, logger *zap.Logger) Service {
+	var sinkConfig model.Sink
+	if err := viper.UnmarshalKey(config.SIEM, &sinkConfig); err != nil {
+		logger.Error("Invalid siem config, not recording security events", zap.Error(err))
+		sinkConfig = model.Sink{}
+	}
+
+	sink, err := newSink(sinkConfig)
+	if err != nil {
+		logger.Error("Invalid siem config, not recording security events", zap.Error(err))
+		sink = nil
+	}
+
+	batchSize := viper.GetInt(config.SIEM_BATCH_SIZE)
+	if batchSize < 1 {
+		logger.Warn("Invalid siem.batch_size, using default 100")
+		batchSize = 100
+	}
+
+	maxBackoff, err := time.ParseDuration(viper.GetString(config.SIEM_MAX_BACKOFF))
+	if err != nil || maxBackoff < minBackoff {
+		logger.Warn("Invalid siem.max_backoff, using default 1h", zap.Error(err))
+		maxBackoff = time.Hour
+	}
+
+	retention, err := time.ParseDuration(viper.GetString(config.SIEM_RETENTION))
+	if err != nil || retention < 0 {
+		logger.Warn("Invalid siem.retention, using default 720h", zap.Error(err))
+		retention = 720 * time.Hour
+	}
+
+	return &siemService{
+		repo:       repo,
+		sink:       sink,
+		batchSize:  batchSize,
+		maxBackoff: maxBackoff,
+		retention:  retention,
+		logger:     logger,
+	}
+}
+
+func (s *siemService) Record(ctx context.Context, message model.Message) {
+	if s.sink == nil {
+		return
+	}
+
+	source := sourceFrom(ctx)
+	message.ID = 0
+	message.IPAddress = truncate(source.IPAddress, 45)
+	message.UserAgent = truncate(source.UserAgent, 255)
+	message.RequestID = truncate(source.RequestID, 128)
+	message.OccurredAt = time.Now()
+
+	event := &model.Event{
+		Message:       message,
+		NextAttemptAt: message.OccurredAt,
+	}
+
+	// The request may be cancelled once its response is written
+	if err := s.repo.CreateEvent(context.WithoutCancel(ctx), event); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Security event lost",
+			zap.String("type", string(message.Type)), zap.Error(err))
+	}
+}
+
+func (s *siemService) Deliver(ctx context.Context) (int, error) {
+	if s.sink == nil {
+		return 0, nil
+	}
+
+	claimed, err := s.repo.ClaimDelivery(ctx, time.Now(), deliverLease)
+	if err != nil || !claimed {
+		return 0, err
+	}
+	defer func() {
+		_ = s.repo.ReleaseDelivery(context.WithoutCancel(ctx))
+	}()
+
+	if s.retention > 0 {
+		_, _ = s.repo.DeleteDelivered(ctx, time.Now().Add(-s.retention))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, deliverLease/2)
+	defer cancel()
+
+	delivered := 0
+	for {
+		events, err := s.repo.GetDueEvents(ctx, time.Now(), s.batchSize)
+		if err != nil {
+			return delivered, err
+		}
+		if len(events) == 0 {
+			return delivered, nil
+		}
+
+		// Users whose event failed are left out of the next batch
+		sent, err := s.deliverBatch(ctx, events)
+		delivered += sent
+		if err != nil || len(events) < s.batchSize {
+			return delivered, err
+		}
+	}
+}
+
+// deliverBatch sends events in ID order. Once one of a user's events
+// fails, the rest of that user's events in the batch are held back.
+func (s *siemService) deliverBatch(ctx context.Context, events []*model.Event) (int, error) {
+	failed := make(map[uuid.UUID]bool)
+	sent, consecutiveFailures := 0, 0
+
+	for _, event := range events {
+		if err := ctx.Err(); err != nil {
+			return sent, err
+		}
+
+		user := uuid.Nil
+		if event.UserID != nil {
+			user = *event.UserID
+		}
+		if failed[user] {
+			continue
+		}
+
+		sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+		err := s.sink.Send(sendCtx, &event.Message)
+		cancel()
+
+		now := time.Now()
+		if err != nil {
+			failed[user] = true
+			attempts := event.Attempts + 1
+			logging.FromContext(ctx, s.logger).Warn("Security event delivery failed",
+				zap.Int64("event_id", event.ID), zap.Int("attempts", attempts), zap.Error(err))
+			if err := s.repo.MarkFailed(ctx, event.ID, attempts, now.Add(s.backoff(attempts)), err.Error()); err != nil {
+				return sent, err
+			}
+
+			if consecutiveFailures++; consecutiveFailures >= maxConsecutiveFailures {
+				return sent, err
+			}
+			continue
+		}
+
+		if err := s.repo.MarkDelivered(ctx, event.ID, now); err != nil {
+			return sent, err
+		}
+		sent++
+		consecutiveFailures = 0
+	}
+
+	return sent, nil
+}
+
+// backoff is how long to wait after the attempts-th failed attempt
+func (s *siemService) backoff(attempts int) time.Duration {
+	backoff := minBackoff
+	for i := 1; i < attempts && backoff < s.maxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, s.maxBackoff)
+}
+
+func (s *siemService) Close() error {
+	if s.sink == nil {
+		return nil
+	}
+	return s.sink.Close()
+}
+
+func (s *siemService) GetEvents(ctx context.Context, filter model.EventFilter, page, perPage int) ([]*model.Event, int64, error) {
+	return s.repo.GetEvents(ctx, filter, page, perPage)
+}
+
+func truncate(value string, length int) string {
+	if len(value) <= length {
+		return value
+	}
+	// Drop a character cut in half
+	return strings.ToValidUTF8(value[:length], "")
+}
@@ -0,0 +1,270 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hafiztri123/document-api/internal/siem/model"
+	"github.com/hafiztri123/document-api/internal/signature"
+)
+
+// sendTimeout bounds the delivery of a single event
+const sendTimeout = 10 * time.Second
+
+// sink delivers one event at a time; an error leaves it to be retried
+type sink interface {
+	Send(ctx context.Context, message *model.Message) error
+	Close() error
+}
+
+// newSink builds the sink configured under siem, or nil when Transport is
+// empty
+func newSink(config model.Sink) (sink, error) {
+	switch config.Transport {
+	case "":
+		return nil, nil
+	case model.TransportHTTPS:
+		if !strings.HasPrefix(config.URL, "https://") {
+			return nil, errors.New("siem.url must be an https URL")
+		}
+		return &httpsSink{
+			url:     config.URL,
+			secret:  config.Secret,
+			headers: config.Headers,
+			client:  &http.Client{Timeout: sendTimeout},
+		}, nil
+	case model.TransportSyslog:
+		if config.Address == "" {
+			return nil, errors.New("siem.address is required for syslog")
+		}
+		format := config.Format
+		if format == "" {
+			format = model.FormatCEF
+		}
+		if format != model.FormatCEF && format != model.FormatJSON {
+			return nil, fmt.Errorf("unknown siem.format %q", format)
+		}
+		hostname, _ := os.Hostname()
+		return &syslogSink{
+			address:  config.Address,
+			insecure: config.Insecure,
+			format:   format,
+			hostname: hostname,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown siem.transport %q", config.Transport)
+	}
+}
+
+// httpsSink posts each event as JSON
+type httpsSink struct {
+	url     string
+	secret  string
+	headers map[string]string
+	client  *http.Client
+}
+
+func (s *httpsSink) Send(ctx context.Context, message *model.Message) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "document-api-siem/1.0")
+	req.Header.Set("X-Event-ID", strconv.FormatInt(message.ID, 10))
+	if s.secret != "" {
+		req.Header.Set("X-Webhook-Signature", signature.Header(body, time.Now(), s.secret))
+	}
+	for name, value := range s.headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("collector responded with status %d: %s", resp.StatusCode, snippet)
+	}
+	return nil
+}
+
+func (s *httpsSink) Close() error {
+	return nil
+}
+
+// syslogSink writes RFC 5424 messages over one TCP connection, framed by
+// octet counting as in RFC 6587. The connection is dialled on first use
+// and again after a failed write.
+type syslogSink struct {
+	address  string
+	insecure bool
+	format   string
+	hostname string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func (s *syslogSink) Send(ctx context.Context, message *model.Message) error {
+	line, err := syslogMessage(message, s.format, s.hostname)
+	if err != nil {
+		return err
+	}
+	frame := strconv.Itoa(len(line)) + " " + line
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		if s.conn, err = s.dial(ctx); err != nil {
+			return err
+		}
+	}
+
+	deadline := time.Now().Add(sendTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = s.conn.SetWriteDeadline(deadline)
+	if _, err := io.WriteString(s.conn, frame); err != nil {
+		s.conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
+
+func (s *syslogSink) dial(ctx context.Context) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: sendTimeout}
+	if s.insecure {
+		return dialer.DialContext(ctx, "tcp", s.address)
+	}
+	tlsDialer := &tls.Dialer{NetDialer: dialer}
+	return tlsDialer.DialContext(ctx, "tcp", s.address)
+}
+
+func (s *syslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// syslogFacility is "security/authorization messages" (10)
+const syslogFacility = 10
+
+// syslogTimestamp has the microsecond precision RFC 5424 allows at most
+const syslogTimestamp = "2006-01-02T15:04:05.000000Z07:00"
+
+// syslogMessage formats an RFC 5424 message whose body is the event in
+// format. The syslog severity follows the CEF one, and the message ID is
+// the type's group, as types may be longer than the 32 characters allowed.
+func syslogMessage(message *model.Message, format, hostname string) (string, error) {
+	var body string
+	switch format {
+	case model.FormatJSON:
+		data, err := json.Marshal(message)
+		if err != nil {
+			return "", err
+		}
+		body = string(data)
+	default:
+		body = cef(message)
+	}
+
+	severity := 6 // informational
+	switch {
+	case message.Type.Severity() >= 6:
+		severity = 4 // warning
+	case message.Type.Severity() >= 4:
+		severity = 5 // notice
+	}
+
+	if hostname == "" {
+		hostname = "-"
+	}
+	return fmt.Sprintf("<%d>1 %s %s document-api - %s - %s",
+		syslogFacility*8+severity,
+		message.OccurredAt.UTC().Format(syslogTimestamp),
+		hostname,
+		strings.SplitN(string(message.Type), ".", 2)[0],
+		body,
+	), nil
+}
+
+// cef formats the event as an ArcSight Common Event Format line
+func cef(message *model.Message) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "CEF:0|hafiztri123|document-api|1.0|%s|%s|%d|",
+		cefHeader(string(message.Type)), cefHeader(string(message.Type)), message.Type.Severity())
+
+	extension := []string{
+		"externalId=" + strconv.FormatInt(message.ID, 10),
+		"rt=" + strconv.FormatInt(message.OccurredAt.UnixMilli(), 10),
+		"outcome=" + cefValue(string(message.Outcome)),
+	}
+	if message.UserID != nil {
+		extension = append(extension, "suid="+message.UserID.String())
+	}
+	if message.IPAddress != "" {
+		extension = append(extension, "src="+cefValue(message.IPAddress))
+	}
+	if message.UserAgent != "" {
+		extension = append(extension, "requestClientApplication="+cefValue(message.UserAgent))
+	}
+	if message.TargetID != nil {
+		extension = append(extension,
+			"cs1Label=targetType", "cs1="+cefValue(message.TargetType),
+			"cs2Label=targetId", "cs2="+message.TargetID.String())
+	}
+	if message.RequestID != "" {
+		extension = append(extension, "cs3Label=requestId", "cs3="+cefValue(message.RequestID))
+	}
+	if len(message.Data) > 0 {
+		if data, err := json.Marshal(message.Data); err == nil {
+			extension = append(extension, "cs4Label=data", "cs4="+cefValue(string(data)))
+		}
+	}
+
+	b.WriteString(strings.Join(extension, " "))
+	return b.String()
+}
+
+var (
+	cefHeaderEscaper = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\r", " ", "\n", " ")
+	cefValueEscaper  = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r", `\r`, "\n", `\n`)
+)
+
+func cefHeader(value string) string {
+	return cefHeaderEscaper.Replace(value)
+}
+
+func cefValue(value string) string {
+	return cefValueEscaper.Replace(value)
+}
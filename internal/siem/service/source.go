@@ -0,0 +1,22 @@
+package service
+
+import "context"
+
+// Source is the client a request came from, recorded on its events
+type Source struct {
+	IPAddress string
+	UserAgent string
+	RequestID string
+}
+
+type sourceKey struct{}
+
+// WithSource returns a copy of ctx carrying source
+func WithSource(ctx context.Context, source Source) context.Context {
+	return context.WithValue(ctx, sourceKey{}, source)
+}
+
+func sourceFrom(ctx context.Context) Source {
+	source, _ := ctx.Value(sourceKey{}).(Source)
+	return source
+}
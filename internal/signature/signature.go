@@ -0,0 +1,94 @@
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTolerance is how old a signed timestamp may be before the payload
+// is treated as a replay
+const DefaultTolerance = 5 * time.Minute
+
+const schemeV1 = "v1"
+
+var (
+	ErrInvalidHeader      = errors.New("invalid signature header")
+	ErrNoValidSignature   = errors.New("no valid signature found")
+	ErrTimestampTolerance = errors.New("signature timestamp outside tolerance window")
+)
+
+// Header signs the payload with every given secret and returns a header of
+// the form "t=<unix>,v1=<hex>[,v1=<hex>]". Signing with more than one secret
+// lets receivers keep verifying while a secret is being rotated.
+func Header(payload []byte, timestamp time.Time, secrets ...string) string {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+
+	parts := make([]string, 0, len(secrets)+1)
+	parts = append(parts, "t="+ts)
+	for _, secret := range secrets {
+		parts = append(parts, schemeV1+"="+compute(secret, ts, payload))
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// Verify checks that the header carries a signature of the payload made by
+// one of the secrets, and that its timestamp is within tolerance of now
+func Verify(payload []byte, header string, tolerance time.Duration, now time.Time, secrets ...string) error {
+	var ts string
+	var signatures []string
+
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return ErrInvalidHeader
+		}
+
+		switch key {
+		case "t":
+			ts = value
+		case schemeV1:
+			signatures = append(signatures, value)
+		}
+	}
+
+	if ts == "" || len(signatures) == 0 {
+		return ErrInvalidHeader
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return ErrInvalidHeader
+	}
+
+	age := now.Sub(time.Unix(unix, 0))
+	if age > tolerance || age < -tolerance {
+		return ErrTimestampTolerance
+	}
+
+	for _, secret := range secrets {
+		expected := compute(secret, ts, payload)
+		for _, signature := range signatures {
+			if hmac.Equal([]byte(expected), []byte(signature)) {
+				return nil
+			}
+		}
+	}
+
+	return ErrNoValidSignature
+}
+
+// compute signs "<timestamp>.<payload>" so the timestamp cannot be altered
+// without invalidating the signature
+func compute(secret, ts string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
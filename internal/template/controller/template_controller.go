@@ -0,0 +1,291 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/hafiztri123/document-api/internal/document/hook"
+	docService "github.com/hafiztri123/document-api/internal/document/service"
+	"github.com/hafiztri123/document-api/internal/errcode"
+	"github.com/hafiztri123/document-api/internal/logging"
+	"github.com/hafiztri123/document-api/internal/template/model"
+	"github.com/hafiztri123/document-api/internal/template/service"
+)
+
+type Controller interface {
+	CreateTemplate(c *gin.Context)
+	GetTemplates(c *gin.Context)
+	GetGallery(c *gin.Context)
+	GetTemplate(c *gin.Context)
+	UpdateTemplate(c *gin.Context)
+	DeleteTemplate(c *gin.Context)
+
+	SaveDocumentAsTemplate(c *gin.Context)
+	CreateDocumentFromTemplate(c *gin.Context)
+}
+
+type templateController struct {
+	service service.Service
+	logger  *zap.Logger
+}
+
+func NewTemplateController(service service.Service, logger *zap.Logger) Controller {
+	return &templateController{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func (ctrl *templateController) CreateTemplate(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req model.TemplateCreateRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	template, err := ctrl.service.CreateTemplate(c.Request.Context(), userID, req)
+	if err != nil {
+		ctrl.handleError(c, err, "Failed to create template")
+		return
+	}
+
+	c.JSON(http.StatusCreated, template)
+}
+
+func (ctrl *templateController) GetTemplates(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	templates, err := ctrl.service.GetTemplates(c.Request.Context(), userID)
+	if err != nil {
+		ctrl.handleError(c, err, "Failed to retrieve templates")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": templates})
+}
+
+func (ctrl *templateController) GetGallery(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+
+	templates, total, err := ctrl.service.GetGallery(c.Request.Context(), page, perPage)
+	if err != nil {
+		ctrl.handleError(c, err, "Failed to retrieve template gallery")
+		return
+	}
+
+	totalPages := (int(total) + perPage - 1) / perPage
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": templates,
+		"pagination": gin.H{
+			"total":       total,
+			"page":        page,
+			"per_page":    perPage,
+			"total_pages": totalPages,
+		},
+	})
+}
+
+func (ctrl *templateController) GetTemplate(c *gin.Context) {
+	templateID, userID, ok := ctrl.parseRequest(c, "id")
+	if !ok {
+		return
+	}
+
+	template, err := ctrl.service.GetTemplate(c.Request.Context(), templateID, userID)
+	if err != nil {
+		ctrl.handleError(c, err, "Failed to retrieve template")
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+func (ctrl *templateController) UpdateTemplate(c *gin.Context) {
+	templateID, userID, ok := ctrl.parseRequest(c, "id")
+	if !ok {
+		return
+	}
+
+	var req model.TemplateUpdateRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	template, err := ctrl.service.UpdateTemplate(c.Request.Context(), templateID, userID, req)
+	if err != nil {
+		ctrl.handleError(c, err, "Failed to update template")
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+func (ctrl *templateController) DeleteTemplate(c *gin.Context) {
+	templateID, userID, ok := ctrl.parseRequest(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := ctrl.service.DeleteTemplate(c.Request.Context(), templateID, userID); err != nil {
+		ctrl.handleError(c, err, "Failed to delete template")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (ctrl *templateController) SaveDocumentAsTemplate(c *gin.Context) {
+	documentID, userID, ok := ctrl.parseRequest(c, "id")
+	if !ok {
+		return
+	}
+
+	var req model.TemplateFromDocumentRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	template, err := ctrl.service.CreateFromDocument(c.Request.Context(), documentID, userID, req)
+	if err != nil {
+		ctrl.handleError(c, err, "Failed to save document as template")
+		return
+	}
+
+	c.JSON(http.StatusCreated, template)
+}
+
+func (ctrl *templateController) CreateDocumentFromTemplate(c *gin.Context) {
+	templateID, userID, ok := ctrl.parseRequest(c, "template_id")
+	if !ok {
+		return
+	}
+
+	// The body is optional; an empty request uses the template's title
+	var req model.DocumentFromTemplateRequest
+	if c.Request.ContentLength > 0 && !bindJSON(c, &req) {
+		return
+	}
+
+	document, err := ctrl.service.CreateDocument(c.Request.Context(), templateID, userID, req)
+	if err != nil {
+		ctrl.handleError(c, err, "Failed to create document from template")
+		return
+	}
+
+	c.JSON(http.StatusCreated, document)
+}
+
+// parseRequest extracts a UUID path parameter and the authenticated user,
+// writing an error response when either is missing
+func (ctrl *templateController) parseRequest(c *gin.Context, param string) (uuid.UUID, uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param(param))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid ID",
+		}})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	userID, ok := currentUser(c)
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return id, userID, true
+}
+
+func currentUser(c *gin.Context) (uuid.UUID, bool) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return uuid.Nil, false
+	}
+	return userID.(uuid.UUID), true
+}
+
+func bindJSON(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid request data",
+			"details": err.Error(),
+		}})
+		return false
+	}
+	return true
+}
+
+func (ctrl *templateController) handleError(c *gin.Context, err error, message string) {
+	var lintErr *docService.LintError
+	if errors.As(err, &lintErr) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": gin.H{
+			"code":       errcode.LintFailed,
+			"message":    "Template content violates lint rules",
+			"violations": lintErr.Report.Violations,
+		}})
+		return
+	}
+
+	var rejection *hook.Rejection
+	if errors.As(err, &rejection) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": gin.H{
+			"code":       errcode.ContentRejected,
+			"message":    "Content rejected by " + rejection.Hook,
+			"hook":       rejection.Hook,
+			"violations": rejection.Violations,
+		}})
+		return
+	}
+
+	switch err {
+	case docService.ErrContentTooLarge:
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": gin.H{
+			"code":    errcode.ContentTooLarge,
+			"message": "Document content exceeds the maximum size",
+		}})
+	case docService.ErrEncryptedDocument:
+		c.JSON(http.StatusConflict, gin.H{"error": gin.H{
+			"code":    errcode.Encrypted,
+			"message": "Encrypted documents cannot be made into templates",
+		}})
+	case service.ErrTemplateNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+			"code":    errcode.TemplateNotFound,
+			"message": "Template not found",
+		}})
+	case service.ErrDocumentNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+			"code":    errcode.DocNotFound,
+			"message": "Document not found",
+		}})
+	case service.ErrUnauthorized:
+		c.JSON(http.StatusForbidden, gin.H{"error": gin.H{
+			"code":    errcode.Forbidden,
+			"message": "You don't have permission to access this template",
+		}})
+	default:
+		logging.FromContext(c.Request.Context(), ctrl.logger).Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
+			"code":    errcode.InternalError,
+			"message": message,
+		}})
+	}
+}
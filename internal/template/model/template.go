@@ -0,0 +1,60 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Template is a reusable starting point for new documents. Public
+// templates appear in the gallery and can be used by anyone.
+type Template struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	OwnerID     uuid.UUID `gorm:"type:uuid;not null" json:"owner_id"`
+	Name        string    `gorm:"type:varchar(255);not null" json:"name"`
+	Description string    `gorm:"type:text" json:"description"`
+	Title       string    `gorm:"type:varchar(255);not null" json:"title"`
+	Content     string    `gorm:"type:text" json:"content"`
+	IsPublic    bool      `gorm:"not null;default:false" json:"is_public"`
+	CreatedAt   time.Time `gorm:"not null" json:"created_at"`
+	UpdatedAt   time.Time `gorm:"not null" json:"updated_at"`
+}
+
+func (t *Template) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
+
+type TemplateCreateRequest struct {
+	Name        string `json:"name" binding:"required,max=255"`
+	Description string `json:"description"`
+	Title       string `json:"title" binding:"required,max=255"`
+	Content     string `json:"content"`
+	IsPublic    bool   `json:"is_public"`
+}
+
+// TemplateFromDocumentRequest saves a document as a template; the
+// document's title and content are copied
+type TemplateFromDocumentRequest struct {
+	Name        string `json:"name" binding:"required,max=255"`
+	Description string `json:"description"`
+	IsPublic    bool   `json:"is_public"`
+}
+
+type TemplateUpdateRequest struct {
+	Name        *string `json:"name" binding:"omitempty,max=255"`
+	Description *string `json:"description"`
+	Title       *string `json:"title" binding:"omitempty,max=255"`
+	Content     *string `json:"content"`
+	IsPublic    *bool   `json:"is_public"`
+}
+
+// DocumentFromTemplateRequest creates a document from a template; Title
+// defaults to the template's title
+type DocumentFromTemplateRequest struct {
+	Title    string `json:"title" binding:"max=255"`
+	IsPublic bool   `json:"is_public"`
+}
@@ -0,0 +1,20 @@
+package template
+
+import (
+	"go.uber.org/fx"
+
+	"github.com/hafiztri123/document-api/internal/api"
+	"github.com/hafiztri123/document-api/internal/template/controller"
+	"github.com/hafiztri123/document-api/internal/template/repository"
+	"github.com/hafiztri123/document-api/internal/template/service"
+)
+
+// Module provides the template repository, service, controller and routes
+var Module = fx.Module("template",
+	fx.Provide(
+		repository.NewTemplateRepository,
+		service.NewTemplateService,
+		controller.NewTemplateController,
+		api.AsRouteRegistrar(newRoutes),
+	),
+)
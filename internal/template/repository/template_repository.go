@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/hafiztri123/document-api/internal/logging"
+	"github.com/hafiztri123/document-api/internal/template/model"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	CreateTemplate(ctx context.Context, template *model.Template) error
+	GetTemplateByID(ctx context.Context, id uuid.UUID) (*model.Template, error)
+	GetTemplatesByOwnerID(ctx context.Context, ownerID uuid.UUID) ([]*model.Template, error)
+	GetPublicTemplates(ctx context.Context, page, perPage int) ([]*model.Template, int64, error)
+	UpdateTemplate(ctx context.Context, template *model.Template) error
+	DeleteTemplate(ctx context.Context, id uuid.UUID) error
+}
+
+type templateRepository struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+func NewTemplateRepository(db *gorm.DB, logger *zap.Logger) Repository {
+	return &templateRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *templateRepository) CreateTemplate(ctx context.Context, template *model.Template) error {
+	if err := r.db.WithContext(ctx).Create(template).Error; err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to create template", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r *templateRepository) GetTemplateByID(ctx context.Context, id uuid.UUID) (*model.Template, error) {
+	var template model.Template
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&template).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get template by ID", zap.Error(err))
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *templateRepository) GetTemplatesByOwnerID(ctx context.Context, ownerID uuid.UUID) ([]*model.Template, error) {
+	var templates []*model.Template
+	err := r.db.WithContext(ctx).Where("owner_id = ?", ownerID).Order("name ASC").Find(&templates).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get templates by owner ID", zap.Error(err))
+		return nil, err
+	}
+	return templates, nil
+}
+
+func (r *templateRepository) GetPublicTemplates(ctx context.Context, page, perPage int) ([]*model.Template, int64, error) {
+	var templates []*model.Template
+	var total int64
+
+	db := r.db.WithContext(ctx).Model(&model.Template{}).Where("is_public = ?", true)
+
+	if err := db.Count(&total).Error; err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to count public templates", zap.Error(err))
+		return nil, 0, err
+	}
+
+	if page < 1 {
+		page = 1
+	}
+
+	if perPage < 1 {
+		perPage = 20
+	}
+
+	offset := (page - 1) * perPage
+
+	if err := db.Order("name ASC").Limit(perPage).Offset(offset).Find(&templates).Error; err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get public templates", zap.Error(err))
+		return nil, 0, err
+	}
+
+	return templates, total, nil
+}
+
+func (r *templateRepository) UpdateTemplate(ctx context.Context, template *model.Template) error {
+	if err := r.db.WithContext(ctx).Save(template).Error; err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to update template", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r *templateRepository) DeleteTemplate(ctx context.Context, id uuid.UUID) error {
+	if err := r.db.WithContext(ctx).Delete(&model.Template{}, id).Error; err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to delete template", zap.Error(err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package template
+
+import (
+	"github.com/hafiztri123/document-api/internal/api"
+	"github.com/hafiztri123/document-api/internal/template/controller"
+)
+
+type routes struct {
+	ctrl controller.Controller
+}
+
+func newRoutes(ctrl controller.Controller) *routes {
+	return &routes{ctrl: ctrl}
+}
+
+func (r *routes) RegisterRoutes(groups api.Groups) {
+	templates := groups.Protected.Group("/templates")
+	{
+		templates.POST("", r.ctrl.CreateTemplate)
+		templates.GET("", r.ctrl.GetTemplates)
+		templates.GET("/gallery", r.ctrl.GetGallery)
+		templates.GET("/:id", r.ctrl.GetTemplate)
+		templates.PUT("/:id", r.ctrl.UpdateTemplate)
+		templates.DELETE("/:id", r.ctrl.DeleteTemplate)
+	}
+
+	// Saving and instantiating templates
+	groups.Protected.POST("/documents/:id/template", r.ctrl.SaveDocumentAsTemplate)
+	groups.Protected.POST("/documents/from-template/:template_id", r.ctrl.CreateDocumentFromTemplate)
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	docModel "github.com/hafiztri123/document-api/internal/document/model"
+	docService "github.com/hafiztri123/document-api/internal/document/service"
+	"github.com/hafiztri123/document-api/internal/template/model"
+	"github.com/hafiztri123/document-api/internal/template/repository"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrTemplateNotFound = errors.New("template not found")
+	ErrDocumentNotFound = errors.New("document not found")
+	ErrUnauthorized     = errors.New("unauthorized access to template")
+)
+
+type Service interface {
+	CreateTemplate(ctx context.Context, ownerID uuid.UUID, req model.TemplateCreateRequest) (*model.Template, error)
+	// CreateFromDocument saves a document the user can read as a template
+	CreateFromDocument(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, req model.TemplateFromDocumentRequest) (*model.Template, error)
+	GetTemplate(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*model.Template, error)
+	GetTemplates(ctx context.Context, ownerID uuid.UUID) ([]*model.Template, error)
+	GetGallery(ctx context.Context, page, perPage int) ([]*model.Template, int64, error)
+	UpdateTemplate(ctx context.Context, id uuid.UUID, ownerID uuid.UUID, req model.TemplateUpdateRequest) (*model.Template, error)
+	DeleteTemplate(ctx context.Context, id uuid.UUID, ownerID uuid.UUID) error
+
+	// CreateDocument starts a new document owned by userID from a template
+	CreateDocument(ctx context.Context, templateID uuid.UUID, userID uuid.UUID, req model.DocumentFromTemplateRequest) (*docModel.Document, error)
+}
+
+type templateService struct {
+	repo       repository.Repository
+	docService docService.Service
+	logger     *zap.Logger
+}
+
+func NewTemplateService(repo repository.Repository, docService docService.Service, logger *zap.Logger) Service {
+	return &templateService{
+		repo:       repo,
+		docService: docService,
+		logger:     logger,
+	}
+}
+
+func (s *templateService) CreateTemplate(ctx context.Context, ownerID uuid.UUID, req model.TemplateCreateRequest) (*model.Template, error) {
+	template := &model.Template{
+		OwnerID:     ownerID,
+		Name:        req.Name,
+		Description: req.Description,
+		Title:       req.Title,
+		Content:     req.Content,
+		IsPublic:    req.IsPublic,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	if err := s.repo.CreateTemplate(ctx, template); err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+func (s *templateService) CreateFromDocument(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, req model.TemplateFromDocumentRequest) (*model.Template, error) {
+	document, err := s.docService.GetDocumentByID(ctx, documentID, userID, false, "", "")
+	if err != nil {
+		switch err {
+		case docService.ErrDocumentNotFound:
+			return nil, ErrDocumentNotFound
+		case docService.ErrUnauthorized, docService.ErrTermsNotAccepted:
+			return nil, ErrUnauthorized
+		}
+		return nil, err
+	}
+
+	// A template of ciphertext could not be decrypted by anyone using it
+	if document.Encrypted {
+		return nil, docService.ErrEncryptedDocument
+	}
+
+	return s.CreateTemplate(ctx, userID, model.TemplateCreateRequest{
+		Name:        req.Name,
+		Description: req.Description,
+		Title:       document.Title,
+		Content:     document.Content,
+		IsPublic:    req.IsPublic,
+	})
+}
+
+// GetTemplate returns a template owned by userID or published to the gallery
+func (s *templateService) GetTemplate(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*model.Template, error) {
+	template, err := s.repo.GetTemplateByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if template == nil {
+		return nil, ErrTemplateNotFound
+	}
+
+	if template.OwnerID != userID && !template.IsPublic {
+		return nil, ErrUnauthorized
+	}
+
+	return template, nil
+}
+
+func (s *templateService) GetTemplates(ctx context.Context, ownerID uuid.UUID) ([]*model.Template, error) {
+	return s.repo.GetTemplatesByOwnerID(ctx, ownerID)
+}
+
+func (s *templateService) GetGallery(ctx context.Context, page, perPage int) ([]*model.Template, int64, error) {
+	return s.repo.GetPublicTemplates(ctx, page, perPage)
+}
+
+func (s *templateService) UpdateTemplate(ctx context.Context, id uuid.UUID, ownerID uuid.UUID, req model.TemplateUpdateRequest) (*model.Template, error) {
+	template, err := s.getOwnedTemplate(ctx, id, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		template.Name = *req.Name
+	}
+	if req.Description != nil {
+		template.Description = *req.Description
+	}
+	if req.Title != nil {
+		template.Title = *req.Title
+	}
+	if req.Content != nil {
+		template.Content = *req.Content
+	}
+	if req.IsPublic != nil {
+		template.IsPublic = *req.IsPublic
+	}
+	template.UpdatedAt = time.Now()
+
+	if err := s.repo.UpdateTemplate(ctx, template); err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+func (s *templateService) DeleteTemplate(ctx context.Context, id uuid.UUID, ownerID uuid.UUID) error {
+	if _, err := s.getOwnedTemplate(ctx, id, ownerID); err != nil {
+		return err
+	}
+
+	return s.repo.DeleteTemplate(ctx, id)
+}
+
+func (s *templateService) CreateDocument(ctx context.Context, templateID uuid.UUID, userID uuid.UUID, req model.DocumentFromTemplateRequest) (*docModel.Document, error) {
+	template, err := s.GetTemplate(ctx, templateID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	title := req.Title
+	if title == "" {
+		title = template.Title
+	}
+
+	return s.docService.CreateDocument(ctx, userID, docModel.DocumentCreateRequest{
+		Title:    title,
+		Content:  template.Content,
+		IsPublic: req.IsPublic,
+	})
+}
+
+// getOwnedTemplate returns a template only its owner may modify; gallery
+// templates are read-only for everyone else
+func (s *templateService) getOwnedTemplate(ctx context.Context, id uuid.UUID, ownerID uuid.UUID) (*model.Template, error) {
+	template, err := s.GetTemplate(ctx, id, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if template.OwnerID != ownerID {
+		return nil, ErrUnauthorized
+	}
+
+	return template, nil
+}
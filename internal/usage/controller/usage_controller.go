@@ -0,0 +1,55 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/hafiztri123/document-api/internal/errcode"
+	"github.com/hafiztri123/document-api/internal/logging"
+	"github.com/hafiztri123/document-api/internal/usage/service"
+)
+
+type Controller interface {
+	GetUsage(c *gin.Context)
+}
+
+type usageController struct {
+	service service.Service
+	logger  *zap.Logger
+}
+
+func NewUsageController(service service.Service, logger *zap.Logger) Controller {
+	return &usageController{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func (ctrl *usageController) GetUsage(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "30"))
+
+	usage, err := ctrl.service.GetUsage(c.Request.Context(), userID.(uuid.UUID), days)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), ctrl.logger).Error("Failed to get API usage", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
+			"code":    errcode.InternalError,
+			"message": "Failed to retrieve API usage",
+		}})
+		return
+	}
+
+	c.JSON(http.StatusOK, usage)
+}
@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+// EventRateLimitWarning notifies a user who reached the warning threshold
+// of their window, once per window, with the RateLimitStatus as data
+const EventRateLimitWarning = "rate_limit.warning"
+
+// RateLimitStatus describes the caller's position in the current window.
+// Warning is set once usage reaches rate_limit.warn_at of the limit.
+type RateLimitStatus struct {
+	Limit     int64     `json:"limit"`
+	Used      int64     `json:"used"`
+	Remaining int64     `json:"remaining"`
+	ResetAt   time.Time `json:"reset_at"`
+	Warning   bool      `json:"warning"`
+}
+
+// Exceeded reports whether the request that produced this status is over the limit
+func (s *RateLimitStatus) Exceeded() bool {
+	return s.Used > s.Limit
+}
+
+type DailyUsage struct {
+	Date      string  `json:"date"`
+	Requests  int64   `json:"requests"`
+	Errors    int64   `json:"errors"`
+	ErrorRate float64 `json:"error_rate"`
+}
+
+// UsageResponse represents a user's API consumption
+type UsageResponse struct {
+	LastUsedAt *time.Time      `json:"last_used_at"`
+	RateLimit  RateLimitStatus `json:"rate_limit"`
+	Totals     struct {
+		Requests  int64   `json:"requests"`
+		Errors    int64   `json:"errors"`
+		ErrorRate float64 `json:"error_rate"`
+	} `json:"totals"`
+	Daily []DailyUsage `json:"daily"`
+}
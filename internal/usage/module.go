@@ -0,0 +1,20 @@
+package usage
+
+import (
+	"go.uber.org/fx"
+
+	"github.com/hafiztri123/document-api/internal/api"
+	"github.com/hafiztri123/document-api/internal/usage/controller"
+	"github.com/hafiztri123/document-api/internal/usage/repository"
+	"github.com/hafiztri123/document-api/internal/usage/service"
+)
+
+// Module provides the usage repository, service, controller and routes
+var Module = fx.Module("usage",
+	fx.Provide(
+		repository.NewUsageRepository,
+		service.NewUsageService,
+		controller.NewUsageController,
+		api.AsRouteRegistrar(newRoutes),
+	),
+)
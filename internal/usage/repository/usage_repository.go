@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hafiztri123/document-api/internal/logging"
+	"github.com/hafiztri123/document-api/internal/usage/model"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// usageRetention is how long daily usage counters are kept
+const usageRetention = 31 * 24 * time.Hour
+
+type Repository interface {
+	// Rate limit windows
+	IncrementWindow(ctx context.Context, userID uuid.UUID, windowStart time.Time, window time.Duration) (int64, error)
+	GetWindowCount(ctx context.Context, userID uuid.UUID, windowStart time.Time) (int64, error)
+	// IncrementPublicWindow counts anonymous requests by client IP
+	IncrementPublicWindow(ctx context.Context, clientIP string, windowStart time.Time, window time.Duration) (int64, error)
+
+	// Usage counters
+	RecordRequest(ctx context.Context, userID uuid.UUID, at time.Time, isError bool) error
+	GetDailyUsage(ctx context.Context, userID uuid.UUID, days []time.Time) ([]model.DailyUsage, error)
+	GetLastUsed(ctx context.Context, userID uuid.UUID) (*time.Time, error)
+}
+
+type usageRepository struct {
+	redis  *redis.Client
+	logger *zap.Logger
+}
+
+func NewUsageRepository(redis *redis.Client, logger *zap.Logger) Repository {
+	return &usageRepository{
+		redis:  redis,
+		logger: logger,
+	}
+}
+
+func (r *usageRepository) IncrementWindow(ctx context.Context, userID uuid.UUID, windowStart time.Time, window time.Duration) (int64, error) {
+	return r.incrementWindow(ctx, windowKey(userID, windowStart), window)
+}
+
+func (r *usageRepository) IncrementPublicWindow(ctx context.Context, clientIP string, windowStart time.Time, window time.Duration) (int64, error) {
+	return r.incrementWindow(ctx, publicWindowKey(clientIP, windowStart), window)
+}
+
+func (r *usageRepository) incrementWindow(ctx context.Context, key string, window time.Duration) (int64, error) {
+	pipe := r.redis.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to increment rate limit window", zap.Error(err))
+		return 0, err
+	}
+
+	return incr.Val(), nil
+}
+
+func (r *usageRepository) GetWindowCount(ctx context.Context, userID uuid.UUID, windowStart time.Time) (int64, error) {
+	count, err := r.redis.Get(ctx, windowKey(userID, windowStart)).Int64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, nil
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get rate limit window", zap.Error(err))
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *usageRepository) RecordRequest(ctx context.Context, userID uuid.UUID, at time.Time, isError bool) error {
+	key := dailyKey(userID, at)
+
+	pipe := r.redis.TxPipeline()
+	pipe.HIncrBy(ctx, key, "requests", 1)
+	if isError {
+		pipe.HIncrBy(ctx, key, "errors", 1)
+	}
+	pipe.Expire(ctx, key, usageRetention)
+	pipe.Set(ctx, lastUsedKey(userID), at.Unix(), usageRetention)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to record API usage", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r *usageRepository) GetDailyUsage(ctx context.Context, userID uuid.UUID, days []time.Time) ([]model.DailyUsage, error) {
+	pipe := r.redis.Pipeline()
+	cmds := make([]*redis.MapStringStringCmd, len(days))
+	for i, day := range days {
+		cmds[i] = pipe.HGetAll(ctx, dailyKey(userID, day))
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		logging.FromContext(ctx, r.logger).Error("Failed to get daily API usage", zap.Error(err))
+		return nil, err
+	}
+
+	usage := make([]model.DailyUsage, 0, len(days))
+	for i, day := range days {
+		var requests, errs int64
+		values := cmds[i].Val()
+		fmt.Sscan(values["requests"], &requests)
+		fmt.Sscan(values["errors"], &errs)
+
+		usage = append(usage, model.DailyUsage{
+			Date:     day.Format("2006-01-02"),
+			Requests: requests,
+			Errors:   errs,
+		})
+	}
+
+	return usage, nil
+}
+
+func (r *usageRepository) GetLastUsed(ctx context.Context, userID uuid.UUID) (*time.Time, error) {
+	unix, err := r.redis.Get(ctx, lastUsedKey(userID)).Int64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get last API use", zap.Error(err))
+		return nil, err
+	}
+
+	lastUsed := time.Unix(unix, 0).UTC()
+	return &lastUsed, nil
+}
+
+func windowKey(userID uuid.UUID, windowStart time.Time) string {
+	return fmt.Sprintf("ratelimit:%s:%d", userID, windowStart.Unix())
+}
+
+func publicWindowKey(clientIP string, windowStart time.Time) string {
+	return fmt.Sprintf("ratelimit:public:%s:%d", clientIP, windowStart.Unix())
+}
+
+func dailyKey(userID uuid.UUID, day time.Time) string {
+	return fmt.Sprintf("usage:%s:%s", userID, day.UTC().Format("2006-01-02"))
+}
+
+func lastUsedKey(userID uuid.UUID) string {
+	return fmt.Sprintf("usage:%s:last_used", userID)
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hafiztri123/document-api/config"
+	"github.com/hafiztri123/document-api/internal/logging"
+	"github.com/hafiztri123/document-api/internal/usage/model"
+	"github.com/hafiztri123/document-api/internal/usage/repository"
+	wsService "github.com/hafiztri123/document-api/internal/ws/service"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+const maxUsageDays = 30
+
+type Service interface {
+	// CheckRateLimit counts a request against the caller's current window.
+	// The request that reaches the warning threshold notifies the user.
+	CheckRateLimit(ctx context.Context, userID uuid.UUID) (*model.RateLimitStatus, error)
+	// CheckPublicRateLimit counts an anonymous request against the client
+	// IP's window, which is separate from every user's
+	CheckPublicRateLimit(ctx context.Context, clientIP string) (*model.RateLimitStatus, error)
+	RecordRequest(ctx context.Context, userID uuid.UUID, status int) error
+	GetUsage(ctx context.Context, userID uuid.UUID, days int) (*model.UsageResponse, error)
+}
+
+// rateLimit allows requests per window, warning from the warnAt-th request
+// on; warnAt is 0 when warnings are disabled
+type rateLimit struct {
+	requests int64
+	window   time.Duration
+	warnAt   int64
+}
+
+// newRateLimit reads a limit from config, falling back to the defaults
+func newRateLimit(requestsKey, durationKey string, requests int64, window time.Duration, warnAt float64, logger *zap.Logger) rateLimit {
+	limit := rateLimit{requests: requests, window: window}
+
+	if configured, err := time.ParseDuration(viper.GetString(durationKey)); err != nil || configured <= 0 {
+		logger.Warn("Invalid "+durationKey+", using default "+window.String(), zap.Error(err))
+	} else {
+		limit.window = configured
+	}
+
+	if configured := viper.GetInt64(requestsKey); configured <= 0 {
+		logger.Warn(fmt.Sprintf("Invalid %s, using default %d", requestsKey, requests))
+	} else {
+		limit.requests = configured
+	}
+
+	if warnAt > 0 {
+		limit.warnAt = int64(math.Ceil(warnAt * float64(limit.requests)))
+	}
+
+	return limit
+}
+
+// warnAt reads the warning threshold as a share of each limit
+func warnAt(logger *zap.Logger) float64 {
+	share := viper.GetFloat64(config.RATE_LIMIT_WARN_AT)
+	if share < 0 || share >= 1 {
+		logger.Warn(fmt.Sprintf("Invalid %s, using default 0.8", config.RATE_LIMIT_WARN_AT))
+		return 0.8
+	}
+	return share
+}
+
+func (l rateLimit) status(windowStart time.Time, used int64) *model.RateLimitStatus {
+	remaining := l.requests - used
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return &model.RateLimitStatus{
+		Limit:     l.requests,
+		Used:      used,
+		Remaining: remaining,
+		ResetAt:   windowStart.Add(l.window),
+		Warning:   l.warnAt > 0 && used >= l.warnAt,
+	}
+}
+
+type usageService struct {
+	repo     repository.Repository
+	limit    rateLimit
+	public   rateLimit
+	realtime wsService.Service
+	logger   *zap.Logger
+}
+
+func NewUsageService(repo repository.Repository, realtime wsService.Service, logger *zap.Logger) Service {
+	share := warnAt(logger)
+
+	return &usageService{
+		repo:     repo,
+		limit:    newRateLimit(config.RATE_LIMIT_REQUESTS, config.RATE_LIMIT_DURATION, 100, time.Minute, share, logger),
+		public:   newRateLimit(config.RATE_LIMIT_PUBLIC_REQUESTS, config.RATE_LIMIT_PUBLIC_DURATION, 30, time.Minute, share, logger),
+		realtime: realtime,
+		logger:   logger,
+	}
+}
+
+func (s *usageService) CheckRateLimit(ctx context.Context, userID uuid.UUID) (*model.RateLimitStatus, error) {
+	windowStart := time.Now().Truncate(s.limit.window)
+
+	used, err := s.repo.IncrementWindow(ctx, userID, windowStart, s.limit.window)
+	if err != nil {
+		return nil, err
+	}
+
+	status := s.limit.status(windowStart, used)
+	// The counter is incremented atomically, so exactly one request per
+	// window lands on the threshold
+	if used == s.limit.warnAt {
+		ctx = logging.Detach(ctx)
+		go func() {
+			if err := s.realtime.NotifyUser(ctx, userID, model.EventRateLimitWarning, status); err != nil {
+				logging.FromContext(ctx, s.logger).Warn("Failed to notify user of rate limit warning", zap.Error(err))
+			}
+		}()
+	}
+
+	return status, nil
+}
+
+func (s *usageService) CheckPublicRateLimit(ctx context.Context, clientIP string) (*model.RateLimitStatus, error) {
+	windowStart := time.Now().Truncate(s.public.window)
+
+	used, err := s.repo.IncrementPublicWindow(ctx, clientIP, windowStart, s.public.window)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.public.status(windowStart, used), nil
+}
+
+func (s *usageService) RecordRequest(ctx context.Context, userID uuid.UUID, status int) error {
+	return s.repo.RecordRequest(ctx, userID, time.Now(), status >= 400)
+}
+
+func (s *usageService) GetUsage(ctx context.Context, userID uuid.UUID, days int) (*model.UsageResponse, error) {
+	if days < 1 || days > maxUsageDays {
+		days = maxUsageDays
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	dates := make([]time.Time, 0, days)
+	for i := days - 1; i >= 0; i-- {
+		dates = append(dates, today.AddDate(0, 0, -i))
+	}
+
+	daily, err := s.repo.GetDailyUsage(ctx, userID, dates)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get daily API usage", zap.Error(err))
+		return nil, err
+	}
+
+	lastUsed, err := s.repo.GetLastUsed(ctx, userID)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get last API use", zap.Error(err))
+		return nil, err
+	}
+
+	windowStart := time.Now().Truncate(s.limit.window)
+	used, err := s.repo.GetWindowCount(ctx, userID, windowStart)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get rate limit window", zap.Error(err))
+		return nil, err
+	}
+
+	response := &model.UsageResponse{
+		LastUsedAt: lastUsed,
+		RateLimit:  *s.limit.status(windowStart, used),
+		Daily:      daily,
+	}
+
+	for i := range response.Daily {
+		day := &response.Daily[i]
+		day.ErrorRate = errorRate(day.Errors, day.Requests)
+		response.Totals.Requests += day.Requests
+		response.Totals.Errors += day.Errors
+	}
+	response.Totals.ErrorRate = errorRate(response.Totals.Errors, response.Totals.Requests)
+
+	return response, nil
+}
+
+func errorRate(errors, requests int64) float64 {
+	if requests == 0 {
+		return 0
+	}
+	return float64(errors) / float64(requests)
+}
@@ -0,0 +1,247 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/hafiztri123/document-api/internal/errcode"
+	"github.com/hafiztri123/document-api/internal/logging"
+	"github.com/hafiztri123/document-api/internal/webhook/model"
+	"github.com/hafiztri123/document-api/internal/webhook/service"
+)
+
+type Controller interface {
+	CreateWebhook(c *gin.Context)
+	GetWebhooks(c *gin.Context)
+	DeleteWebhook(c *gin.Context)
+	RotateSecret(c *gin.Context)
+
+	SendTestEvent(c *gin.Context)
+	GetDeliveries(c *gin.Context)
+	ReplayDelivery(c *gin.Context)
+}
+
+type webhookController struct {
+	service service.Service
+	logger  *zap.Logger
+}
+
+func NewWebhookController(service service.Service, logger *zap.Logger) Controller {
+	return &webhookController{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func (ctrl *webhookController) CreateWebhook(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+
+	var req model.WebhookCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid request data",
+			"details": err.Error(),
+		}})
+		return
+	}
+
+	webhook, err := ctrl.service.CreateWebhook(c.Request.Context(), userID.(uuid.UUID), req)
+	if err != nil {
+		ctrl.handleError(c, err, "Failed to create webhook")
+		return
+	}
+
+	c.JSON(http.StatusCreated, webhook)
+}
+
+func (ctrl *webhookController) GetWebhooks(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return
+	}
+
+	webhooks, err := ctrl.service.GetWebhooks(c.Request.Context(), userID.(uuid.UUID))
+	if err != nil {
+		ctrl.handleError(c, err, "Failed to retrieve webhooks")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": webhooks})
+}
+
+func (ctrl *webhookController) DeleteWebhook(c *gin.Context) {
+	webhookID, userID, ok := ctrl.parseRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.service.DeleteWebhook(c.Request.Context(), webhookID, userID); err != nil {
+		ctrl.handleError(c, err, "Failed to delete webhook")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (ctrl *webhookController) RotateSecret(c *gin.Context) {
+	webhookID, userID, ok := ctrl.parseRequest(c)
+	if !ok {
+		return
+	}
+
+	var req model.RotateSecretRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+				"code":    errcode.ValidationError,
+				"message": "Invalid request data",
+				"details": err.Error(),
+			}})
+			return
+		}
+	}
+
+	webhook, err := ctrl.service.RotateSecret(c.Request.Context(), webhookID, userID, req)
+	if err != nil {
+		ctrl.handleError(c, err, "Failed to rotate webhook secret")
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+func (ctrl *webhookController) SendTestEvent(c *gin.Context) {
+	webhookID, userID, ok := ctrl.parseRequest(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := ctrl.service.SendTestEvent(c.Request.Context(), webhookID, userID)
+	if err != nil {
+		ctrl.handleError(c, err, "Failed to send test event")
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+func (ctrl *webhookController) GetDeliveries(c *gin.Context) {
+	webhookID, userID, ok := ctrl.parseRequest(c)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+
+	deliveries, total, err := ctrl.service.GetDeliveries(c.Request.Context(), webhookID, userID, page, perPage)
+	if err != nil {
+		ctrl.handleError(c, err, "Failed to retrieve webhook deliveries")
+		return
+	}
+
+	if perPage < 1 {
+		perPage = 20
+	}
+	totalPages := (int(total) + perPage - 1) / perPage
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": deliveries,
+		"pagination": gin.H{
+			"total":       total,
+			"page":        page,
+			"per_page":    perPage,
+			"total_pages": totalPages,
+		},
+	})
+}
+
+func (ctrl *webhookController) ReplayDelivery(c *gin.Context) {
+	webhookID, userID, ok := ctrl.parseRequest(c)
+	if !ok {
+		return
+	}
+
+	deliveryID, err := uuid.Parse(c.Param("delivery_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid delivery ID",
+		}})
+		return
+	}
+
+	delivery, err := ctrl.service.ReplayDelivery(c.Request.Context(), webhookID, deliveryID, userID)
+	if err != nil {
+		ctrl.handleError(c, err, "Failed to replay webhook delivery")
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+// parseRequest extracts the webhook ID path parameter and the authenticated
+// user, writing an error response when either is missing
+func (ctrl *webhookController) parseRequest(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	webhookID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid webhook ID",
+		}})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+			"code":    errcode.Unauthorized,
+			"message": "User not authenticated",
+		}})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return webhookID, userID.(uuid.UUID), true
+}
+
+func (ctrl *webhookController) handleError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrWebhookNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+			"code":    errcode.WebhookNotFound,
+			"message": "Webhook not found",
+		}})
+	case service.ErrDeliveryNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": gin.H{
+			"code":    errcode.DeliveryNotFound,
+			"message": "Webhook delivery not found",
+		}})
+	case service.ErrUnauthorized:
+		c.JSON(http.StatusForbidden, gin.H{"error": gin.H{
+			"code":    errcode.Forbidden,
+			"message": "You don't have permission to access this webhook",
+		}})
+	default:
+		logging.FromContext(c.Request.Context(), ctrl.logger).Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
+			"code":    errcode.InternalError,
+			"message": message,
+		}})
+	}
+}
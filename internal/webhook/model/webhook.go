@@ -0,0 +1,132 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Event string
+
+const (
+	EventDocumentCreated Event = "document.created"
+	EventDocumentUpdated Event = "document.updated"
+	EventDocumentDeleted Event = "document.deleted"
+	EventDocumentShared  Event = "document.shared"
+	EventDocumentStale   Event = "document.stale"
+	EventDocumentState   Event = "document.state_changed"
+	EventDocumentExpiring Event = "document.expiring"
+	EventDocumentRestored Event = "document.restored"
+	EventTest            Event = "webhook.test"
+)
+
+// Events lists the events a webhook can subscribe to
+var Events = []Event{
+	EventDocumentCreated,
+	EventDocumentUpdated,
+	EventDocumentDeleted,
+	EventDocumentShared,
+	EventDocumentStale,
+	EventDocumentState,
+	EventDocumentExpiring,
+	EventDocumentRestored,
+}
+
+// Webhook is a user-registered HTTP endpoint receiving document events
+type Webhook struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	OwnerID   uuid.UUID `gorm:"type:uuid;not null" json:"owner_id"`
+	URL       string    `gorm:"type:varchar(2048);not null" json:"url"`
+	Secret    string    `gorm:"type:varchar(255);not null" json:"-"`
+	Events    []Event   `gorm:"type:jsonb;serializer:json;not null" json:"events"`
+	Active    bool      `gorm:"not null;default:true" json:"active"`
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+	UpdatedAt time.Time `gorm:"not null" json:"updated_at"`
+
+	// The secret replaced by the last rotation stays valid until it expires
+	PreviousSecret          string     `gorm:"type:varchar(255)" json:"-"`
+	PreviousSecretExpiresAt *time.Time `json:"previous_secret_expires_at,omitempty"`
+}
+
+func (w *Webhook) BeforeCreate(tx *gorm.DB) error {
+	if w.ID == uuid.Nil {
+		w.ID = uuid.New()
+	}
+	return nil
+}
+
+// ActiveSecrets returns the secrets deliveries are signed with, newest first
+func (w *Webhook) ActiveSecrets(now time.Time) []string {
+	secrets := []string{w.Secret}
+	if w.PreviousSecret != "" && w.PreviousSecretExpiresAt != nil && now.Before(*w.PreviousSecretExpiresAt) {
+		secrets = append(secrets, w.PreviousSecret)
+	}
+	return secrets
+}
+
+// Subscribes reports whether the webhook should receive the event
+func (w *Webhook) Subscribes(event Event) bool {
+	if event == EventTest {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery records a single attempt to deliver an event, including a
+// snapshot of the payload so it can be inspected and replayed
+type WebhookDelivery struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	WebhookID      uuid.UUID  `gorm:"type:uuid;not null" json:"webhook_id"`
+	Event          Event      `gorm:"type:varchar(100);not null" json:"event"`
+	Payload        string     `gorm:"type:text;not null" json:"payload"`
+	ResponseStatus int        `gorm:"not null;default:0" json:"response_status"`
+	ResponseBody   string     `gorm:"type:text" json:"response_body"`
+	Error          string     `gorm:"type:text" json:"error,omitempty"`
+	DurationMs     int64      `gorm:"not null;default:0" json:"duration_ms"`
+	ReplayOfID     *uuid.UUID `gorm:"type:uuid" json:"replay_of_id,omitempty"`
+	CreatedAt      time.Time  `gorm:"not null" json:"created_at"`
+}
+
+func (d *WebhookDelivery) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return nil
+}
+
+// Succeeded reports whether the receiver acknowledged the delivery
+func (d *WebhookDelivery) Succeeded() bool {
+	return d.Error == "" && d.ResponseStatus >= 200 && d.ResponseStatus < 300
+}
+
+// Payload is the JSON envelope posted to webhook receivers
+type Payload struct {
+	ID        uuid.UUID   `json:"id"`
+	Event     Event       `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+type WebhookCreateRequest struct {
+	URL    string  `json:"url" binding:"required,url"`
+	Events []Event `json:"events" binding:"required,min=1,dive,oneof=document.created document.updated document.deleted document.shared document.stale document.state_changed"`
+}
+
+// RotateSecretRequest controls how long the replaced secret keeps signing
+// deliveries alongside the new one
+type RotateSecretRequest struct {
+	GracePeriodHours *int `json:"grace_period_hours" binding:"omitempty,min=0,max=168"`
+}
+
+// WebhookCreateResponse is returned on creation and secret rotation; it is
+// the only time the signing secret is revealed
+type WebhookCreateResponse struct {
+	Webhook
+	Secret string `json:"secret"`
+}
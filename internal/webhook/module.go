@@ -0,0 +1,20 @@
+package webhook
+
+import (
+	"go.uber.org/fx"
+
+	"github.com/hafiztri123/document-api/internal/api"
+	"github.com/hafiztri123/document-api/internal/webhook/controller"
+	"github.com/hafiztri123/document-api/internal/webhook/repository"
+	"github.com/hafiztri123/document-api/internal/webhook/service"
+)
+
+// Module provides the webhook repository, service, controller and routes
+var Module = fx.Module("webhook",
+	fx.Provide(
+		repository.NewWebhookRepository,
+		service.NewWebhookService,
+		controller.NewWebhookController,
+		api.AsRouteRegistrar(newRoutes),
+	),
+)
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/hafiztri123/document-api/internal/logging"
+	"github.com/hafiztri123/document-api/internal/webhook/model"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	CreateWebhook(ctx context.Context, webhook *model.Webhook) error
+	GetWebhookByID(ctx context.Context, id uuid.UUID) (*model.Webhook, error)
+	GetWebhooksByOwnerID(ctx context.Context, ownerID uuid.UUID) ([]*model.Webhook, error)
+	UpdateWebhook(ctx context.Context, webhook *model.Webhook) error
+	DeleteWebhook(ctx context.Context, id uuid.UUID) error
+
+	CreateDelivery(ctx context.Context, delivery *model.WebhookDelivery) error
+	GetDeliveryByID(ctx context.Context, webhookID, id uuid.UUID) (*model.WebhookDelivery, error)
+	GetDeliveries(ctx context.Context, webhookID uuid.UUID, page, perPage int) ([]*model.WebhookDelivery, int64, error)
+}
+
+type webhookRepository struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+func NewWebhookRepository(db *gorm.DB, logger *zap.Logger) Repository {
+	return &webhookRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *webhookRepository) CreateWebhook(ctx context.Context, webhook *model.Webhook) error {
+	if err := r.db.WithContext(ctx).Create(webhook).Error; err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to create webhook", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r *webhookRepository) GetWebhookByID(ctx context.Context, id uuid.UUID) (*model.Webhook, error) {
+	var webhook model.Webhook
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&webhook).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get webhook by ID", zap.Error(err))
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func (r *webhookRepository) GetWebhooksByOwnerID(ctx context.Context, ownerID uuid.UUID) ([]*model.Webhook, error) {
+	var webhooks []*model.Webhook
+	err := r.db.WithContext(ctx).Where("owner_id = ?", ownerID).Order("created_at DESC").Find(&webhooks).Error
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get webhooks by owner ID", zap.Error(err))
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (r *webhookRepository) UpdateWebhook(ctx context.Context, webhook *model.Webhook) error {
+	if err := r.db.WithContext(ctx).Save(webhook).Error; err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to update webhook", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r *webhookRepository) DeleteWebhook(ctx context.Context, id uuid.UUID) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", id).Delete(&model.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Webhook{}, id).Error
+	})
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to delete webhook", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r *webhookRepository) CreateDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	if err := r.db.WithContext(ctx).Create(delivery).Error; err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to record webhook delivery", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r *webhookRepository) GetDeliveryByID(ctx context.Context, webhookID, id uuid.UUID) (*model.WebhookDelivery, error) {
+	var delivery model.WebhookDelivery
+	err := r.db.WithContext(ctx).Where("id = ? AND webhook_id = ?", id, webhookID).First(&delivery).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get webhook delivery", zap.Error(err))
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func (r *webhookRepository) GetDeliveries(ctx context.Context, webhookID uuid.UUID, page, perPage int) ([]*model.WebhookDelivery, int64, error) {
+	var deliveries []*model.WebhookDelivery
+	var total int64
+
+	db := r.db.WithContext(ctx).Model(&model.WebhookDelivery{}).Where("webhook_id = ?", webhookID)
+
+	if err := db.Count(&total).Error; err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to count webhook deliveries", zap.Error(err))
+		return nil, 0, err
+	}
+
+	if page < 1 {
+		page = 1
+	}
+
+	if perPage < 1 {
+		perPage = 20
+	}
+
+	offset := (page - 1) * perPage
+
+	if err := db.Order("created_at DESC").Limit(perPage).Offset(offset).Find(&deliveries).Error; err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get webhook deliveries", zap.Error(err))
+		return nil, 0, err
+	}
+
+	return deliveries, total, nil
+}
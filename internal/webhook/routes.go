@@ -0,0 +1,27 @@
+package webhook
+
+import (
+	"github.com/hafiztri123/document-api/internal/api"
+	"github.com/hafiztri123/document-api/internal/webhook/controller"
+)
+
+type routes struct {
+	ctrl controller.Controller
+}
+
+func newRoutes(ctrl controller.Controller) *routes {
+	return &routes{ctrl: ctrl}
+}
+
+func (r *routes) RegisterRoutes(groups api.Groups) {
+	webhooks := groups.Protected.Group("/webhooks")
+	{
+		webhooks.POST("", r.ctrl.CreateWebhook)
+		webhooks.GET("", r.ctrl.GetWebhooks)
+		webhooks.DELETE("/:id", r.ctrl.DeleteWebhook)
+		webhooks.POST("/:id/rotate-secret", r.ctrl.RotateSecret)
+		webhooks.POST("/:id/test", r.ctrl.SendTestEvent)
+		webhooks.GET("/:id/deliveries", r.ctrl.GetDeliveries)
+		webhooks.POST("/:id/deliveries/:delivery_id/replay", r.ctrl.ReplayDelivery)
+	}
+}
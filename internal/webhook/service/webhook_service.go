@@ -0,0 +1,308 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hafiztri123/document-api/internal/logging"
+	"github.com/hafiztri123/document-api/internal/signature"
+	"github.com/hafiztri123/document-api/internal/webhook/model"
+	"github.com/hafiztri123/document-api/internal/webhook/repository"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+	ErrUnauthorized     = errors.New("unauthorized access to webhook")
+)
+
+const (
+	deliveryTimeout = 10 * time.Second
+
+	// defaultGracePeriod is how long a rotated-out secret keeps signing
+	defaultGracePeriod = 24 * time.Hour
+
+	// maxResponseSnapshot caps how much of a receiver's response body is stored
+	maxResponseSnapshot = 4096
+)
+
+type Service interface {
+	CreateWebhook(ctx context.Context, ownerID uuid.UUID, req model.WebhookCreateRequest) (*model.WebhookCreateResponse, error)
+	GetWebhooks(ctx context.Context, ownerID uuid.UUID) ([]*model.Webhook, error)
+	DeleteWebhook(ctx context.Context, id uuid.UUID, ownerID uuid.UUID) error
+	RotateSecret(ctx context.Context, id uuid.UUID, ownerID uuid.UUID, req model.RotateSecretRequest) (*model.WebhookCreateResponse, error)
+
+	// Debugging tools for integrators
+	SendTestEvent(ctx context.Context, id uuid.UUID, ownerID uuid.UUID) (*model.WebhookDelivery, error)
+	ReplayDelivery(ctx context.Context, id uuid.UUID, deliveryID uuid.UUID, ownerID uuid.UUID) (*model.WebhookDelivery, error)
+	GetDeliveries(ctx context.Context, id uuid.UUID, ownerID uuid.UUID, page, perPage int) ([]*model.WebhookDelivery, int64, error)
+
+	// Dispatch delivers an event in the background to every active webhook
+	// of the owner subscribed to it
+	Dispatch(ctx context.Context, ownerID uuid.UUID, event model.Event, data interface{})
+}
+
+type webhookService struct {
+	repo   repository.Repository
+	client *http.Client
+	logger *zap.Logger
+}
+
+func NewWebhookService(repo repository.Repository, logger *zap.Logger) Service {
+	return &webhookService{
+		repo:   repo,
+		client: &http.Client{Timeout: deliveryTimeout},
+		logger: logger,
+	}
+}
+
+func (s *webhookService) CreateWebhook(ctx context.Context, ownerID uuid.UUID, req model.WebhookCreateRequest) (*model.WebhookCreateResponse, error) {
+	secret, err := generateSecret()
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to generate webhook secret", zap.Error(err))
+		return nil, err
+	}
+
+	webhook := &model.Webhook{
+		OwnerID:   ownerID,
+		URL:       req.URL,
+		Secret:    secret,
+		Events:    req.Events,
+		Active:    true,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	if err := s.repo.CreateWebhook(ctx, webhook); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to create webhook", zap.Error(err))
+		return nil, err
+	}
+
+	return &model.WebhookCreateResponse{
+		Webhook: *webhook,
+		Secret:  secret,
+	}, nil
+}
+
+func (s *webhookService) GetWebhooks(ctx context.Context, ownerID uuid.UUID) ([]*model.Webhook, error) {
+	return s.repo.GetWebhooksByOwnerID(ctx, ownerID)
+}
+
+func (s *webhookService) DeleteWebhook(ctx context.Context, id uuid.UUID, ownerID uuid.UUID) error {
+	if _, err := s.getOwnedWebhook(ctx, id, ownerID); err != nil {
+		return err
+	}
+
+	return s.repo.DeleteWebhook(ctx, id)
+}
+
+// RotateSecret issues a new signing secret. The previous one stays active
+// for the grace period so receivers can roll over without dropping events.
+func (s *webhookService) RotateSecret(ctx context.Context, id uuid.UUID, ownerID uuid.UUID, req model.RotateSecretRequest) (*model.WebhookCreateResponse, error) {
+	webhook, err := s.getOwnedWebhook(ctx, id, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to generate webhook secret", zap.Error(err))
+		return nil, err
+	}
+
+	gracePeriod := defaultGracePeriod
+	if req.GracePeriodHours != nil {
+		gracePeriod = time.Duration(*req.GracePeriodHours) * time.Hour
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(gracePeriod)
+
+	webhook.PreviousSecret = webhook.Secret
+	webhook.PreviousSecretExpiresAt = &expiresAt
+	webhook.Secret = secret
+	webhook.UpdatedAt = now
+
+	if err := s.repo.UpdateWebhook(ctx, webhook); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to rotate webhook secret", zap.Error(err))
+		return nil, err
+	}
+
+	return &model.WebhookCreateResponse{
+		Webhook: *webhook,
+		Secret:  secret,
+	}, nil
+}
+
+func (s *webhookService) SendTestEvent(ctx context.Context, id uuid.UUID, ownerID uuid.UUID) (*model.WebhookDelivery, error) {
+	webhook, err := s.getOwnedWebhook(ctx, id, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := buildPayload(model.EventTest, map[string]interface{}{
+		"webhook_id": webhook.ID,
+		"message":    "This is a test event",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.deliver(ctx, webhook, model.EventTest, payload, nil), nil
+}
+
+func (s *webhookService) ReplayDelivery(ctx context.Context, id uuid.UUID, deliveryID uuid.UUID, ownerID uuid.UUID) (*model.WebhookDelivery, error) {
+	webhook, err := s.getOwnedWebhook(ctx, id, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	original, err := s.repo.GetDeliveryByID(ctx, webhook.ID, deliveryID)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get webhook delivery", zap.Error(err))
+		return nil, err
+	}
+
+	if original == nil {
+		return nil, ErrDeliveryNotFound
+	}
+
+	// Replays resend the exact payload snapshot so receivers can dedupe on its
+	// ID; the signature is computed fresh so it passes the timestamp check
+	return s.deliver(ctx, webhook, original.Event, []byte(original.Payload), &original.ID), nil
+}
+
+func (s *webhookService) GetDeliveries(ctx context.Context, id uuid.UUID, ownerID uuid.UUID, page, perPage int) ([]*model.WebhookDelivery, int64, error) {
+	if _, err := s.getOwnedWebhook(ctx, id, ownerID); err != nil {
+		return nil, 0, err
+	}
+
+	return s.repo.GetDeliveries(ctx, id, page, perPage)
+}
+
+func (s *webhookService) Dispatch(ctx context.Context, ownerID uuid.UUID, event model.Event, data interface{}) {
+	ctx = logging.Detach(ctx)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+
+		webhooks, err := s.repo.GetWebhooksByOwnerID(ctx, ownerID)
+		if err != nil {
+			logging.FromContext(ctx, s.logger).Error("Failed to load webhooks for dispatch", zap.Error(err))
+			return
+		}
+
+		var payload []byte
+		for _, webhook := range webhooks {
+			if !webhook.Active || !webhook.Subscribes(event) {
+				continue
+			}
+
+			if payload == nil {
+				payload, err = buildPayload(event, data)
+				if err != nil {
+					logging.FromContext(ctx, s.logger).Error("Failed to build webhook payload", zap.Error(err))
+					return
+				}
+			}
+
+			s.deliver(ctx, webhook, event, payload, nil)
+		}
+	}()
+}
+
+// deliver posts the payload to the webhook and records the attempt. A
+// failed delivery is reported on the returned record rather than as an error.
+func (s *webhookService) deliver(ctx context.Context, webhook *model.Webhook, event model.Event, payload []byte, replayOf *uuid.UUID) *model.WebhookDelivery {
+	delivery := &model.WebhookDelivery{
+		ID:         uuid.New(),
+		WebhookID:  webhook.ID,
+		Event:      event,
+		Payload:    string(payload),
+		ReplayOfID: replayOf,
+		CreatedAt:  time.Now(),
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		delivery.Error = err.Error()
+	} else {
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "document-api-webhooks/1.0")
+		req.Header.Set("X-Webhook-Event", string(event))
+		req.Header.Set("X-Webhook-Delivery", delivery.ID.String())
+		req.Header.Set("X-Webhook-Signature", signature.Header(payload, time.Now(), webhook.ActiveSecrets(time.Now())...))
+
+		start := time.Now()
+		resp, err := s.client.Do(req)
+		delivery.DurationMs = time.Since(start).Milliseconds()
+
+		if err != nil {
+			delivery.Error = err.Error()
+		} else {
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseSnapshot))
+			resp.Body.Close()
+			delivery.ResponseStatus = resp.StatusCode
+			delivery.ResponseBody = string(body)
+		}
+	}
+
+	if !delivery.Succeeded() {
+		logging.FromContext(ctx, s.logger).Warn("Webhook delivery failed",
+			zap.String("webhookID", webhook.ID.String()),
+			zap.String("deliveryID", delivery.ID.String()),
+			zap.Int("status", delivery.ResponseStatus),
+			zap.String("error", delivery.Error))
+	}
+
+	if err := s.repo.CreateDelivery(ctx, delivery); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to record webhook delivery", zap.Error(err))
+	}
+
+	return delivery
+}
+
+func (s *webhookService) getOwnedWebhook(ctx context.Context, id uuid.UUID, ownerID uuid.UUID) (*model.Webhook, error) {
+	webhook, err := s.repo.GetWebhookByID(ctx, id)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get webhook by ID", zap.Error(err))
+		return nil, err
+	}
+
+	if webhook == nil {
+		return nil, ErrWebhookNotFound
+	}
+
+	if webhook.OwnerID != ownerID {
+		return nil, ErrUnauthorized
+	}
+
+	return webhook, nil
+}
+
+func buildPayload(event model.Event, data interface{}) ([]byte, error) {
+	return json.Marshal(model.Payload{
+		ID:        uuid.New(),
+		Event:     event,
+		CreatedAt: time.Now(),
+		Data:      data,
+	})
+}
+
+func generateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
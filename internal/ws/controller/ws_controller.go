@@ -4,15 +4,22 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 	
 	authService "github.com/hafiztri123/document-api/internal/auth/service"
+	"github.com/hafiztri123/document-api/internal/errcode"
+	"github.com/hafiztri123/document-api/internal/logging"
+	wsModel "github.com/hafiztri123/document-api/internal/ws/model"
 	wsService "github.com/hafiztri123/document-api/internal/ws/service"
 )
 
 type Controller interface {
 	HandleWebSocket(c *gin.Context)
+	GetRealtimeStats(c *gin.Context)
+	BroadcastSystemMessage(c *gin.Context)
+	DisconnectUser(c *gin.Context)
 }
 
 type wsController struct {
@@ -30,6 +37,8 @@ func NewWSController(wsService wsService.Service, authService authService.Servic
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
+			// Offer permessage-deflate; writes use it once negotiated in hello
+			EnableCompression: true,
 			CheckOrigin: func(r *http.Request) bool {
 				// Allow all origins in development
 				// In production, this should be restricted
@@ -43,7 +52,7 @@ func (ctrl *wsController) HandleWebSocket(c *gin.Context) {
 	token := c.Query("token")
 	if token == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
-			"code":    "unauthorized",
+			"code":    errcode.Unauthorized,
 			"message": "Missing token",
 		}})
 		return
@@ -52,7 +61,7 @@ func (ctrl *wsController) HandleWebSocket(c *gin.Context) {
 	claims, err := ctrl.authService.ValidateToken(token)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{
-			"code":    "unauthorized",
+			"code":    errcode.InvalidToken,
 			"message": "Invalid or expired token",
 		}})
 		return
@@ -60,9 +69,55 @@ func (ctrl *wsController) HandleWebSocket(c *gin.Context) {
 	
 	conn, err := ctrl.upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
-		ctrl.logger.Error("Failed to upgrade connection to WebSocket", zap.Error(err))
+		logging.FromContext(c.Request.Context(), ctrl.logger).Error("Failed to upgrade connection to WebSocket", zap.Error(err))
 		return
 	}
 	
-	ctrl.wsService.HandleConnection(conn, claims.UserID, claims.Email)
-}
\ No newline at end of file
+	ctx := logging.With(c.Request.Context(), zap.String("user_id", claims.UserID.String()))
+	ctrl.wsService.HandleConnection(ctx, conn, claims.UserID, claims.Email)
+}
+
+// GetRealtimeStats reports live hub metrics, see wsModel.HubStats
+func (ctrl *wsController) GetRealtimeStats(c *gin.Context) {
+	c.JSON(http.StatusOK, ctrl.wsService.Stats())
+}
+
+func (ctrl *wsController) BroadcastSystemMessage(c *gin.Context) {
+	var req wsModel.BroadcastRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid request data",
+			"details": err.Error(),
+		}})
+		return
+	}
+	
+	adminID := c.MustGet("userID").(uuid.UUID)
+	result, err := ctrl.wsService.BroadcastSystemMessage(c.Request.Context(), adminID, req.Message)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), ctrl.logger).Error("Failed to broadcast system message", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
+			"code":    errcode.InternalError,
+			"message": "Failed to broadcast system message",
+		}})
+		return
+	}
+	
+	c.JSON(http.StatusOK, result)
+}
+
+// DisconnectUser closes every realtime connection of the user in the path
+func (ctrl *wsController) DisconnectUser(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    errcode.ValidationError,
+			"message": "Invalid user ID",
+		}})
+		return
+	}
+	
+	adminID := c.MustGet("userID").(uuid.UUID)
+	c.JSON(http.StatusOK, ctrl.wsService.DisconnectUser(c.Request.Context(), adminID, userID))
+}
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// SystemMessage is an operator announcement pushed to every connection,
+// such as a maintenance warning. Clients should show it to the user.
+type SystemMessage struct {
+	BaseMessage
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// BroadcastRequest is sent to POST /admin/realtime/broadcast
+type BroadcastRequest struct {
+	Message string `json:"message" binding:"required,max=1000"`
+}
+
+// BroadcastResult reports how many connections the message was queued for
+type BroadcastResult struct {
+	Recipients int `json:"recipients"`
+}
+
+// DisconnectResult reports how many of a user's connections were closed
+type DisconnectResult struct {
+	Sessions int `json:"sessions"`
+}
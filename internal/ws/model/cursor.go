@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SavedCursor is where a user last was in a document
+type SavedCursor struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Name      string    `json:"name"`
+	Color     string    `json:"color,omitempty"`
+	Position  Position  `json:"position"`
+	Scroll    *Position `json:"scroll,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CursorsMessage answers subscribe with the last known cursors in the
+// document, the subscriber's own included, so a reopened editor can put
+// everyone back where they were
+type CursorsMessage struct {
+	BaseMessage
+	DocumentID uuid.UUID     `json:"document_id"`
+	Cursors    []SavedCursor `json:"cursors"`
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/hafiztri123/document-api/internal/errcode"
 )
 
 type MessageType string
@@ -12,11 +13,67 @@ const (
 	MessageTypeSubscribe MessageType = "subscribe"
 	MessageTypeUpdate MessageType = "update"
 	MessageTypeCursor MessageType = "cursor"
+	MessageTypeCursors MessageType = "cursors"
 	MessageTypeError MessageType = "error"
 	MessageTypePing MessageType = "ping"
 	MessageTypePong MessageType = "pong"
+	MessageTypeHello MessageType = "hello"
+	MessageTypeWelcome MessageType = "welcome"
+	MessageTypeNotification MessageType = "notification"
+	MessageTypeSystem MessageType = "system"
+	MessageTypeSettings MessageType = "settings"
+	MessageTypeReaction MessageType = "reaction"
 )
 
+// Realtime protocol versions. Clients that never send hello are treated as
+// speaking MinProtocolVersion with no capabilities.
+const (
+	ProtocolVersion    = 1
+	MinProtocolVersion = 1
+)
+
+// Capability is an optional protocol feature negotiated in the handshake
+type Capability string
+
+const (
+	CapabilityOT          Capability = "ot"
+	CapabilityCompression Capability = "compression"
+	CapabilityBinary      Capability = "binary"
+)
+
+// ServerCapabilities lists the capabilities this server can enable.
+// Compression only takes effect when permessage-deflate was negotiated
+// during the HTTP upgrade.
+var ServerCapabilities = []Capability{
+	CapabilityCompression,
+}
+
+// HelloMessage opens the handshake; unknown capabilities are ignored so
+// newer clients can talk to older servers
+type HelloMessage struct {
+	BaseMessage
+	ProtocolVersion int          `json:"protocol_version"`
+	Capabilities    []Capability `json:"capabilities"`
+}
+
+// WelcomeMessage answers hello with the negotiated protocol
+type WelcomeMessage struct {
+	BaseMessage
+	ClientID           string       `json:"client_id"`
+	ProtocolVersion    int          `json:"protocol_version"`
+	MinProtocolVersion int          `json:"min_protocol_version"`
+	Capabilities       []Capability `json:"capabilities"`
+}
+
+// SettingsMessage changes preferences of the connection it is sent on and
+// is answered with the settings in effect. Omitted settings are unchanged.
+type SettingsMessage struct {
+	BaseMessage
+	// Focus suppresses non-essential broadcasts, such as other users'
+	// cursors, while content updates and notifications still arrive
+	Focus *bool `json:"focus,omitempty"`
+}
+
 type BaseMessage struct {
 	Type MessageType `json:"type"`
 }
@@ -42,6 +99,9 @@ type UpdateMessage struct {
 		Name string    `json:"name"`
 	} `json:"user"`
 	Timestamp time.Time `json:"timestamp"`
+	// ClientID identifies the originating connection, so a user's other
+	// devices can tell their own edits apart; empty for non-WebSocket edits
+	ClientID string `json:"client_id,omitempty"`
 }
 
 type Position struct {
@@ -53,6 +113,8 @@ type CursorMessage struct {
 	BaseMessage
 	DocumentID uuid.UUID `json:"document_id"`
 	Position   Position  `json:"position"`
+	// Scroll is the first line in view, restored with the cursor
+	Scroll     *Position `json:"scroll,omitempty"`
 	User       struct {
 		ID    uuid.UUID `json:"id"`
 		Name  string    `json:"name"`
@@ -60,10 +122,37 @@ type CursorMessage struct {
 	} `json:"user"`
 }
 
+// ReactionMessage tells a document's subscribers that a user added or
+// removed an emoji on a version; Count is how many users now have it there
+type ReactionMessage struct {
+	BaseMessage
+	DocumentID uuid.UUID `json:"document_id"`
+	Version    int       `json:"version"`
+	Emoji      string    `json:"emoji"`
+	Added      bool      `json:"added"`
+	Count      int       `json:"count"`
+	User       struct {
+		ID   uuid.UUID `json:"id"`
+		Name string    `json:"name"`
+	} `json:"user"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
 type ErrorMessage struct {
 	BaseMessage
-	Code    string `json:"code"`
-	Message string `json:"message"`
+	Code    errcode.Code `json:"code"`
+	Message string       `json:"message"`
+	// Field is the JSON path of the offending field for validation errors
+	Field string `json:"field,omitempty"`
+}
+
+// NotificationMessage is pushed to every connection of a user, regardless
+// of which documents they are subscribed to
+type NotificationMessage struct {
+	BaseMessage
+	Event     string      `json:"event"`
+	Data      interface{} `json:"data"`
+	Timestamp time.Time   `json:"timestamp"`
 }
 
 type PingMessage struct {
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// HubStats is a snapshot of the realtime hub for operators
+type HubStats struct {
+	Connections int `json:"connections"`
+	Users       int `json:"users"`
+	// Subscriptions counts client-document pairs across Documents documents
+	Subscriptions int `json:"subscriptions"`
+	Documents     int `json:"documents"`
+	// TopDocuments are the documents with the most subscribers
+	TopDocuments []DocumentSubscribers `json:"top_documents"`
+	// Message rates are averaged over the last minute. Outbound counts
+	// every broadcast and notification delivery, so one broadcast to ten
+	// clients counts ten times; replies such as pongs are not counted.
+	MessagesInPerSecond  float64 `json:"messages_in_per_second"`
+	MessagesOutPerSecond float64 `json:"messages_out_per_second"`
+	// MessagesSuppressedPerSecond counts broadcasts skipped for clients in
+	// focus mode; they are not part of the outbound rate
+	MessagesSuppressedPerSecond float64 `json:"messages_suppressed_per_second"`
+	// SlowClientsDropped counts clients disconnected for a full send
+	// buffer since startup; RecentDrops lists the latest, newest first
+	SlowClientsDropped int64           `json:"slow_clients_dropped"`
+	RecentDrops        []DroppedClient `json:"recent_drops"`
+}
+
+type DocumentSubscribers struct {
+	DocumentID  uuid.UUID `json:"document_id"`
+	Subscribers int       `json:"subscribers"`
+}
+
+// DroppedClient is a connection closed because it could not keep up
+type DroppedClient struct {
+	ClientID  string    `json:"client_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	DroppedAt time.Time `json:"dropped_at"`
+}
@@ -0,0 +1,21 @@
+package ws
+
+import (
+	"go.uber.org/fx"
+
+	"github.com/hafiztri123/document-api/internal/api"
+	"github.com/hafiztri123/document-api/internal/ws/controller"
+	"github.com/hafiztri123/document-api/internal/ws/repository"
+	"github.com/hafiztri123/document-api/internal/ws/service"
+)
+
+// Module provides the WebSocket repository, service, controller and routes
+var Module = fx.Module("ws",
+	fx.Provide(
+		repository.NewWSRepository,
+		repository.NewCursorRepository,
+		service.NewWSService,
+		controller.NewWSController,
+		api.AsRouteRegistrar(newRoutes),
+	),
+)
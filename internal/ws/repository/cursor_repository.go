@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hafiztri123/document-api/internal/logging"
+	"github.com/hafiztri123/document-api/internal/ws/model"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// CursorRepository keeps each user's last cursor in a document in Redis,
+// one hash per document, so editors can resume where everyone was
+type CursorRepository interface {
+	// SaveCursor replaces the user's cursor in the document. The document's
+	// cursors expire together ttl after the last save.
+	SaveCursor(ctx context.Context, documentID uuid.UUID, cursor *model.SavedCursor, ttl time.Duration) error
+	// GetCursors returns the cursors saved at or after since, most recent
+	// first, and drops older ones
+	GetCursors(ctx context.Context, documentID uuid.UUID, since time.Time) ([]model.SavedCursor, error)
+}
+
+type cursorRepository struct {
+	redis  *redis.Client
+	logger *zap.Logger
+}
+
+func NewCursorRepository(redis *redis.Client, logger *zap.Logger) CursorRepository {
+	return &cursorRepository{
+		redis:  redis,
+		logger: logger,
+	}
+}
+
+func (r *cursorRepository) SaveCursor(ctx context.Context, documentID uuid.UUID, cursor *model.SavedCursor, ttl time.Duration) error {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to marshal cursor", zap.Error(err))
+		return err
+	}
+
+	key := cursorsKey(documentID)
+	pipe := r.redis.TxPipeline()
+	pipe.HSet(ctx, key, cursor.UserID.String(), data)
+	pipe.Expire(ctx, key, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to save cursor", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *cursorRepository) GetCursors(ctx context.Context, documentID uuid.UUID, since time.Time) ([]model.SavedCursor, error) {
+	key := cursorsKey(documentID)
+	entries, err := r.redis.HGetAll(ctx, key).Result()
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get cursors", zap.Error(err))
+		return nil, err
+	}
+
+	cursors := make([]model.SavedCursor, 0, len(entries))
+	var stale []string
+	for field, data := range entries {
+		var cursor model.SavedCursor
+		if err := json.Unmarshal([]byte(data), &cursor); err != nil || cursor.UpdatedAt.Before(since) {
+			stale = append(stale, field)
+			continue
+		}
+		cursors = append(cursors, cursor)
+	}
+
+	// The hash only expires as a whole, so users who left long ago are
+	// pruned as they are found
+	if len(stale) > 0 {
+		if err := r.redis.HDel(ctx, key, stale...).Err(); err != nil {
+			logging.FromContext(ctx, r.logger).Warn("Failed to prune stale cursors", zap.Error(err))
+		}
+	}
+
+	sort.Slice(cursors, func(i, j int) bool { return cursors[i].UpdatedAt.After(cursors[j].UpdatedAt) })
+	return cursors, nil
+}
+
+func cursorsKey(documentID uuid.UUID) string {
+	return fmt.Sprintf("ws_cursors:%s", documentID)
+}
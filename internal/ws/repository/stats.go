@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hafiztri123/document-api/internal/ws/model"
+	"go.uber.org/zap"
+)
+
+const (
+	// rateWindow is how many seconds message rates are averaged over
+	rateWindow = 60
+	// recentDrops is how many dropped clients the hub remembers
+	recentDrops = 20
+	// topDocuments is how many documents HubStats lists by subscribers
+	topDocuments = 20
+)
+
+// rateCounter counts events in one-second buckets over the last rateWindow
+// seconds
+type rateCounter struct {
+	mutex   sync.Mutex
+	buckets [rateWindow]int64
+	seconds [rateWindow]int64
+}
+
+func (c *rateCounter) add(n int) {
+	now := time.Now().Unix()
+	i := now % rateWindow
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.seconds[i] != now {
+		c.seconds[i] = now
+		c.buckets[i] = 0
+	}
+	c.buckets[i] += int64(n)
+}
+
+// perSecond averages the buckets still inside the window
+func (c *rateCounter) perSecond() float64 {
+	now := time.Now().Unix()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var total int64
+	for i := range c.buckets {
+		if now-c.seconds[i] < rateWindow {
+			total += c.buckets[i]
+		}
+	}
+	return float64(total) / rateWindow
+}
+
+// hubMetrics records traffic and slow-client drops for Stats
+type hubMetrics struct {
+	received   rateCounter
+	sent       rateCounter
+	suppressed rateCounter
+
+	dropMutex sync.Mutex
+	dropped   int64
+	drops     []model.DroppedClient
+}
+
+func (m *hubMetrics) recordDrop(client *Client) {
+	m.dropMutex.Lock()
+	defer m.dropMutex.Unlock()
+
+	m.dropped++
+	m.drops = append([]model.DroppedClient{{
+		ClientID:  client.ID,
+		UserID:    client.UserID,
+		DroppedAt: time.Now(),
+	}}, m.drops...)
+	if len(m.drops) > recentDrops {
+		m.drops = m.drops[:recentDrops]
+	}
+}
+
+func (r *wsRepository) RecordReceived() {
+	r.metrics.received.add(1)
+}
+
+// dropSlowClient disconnects a client whose send buffer is full
+func (r *wsRepository) dropSlowClient(client *Client) {
+	r.logger.Warn("Client send buffer full, closing connection",
+		zap.String("clientID", client.ID))
+	r.metrics.recordDrop(client)
+	r.UnregisterClient(client)
+}
+
+func (r *wsRepository) Stats() model.HubStats {
+	r.mutex.RLock()
+	stats := model.HubStats{
+		Connections:  len(r.clients),
+		Users:        len(r.userClients),
+		Documents:    len(r.subscribers),
+		TopDocuments: make([]model.DocumentSubscribers, 0, len(r.subscribers)),
+	}
+	for documentID, subscribers := range r.subscribers {
+		stats.Subscriptions += len(subscribers)
+		stats.TopDocuments = append(stats.TopDocuments, model.DocumentSubscribers{
+			DocumentID:  documentID,
+			Subscribers: len(subscribers),
+		})
+	}
+	r.mutex.RUnlock()
+
+	sort.Slice(stats.TopDocuments, func(i, j int) bool {
+		return stats.TopDocuments[i].Subscribers > stats.TopDocuments[j].Subscribers
+	})
+	if len(stats.TopDocuments) > topDocuments {
+		stats.TopDocuments = stats.TopDocuments[:topDocuments]
+	}
+
+	stats.MessagesInPerSecond = r.metrics.received.perSecond()
+	stats.MessagesOutPerSecond = r.metrics.sent.perSecond()
+	stats.MessagesSuppressedPerSecond = r.metrics.suppressed.perSecond()
+
+	r.metrics.dropMutex.Lock()
+	stats.SlowClientsDropped = r.metrics.dropped
+	stats.RecentDrops = append([]model.DroppedClient{}, r.metrics.drops...)
+	r.metrics.dropMutex.Unlock()
+
+	return stats
+}
@@ -3,26 +3,113 @@ package repository
 import (
 	"encoding/json"
 	"sync"
+	"sync/atomic"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"github.com/hafiztri123/document-api/internal/chaos"
 	"github.com/hafiztri123/document-api/internal/ws/model"
 	"go.uber.org/zap"
 )
 
+// sendBuffer is how many messages may wait for a client's write pump
+const sendBuffer = 256
+
 type Client struct {
 	ID string
 	UserID uuid.UUID
 	Name string
 	Conn *websocket.Conn
+	// Send queues messages for the write pump. It is never closed, since
+	// broadcasts may still hold the client after it is unregistered;
+	// done is closed instead.
 	Send chan []byte
+
+	done chan struct{}
+	closeOnce sync.Once
+
+	// Negotiated protocol, written by the read pump and read by others
+	protocolMutex sync.RWMutex
+	protocolVersion int
+	capabilities map[model.Capability]bool
+
+	// focused is set by the settings message, see SettingsMessage.Focus
+	focused atomic.Bool
+}
+
+// NewClient creates a client for a connection, not yet registered
+func NewClient(id string, userID uuid.UUID, name string, conn *websocket.Conn) *Client {
+	return &Client{
+		ID: id,
+		UserID: userID,
+		Name: name,
+		Conn: conn,
+		Send: make(chan []byte, sendBuffer),
+		done: make(chan struct{}),
+	}
+}
+
+// Close marks the client as gone, telling the write pump to flush what is
+// queued and close the connection. It is safe to call more than once.
+func (c *Client) Close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+}
+
+// Done is closed once the client is closed
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// SetFocused turns focus mode on or off for the connection
+func (c *Client) SetFocused(focused bool) {
+	c.focused.Store(focused)
+}
+
+// Focused reports whether non-essential broadcasts should skip the client
+func (c *Client) Focused() bool {
+	return c.focused.Load()
+}
+
+// SetProtocol records the outcome of the hello handshake
+func (c *Client) SetProtocol(version int, capabilities []model.Capability) {
+	c.protocolMutex.Lock()
+	defer c.protocolMutex.Unlock()
+
+	c.protocolVersion = version
+	c.capabilities = make(map[model.Capability]bool, len(capabilities))
+	for _, capability := range capabilities {
+		c.capabilities[capability] = true
+	}
+}
+
+// ProtocolVersion returns the negotiated version, MinProtocolVersion
+// until the client says hello
+func (c *Client) ProtocolVersion() int {
+	c.protocolMutex.RLock()
+	defer c.protocolMutex.RUnlock()
+
+	if c.protocolVersion == 0 {
+		return model.MinProtocolVersion
+	}
+	return c.protocolVersion
+}
+
+// Supports reports whether a capability was negotiated
+func (c *Client) Supports(capability model.Capability) bool {
+	c.protocolMutex.RLock()
+	defer c.protocolMutex.RUnlock()
+
+	return c.capabilities[capability]
 }
 
 type Repository interface {
 	// Client management
 	RegisterClient(client *Client)
 	UnregisterClient(client *Client)
-	GetClients() []*Client
+	GetClient(clientID string) *Client
+	GetClientsByUser(userID uuid.UUID) []*Client
 	
 	// Document subscriptions
 	Subscribe(documentID uuid.UUID, clientID string)
@@ -32,20 +119,47 @@ type Repository interface {
 	// Broadcasting
 	BroadcastToDocument(documentID uuid.UUID, message []byte, excludeClientID string)
 	BroadcastCursorPosition(documentID uuid.UUID, message model.CursorMessage)
+	SendToUser(userID uuid.UUID, message []byte)
+	// BroadcastToAll delivers a message to every connection, returning how
+	// many it was queued for
+	BroadcastToAll(message []byte) int
+	
+	// DisconnectUser closes every connection of a user after delivering
+	// message, which may be nil, and returns how many were closed
+	DisconnectUser(userID uuid.UUID, message []byte) int
+	
+	// Metrics
+	// RecordReceived counts a message read from a client
+	RecordReceived()
+	Stats() model.HubStats
 }
 
+// wsRepository indexes clients by ID, by user and by subscribed document so
+// that lookups never scan every connection. All indexes are guarded by mutex.
 type wsRepository struct {
 	clients map[string]*Client
+	userClients map[uuid.UUID]map[string]*Client
 	subscribers map[uuid.UUID]map[string]bool
+	subscriptions map[string]map[uuid.UUID]bool
 	mutex sync.RWMutex
+	metrics hubMetrics
+	chaos chaos.Config
 	logger *zap.Logger
 }
 
 
 func NewWSRepository(logger *zap.Logger) Repository {
+	cfg := chaos.Load(logger)
+	if cfg.Enabled && cfg.WSDropRate > 0 {
+		logger.Warn("Websocket message drops enabled", zap.Float64("ws_drop_rate", cfg.WSDropRate))
+	}
+
 	return &wsRepository{
 		clients: make(map[string]*Client),
+		userClients: make(map[uuid.UUID]map[string]*Client),
 		subscribers: make(map[uuid.UUID]map[string]bool),
+		subscriptions: make(map[string]map[uuid.UUID]bool),
+		chaos: cfg,
 		logger: logger,
 	}
 }
@@ -59,6 +173,12 @@ func (r *wsRepository)	RegisterClient(client *Client) {
 	defer r.mutex.Unlock()
 
 	r.clients[client.ID] = client
+
+	if _, ok := r.userClients[client.UserID]; !ok {
+		r.userClients[client.UserID] = make(map[string]*Client)
+	}
+	r.userClients[client.UserID][client.ID] = client
+
 	r.logger.Debug("Registered Websocket client",
 		zap.String("clientID", client.ID),
 		zap.String("userID", client.UserID.String()))
@@ -69,34 +189,43 @@ func (r *wsRepository)	UnregisterClient(client *Client){
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	for documentID, subscribers := range r.subscribers {
-		if _, ok := subscribers[client.ID]; ok {
-			delete(subscribers, client.ID)
-			r.logger.Debug("Unsubscriber client from document",
-				zap.String("clientID", client.ID),
-				zap.String("documentID", documentID.String()))
-		}
-
-		if len(subscribers) == 0 {
-			delete(r.subscribers, documentID)
-		}
+	for documentID := range r.subscriptions[client.ID] {
+		r.removeSubscription(documentID, client.ID)
 	}
 
 	if _, ok := r.clients[client.ID]; ok {
 		delete(r.clients, client.ID)
-		close(client.Send)
+
+		if userClients, ok := r.userClients[client.UserID]; ok {
+			delete(userClients, client.ID)
+			if len(userClients) == 0 {
+				delete(r.userClients, client.UserID)
+			}
+		}
+
+		client.Close()
 		r.logger.Debug("Unregistered Websocket client",
 			zap.String("clientID", client.ID))
 	}
 }
 
 
-func (r *wsRepository)	GetClients() []*Client{
+// GetClient returns the client with the given ID, or nil if it is not connected
+func (r *wsRepository)	GetClient(clientID string) *Client{
 	r.mutex.RLock()
-	defer r.mutex.RLock()
+	defer r.mutex.RUnlock()
 
-	clients := make([]*Client, 0, len(r.clients))
-	for _, client := range r.clients {
+	return r.clients[clientID]
+}
+
+
+// GetClientsByUser returns every connection of a user, one per device or tab
+func (r *wsRepository)	GetClientsByUser(userID uuid.UUID) []*Client{
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	clients := make([]*Client, 0, len(r.userClients[userID]))
+	for _, client := range r.userClients[userID] {
 		clients = append(clients, client)
 	}
 
@@ -113,6 +242,12 @@ func (r *wsRepository)	Subscribe(documentID uuid.UUID, clientID string){
 	}
 
 	r.subscribers[documentID][clientID] = true
+
+	if _, ok := r.subscriptions[clientID]; !ok {
+		r.subscriptions[clientID] = make(map[uuid.UUID]bool)
+	}
+	r.subscriptions[clientID][documentID] = true
+
 	r.logger.Debug("Client subscribed to document",
 		zap.String("clientID", clientID),
 		zap.String("documentID", documentID.String()))
@@ -123,18 +258,30 @@ func (r *wsRepository)	Unsubscribe(documentID uuid.UUID, clientID string){
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
+	r.removeSubscription(documentID, clientID)
+}
+
 
+// removeSubscription drops a subscription from both indexes; the caller
+// must hold the write lock
+func (r *wsRepository) removeSubscription(documentID uuid.UUID, clientID string) {
 	if subscribers, ok := r.subscribers[documentID]; ok {
 		delete(subscribers, clientID)
 		r.logger.Debug("Client unsubscribed from document",
 			zap.String("clientID", clientID),
 			zap.String("documentID", documentID.String()))
-		
+
 		if len(subscribers) == 0 {
 			delete(r.subscribers, documentID)
 		}
 	}
 
+	if subscriptions, ok := r.subscriptions[clientID]; ok {
+		delete(subscriptions, documentID)
+		if len(subscriptions) == 0 {
+			delete(r.subscriptions, clientID)
+		}
+	}
 }
 
 
@@ -161,19 +308,21 @@ func (r *wsRepository)	BroadcastToDocument(documentID uuid.UUID, message []byte,
 	subscribers := r.GetSubscribers(documentID)
 
 	for _, client := range subscribers {
-		if client.ID == excludeClientID {
+		if client.ID == excludeClientID || r.chaos.DropMessage() {
 			continue
 		}
 
+		// A client unregistered since the lookup is skipped, not dropped
+		// as slow
 		select {
+		case <-client.Done():
 		case client.Send <- message:
+			r.metrics.sent.add(1)
 			r.logger.Debug("Broadcast to document",
 				zap.String("clientID", client.ID),
 				zap.String("documentID", documentID.String()))
 		default:
-			r.logger.Warn("Client send buffer full, closing connection",
-				zap.String("clientID", client.ID))
-			r.UnregisterClient(client)
+			r.dropSlowClient(client)
 		}
 	}
 }
@@ -187,6 +336,14 @@ func (r *wsRepository) BroadcastCursorPosition(documentID uuid.UUID, message mod
 		if client.UserID == message.User.ID {
 			continue
 		}
+		// Cursors are non-essential, so focused clients go without
+		if client.Focused() {
+			r.metrics.suppressed.add(1)
+			continue
+		}
+		if r.chaos.DropMessage() {
+			continue
+		}
 
 		messageBytes, err := json.Marshal(message)
 		if err != nil {
@@ -198,17 +355,88 @@ func (r *wsRepository) BroadcastCursorPosition(documentID uuid.UUID, message mod
 		}
 
 		select {
+		case <-client.Done():
 		case client.Send <- messageBytes:
+			r.metrics.sent.add(1)
 			r.logger.Debug("Cursor position broadcasted to client",
 				zap.String("clientID", client.ID),
 				zap.String("documentID", documentID.String()))
 		default:
-			// Client send buffer is full, unregister the client
-			r.logger.Warn("Client send buffer full, closing connection", 
-				zap.String("clientID", client.ID))
-			r.UnregisterClient(client)
+			r.dropSlowClient(client)
+		}
+	}
+}
+
+
+// SendToUser delivers a message to every connection of a user
+func (r *wsRepository) SendToUser(userID uuid.UUID, message []byte) {
+	for _, client := range r.GetClientsByUser(userID) {
+		if r.chaos.DropMessage() {
+			continue
+		}
+
+		select {
+		case <-client.Done():
+		case client.Send <- message:
+			r.metrics.sent.add(1)
+			r.logger.Debug("Message sent to user",
+				zap.String("clientID", client.ID),
+				zap.String("userID", userID.String()))
+		default:
+			r.dropSlowClient(client)
 		}
 	}
 }
 
 
+func (r *wsRepository) BroadcastToAll(message []byte) int {
+	r.mutex.RLock()
+	clients := make([]*Client, 0, len(r.clients))
+	for _, client := range r.clients {
+		clients = append(clients, client)
+	}
+	r.mutex.RUnlock()
+
+	recipients := 0
+	for _, client := range clients {
+		if r.chaos.DropMessage() {
+			continue
+		}
+
+		select {
+		case <-client.Done():
+		case client.Send <- message:
+			r.metrics.sent.add(1)
+			recipients++
+		default:
+			r.dropSlowClient(client)
+		}
+	}
+
+	return recipients
+}
+
+
+// DisconnectUser relies on the write pump flushing queued messages once
+// the client is closed, before it closes the connection
+func (r *wsRepository) DisconnectUser(userID uuid.UUID, message []byte) int {
+	clients := r.GetClientsByUser(userID)
+
+	for _, client := range clients {
+		if message != nil {
+			select {
+			case <-client.Done():
+			case client.Send <- message:
+				r.metrics.sent.add(1)
+			default:
+			}
+		}
+
+		r.UnregisterClient(client)
+		r.logger.Debug("Disconnected Websocket client",
+			zap.String("clientID", client.ID),
+			zap.String("userID", userID.String()))
+	}
+
+	return len(clients)
+}
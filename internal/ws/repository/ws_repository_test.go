@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Broadcasts hold clients looked up before the lock is released, so a
+// client may be unregistered while they send to it
+func TestUnregisterKeepsSendOpen(t *testing.T) {
+	repo := NewWSRepository(zap.NewNop())
+	client := NewClient(uuid.NewString(), uuid.New(), "Test User", nil)
+	repo.RegisterClient(client)
+
+	repo.UnregisterClient(client)
+	repo.UnregisterClient(client)
+
+	select {
+	case <-client.Done():
+	default:
+		t.Fatal("client was not closed")
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("sending to an unregistered client panicked: %v", r)
+		}
+	}()
+	select {
+	case client.Send <- []byte(`{"type":"system"}`):
+	default:
+	}
+}
+
+func TestUnregisterWhileBroadcasting(t *testing.T) {
+	repo := NewWSRepository(zap.NewNop())
+	documentID := uuid.New()
+	userID := uuid.New()
+	message := []byte(`{"type":"system"}`)
+
+	stop := make(chan struct{})
+	broadcasts := []func(){
+		func() { repo.BroadcastToDocument(documentID, message, "") },
+		func() { repo.SendToUser(userID, message) },
+		func() { repo.BroadcastToAll(message) },
+		func() { repo.DisconnectUser(userID, message) },
+	}
+
+	var wg sync.WaitGroup
+	for _, broadcast := range broadcasts {
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					select {
+					case <-stop:
+						return
+					default:
+						broadcast()
+					}
+				}
+			}()
+		}
+	}
+
+	for i := 0; i < 200; i++ {
+		client := NewClient(uuid.NewString(), userID, "Test User", nil)
+		repo.RegisterClient(client)
+		repo.Subscribe(documentID, client.ID)
+		repo.UnregisterClient(client)
+	}
+	close(stop)
+	wg.Wait()
+
+	if clients := repo.GetClientsByUser(userID); len(clients) != 0 {
+		t.Errorf("user still has %d clients", len(clients))
+	}
+}
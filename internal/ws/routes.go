@@ -0,0 +1,25 @@
+package ws
+
+import (
+	"github.com/hafiztri123/document-api/internal/api"
+	"github.com/hafiztri123/document-api/internal/ws/controller"
+)
+
+type routes struct {
+	ctrl controller.Controller
+}
+
+func newRoutes(ctrl controller.Controller) *routes {
+	return &routes{ctrl: ctrl}
+}
+
+// RegisterRoutes mounts the WebSocket endpoint on the root router; it
+// authenticates with a query token rather than the protected group
+func (r *routes) RegisterRoutes(groups api.Groups) {
+	groups.Root.GET("/ws/documents/:id", r.ctrl.HandleWebSocket)
+
+	// Live hub metrics and operator tools
+	groups.Admin.GET("/realtime", r.ctrl.GetRealtimeStats)
+	groups.Admin.POST("/realtime/broadcast", r.ctrl.BroadcastSystemMessage)
+	groups.Admin.POST("/realtime/users/:user_id/disconnect", r.ctrl.DisconnectUser)
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	wsModel "github.com/hafiztri123/document-api/internal/ws/model"
+)
+
+// ValidationError reports an inbound message that does not match its
+// schema. Field is the JSON path of the offending field, when known.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// Inbound schemas. They mirror the message models but keep identifiers as
+// strings so format errors can be reported against the field instead of
+// failing the whole decode.
+type (
+	subscribeSchema struct {
+		Type       string `json:"type"`
+		DocumentID string `json:"document_id" validate:"required,uuid"`
+	}
+
+	cursorSchema struct {
+		Type       string `json:"type"`
+		DocumentID string `json:"document_id" validate:"required,uuid"`
+		Position   *struct {
+			Line   *int `json:"line" validate:"required,min=0"`
+			Column *int `json:"column" validate:"required,min=0"`
+		} `json:"position" validate:"required"`
+		Scroll *struct {
+			Line   *int `json:"line" validate:"required,min=0"`
+			Column *int `json:"column" validate:"required,min=0"`
+		} `json:"scroll,omitempty"`
+		// User is overwritten with the connection's user; accepted for
+		// compatibility with clients that echo it
+		User json.RawMessage `json:"user,omitempty"`
+	}
+
+	settingsSchema struct {
+		Type  string `json:"type"`
+		Focus *bool  `json:"focus,omitempty"`
+	}
+
+	pingSchema struct {
+		Type string `json:"type"`
+	}
+
+	// The protocol version is only required here; handleHello rejects
+	// versions the server does not support
+	helloSchema struct {
+		Type            string   `json:"type"`
+		ProtocolVersion *int     `json:"protocol_version" validate:"required"`
+		Capabilities    []string `json:"capabilities" validate:"max=16,dive,max=32"`
+	}
+)
+
+// schemas maps each inbound message type to a constructor for its schema.
+// Update is sent by the server only: clients change content over REST, so
+// inbound patches have no schema and are rejected as an invalid type.
+var schemas = map[wsModel.MessageType]func() interface{}{
+	wsModel.MessageTypeSubscribe: func() interface{} { return &subscribeSchema{} },
+	wsModel.MessageTypeCursor:    func() interface{} { return &cursorSchema{} },
+	wsModel.MessageTypePing:      func() interface{} { return &pingSchema{} },
+	wsModel.MessageTypeSettings:  func() interface{} { return &settingsSchema{} },
+	wsModel.MessageTypeHello:     func() interface{} { return &helloSchema{} },
+}
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	return v
+}
+
+// validateMessage checks data against the schema of messageType. Unknown
+// fields are rejected. It returns ErrInvalidMessageType for types without a
+// schema and a *ValidationError for schema violations.
+func validateMessage(messageType wsModel.MessageType, data []byte) error {
+	newSchema, ok := schemas[messageType]
+	if !ok {
+		return ErrInvalidMessageType
+	}
+
+	schema := newSchema()
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(schema); err != nil {
+		return decodeError(err)
+	}
+
+	if err := validate.Struct(schema); err != nil {
+		var fieldErrs validator.ValidationErrors
+		if errors.As(err, &fieldErrs) && len(fieldErrs) > 0 {
+			return fieldError(fieldErrs[0])
+		}
+		return &ValidationError{Message: err.Error()}
+	}
+
+	return nil
+}
+
+func decodeError(err error) *ValidationError {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return &ValidationError{
+			Field:   typeErr.Field,
+			Message: fmt.Sprintf("must be of type %s", typeErr.Type),
+		}
+	}
+
+	// The decoder reports unknown fields only as text
+	const unknownPrefix = "json: unknown field "
+	if msg := err.Error(); strings.HasPrefix(msg, unknownPrefix) {
+		return &ValidationError{
+			Field:   strings.Trim(strings.TrimPrefix(msg, unknownPrefix), `"`),
+			Message: "unknown field",
+		}
+	}
+
+	return &ValidationError{Message: "malformed JSON"}
+}
+
+func fieldError(fe validator.FieldError) *ValidationError {
+	// Drop the schema struct name from the namespace
+	field := fe.Namespace()
+	if i := strings.Index(field, "."); i >= 0 {
+		field = field[i+1:]
+	}
+
+	var message string
+	switch fe.Tag() {
+	case "required":
+		message = "is required"
+	case "uuid":
+		message = "must be a valid UUID"
+	case "min":
+		if fe.Kind() == reflect.Slice {
+			message = "must contain at least " + fe.Param() + " items"
+		} else {
+			message = "must be at least " + fe.Param()
+		}
+	case "max":
+		if fe.Kind() == reflect.Slice {
+			message = "must contain at most " + fe.Param() + " items"
+		} else {
+			message = "must be at most " + fe.Param()
+		}
+	default:
+		message = "is invalid"
+	}
+
+	return &ValidationError{Field: field, Message: message}
+}
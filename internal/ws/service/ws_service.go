@@ -4,79 +4,141 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"expvar"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"github.com/hafiztri123/document-api/config"
 	"github.com/hafiztri123/document-api/internal/document/model"
 	docRepo "github.com/hafiztri123/document-api/internal/document/repository"
+	"github.com/hafiztri123/document-api/internal/errcode"
+	"github.com/hafiztri123/document-api/internal/logging"
+	pushService "github.com/hafiztri123/document-api/internal/push/service"
 	wsModel "github.com/hafiztri123/document-api/internal/ws/model"
 	wsRepo "github.com/hafiztri123/document-api/internal/ws/repository"
+	"github.com/spf13/viper"
 	"go.uber.org/zap"
 )
 
 
 var (
-	ErrInvalidMessageType = errors.New("invalid message type")
-	ErrUnauthorized       = errors.New("unauthorized access to document")
+	ErrInvalidMessageType         = errors.New("invalid message type")
+	ErrUnauthorized               = errors.New("unauthorized access to document")
+	ErrUnsupportedProtocolVersion = errors.New("unsupported protocol version")
+	ErrTermsNotAccepted           = errors.New("document terms have not been accepted")
 )
 
+// PumpPanics counts panics recovered in the read and write pumps, keyed by
+// pump. It is published with the other expvars at
+// /api/v1/admin/debug/vars.
+var PumpPanics = expvar.NewMap("ws_pump_panics")
+
 
 type Service interface {
 	// Client operations
-	HandleConnection(conn *websocket.Conn, userID uuid.UUID, userName string)
+	HandleConnection(ctx context.Context, conn *websocket.Conn, userID uuid.UUID, userName string)
 	
 	// Message handling
 	ProcessMessage(ctx context.Context, clientID string, userID uuid.UUID, messageType string, data []byte) error
 	
-	// Document update broadcasting
-	BroadcastDocumentUpdate(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, userName string, version int, patches []wsModel.JSONPatchOperation) error
+	// Document update broadcasting; the update is delivered to every
+	// subscriber except originClientID, including the editor's other devices
+	BroadcastDocumentUpdate(ctx context.Context, documentID uuid.UUID, originClientID string, userID uuid.UUID, userName string, version int, patches []wsModel.JSONPatchOperation) error
+	// BroadcastReaction delivers a reaction change to every subscriber of
+	// the document, the reacting user's connections included
+	BroadcastReaction(ctx context.Context, message wsModel.ReactionMessage) error
+
+	// NotifyUser pushes a notification to every connection of a user. When
+	// the user has no connection it goes to their mobile devices instead.
+	NotifyUser(ctx context.Context, userID uuid.UUID, event string, data interface{}) error
+
+	// Stats reports live hub metrics for operators
+	Stats() wsModel.HubStats
+
+	// Admin operations; both are written to the audit log
+	// BroadcastSystemMessage pushes an announcement to every connection
+	BroadcastSystemMessage(ctx context.Context, adminID uuid.UUID, message string) (*wsModel.BroadcastResult, error)
+	// DisconnectUser closes every connection of a user. It does not revoke
+	// their tokens, so clients may reconnect.
+	DisconnectUser(ctx context.Context, adminID uuid.UUID, userID uuid.UUID) *wsModel.DisconnectResult
 }
 
 type wsService struct {
 	wsRepo wsRepo.Repository
+	cursorRepo wsRepo.CursorRepository
 	docRepo docRepo.Repository
+	push pushService.Service
+	// cursorTTL is how long cursors are kept after their last move; zero
+	// turns saving them off
+	cursorTTL time.Duration
 	logger *zap.Logger
 }
 
-func NewWSService(wsRepo wsRepo.Repository, docRepo docRepo.Repository, logger *zap.Logger) Service {
+func NewWSService(wsRepo wsRepo.Repository, cursorRepo wsRepo.CursorRepository, docRepo docRepo.Repository, push pushService.Service, logger *zap.Logger) Service {
 	return &wsService{
 		wsRepo: wsRepo,
+		cursorRepo: cursorRepo,
 		docRepo: docRepo,
+		push: push,
+		cursorTTL: viper.GetDuration(config.WS_CURSOR_TTL),
 		logger: logger,
 	}
 }
 
 
-func (s *wsService)	HandleConnection(conn *websocket.Conn, userID uuid.UUID, userName string){
+func (s *wsService)	HandleConnection(ctx context.Context, conn *websocket.Conn, userID uuid.UUID, userName string){
 	clientID := uuid.New().String()
 
-	client := &wsRepo.Client{
-		ID: clientID,
-		UserID: userID,
-		Name: userName,
-		Conn: conn,
-		Send: make(chan []byte, 256),
-	}
+	// The pumps outlive the upgrade request; keep its correlation fields
+	// and tag every later line with the client
+	ctx = logging.With(logging.Detach(ctx), zap.String("client_id", clientID))
+
+	client := wsRepo.NewClient(clientID, userID, userName, conn)
+
+	// Compressed writes are opted into through the hello handshake
+	conn.EnableWriteCompression(false)
 
 	s.wsRepo.RegisterClient(client)
-	s.logger.Info("Websocket client connected",
+	logging.FromContext(ctx, s.logger).Info("Websocket client connected",
 		zap.String("clientID", clientID),
 		zap.String("userID", userID.String()),
 		zap.String("userName", userName))
 	
-	go s.readPump(client)
-	go s.writePump(client)
+	go s.readPump(ctx, client)
+	go s.writePump(ctx, client)
+
+}
+
+// recoverPump must be deferred directly by a pump. A panic is logged and
+// counted, and the client is unregistered and its connection closed so the
+// other pump exits too; a panicking connection never takes down the server.
+func (s *wsService) recoverPump(ctx context.Context, pump string, client *wsRepo.Client) {
+	r := recover()
+	if r == nil {
+		return
+	}
 
+	PumpPanics.Add(pump, 1)
+	logging.FromContext(ctx, s.logger).Error("Recovered from panic in WebSocket pump",
+		zap.String("pump", pump),
+		zap.String("clientID", client.ID),
+		zap.String("panic", fmt.Sprint(r)),
+		zap.StackSkip("stack", 1))
+
+	s.wsRepo.UnregisterClient(client)
+	client.Conn.Close()
 }
 
-func (s *wsService) readPump(client *wsRepo.Client) {
+func (s *wsService) readPump(ctx context.Context, client *wsRepo.Client) {
 	defer func() {
 		s.wsRepo.UnregisterClient(client)
 		client.Conn.Close()
-		s.logger.Info("WebSocket client disconnected", 
+		logging.FromContext(ctx, s.logger).Info("WebSocket client disconnected", 
 			zap.String("clientID", client.ID))
 	}()
+	defer s.recoverPump(ctx, "read", client)
 	
 	client.Conn.SetReadLimit(4096) // Max message size
 	client.Conn.SetReadDeadline(time.Now().Add(60 * time.Second))
@@ -89,60 +151,98 @@ func (s *wsService) readPump(client *wsRepo.Client) {
 		_, message, err := client.Conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				s.logger.Error("WebSocket error", zap.Error(err))
+				logging.FromContext(ctx, s.logger).Error("WebSocket error", zap.Error(err))
 			}
 			break
 		}
+		s.wsRepo.RecordReceived()
 		
 		var baseMsg wsModel.BaseMessage
 		if err := json.Unmarshal(message, &baseMsg); err != nil {
-			s.logger.Error("Failed to parse WebSocket message", zap.Error(err))
+			logging.FromContext(ctx, s.logger).Warn("Failed to parse WebSocket message", zap.Error(err))
+			s.sendError(client, &ValidationError{Message: "malformed JSON"})
 			continue
 		}
 		
-		if err := s.ProcessMessage(context.Background(), client.ID, client.UserID, string(baseMsg.Type), message); err != nil {
-			s.logger.Error("Failed to process WebSocket message", 
-				zap.Error(err),
-				zap.String("messageType", string(baseMsg.Type)))
-			
-			errorMsg := wsModel.ErrorMessage{
-				BaseMessage: wsModel.BaseMessage{Type: wsModel.MessageTypeError},
-				Code:        "error",
-				Message:     err.Error(),
-			}
-			
-			if errorBytes, err := json.Marshal(errorMsg); err == nil {
-				client.Send <- errorBytes
+		if err := s.ProcessMessage(ctx, client.ID, client.UserID, string(baseMsg.Type), message); err != nil {
+			var validationErr *ValidationError
+			if errors.As(err, &validationErr) {
+				logging.FromContext(ctx, s.logger).Warn("Rejected invalid WebSocket message",
+					zap.Error(err),
+					zap.String("messageType", string(baseMsg.Type)))
+			} else {
+				logging.FromContext(ctx, s.logger).Error("Failed to process WebSocket message", 
+					zap.Error(err),
+					zap.String("messageType", string(baseMsg.Type)))
 			}
+
+			s.sendError(client, err)
 		}
 	}
 }
 
-func (s *wsService) writePump(client *wsRepo.Client) {
+// sendError reports a message processing error to the client
+func (s *wsService) sendError(client *wsRepo.Client, err error) {
+	errorMsg := wsModel.ErrorMessage{
+		BaseMessage: wsModel.BaseMessage{Type: wsModel.MessageTypeError},
+		Code:        errorCode(err),
+		Message:     err.Error(),
+	}
+
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		errorMsg.Field = validationErr.Field
+	}
+
+	if errorBytes, err := json.Marshal(errorMsg); err == nil {
+		reply(client, errorBytes)
+	}
+}
+
+// reply queues a response to the client's own message. It waits for room
+// in the send buffer, unless the client is closed meanwhile.
+func reply(client *wsRepo.Client, message []byte) {
+	select {
+	case client.Send <- message:
+	case <-client.Done():
+	}
+}
+
+func (s *wsService) writePump(ctx context.Context, client *wsRepo.Client) {
 	ticker := time.NewTicker(45 *time.Second)
 	defer func ()  {
 		ticker.Stop()
 		client.Conn.Close()
 	}()
+	defer s.recoverPump(ctx, "write", client)
 
 	for {
 		select {
-		case message, ok := <- client.Send:
+		case message := <- client.Send:
 			client.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
-			if !ok {
-				client.Conn.WriteMessage(websocket.CloseMessage, []byte{})
+			client.Conn.EnableWriteCompression(client.Supports(wsModel.CapabilityCompression))
+			if err := client.Conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				logging.FromContext(ctx, s.logger).Error("Failed to write websocket message", zap.Error(err))
 				return
 			}
 
-			if err := client.Conn.WriteMessage(websocket.TextMessage, message); err != nil {
-				s.logger.Error("Failed to write websocket message", zap.Error(err))
-				return
+		case <- client.Done():
+			// Flush what was queued before the client was closed, such as
+			// the notice DisconnectUser sends
+			client.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			client.Conn.EnableWriteCompression(client.Supports(wsModel.CapabilityCompression))
+			for queued := len(client.Send); queued > 0; queued-- {
+				if err := client.Conn.WriteMessage(websocket.TextMessage, <-client.Send); err != nil {
+					return
+				}
 			}
+			client.Conn.WriteMessage(websocket.CloseMessage, []byte{})
+			return
 		
 		case <- ticker.C:
 			client.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
 			if err := client.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
-				s.logger.Error("Failed to write ping message", zap.Error(err))
+				logging.FromContext(ctx, s.logger).Error("Failed to write ping message", zap.Error(err))
 				return
 			}
 		}
@@ -151,13 +251,21 @@ func (s *wsService) writePump(client *wsRepo.Client) {
 
 
 func (s *wsService)	ProcessMessage(ctx context.Context, clientID string, userID uuid.UUID, messageType string, data []byte) error{
+	if err := validateMessage(wsModel.MessageType(messageType), data); err != nil {
+		return err
+	}
+
 	switch messageType {
+	case string(wsModel.MessageTypeHello):
+		return s.handleHello(ctx, clientID, data)
 	case string(wsModel.MessageTypeSubscribe):
 		return s.handleSubscribe(ctx, clientID, userID, data)
 	case string(wsModel.MessageTypeCursor):
 		return s.handleCursor(ctx, clientID, userID, data)
 	case string(wsModel.MessageTypePing):
 		return s.handlePing(ctx, clientID, data)
+	case string(wsModel.MessageTypeSettings):
+		return s.handleSettings(ctx, clientID, data)
 	default:
 		return ErrInvalidMessageType
 	}
@@ -177,14 +285,84 @@ func (s *wsService) handleSubscribe(ctx context.Context, clientID string, userID
 		return ErrUnauthorized
 	}
 
+	// Subscribers receive content changes, so the terms apply as they do
+	// to reads
+	document, err := s.docRepo.GetDocumentByID(ctx, message.DocumentID)
+	if err != nil {
+		return err
+	}
+	if document != nil {
+		if err := s.checkTerms(ctx, document, userID); err != nil {
+			return err
+		}
+	}
+
 	s.wsRepo.Subscribe(message.DocumentID, clientID)
-	s.logger.Info("Client subscribed to document",
+	logging.FromContext(ctx, s.logger).Info("Client subscribed to document",
 		zap.String("clientID", clientID),
 		zap.String("documentID", message.DocumentID.String()))
+
+	s.sendSavedCursors(ctx, clientID, message.DocumentID)
 	
 	return nil
 }
 
+// checkTerms refuses userID until they accept the document's terms;
+// owners, co-owners included, never need to
+func (s *wsService) checkTerms(ctx context.Context, document *model.Document, userID uuid.UUID) error {
+	if !document.RequiresTerms(userID) {
+		return nil
+	}
+
+	owner, err := s.docRepo.IsDocumentOwner(ctx, document.ID, userID)
+	if err != nil {
+		return err
+	}
+	if owner {
+		return nil
+	}
+
+	acceptance, err := s.docRepo.GetTermsAcceptance(ctx, document.ID, userID, *document.TermsHash)
+	if err != nil {
+		return err
+	}
+	if acceptance == nil {
+		return ErrTermsNotAccepted
+	}
+	return nil
+}
+
+// sendSavedCursors sends a new subscriber the last known cursors in the
+// document, unless it is in focus mode. Failing to load them does not fail
+// the subscription.
+func (s *wsService) sendSavedCursors(ctx context.Context, clientID string, documentID uuid.UUID) {
+	if s.cursorTTL <= 0 {
+		return
+	}
+
+	client := s.wsRepo.GetClient(clientID)
+	if client == nil || client.Focused() {
+		return
+	}
+
+	cursors, err := s.cursorRepo.GetCursors(ctx, documentID, time.Now().Add(-s.cursorTTL))
+	if err != nil {
+		return
+	}
+
+	response, err := json.Marshal(wsModel.CursorsMessage{
+		BaseMessage: wsModel.BaseMessage{Type: wsModel.MessageTypeCursors},
+		DocumentID:  documentID,
+		Cursors:     cursors,
+	})
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to marshal cursors message", zap.Error(err))
+		return
+	}
+
+	reply(client, response)
+}
+
 func (s *wsService) handleCursor(ctx context.Context, clientID string, userID uuid.UUID, data []byte) error {
 	var message wsModel.CursorMessage
 	if err := json.Unmarshal(data, &message); err != nil {
@@ -200,8 +378,118 @@ func (s *wsService) handleCursor(ctx context.Context, clientID string, userID uu
 		return ErrUnauthorized
 	}
 
+	// Cursors are attributed to the connection's user, whatever the
+	// client sent
+	message.User.ID = userID
+	if client := s.wsRepo.GetClient(clientID); client != nil {
+		message.User.Name = client.Name
+	}
+
 	s.wsRepo.BroadcastCursorPosition(message.DocumentID, message)
 
+	// A cursor that could not be saved still reached the live
+	// subscribers, so saving is best effort
+	if s.cursorTTL > 0 {
+		_ = s.cursorRepo.SaveCursor(ctx, message.DocumentID, &wsModel.SavedCursor{
+			UserID:    userID,
+			Name:      message.User.Name,
+			Color:     message.User.Color,
+			Position:  message.Position,
+			Scroll:    message.Scroll,
+			UpdatedAt: time.Now(),
+		}, s.cursorTTL)
+	}
+
+	return nil
+}
+
+// handleHello negotiates the protocol version and capabilities. A client
+// newer than the server is answered with the server's version and is
+// expected to downgrade; a client older than MinProtocolVersion is rejected.
+func (s *wsService) handleHello(ctx context.Context, clientID string, data []byte) error {
+	var message wsModel.HelloMessage
+	if err := json.Unmarshal(data, &message); err != nil {
+		return err
+	}
+
+	if message.ProtocolVersion < wsModel.MinProtocolVersion {
+		return ErrUnsupportedProtocolVersion
+	}
+
+	client := s.wsRepo.GetClient(clientID)
+	if client == nil {
+		return nil
+	}
+
+	version := message.ProtocolVersion
+	if version > wsModel.ProtocolVersion {
+		version = wsModel.ProtocolVersion
+	}
+
+	capabilities := make([]wsModel.Capability, 0, len(wsModel.ServerCapabilities))
+	for _, supported := range wsModel.ServerCapabilities {
+		for _, requested := range message.Capabilities {
+			if requested == supported {
+				capabilities = append(capabilities, supported)
+				break
+			}
+		}
+	}
+
+	client.SetProtocol(version, capabilities)
+
+	welcome := wsModel.WelcomeMessage{
+		BaseMessage:        wsModel.BaseMessage{Type: wsModel.MessageTypeWelcome},
+		ClientID:           clientID,
+		ProtocolVersion:    version,
+		MinProtocolVersion: wsModel.MinProtocolVersion,
+		Capabilities:       capabilities,
+	}
+
+	response, err := json.Marshal(welcome)
+	if err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx, s.logger).Debug("WebSocket protocol negotiated",
+		zap.Int("protocolVersion", version),
+		zap.Any("capabilities", capabilities))
+
+	reply(client, response)
+	return nil
+}
+
+// handleSettings applies connection preferences and answers with the
+// settings in effect
+func (s *wsService) handleSettings(ctx context.Context, clientID string, data []byte) error {
+	var message wsModel.SettingsMessage
+	if err := json.Unmarshal(data, &message); err != nil {
+		return err
+	}
+
+	client := s.wsRepo.GetClient(clientID)
+	if client == nil {
+		return nil
+	}
+
+	if message.Focus != nil {
+		client.SetFocused(*message.Focus)
+	}
+
+	focused := client.Focused()
+	response, err := json.Marshal(wsModel.SettingsMessage{
+		BaseMessage: wsModel.BaseMessage{Type: wsModel.MessageTypeSettings},
+		Focus:       &focused,
+	})
+	if err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx, s.logger).Debug("WebSocket settings changed",
+		zap.String("clientID", clientID),
+		zap.Bool("focus", focused))
+
+	reply(client, response)
 	return nil
 }
 
@@ -217,19 +505,15 @@ func (s *wsService) handlePing(ctx context.Context, clientID string, data []byte
 		return err
 	}
 
-	clients := s.wsRepo.GetClients()
-	for _, client := range clients {
-		if client.ID == clientID {
-			client.Send <- response
-			break
-		}
+	if client := s.wsRepo.GetClient(clientID); client != nil {
+		reply(client, response)
 	}
 
 	return nil
 }
 
 
-func (s *wsService)	BroadcastDocumentUpdate(ctx context.Context, documentID uuid.UUID, userID uuid.UUID, userName string, version int, patches []wsModel.JSONPatchOperation) error{
+func (s *wsService)	BroadcastDocumentUpdate(ctx context.Context, documentID uuid.UUID, originClientID string, userID uuid.UUID, userName string, version int, patches []wsModel.JSONPatchOperation) error{
 	message := wsModel.UpdateMessage{
 		BaseMessage: wsModel.BaseMessage{
 			Type: wsModel.MessageTypeUpdate,
@@ -245,6 +529,7 @@ func (s *wsService)	BroadcastDocumentUpdate(ctx context.Context, documentID uuid
 			Name: userName,
 		},
 		Timestamp: time.Now(),
+		ClientID: originClientID,
 	}
 
 	data, err := json.Marshal(message)
@@ -252,19 +537,114 @@ func (s *wsService)	BroadcastDocumentUpdate(ctx context.Context, documentID uuid
 		return err
 	}
 
-	var excludeClientID string
-	clients := s.wsRepo.GetClients()
-	for _, client := range clients {
-		if client.UserID == userID {
-			excludeClientID = client.ID
-			break
-		}
+	s.wsRepo.BroadcastToDocument(documentID, data, originClientID)
+	
+	return nil
+
+}
+
+func (s *wsService) BroadcastReaction(ctx context.Context, message wsModel.ReactionMessage) error {
+	message.Type = wsModel.MessageTypeReaction
+	message.Timestamp = time.Now()
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
 	}
 
-	s.wsRepo.BroadcastToDocument(documentID, data, excludeClientID)
-	
+	s.wsRepo.BroadcastToDocument(message.DocumentID, data, "")
+
 	return nil
+}
+
+func (s *wsService) NotifyUser(ctx context.Context, userID uuid.UUID, event string, data interface{}) error {
+	if len(s.wsRepo.GetClientsByUser(userID)) == 0 {
+		s.push.Notify(ctx, userID, event, data)
+		return nil
+	}
+
+	message := wsModel.NotificationMessage{
+		BaseMessage: wsModel.BaseMessage{Type: wsModel.MessageTypeNotification},
+		Event:       event,
+		Data:        data,
+		Timestamp:   time.Now(),
+	}
+
+	payload, err := json.Marshal(message)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to marshal notification", zap.Error(err))
+		return err
+	}
 
+	s.wsRepo.SendToUser(userID, payload)
+
+	return nil
 }
 
+func (s *wsService) Stats() wsModel.HubStats {
+	return s.wsRepo.Stats()
+}
+
+func (s *wsService) BroadcastSystemMessage(ctx context.Context, adminID uuid.UUID, message string) (*wsModel.BroadcastResult, error) {
+	payload, err := json.Marshal(wsModel.SystemMessage{
+		BaseMessage: wsModel.BaseMessage{Type: wsModel.MessageTypeSystem},
+		Message:     message,
+		Timestamp:   time.Now(),
+	})
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to marshal system message", zap.Error(err))
+		return nil, err
+	}
 
+	recipients := s.wsRepo.BroadcastToAll(payload)
+	logging.FromContext(ctx, s.logger).Info("Admin broadcast system message",
+		zap.String("audit", "ws_broadcast"),
+		zap.String("adminID", adminID.String()),
+		zap.String("message", message),
+		zap.Int("recipients", recipients))
+
+	return &wsModel.BroadcastResult{Recipients: recipients}, nil
+}
+
+func (s *wsService) DisconnectUser(ctx context.Context, adminID uuid.UUID, userID uuid.UUID) *wsModel.DisconnectResult {
+	payload, err := json.Marshal(wsModel.SystemMessage{
+		BaseMessage: wsModel.BaseMessage{Type: wsModel.MessageTypeSystem},
+		Message:     "You have been disconnected by an administrator",
+		Timestamp:   time.Now(),
+	})
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to marshal system message", zap.Error(err))
+		payload = nil
+	}
+
+	sessions := s.wsRepo.DisconnectUser(userID, payload)
+	logging.FromContext(ctx, s.logger).Info("Admin disconnected user",
+		zap.String("audit", "ws_disconnect"),
+		zap.String("adminID", adminID.String()),
+		zap.String("userID", userID.String()),
+		zap.Int("sessions", sessions))
+
+	return &wsModel.DisconnectResult{Sessions: sessions}
+}
+
+// errorCode maps a message processing error to its stable error code
+func errorCode(err error) errcode.Code {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var validationErr *ValidationError
+
+	switch {
+	case errors.Is(err, ErrInvalidMessageType):
+		return errcode.InvalidMessageType
+	case errors.Is(err, ErrUnsupportedProtocolVersion):
+		return errcode.UnsupportedProtocolVersion
+	case errors.Is(err, ErrTermsNotAccepted):
+		return errcode.TermsNotAccepted
+	case errors.Is(err, ErrUnauthorized):
+		return errcode.Forbidden
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr), errors.As(err, &validationErr):
+		return errcode.ValidationError
+	default:
+		return errcode.InternalError
+	}
+}
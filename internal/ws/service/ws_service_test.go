@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"expvar"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+
+	wsRepo "github.com/hafiztri123/document-api/internal/ws/repository"
+)
+
+// panicConn is a connection whose reads or writes panic once armed
+type panicConn struct {
+	net.Conn
+	panicRead  atomic.Bool
+	panicWrite atomic.Bool
+	closed     atomic.Bool
+}
+
+func (c *panicConn) Read(b []byte) (int, error) {
+	if c.panicRead.Load() {
+		panic("injected read panic")
+	}
+	return c.Conn.Read(b)
+}
+
+func (c *panicConn) Write(b []byte) (int, error) {
+	if c.panicWrite.Load() {
+		panic("injected write panic")
+	}
+	return c.Conn.Write(b)
+}
+
+func (c *panicConn) Close() error {
+	c.closed.Store(true)
+	return c.Conn.Close()
+}
+
+// dialPanicConn connects a WebSocket to a server that holds the connection
+// open, returning it with the connection underneath and the text messages
+// the server receives, closed once the client closes
+func dialPanicConn(t *testing.T) (*websocket.Conn, *panicConn, <-chan []byte) {
+	t.Helper()
+
+	received := make(chan []byte, 16)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		defer close(received)
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			received <- message
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	var underlying *panicConn
+	dialer := websocket.Dialer{
+		NetDialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := (&net.Dialer{}).DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			underlying = &panicConn{Conn: conn}
+			return underlying, nil
+		},
+	}
+
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn, underlying, received
+}
+
+func pumpPanics(pump string) int64 {
+	if count, ok := PumpPanics.Get(pump).(*expvar.Int); ok {
+		return count.Value()
+	}
+	return 0
+}
+
+func TestPumpPanicUnregistersClient(t *testing.T) {
+	tests := []struct {
+		pump string
+		run  func(s *wsService, ctx context.Context, client *wsRepo.Client)
+		arm  func(conn *panicConn, client *wsRepo.Client)
+	}{
+		{
+			pump: "read",
+			run:  (*wsService).readPump,
+			arm: func(conn *panicConn, client *wsRepo.Client) {
+				conn.panicRead.Store(true)
+			},
+		},
+		{
+			pump: "write",
+			run:  (*wsService).writePump,
+			arm: func(conn *panicConn, client *wsRepo.Client) {
+				conn.panicWrite.Store(true)
+				client.Send <- []byte(`{"type":"pong"}`)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pump, func(t *testing.T) {
+			logger := zap.NewNop()
+			repo := wsRepo.NewWSRepository(logger)
+			s := &wsService{wsRepo: repo, logger: logger}
+
+			conn, underlying, _ := dialPanicConn(t)
+			client := wsRepo.NewClient(uuid.New().String(), uuid.New(), "Test User", conn)
+			documentID := uuid.New()
+			repo.RegisterClient(client)
+			repo.Subscribe(documentID, client.ID)
+
+			before := pumpPanics(tt.pump)
+			tt.arm(underlying, client)
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				tt.run(s, context.Background(), client)
+			}()
+
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("pump did not return after panicking")
+			}
+
+			if repo.GetClient(client.ID) != nil {
+				t.Error("client is still registered")
+			}
+			if clients := repo.GetClientsByUser(client.UserID); len(clients) != 0 {
+				t.Errorf("user still has %d clients", len(clients))
+			}
+			if subscribers := repo.GetSubscribers(documentID); len(subscribers) != 0 {
+				t.Errorf("document still has %d subscribers", len(subscribers))
+			}
+			if !underlying.closed.Load() {
+				t.Error("connection was not closed")
+			}
+			if got := pumpPanics(tt.pump) - before; got != 1 {
+				t.Errorf("PumpPanics[%q] increased by %d, want 1", tt.pump, got)
+			}
+		})
+	}
+}
+
+func TestWritePumpFlushesOnClose(t *testing.T) {
+	logger := zap.NewNop()
+	repo := wsRepo.NewWSRepository(logger)
+	s := &wsService{wsRepo: repo, logger: logger}
+
+	conn, _, received := dialPanicConn(t)
+	client := wsRepo.NewClient(uuid.New().String(), uuid.New(), "Test User", conn)
+	repo.RegisterClient(client)
+
+	notice := []byte(`{"type":"system"}`)
+	if sessions := repo.DisconnectUser(client.UserID, notice); sessions != 1 {
+		t.Fatalf("DisconnectUser closed %d sessions, want 1", sessions)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.writePump(context.Background(), client)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("write pump did not return after the client was closed")
+	}
+
+	select {
+	case message, ok := <-received:
+		if !ok || string(message) != string(notice) {
+			t.Errorf("server received %q, want the notice queued before closing", message)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server received nothing")
+	}
+}
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_webhook_deliveries_created_at;
+DROP INDEX IF EXISTS idx_webhook_deliveries_webhook_id;
+DROP INDEX IF EXISTS idx_webhooks_owner_id;
+
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
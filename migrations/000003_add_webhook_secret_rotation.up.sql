@@ -0,0 +1,2 @@
+ALTER TABLE webhooks ADD COLUMN previous_secret VARCHAR(255);
+ALTER TABLE webhooks ADD COLUMN previous_secret_expires_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,6 @@
+UPDATE collaborators SET permission = 'read' WHERE permission = 'viewer';
+UPDATE collaborators SET permission = 'write' WHERE permission IN ('editor', 'admin');
+
+ALTER TABLE collaborators DROP CONSTRAINT IF EXISTS collaborators_permission_check;
+ALTER TABLE collaborators ADD CONSTRAINT collaborators_permission_check
+    CHECK (permission IN ('read', 'write'));
@@ -0,0 +1,6 @@
+-- Accept roles alongside the legacy read/write values. Existing rows are
+-- left untouched and translated on read; run `migrate -backfill-roles` to
+-- rewrite them.
+ALTER TABLE collaborators DROP CONSTRAINT IF EXISTS collaborators_permission_check;
+ALTER TABLE collaborators ADD CONSTRAINT collaborators_permission_check
+    CHECK (permission IN ('read', 'write', 'viewer', 'editor', 'admin'));
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_documents_folder_id;
+ALTER TABLE documents DROP COLUMN IF EXISTS folder_id;
+
+DROP TABLE IF EXISTS folders;
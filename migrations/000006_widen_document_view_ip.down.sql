@@ -0,0 +1,3 @@
+-- Hashed addresses do not fit the original column and cannot be reversed
+UPDATE document_views SET ip_address = NULL WHERE LENGTH(ip_address) > 45;
+ALTER TABLE document_views ALTER COLUMN ip_address TYPE VARCHAR(45);
@@ -0,0 +1,3 @@
+-- Room for salted IP hashes when analytics.hash_ips is enabled. Existing
+-- rows keep their raw addresses; run `migrate -hash-ips` to hash them.
+ALTER TABLE document_views ALTER COLUMN ip_address TYPE VARCHAR(64);
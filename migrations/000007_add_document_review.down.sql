@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_documents_stale_at;
+DROP INDEX IF EXISTS idx_documents_review_by;
+
+ALTER TABLE documents DROP COLUMN IF EXISTS stale_at;
+ALTER TABLE documents DROP COLUMN IF EXISTS review_by;
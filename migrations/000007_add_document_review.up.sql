@@ -0,0 +1,6 @@
+-- Review schedule; stale_at is set by the review scan once review_by passes
+ALTER TABLE documents ADD COLUMN review_by TIMESTAMP WITH TIME ZONE;
+ALTER TABLE documents ADD COLUMN stale_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_documents_review_by ON documents(review_by) WHERE stale_at IS NULL;
+CREATE INDEX idx_documents_stale_at ON documents(stale_at);
@@ -0,0 +1,15 @@
+-- Create templates table; public templates form the shared gallery
+CREATE TABLE templates (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    title VARCHAR(255) NOT NULL,
+    content TEXT,
+    is_public BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_templates_owner_id ON templates(owner_id);
+CREATE INDEX idx_templates_is_public ON templates(is_public) WHERE is_public;
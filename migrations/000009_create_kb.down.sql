@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS kb_redirects;
+DROP TABLE IF EXISTS kb_pages;
+DROP TABLE IF EXISTS kb_spaces;
@@ -0,0 +1,34 @@
+-- Knowledge base spaces; the slug is the first segment of published URLs
+CREATE TABLE kb_spaces (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    slug VARCHAR(100) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_kb_spaces_owner_id ON kb_spaces(owner_id);
+
+-- Published documents, at most one page per document
+CREATE TABLE kb_pages (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    space_id UUID NOT NULL REFERENCES kb_spaces(id) ON DELETE CASCADE,
+    document_id UUID NOT NULL UNIQUE REFERENCES documents(id) ON DELETE CASCADE,
+    slug VARCHAR(100) NOT NULL,
+    published_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE(space_id, slug)
+);
+
+-- Previous addresses of renamed or moved pages
+CREATE TABLE kb_redirects (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    space_id UUID NOT NULL REFERENCES kb_spaces(id) ON DELETE CASCADE,
+    slug VARCHAR(100) NOT NULL,
+    page_id UUID NOT NULL REFERENCES kb_pages(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE(space_id, slug)
+);
+
+CREATE INDEX idx_kb_redirects_page_id ON kb_redirects(page_id);
@@ -0,0 +1,2 @@
+-- Headings with their stable anchors; computed lazily for older documents
+ALTER TABLE documents ADD COLUMN outline JSONB;
@@ -0,0 +1,4 @@
+-- Archived documents are hidden from default listings
+ALTER TABLE documents ADD COLUMN archived_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_documents_archived_at ON documents(archived_at);
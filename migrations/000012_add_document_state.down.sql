@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_documents_state;
+ALTER TABLE documents DROP COLUMN IF EXISTS state;
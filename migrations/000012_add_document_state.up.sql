@@ -0,0 +1,6 @@
+-- Lifecycle state: draft, in_review, published or archived
+ALTER TABLE documents ADD COLUMN state VARCHAR(20) NOT NULL DEFAULT 'draft';
+
+UPDATE documents SET state = 'archived' WHERE archived_at IS NOT NULL;
+
+CREATE INDEX idx_documents_state ON documents(state);
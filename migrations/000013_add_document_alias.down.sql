@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS document_alias_redirects;
+DROP INDEX IF EXISTS idx_documents_alias;
+ALTER TABLE documents DROP COLUMN IF EXISTS alias;
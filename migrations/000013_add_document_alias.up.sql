@@ -0,0 +1,14 @@
+-- Vanity aliases, resolvable at /d/:alias
+ALTER TABLE documents ADD COLUMN alias VARCHAR(64);
+
+-- Deleted documents give up their alias
+CREATE UNIQUE INDEX idx_documents_alias ON documents(alias) WHERE deleted_at IS NULL;
+
+-- Aliases a document has given up keep redirecting to it
+CREATE TABLE document_alias_redirects (
+    alias VARCHAR(64) PRIMARY KEY,
+    document_id UUID NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_document_alias_redirects_document_id ON document_alias_redirects(document_id);
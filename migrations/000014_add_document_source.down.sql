@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_documents_owner_source_path;
+ALTER TABLE documents DROP COLUMN IF EXISTS source_revision;
+ALTER TABLE documents DROP COLUMN IF EXISTS source_path;
@@ -0,0 +1,5 @@
+-- Documents synced from a repository are upserted by an owner-scoped path
+ALTER TABLE documents ADD COLUMN source_path VARCHAR(512);
+ALTER TABLE documents ADD COLUMN source_revision VARCHAR(64);
+
+CREATE UNIQUE INDEX idx_documents_owner_source_path ON documents(owner_id, source_path) WHERE deleted_at IS NULL;
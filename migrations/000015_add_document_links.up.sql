@@ -0,0 +1,9 @@
+-- Links between documents, parsed from content on save
+CREATE TABLE document_links (
+    source_id UUID NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    target_id UUID NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (source_id, target_id)
+);
+
+CREATE INDEX idx_document_links_target_id ON document_links(target_id);
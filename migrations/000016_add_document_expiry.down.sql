@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_documents_expires_at;
+
+ALTER TABLE documents DROP COLUMN IF EXISTS expiry_notified_at;
+ALTER TABLE documents DROP COLUMN IF EXISTS expiry_action;
+ALTER TABLE documents DROP COLUMN IF EXISTS expires_at;
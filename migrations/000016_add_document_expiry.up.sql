@@ -0,0 +1,7 @@
+-- Expiry policy; the expiry scan notifies the owner ahead of expires_at,
+-- then archives or deletes the document
+ALTER TABLE documents ADD COLUMN expires_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE documents ADD COLUMN expiry_action VARCHAR(20) NOT NULL DEFAULT 'archive';
+ALTER TABLE documents ADD COLUMN expiry_notified_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_documents_expires_at ON documents(expires_at) WHERE expires_at IS NOT NULL;
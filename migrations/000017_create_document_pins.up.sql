@@ -0,0 +1,7 @@
+-- Documents each user pinned to the top of their document list
+CREATE TABLE document_pins (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    document_id UUID NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, document_id)
+);
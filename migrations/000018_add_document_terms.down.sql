@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS document_terms_acceptances;
+
+ALTER TABLE documents DROP COLUMN IF EXISTS terms_hash;
+ALTER TABLE documents DROP COLUMN IF EXISTS terms;
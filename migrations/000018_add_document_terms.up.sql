@@ -0,0 +1,13 @@
+-- Terms other users must accept before reading a document; terms_hash
+-- identifies the text, so changing it asks everyone to accept again
+ALTER TABLE documents ADD COLUMN terms TEXT;
+ALTER TABLE documents ADD COLUMN terms_hash VARCHAR(64);
+
+CREATE TABLE document_terms_acceptances (
+    document_id UUID NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    terms_hash VARCHAR(64) NOT NULL,
+    ip_address VARCHAR(45) NOT NULL,
+    accepted_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (document_id, user_id, terms_hash)
+);
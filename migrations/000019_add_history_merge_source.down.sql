@@ -0,0 +1,2 @@
+ALTER TABLE document_histories DROP COLUMN IF EXISTS summary;
+ALTER TABLE document_histories DROP COLUMN IF EXISTS merged_from_id;
@@ -0,0 +1,4 @@
+-- Versions created by merging another document record where the merged
+-- content came from
+ALTER TABLE document_histories ADD COLUMN merged_from_id UUID;
+ALTER TABLE document_histories ADD COLUMN summary VARCHAR(512);
@@ -0,0 +1,9 @@
+-- Users notified when an edit touches a section of a document, identified
+-- by its heading anchor
+CREATE TABLE section_subscriptions (
+    document_id UUID NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    anchor VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (document_id, user_id, anchor)
+);
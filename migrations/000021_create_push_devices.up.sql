@@ -0,0 +1,12 @@
+-- Mobile devices registered for push notifications. A token identifies one
+-- app install, so it belongs to at most one user.
+CREATE TABLE push_devices (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    platform VARCHAR(10) NOT NULL,
+    token VARCHAR(512) NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_push_devices_user_id ON push_devices(user_id);
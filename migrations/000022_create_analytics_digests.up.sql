@@ -0,0 +1,13 @@
+-- Opt-in weekly analytics emails, for one document or, with no
+-- document_id, for every document the user owns
+CREATE TABLE analytics_digests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    document_id UUID REFERENCES documents(id) ON DELETE CASCADE,
+    last_sent_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_analytics_digests_document ON analytics_digests(user_id, document_id) WHERE document_id IS NOT NULL;
+CREATE UNIQUE INDEX idx_analytics_digests_workspace ON analytics_digests(user_id) WHERE document_id IS NULL;
+CREATE INDEX idx_analytics_digests_due ON analytics_digests((COALESCE(last_sent_at, created_at)));
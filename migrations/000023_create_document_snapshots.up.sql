@@ -0,0 +1,13 @@
+-- Immutable published copies of one document version, readable without
+-- authentication
+CREATE TABLE document_snapshots (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    document_id UUID NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    content TEXT NOT NULL,
+    html TEXT NOT NULL,
+    published_by_id UUID NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (document_id, version)
+);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_documents_copied_from_id;
+ALTER TABLE documents DROP COLUMN IF EXISTS copied_from_version;
+ALTER TABLE documents DROP COLUMN IF EXISTS copied_from_id;
@@ -0,0 +1,6 @@
+-- Documents copied from a public document point back at it for
+-- attribution; the link is cleared when the source is purged
+ALTER TABLE documents ADD COLUMN copied_from_id UUID REFERENCES documents(id) ON DELETE SET NULL;
+ALTER TABLE documents ADD COLUMN copied_from_version INTEGER;
+
+CREATE INDEX idx_documents_copied_from_id ON documents(copied_from_id) WHERE copied_from_id IS NOT NULL;
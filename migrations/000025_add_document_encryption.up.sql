@@ -0,0 +1,17 @@
+-- Encrypted documents hold ciphertext produced by their members' clients;
+-- the server stores and relays it without being able to read it
+ALTER TABLE documents ADD COLUMN encrypted BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Key exchange for encrypted documents: each member registers a public
+-- key, and a holder of the document key stores it wrapped to that key
+CREATE TABLE document_keys (
+    document_id UUID NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    algorithm VARCHAR(50) NOT NULL,
+    public_key TEXT NOT NULL,
+    wrapped_key TEXT,
+    wrapped_by_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (document_id, user_id)
+);
@@ -0,0 +1,10 @@
+-- How far each stream of analytics events has been exported to the data
+-- warehouse, as the (time, id) of the last exported event. locked_until
+-- leases a stream to one instance while it exports.
+CREATE TABLE warehouse_checkpoints (
+    stream VARCHAR(64) PRIMARY KEY,
+    last_at TIMESTAMP WITH TIME ZONE,
+    last_id UUID,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
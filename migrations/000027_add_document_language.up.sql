@@ -0,0 +1,2 @@
+-- The BCP 47 language tag of a document's content, e.g. "de" or "pt-BR"
+ALTER TABLE documents ADD COLUMN language VARCHAR(35);
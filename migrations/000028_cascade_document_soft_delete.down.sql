@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_document_edits_deleted_at;
+DROP INDEX IF EXISTS idx_document_views_deleted_at;
+DROP INDEX IF EXISTS idx_collaborators_deleted_at;
+DROP INDEX IF EXISTS idx_document_histories_deleted_at;
+
+ALTER TABLE document_edits DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE document_views DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE collaborators DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE document_histories DROP COLUMN IF EXISTS deleted_at;
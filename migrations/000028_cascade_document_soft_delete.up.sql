@@ -0,0 +1,18 @@
+-- Deleting a document moves its history, collaborators, views and edits to
+-- the trash with it, stamped with the document's deleted_at, so restoring
+-- it brings back exactly what was deleted along with it
+ALTER TABLE document_histories ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE collaborators ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE document_views ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE document_edits ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_document_histories_deleted_at ON document_histories(deleted_at);
+CREATE INDEX idx_collaborators_deleted_at ON collaborators(deleted_at);
+CREATE INDEX idx_document_views_deleted_at ON document_views(deleted_at);
+CREATE INDEX idx_document_edits_deleted_at ON document_edits(deleted_at);
+
+-- Documents already in the trash take their related rows with them
+UPDATE document_histories t SET deleted_at = d.deleted_at FROM documents d WHERE d.id = t.document_id AND d.deleted_at IS NOT NULL;
+UPDATE collaborators t SET deleted_at = d.deleted_at FROM documents d WHERE d.id = t.document_id AND d.deleted_at IS NOT NULL;
+UPDATE document_views t SET deleted_at = d.deleted_at FROM documents d WHERE d.id = t.document_id AND d.deleted_at IS NOT NULL;
+UPDATE document_edits t SET deleted_at = d.deleted_at FROM documents d WHERE d.id = t.document_id AND d.deleted_at IS NOT NULL;
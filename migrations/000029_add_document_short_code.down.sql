@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_documents_short_code;
+ALTER TABLE documents DROP COLUMN IF EXISTS short_code;
@@ -0,0 +1,30 @@
+-- Short codes for dictating or typing a reference to a document. Codes are
+-- never reused, so the index also covers documents in the trash.
+ALTER TABLE documents ADD COLUMN short_code VARCHAR(6);
+CREATE UNIQUE INDEX idx_documents_short_code ON documents(short_code);
+
+-- Existing documents get a random code each, in Crockford's base32
+DO $$
+DECLARE
+    alphabet CONSTANT TEXT := '0123456789ABCDEFGHJKMNPQRSTVWXYZ';
+    doc_id UUID;
+    candidate TEXT;
+BEGIN
+    FOR doc_id IN SELECT id FROM documents WHERE short_code IS NULL LOOP
+        LOOP
+            candidate := '';
+            FOR i IN 1..6 LOOP
+                candidate := candidate || substr(alphabet, 1 + floor(random() * 32)::INTEGER, 1);
+            END LOOP;
+            BEGIN
+                UPDATE documents SET short_code = candidate WHERE id = doc_id;
+                EXIT;
+            EXCEPTION WHEN unique_violation THEN
+                -- Taken by another document; try a new code
+            END;
+        END LOOP;
+    END LOOP;
+END
+$$;
+
+ALTER TABLE documents ALTER COLUMN short_code SET NOT NULL;
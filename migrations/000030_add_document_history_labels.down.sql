@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_document_histories_label;
+ALTER TABLE document_histories DROP COLUMN IF EXISTS label;
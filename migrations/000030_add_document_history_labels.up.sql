@@ -0,0 +1,4 @@
+-- Named versions, e.g. "v1.0 sent to client"; a label names at most one
+-- version of a document
+ALTER TABLE document_histories ADD COLUMN label VARCHAR(100);
+CREATE UNIQUE INDEX idx_document_histories_label ON document_histories(document_id, label) WHERE label IS NOT NULL;
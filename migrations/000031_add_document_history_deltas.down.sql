@@ -0,0 +1,10 @@
+-- Versions stored as deltas have no content of their own and would be lost
+-- with the column
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM document_histories WHERE delta IS NOT NULL) THEN
+        RAISE EXCEPTION 'document_histories has versions stored as deltas';
+    END IF;
+END
+$$;
+ALTER TABLE document_histories DROP COLUMN IF EXISTS delta;
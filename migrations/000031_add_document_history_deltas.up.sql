@@ -0,0 +1,3 @@
+-- History versions between snapshots store a delta from the version before
+-- them instead of their content; a version with no delta is a snapshot
+ALTER TABLE document_histories ADD COLUMN delta TEXT;
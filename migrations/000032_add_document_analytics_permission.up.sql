@@ -0,0 +1,3 @@
+-- The role collaborators need to see a document's analytics; the owner
+-- always can
+ALTER TABLE documents ADD COLUMN analytics_permission VARCHAR(20) NOT NULL DEFAULT 'admin';
@@ -0,0 +1,12 @@
+-- Documents pinned to the top of a folder listing in the order the folder's
+-- owner chose; a null folder_id pins to the owner's workspace listing
+CREATE TABLE folder_pins (
+    owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    folder_id UUID REFERENCES folders(id) ON DELETE CASCADE,
+    document_id UUID NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_folder_pins_folder ON folder_pins(folder_id, document_id) WHERE folder_id IS NOT NULL;
+CREATE UNIQUE INDEX idx_folder_pins_workspace ON folder_pins(owner_id, document_id) WHERE folder_id IS NULL;
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS replication_capture_trigger ON document_histories;
+DROP TRIGGER IF EXISTS replication_capture_trigger ON documents;
+DROP FUNCTION IF EXISTS capture_replication_change();
+DROP TABLE IF EXISTS replication_checkpoints;
+DROP TABLE IF EXISTS replication_changes;
@@ -0,0 +1,49 @@
+-- Change stream for cross-region replication. Rows are only captured in
+-- sessions with document_api.capture_changes on, which the API sets while
+-- replication.publish is configured.
+CREATE TABLE replication_changes (
+    id BIGSERIAL PRIMARY KEY,
+    table_name VARCHAR(64) NOT NULL,
+    operation VARCHAR(10) NOT NULL,
+    row_id UUID NOT NULL,
+    row_data JSONB,
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT clock_timestamp()
+);
+
+-- How far each side of replication got: "publish" in the primary, "apply"
+-- in the secondary
+CREATE TABLE replication_checkpoints (
+    name VARCHAR(64) PRIMARY KEY,
+    last_id BIGINT NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Trigger arguments name columns left out of row_data, such as ones the
+-- secondary derives itself
+CREATE OR REPLACE FUNCTION capture_replication_change() RETURNS trigger AS $$
+BEGIN
+    IF COALESCE(current_setting('document_api.capture_changes', true), '') <> 'on' THEN
+        RETURN NULL;
+    END IF;
+
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO replication_changes (table_name, operation, row_id)
+        VALUES (TG_TABLE_NAME, 'delete', OLD.id);
+    ELSE
+        INSERT INTO replication_changes (table_name, operation, row_id, row_data)
+        VALUES (TG_TABLE_NAME, 'upsert', NEW.id, to_jsonb(NEW) - COALESCE(TG_ARGV, '{}'));
+    END IF;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER replication_capture_trigger
+    AFTER INSERT OR UPDATE OR DELETE ON documents
+    FOR EACH ROW
+    EXECUTE FUNCTION capture_replication_change('content_tsv');
+
+CREATE TRIGGER replication_capture_trigger
+    AFTER INSERT OR UPDATE OR DELETE ON document_histories
+    FOR EACH ROW
+    EXECUTE FUNCTION capture_replication_change();
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS security_event_leases;
+DROP TABLE IF EXISTS security_events;
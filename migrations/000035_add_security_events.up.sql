@@ -0,0 +1,29 @@
+-- Security events streamed to a SIEM, in ID order per user. Delivered
+-- events are kept for siem.retention.
+CREATE TABLE security_events (
+    id BIGSERIAL PRIMARY KEY,
+    type VARCHAR(64) NOT NULL,
+    outcome VARCHAR(16) NOT NULL,
+    user_id UUID,
+    target_type VARCHAR(32),
+    target_id UUID,
+    ip_address VARCHAR(45),
+    user_agent VARCHAR(255),
+    request_id VARCHAR(128),
+    data JSONB,
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_error TEXT
+);
+
+CREATE INDEX idx_security_events_pending ON security_events(user_id, id) WHERE delivered_at IS NULL;
+CREATE INDEX idx_security_events_occurred_at ON security_events(occurred_at);
+
+-- Delivery is leased to one instance at a time
+CREATE TABLE security_event_leases (
+    name VARCHAR(64) PRIMARY KEY,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
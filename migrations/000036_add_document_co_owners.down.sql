@@ -0,0 +1,5 @@
+UPDATE collaborators SET permission = 'admin' WHERE permission = 'owner';
+
+ALTER TABLE collaborators DROP CONSTRAINT IF EXISTS collaborators_permission_check;
+ALTER TABLE collaborators ADD CONSTRAINT collaborators_permission_check
+    CHECK (permission IN ('read', 'write', 'viewer', 'editor', 'admin'));
@@ -0,0 +1,5 @@
+-- Collaborators with the owner role are co-owners, with every right of
+-- the document's owner
+ALTER TABLE collaborators DROP CONSTRAINT IF EXISTS collaborators_permission_check;
+ALTER TABLE collaborators ADD CONSTRAINT collaborators_permission_check
+    CHECK (permission IN ('read', 'write', 'viewer', 'editor', 'admin', 'owner'));
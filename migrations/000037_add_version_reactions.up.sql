@@ -0,0 +1,13 @@
+-- Emoji users leave on a version. Reactions follow their version when a
+-- debounced save moves it and go when compaction deletes it.
+CREATE TABLE version_reactions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    document_id UUID NOT NULL,
+    version INTEGER NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    emoji VARCHAR(32) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (document_id, version) REFERENCES document_histories(document_id, version)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    UNIQUE (document_id, version, user_id, emoji)
+);
@@ -0,0 +1,6 @@
+-- Forks are branches of another document, e.g. to propose changes to it;
+-- the link is cleared when the source is purged
+ALTER TABLE documents ADD COLUMN forked_from_id UUID REFERENCES documents(id) ON DELETE SET NULL;
+ALTER TABLE documents ADD COLUMN forked_from_version INTEGER;
+
+CREATE INDEX idx_documents_forked_from_id ON documents(forked_from_id) WHERE forked_from_id IS NOT NULL;
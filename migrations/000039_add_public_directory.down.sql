@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS directory_listings;
+DROP TABLE IF EXISTS directory_categories;
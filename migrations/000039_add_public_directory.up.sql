@@ -0,0 +1,29 @@
+-- Categories of the public directory. Categories nest; deleting one moves
+-- its children up to its parent.
+CREATE TABLE directory_categories (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    parent_id UUID REFERENCES directory_categories(id) ON DELETE SET NULL,
+    slug VARCHAR(100) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    position INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_directory_categories_parent_id ON directory_categories(parent_id) WHERE parent_id IS NOT NULL;
+
+-- How admins curated a document for the directory. Public documents
+-- without a listing are listed uncategorized.
+CREATE TABLE directory_listings (
+    document_id UUID PRIMARY KEY REFERENCES documents(id) ON DELETE CASCADE,
+    category_id UUID REFERENCES directory_categories(id) ON DELETE SET NULL,
+    featured_at TIMESTAMP WITH TIME ZONE,
+    hidden_at TIMESTAMP WITH TIME ZONE,
+    hidden_reason VARCHAR(255),
+    curated_by_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_directory_listings_category_id ON directory_listings(category_id) WHERE category_id IS NOT NULL;
+CREATE INDEX idx_directory_listings_featured_at ON directory_listings(featured_at) WHERE featured_at IS NOT NULL;
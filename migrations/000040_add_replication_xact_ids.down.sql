@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_replication_changes_xact_id;
+ALTER TABLE replication_checkpoints DROP COLUMN IF EXISTS last_xact_id;
+ALTER TABLE replication_changes DROP COLUMN IF EXISTS xact_id;
@@ -0,0 +1,7 @@
+-- The transaction that captured each change. Changes are published in
+-- (xact_id, id) order once every transaction up to theirs has ended, so
+-- one that commits late cannot land behind the checkpoint.
+ALTER TABLE replication_changes ADD COLUMN xact_id BIGINT NOT NULL DEFAULT pg_current_xact_id()::text::bigint;
+ALTER TABLE replication_checkpoints ADD COLUMN last_xact_id BIGINT NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_replication_changes_xact_id ON replication_changes(xact_id, id);
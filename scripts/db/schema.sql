@@ -55,17 +55,28 @@ CREATE INDEX IF NOT EXISTS idx_document_history_document_id ON document_historie
 CREATE INDEX IF NOT EXISTS idx_document_history_updated_by_id ON document_histories(updated_by_id);
 CREATE INDEX IF NOT EXISTS idx_document_history_updated_at ON document_histories(updated_at);
 
+-- Versions created by merging another document record where the merged
+-- content came from
+ALTER TABLE document_histories ADD COLUMN IF NOT EXISTS merged_from_id UUID;
+ALTER TABLE document_histories ADD COLUMN IF NOT EXISTS summary VARCHAR(512);
+
 -- Create collaborators table
 CREATE TABLE IF NOT EXISTS collaborators (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     document_id UUID NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
     user_id UUID NOT NULL REFERENCES users(id),
-    permission VARCHAR(20) NOT NULL CHECK (permission IN ('read', 'write')),
+    permission VARCHAR(20) NOT NULL CHECK (permission IN ('read', 'write', 'viewer', 'editor', 'admin', 'owner')),
     created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
     UNIQUE (document_id, user_id)
 );
 
+-- Accept roles on databases created before they existed; collaborators
+-- with the owner role are co-owners
+ALTER TABLE collaborators DROP CONSTRAINT IF EXISTS collaborators_permission_check;
+ALTER TABLE collaborators ADD CONSTRAINT collaborators_permission_check
+    CHECK (permission IN ('read', 'write', 'viewer', 'editor', 'admin', 'owner'));
+
 -- Create indexes for collaborators
 CREATE INDEX IF NOT EXISTS idx_collaborators_document_id ON collaborators(document_id);
 CREATE INDEX IF NOT EXISTS idx_collaborators_user_id ON collaborators(user_id);
@@ -87,6 +98,9 @@ CREATE INDEX IF NOT EXISTS idx_document_views_user_id ON document_views(user_id)
 CREATE INDEX IF NOT EXISTS idx_document_views_viewed_at ON document_views(viewed_at);
 CREATE INDEX IF NOT EXISTS idx_document_views_ip_address ON document_views(ip_address);
 
+-- Room for salted IP hashes (analytics.hash_ips)
+ALTER TABLE document_views ALTER COLUMN ip_address TYPE VARCHAR(64);
+
 -- Create document_edits table for analytics
 CREATE TABLE IF NOT EXISTS document_edits (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
@@ -101,6 +115,338 @@ CREATE INDEX IF NOT EXISTS idx_document_edits_document_id ON document_edits(docu
 CREATE INDEX IF NOT EXISTS idx_document_edits_user_id ON document_edits(user_id);
 CREATE INDEX IF NOT EXISTS idx_document_edits_edited_at ON document_edits(edited_at);
 
+-- Create webhooks table
+CREATE TABLE IF NOT EXISTS webhooks (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    owner_id UUID NOT NULL REFERENCES users(id),
+    url VARCHAR(2048) NOT NULL,
+    secret VARCHAR(255) NOT NULL,
+    events JSONB NOT NULL DEFAULT '[]',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS previous_secret VARCHAR(255);
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS previous_secret_expires_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_owner_id ON webhooks(owner_id);
+
+-- Create webhook_deliveries table
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    webhook_id UUID NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event VARCHAR(100) NOT NULL,
+    payload TEXT NOT NULL,
+    response_status INTEGER NOT NULL DEFAULT 0,
+    response_body TEXT,
+    error TEXT,
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    replay_of_id UUID REFERENCES webhook_deliveries(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries(webhook_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_created_at ON webhook_deliveries(created_at);
+
+-- Create folders table
+CREATE TABLE IF NOT EXISTS folders (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    parent_id UUID REFERENCES folders(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_folders_owner_id ON folders(owner_id);
+CREATE INDEX IF NOT EXISTS idx_folders_parent_id ON folders(parent_id);
+
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS folder_id UUID REFERENCES folders(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_documents_folder_id ON documents(folder_id);
+
+-- Review schedule; stale_at is set by the review scan once review_by passes
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS review_by TIMESTAMP WITH TIME ZONE;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS stale_at TIMESTAMP WITH TIME ZONE;
+CREATE INDEX IF NOT EXISTS idx_documents_review_by ON documents(review_by) WHERE stale_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_documents_stale_at ON documents(stale_at);
+
+-- Headings with their stable anchors; computed lazily for older documents
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS outline JSONB;
+
+-- Archived documents are hidden from default listings
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP WITH TIME ZONE;
+CREATE INDEX IF NOT EXISTS idx_documents_archived_at ON documents(archived_at);
+
+-- Lifecycle state: draft, in_review, published or archived
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS state VARCHAR(20) NOT NULL DEFAULT 'draft';
+UPDATE documents SET state = 'archived' WHERE archived_at IS NOT NULL AND state <> 'archived';
+CREATE INDEX IF NOT EXISTS idx_documents_state ON documents(state);
+
+-- Vanity aliases, resolvable at /d/:alias
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS alias VARCHAR(64);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_documents_alias ON documents(alias) WHERE deleted_at IS NULL;
+
+-- Documents synced from a repository are upserted by an owner-scoped path
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS source_path VARCHAR(512);
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS source_revision VARCHAR(64);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_documents_owner_source_path ON documents(owner_id, source_path) WHERE deleted_at IS NULL;
+
+-- Expiry policy; the expiry scan notifies the owner ahead of expires_at,
+-- then archives or deletes the document
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS expiry_action VARCHAR(20) NOT NULL DEFAULT 'archive';
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS expiry_notified_at TIMESTAMP WITH TIME ZONE;
+CREATE INDEX IF NOT EXISTS idx_documents_expires_at ON documents(expires_at) WHERE expires_at IS NOT NULL;
+
+-- Documents copied from a public document point back at it for attribution
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS copied_from_id UUID REFERENCES documents(id) ON DELETE SET NULL;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS copied_from_version INTEGER;
+CREATE INDEX IF NOT EXISTS idx_documents_copied_from_id ON documents(copied_from_id) WHERE copied_from_id IS NOT NULL;
+
+-- Forks point back at the document they branched from
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS forked_from_id UUID REFERENCES documents(id) ON DELETE SET NULL;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS forked_from_version INTEGER;
+CREATE INDEX IF NOT EXISTS idx_documents_forked_from_id ON documents(forked_from_id) WHERE forked_from_id IS NOT NULL;
+
+-- Encrypted documents hold ciphertext the server cannot read
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS encrypted BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- The BCP 47 language tag of a document's content
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS language VARCHAR(35);
+
+-- Related rows go to the trash with their document, stamped with its
+-- deleted_at, and come back when it is restored
+ALTER TABLE document_histories ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE collaborators ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE document_views ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE document_edits ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+CREATE INDEX IF NOT EXISTS idx_document_histories_deleted_at ON document_histories(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_collaborators_deleted_at ON collaborators(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_document_views_deleted_at ON document_views(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_document_edits_deleted_at ON document_edits(deleted_at);
+
+-- Short codes for dictating or typing a reference to a document, in
+-- Crockford's base32; codes are never reused, so the index covers the trash
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS short_code VARCHAR(6);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_documents_short_code ON documents(short_code);
+DO $$
+DECLARE
+    alphabet CONSTANT TEXT := '0123456789ABCDEFGHJKMNPQRSTVWXYZ';
+    doc_id UUID;
+    candidate TEXT;
+BEGIN
+    FOR doc_id IN SELECT id FROM documents WHERE short_code IS NULL LOOP
+        LOOP
+            candidate := '';
+            FOR i IN 1..6 LOOP
+                candidate := candidate || substr(alphabet, 1 + floor(random() * 32)::INTEGER, 1);
+            END LOOP;
+            BEGIN
+                UPDATE documents SET short_code = candidate WHERE id = doc_id;
+                EXIT;
+            EXCEPTION WHEN unique_violation THEN
+                -- Taken by another document; try a new code
+            END;
+        END LOOP;
+    END LOOP;
+END
+$$;
+ALTER TABLE documents ALTER COLUMN short_code SET NOT NULL;
+
+-- Named versions, e.g. "v1.0 sent to client"; a label names at most one
+-- version of a document
+ALTER TABLE document_histories ADD COLUMN IF NOT EXISTS label VARCHAR(100);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_document_histories_label ON document_histories(document_id, label) WHERE label IS NOT NULL;
+
+-- History versions between snapshots store a delta from the version before
+-- them instead of their content; a version with no delta is a snapshot
+ALTER TABLE document_histories ADD COLUMN IF NOT EXISTS delta TEXT;
+
+-- The role collaborators need to see a document's analytics; the owner
+-- always can
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS analytics_permission VARCHAR(20) NOT NULL DEFAULT 'admin';
+
+-- Terms other users must accept before reading a document; terms_hash
+-- identifies the text, so changing it asks everyone to accept again
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS terms TEXT;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS terms_hash VARCHAR(64);
+
+-- Create templates table; public templates form the shared gallery
+CREATE TABLE IF NOT EXISTS templates (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    title VARCHAR(255) NOT NULL,
+    content TEXT,
+    is_public BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_templates_owner_id ON templates(owner_id);
+CREATE INDEX IF NOT EXISTS idx_templates_is_public ON templates(is_public) WHERE is_public;
+
+-- Knowledge base spaces; the slug is the first segment of published URLs
+CREATE TABLE IF NOT EXISTS kb_spaces (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    slug VARCHAR(100) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_kb_spaces_owner_id ON kb_spaces(owner_id);
+
+-- Published documents, at most one page per document
+CREATE TABLE IF NOT EXISTS kb_pages (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    space_id UUID NOT NULL REFERENCES kb_spaces(id) ON DELETE CASCADE,
+    document_id UUID NOT NULL UNIQUE REFERENCES documents(id) ON DELETE CASCADE,
+    slug VARCHAR(100) NOT NULL,
+    published_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE(space_id, slug)
+);
+
+-- Previous addresses of renamed or moved pages
+CREATE TABLE IF NOT EXISTS kb_redirects (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    space_id UUID NOT NULL REFERENCES kb_spaces(id) ON DELETE CASCADE,
+    slug VARCHAR(100) NOT NULL,
+    page_id UUID NOT NULL REFERENCES kb_pages(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE(space_id, slug)
+);
+
+CREATE INDEX IF NOT EXISTS idx_kb_redirects_page_id ON kb_redirects(page_id);
+
+-- Aliases a document has given up keep redirecting to it
+CREATE TABLE IF NOT EXISTS document_alias_redirects (
+    alias VARCHAR(64) PRIMARY KEY,
+    document_id UUID NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_document_alias_redirects_document_id ON document_alias_redirects(document_id);
+
+-- Links between documents, parsed from content on save
+CREATE TABLE IF NOT EXISTS document_links (
+    source_id UUID NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    target_id UUID NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (source_id, target_id)
+);
+CREATE INDEX IF NOT EXISTS idx_document_links_target_id ON document_links(target_id);
+
+-- Documents each user pinned to the top of their document list
+CREATE TABLE IF NOT EXISTS document_pins (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    document_id UUID NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, document_id)
+);
+
+-- Documents pinned to the top of a folder listing in the order the folder's
+-- owner chose; a null folder_id pins to the owner's workspace listing
+CREATE TABLE IF NOT EXISTS folder_pins (
+    owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    folder_id UUID REFERENCES folders(id) ON DELETE CASCADE,
+    document_id UUID NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_folder_pins_folder ON folder_pins(folder_id, document_id) WHERE folder_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_folder_pins_workspace ON folder_pins(owner_id, document_id) WHERE folder_id IS NULL;
+
+-- Acceptances of document terms, kept for every version of the terms
+CREATE TABLE IF NOT EXISTS document_terms_acceptances (
+    document_id UUID NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    terms_hash VARCHAR(64) NOT NULL,
+    ip_address VARCHAR(45) NOT NULL,
+    accepted_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (document_id, user_id, terms_hash)
+);
+
+-- Users notified when an edit touches a section of a document, identified
+-- by its heading anchor
+CREATE TABLE IF NOT EXISTS section_subscriptions (
+    document_id UUID NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    anchor VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (document_id, user_id, anchor)
+);
+
+-- Immutable published copies of one document version, readable without
+-- authentication
+CREATE TABLE IF NOT EXISTS document_snapshots (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    document_id UUID NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    content TEXT NOT NULL,
+    html TEXT NOT NULL,
+    published_by_id UUID NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (document_id, version)
+);
+
+-- Key exchange for encrypted documents: each member registers a public
+-- key, and a holder of the document key stores it wrapped to that key
+CREATE TABLE IF NOT EXISTS document_keys (
+    document_id UUID NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    algorithm VARCHAR(50) NOT NULL,
+    public_key TEXT NOT NULL,
+    wrapped_key TEXT,
+    wrapped_by_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (document_id, user_id)
+);
+
+-- Mobile devices registered for push notifications. A token identifies one
+-- app install, so it belongs to at most one user.
+CREATE TABLE IF NOT EXISTS push_devices (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    platform VARCHAR(10) NOT NULL,
+    token VARCHAR(512) NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_push_devices_user_id ON push_devices(user_id);
+
+-- Opt-in weekly analytics emails, for one document or, with no
+-- document_id, for every document the user owns
+CREATE TABLE IF NOT EXISTS analytics_digests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    document_id UUID REFERENCES documents(id) ON DELETE CASCADE,
+    last_sent_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_analytics_digests_document ON analytics_digests(user_id, document_id) WHERE document_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_analytics_digests_workspace ON analytics_digests(user_id) WHERE document_id IS NULL;
+CREATE INDEX IF NOT EXISTS idx_analytics_digests_due ON analytics_digests((COALESCE(last_sent_at, created_at)));
+
+-- How far each stream of analytics events has been exported to the data
+-- warehouse, as the (time, id) of the last exported event. locked_until
+-- leases a stream to one instance while it exports.
+CREATE TABLE IF NOT EXISTS warehouse_checkpoints (
+    stream VARCHAR(64) PRIMARY KEY,
+    last_at TIMESTAMP WITH TIME ZONE,
+    last_id UUID,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
 -- Create full-text search index for document content (PostgreSQL specific)
 -- This enables efficient searching within documents
 ALTER TABLE documents ADD COLUMN IF NOT EXISTS content_tsv TSVECTOR;
@@ -123,6 +469,137 @@ CREATE TRIGGER tsvector_update_trigger
     FOR EACH ROW
     EXECUTE FUNCTION documents_search_trigger();
 
+-- Change stream for cross-region replication. Rows are only captured in
+-- sessions with document_api.capture_changes on, which the API sets while
+-- replication.publish is configured.
+CREATE TABLE IF NOT EXISTS replication_changes (
+    id BIGSERIAL PRIMARY KEY,
+    table_name VARCHAR(64) NOT NULL,
+    operation VARCHAR(10) NOT NULL,
+    row_id UUID NOT NULL,
+    row_data JSONB,
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT clock_timestamp()
+);
+
+-- How far each side of replication got: "publish" in the primary, "apply"
+-- in the secondary
+CREATE TABLE IF NOT EXISTS replication_checkpoints (
+    name VARCHAR(64) PRIMARY KEY,
+    last_id BIGINT NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- The transaction that captured each change. Changes are published in
+-- (xact_id, id) order once every transaction up to theirs has ended, so
+-- one that commits late cannot land behind the checkpoint.
+ALTER TABLE replication_changes ADD COLUMN IF NOT EXISTS xact_id BIGINT NOT NULL DEFAULT pg_current_xact_id()::text::bigint;
+ALTER TABLE replication_checkpoints ADD COLUMN IF NOT EXISTS last_xact_id BIGINT NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_replication_changes_xact_id ON replication_changes(xact_id, id);
+
+-- Trigger arguments name columns left out of row_data, such as ones the
+-- secondary derives itself
+CREATE OR REPLACE FUNCTION capture_replication_change() RETURNS trigger AS $$
+BEGIN
+    IF COALESCE(current_setting('document_api.capture_changes', true), '') <> 'on' THEN
+        RETURN NULL;
+    END IF;
+
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO replication_changes (table_name, operation, row_id)
+        VALUES (TG_TABLE_NAME, 'delete', OLD.id);
+    ELSE
+        INSERT INTO replication_changes (table_name, operation, row_id, row_data)
+        VALUES (TG_TABLE_NAME, 'upsert', NEW.id, to_jsonb(NEW) - COALESCE(TG_ARGV, '{}'));
+    END IF;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS replication_capture_trigger ON documents;
+CREATE TRIGGER replication_capture_trigger
+    AFTER INSERT OR UPDATE OR DELETE ON documents
+    FOR EACH ROW
+    EXECUTE FUNCTION capture_replication_change('content_tsv');
+
+DROP TRIGGER IF EXISTS replication_capture_trigger ON document_histories;
+CREATE TRIGGER replication_capture_trigger
+    AFTER INSERT OR UPDATE OR DELETE ON document_histories
+    FOR EACH ROW
+    EXECUTE FUNCTION capture_replication_change();
+
+-- Security events streamed to a SIEM, in ID order per user
+CREATE TABLE IF NOT EXISTS security_events (
+    id BIGSERIAL PRIMARY KEY,
+    type VARCHAR(64) NOT NULL,
+    outcome VARCHAR(16) NOT NULL,
+    user_id UUID,
+    target_type VARCHAR(32),
+    target_id UUID,
+    ip_address VARCHAR(45),
+    user_agent VARCHAR(255),
+    request_id VARCHAR(128),
+    data JSONB,
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_error TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_security_events_pending ON security_events(user_id, id) WHERE delivered_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_security_events_occurred_at ON security_events(occurred_at);
+
+CREATE TABLE IF NOT EXISTS security_event_leases (
+    name VARCHAR(64) PRIMARY KEY,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Emoji users leave on a version. Reactions follow their version when a
+-- debounced save moves it and go when compaction deletes it.
+CREATE TABLE IF NOT EXISTS version_reactions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    document_id UUID NOT NULL,
+    version INTEGER NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    emoji VARCHAR(32) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (document_id, version) REFERENCES document_histories(document_id, version)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    UNIQUE (document_id, version, user_id, emoji)
+);
+
+-- Categories of the public directory. Categories nest; deleting one moves
+-- its children up to its parent.
+CREATE TABLE IF NOT EXISTS directory_categories (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    parent_id UUID REFERENCES directory_categories(id) ON DELETE SET NULL,
+    slug VARCHAR(100) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    position INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_directory_categories_parent_id ON directory_categories(parent_id) WHERE parent_id IS NOT NULL;
+
+-- How admins curated a document for the directory. Public documents
+-- without a listing are listed uncategorized.
+CREATE TABLE IF NOT EXISTS directory_listings (
+    document_id UUID PRIMARY KEY REFERENCES documents(id) ON DELETE CASCADE,
+    category_id UUID REFERENCES directory_categories(id) ON DELETE SET NULL,
+    featured_at TIMESTAMP WITH TIME ZONE,
+    hidden_at TIMESTAMP WITH TIME ZONE,
+    hidden_reason VARCHAR(255),
+    curated_by_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_directory_listings_category_id ON directory_listings(category_id) WHERE category_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_directory_listings_featured_at ON directory_listings(featured_at) WHERE featured_at IS NOT NULL;
+
 -- Create views for common analytics queries
 
 -- View for document activity (last 30 days)
@@ -171,7 +648,7 @@ GROUP BY
 CREATE OR REPLACE FUNCTION record_document_view(
     doc_id UUID,
     usr_id UUID,
-    ip VARCHAR(45),
+    ip VARCHAR(64),
     agent VARCHAR(255)
 ) RETURNS VOID AS $$
 BEGIN
@@ -232,12 +709,16 @@ BEGIN
         RETURN FALSE;
     END IF;
     
-    -- For read access, both read and write permissions are sufficient
-    IF required_permission = 'read' THEN
+    -- For read access, any permission is sufficient
+    IF required_permission IN ('read', 'viewer') THEN
         RETURN TRUE;
     END IF;
     
-    -- For write access, only write permission is sufficient
-    RETURN collab_permission = 'write';
+    -- For write access, legacy write or an editing role is required
+    IF required_permission IN ('write', 'editor') THEN
+        RETURN collab_permission IN ('write', 'editor', 'admin', 'owner');
+    END IF;
+    
+    RETURN collab_permission IN ('admin', 'owner');
 END;
 $$ LANGUAGE plpgsql;
\ No newline at end of file
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"flag"
 	"fmt"
 	"log"
@@ -9,8 +11,12 @@ import (
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/hafiztri123/document-api/internal/analytics/privacy"
 	"github.com/hafiztri123/document-api/internal/database"
+	docModel "github.com/hafiztri123/document-api/internal/document/model"
+	"github.com/hafiztri123/document-api/internal/replication/check"
 	"github.com/spf13/viper"
+	"go.uber.org/zap"
 )
 
 func main() {
@@ -23,6 +29,10 @@ func main() {
 	upCmd := flag.Bool("up", false, "Run migrations up")
 	downCmd := flag.Bool("down", false, "Run migrations down")
 	versionCmd := flag.Bool("version", false, "Show current migration version")
+	backfillRolesCmd := flag.Bool("backfill-roles", false, "Rewrite legacy read/write collaborator permissions as roles")
+	hashIPsCmd := flag.Bool("hash-ips", false, "Replace raw IP addresses in document views with salted hashes")
+	checkReplicaCmd := flag.Bool("check-replica", false, "Compare document content and history hashes with a replica region")
+	replicaDSN := flag.String("replica", "", "Connection string of the replica database, for -check-replica")
 	flag.Parse()
 
 	viper.SetConfigName("config")
@@ -33,11 +43,16 @@ func main() {
 		log.Fatalf("Error reading config file: %v", err)
 	}
 
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		log.Fatalf("Error creating logger: %v", err)
+	}
+
 	dsn := fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=require",
 		os.Getenv("PGUSER"),
         os.Getenv("PGPASSWORD"),   // Railway provides PGPASSWORD for PostgreSQL password
         os.Getenv("PGHOST"),       // Railway provides PGHOST for PostgreSQL host
-        database.GetEnvAsInt("PGPORT", 5432), // Default port is 5432 if not set
+        database.GetEnvAsInt("PGPORT", 5432, logger), // Default port is 5432 if not set
 		os.Getenv("PGDATABASE"),
 	)
 
@@ -67,8 +82,139 @@ func main() {
 			log.Fatalf("[ERROR] An error occurred while getting migration version: %v", err)
 		}
 		log.Printf("Current version: %d, Dirty: %v\n", version, dirty)
+	} else if *backfillRolesCmd {
+		if err := backfillRoles(dsn); err != nil {
+			log.Fatalf("[ERROR] An error occurred while backfilling collaborator roles: %v", err)
+		}
+	} else if *hashIPsCmd {
+		if err := hashIPs(dsn); err != nil {
+			log.Fatalf("[ERROR] An error occurred while hashing IP addresses: %v", err)
+		}
+	} else if *checkReplicaCmd {
+		consistent, err := checkReplica(dsn, *replicaDSN)
+		if err != nil {
+			log.Fatalf("[ERROR] An error occurred while checking the replica: %v", err)
+		}
+		if !consistent {
+			os.Exit(1)
+		}
 	} else {
-		log.Println("No command specified. Use -up, -down, or -version")
+		log.Println("No command specified. Use -up, -down, -version, -backfill-roles, -hash-ips or -check-replica")
 		os.Exit(1)
 	}
-}
\ No newline at end of file
+}
+
+// backfillRoles rewrites legacy collaborator permissions to their roles. It
+// is idempotent and safe to run while the API is serving traffic, since
+// both forms are read as the same role.
+func backfillRoles(dsn string) error {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	for legacy, role := range docModel.LegacyPermissions {
+		result, err := db.Exec(
+			"UPDATE collaborators SET permission = $1, updated_at = NOW() WHERE permission = $2",
+			string(role), string(legacy),
+		)
+		if err != nil {
+			return err
+		}
+
+		rows, _ := result.RowsAffected()
+		log.Printf("Backfilled %d collaborators from %q to %q\n", rows, legacy, role)
+	}
+
+	return nil
+}
+
+// hashIPs replaces every raw address in document_views with its salted
+// hash. It uses the salt the API uses, so historical and new views hash
+// identically; rows that are already hashed are skipped, so it can be
+// re-run safely.
+func hashIPs(dsn string) error {
+	salt := os.Getenv(privacy.SaltEnv)
+	if salt == "" {
+		return fmt.Errorf("%s must be set", privacy.SaltEnv)
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	rows, err := db.Query(
+		"SELECT DISTINCT ip_address FROM document_views WHERE ip_address IS NOT NULL AND ip_address <> '' AND LENGTH(ip_address) <> $1",
+		privacy.HashedIPLength,
+	)
+	if err != nil {
+		return err
+	}
+
+	var addresses []string
+	for rows.Next() {
+		var address string
+		if err := rows.Scan(&address); err != nil {
+			rows.Close()
+			return err
+		}
+		addresses = append(addresses, address)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var total int64
+	for _, address := range addresses {
+		result, err := db.Exec(
+			"UPDATE document_views SET ip_address = $1 WHERE ip_address = $2",
+			privacy.HashIP(salt, address), address,
+		)
+		if err != nil {
+			return err
+		}
+
+		affected, _ := result.RowsAffected()
+		total += affected
+	}
+
+	log.Printf("Hashed %d distinct addresses across %d document views\n", len(addresses), total)
+	return nil
+}
+
+// checkReplica compares every document in this database with the replica,
+// logging each one that differs. It reports whether the two are consistent;
+// documents written during the check may differ only until replication
+// catches up, so re-run it before investigating.
+func checkReplica(dsn, replicaDSN string) (bool, error) {
+	if replicaDSN == "" {
+		return false, fmt.Errorf("-replica must be set")
+	}
+
+	primary, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return false, err
+	}
+	defer primary.Close()
+
+	replica, err := sql.Open("postgres", replicaDSN)
+	if err != nil {
+		return false, err
+	}
+	defer replica.Close()
+
+	report, err := check.Compare(context.Background(), primary, replica)
+	if err != nil {
+		return false, err
+	}
+
+	for _, mismatch := range report.Mismatches {
+		log.Printf("Document %s: %s\n", mismatch.DocumentID, mismatch.Reason)
+	}
+	log.Printf("Compared %d documents, %d differ\n", report.Documents, len(report.Mismatches))
+	return len(report.Mismatches) == 0, nil
+}